@@ -20,11 +20,20 @@ type Claims struct {
 	jwt.RegisteredClaims        // ข้อมูลมาตรฐานของ JWT
 }
 
+// TokenTTL อายุของ token (24 ชั่วโมง)
+const TokenTTL = 24 * time.Hour
+
 // GenerateToken สร้าง JWT token
 // ฟังก์ชันสำหรับสร้าง JWT token ใหม่สำหรับผู้ใช้
 func GenerateToken(userID int, username, email, role string) (string, error) {
-	// ตั้งค่าเวลาหมดอายุของ token (24 ชั่วโมง)
-	expirationTime := time.Now().Add(24 * time.Hour)
+	return GenerateSessionToken(userID, username, email, role, "")
+}
+
+// GenerateSessionToken สร้าง JWT token ที่ผูกกับ session (เก็บ tokenID ไว้ใน claim "jti")
+// ใช้ตรวจสอบว่า token ถูกเพิกถอน (ออกจากระบบจากอุปกรณ์อื่น) หรือไม่
+func GenerateSessionToken(userID int, username, email, role, tokenID string) (string, error) {
+	// ตั้งค่าเวลาหมดอายุของ token
+	expirationTime := time.Now().Add(TokenTTL)
 
 	// สร้าง claims (ข้อมูลที่อยู่ใน token)
 	claims := &Claims{
@@ -33,6 +42,7 @@ func GenerateToken(userID int, username, email, role string) (string, error) {
 		Email:    email,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,                            // รหัส session (ว่างถ้าไม่ผูกกับ session)
 			ExpiresAt: jwt.NewNumericDate(expirationTime), // เวลาหมดอายุ
 			IssuedAt:  jwt.NewNumericDate(time.Now()),     // เวลาที่สร้าง
 			NotBefore: jwt.NewNumericDate(time.Now()),     // เวลาที่เริ่มใช้งานได้
@@ -0,0 +1,42 @@
+// cache/cache.go
+package cache
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Cache คือที่เก็บข้อมูลชั่วคราวแบบ key/value พร้อมอายุข้อมูล (TTL)
+type Cache interface {
+	// Get คืนค่าข้อมูลและ true ถ้าพบและยังไม่หมดอายุ
+	Get(key string) ([]byte, bool)
+	// Set เก็บข้อมูลพร้อมอายุข้อมูล
+	Set(key string, value []byte, ttl time.Duration)
+	// Delete ลบข้อมูลตาม key
+	Delete(keys ...string)
+	// DeletePrefix ลบข้อมูลทุก key ที่ขึ้นต้นด้วย prefix (ใช้ล้าง cache ทั้งกลุ่ม)
+	DeletePrefix(prefix string)
+}
+
+// Default cache ที่ใช้ทั้งระบบ (ค่าเริ่มต้นเป็น in-memory)
+var Default Cache = NewMemoryCache()
+
+// Init เลือกใช้ Redis ถ้าตั้งค่า REDIS_ADDR ไว้ (เช่น localhost:6379) มิฉะนั้นใช้ in-memory
+// REDIS_PASSWORD, REDIS_DB และ REDIS_POOL_SIZE (จำนวน connection สูงสุด ค่าเริ่มต้น 10) ไม่บังคับ
+func Init() {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		log.Println("✅ Cache backend: in-memory")
+		return
+	}
+
+	redisCache, err := NewRedisCache(addr, os.Getenv("REDIS_PASSWORD"), os.Getenv("REDIS_DB"))
+	if err != nil {
+		log.Printf("❌ Error connecting to Redis, using in-memory cache: %v", err)
+		return
+	}
+
+	Default = redisCache
+	log.Printf("✅ Cache backend: redis (%s)", addr)
+}
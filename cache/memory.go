@@ -0,0 +1,74 @@
+// cache/memory.go
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache เก็บข้อมูลในหน่วยความจำของ process (ใช้ได้เมื่อรันเซิร์ฟเวอร์ตัวเดียว)
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryCache สร้าง cache ในหน่วยความจำ
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryEntry{}}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.Delete(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// ล้างข้อมูลที่หมดอายุเป็นครั้งคราวเพื่อไม่ให้หน่วยความจำโตไม่จำกัด
+	if len(c.entries) >= 1000 {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	c.entries[key] = memoryEntry{value: value, expiresAt: time.Now().Add(ttl)}
+}
+
+func (c *MemoryCache) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *MemoryCache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// cache/redis.go
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	redisDialTimeout    = time.Second           // เวลาสูงสุดในการเชื่อมต่อ
+	redisIOTimeout      = time.Second           // เวลาสูงสุดของคำสั่งหนึ่งคำสั่ง
+	redisPoolWait       = 50 * time.Millisecond // เวลารอ connection ว่างเมื่อใช้ครบทุก connection
+	redisFailureBackoff = 5 * time.Second       // ช่วงที่ข้าม Redis หลังเชื่อมต่อไม่สำเร็จ (ใช้ฐานข้อมูลแทน)
+	defaultRedisPool    = 10                    // จำนวน connection สูงสุด (ปรับด้วย REDIS_POOL_SIZE)
+)
+
+// errRedisUnavailable คืนทันทีระหว่างช่วง redisFailureBackoff หรือเมื่อไม่มี connection ว่าง (ถือเป็น cache miss)
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// RedisCache เก็บข้อมูลบน Redis ผ่านโปรโตคอล RESP (ไม่ต้องพึ่ง client library)
+// ใช้ pool ของ connection (request ไม่ต้องรอกันเอง) และเชื่อมต่อใหม่อัตโนมัติเมื่อหลุด
+// เมื่อ Redis ล่ม จะข้าม Redis ทันทีเป็นเวลา redisFailureBackoff แทนการรอ timeout ทุก request
+type RedisCache struct {
+	addr     string
+	password string
+	db       string
+
+	slots     chan struct{} // จำกัดจำนวน connection ที่เปิดพร้อมกัน
+	mu        sync.Mutex
+	idle      []*redisConn
+	downUntil atomic.Int64 // unix nano ที่จะลองเชื่อมต่อ Redis อีกครั้ง (0 = ใช้งานได้)
+}
+
+// redisConn connection หนึ่งรายการใน pool
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisCache เชื่อมต่อ Redis และตรวจสอบการเชื่อมต่อด้วย PING
+func NewRedisCache(addr, password, db string) (*RedisCache, error) {
+	size := defaultRedisPool
+	if n, err := strconv.Atoi(os.Getenv("REDIS_POOL_SIZE")); err == nil && n > 0 {
+		size = n
+	}
+	c := &RedisCache{addr: addr, password: password, db: db, slots: make(chan struct{}, size)}
+
+	if _, err := c.do("PING"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *RedisCache) Get(key string) ([]byte, bool) {
+	reply, err := c.do("GET", key)
+	if err != nil {
+		logRedisError("GET", err)
+		return nil, false
+	}
+	value, ok := reply.([]byte)
+	return value, ok
+}
+
+func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) {
+	if _, err := c.do("SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+		logRedisError("SET", err)
+	}
+}
+
+func (c *RedisCache) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if _, err := c.do("DEL", keys...); err != nil {
+		logRedisError("DEL", err)
+	}
+}
+
+// DeletePrefix ใช้ SCAN แทน KEYS เพื่อไม่ให้ Redis ค้างเมื่อมีข้อมูลจำนวนมาก
+func (c *RedisCache) DeletePrefix(prefix string) {
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", prefix+"*", "COUNT", "100")
+		if err != nil {
+			logRedisError("SCAN", err)
+			return
+		}
+
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return
+		}
+		next, _ := parts[0].([]byte)
+		items, _ := parts[1].([]interface{})
+
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			if key, ok := item.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+		c.Delete(keys...)
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return
+		}
+	}
+}
+
+// logRedisError แสดงข้อผิดพลาดใน log (ไม่แสดงซ้ำทุก request ระหว่างที่ข้าม Redis อยู่)
+func logRedisError(command string, err error) {
+	if err != errRedisUnavailable {
+		log.Printf("⚠️ Redis %s error: %v", command, err)
+	}
+}
+
+// do ส่งคำสั่งไปยัง Redis และอ่านผลลัพธ์
+// connection เดิมใน pool ที่หลุดไปแล้วจะถูกปิดและลองใหม่ด้วย connection ใหม่หนึ่งครั้ง
+func (c *RedisCache) do(command string, args ...string) (interface{}, error) {
+	if time.Now().UnixNano() < c.downUntil.Load() {
+		return nil, errRedisUnavailable
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-time.After(redisPoolWait):
+		return nil, errRedisUnavailable
+	}
+	defer func() { <-c.slots }()
+
+	for attempt := 0; ; attempt++ {
+		rc, reused, err := c.get()
+		if err != nil {
+			return nil, c.markDown(err)
+		}
+
+		reply, err := rc.do(command, args...)
+		if err != nil && isConnError(err) {
+			rc.conn.Close()
+			if reused && attempt == 0 {
+				continue
+			}
+			return nil, c.markDown(err)
+		}
+		c.put(rc)
+		c.downUntil.Store(0)
+		return reply, err
+	}
+}
+
+// markDown ข้าม Redis เป็นเวลา redisFailureBackoff เพื่อให้ request อื่นไม่ต้องรอ timeout ซ้ำ
+func (c *RedisCache) markDown(err error) error {
+	c.downUntil.Store(time.Now().Add(redisFailureBackoff).UnixNano())
+	log.Printf("⚠️ Redis unavailable, bypassing cache for %s: %v", redisFailureBackoff, err)
+	return errRedisUnavailable
+}
+
+// get ดึง connection ว่างจาก pool หรือเชื่อมต่อใหม่ (reused = true ถ้าเป็น connection เดิม)
+func (c *RedisCache) get() (*redisConn, bool, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		rc := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return rc, true, nil
+	}
+	c.mu.Unlock()
+
+	rc, err := c.connect()
+	return rc, false, err
+}
+
+// put คืน connection เข้า pool
+func (c *RedisCache) put(rc *redisConn) {
+	c.mu.Lock()
+	c.idle = append(c.idle, rc)
+	c.mu.Unlock()
+}
+
+func (c *RedisCache) connect() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if c.password != "" {
+		if _, err := rc.do("AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth failed: %v", err)
+		}
+	}
+	if c.db != "" && c.db != "0" {
+		if _, err := rc.do("SELECT", c.db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select db failed: %v", err)
+		}
+	}
+	return rc, nil
+}
+
+// do ส่งคำสั่งหนึ่งคำสั่งบน connection นี้
+func (rc *redisConn) do(command string, args ...string) (interface{}, error) {
+	rc.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	if err := writeCommand(rc.conn, command, args...); err != nil {
+		return nil, err
+	}
+	return readReply(rc.reader)
+}
+
+// redisError ข้อผิดพลาดที่ Redis ตอบกลับมา (การเชื่อมต่อยังใช้ได้)
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func isConnError(err error) bool {
+	_, ok := err.(redisError)
+	return !ok
+}
+
+// writeCommand เขียนคำสั่งในรูปแบบ RESP array of bulk strings
+func writeCommand(w io.Writer, command string, args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)+1) + "\r\n")
+	for _, arg := range append([]string{command}, args...) {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readReply อ่านผลลัพธ์ RESP หนึ่งรายการ
+// simple string → string, integer → int64, bulk string → []byte (nil ถ้าไม่มีค่า), array → []interface{}
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("invalid redis reply: %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply type: %q", line)
+}
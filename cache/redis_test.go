@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis เซิร์ฟเวอร์ RESP อย่างง่ายสำหรับทดสอบ (GET คืนค่าที่ SET ไว้ คำสั่งอื่นตอบ OK)
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+	data     map[string]string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{listener: listener, data: map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(s.stop)
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		parts, _ := reply.([]interface{})
+		args := make([]string, len(parts))
+		for i, part := range parts {
+			b, _ := part.([]byte)
+			args[i] = string(b)
+		}
+		response := "+OK\r\n"
+		s.mu.Lock()
+		switch args[0] {
+		case "PING":
+			response = "+PONG\r\n"
+		case "SET":
+			s.data[args[1]] = args[2]
+		case "GET":
+			if value, ok := s.data[args[1]]; ok {
+				response = "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			} else {
+				response = "$-1\r\n"
+			}
+		}
+		s.mu.Unlock()
+		if _, err := conn.Write([]byte(response)); err != nil {
+			return
+		}
+	}
+}
+
+// stop ปิดเซิร์ฟเวอร์และทุก connection (จำลอง Redis ล่ม)
+func (s *fakeRedis) stop() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func TestRedisCacheConcurrentRequests(t *testing.T) {
+	server := startFakeRedis(t)
+	c, err := NewRedisCache(server.listener.Addr().String(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("games:1", []byte("hello"), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, ok := c.Get("games:1"); !ok || string(value) != "hello" {
+				t.Errorf("Get = %q, %v", value, ok)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	idle := len(c.idle)
+	c.mu.Unlock()
+	if idle < 1 || idle > cap(c.slots) {
+		t.Errorf("idle connections = %d, want 1..%d", idle, cap(c.slots))
+	}
+}
+
+func TestRedisCacheFailsFastWhenDown(t *testing.T) {
+	server := startFakeRedis(t)
+	c, err := NewRedisCache(server.listener.Addr().String(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.stop()
+
+	// request แรกพบว่า Redis ล่ม หลังจากนั้นต้องข้าม Redis ทันที
+	if _, ok := c.Get("games:1"); ok {
+		t.Fatal("Get succeeded with Redis down")
+	}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if _, ok := c.Get("games:1"); ok {
+			t.Fatal("Get succeeded with Redis down")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("100 Gets during backoff took %s, want fail-fast", elapsed)
+	}
+}
@@ -0,0 +1,422 @@
+// cmd/seed/main.go
+//
+// สร้างข้อมูลตัวอย่างจำนวนมาก (ผู้ใช้ หมวดหมู่ เกม รหัสส่วนลด และคำสั่งซื้อ) สำหรับทดสอบประสิทธิภาพและ demo
+// โดยไม่ต้องใช้ฐานข้อมูลจริงของมหาวิทยาลัย
+//
+// ต้องรัน API กับฐานข้อมูลนี้อย่างน้อยหนึ่งครั้งก่อน เพื่อให้ ensureSchema เพิ่มตารางและคอลัมน์ที่ระบบใช้
+//
+//	go run ./cmd/seed -dsn "user:pass@tcp(localhost:3306)/gamestore" -users 5000 -purchases 50000
+//
+// ข้อมูลที่สร้างใช้ prefix (-prefix) ในชื่อผู้ใช้ อีเมล ชื่อเกม และรหัสส่วนลด จึงแยกจากข้อมูลจริงได้
+// และรันซ้ำด้วย prefix ใหม่ได้ ผู้ใช้ทุกคนใช้รหัสผ่านเดียวกัน (-password)
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"go-api-game/utils"
+
+	_ "github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// batchSize จำนวนแถวต่อคำสั่ง INSERT
+const batchSize = 500
+
+// ราคาเกมที่พบบ่อย (น้ำหนักสูง = มีเกมราคานี้มาก)
+var priceTiers = []struct {
+	price  float64
+	weight int
+}{
+	{4.99, 10}, {9.99, 20}, {14.99, 15}, {19.99, 20}, {29.99, 15}, {39.99, 8}, {59.99, 8}, {69.99, 4},
+}
+
+var categoryNames = []string{
+	"Action", "Adventure", "RPG", "Strategy", "Simulation", "Sports", "Racing", "Puzzle",
+	"Horror", "Shooter", "Platformer", "Fighting", "Survival", "Sandbox", "Roguelike", "Visual Novel",
+}
+
+var titleWords = []string{
+	"Shadow", "Crystal", "Iron", "Last", "Eternal", "Neon", "Lost", "Silent", "Crimson", "Star",
+	"Kingdom", "Legends", "Frontier", "Odyssey", "Chronicles", "Protocol", "Horizon", "Rift", "Echoes", "Forge",
+}
+
+// seeder สถานะของการสร้างข้อมูล (id ที่สร้างแล้วใช้อ้างอิงในตารางถัดไป)
+type seeder struct {
+	db     *sql.DB
+	rnd    *rand.Rand
+	prefix string
+	now    time.Time
+	days   int
+
+	userIDs      []int64
+	userJoined   map[int64]time.Time
+	categoryIDs  []int64
+	gameIDs      []int64
+	gamePrices   map[int64]float64
+	discountIDs  []int64
+	discountRate map[int64]float64 // สัดส่วนส่วนลดโดยประมาณของแต่ละรหัส
+	discountCap  map[int64]int     // usage_limit (0 = ไม่จำกัด)
+	singleUse    map[int64]bool    // single_use_per_user
+}
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DB_DSN"), "MySQL DSN (default $DB_DSN)")
+	users := flag.Int("users", 1000, "number of users")
+	categories := flag.Int("categories", 12, "number of categories (max 16)")
+	games := flag.Int("games", 300, "number of games")
+	discounts := flag.Int("discounts", 40, "number of discount codes")
+	purchases := flag.Int("purchases", 10000, "number of purchases")
+	days := flag.Int("days", 365, "history spread over the last N days")
+	seed := flag.Int64("seed", 1, "random seed (same seed = same data)")
+	prefix := flag.String("prefix", "seed", "prefix for generated names, codes and emails")
+	password := flag.String("password", "password123", "password of every generated user")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("❌ -dsn or DB_DSN is required")
+	}
+	if *categories < 1 || *categories > len(categoryNames) {
+		log.Fatalf("❌ -categories must be between 1 and %d", len(categoryNames))
+	}
+	if *users < 1 || *games < 1 || *days < 1 || *purchases < 0 || *discounts < 0 {
+		log.Fatal("❌ -users, -games and -days must be at least 1, -purchases and -discounts at least 0")
+	}
+
+	db, err := sql.Open("mysql", *dsn)
+	if err != nil {
+		log.Fatal("❌ Cannot connect to database: ", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatal("❌ Cannot ping database: ", err)
+	}
+
+	s := &seeder{
+		db:           db,
+		rnd:          rand.New(rand.NewSource(*seed)),
+		prefix:       *prefix,
+		now:          time.Now(),
+		days:         *days,
+		userJoined:   map[int64]time.Time{},
+		gamePrices:   map[int64]float64{},
+		discountRate: map[int64]float64{},
+		discountCap:  map[int64]int{},
+		singleUse:    map[int64]bool{},
+	}
+
+	start := time.Now()
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"categories", func() error { return s.seedCategories(*categories) }},
+		{"users", func() error { return s.seedUsers(*users, *password) }},
+		{"games", func() error { return s.seedGames(*games) }},
+		{"discount codes", func() error { return s.seedDiscounts(*discounts) }},
+		{"purchases", func() error { return s.seedPurchases(*purchases) }},
+	}
+	for _, step := range steps {
+		t := time.Now()
+		if err := step.run(); err != nil {
+			log.Fatalf("❌ Error seeding %s: %v", step.name, err)
+		}
+		fmt.Printf("✅ Seeded %s in %s\n", step.name, time.Since(t).Round(time.Millisecond))
+	}
+	fmt.Printf("🌱 Done in %s\n", time.Since(start).Round(time.Millisecond))
+}
+
+// randomTime เวลาสุ่มในช่วง [from, now) เอนไปทางล่าสุด (ร้านโตขึ้นเรื่อยๆ)
+func (s *seeder) randomTime(from time.Time) time.Time {
+	span := s.now.Sub(from)
+	if span <= 0 {
+		return s.now
+	}
+	// sqrt ของค่าสุ่มทำให้ช่วงท้ายมีข้อมูลหนาแน่นกว่า
+	return from.Add(time.Duration(math.Sqrt(s.rnd.Float64()) * float64(span)))
+}
+
+// insertBatch เพิ่มข้อมูลครั้งละ batchSize แถว และคืน id ของทุกแถวตามลำดับ
+// (MySQL คืน LAST_INSERT_ID ของแถวแรก และ id ของ multi-row insert ต่อเนื่องกันเมื่อใช้ innodb_autoinc_lock_mode ปกติ)
+func (s *seeder) insertBatch(table string, columns []string, rows [][]interface{}) ([]int64, error) {
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	ids := make([]int64, 0, len(rows))
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, row := range rows[start:end] {
+			values = append(values, placeholder)
+			args = append(args, row...)
+		}
+		result, err := s.db.Exec("INSERT INTO "+table+" ("+strings.Join(columns, ", ")+") VALUES "+strings.Join(values, ", "), args...)
+		if err != nil {
+			return nil, err
+		}
+		first, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		for i := range end - start {
+			ids = append(ids, first+int64(i))
+		}
+	}
+	return ids, nil
+}
+
+func (s *seeder) seedCategories(n int) error {
+	// ใช้หมวดหมู่ที่มีอยู่แล้วถ้าชื่อซ้ำ
+	for _, name := range categoryNames[:n] {
+		var id int64
+		err := s.db.QueryRow("SELECT id FROM categories WHERE name = ?", name).Scan(&id)
+		if err == sql.ErrNoRows {
+			result, err := s.db.Exec("INSERT INTO categories (name) VALUES (?)", name)
+			if err != nil {
+				return err
+			}
+			id, _ = result.LastInsertId()
+		} else if err != nil {
+			return err
+		}
+		s.categoryIDs = append(s.categoryIDs, id)
+	}
+	return nil
+}
+
+func (s *seeder) seedUsers(n int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	from := s.now.AddDate(0, 0, -s.days)
+	joined := make([]time.Time, n)
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		name := fmt.Sprintf("%s_user_%05d", s.prefix, i+1)
+		joined[i] = s.randomTime(from)
+		// ยอดเงินส่วนใหญ่ต่ำ มีบางคนเติมเงินไว้มาก
+		balance := math.Round(s.rnd.ExpFloat64()*40*100) / 100
+		rows[i] = []interface{}{name, utils.UsernameKey(name), name + "@example.test", string(hash), "user", balance, joined[i]}
+	}
+	ids, err := s.insertBatch("users", []string{"username", "username_key", "email", "password_hash", "role", "wallet_balance", "created_at"}, rows)
+	if err != nil {
+		return err
+	}
+
+	carts := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		s.userIDs = append(s.userIDs, id)
+		s.userJoined[id] = joined[i]
+		carts[i] = []interface{}{id}
+	}
+	_, err = s.insertBatch("carts", []string{"user_id"}, carts)
+	return err
+}
+
+func (s *seeder) seedGames(n int) error {
+	totalWeight := 0
+	for _, tier := range priceTiers {
+		totalWeight += tier.weight
+	}
+
+	rows := make([][]interface{}, n)
+	prices := make([]float64, n)
+	for i := range rows {
+		pick := s.rnd.Intn(totalWeight)
+		for _, tier := range priceTiers {
+			if pick -= tier.weight; pick < 0 {
+				prices[i] = tier.price
+				break
+			}
+		}
+		name := fmt.Sprintf("%s %s %s %d", s.prefix, titleWords[s.rnd.Intn(len(titleWords))], titleWords[s.rnd.Intn(len(titleWords))], i+1)
+		release := s.now.AddDate(0, 0, -s.rnd.Intn(s.days*3)).Format("2006-01-02")
+		// สต็อกจำกัดเฉพาะบางเกม (NULL = ไม่จำกัด)
+		var stock interface{}
+		if s.rnd.Intn(10) == 0 {
+			stock = 5 + s.rnd.Intn(200)
+		}
+		rows[i] = []interface{}{
+			name, prices[i], s.categoryIDs[s.rnd.Intn(len(s.categoryIDs))],
+			fmt.Sprintf("Generated game %d for load testing.", i+1), release, "published", stock,
+		}
+	}
+	ids, err := s.insertBatch("games", []string{"name", "price", "category_id", "description", "release_date", "status", "stock_count"}, rows)
+	if err != nil {
+		return err
+	}
+
+	ranking := make([][]interface{}, len(ids))
+	for i, id := range ids {
+		s.gameIDs = append(s.gameIDs, id)
+		s.gamePrices[id] = prices[i]
+		ranking[i] = []interface{}{id, 0}
+	}
+	_, err = s.insertBatch("ranking", []string{"game_id", "sales_count"}, ranking)
+	return err
+}
+
+func (s *seeder) seedDiscounts(n int) error {
+	if n == 0 {
+		return nil
+	}
+	rows := make([][]interface{}, n)
+	rates := make([]float64, n)
+	caps := make([]int, n)
+	single := make([]bool, n)
+	for i := range rows {
+		code := strings.ToUpper(fmt.Sprintf("%s%04d", s.prefix, i+1))
+		discountType, value := "percent", float64(5*(1+s.rnd.Intn(10)))
+		rates[i] = value / 100
+		if s.rnd.Intn(3) == 0 {
+			discountType, value = "fixed", float64(2+s.rnd.Intn(9))
+			rates[i] = value / 40
+		}
+		start := s.now.AddDate(0, 0, -s.rnd.Intn(s.days))
+		end := start.AddDate(0, 0, 7+s.rnd.Intn(60))
+		var usageLimit interface{}
+		if s.rnd.Intn(2) == 0 {
+			caps[i] = 50 + s.rnd.Intn(1000)
+			usageLimit = caps[i]
+		}
+		single[i] = s.rnd.Intn(2) == 0
+		rows[i] = []interface{}{
+			code, discountType, value, float64(s.rnd.Intn(4) * 10),
+			start.Format("2006-01-02"), end.Format("2006-01-02"), usageLimit, single[i], true, false,
+		}
+	}
+	ids, err := s.insertBatch("discount_codes",
+		[]string{"code", "type", "value", "min_total", "start_date", "end_date", "usage_limit", "single_use_per_user", "active", "auto_apply"}, rows)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		s.discountIDs = append(s.discountIDs, id)
+		s.discountRate[id] = min(rates[i], 0.9)
+		s.discountCap[id] = caps[i]
+		s.singleUse[id] = single[i]
+	}
+	return nil
+}
+
+// seedPurchases สร้างคำสั่งซื้อ รายการเกม คลังเกม ธุรกรรม และการใช้รหัสส่วนลด
+// ความนิยมของเกมเป็นแบบ Zipf (เกมไม่กี่เกมขายได้มาก) และผู้ใช้บางคนซื้อบ่อยกว่าคนอื่นมาก
+func (s *seeder) seedPurchases(n int) error {
+	if n == 0 {
+		return nil
+	}
+	gamePick := rand.NewZipf(s.rnd, 1.2, 1, uint64(len(s.gameIDs)-1))
+	userPick := rand.NewZipf(s.rnd, 1.1, 4, uint64(len(s.userIDs)-1))
+
+	type purchase struct {
+		userID   int64
+		games    []int64
+		total    float64
+		final    float64
+		discount interface{}
+		at       time.Time
+		status   string
+	}
+	owned := map[int64]map[int64]bool{}
+	used := map[int64]int{}
+	usedBy := map[[2]int64]bool{}
+	purchases := make([]purchase, 0, n)
+	// จำกัดจำนวนรอบ เผื่อผู้ใช้ที่ถูกสุ่มบ่อยมีเกมครบทุกเกมแล้ว
+	for attempt := 0; len(purchases) < n && attempt < n*20; attempt++ {
+		userID := s.userIDs[userPick.Uint64()]
+		if owned[userID] == nil {
+			owned[userID] = map[int64]bool{}
+		}
+		p := purchase{userID: userID, at: s.randomTime(s.userJoined[userID]), status: "fulfilled"}
+		// ส่วนใหญ่ซื้อ 1 เกม บางครั้ง 2-4 เกม
+		for items := 1 + int(s.rnd.ExpFloat64()*0.6); len(p.games) < min(items, 4); {
+			gameID := s.gameIDs[gamePick.Uint64()]
+			if owned[userID][gameID] {
+				if s.rnd.Intn(4) == 0 {
+					break
+				}
+				continue
+			}
+			owned[userID][gameID] = true
+			p.games = append(p.games, gameID)
+			p.total += s.gamePrices[gameID]
+		}
+		if len(p.games) == 0 {
+			continue
+		}
+		p.final = p.total
+		if len(s.discountIDs) > 0 && s.rnd.Intn(100) < 15 {
+			id := s.discountIDs[s.rnd.Intn(len(s.discountIDs))]
+			// ไม่เกิน usage_limit และใช้ได้ครั้งเดียวต่อผู้ใช้ตาม single_use_per_user (เหมือนตอน checkout)
+			if (s.discountCap[id] == 0 || used[id] < s.discountCap[id]) && !(s.singleUse[id] && usedBy[[2]int64{userID, id}]) {
+				used[id]++
+				usedBy[[2]int64{userID, id}] = true
+				p.discount = id
+				p.final = math.Round(p.total*(1-s.discountRate[id])*100) / 100
+			}
+		}
+		if s.rnd.Intn(100) < 2 {
+			p.status = "refunded"
+		}
+		purchases = append(purchases, p)
+	}
+
+	rows := make([][]interface{}, len(purchases))
+	for i, p := range purchases {
+		rows[i] = []interface{}{p.userID, p.total, p.discount, p.final, 0, 0, p.status, p.at, p.at}
+	}
+	ids, err := s.insertBatch("purchases",
+		[]string{"user_id", "total_amount", "discount_code_id", "final_amount", "tax_amount", "tax_rate", "status", "purchase_date", "status_updated_at"}, rows)
+	if err != nil {
+		return err
+	}
+
+	var items, library, transactions, usages [][]interface{}
+	for i, p := range purchases {
+		for _, gameID := range p.games {
+			items = append(items, []interface{}{ids[i], gameID, s.gamePrices[gameID], 0})
+			if p.status == "fulfilled" {
+				library = append(library, []interface{}{p.userID, gameID, p.at})
+			}
+		}
+		transactions = append(transactions, []interface{}{p.userID, "purchase", p.final, fmt.Sprintf("Purchase #%d", ids[i]), p.at})
+		if p.status == "refunded" {
+			transactions = append(transactions, []interface{}{p.userID, "refund", p.final, fmt.Sprintf("Refund #%d", ids[i]), p.at.Add(time.Hour)})
+		}
+		if p.discount != nil {
+			usages = append(usages, []interface{}{p.userID, p.discount})
+		}
+	}
+	if _, err := s.insertBatch("purchase_items", []string{"purchase_id", "game_id", "price_at_purchase", "tax_amount"}, items); err != nil {
+		return err
+	}
+	if _, err := s.insertBatch("purchased_games", []string{"user_id", "game_id", "purchased_at"}, library); err != nil {
+		return err
+	}
+	if _, err := s.insertBatch("user_transactions", []string{"user_id", "type", "amount", "description", "created_at"}, transactions); err != nil {
+		return err
+	}
+	if _, err := s.insertBatch("user_discount_codes", []string{"user_id", "discount_code_id"}, usages); err != nil {
+		return err
+	}
+
+	// ยอดขายใน ranking (อันดับจะถูกคำนวณใหม่โดยงานเบื้องหลังของ API)
+	_, err = s.db.Exec(`
+		UPDATE ranking r
+		JOIN (SELECT pi.game_id, COUNT(*) as sales
+		      FROM purchase_items pi JOIN purchases p ON p.id = pi.purchase_id
+		      WHERE p.status = 'fulfilled' AND pi.game_id IN (SELECT id FROM games WHERE name LIKE ?)
+		      GROUP BY pi.game_id) s ON s.game_id = r.game_id
+		SET r.sales_count = s.sales
+	`, s.prefix+" %")
+	return err
+}
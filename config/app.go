@@ -0,0 +1,97 @@
+// config/app.go
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ค่า origin เริ่มต้นเมื่อไม่ได้ตั้ง CORS_ALLOWED_ORIGINS (frontend ตอนพัฒนาและที่ deploy ไว้)
+var defaultAllowedOrigins = []string{
+	"http://localhost:4200",
+	"https://game-shop-web.onrender.com",
+}
+
+// CORSConfig การตั้งค่า CORS ที่อ่านจาก environment
+type CORSConfig struct {
+	AllowedOrigins   []string // รองรับ wildcard หนึ่งตัวต่อ origin เช่น https://*.example.com
+	AllowCredentials bool
+	Debug            bool
+}
+
+// IsProduction ตรวจสอบว่ากำลังทำงานในโหมด production (APP_ENV=production)
+func IsProduction() bool {
+	env := strings.ToLower(os.Getenv("APP_ENV"))
+	return env == "production" || env == "prod"
+}
+
+// LoadCORS อ่านการตั้งค่า CORS จาก environment
+// CORS_ALLOWED_ORIGINS   - รายการ origin คั่นด้วย comma
+// CORS_ALLOW_CREDENTIALS - true/false (ค่าเริ่มต้น true)
+// CORS_DEBUG             - true/false (ค่าเริ่มต้น false และปิดเสมอใน production)
+func LoadCORS() CORSConfig {
+	cfg := CORSConfig{
+		AllowedOrigins:   defaultAllowedOrigins,
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") != "false",
+		Debug:            os.Getenv("CORS_DEBUG") == "true" && !IsProduction(),
+	}
+
+	if raw := os.Getenv("CORS_ALLOWED_ORIGINS"); raw != "" {
+		origins := []string{}
+		for _, origin := range strings.Split(raw, ",") {
+			if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		if len(origins) > 0 {
+			cfg.AllowedOrigins = origins
+		}
+	}
+
+	// browser ไม่ยอมรับ "Access-Control-Allow-Origin: *" พร้อม credentials
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" && cfg.AllowCredentials {
+			fmt.Println("⚠️ CORS_ALLOWED_ORIGINS=* cannot be used with credentials; list origins explicitly or use a wildcard subdomain")
+			break
+		}
+	}
+
+	return cfg
+}
+
+// SilenceVerboseLogs กรอง log ที่ส่งออกทาง stdout ให้เหลือเฉพาะข้อผิดพลาด คำเตือน และข้อความตอนเริ่มระบบ
+// (log ส่วนใหญ่ในระบบใช้ fmt.Printf พร้อม emoji นำหน้า จึงกรองจาก emoji ได้)
+// ใช้ในโหมด production เพื่อลด log และไม่ให้ข้อมูลอย่าง token ถูกพิมพ์ออกมา
+func SilenceVerboseLogs() {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		fmt.Printf("⚠️ Cannot filter verbose logs: %v\n", err)
+		return
+	}
+
+	out := os.Stdout
+	os.Stdout = writer
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if keepLogLine(line) {
+				fmt.Fprintln(out, line)
+			}
+		}
+	}()
+}
+
+// keepLogLine บรรทัดที่ยังแสดงในโหมด production
+func keepLogLine(line string) bool {
+	for _, marker := range []string{"❌", "⚠️", "🚀"} {
+		if strings.HasPrefix(strings.TrimSpace(line), marker) {
+			return true
+		}
+	}
+	return false
+}
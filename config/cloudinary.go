@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
@@ -61,13 +62,18 @@ func UploadImage(filePath string) (string, error) {
 	return uploadResult.SecureURL, nil
 }
 
+// uploadTimeout เวลาสูงสุดของการอัพโหลดภาพหนึ่งไฟล์ไปยัง Cloudinary
+const uploadTimeout = 30 * time.Second
+
 // UploadImageFromBytes อัพโหลดภาพจาก byte data (สำหรับ multipart form)
 func UploadImageFromBytes(fileBytes []byte, fileName string) (string, error) {
 	if Cld == nil {
 		return "", fmt.Errorf("cloudinary not initialized")
 	}
 
-	ctx := context.Background()
+	// จำกัดเวลาอัพโหลด เพื่อให้ Cloudinary ที่ค้างนับเป็นความล้มเหลวและ fallback ไป local ได้
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
 
 	// ✅ แก้ไข: ใช้ io.Reader แทน []byte
 	fileReader := bytes.NewReader(fileBytes)
@@ -80,6 +86,10 @@ func UploadImageFromBytes(fileBytes []byte, fileName string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("cloudinary upload error: %v", err)
 	}
+	// error จาก API ของ Cloudinary (เช่น credential ผิด) ส่งกลับมาใน result โดย err เป็น nil
+	if uploadResult.Error.Message != "" {
+		return "", fmt.Errorf("cloudinary upload error: %s", uploadResult.Error.Message)
+	}
 
 	fmt.Printf("✅ Image uploaded to Cloudinary: %s\n", uploadResult.SecureURL)
 	return uploadResult.SecureURL, nil
@@ -0,0 +1,80 @@
+// config/database.go
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ค่าเริ่มต้นของ connection pool (max_connections เริ่มต้นของ MySQL คือ 151 ต้องแบ่งให้ทุก instance ของ API)
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = 5 * time.Minute
+	defaultConnMaxIdleTime = 2 * time.Minute
+)
+
+// DBPoolConfig การตั้งค่า connection pool ของ database/sql ที่อ่านจาก environment
+type DBPoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// LoadDBPool อ่านการตั้งค่า connection pool จาก environment
+// DB_MAX_OPEN_CONNS      - จำนวน connection สูงสุด (ค่าเริ่มต้น 25)
+// DB_MAX_IDLE_CONNS      - จำนวน connection ที่เปิดค้างไว้ได้ (ค่าเริ่มต้น 10 และไม่เกิน DB_MAX_OPEN_CONNS)
+// DB_CONN_MAX_LIFETIME   - อายุสูงสุดของ connection เช่น 5m (ต้องน้อยกว่า wait_timeout ของ MySQL)
+// DB_CONN_MAX_IDLE_TIME  - เวลาที่ connection ว่างได้ก่อนถูกปิด เช่น 2m
+func LoadDBPool() DBPoolConfig {
+	cfg := DBPoolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", defaultMaxOpenConns),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", defaultMaxIdleConns),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime),
+		ConnMaxIdleTime: envDuration("DB_CONN_MAX_IDLE_TIME", defaultConnMaxIdleTime),
+	}
+	if cfg.MaxIdleConns > cfg.MaxOpenConns {
+		cfg.MaxIdleConns = cfg.MaxOpenConns
+	}
+	return cfg
+}
+
+// Apply ตั้งค่า connection pool ให้กับ database
+func (c DBPoolConfig) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
+
+// envInt อ่านจำนวนเต็มบวกจาก environment (ค่าไม่ถูกต้องใช้ค่าเริ่มต้นพร้อมคำเตือน)
+func envInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		fmt.Printf("⚠️ Invalid %s=%q, using %d\n", key, raw, fallback)
+		return fallback
+	}
+	return n
+}
+
+// envDuration อ่านช่วงเวลาจาก environment เช่น 30s, 5m (ค่าไม่ถูกต้องใช้ค่าเริ่มต้นพร้อมคำเตือน)
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		fmt.Printf("⚠️ Invalid %s=%q, using %s\n", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,214 @@
+// errreport/errreport.go
+package errreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sendTimeout เวลาสูงสุดในการส่งรายงานหนึ่งครั้ง
+const sendTimeout = 5 * time.Second
+
+// Event ข้อมูลข้อผิดพลาดที่ส่งไปยังระบบรายงาน
+type Event struct {
+	Message   string            // ข้อความของ panic หรือ error
+	Frames    []runtime.Frame   // stack ณ จุดที่เกิดข้อผิดพลาด (ใหม่สุดก่อน ตาม runtime.CallersFrames)
+	Method    string            // HTTP method ของ request (ถ้ามี)
+	URL       string            // URL ของ request (ถ้ามี)
+	Headers   map[string]string // header ของ request ที่ลบข้อมูลลับแล้ว
+	UserID    int               // ผู้ใช้ที่ยืนยันตัวตนแล้ว (0 = ไม่ทราบ)
+	Tags      map[string]string // เช่น request_id, trace_id
+	Timestamp time.Time
+}
+
+// Reporter ปลายทางของรายงานข้อผิดพลาด (เช่น Sentry หรือระบบที่รับรูปแบบเดียวกัน)
+type Reporter interface {
+	Report(event Event) error
+}
+
+// Default ปลายทางที่ใช้ทั้งระบบ (nil = ไม่ส่งรายงาน แสดงเฉพาะใน log)
+var Default Reporter
+
+// Init ตั้งค่าการรายงานข้อผิดพลาดจาก environment
+// SENTRY_DSN         - DSN ของ Sentry หรือบริการที่รองรับ API เดียวกัน (เช่น GlitchTip) ไม่ตั้ง = ปิด
+// SENTRY_ENVIRONMENT - ชื่อ environment (ค่าเริ่มต้นตาม APP_ENV)
+// SENTRY_RELEASE     - เวอร์ชันของระบบที่ deploy
+func Init() {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		return
+	}
+	reporter, err := NewSentryReporter(dsn)
+	if err != nil {
+		log.Printf("❌ Invalid SENTRY_DSN, error reporting disabled: %v", err)
+		return
+	}
+	reporter.Environment = os.Getenv("SENTRY_ENVIRONMENT")
+	if reporter.Environment == "" {
+		reporter.Environment = os.Getenv("APP_ENV")
+	}
+	reporter.Release = os.Getenv("SENTRY_RELEASE")
+	Default = reporter
+	log.Printf("✅ Error reporting: sending panics to %s", reporter.endpoint)
+}
+
+// Capture ส่งรายงานแบบไม่รอผล (ข้อผิดพลาดในการส่งแสดงใน log เท่านั้น)
+func Capture(event Event) {
+	reporter := Default
+	if reporter == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	go func() {
+		if err := reporter.Report(event); err != nil {
+			log.Printf("⚠️ Error sending error report: %v", err)
+		}
+	}()
+}
+
+// SentryReporter ส่งรายงานไปยัง store endpoint ของ Sentry (/api/{project}/store/)
+type SentryReporter struct {
+	Environment string
+	Release     string
+
+	endpoint  string
+	publicKey string
+	client    *http.Client
+}
+
+// NewSentryReporter สร้าง reporter จาก DSN รูปแบบ https://{public_key}@{host}/{project_id}
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("missing public key")
+	}
+	path := strings.Trim(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("missing project ID")
+	}
+	prefix := ""
+	if slash >= 0 {
+		prefix = "/" + path[:slash]
+	}
+
+	return &SentryReporter{
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID),
+		publicKey: u.User.Username(),
+		client:    &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// sentryFrame frame ของ stacktrace ตามรูปแบบของ Sentry (เก่าสุดก่อน)
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// splitFunction แยก package ออกจากชื่อฟังก์ชัน (go-api-game/handlers.CheckoutHandler → go-api-game/handlers, CheckoutHandler)
+func splitFunction(name string) (string, string) {
+	lastSlash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[lastSlash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	dot += lastSlash + 1
+	return name[:dot], name[dot+1:]
+}
+
+// Report ส่งรายงานหนึ่งรายการ
+func (s *SentryReporter) Report(event Event) error {
+	frames := make([]sentryFrame, 0, len(event.Frames))
+	for i := len(event.Frames) - 1; i >= 0; i-- {
+		f := event.Frames[i]
+		module, function := splitFunction(f.Function)
+		frames = append(frames, sentryFrame{
+			Function: function,
+			Module:   module,
+			Filename: f.File[strings.LastIndex(f.File, "/")+1:],
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(module, "go-api-game"),
+		})
+	}
+
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	hostname, _ := os.Hostname()
+
+	payload := map[string]interface{}{
+		"event_id":    hex.EncodeToString(idBytes),
+		"timestamp":   event.Timestamp.UTC().Format(time.RFC3339),
+		"level":       "fatal",
+		"platform":    "go",
+		"logger":      "go-api-game",
+		"server_name": hostname,
+		"message":     event.Message,
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       "panic",
+				"value":      event.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+				"mechanism":  map[string]interface{}{"type": "recover", "handled": true},
+			}},
+		},
+		"tags": event.Tags,
+	}
+	if s.Environment != "" {
+		payload["environment"] = s.Environment
+	}
+	if s.Release != "" {
+		payload["release"] = s.Release
+	}
+	if event.URL != "" {
+		payload["request"] = map[string]interface{}{
+			"method":  event.Method,
+			"url":     event.URL,
+			"headers": event.Headers,
+		}
+	}
+	if event.UserID > 0 {
+		payload["user"] = map[string]interface{}{"id": fmt.Sprint(event.UserID)}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=go-api-game/1.0, sentry_key=%s", s.publicKey))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error reporting service responded %s", resp.Status)
+	}
+	return nil
+}
@@ -2,16 +2,24 @@ module go-api-game
 
 go 1.24.0
 
-require github.com/golang-jwt/jwt/v4 v4.5.2
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/cloudinary/cloudinary-go/v2 v2.13.0
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/rs/cors v1.11.1
+	go.opentelemetry.io/proto/otlp v1.5.0
+	golang.org/x/crypto v0.42.0
+	golang.org/x/text v0.29.0
+	google.golang.org/protobuf v1.36.6
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/cloudinary/cloudinary-go/v2 v2.13.0 // indirect
 	github.com/creasty/defaults v1.7.0 // indirect
-	github.com/go-sql-driver/mysql v1.9.3 // indirect
 	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
 	github.com/google/uuid v1.5.0 // indirect
 	github.com/gorilla/schema v1.4.1 // indirect
-	github.com/rs/cors v1.11.1 // indirect
-	golang.org/x/crypto v0.42.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
 )
@@ -0,0 +1,359 @@
+// graphql/execute.go
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolveFunc ดึงข้อมูลของ root field จาก argument ที่แทนค่าตัวแปรแล้ว
+// ค่าที่คืนได้: scalar, map[string]interface{}, []map[string]interface{}, []interface{}
+// หรือ Lazy (โหลดเมื่อ client เลือก field นั้นเท่านั้น)
+type ResolveFunc func(args map[string]interface{}) (interface{}, error)
+
+// Lazy ค่าที่คำนวณเมื่อถูกเลือกใน query (ใช้กับข้อมูลที่ต้อง query เพิ่ม เช่น รายการสินค้าในคำสั่งซื้อ)
+type Lazy func() (interface{}, error)
+
+// RootField field ระดับบนสุดของ Query พร้อมรายชื่อ argument ที่รับ
+type RootField struct {
+	Args    []string
+	Resolve ResolveFunc
+}
+
+// Schema root fields ของ Query (API นี้เป็น read-only ไม่มี mutation)
+type Schema struct {
+	Query map[string]RootField
+}
+
+// Request คำขอ GraphQL ตามรูปแบบมาตรฐานของ GraphQL over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error ข้อผิดพลาดของ field พร้อม path ของ field ใน response
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response ผลลัพธ์ของคำขอ (data เป็น null เมื่อ query ไม่ถูกต้อง)
+type Response struct {
+	Data   *OrderedMap `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// OrderedMap object ที่คงลำดับ key ตามที่ client เลือก (encoding/json เรียง key ของ map)
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap สร้าง OrderedMap ว่าง
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: map[string]interface{}{}}
+}
+
+// Set กำหนดค่าของ key (key ใหม่ต่อท้าย key ที่มีอยู่แล้วคงตำแหน่งเดิม)
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get คืนค่าของ key
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// MarshalJSON เขียน object ตามลำดับ key
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// fieldError ข้อผิดพลาดระหว่างประมวลผล field ย่อย (ทำให้ root field นั้นเป็น null)
+type fieldError struct {
+	message string
+	path    []interface{}
+}
+
+func (e *fieldError) Error() string { return e.message }
+
+// Execute ประมวลผลคำขอหนึ่งรายการ
+// ข้อผิดพลาดของ root field หนึ่งไม่กระทบ field อื่น (field นั้นเป็น null และมีรายการใน errors)
+func (s *Schema) Execute(req Request) Response {
+	if strings.TrimSpace(req.Query) == "" {
+		return Response{Errors: []Error{{Message: "Must provide query string"}}}
+	}
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	if op.Type != "query" {
+		return Response{Errors: []Error{{Message: fmt.Sprintf("%s operations are not supported", op.Type)}}}
+	}
+
+	variables, err := coerceVariables(op.Variables, req.Variables)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	resp := Response{Data: NewOrderedMap()}
+	for _, field := range op.SelectionSet {
+		key := field.ResponseKey()
+		if field.Name == "__typename" {
+			resp.Data.Set(key, "Query")
+			continue
+		}
+
+		value, err := s.resolveRoot(field, variables)
+		if err != nil {
+			resp.Data.Set(key, nil)
+			path := []interface{}{key}
+			if fe, ok := err.(*fieldError); ok && fe.path != nil {
+				path = fe.path
+			}
+			resp.Errors = append(resp.Errors, Error{Message: err.Error(), Path: path})
+			continue
+		}
+		resp.Data.Set(key, value)
+	}
+	return resp
+}
+
+// resolveRoot เรียก resolver ของ root field แล้วเลือกเฉพาะ field ย่อยที่ client ขอ
+func (s *Schema) resolveRoot(field *Field, variables map[string]interface{}) (interface{}, error) {
+	root, ok := s.Query[field.Name]
+	if !ok {
+		return nil, fmt.Errorf("Cannot query field %q on type \"Query\". Available fields: %s",
+			field.Name, strings.Join(s.fieldNames(), ", "))
+	}
+
+	args := map[string]interface{}{}
+	for name, raw := range field.Arguments {
+		if !contains(root.Args, name) {
+			return nil, fmt.Errorf("Unknown argument %q on field \"Query.%s\"", name, field.Name)
+		}
+		value, err := substitute(raw, variables)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+
+	value, err := root.Resolve(args)
+	if err != nil {
+		return nil, err
+	}
+	return complete(value, field, []interface{}{field.ResponseKey()})
+}
+
+func (s *Schema) fieldNames() []string {
+	names := make([]string, 0, len(s.Query))
+	for name := range s.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// complete ตัดผลลัพธ์ให้เหลือเฉพาะ field ที่เลือก (object ต้องมี selection set, scalar ห้ามมี)
+func complete(value interface{}, field *Field, path []interface{}) (interface{}, error) {
+	if lazy, ok := value.(Lazy); ok {
+		loaded, err := lazy()
+		if err != nil {
+			return nil, &fieldError{err.Error(), path}
+		}
+		value = loaded
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		if len(field.SelectionSet) == 0 {
+			return nil, &fieldError{fmt.Sprintf("Field %q of object type must have a selection of subfields", field.Name), path}
+		}
+		out := NewOrderedMap()
+		for _, sub := range field.SelectionSet {
+			key := sub.ResponseKey()
+			if len(sub.Arguments) > 0 {
+				return nil, &fieldError{fmt.Sprintf("Field %q does not accept arguments", sub.Name), appendPath(path, key)}
+			}
+			child, ok := v[sub.Name]
+			if !ok {
+				return nil, &fieldError{fmt.Sprintf("Cannot query field %q on %q", sub.Name, field.Name), appendPath(path, key)}
+			}
+			completed, err := complete(child, sub, appendPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			out.Set(key, completed)
+		}
+		return out, nil
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			completed, err := complete(item, field, appendPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = completed
+		}
+		return list, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			completed, err := complete(item, field, appendPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = completed
+		}
+		return list, nil
+	}
+
+	if len(field.SelectionSet) > 0 {
+		return nil, &fieldError{fmt.Sprintf("Field %q must not have a selection since it is a scalar", field.Name), path}
+	}
+	return value, nil
+}
+
+func appendPath(path []interface{}, segment interface{}) []interface{} {
+	out := make([]interface{}, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, segment)
+}
+
+// selectOperation เลือก operation ที่จะประมวลผล (ต้องระบุ operationName เมื่อมีหลาย operation)
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("Must provide operation name if query contains multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown operation named %q", name)
+}
+
+// coerceVariables รวมค่าตัวแปรที่ส่งมากับค่าเริ่มต้น และตรวจตัวแปรที่ห้ามเป็น null
+func coerceVariables(defs []*VariableDefinition, provided map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, def := range defs {
+		value, ok := provided[def.Name]
+		if !ok && def.HasDefault {
+			value, ok = def.Default, true
+		}
+		if def.NonNull && value == nil {
+			return nil, fmt.Errorf("Variable \"$%s\" of required type %q was not provided", def.Name, def.Type)
+		}
+		if ok {
+			values[def.Name] = value
+		}
+	}
+	return values, nil
+}
+
+// substitute แทนค่าตัวแปรใน argument (รวมถึงใน list และ object)
+func substitute(value interface{}, variables map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case variableRef:
+		resolved, ok := variables[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("Variable \"$%s\" is not defined", string(v))
+		}
+		return resolved, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := substitute(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := substitute(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IntArg อ่าน argument ชนิด Int (ตัวแปรจาก JSON เป็น float64 จึงรับเลขที่ไม่มีเศษด้วย)
+func IntArg(args map[string]interface{}, name string, def int) (int, error) {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("Argument %q must be an Int", name)
+}
+
+// StringArg อ่าน argument ชนิด String (คืน "" เมื่อไม่ได้ส่งมา)
+func StringArg(args map[string]interface{}, name string) (string, error) {
+	value, ok := args[name]
+	if !ok || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Argument %q must be a String", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,521 @@
+// graphql/parser.go
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser นี้รองรับ GraphQL query language บางส่วนที่ API ใช้ (ไม่ต้องพึ่ง library ภายนอก):
+// operation แบบ query, ตัวแปร, alias, argument และ selection set ซ้อนกัน
+// fragment และ directive ยังไม่รองรับ (จะได้ error ที่อ่านเข้าใจได้แทน)
+
+// Document เอกสาร GraphQL ที่ parse แล้ว
+type Document struct {
+	Operations []*Operation
+}
+
+// Operation หนึ่ง operation ในเอกสาร (query { ... })
+type Operation struct {
+	Type         string
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []*Field
+}
+
+// VariableDefinition การประกาศตัวแปร เช่น ($limit: Int = 20)
+type VariableDefinition struct {
+	Name       string
+	Type       string
+	NonNull    bool
+	Default    interface{}
+	HasDefault bool
+}
+
+// Field field ที่เลือก พร้อม alias, argument และ field ย่อย
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    map[string]interface{}
+	SelectionSet []*Field
+}
+
+// ResponseKey ชื่อ key ใน response (alias ถ้ามี)
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// variableRef การอ้างอิงตัวแปรใน argument ($name) แทนค่าตอน execute
+type variableRef string
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// SyntaxError ข้อผิดพลาดจากการ parse พร้อมตำแหน่งในข้อความ
+type SyntaxError struct {
+	Message string
+	Pos     int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax error at position %d: %s", e.Pos, e.Message)
+}
+
+// tokenize แยกข้อความเป็น token (ข้ามช่องว่าง, comma และ comment)
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			pos++
+		case c == '#':
+			for pos < len(src) && src[pos] != '\n' {
+				pos++
+			}
+		case strings.HasPrefix(src[pos:], "..."):
+			tokens = append(tokens, token{tokPunct, "...", pos})
+			pos += 3
+		case strings.IndexByte("!$()[]{}:=@|&", c) >= 0:
+			tokens = append(tokens, token{tokPunct, string(c), pos})
+			pos++
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			start := pos
+			for pos < len(src) && (src[pos] == '_' || src[pos] >= 'A' && src[pos] <= 'Z' ||
+				src[pos] >= 'a' && src[pos] <= 'z' || src[pos] >= '0' && src[pos] <= '9') {
+				pos++
+			}
+			tokens = append(tokens, token{tokName, src[start:pos], start})
+		case c == '-' || c >= '0' && c <= '9':
+			start := pos
+			kind := tokInt
+			if c == '-' {
+				pos++
+			}
+			for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+				pos++
+			}
+			if pos < len(src) && src[pos] == '.' {
+				kind = tokFloat
+				pos++
+				for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+					pos++
+				}
+			}
+			if pos < len(src) && (src[pos] == 'e' || src[pos] == 'E') {
+				kind = tokFloat
+				pos++
+				if pos < len(src) && (src[pos] == '+' || src[pos] == '-') {
+					pos++
+				}
+				for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+					pos++
+				}
+			}
+			tokens = append(tokens, token{kind, src[start:pos], start})
+		case c == '"':
+			value, end, err := readString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, value, pos})
+			pos = end
+		default:
+			return nil, &SyntaxError{fmt.Sprintf("unexpected character %q", c), pos}
+		}
+	}
+	return append(tokens, token{tokEOF, "", pos}), nil
+}
+
+// readString อ่าน string literal ("..." หรือ block string """...""") คืนค่าและตำแหน่งถัดไป
+func readString(src string, pos int) (string, int, error) {
+	if strings.HasPrefix(src[pos:], `"""`) {
+		end := strings.Index(src[pos+3:], `"""`)
+		if end < 0 {
+			return "", 0, &SyntaxError{"unterminated block string", pos}
+		}
+		return strings.TrimSpace(src[pos+3 : pos+3+end]), pos + 6 + end, nil
+	}
+
+	var sb strings.Builder
+	i := pos + 1
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '"':
+			return sb.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, &SyntaxError{"unterminated string", pos}
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case '"', '\\', '/':
+				sb.WriteByte(src[i])
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, &SyntaxError{"invalid unicode escape", i}
+				}
+				code, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, &SyntaxError{"invalid unicode escape", i}
+				}
+				sb.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", 0, &SyntaxError{fmt.Sprintf("invalid escape \\%c", src[i]), i}
+			}
+			i++
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, &SyntaxError{"unterminated string", pos}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.value == value
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{fmt.Sprintf(format, args...), p.peek().pos}
+}
+
+func (p *parser) expectPunct(value string) error {
+	if !p.isPunct(value) {
+		return p.errorf("expected %q, found %s", value, describe(p.peek()))
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return "", p.errorf("expected name, found %s", describe(t))
+	}
+	p.advance()
+	return t.value, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.value)
+}
+
+// Parse แปลงข้อความ query เป็น Document
+func Parse(src string) (*Document, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	doc := &Document{}
+	for p.peek().kind != tokEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.Operations = append(doc.Operations, op)
+	}
+	if len(doc.Operations) == 0 {
+		return nil, &SyntaxError{"query is empty", 0}
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: "query"}
+
+	// รูปแบบย่อ: { games { id } }
+	if p.isPunct("{") {
+		selection, err := p.parseSelectionSet()
+		op.SelectionSet = selection
+		return op, err
+	}
+
+	t := p.peek()
+	if t.kind != tokName {
+		return nil, p.errorf("expected operation, found %s", describe(t))
+	}
+	switch t.value {
+	case "query", "mutation", "subscription":
+		op.Type = t.value
+		p.advance()
+	case "fragment":
+		return nil, p.errorf("fragments are not supported")
+	default:
+		return nil, p.errorf("unknown operation %q", t.value)
+	}
+
+	if p.peek().kind == tokName {
+		op.Name = p.advance().value
+	}
+	if p.isPunct("(") {
+		vars, err := p.parseVariableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.Variables = vars
+	}
+	if p.isPunct("@") {
+		return nil, p.errorf("directives are not supported")
+	}
+
+	selection, err := p.parseSelectionSet()
+	op.SelectionSet = selection
+	return op, err
+}
+
+func (p *parser) parseVariableDefinitions() ([]*VariableDefinition, error) {
+	p.advance() // (
+	var defs []*VariableDefinition
+	for !p.isPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		typ, nonNull, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		def := &VariableDefinition{Name: name, Type: typ, NonNull: nonNull}
+		if p.isPunct("=") {
+			p.advance()
+			value, err := p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			def.Default, def.HasDefault = value, true
+		}
+		defs = append(defs, def)
+	}
+	p.advance() // )
+	return defs, nil
+}
+
+// parseType อ่านชนิดของตัวแปร เช่น Int, [String!]! คืนข้อความของชนิดและว่าห้ามเป็น null หรือไม่
+func (p *parser) parseType() (string, bool, error) {
+	var typ string
+	if p.isPunct("[") {
+		p.advance()
+		inner, _, err := p.parseType()
+		if err != nil {
+			return "", false, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return "", false, err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return "", false, err
+		}
+		typ = name
+	}
+	if p.isPunct("!") {
+		p.advance()
+		return typ + "!", true, nil
+	}
+	return typ, false, nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Field, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	for !p.isPunct("}") {
+		if p.isPunct("...") {
+			return nil, p.errorf("fragments are not supported")
+		}
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	p.advance() // }
+	if len(fields) == 0 {
+		return nil, p.errorf("selection set must not be empty")
+	}
+	return fields, nil
+}
+
+func (p *parser) parseField() (*Field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{Name: name}
+	if p.isPunct(":") {
+		p.advance()
+		if field.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		field.Alias = name
+	}
+
+	if p.isPunct("(") {
+		p.advance()
+		field.Arguments = map[string]interface{}{}
+		for !p.isPunct(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue(false)
+			if err != nil {
+				return nil, err
+			}
+			field.Arguments[argName] = value
+		}
+		p.advance() // )
+	}
+	if p.isPunct("@") {
+		return nil, p.errorf("directives are not supported")
+	}
+	if p.isPunct("{") {
+		if field.SelectionSet, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+// parseValue อ่านค่าของ argument (constOnly = ห้ามใช้ตัวแปร เช่น ค่าเริ่มต้นของตัวแปร)
+func (p *parser) parseValue(constOnly bool) (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokInt:
+		p.advance()
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, &SyntaxError{"invalid integer " + t.value, t.pos}
+		}
+		return n, nil
+	case tokFloat:
+		p.advance()
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, &SyntaxError{"invalid number " + t.value, t.pos}
+		}
+		return f, nil
+	case tokString:
+		p.advance()
+		return t.value, nil
+	case tokName:
+		p.advance()
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.value, nil // enum
+	case tokPunct:
+		switch t.value {
+		case "$":
+			if constOnly {
+				return nil, p.errorf("variables are not allowed here")
+			}
+			p.advance()
+			name, err := p.expectName()
+			return variableRef(name), err
+		case "[":
+			p.advance()
+			list := []interface{}{}
+			for !p.isPunct("]") {
+				value, err := p.parseValue(constOnly)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.advance()
+			return list, nil
+		case "{":
+			p.advance()
+			object := map[string]interface{}{}
+			for !p.isPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue(constOnly)
+				if err != nil {
+					return nil, err
+				}
+				object[name] = value
+			}
+			p.advance()
+			return object, nil
+		}
+	}
+	return nil, p.errorf("expected value, found %s", describe(t))
+}
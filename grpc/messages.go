@@ -0,0 +1,216 @@
+// grpc/messages.go
+package grpc
+
+// message ของ GameService ตาม game_service.proto (เขียนแทนโค้ดที่ generate จาก protoc-gen-go)
+// การเข้ารหัสถูกเทียบกับ library protobuf อ้างอิงใน messages_test.go ซึ่งอ่านนิยามจากไฟล์ .proto โดยตรง
+// เพิ่มหรือเปลี่ยน field ต้องแก้ทั้งไฟล์ .proto และที่นี่ (test จะล้มเหลวถ้าไม่ตรงกัน)
+
+// setText อ่าน field ชนิด string ลงใน dst
+func setText(dst *string, v Value) error {
+	text, err := v.Text()
+	*dst = text
+	return err
+}
+
+// GetGameRequest ข้อมูลของ call GetGame
+type GetGameRequest struct {
+	GameID int64  // 1
+	Locale string // 2
+}
+
+func (m *GetGameRequest) Marshal() []byte {
+	var e Encoder
+	e.Int64(1, m.GameID)
+	e.String(2, m.Locale)
+	return e.Bytes()
+}
+
+func (m *GetGameRequest) Unmarshal(data []byte) error {
+	*m = GetGameRequest{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.GameID = v.Int64()
+		case field == 2 && v.Wire == wireBytes:
+			return setText(&m.Locale, v)
+		}
+		return nil
+	})
+}
+
+// Game ข้อมูลเกมที่ GetGame ส่งกลับ
+type Game struct {
+	ID          int64   // 1
+	Name        string  // 2
+	Price       float64 // 3
+	Category    string  // 4
+	ImageURL    string  // 5
+	Description string  // 6
+	ReleaseDate string  // 7
+	Status      string  // 8
+	MinAge      int32   // 9
+	AgeRating   string  // 10
+}
+
+func (m *Game) Marshal() []byte {
+	var e Encoder
+	e.Int64(1, m.ID)
+	e.String(2, m.Name)
+	e.Double(3, m.Price)
+	e.String(4, m.Category)
+	e.String(5, m.ImageURL)
+	e.String(6, m.Description)
+	e.String(7, m.ReleaseDate)
+	e.String(8, m.Status)
+	e.Int64(9, int64(m.MinAge))
+	e.String(10, m.AgeRating)
+	return e.Bytes()
+}
+
+func (m *Game) Unmarshal(data []byte) error {
+	*m = Game{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.ID = v.Int64()
+		case field == 3 && v.Wire == wireFixed64:
+			m.Price = v.Double()
+		case field == 9 && v.Wire == wireVarint:
+			m.MinAge = int32(v.Int64())
+		case v.Wire == wireBytes:
+			switch field {
+			case 2:
+				return setText(&m.Name, v)
+			case 4:
+				return setText(&m.Category, v)
+			case 5:
+				return setText(&m.ImageURL, v)
+			case 6:
+				return setText(&m.Description, v)
+			case 7:
+				return setText(&m.ReleaseDate, v)
+			case 8:
+				return setText(&m.Status, v)
+			case 10:
+				return setText(&m.AgeRating, v)
+			}
+		}
+		return nil
+	})
+}
+
+// CheckOwnershipRequest ข้อมูลของ call CheckOwnership
+type CheckOwnershipRequest struct {
+	UserID int64 // 1
+	GameID int64 // 2
+}
+
+func (m *CheckOwnershipRequest) Marshal() []byte {
+	var e Encoder
+	e.Int64(1, m.UserID)
+	e.Int64(2, m.GameID)
+	return e.Bytes()
+}
+
+func (m *CheckOwnershipRequest) Unmarshal(data []byte) error {
+	*m = CheckOwnershipRequest{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.UserID = v.Int64()
+		case field == 2 && v.Wire == wireVarint:
+			m.GameID = v.Int64()
+		}
+		return nil
+	})
+}
+
+// CheckOwnershipResponse ผลของ CheckOwnership
+type CheckOwnershipResponse struct {
+	Owned       bool   // 1
+	PurchasedAt string // 2
+	Delisted    bool   // 3
+}
+
+func (m *CheckOwnershipResponse) Marshal() []byte {
+	var e Encoder
+	e.Bool(1, m.Owned)
+	e.String(2, m.PurchasedAt)
+	e.Bool(3, m.Delisted)
+	return e.Bytes()
+}
+
+func (m *CheckOwnershipResponse) Unmarshal(data []byte) error {
+	*m = CheckOwnershipResponse{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.Owned = v.Bool()
+		case field == 2 && v.Wire == wireBytes:
+			return setText(&m.PurchasedAt, v)
+		case field == 3 && v.Wire == wireVarint:
+			m.Delisted = v.Bool()
+		}
+		return nil
+	})
+}
+
+// GrantLicenseRequest ข้อมูลของ call GrantLicense
+type GrantLicenseRequest struct {
+	UserID int64  // 1
+	GameID int64  // 2
+	Reason string // 3
+}
+
+func (m *GrantLicenseRequest) Marshal() []byte {
+	var e Encoder
+	e.Int64(1, m.UserID)
+	e.Int64(2, m.GameID)
+	e.String(3, m.Reason)
+	return e.Bytes()
+}
+
+func (m *GrantLicenseRequest) Unmarshal(data []byte) error {
+	*m = GrantLicenseRequest{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.UserID = v.Int64()
+		case field == 2 && v.Wire == wireVarint:
+			m.GameID = v.Int64()
+		case field == 3 && v.Wire == wireBytes:
+			return setText(&m.Reason, v)
+		}
+		return nil
+	})
+}
+
+// GrantLicenseResponse ผลของ GrantLicense
+type GrantLicenseResponse struct {
+	Granted      bool   // 1
+	AlreadyOwned bool   // 2
+	PurchasedAt  string // 3
+}
+
+func (m *GrantLicenseResponse) Marshal() []byte {
+	var e Encoder
+	e.Bool(1, m.Granted)
+	e.Bool(2, m.AlreadyOwned)
+	e.String(3, m.PurchasedAt)
+	return e.Bytes()
+}
+
+func (m *GrantLicenseResponse) Unmarshal(data []byte) error {
+	*m = GrantLicenseResponse{}
+	return Decode(data, func(field int, v Value) error {
+		switch {
+		case field == 1 && v.Wire == wireVarint:
+			m.Granted = v.Bool()
+		case field == 2 && v.Wire == wireVarint:
+			m.AlreadyOwned = v.Bool()
+		case field == 3 && v.Wire == wireBytes:
+			return setText(&m.PurchasedAt, v)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,233 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ทดสอบ message ที่เขียนเองกับ library protobuf อ้างอิง (google.golang.org/protobuf)
+// นิยาม message อ่านจาก game_service.proto ด้วย compiler ของ protobuf จึงจับได้ถ้าไฟล์ .proto กับ messages.go ไม่ตรงกัน
+
+type wireMessage interface {
+	Marshal() []byte
+	Unmarshal(data []byte) error
+}
+
+// messageCase ค่าตัวอย่างของ message พร้อมค่าที่คาดหวังตามชื่อ field ใน .proto
+type messageCase struct {
+	name   string
+	msg    wireMessage
+	fields map[string]interface{}
+}
+
+// loadServiceProto อ่าน game_service.proto ด้วย compiler อ้างอิง
+func loadServiceProto(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{}}
+	files, err := compiler.Compile(context.Background(), "game_service.proto")
+	if err != nil {
+		t.Fatalf("compile game_service.proto: %v", err)
+	}
+	return files[0]
+}
+
+func messageCases() []messageCase {
+	return []messageCase{
+		{"GetGameRequest", &GetGameRequest{GameID: 42, Locale: "th"},
+			map[string]interface{}{"game_id": int64(42), "locale": "th"}},
+		{"GetGameRequest", &GetGameRequest{},
+			map[string]interface{}{"game_id": int64(0), "locale": ""}},
+		{"Game", &Game{
+			ID: 1 << 40, Name: "เกมผจญภัย", Price: 59.99, Category: "RPG", ImageURL: "/uploads/a.png",
+			Description: "line1\nline2", ReleaseDate: "2024-05-01", Status: "published", MinAge: 18, AgeRating: "PEGI 18",
+		}, map[string]interface{}{
+			"id": int64(1 << 40), "name": "เกมผจญภัย", "price": 59.99, "category": "RPG", "image_url": "/uploads/a.png",
+			"description": "line1\nline2", "release_date": "2024-05-01", "status": "published", "min_age": int32(18), "age_rating": "PEGI 18",
+		}},
+		// ค่าติดลบ (varint 10 ไบต์) และ -0 ซึ่งไม่ใช่ค่า default ของ double
+		{"Game", &Game{ID: math.MinInt64, Price: math.Copysign(0, -1), MinAge: -1},
+			map[string]interface{}{
+				"id": int64(math.MinInt64), "name": "", "price": math.Copysign(0, -1), "category": "", "image_url": "",
+				"description": "", "release_date": "", "status": "", "min_age": int32(-1), "age_rating": "",
+			}},
+		{"Game", &Game{Price: math.Inf(1), MinAge: math.MaxInt32},
+			map[string]interface{}{
+				"id": int64(0), "name": "", "price": math.Inf(1), "category": "", "image_url": "",
+				"description": "", "release_date": "", "status": "", "min_age": int32(math.MaxInt32), "age_rating": "",
+			}},
+		{"CheckOwnershipRequest", &CheckOwnershipRequest{UserID: 7, GameID: 300},
+			map[string]interface{}{"user_id": int64(7), "game_id": int64(300)}},
+		{"CheckOwnershipResponse", &CheckOwnershipResponse{Owned: true, PurchasedAt: "2024-05-01 10:00:00", Delisted: true},
+			map[string]interface{}{"owned": true, "purchased_at": "2024-05-01 10:00:00", "delisted": true}},
+		{"CheckOwnershipResponse", &CheckOwnershipResponse{},
+			map[string]interface{}{"owned": false, "purchased_at": "", "delisted": false}},
+		{"GrantLicenseRequest", &GrantLicenseRequest{UserID: 1, GameID: math.MaxInt64, Reason: "beta key 🎮"},
+			map[string]interface{}{"user_id": int64(1), "game_id": int64(math.MaxInt64), "reason": "beta key 🎮"}},
+		{"GrantLicenseResponse", &GrantLicenseResponse{AlreadyOwned: true, PurchasedAt: "2023-01-02 03:04:05"},
+			map[string]interface{}{"granted": false, "already_owned": true, "purchased_at": "2023-01-02 03:04:05"}},
+	}
+}
+
+// referenceMessage สร้าง message ของ library อ้างอิงจากค่าที่คาดหวัง (ทุก field ใน .proto ต้องมีค่าใน fields)
+func referenceMessage(t *testing.T, file protoreflect.FileDescriptor, tc messageCase) *dynamicpb.Message {
+	t.Helper()
+	desc := file.Messages().ByName(protoreflect.Name(tc.name))
+	if desc == nil {
+		t.Fatalf("message %s is not defined in game_service.proto", tc.name)
+	}
+	if desc.Fields().Len() != len(tc.fields) {
+		t.Fatalf("%s has %d fields in game_service.proto, test covers %d", tc.name, desc.Fields().Len(), len(tc.fields))
+	}
+	msg := dynamicpb.NewMessage(desc)
+	for name, value := range tc.fields {
+		fd := desc.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			t.Fatalf("%s.%s is not defined in game_service.proto", tc.name, name)
+		}
+		msg.Set(fd, protoreflect.ValueOf(value))
+	}
+	return msg
+}
+
+func TestMessagesMatchReferenceEncoding(t *testing.T) {
+	file := loadServiceProto(t)
+	for _, tc := range messageCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			ref := referenceMessage(t, file, tc)
+			want, err := proto.MarshalOptions{Deterministic: true}.Marshal(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := tc.msg.Marshal()
+			if !bytes.Equal(got, want) {
+				t.Fatalf("Marshal = % x\nreference = % x", got, want)
+			}
+
+			decoded := dynamicpb.NewMessage(ref.Descriptor())
+			if err := proto.Unmarshal(got, decoded); err != nil {
+				t.Fatalf("reference Unmarshal: %v", err)
+			}
+			if !proto.Equal(decoded, ref) {
+				t.Errorf("reference decoded %v, want %v", decoded, ref)
+			}
+
+			back := reflect.New(reflect.TypeOf(tc.msg).Elem()).Interface().(wireMessage)
+			if err := back.Unmarshal(want); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(back, tc.msg) {
+				t.Errorf("Unmarshal = %+v, want %+v", back, tc.msg)
+			}
+		})
+	}
+}
+
+func TestMessagesMatchServiceDefinition(t *testing.T) {
+	file := loadServiceProto(t)
+	if file.Package() != "gamestore.v1" {
+		t.Errorf("package = %s, want gamestore.v1 (method paths use this prefix)", file.Package())
+	}
+	service := file.Services().ByName("GameService")
+	if service == nil {
+		t.Fatal("GameService is not defined")
+	}
+	want := map[string][2]string{
+		"GetGame":        {"GetGameRequest", "Game"},
+		"CheckOwnership": {"CheckOwnershipRequest", "CheckOwnershipResponse"},
+		"GrantLicense":   {"GrantLicenseRequest", "GrantLicenseResponse"},
+	}
+	if service.Methods().Len() != len(want) {
+		t.Errorf("GameService has %d methods, want %d", service.Methods().Len(), len(want))
+	}
+	for name, types := range want {
+		method := service.Methods().ByName(protoreflect.Name(name))
+		if method == nil {
+			t.Errorf("method %s is not defined", name)
+			continue
+		}
+		if string(method.Input().Name()) != types[0] || string(method.Output().Name()) != types[1] {
+			t.Errorf("%s(%s) returns (%s), want %s(%s) returns (%s)",
+				name, method.Input().Name(), method.Output().Name(), name, types[0], types[1])
+		}
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			t.Errorf("%s is streaming, the server only supports unary calls", name)
+		}
+	}
+}
+
+// ข้อมูลจาก client รุ่นอื่นอาจมี field ที่ไม่รู้จักหรือชนิดไม่ตรง ต้องอ่านได้ผลเดียวกับ library อ้างอิง
+func TestUnmarshalMatchesReferenceOnUnusualInput(t *testing.T) {
+	file := loadServiceProto(t)
+	desc := file.Messages().ByName("GrantLicenseRequest")
+
+	base := (&GrantLicenseRequest{UserID: 5, GameID: 9, Reason: "ok"}).Marshal()
+	unknown := append([]byte{}, base...)
+	unknown = protowire.AppendTag(unknown, 20, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+	unknown = protowire.AppendTag(unknown, 21, protowire.Fixed32Type)
+	unknown = protowire.AppendFixed32(unknown, 7)
+	unknown = protowire.AppendTag(unknown, 22, protowire.Fixed64Type)
+	unknown = protowire.AppendFixed64(unknown, 7)
+	unknown = protowire.AppendTag(unknown, 23, protowire.BytesType)
+	unknown = protowire.AppendString(unknown, "future")
+	unknown = protowire.AppendTag(unknown, 24, protowire.StartGroupType)
+	unknown = protowire.AppendTag(unknown, 1, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 3)
+	unknown = protowire.AppendTag(unknown, 24, protowire.EndGroupType)
+
+	wrongType := protowire.AppendTag(nil, 1, protowire.BytesType) // user_id ส่งมาเป็น bytes
+	wrongType = protowire.AppendString(wrongType, "5")
+	wrongType = append(wrongType, base[2:]...)
+
+	// field ที่ส่งซ้ำ: ค่าสุดท้ายชนะ
+	repeated := append(append([]byte{}, base...), (&GrantLicenseRequest{GameID: 10}).Marshal()...)
+
+	invalidUTF8 := protowire.AppendTag(nil, 3, protowire.BytesType)
+	invalidUTF8 = protowire.AppendBytes(invalidUTF8, []byte{0xff, 0xfe})
+
+	inputs := map[string][]byte{
+		"unknown fields":     unknown,
+		"wrong wire type":    wrongType,
+		"repeated field":     repeated,
+		"invalid UTF-8":      invalidUTF8,
+		"bad field zero":     {0x00, 0x01},
+		"unterminated group": protowire.AppendTag(nil, 24, protowire.StartGroupType),
+	}
+	for i := range base {
+		inputs["truncated/"+string(rune('a'+i))] = base[:i]
+	}
+
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			ref := dynamicpb.NewMessage(desc)
+			refErr := proto.Unmarshal(data, ref)
+
+			var got GrantLicenseRequest
+			err := got.Unmarshal(data)
+			if (err != nil) != (refErr != nil) {
+				t.Fatalf("Unmarshal error = %v, reference error = %v", err, refErr)
+			}
+			if err != nil {
+				return
+			}
+			want := GrantLicenseRequest{
+				UserID: ref.Get(desc.Fields().ByName("user_id")).Int(),
+				GameID: ref.Get(desc.Fields().ByName("game_id")).Int(),
+				Reason: ref.Get(desc.Fields().ByName("reason")).String(),
+			}
+			if got != want {
+				t.Errorf("Unmarshal = %+v, reference = %+v", got, want)
+			}
+		})
+	}
+}
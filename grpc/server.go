@@ -0,0 +1,181 @@
+// grpc/server.go
+package grpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// server นี้ implement gRPC over HTTP/2 (https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md)
+// เฉพาะ unary call แบบไม่บีบอัด ซึ่งเพียงพอสำหรับ service ภายใน และไม่ต้องพึ่ง library ภายนอก
+
+// maxMessageSize ขนาด message สูงสุดที่รับ (เท่ากับค่าเริ่มต้นของ gRPC)
+const maxMessageSize = 4 << 20
+
+// Code รหัสสถานะของ gRPC
+type Code int
+
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+)
+
+// Status ข้อผิดพลาดพร้อมรหัสสถานะที่ส่งกลับใน trailer grpc-status
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("grpc: code %d: %s", s.Code, s.Message)
+}
+
+// Errorf สร้างข้อผิดพลาดพร้อมรหัสสถานะ
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler ประมวลผล unary call: รับ message ที่เข้ารหัสแล้ว คืน message ของ response
+// error ที่ไม่ใช่ *Status จะถูกส่งเป็น Internal
+type Handler func(r *http.Request, req []byte) ([]byte, error)
+
+// Server รับ gRPC call และส่งต่อไปยัง Handler ตามชื่อ method ("/package.Service/Method")
+type Server struct {
+	methods map[string]Handler
+	// Authorize ตรวจสอบสิทธิ์ก่อนเรียก handler (nil = ไม่ตรวจ) คืน *Status เพื่อปฏิเสธ
+	Authorize func(r *http.Request) error
+}
+
+// NewServer สร้าง Server ว่าง
+func NewServer() *Server {
+	return &Server{methods: map[string]Handler{}}
+}
+
+// Handle ลงทะเบียน handler ของ method
+func (s *Server) Handle(fullMethod string, h Handler) {
+	s.methods[fullMethod] = h
+}
+
+// ListenAndServe เปิด HTTP/2 แบบไม่เข้ารหัส (h2c prior knowledge) ตามที่ client gRPC ใช้กับ plaintext
+// ควรใช้ในเครือข่ายภายในหรือหลัง proxy ที่ทำ TLS
+func (s *Server) ListenAndServe(addr string) error {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{Addr: addr, Handler: s, Protocols: &protocols}
+	return server.ListenAndServe()
+}
+
+// ServeHTTP รับ call หนึ่งครั้ง: อ่าน message (length-prefixed) เรียก handler แล้วส่ง message และ trailer สถานะ
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "Unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	resp, err := s.call(r)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+
+	frame := make([]byte, 5, 5+len(resp))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+	w.Write(append(frame, resp...))
+	writeStatus(w, nil)
+}
+
+func (s *Server) call(r *http.Request) ([]byte, error) {
+	handler, ok := s.methods[r.URL.Path]
+	if !ok {
+		return nil, Errorf(Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(r); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := readMessage(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return handler(r, req)
+}
+
+// readMessage อ่าน message หนึ่งรายการ: 1 ไบต์ flag การบีบอัด + 4 ไบต์ความยาว (big-endian) + ข้อมูล
+func readMessage(body io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(body, header[:]); err != nil {
+		return nil, Errorf(InvalidArgument, "missing request message")
+	}
+	if header[0] != 0 {
+		return nil, Errorf(Unimplemented, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessageSize {
+		return nil, Errorf(ResourceExhausted, "request message larger than %d bytes", maxMessageSize)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, Errorf(InvalidArgument, "request message is truncated")
+	}
+	return msg, nil
+}
+
+// writeStatus ตั้ง trailer grpc-status/grpc-message (ข้อผิดพลาดที่ไม่รู้จักซ่อนรายละเอียดเป็น Internal)
+func writeStatus(w http.ResponseWriter, err error) {
+	code, message := OK, ""
+	if err != nil {
+		if status, ok := err.(*Status); ok {
+			code, message = status.Code, status.Message
+		} else {
+			log.Printf("❌ gRPC handler error: %v", err)
+			code, message = Internal, "internal error"
+		}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+	if message != "" {
+		w.Header().Set("Grpc-Message", encodeGrpcMessage(message))
+	}
+}
+
+// encodeGrpcMessage percent-encode ข้อความตามสเปก (ไบต์นอกช่วง ASCII ที่พิมพ์ได้และ '%')
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= 0x20 && c <= 0x7e && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,191 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ทดสอบ framing ตาม PROTOCOL-HTTP2.md ผ่าน HTTP/2 แบบไม่เข้ารหัส (h2c prior knowledge) เหมือน client gRPC
+
+func startTestServer(t *testing.T, s *Server) (*httptest.Server, *http.Client) {
+	t.Helper()
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.Protocols = &protocols
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	return ts, client
+}
+
+// frame ห่อ message ด้วย header 5 ไบต์ (flag การบีบอัด + ความยาว big-endian)
+func frame(compressed byte, msg []byte) []byte {
+	out := make([]byte, 5, 5+len(msg))
+	out[0] = compressed
+	binary.BigEndian.PutUint32(out[1:], uint32(len(msg)))
+	return append(out, msg...)
+}
+
+// invoke ส่ง unary call คืน message ของ response, grpc-status และ grpc-message
+func invoke(t *testing.T, ts *httptest.Server, client *http.Client, method string, body []byte) ([]byte, string, string) {
+	t.Helper()
+	req, err := http.NewRequest("POST", ts.URL+method, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HTTP status = %d, want 200 (gRPC errors travel in grpc-status)", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/grpc+proto" {
+		t.Errorf("content-type = %q", ct)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg []byte
+	if len(data) > 0 {
+		if len(data) < 5 || data[0] != 0 || int(binary.BigEndian.Uint32(data[1:5])) != len(data)-5 {
+			t.Fatalf("malformed response frame % x", data)
+		}
+		msg = data[5:]
+	}
+
+	// trailers-only response ส่งสถานะใน header แทน trailer
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	return msg, status, message
+}
+
+func TestServerUnaryCall(t *testing.T) {
+	s := NewServer()
+	s.Handle("/gamestore.v1.GameService/CheckOwnership", func(r *http.Request, req []byte) ([]byte, error) {
+		var in CheckOwnershipRequest
+		if err := in.Unmarshal(req); err != nil {
+			return nil, Errorf(InvalidArgument, "%v", err)
+		}
+		out := CheckOwnershipResponse{Owned: in.UserID == 7 && in.GameID == 42, PurchasedAt: "2024-05-01 10:00:00"}
+		return out.Marshal(), nil
+	})
+	ts, client := startTestServer(t, s)
+
+	req := CheckOwnershipRequest{UserID: 7, GameID: 42}
+	msg, status, _ := invoke(t, ts, client, "/gamestore.v1.GameService/CheckOwnership", frame(0, req.Marshal()))
+	if status != "0" {
+		t.Fatalf("grpc-status = %q, want 0", status)
+	}
+	var out CheckOwnershipResponse
+	if err := out.Unmarshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Owned || out.PurchasedAt != "2024-05-01 10:00:00" {
+		t.Errorf("response = %+v", out)
+	}
+}
+
+func TestServerEmptyResponse(t *testing.T) {
+	s := NewServer()
+	s.Handle("/svc/Empty", func(r *http.Request, req []byte) ([]byte, error) { return nil, nil })
+	ts, client := startTestServer(t, s)
+
+	// message ว่างยังต้องส่ง frame ความยาว 0
+	req, _ := http.NewRequest("POST", ts.URL+"/svc/Empty", bytes.NewReader(frame(0, nil)))
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(data, []byte{0, 0, 0, 0, 0}) {
+		t.Errorf("body = % x, want an empty frame", data)
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("grpc-status trailer = %q, want 0", got)
+	}
+}
+
+func TestServerErrorStatuses(t *testing.T) {
+	s := NewServer()
+	s.Authorize = func(r *http.Request) error {
+		if r.Header.Get("Authorization") == "" {
+			return Errorf(Unauthenticated, "invalid or missing token")
+		}
+		return nil
+	}
+	ts, client := startTestServer(t, s)
+	s.Handle("/svc/Fail", func(r *http.Request, req []byte) ([]byte, error) {
+		return nil, errors.New("database is down")
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		body        []byte
+		wantStatus  string
+		wantMessage string
+	}{
+		{"unknown method", "/svc/Missing", frame(0, nil), "12", "unknown method /svc/Missing"},
+		{"unauthenticated", "/svc/Fail", frame(0, nil), "16", "invalid or missing token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, status, message := invoke(t, ts, client, tt.method, tt.body)
+			if status != tt.wantStatus || message != tt.wantMessage {
+				t.Errorf("status = %q %q, want %q %q", status, message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+
+	s.Authorize = nil
+	authorized := []struct {
+		name        string
+		body        []byte
+		wantStatus  string
+		wantMessage string
+	}{
+		// error ที่ไม่ใช่ *Status ไม่เปิดเผยรายละเอียดให้ client
+		{"internal error", frame(0, nil), "13", "internal error"},
+		{"compressed message", frame(1, []byte{1}), "12", "compressed messages are not supported"},
+		{"truncated message", frame(0, []byte{1, 2, 3})[:6], "3", "request message is truncated"},
+		{"missing message", nil, "3", "missing request message"},
+	}
+	for _, tt := range authorized {
+		t.Run(tt.name, func(t *testing.T) {
+			_, status, message := invoke(t, ts, client, "/svc/Fail", tt.body)
+			if status != tt.wantStatus || message != tt.wantMessage {
+				t.Errorf("status = %q %q, want %q %q", status, message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestEncodeGrpcMessage(t *testing.T) {
+	// ตามสเปก: percent-encode ไบต์นอก 0x20-0x7E และ '%' (UTF-8 ทีละไบต์)
+	got := encodeGrpcMessage("game 100% ไม่พบ\n")
+	want := "game 100%25 %E0%B9%84%E0%B8%A1%E0%B9%88%E0%B8%9E%E0%B8%9A%0A"
+	if got != want {
+		t.Errorf("encodeGrpcMessage = %q, want %q", got, want)
+	}
+}
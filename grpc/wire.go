@@ -0,0 +1,225 @@
+// grpc/wire.go
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// protobuf wire format (https://protobuf.dev/programming-guides/encoding/)
+// รองรับเฉพาะชนิดที่ message ของ service ใช้: varint, fixed64 (double) และ length-delimited (string/bytes)
+
+// wire types
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+var (
+	errTruncated   = errors.New("proto: message is truncated")
+	errInvalidUTF8 = errors.New("proto: string field contains invalid UTF-8")
+)
+
+// Encoder เขียน message ตาม protobuf wire format (proto3: field ที่เป็นค่า default ไม่ถูกเขียน)
+type Encoder struct {
+	buf []byte
+}
+
+func (e *Encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+// Int64 เขียน field ชนิด int64/int32 (ค่าติดลบใช้ 10 ไบต์ตามสเปก)
+func (e *Encoder) Int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+// Bool เขียน field ชนิด bool
+func (e *Encoder) Bool(field int, v bool) {
+	if !v {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = append(e.buf, 1)
+}
+
+// Double เขียน field ชนิด double (-0 ไม่ใช่ค่า default จึงถูกเขียนเหมือน protobuf อ้างอิง)
+func (e *Encoder) Double(field int, v float64) {
+	if math.Float64bits(v) == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// String เขียน field ชนิด string
+func (e *Encoder) String(field int, v string) {
+	if v == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// Message เขียน field ที่เป็น message ซ้อน
+func (e *Encoder) Message(field int, m *Encoder) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(m.buf)))
+	e.buf = append(e.buf, m.buf...)
+}
+
+// Bytes ข้อมูลที่เข้ารหัสแล้ว
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Value ค่าของ field หนึ่งที่อ่านได้ (varint/fixed อยู่ใน Uint, length-delimited อยู่ใน Raw)
+type Value struct {
+	Wire int
+	Uint uint64
+	Raw  []byte
+}
+
+// Int64 ค่าแบบ int64/int32
+func (v Value) Int64() int64 { return int64(v.Uint) }
+
+// Bool ค่าแบบ bool
+func (v Value) Bool() bool { return v.Uint != 0 }
+
+// Double ค่าแบบ double
+func (v Value) Double() float64 { return math.Float64frombits(v.Uint) }
+
+// String ค่าแบบ string (ไม่ตรวจ UTF-8 ใช้ Text เมื่ออ่าน field ชนิด string ของ proto3)
+func (v Value) String() string { return string(v.Raw) }
+
+// Text ค่าแบบ string ของ proto3 ซึ่งต้องเป็น UTF-8 ที่ถูกต้อง (เหมือน library protobuf อ้างอิง)
+func (v Value) Text() (string, error) {
+	if !utf8.Valid(v.Raw) {
+		return "", errInvalidUTF8
+	}
+	return string(v.Raw), nil
+}
+
+// Decode อ่าน field ทั้งหมดของ message แล้วเรียก fn ทีละ field
+// field ที่ไม่รู้จักให้ fn ข้ามไปได้เลย (ตามกฎ forward compatibility ของ protobuf)
+// group (wire type 3/4 ของ proto2) ไม่มีใน service นี้ จึงถูกข้ามทั้งก้อนเหมือน unknown field ของ library อ้างอิง
+func Decode(data []byte, fn func(field int, v Value) error) error {
+	for len(data) > 0 {
+		field, wire, rest, err := decodeTag(data)
+		if err != nil {
+			return err
+		}
+		data = rest
+		if wire == wireStartGroup {
+			if data, err = skipGroup(data, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, err := valueLen(data, wire)
+		if err != nil {
+			return err
+		}
+		v := Value{Wire: wire}
+		switch wire {
+		case wireVarint:
+			v.Uint, _ = binary.Uvarint(data)
+		case wireFixed64:
+			v.Uint = binary.LittleEndian.Uint64(data)
+		case wireFixed32:
+			v.Uint = uint64(binary.LittleEndian.Uint32(data))
+		case wireBytes:
+			_, prefix := binary.Uvarint(data)
+			v.Raw = data[prefix:n]
+		}
+		data = data[n:]
+
+		if err := fn(field, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeTag อ่าน field number และ wire type
+func decodeTag(data []byte) (int, int, []byte, error) {
+	key, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0, nil, errTruncated
+	}
+	field, wire := int(key>>3), int(key&7)
+	if field <= 0 || key>>3 > math.MaxInt32 {
+		return 0, 0, nil, fmt.Errorf("proto: invalid field number %d", key>>3)
+	}
+	return field, wire, data[n:], nil
+}
+
+// skipGroup ข้ามเนื้อหาของ group จนถึง end group ของ field เดียวกัน คืนข้อมูลที่เหลือ
+func skipGroup(data []byte, field int) ([]byte, error) {
+	for {
+		if len(data) == 0 {
+			return nil, errTruncated
+		}
+		inner, wire, rest, err := decodeTag(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		switch wire {
+		case wireEndGroup:
+			if inner != field {
+				return nil, fmt.Errorf("proto: mismatched end group for field %d", field)
+			}
+			return data, nil
+		case wireStartGroup:
+			if data, err = skipGroup(data, inner); err != nil {
+				return nil, err
+			}
+		default:
+			n, err := valueLen(data, wire)
+			if err != nil {
+				return nil, err
+			}
+			data = data[n:]
+		}
+	}
+}
+
+// valueLen ความยาวของค่าชนิด varint/fixed/length-delimited ที่ต้นข้อมูล
+func valueLen(data []byte, wire int) (int, error) {
+	switch wire {
+	case wireVarint:
+		if _, n := binary.Uvarint(data); n > 0 {
+			return n, nil
+		}
+	case wireFixed64:
+		if len(data) >= 8 {
+			return 8, nil
+		}
+	case wireFixed32:
+		if len(data) >= 4 {
+			return 4, nil
+		}
+	case wireBytes:
+		length, n := binary.Uvarint(data)
+		if n > 0 && uint64(len(data)-n) >= length {
+			return n + int(length), nil
+		}
+	default:
+		return 0, fmt.Errorf("proto: unsupported wire type %d", wire)
+	}
+	return 0, errTruncated
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// defaultCartExpiryDays สินค้าในตะกร้าที่ไม่มีการเปลี่ยนแปลงนานกว่านี้จะถูกลบออก (ปรับได้ด้วย CART_EXPIRY_DAYS, 0 = ไม่ลบ)
+const defaultCartExpiryDays = 30
+
+// defaultAbandonedCartHours ตะกร้าที่ไม่มีการเปลี่ยนแปลงนานกว่านี้ถือว่าถูกทิ้ง (ใช้ในรายงาน ?hours= เปลี่ยนได้)
+const defaultAbandonedCartHours = 24
+
+// cartRemindersBatch จำนวนตะกร้าสูงสุดที่แจ้งเตือนต่อการทำงานหนึ่งครั้ง
+const cartRemindersBatch = 500
+
+func cartExpiryDays() int {
+	if n, err := strconv.Atoi(os.Getenv("CART_EXPIRY_DAYS")); err == nil && n >= 0 {
+		return n
+	}
+	return defaultCartExpiryDays
+}
+
+// cartReminderHours จำนวนชั่วโมงหลังการเปลี่ยนแปลงล่าสุดที่จะแจ้งเตือนให้กลับมาชำระเงิน
+// (CART_REMINDER_HOURS, ไม่ได้ตั้งหรือเป็น 0 = ไม่แจ้งเตือน)
+func cartReminderHours() int {
+	if n, err := strconv.Atoi(os.Getenv("CART_REMINDER_HOURS")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// expireStaleCartItems ลบสินค้าในตะกร้าที่ไม่มีการเปลี่ยนแปลงนานเกิน CART_EXPIRY_DAYS
+func expireStaleCartItems() error {
+	days := cartExpiryDays()
+	if days == 0 {
+		return nil
+	}
+	result, err := db.Exec(`
+		DELETE FROM cart_items
+		WHERE updated_at < DATE_SUB(NOW(), INTERVAL ? DAY)
+	`, days)
+	if err != nil {
+		return fmt.Errorf("error expiring cart items: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		fmt.Printf("🧹 Removed %d stale cart items\n", affected)
+	}
+	return nil
+}
+
+// sendCartReminders แจ้งเตือนผู้ใช้ที่มีสินค้าค้างในตะกร้า (แจ้งครั้งเดียวจนกว่าตะกร้าจะมีการเปลี่ยนแปลงอีก)
+func sendCartReminders() error {
+	hours := cartReminderHours()
+	if hours == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(`
+		SELECT ca.id, ca.user_id, COUNT(*), COALESCE(SUM(g.price * ci.quantity), 0)
+		FROM carts ca
+		JOIN cart_items ci ON ci.cart_id = ca.id
+		JOIN games g ON g.id = ci.game_id
+		GROUP BY ca.id, ca.user_id, ca.reminded_at
+		HAVING MAX(ci.updated_at) < DATE_SUB(NOW(), INTERVAL ? HOUR)
+		   AND (ca.reminded_at IS NULL OR ca.reminded_at < MAX(ci.updated_at))
+		LIMIT ?
+	`, hours, cartRemindersBatch)
+	if err != nil {
+		return fmt.Errorf("error fetching abandoned carts: %v", err)
+	}
+	type pendingCart struct {
+		cartID, userID, itemCount int
+		value                     float64
+	}
+	var carts []pendingCart
+	for rows.Next() {
+		var c pendingCart
+		if err := rows.Scan(&c.cartID, &c.userID, &c.itemCount, &c.value); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning abandoned cart: %v", err)
+		}
+		carts = append(carts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading abandoned carts: %v", err)
+	}
+
+	for _, c := range carts {
+		notify(c.userID, NotificationCartReminder, "Items waiting in your cart",
+			fmt.Sprintf("You have %d games waiting in your cart", c.itemCount),
+			map[string]interface{}{"item_count": c.itemCount, "total": c.value})
+		if _, err := db.Exec("UPDATE carts SET reminded_at = NOW() WHERE id = ?", c.cartID); err != nil {
+			fmt.Printf("⚠️ Error marking cart reminder for cart %d: %v\n", c.cartID, err)
+		}
+	}
+
+	if len(carts) > 0 {
+		fmt.Printf("🛒 Cart reminders sent for %d carts\n", len(carts))
+	}
+	return nil
+}
+
+// AdminAbandonedCartsHandler handles the abandoned cart report
+// ฟังก์ชันสำหรับรายงานตะกร้าที่ถูกทิ้ง (มีสินค้าแต่ไม่มีการเปลี่ยนแปลงนานเกิน ?hours= ชั่วโมง)
+// GET /admin/stats/abandoned-carts?hours=24&limit=20&offset=0&format=csv
+func AdminAbandonedCartsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	hours := defaultAbandonedCartHours
+	limit := 20
+	offset := 0
+	if h, err := strconv.Atoi(query.Get("hours")); err == nil && h > 0 && h <= 24*365 {
+		hours = h
+	}
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	fmt.Printf("📊 Fetching abandoned carts: hours=%d limit=%d offset=%d\n", hours, limit, offset)
+
+	// ตะกร้าที่ถูกทิ้ง เรียงตามมูลค่า (ใช้ทั้งรายการและ CSV)
+	cartsQuery := `
+		SELECT u.id as user_id, u.username, u.email, COUNT(*) as item_count,
+		       COALESCE(SUM(g.price * ci.quantity), 0) as cart_value,
+		       MAX(ci.updated_at) as last_activity, ca.reminded_at
+		FROM carts ca
+		JOIN users u ON u.id = ca.user_id
+		JOIN cart_items ci ON ci.cart_id = ca.id
+		JOIN games g ON g.id = ci.game_id
+		GROUP BY u.id, u.username, u.email, ca.reminded_at
+		HAVING MAX(ci.updated_at) < DATE_SUB(NOW(), INTERVAL ? HOUR)
+		ORDER BY cart_value DESC, last_activity ASC
+	`
+
+	// ส่งออกเป็น CSV: รายการตะกร้าที่ถูกทิ้งทั้งหมด (อ่านจาก cursor โดยตรง)
+	if wantsCSV(r) {
+		csvArgs := []interface{}{hours}
+		if query.Get("limit") != "" {
+			cartsQuery += " LIMIT ? OFFSET ?"
+			csvArgs = append(csvArgs, limit, offset)
+		}
+		loc, err := exportLocation(r)
+		if err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+		streamQueryCSV(w, loc, "abandoned-carts.csv", cartsQuery, csvArgs...)
+		return
+	}
+
+	// 1. สรุปจำนวนตะกร้า สินค้า และมูลค่าที่ค้างอยู่
+	var cartCount, itemCount, remindedCount int
+	var totalValue float64
+	err := readQueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(item_count), 0), COALESCE(SUM(cart_value), 0),
+		       COALESCE(SUM(CASE WHEN reminded_at IS NOT NULL THEN 1 ELSE 0 END), 0)
+		FROM (
+			SELECT ca.id, COUNT(*) as item_count, SUM(g.price * ci.quantity) as cart_value, ca.reminded_at
+			FROM carts ca
+			JOIN cart_items ci ON ci.cart_id = ca.id
+			JOIN games g ON g.id = ci.game_id
+			GROUP BY ca.id, ca.reminded_at
+			HAVING MAX(ci.updated_at) < DATE_SUB(NOW(), INTERVAL ? HOUR)
+		) t
+	`, hours).Scan(&cartCount, &itemCount, &totalValue, &remindedCount)
+	if err != nil {
+		fmt.Printf("❌ Error fetching abandoned cart summary: %v\n", err)
+		utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+		return
+	}
+
+	// 2. รายการตะกร้าที่ถูกทิ้ง
+	rows, err := readQuery(cartsQuery+" LIMIT ? OFFSET ?", hours, limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching abandoned carts: %v\n", err)
+		utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+		return
+	}
+	carts := []map[string]interface{}{}
+	for rows.Next() {
+		var userID, items int
+		var username, email string
+		var value float64
+		var lastActivity utils.Timestamp
+		var remindedAt utils.NullTimestamp
+		if err := rows.Scan(&userID, &username, &email, &items, &value, &lastActivity, &remindedAt); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning abandoned cart: %v\n", err)
+			utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+			return
+		}
+		carts = append(carts, map[string]interface{}{
+			"user_id":       userID,
+			"username":      username,
+			"email":         email,
+			"item_count":    items,
+			"cart_value":    value,
+			"last_activity": lastActivity,
+			"reminded_at":   remindedAt,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading abandoned carts: %v\n", err)
+		utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+		return
+	}
+
+	// 3. เกมที่ถูกทิ้งไว้ในตะกร้ามากที่สุด
+	rows, err = readQuery(`
+		SELECT g.id, g.name, COUNT(DISTINCT ci.cart_id) as cart_count, SUM(g.price * ci.quantity) as value
+		FROM cart_items ci
+		JOIN games g ON g.id = ci.game_id
+		WHERE ci.cart_id IN (
+			SELECT cart_id FROM cart_items
+			GROUP BY cart_id
+			HAVING MAX(updated_at) < DATE_SUB(NOW(), INTERVAL ? HOUR)
+		)
+		GROUP BY g.id, g.name
+		ORDER BY cart_count DESC, value DESC
+		LIMIT 10
+	`, hours)
+	if err != nil {
+		fmt.Printf("❌ Error fetching abandoned games: %v\n", err)
+		utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+		return
+	}
+	topGames := []map[string]interface{}{}
+	for rows.Next() {
+		var gameID, count int
+		var name string
+		var value float64
+		if err := rows.Scan(&gameID, &name, &count, &value); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning abandoned game: %v\n", err)
+			utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+			return
+		}
+		topGames = append(topGames, map[string]interface{}{
+			"game_id":    gameID,
+			"name":       name,
+			"cart_count": count,
+			"value":      value,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading abandoned games: %v\n", err)
+		utils.JSONError(w, "Error fetching abandoned carts", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"hours":          hours,
+		"expiry_days":    cartExpiryDays(),
+		"reminder_hours": cartReminderHours(),
+		"summary": map[string]interface{}{
+			"cart_count":     cartCount,
+			"item_count":     itemCount,
+			"total_value":    totalValue,
+			"reminded_count": remindedCount,
+		},
+		"carts":     carts,
+		"top_games": topGames,
+		"limit":     limit,
+		"offset":    offset,
+	}, http.StatusOK)
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-api-game/tracing"
+	"go-api-game/utils"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ค่าเริ่มต้นของการหมุนไฟล์ access log (ปรับได้ด้วย ACCESS_LOG_MAX_SIZE_MB และ ACCESS_LOG_MAX_BACKUPS)
+const (
+	defaultAccessLogMaxSizeMB  = 100
+	defaultAccessLogMaxBackups = 5
+)
+
+// accessLogFormats รูปแบบของ access log ที่รองรับ
+var accessLogFormats = map[string]bool{"common": true, "json": true}
+
+// accessLog ปลายทางและรูปแบบของ access log (nil = ปิด access log)
+var accessLog struct {
+	sync.Mutex
+	out    io.Writer
+	format string
+}
+
+// rotatingFile ไฟล์ log ที่เปลี่ยนชื่อเป็น .1, .2, ... เมื่อขนาดเกินที่กำหนด (เก็บไว้ maxBackups ไฟล์)
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate ปิดไฟล์ปัจจุบัน เลื่อนไฟล์สำรอง (path.1 → path.2, ...) แล้วเปิดไฟล์ใหม่
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+	for n := f.maxBackups - 1; n >= 1; n-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, n), fmt.Sprintf("%s.%d", f.path, n+1))
+	}
+	if f.maxBackups > 0 {
+		os.Rename(f.path, f.path+".1")
+	} else {
+		os.Remove(f.path)
+	}
+	return f.open()
+}
+
+// Write เรียกภายใต้ lock ของ accessLog เท่านั้น
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// InitAccessLog เปิด access log ตาม environment (เรียกก่อน config.SilenceVerboseLogs เพื่อให้ access log ไม่ถูกกรอง)
+// ACCESS_LOG             - off เพื่อปิด (ค่าเริ่มต้นเปิด)
+// ACCESS_LOG_FORMAT      - common (Common Log Format พร้อมเวลาที่ใช้) หรือ json (ค่าเริ่มต้น common)
+// ACCESS_LOG_FILE        - path ของไฟล์ log (ค่าเริ่มต้น stdout)
+// ACCESS_LOG_MAX_SIZE_MB - ขนาดไฟล์ก่อนหมุนไฟล์ (ค่าเริ่มต้น 100)
+// ACCESS_LOG_MAX_BACKUPS - จำนวนไฟล์เก่าที่เก็บไว้ (ค่าเริ่มต้น 5)
+func InitAccessLog() error {
+	if strings.EqualFold(os.Getenv("ACCESS_LOG"), "off") {
+		return nil
+	}
+
+	format := strings.ToLower(os.Getenv("ACCESS_LOG_FORMAT"))
+	if format == "" {
+		format = "common"
+	}
+	if !accessLogFormats[format] {
+		return fmt.Errorf("invalid ACCESS_LOG_FORMAT %q (use common or json)", format)
+	}
+
+	var out io.Writer = os.Stdout
+	if path := os.Getenv("ACCESS_LOG_FILE"); path != "" {
+		maxSizeMB := defaultAccessLogMaxSizeMB
+		if n, err := strconv.Atoi(os.Getenv("ACCESS_LOG_MAX_SIZE_MB")); err == nil && n > 0 {
+			maxSizeMB = n
+		}
+		maxBackups := defaultAccessLogMaxBackups
+		if n, err := strconv.Atoi(os.Getenv("ACCESS_LOG_MAX_BACKUPS")); err == nil && n >= 0 {
+			maxBackups = n
+		}
+		file, err := openRotatingFile(path, int64(maxSizeMB)<<20, maxBackups)
+		if err != nil {
+			return fmt.Errorf("cannot open access log: %v", err)
+		}
+		out = file
+	}
+
+	accessLog.Lock()
+	accessLog.out, accessLog.format = out, format
+	accessLog.Unlock()
+	return nil
+}
+
+// accessLogEntry ข้อมูลของ request หนึ่งรายการ (handler ด้านในแนบผู้ใช้ผ่าน noteAccessLogUser)
+type accessLogEntry struct {
+	userID int
+}
+
+// noteAccessLogUser บันทึกผู้ใช้ที่ยืนยันตัวตนแล้วลงใน access log ของ request (เรียกจาก withIdentity)
+func noteAccessLogUser(r *http.Request, userID int) {
+	if entry, ok := r.Context().Value(accessLogContextKey).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+}
+
+// accessLogWriter จำ status code และจำนวน byte ของ response
+type accessLogWriter struct {
+	statusWriter
+	bytes int
+}
+
+func (aw *accessLogWriter) Write(b []byte) (int, error) {
+	n, err := aw.statusWriter.Write(b)
+	aw.bytes += n
+	return n, err
+}
+
+// AccessLog middleware writes one access log line per request
+// Middleware สำหรับบันทึก access log ทุก request (method, path, status, ขนาด response, เวลาที่ใช้ และผู้ใช้)
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessLog.Lock()
+		enabled := accessLog.out != nil
+		accessLog.Unlock()
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		entry := &accessLogEntry{}
+		aw := &accessLogWriter{statusWriter: statusWriter{ResponseWriter: w}}
+		next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), accessLogContextKey, entry)))
+
+		status := aw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeAccessLog(r, start, status, aw.bytes, entry.userID)
+	})
+}
+
+// loggedQuery query string ที่บันทึกลง log (ค่าของ parameter ที่เป็นข้อมูลลับ เช่น token ถูกแทนด้วย [REDACTED])
+func loggedQuery(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return ""
+	}
+	query := r.URL.Query()
+	redacted := false
+	for name := range query {
+		if isSecretField(name) {
+			query[name] = []string{adminRequestRedacted}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return r.URL.RawQuery
+	}
+	return query.Encode()
+}
+
+// writeAccessLog เขียน access log หนึ่งบรรทัด
+func writeAccessLog(r *http.Request, start time.Time, status, bytes, userID int) {
+	duration := time.Since(start)
+	query := loggedQuery(r)
+
+	accessLog.Lock()
+	defer accessLog.Unlock()
+
+	if accessLog.format == "json" {
+		line := map[string]interface{}{
+			"time":        utils.FormatTimestamp(start),
+			"request_id":  r.Header.Get(utils.RequestIDHeader),
+			"remote_addr": clientIP(r),
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"query":       query,
+			"proto":       r.Proto,
+			"status":      status,
+			"bytes":       bytes,
+			"duration_ms": float64(duration.Microseconds()) / 1000,
+			"user_agent":  r.UserAgent(),
+		}
+		if userID > 0 {
+			line["user_id"] = userID
+		}
+		if traceID := tracing.FromContext(r.Context()).TraceID(); traceID != "" {
+			line["trace_id"] = traceID
+		}
+		data, _ := json.Marshal(line)
+		accessLog.out.Write(append(data, '\n'))
+		return
+	}
+
+	// Common Log Format โดยใช้ user ID เป็น authuser ตามด้วยเวลาที่ใช้ (ms) และ request ID
+	user := "-"
+	if userID > 0 {
+		user = strconv.Itoa(userID)
+	}
+	uri := r.URL.EscapedPath()
+	if query != "" {
+		uri += "?" + query
+	}
+	size := "-"
+	if bytes > 0 {
+		size = strconv.Itoa(bytes)
+	}
+	fmt.Fprintf(accessLog.out, "%s - %s [%s] %q %d %s %.3fms %s\n",
+		clientIP(r), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+uri+" "+r.Proto, status, size,
+		float64(duration.Microseconds())/1000, r.Header.Get(utils.RequestIDHeader))
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ประเภทของกิจกรรมบัญชีที่บันทึกไว้
+const (
+	ActivityLogin           = "login"            // เข้าสู่ระบบสำเร็จ
+	ActivityLoginFailed     = "login_failed"     // ใส่รหัสผ่านผิด
+	ActivityPasswordChanged = "password_changed" // เปลี่ยนรหัสผ่าน
+	ActivityEmailChanged    = "email_changed"    // เปลี่ยนอีเมล
+	ActivitySessionRevoked  = "session_revoked"  // ออกจากระบบจากอุปกรณ์อื่น
+	ActivityAPIKeyCreated   = "api_key_created"  // สร้างคีย์ API
+	ActivityAPIKeyRevoked   = "api_key_revoked"  // เพิกถอนคีย์ API
+)
+
+// recordAccountActivity บันทึกกิจกรรมของบัญชีพร้อม IP และ user agent ของผู้เรียก
+// การบันทึกที่ล้มเหลวจะไม่กระทบผลลัพธ์ของ request (แค่แสดง log)
+func recordAccountActivity(r *http.Request, userID int, event, details string) {
+	var detailsValue interface{}
+	if details != "" {
+		detailsValue = details
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO account_activity (user_id, event, ip_address, user_agent, details)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, event, clientIP(r), userAgent, detailsValue)
+	if err != nil {
+		fmt.Printf("⚠️ Error recording account activity: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🛡️ Account activity %s for user %d\n", event, userID)
+}
+
+// ProfileActivityHandler returns the security log of the current user
+// ฟังก์ชันสำหรับดึงประวัติกิจกรรมของบัญชีตัวเอง (GET /profile/activity?event=&limit=&offset=)
+func ProfileActivityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	getAccountActivity(w, r, userID)
+}
+
+// AdminUserActivityHandler returns the security log of a specific user
+// ฟังก์ชันสำหรับผู้ดูแลระบบดูประวัติกิจกรรมของบัญชีผู้ใช้ (GET /admin/activity/user/{id})
+func AdminUserActivityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ตัวอย่าง URL: /admin/activity/user/123 → userID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 4 {
+		utils.JSONError(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[3])
+	if err != nil {
+		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", userID).Scan(&exists); err != nil || exists == 0 {
+		utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		return
+	}
+
+	getAccountActivity(w, r, userID)
+}
+
+// getAccountActivity ดึงกิจกรรมของบัญชี (ใหม่สุดก่อน) ใช้ร่วมกันทั้งฝั่งผู้ใช้และผู้ดูแลระบบ
+func getAccountActivity(w http.ResponseWriter, r *http.Request, userID int) {
+	query := r.URL.Query()
+	page, err := parsePage(query, 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	whereSQL := "WHERE user_id = ?"
+	args := []interface{}{userID}
+	if event := query.Get("event"); event != "" {
+		whereSQL += " AND event = ?"
+		args = append(args, event)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM account_activity "+whereSQL, args...).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting account activity: %v\n", err)
+		utils.JSONError(w, "Error fetching account activity", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, event, ip_address, user_agent, details, created_at
+		FROM account_activity
+		`+whereSQL+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching account activity: %v\n", err)
+		utils.JSONError(w, "Error fetching account activity", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	activity := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var event string
+		var createdAt utils.Timestamp
+		var ipAddress, userAgent, details sql.NullString
+
+		if err := rows.Scan(&id, &event, &ipAddress, &userAgent, &details, &createdAt); err != nil {
+			fmt.Printf("❌ Error scanning account activity row: %v\n", err)
+			utils.JSONError(w, "Error fetching account activity", http.StatusInternalServerError)
+			return
+		}
+
+		activity = append(activity, map[string]interface{}{
+			"id":         id,
+			"event":      event,
+			"ip_address": ipAddress.String,
+			"user_agent": userAgent.String,
+			"details":    details.String,
+			"created_at": createdAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading account activity rows: %v\n", err)
+		utils.JSONError(w, "Error fetching account activity", http.StatusInternalServerError)
+		return
+	}
+
+	response := paginatedResponse(activity, total, page)
+	response["user_id"] = userID
+	utils.JSONResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// adminAllowlist เครือข่ายที่เรียก /admin/* ได้ (ว่าง = ไม่จำกัด IP)
+var adminAllowlist []*net.IPNet
+
+// SetAdminAllowlist ตั้งค่า IP ที่เรียก route ของ admin ได้จาก ADMIN_ALLOWED_CIDRS
+// รูปแบบ: CIDR หรือ IP คั่นด้วย comma เช่น "10.0.0.0/8, 203.0.113.7" (ว่าง = ไม่จำกัด)
+// ใช้ป้องกันอีกชั้นหนึ่งนอกจาก role admin เช่นจำกัดให้เข้าได้จาก VPN ของบริษัทเท่านั้น
+func SetAdminAllowlist(raw string) error {
+	networks := []*net.IPNet{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// IP เดี่ยวถือเป็น /32 (IPv4) หรือ /128 (IPv6)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("invalid ADMIN_ALLOWED_CIDRS entry %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid ADMIN_ALLOWED_CIDRS entry %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	adminAllowlist = networks
+	return nil
+}
+
+// adminIPAllowed ตรวจสอบว่า request มาจากเครือข่ายที่อนุญาตหรือไม่
+func adminIPAllowed(r *http.Request) bool {
+	if len(adminAllowlist) == 0 {
+		return true
+	}
+	ip := sourceIP(r)
+	if ip == nil {
+		return false
+	}
+	for _, network := range adminAllowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,108 +1,65 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"go-api-game/config"
+	"go-api-game/storage"
 	"go-api-game/utils"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// saveImage handles image upload to Cloudinary with fallback to local storage
-func saveImage(file io.Reader, header *multipart.FileHeader) (string, error) {
-	// Read file bytes
-	fileBytes, err := io.ReadAll(file)
+// saveImage handles image upload to the configured storage backend with fallback to local storage
+func saveImage(ctx context.Context, file io.Reader, header *multipart.FileHeader) (string, error) {
+	// Read and validate file bytes (type is detected from content, not the filename)
+	fileBytes, ext, err := readImageUpload(file)
 	if err != nil {
-		return "", fmt.Errorf("error reading image file: %v", err)
+		return "", err
 	}
 
-	// Check file type
-	allowedTypes := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-		".webp": true, ".avif": true,
-	}
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if !allowedTypes[ext] {
-		return "", fmt.Errorf("invalid file type. Allowed: jpg, jpeg, png, gif, webp, avif")
-	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("game_%d%s", time.Now().UnixNano(), ext)
-
-	// Try Cloudinary first
-	if config.Cld != nil {
-		imageURL, err := config.UploadImageFromBytes(fileBytes, filename)
-		if err != nil {
-			fmt.Printf("❌ Cloudinary upload failed, using local storage: %v\n", err)
-			// Fallback to local storage
-			return saveToLocalStorage(fileBytes, filename)
-		}
-		fmt.Printf("✅ Image uploaded to Cloudinary: %s\n", imageURL)
-		return imageURL, nil
-	}
-
-	// Use local storage if Cloudinary not configured
-	return saveToLocalStorage(fileBytes, filename)
-}
-
-// saveToLocalStorage saves image to local file system
-func saveToLocalStorage(fileBytes []byte, filename string) (string, error) {
-	// Create uploads directory if not exists
-	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
-		os.Mkdir("uploads", 0755)
-	}
+	// ตั้งชื่อไฟล์ตามเนื้อหา (ภาพใหม่ได้ URL ใหม่ จึงไม่ติด cache ของภาพเก่า)
+	filename := contentHashName("game_", fileBytes, ext)
 
-	filePath := filepath.Join("uploads", filename)
-
-	err := os.WriteFile(filePath, fileBytes, 0644)
+	url, err := storage.SaveContext(ctx, filename, fileBytes)
 	if err != nil {
-		return "", fmt.Errorf("error saving image locally: %v", err)
+		return "", err
 	}
 
-	localURL := "/uploads/" + filename
-	fmt.Printf("✅ Image saved locally: %s\n", localURL)
-	return localURL, nil
+	// สร้างภาพย่อสำหรับหน้ารายการและอุปกรณ์มือถือ
+	createImageVariants(ctx, url, fileBytes, filename)
+	return url, nil
 }
 
-// deleteImage handles image deletion from both Cloudinary and local storage
+// deleteImage handles image deletion from whichever storage backend holds the image
 func deleteImage(imageURL string) error {
-	if imageURL == "" {
-		return nil
-	}
-
-	// Check if it's a Cloudinary URL
-	if strings.Contains(imageURL, "cloudinary.com") {
-		// Delete from Cloudinary
-		err := config.DeleteImage(imageURL)
-		if err != nil {
-			return fmt.Errorf("error deleting Cloudinary image: %v", err)
-		}
-		fmt.Printf("🗑️ Deleted Cloudinary image: %s\n", imageURL)
-	} else {
-		// Delete from local storage
-		filePath := strings.TrimPrefix(imageURL, "/")
-		if _, err := os.Stat(filePath); err == nil {
-			err := os.Remove(filePath)
-			if err != nil {
-				return fmt.Errorf("error deleting local image: %v", err)
-			}
-			fmt.Printf("🗑️ Deleted local image: %s\n", filePath)
-		}
-	}
-	return nil
+	deleteImageVariants(imageURL)
+	return storage.Remove(imageURL)
 }
 
+// สถานะของเกม: draft (ร่าง, เห็นเฉพาะ admin), published (แสดงในร้านค้า), delisted (ถอดออกจากร้าน),
+// hidden (ซ่อนอัตโนมัติจากการถูกรายงาน รอ admin ตรวจสอบ)
+const (
+	GameStatusDraft     = "draft"
+	GameStatusPublished = "published"
+	GameStatusDelisted  = "delisted"
+	GameStatusHidden    = "hidden"
+)
+
 // AdminAddGameHandler handles adding new games
 // ฟังก์ชันสำหรับผู้ดูแลระบบเพิ่มเกมใหม่เข้าสู่ระบบ
 func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
+	// GET /admin/games → รายการเกมทั้งหมดรวมถึงเกมที่ยังเป็นร่าง
+	if r.Method == "GET" {
+		adminListGames(w, r)
+		return
+	}
+
 	// ตรวจสอบว่าเป็นเมธอด POST หรือไม่
 	if r.Method != "POST" {
 		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -114,14 +71,20 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
-		Name        string  `json:"name"`         // ชื่อเกม (จำเป็น)
-		Price       float64 `json:"price"`        // ราคาเกม (จำเป็น)
-		CategoryID  int     `json:"category_id"`  // ID หมวดหมู่ (จำเป็น)
-		Description string  `json:"description"`  // คำอธิบายเกม
-		ReleaseDate string  `json:"release_date"` // วันที่วางจำหน่าย (ถ้าไม่ส่งจะใช้วันที่ปัจจุบัน)
+		Name         string              `json:"name" validate:"required"`                // ชื่อเกม (จำเป็น)
+		Price        float64             `json:"price" validate:"gt=0"`                   // ราคาเกม (จำเป็น)
+		CategoryID   int                 `json:"category_id" validate:"gt=0"`             // ID หมวดหมู่ (จำเป็น)
+		Description  string              `json:"description"`                             // คำอธิบายเกม
+		ReleaseDate  string              `json:"release_date" validate:"date"`            // วันที่วางจำหน่าย (ถ้าไม่ส่งจะใช้วันที่ปัจจุบัน)
+		Status       string              `json:"status" validate:"oneof=draft published"` // draft หรือ published (ค่าเริ่มต้น published)
+		MediaID      int                 `json:"media_id"`                                // ใช้ภาพจากคลังภาพแทนการอัพโหลดใหม่
+		AgeRating    string              `json:"age_rating"`                              // เรตติ้งอายุ เช่น "ESRB M" หรือ "PEGI 18" (ถ้ามี)
+		Descriptors  []string            `json:"content_descriptors"`                     // คำอธิบายเนื้อหา เช่น ["Violence", "Blood"]
+		Requirements *systemRequirements `json:"system_requirements"`                     // ความต้องการของระบบ {"minimum": {...}, "recommended": {...}}
 	}
 
 	var imageURL string // ตัวแปรเก็บ URL ของภาพเกม
+	uploaded := false   // ภาพถูกอัพโหลดใหม่ใน request นี้หรือไม่ (ใช้ตัดสินใจลบเมื่อเกิดข้อผิดพลาด)
 
 	// กรณีส่งข้อมูลแบบ Form-data (มีการอัพโหลดไฟล์ภาพ)
 	if strings.Contains(contentType, "multipart/form-data") {
@@ -138,6 +101,15 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 		categoryIDStr := r.FormValue("category_id")
 		req.Description = r.FormValue("description")
 		req.ReleaseDate = r.FormValue("release_date") // Optional
+		req.Status = r.FormValue("status")            // Optional
+		req.MediaID, _ = strconv.Atoi(r.FormValue("media_id"))
+		req.AgeRating = r.FormValue("age_rating")
+		req.Descriptors = r.Form["content_descriptors"]
+		req.Requirements, err = parseSystemRequirementsForm(r.FormValue("system_requirements"))
+		if err != nil {
+			utils.JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// แปลงสตริงเป็นตัวเลข
 		if priceStr != "" {
@@ -162,9 +134,9 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 			defer file.Close()
 
 			// ใช้ฟังก์ชันใหม่สำหรับอัพโหลดภาพ
-			imageURL, err = saveImage(file, header)
+			imageURL, uploaded, err = uploadMediaAsset(r, file, header)
 			if err != nil {
-				utils.JSONError(w, "Error uploading image: "+err.Error(), http.StatusInternalServerError)
+				utils.JSONError(w, "Error uploading image: "+err.Error(), uploadErrorStatus(err))
 				return
 			}
 		}
@@ -176,20 +148,46 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// ตรวจสอบความถูกต้องของข้อมูลที่จำเป็น
-	if req.Name == "" {
-		utils.JSONError(w, "Game name is required", http.StatusBadRequest)
-		return
+	// เกมใหม่เป็นได้แค่ร่างหรือเผยแพร่ทันที
+	if req.Status == "" {
+		req.Status = GameStatusPublished
 	}
 
-	if req.Price <= 0 {
-		utils.JSONError(w, "Price must be greater than 0", http.StatusBadRequest)
+	// ตรวจสอบความถูกต้องของข้อมูลตาม tag validate
+	errs := utils.Validate(req)
+	var ageRating interface{}
+	minAge := 0
+	if req.AgeRating != "" {
+		rating, age, ok := normalizeAgeRating(req.AgeRating)
+		if !ok {
+			errs = append(errs, utils.FieldError{Field: "age_rating", Rule: "age_rating", Message: "age_rating must be an ESRB or PEGI rating (e.g. ESRB M, PEGI 18)"})
+		}
+		ageRating, minAge = rating, age
+	}
+	descriptors := parseContentDescriptors(req.Descriptors)
+	if len(descriptors) > 255 {
+		errs = append(errs, utils.FieldError{Field: "content_descriptors", Rule: "max", Message: "content_descriptors is too long"})
+	}
+	requirements, err := encodeSystemRequirements(req.Requirements)
+	if err != nil {
+		errs = append(errs, utils.FieldError{Field: "system_requirements", Rule: "max", Message: err.Error()})
+	}
+	if errs != nil {
+		if uploaded {
+			deleteImage(imageURL)
+		}
+		utils.JSONValidationError(w, errs)
 		return
 	}
 
-	if req.CategoryID <= 0 {
-		utils.JSONError(w, "Valid category ID is required", http.StatusBadRequest)
-		return
+	// ผูกภาพจากคลังภาพ (ถ้าไม่ได้อัพโหลดภาพใหม่)
+	if imageURL == "" && req.MediaID > 0 {
+		mediaURL, err := mediaURLByID(req.MediaID)
+		if err != nil {
+			utils.JSONErrorCode(w, utils.ErrMediaNotFound, "Media not found", http.StatusBadRequest)
+			return
+		}
+		imageURL = mediaURL
 	}
 
 	// จัดการวันที่วางจำหน่าย
@@ -212,26 +210,29 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 
 	// เพิ่มเกมลงฐานข้อมูล
 	var result sql.Result
-	var err error
 
 	// สร้างคำสั่ง SQL สำหรับเพิ่มเกม โดยตรวจสอบว่ามี release_date หรือไม่
 	if releaseDate != nil {
 		result, err = db.Exec(`
-			INSERT INTO games (name, price, category_id, image_url, description, release_date)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, req.Name, req.Price, req.CategoryID, imageURL, req.Description, releaseDate)
+			INSERT INTO games (name, price, category_id, image_url, description, release_date, status,
+			                   age_rating, min_age, content_descriptors, system_requirements)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		`, req.Name, req.Price, req.CategoryID, imageURL, req.Description, releaseDate, req.Status,
+			ageRating, minAge, descriptors, requirements)
 	} else {
 		result, err = db.Exec(`
-			INSERT INTO games (name, price, category_id, image_url, description)
-			VALUES (?, ?, ?, ?, ?)
-		`, req.Name, req.Price, req.CategoryID, imageURL, req.Description)
+			INSERT INTO games (name, price, category_id, image_url, description, status,
+			                   age_rating, min_age, content_descriptors, system_requirements)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		`, req.Name, req.Price, req.CategoryID, imageURL, req.Description, req.Status,
+			ageRating, minAge, descriptors, requirements)
 	}
 
 	if err != nil {
 		fmt.Printf("❌ Error adding game: %v\n", err)
 		// ลบไฟล์ที่อัพโหลดไว้ถ้าเพิ่มข้อมูลในฐานข้อมูลล้มเหลว
-		if imageURL != "" {
-			deleteImage(imageURL)
+		if uploaded {
+			discardMediaAsset(imageURL)
 		}
 		utils.JSONError(w, "Error adding game: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -240,6 +241,12 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 	// ดึง ID ของเกมที่เพิ่งเพิ่ม
 	gameID, _ := result.LastInsertId()
 
+	// slug สำหรับ URL ของเกม (เติมเลขต่อท้ายถ้าชื่อซ้ำกับเกมอื่น)
+	slug, err := assignGameSlug(db, gameID, req.Name)
+	if err != nil {
+		fmt.Printf("⚠️ Error assigning game slug: %v\n", err)
+	}
+
 	// เริ่มต้นระบบจัดอันดับด้วยยอดขาย 0
 	_, err = db.Exec("INSERT INTO ranking (game_id, sales_count) VALUES (?, 0)", gameID)
 	if err != nil {
@@ -247,12 +254,28 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 		// ดำเนินการต่อแม้ว่าการเริ่มต้นระบบจัดอันดับจะล้มเหลว
 	}
 
-	fmt.Printf("✅ Game added successfully: ID=%d, Name=%s\n", gameID, req.Name)
+	// ราคาเริ่มต้นเป็นจุดแรกของประวัติราคา
+	if err := recordPriceChange(db, r, gameID, -1, req.Price, PriceSourceCreate); err != nil {
+		fmt.Printf("⚠️ Error recording price history: %v\n", err)
+	}
+
+	fmt.Printf("✅ Game added successfully: ID=%d, Name=%s, Status=%s\n", gameID, req.Name, req.Status)
+	recordAudit(r, "create", "game", gameID, nil, snapshotRow("SELECT * FROM games WHERE id = ?", gameID))
+	invalidateCatalogCache()
+	requestRankingRecompute()
+	emitWebhookEvent(WebhookGameCreated, map[string]interface{}{
+		"game_id": gameID,
+		"name":    req.Name,
+		"price":   req.Price,
+		"status":  req.Status,
+	})
 
 	// ส่ง response กลับไปยัง client
 	utils.JSONResponse(w, map[string]interface{}{
 		"message": "Game added successfully",
 		"game_id": gameID,
+		"slug":    slug,
+		"status":  req.Status,
 		"release_date": func() string {
 			// แปลง releaseDate ให้เป็นสตริงรูปแบบ YYYY-MM-DD
 			if date, ok := releaseDate.(time.Time); ok {
@@ -266,6 +289,42 @@ func AdminAddGameHandler(w http.ResponseWriter, r *http.Request) {
 // AdminUpdateGameHandler handles updating games
 // ฟังก์ชันสำหรับผู้ดูแลระบบอัพเดทข้อมูลเกมที่มีอยู่
 func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
+	// GET /admin/games/{id}/stats → สถิติของเกมเฉพาะเกม
+	if r.Method == "GET" && strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/stats") {
+		AdminGameStatsHandler(w, r)
+		return
+	}
+
+	// PATCH /admin/games/prices → แก้ราคาหลายเกมพร้อมกัน
+	if strings.TrimSuffix(r.URL.Path, "/") == "/admin/games/prices" {
+		AdminBulkPriceHandler(w, r)
+		return
+	}
+
+	// /admin/games/{id}/translations[/{locale}] → คำแปลชื่อและคำอธิบายของเกม
+	if strings.Contains(r.URL.Path, "/translations") {
+		AdminGameTranslationsHandler(w, r)
+		return
+	}
+
+	// POST /admin/games/{id}/duplicate → คัดลอกเกมเป็นร่างใหม่
+	if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/duplicate") {
+		AdminDuplicateGameHandler(w, r)
+		return
+	}
+
+	// POST /admin/games/{id}/restock → เติมหรือกำหนดสต็อก
+	if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/restock") {
+		AdminRestockHandler(w, r)
+		return
+	}
+
+	// GET /admin/games/{id} → ดูตัวอย่างเกม (รวมถึงเกมที่ยังเป็นร่าง)
+	if r.Method == "GET" {
+		adminGetGame(w, r)
+		return
+	}
+
 	// ตรวจสอบว่าเป็นเมธอด PUT หรือ PATCH
 	if r.Method != "PUT" && r.Method != "PATCH" {
 		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -287,14 +346,21 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 	// ตรวจสอบประเภทของข้อมูลที่ส่งมา
 	contentType := r.Header.Get("Content-Type")
 	var req struct {
-		Name        string  `json:"name"`
-		Price       float64 `json:"price"`
-		CategoryID  int     `json:"category_id"`
-		Description string  `json:"description"`
-		ReleaseDate string  `json:"release_date"`
+		Name         string              `json:"name"`
+		Price        float64             `json:"price"`
+		CategoryID   int                 `json:"category_id"`
+		Description  string              `json:"description"`
+		ReleaseDate  string              `json:"release_date"`
+		Status       string              `json:"status"`
+		MediaID      int                 `json:"media_id"`
+		AgeRating    string              `json:"age_rating"`          // ส่ง "none" เพื่อลบเรตติ้ง
+		Descriptors  []string            `json:"content_descriptors"` // ส่ง [] เพื่อลบคำอธิบายเนื้อหา
+		Requirements *systemRequirements `json:"system_requirements"` // ส่ง {} เพื่อลบความต้องการของระบบ
+		Slug         string              `json:"slug"`                // เปลี่ยน slug (ลิงก์เดิมจะใช้ไม่ได้)
 	}
 
 	var imageURL string
+	uploaded := false
 
 	// กรณีส่งข้อมูลแบบ Form-data
 	if strings.Contains(contentType, "multipart/form-data") {
@@ -310,6 +376,16 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 		categoryIDStr := r.FormValue("category_id")
 		req.Description = r.FormValue("description")
 		req.ReleaseDate = r.FormValue("release_date")
+		req.Status = r.FormValue("status")
+		req.Slug = r.FormValue("slug")
+		req.MediaID, _ = strconv.Atoi(r.FormValue("media_id"))
+		req.AgeRating = r.FormValue("age_rating")
+		req.Descriptors = r.Form["content_descriptors"]
+		req.Requirements, err = parseSystemRequirementsForm(r.FormValue("system_requirements"))
+		if err != nil {
+			utils.JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// แปลงสตริงเป็นตัวเลข
 		if priceStr != "" {
@@ -334,9 +410,9 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 			defer file.Close()
 
 			// ใช้ฟังก์ชันใหม่สำหรับอัพโหลดภาพ
-			imageURL, err = saveImage(file, header)
+			imageURL, uploaded, err = uploadMediaAsset(r, file, header)
 			if err != nil {
-				utils.JSONError(w, "Error uploading image: "+err.Error(), http.StatusInternalServerError)
+				utils.JSONError(w, "Error uploading image: "+err.Error(), uploadErrorStatus(err))
 				return
 			}
 		}
@@ -348,10 +424,14 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// ดึง URL ภาพเก่าเพื่อลบในภายหลัง (ถ้ามีการอัพโหลดภาพใหม่)
-	var oldImageURL sql.NullString
-	if imageURL != "" {
-		db.QueryRow("SELECT image_url FROM games WHERE id = ?", gameID).Scan(&oldImageURL)
+	// ผูกภาพจากคลังภาพ (ถ้าไม่ได้อัพโหลดภาพใหม่)
+	if imageURL == "" && req.MediaID > 0 {
+		mediaURL, err := mediaURLByID(req.MediaID)
+		if err != nil {
+			utils.JSONErrorCode(w, utils.ErrMediaNotFound, "Media not found", http.StatusBadRequest)
+			return
+		}
+		imageURL = mediaURL
 	}
 
 	// สร้างคำสั่งอัพเดทแบบไดนามิกตามฟิลด์ที่มีการส่งมา
@@ -394,6 +474,66 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 		args = append(args, imageURL)
 	}
 
+	// เรตติ้งอายุ (อายุขั้นต่ำอัพเดทตามเรตติ้งเสมอ)
+	if strings.EqualFold(req.AgeRating, ageRatingNone) {
+		updateFields = append(updateFields, "age_rating = NULL", "min_age = 0")
+	} else if req.AgeRating != "" {
+		rating, minAge, ok := normalizeAgeRating(req.AgeRating)
+		if !ok {
+			if uploaded {
+				discardMediaAsset(imageURL)
+			}
+			utils.JSONError(w, "age_rating must be an ESRB or PEGI rating (e.g. ESRB M, PEGI 18) or 'none'", http.StatusBadRequest)
+			return
+		}
+		updateFields = append(updateFields, "age_rating = ?", "min_age = ?")
+		args = append(args, rating, minAge)
+	}
+
+	if req.Descriptors != nil {
+		updateFields = append(updateFields, "content_descriptors = NULLIF(?, '')")
+		args = append(args, parseContentDescriptors(req.Descriptors))
+	}
+
+	if req.Slug != "" {
+		if !validGameSlug(req.Slug) {
+			if uploaded {
+				discardMediaAsset(imageURL)
+			}
+			utils.JSONError(w, "slug must contain only lowercase letters, numbers and hyphens (max 100 characters)", http.StatusBadRequest)
+			return
+		}
+		updateFields = append(updateFields, "slug = ?")
+		args = append(args, req.Slug)
+	}
+
+	// ความต้องการของระบบ (แทนที่ทั้งก้อน)
+	if req.Requirements != nil {
+		requirements, err := encodeSystemRequirements(req.Requirements)
+		if err != nil {
+			if uploaded {
+				discardMediaAsset(imageURL)
+			}
+			utils.JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updateFields = append(updateFields, "system_requirements = NULLIF(?, '')")
+		args = append(args, requirements)
+	}
+
+	// เปลี่ยนสถานะระหว่างร่างและเผยแพร่ (การถอดเกมออกจากร้านใช้ DELETE /admin/games/delete/{id})
+	if req.Status != "" {
+		if req.Status != GameStatusDraft && req.Status != GameStatusPublished {
+			if uploaded {
+				discardMediaAsset(imageURL)
+			}
+			utils.JSONError(w, "Status must be 'draft' or 'published'", http.StatusBadRequest)
+			return
+		}
+		updateFields = append(updateFields, "status = ?", "delisted_at = NULL")
+		args = append(args, req.Status)
+	}
+
 	// ตรวจสอบว่ามีฟิลด์ที่จะอัพเดทหรือไม่
 	if len(updateFields) == 0 {
 		utils.JSONError(w, "No fields to update", http.StatusBadRequest)
@@ -403,14 +543,21 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 	// เพิ่ม game ID ไปยัง args สำหรับเงื่อนไข WHERE
 	args = append(args, gameID)
 
+	// เก็บข้อมูลก่อนแก้ไขสำหรับ audit log
+	before := snapshotRow("SELECT * FROM games WHERE id = ?", gameID)
+
 	// สร้างและ execute คำสั่ง UPDATE
 	query := fmt.Sprintf("UPDATE games SET %s WHERE id = ?", strings.Join(updateFields, ", "))
 	result, err := db.Exec(query, args...)
 	if err != nil {
 		fmt.Printf("❌ Error updating game: %v\n", err)
 		// ลบไฟล์ภาพใหม่ถ้าอัพเดทฐานข้อมูลล้มเหลว
-		if imageURL != "" {
-			deleteImage(imageURL)
+		if uploaded {
+			discardMediaAsset(imageURL)
+		}
+		if key, ok := duplicateKeyName(err); ok && key == "uniq_games_slug" {
+			utils.JSONError(w, "Slug is already used by another game", http.StatusConflict)
+			return
 		}
 		utils.JSONError(w, "Error updating game: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -419,24 +566,36 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 	// ตรวจสอบว่ามีแถวถูกอัพเดทจริงหรือไม่
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		if imageURL != "" {
-			deleteImage(imageURL)
+		if uploaded {
+			discardMediaAsset(imageURL)
 		}
-		utils.JSONError(w, "Game not found", http.StatusNotFound)
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
 		return
 	}
 
-	// ลบไฟล์ภาพเก่าถ้ามีการอัพโหลดภาพใหม่
-	if imageURL != "" && oldImageURL.Valid && oldImageURL.String != "" {
-		err := deleteImage(oldImageURL.String)
-		if err != nil {
-			fmt.Printf("⚠️ Error deleting old image: %v\n", err)
-		} else {
-			fmt.Printf("🗑️ Deleted old image: %s\n", oldImageURL.String)
+	// บันทึกประวัติราคาเมื่อราคาเปลี่ยน (ใช้คำนวณป้าย "ราคาต่ำสุดใน 30 วัน")
+	if req.Price > 0 && before != nil {
+		oldPrice, _ := before["price"].(string)
+		if old, err := strconv.ParseFloat(oldPrice, 64); err == nil && old != req.Price {
+			if err := recordPriceChange(db, r, int64(gameID), old, req.Price, PriceSourceUpdate); err != nil {
+				fmt.Printf("⚠️ Error recording price history: %v\n", err)
+			}
+			enqueuePriceDrop(int64(gameID), old, req.Price)
 		}
 	}
 
+	// เกมที่กลับไปเป็นร่างจะซื้อไม่ได้ ให้นำออกจากตะกร้าสินค้าของผู้ใช้
+	if req.Status == GameStatusDraft {
+		if _, err := db.Exec("DELETE FROM cart_items WHERE game_id = ?", gameID); err != nil {
+			fmt.Printf("⚠️ Error removing draft game from carts: %v\n", err)
+		}
+	}
+
+	// ภาพเก่ายังคงอยู่ในคลังภาพเพื่อนำกลับมาใช้ได้ (ลบได้ทาง DELETE /admin/media/{id})
+
 	fmt.Printf("✅ Game updated successfully: ID=%d\n", gameID)
+	recordAudit(r, "update", "game", gameID, before, snapshotRow("SELECT * FROM games WHERE id = ?", gameID))
+	invalidateCatalogCache()
 
 	// ส่ง response สำเร็จกลับไป
 	utils.JSONResponse(w, map[string]interface{}{
@@ -445,8 +604,148 @@ func AdminUpdateGameHandler(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
-// AdminDeleteGameHandler handles deleting games
-// ฟังก์ชันสำหรับผู้ดูแลระบบลบเกมออกจากระบบ
+// GET /admin/games?status=&limit=&offset= - รายการเกมทั้งหมดสำหรับผู้ดูแลระบบ (รวมร่างและเกมที่ถูกถอด)
+func adminListGames(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	whereSQL := ""
+	args := []interface{}{}
+	if status := query.Get("status"); status != "" {
+		if status != GameStatusDraft && status != GameStatusPublished && status != GameStatusDelisted && status != GameStatusHidden {
+			utils.JSONError(w, "Invalid status filter. Use draft, published, delisted or hidden", http.StatusBadRequest)
+			return
+		}
+		whereSQL = " WHERE g.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM games g"+whereSQL, args...).Scan(&total); err != nil {
+		utils.JSONError(w, "Error fetching games", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, c.name, g.image_url, g.description,
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+`, g.status,
+		       g.delisted_at
+		FROM games g
+		LEFT JOIN categories c ON g.category_id = c.id`+whereSQL+`
+		ORDER BY g.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching admin games: %v\n", err)
+		utils.JSONError(w, "Error fetching games", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name, status string
+		var price float64
+		var category, imageURL, description, releaseDate sql.NullString
+		var delistedAt utils.NullTimestamp
+
+		if err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &status, &delistedAt); err != nil {
+			fmt.Printf("❌ Error scanning admin game: %v\n", err)
+			utils.JSONError(w, "Error fetching games", http.StatusInternalServerError)
+			return
+		}
+
+		games = append(games, map[string]interface{}{
+			"id":           id,
+			"name":         name,
+			"price":        price,
+			"category":     category.String,
+			"image_url":    imageURL.String,
+			"description":  description.String,
+			"release_date": releaseDate.String,
+			"status":       status,
+			"delisted_at":  delistedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading admin game rows: %v\n", err)
+		utils.JSONError(w, "Error fetching games", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"games":  games,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(games),
+	}, http.StatusOK)
+}
+
+// GET /admin/games/{id} - ดูตัวอย่างเกมก่อนเผยแพร่ (ไม่กรองตามสถานะ)
+func adminGetGame(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	gameID, err := strconv.Atoi(pathParts[len(pathParts)-1])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var id int
+	var name, status string
+	var price float64
+	var category, imageURL, description, releaseDate sql.NullString
+	var delistedAt utils.NullTimestamp
+	var rank sql.NullInt64
+	var requirements, slug sql.NullString
+
+	err = db.QueryRow(`
+		SELECT g.id, g.name, g.price, c.name, g.image_url, g.description,
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+`, g.status,
+		       g.delisted_at, r.rank_position, g.system_requirements, g.slug
+		FROM games g
+		LEFT JOIN categories c ON g.category_id = c.id
+		LEFT JOIN ranking r ON g.id = r.game_id
+		WHERE g.id = ?
+	`, gameID).Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &status, &delistedAt, &rank, &requirements, &slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching game", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	game := map[string]interface{}{
+		"id":           id,
+		"name":         name,
+		"price":        price,
+		"category":     category.String,
+		"image_url":    imageURL.String,
+		"description":  description.String,
+		"release_date": releaseDate.String,
+		"status":       status,
+		"delisted_at":  delistedAt,
+		"rank":         rank.Int64,
+		"slug":         slug.String,
+	}
+	addSystemRequirementsField(game, requirements)
+	utils.JSONResponse(w, game, http.StatusOK)
+}
+
+// AdminDeleteGameHandler handles delisting games (soft delete)
+// ฟังก์ชันสำหรับผู้ดูแลระบบถอดเกมออกจากร้านค้า (soft delete)
+// เกมจะถูกซ่อนจากหน้าร้านและการค้นหา แต่ยังคงอยู่ในคลังเกมของผู้ใช้และรายงานทางการเงิน
 func AdminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
 	// ตรวจสอบว่าเป็นเมธอด DELETE หรือไม่
 	if r.Method != "DELETE" {
@@ -463,20 +762,115 @@ func AdminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("🔍 Admin deleting game ID: %d\n", gameID)
+	fmt.Printf("🔍 Admin delisting game ID: %d\n", gameID)
+	before := snapshotRow("SELECT * FROM games WHERE id = ?", gameID)
+
+	// เริ่มต้น transaction เพื่อความปลอดภัยของข้อมูล
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// 1. เปลี่ยนสถานะเกมเป็น delisted
+	result, err := tx.Exec(`
+		UPDATE games SET status = 'delisted', delisted_at = NOW()
+		WHERE id = ? AND status != 'delisted'
+	`, gameID)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error delisting game", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		tx.Rollback()
+		// แยกกรณีไม่พบเกมกับเกมที่ถูกถอดไปแล้ว
+		var status string
+		err := db.QueryRow("SELECT status FROM games WHERE id = ?", gameID).Scan(&status)
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Game is already delisted", http.StatusConflict)
+		}
+		return
+	}
+
+	// 2. นำเกมออกจากตะกร้าสินค้าของผู้ใช้ (ซื้อไม่ได้แล้ว)
+	_, err = tx.Exec("DELETE FROM cart_items WHERE game_id = ?", gameID)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error deleting game from carts", http.StatusInternalServerError)
+		return
+	}
+
+	// ยืนยัน transaction
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("✅ Game delisted successfully: ID=%d\n", gameID)
+	recordAudit(r, "delist", "game", gameID, before, snapshotRow("SELECT * FROM games WHERE id = ?", gameID))
+	invalidateCatalogCache()
+
+	// ส่ง response สำเร็จกลับไป
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Game delisted successfully",
+		"game_id": gameID,
+		"status":  GameStatusDelisted,
+	}, http.StatusOK)
+}
+
+// AdminPurgeGameHandler handles permanently deleting delisted games
+// ฟังก์ชันสำหรับผู้ดูแลระบบลบเกมที่ถูกถอดออกจากร้านแล้วออกจากระบบอย่างถาวร (ย้อนกลับไม่ได้)
+// จะลบเกมออกจากคลังเกมของผู้ใช้และประวัติการซื้อด้วย ต้องส่ง ?confirm=true เพื่อยืนยัน
+func AdminPurgeGameHandler(w http.ResponseWriter, r *http.Request) {
+	// ตรวจสอบว่าเป็นเมธอด DELETE หรือไม่
+	if r.Method != "DELETE" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ดึง game_id จาก URL path
+	pathParts := strings.Split(r.URL.Path, "/")
+	gameIDStr := pathParts[len(pathParts)-1]
+	gameID, err := strconv.Atoi(gameIDStr)
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("🔍 Admin purging game ID: %d\n", gameID)
+
+	// ต้องยืนยันการลบถาวร
+	if r.URL.Query().Get("confirm") != "true" {
+		utils.JSONError(w, "Purge is irreversible; add ?confirm=true", http.StatusBadRequest)
+		return
+	}
 
 	// ดึง URL ภาพก่อนลบ (เพื่อลบไฟล์ภาพออกจากระบบไฟล์)
 	var imageURL sql.NullString
-	err = db.QueryRow("SELECT image_url FROM games WHERE id = ?", gameID).Scan(&imageURL)
+	var status string
+	err = db.QueryRow("SELECT image_url, status FROM games WHERE id = ?", gameID).Scan(&imageURL, &status)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Game not found", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Error fetching game", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	// ลบถาวรได้เฉพาะเกมที่ถูกถอดออกจากร้านแล้วเท่านั้น
+	if status != GameStatusDelisted {
+		utils.JSONError(w, "Only delisted games can be purged. Delist the game first", http.StatusConflict)
+		return
+	}
+
+	before := snapshotRow("SELECT * FROM games WHERE id = ?", gameID)
+
 	// เริ่มต้น transaction เพื่อความปลอดภัยของข้อมูล
 	tx, err := db.Begin()
 	if err != nil {
@@ -503,7 +897,7 @@ func AdminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. ลบจากตาราง purchase_items (รายการเกมในการซื้อ)
-	_, err = tx.Exec("DELETE pi FROM purchase_items pi WHERE pi.game_id = ?", gameID)
+	_, err = tx.Exec("DELETE FROM purchase_items WHERE game_id = ?", gameID)
 	if err != nil {
 		tx.Rollback()
 		utils.JSONError(w, "Error deleting game purchase records", http.StatusInternalServerError)
@@ -530,7 +924,7 @@ func AdminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
 		tx.Rollback()
-		utils.JSONError(w, "Game not found", http.StatusNotFound)
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
 		return
 	}
 
@@ -540,21 +934,23 @@ func AdminDeleteGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ลบไฟล์ภาพถ้ามี
+	// ลบไฟล์ภาพถ้าไม่มีเกมอื่นใช้ภาพเดียวกันจากคลังภาพ
 	if imageURL.Valid && imageURL.String != "" {
-		err := deleteImage(imageURL.String)
-		if err != nil {
-			fmt.Printf("⚠️ Error deleting game image: %v\n", err)
-		} else {
-			fmt.Printf("🗑️ Deleted game image: %s\n", imageURL.String)
+		var usedBy int
+		db.QueryRow("SELECT COUNT(*) FROM games WHERE image_url = ?", imageURL.String).Scan(&usedBy)
+		if usedBy == 0 {
+			discardMediaAsset(imageURL.String)
 		}
 	}
 
-	fmt.Printf("✅ Game deleted successfully: ID=%d\n", gameID)
+	fmt.Printf("✅ Game purged successfully: ID=%d\n", gameID)
+	recordAudit(r, "purge", "game", gameID, before, nil)
+	invalidateCatalogCache()
+	requestRankingRecompute()
 
 	// ส่ง response สำเร็จกลับไป
 	utils.JSONResponse(w, map[string]interface{}{
-		"message": "Game deleted successfully",
+		"message": "Game purged successfully",
 		"game_id": gameID,
 	}, http.StatusOK)
 }
@@ -569,15 +965,28 @@ func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Printf("🔍 Admin fetching all users (excluding admins)\n")
 
+	page, err := parsePage(r.URL.Query(), 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE role != 'admin'").Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting users: %v\n", err)
+		utils.JSONError(w, "Error fetching users", http.StatusInternalServerError)
+		return
+	}
+
 	// ดึงข้อมูลผู้ใช้ทั้งหมดที่ไม่ใช่ admin เรียงตามวันที่สร้างล่าสุด
 	rows, err := db.Query(`
 		SELECT id, username, email, role, 
-		       DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') as created_date,
+		       created_at as created_date,
 		       wallet_balance
 		FROM users
 		WHERE role != 'admin'
-		ORDER BY created_at DESC
-	`)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, page.Limit, page.Offset)
 	if err != nil {
 		fmt.Printf("❌ Error fetching users: %v\n", err)
 		utils.JSONError(w, "Error fetching users: "+err.Error(), http.StatusInternalServerError)
@@ -592,12 +1001,13 @@ func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var id int
 		var username, email, role string
-		var createdDate string
+		var createdDate utils.Timestamp
 		var walletBalance float64
 
 		if err := rows.Scan(&id, &username, &email, &role, &createdDate, &walletBalance); err != nil {
 			fmt.Printf("❌ Error scanning user row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching users", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object ผู้ใช้
@@ -622,7 +1032,7 @@ func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("✅ Total users found (excluding admins): %d\n", count)
+	fmt.Printf("✅ Total users found (excluding admins): %d (showing %d)\n", total, count)
 
 	// ตรวจสอบว่า users ไม่เป็น nil
 	if users == nil {
@@ -630,7 +1040,159 @@ func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ส่ง response กลับไป
-	utils.JSONResponse(w, users, http.StatusOK)
+	utils.JSONResponse(w, paginatedResponse(users, total, page), http.StatusOK)
+}
+
+// AdminUserActionsHandler routes admin actions on a specific user
+// ฟังก์ชันสำหรับแยกเส้นทางของการจัดการผู้ใช้รายคน
+// POST /admin/users/{id}/wallet/adjust - ปรับยอดเงินในกระเป๋า (ดู AdminWalletAdjustHandler)
+// POST /admin/users/{id}/grant-game    - มอบเกมให้ผู้ใช้ (ดู AdminGrantGameHandler)
+func AdminUserActionsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/users/123/grant-game → ["admin", "users", "123", "grant-game"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) >= 4 && pathParts[3] == "grant-game" {
+		AdminGrantGameHandler(w, r)
+		return
+	}
+	if len(pathParts) >= 4 && pathParts[3] == "permissions" {
+		AdminUserPermissionsHandler(w, r)
+		return
+	}
+	AdminWalletAdjustHandler(w, r)
+}
+
+// AdminGrantGameHandler handles giving a game to a user without payment
+// ฟังก์ชันสำหรับมอบเกมให้ผู้ใช้ (เช่น โปรโมชัน ของรางวัลจากการแข่งขัน หรือชดเชยจากฝ่ายซัพพอร์ต)
+// สร้างคำสั่งซื้อมูลค่า 0 เพื่อให้เกมแสดงในประวัติการซื้อและตรวจสอบย้อนหลังได้
+// POST /admin/users/{id}/grant-game  body: {"game_id": 5, "reason": "Contest prize"}
+func AdminGrantGameHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/users/123/grant-game → userID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[3] != "grant-game" {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		GameID int    `json:"game_id" validate:"gt=0"`
+		Reason string `json:"reason" validate:"required,max=255"` // เหตุผล (บันทึกในประวัติสถานะคำสั่งซื้อและ audit log)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	adminID, _ := requestUserID(r)
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// ล็อกแถวของผู้ใช้ ป้องกันการมอบเกมซ้ำเมื่อมี request พร้อมกัน
+	var lockedID int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&lockedID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var gameName string
+	if err := tx.QueryRow("SELECT name FROM games WHERE id = ?", req.GameID).Scan(&gameName); err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error checking game", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var owned bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?)
+	`, userID, req.GameID).Scan(&owned); err != nil {
+		utils.JSONError(w, "Error checking ownership", http.StatusInternalServerError)
+		return
+	}
+	if owned {
+		utils.JSONErrorCode(w, utils.ErrAlreadyOwned, "User already owns this game", http.StatusConflict)
+		return
+	}
+
+	// คำสั่งซื้อมูลค่า 0 (granted_by = admin ที่มอบเกม) แล้วเพิ่มเกมเข้าคลัง
+	result, err := tx.Exec(`
+		INSERT INTO purchases (user_id, total_amount, final_amount, granted_by, status, status_updated_at)
+		VALUES (?, 0, 0, ?, ?, NOW())
+	`, userID, adminID, PurchasePending)
+	if err != nil {
+		fmt.Printf("❌ Error creating granted purchase: %v\n", err)
+		utils.JSONError(w, "Error creating purchase record", http.StatusInternalServerError)
+		return
+	}
+	purchaseID, _ := result.LastInsertId()
+
+	if _, err := tx.Exec(`
+		INSERT INTO purchase_items (purchase_id, game_id, price_at_purchase) VALUES (?, ?, 0)
+	`, purchaseID, req.GameID); err != nil {
+		utils.JSONError(w, "Error recording purchase items", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("INSERT INTO purchased_games (user_id, game_id) VALUES (?, ?)", userID, req.GameID); err != nil {
+		utils.JSONError(w, "Error adding to library", http.StatusInternalServerError)
+		return
+	}
+	for _, status := range []string{PurchasePaid, PurchaseFulfilled} {
+		if err := transitionPurchase(tx, purchaseID, status, "granted: "+req.Reason); err != nil {
+			fmt.Printf("❌ Error updating purchase status: %v\n", err)
+			utils.JSONError(w, "Error updating purchase status", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "game_grant", "user", userID, nil, map[string]interface{}{
+		"game_id":     req.GameID,
+		"game_name":   gameName,
+		"purchase_id": purchaseID,
+		"reason":      req.Reason,
+	})
+	notify(userID, NotificationGiftReceived, "You received a game",
+		fmt.Sprintf("%s has been added to your library", gameName),
+		map[string]interface{}{"game_id": req.GameID, "purchase_id": purchaseID})
+	publishToUser(userID, EventOrderStatus, map[string]interface{}{"purchase_id": purchaseID, "status": "completed"})
+
+	fmt.Printf("🎁 Game granted: user %d, game %d (%s) by admin %d\n", userID, req.GameID, req.Reason, adminID)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "Game granted",
+		"purchase_id": purchaseID,
+		"user_id":     userID,
+		"game_id":     req.GameID,
+		"game_name":   gameName,
+	}, http.StatusCreated)
 }
 
 // AdminStatsHandler handles admin statistics
@@ -645,16 +1207,28 @@ func AdminStatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ดึงจำนวนผู้ใช้ทั้งหมด
-	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
+	readQueryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
 
 	// ดึงจำนวนเกมทั้งหมด
-	db.QueryRow("SELECT COUNT(*) FROM games").Scan(&stats.TotalGames)
+	readQueryRow("SELECT COUNT(*) FROM games").Scan(&stats.TotalGames)
 
 	// ดึงยอดขายรวมทั้งหมด (ใช้ COALESCE เพื่อป้องกัน NULL)
-	db.QueryRow("SELECT COALESCE(SUM(final_amount), 0) FROM purchases").Scan(&stats.TotalSales)
+	readQueryRow("SELECT COALESCE(SUM(final_amount), 0) FROM purchases").Scan(&stats.TotalSales)
 
 	// ดึงจำนวนการซื้อทั้งหมด
-	db.QueryRow("SELECT COUNT(*) FROM purchases").Scan(&stats.TotalPurchases)
+	readQueryRow("SELECT COUNT(*) FROM purchases").Scan(&stats.TotalPurchases)
+
+	// ส่งออกเป็น CSV (metric,value)
+	if wantsCSV(r) {
+		cw := utils.CSVWriter(w, "stats.csv")
+		cw.Write([]string{"metric", "value"})
+		cw.Write([]string{"total_users", strconv.Itoa(stats.TotalUsers)})
+		cw.Write([]string{"total_games", strconv.Itoa(stats.TotalGames)})
+		cw.Write([]string{"total_sales", formatAmount(stats.TotalSales)})
+		cw.Write([]string{"total_purchases", strconv.Itoa(stats.TotalPurchases)})
+		cw.Flush()
+		return
+	}
 
 	// ส่งสถิติกลับไป
 	utils.JSONResponse(w, stats, http.StatusOK)
@@ -663,8 +1237,6 @@ func AdminStatsHandler(w http.ResponseWriter, r *http.Request) {
 // AdminTransactionsHandler handles admin transaction management
 // ฟังก์ชันหลักสำหรับจัดการธุรกรรมโดยผู้ดูแลระบบ
 func AdminTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("💰 AdminTransactionsHandler: %s %s\n", r.Method, r.URL.Path)
-
 	// ตรวจสอบเมธอดและเรียกฟังก์ชันที่เหมาะสม
 	switch r.Method {
 	case "GET":
@@ -677,8 +1249,6 @@ func AdminTransactionsHandler(w http.ResponseWriter, r *http.Request) {
 // AdminUserTransactionsHandler handles user-specific transaction management for admin
 // ฟังก์ชันสำหรับจัดการธุรกรรมเฉพาะผู้ใช้โดยผู้ดูแลระบบ
 func AdminUserTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("💰 AdminUserTransactionsHandler: %s %s\n", r.Method, r.URL.Path)
-
 	// แยก user ID จาก URL path
 	// ตัวอย่าง URL: /admin/transactions/user/123 → userID = 123
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
@@ -709,48 +1279,55 @@ func getAllTransactions(w http.ResponseWriter, r *http.Request) {
 
 	// รับ query parameters สำหรับ filtering และ pagination
 	query := r.URL.Query()
-	transactionType := query.Get("type") // ประเภทธุรกรรม (ฝากเงิน, ถอนเงิน, ซื้อเกม)
-	limitStr := query.Get("limit")       // จำนวนรายการต่อหน้า
-	offsetStr := query.Get("offset")     // ตำแหน่งเริ่มต้น
-
-	// ตั้งค่า default values
-	limit := 100
-	offset := 0
+	limitStr := query.Get("limit") // จำนวนรายการต่อหน้า (CSV ดึงทุกรายการถ้าไม่ระบุ)
 
-	// แปลงค่า limit และ offset เป็นตัวเลข
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	// ประเภท ช่วงวันที่ คำค้นหา และช่วงจำนวนเงิน (ดู transactionFilters)
+	filters, filterArgs, err := transactionFilters(query, "t.")
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+
+	page, err := parsePage(query, 100)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	limit, offset := page.Limit, page.Offset
 
 	// สร้างคำสั่ง SQL พื้นฐาน
 	baseQuery := `
 		SELECT 
-			t.id, t.user_id, u.username, t.type, t.amount, 
-			t.description, DATE_FORMAT(t.created_at, '%Y-%m-%d %H:%i:%s') as created_at
+			t.id, t.user_id, COALESCE(u.username, ''), t.type, t.amount, 
+			COALESCE(t.description, ''), t.created_at
 		FROM user_transactions t
 		LEFT JOIN users u ON t.user_id = u.id
 	`
-	var args []interface{}
-	whereClauses := []string{}
-
-	// เพิ่มเงื่อนไข WHERE ถ้ามีการกรองประเภทธุรกรรม
-	if transactionType != "" {
-		whereClauses = append(whereClauses, "t.type = ?")
-		args = append(args, transactionType)
-	}
+	args := filterArgs
+	whereClauses := filters
 
 	// รวมเงื่อนไข WHERE ถ้ามี
 	if len(whereClauses) > 0 {
 		baseQuery += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
 
+	// ส่งออกเป็น CSV: ดึงทุกรายการ (ใช้ pagination เฉพาะเมื่อระบุ limit มา)
+	if wantsCSV(r) {
+		csvQuery := baseQuery + " ORDER BY t.created_at DESC"
+		csvArgs := append([]interface{}{}, args...)
+		if limitStr != "" {
+			csvQuery += " LIMIT ? OFFSET ?"
+			csvArgs = append(csvArgs, limit, offset)
+		}
+		loc, err := exportLocation(r)
+		if err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+		streamQueryCSV(w, loc, "transactions.csv", csvQuery, csvArgs...)
+		return
+	}
+
 	// เพิ่มการเรียงลำดับและ pagination
 	baseQuery += " ORDER BY t.created_at DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
@@ -770,13 +1347,15 @@ func getAllTransactions(w http.ResponseWriter, r *http.Request) {
 	// อ่านข้อมูลธุรกรรมทีละแถว
 	for rows.Next() {
 		var id, userID int
-		var username, transactionType, description, createdAt string
+		var username, transactionType, description string
+		var createdAt utils.Timestamp
 		var amount float64
 
 		err := rows.Scan(&id, &userID, &username, &transactionType, &amount, &description, &createdAt)
 		if err != nil {
 			fmt.Printf("❌ Error scanning transaction row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching transactions", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object ธุรกรรม
@@ -820,14 +1399,10 @@ func getAllTransactions(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("✅ Total transactions found: %d (showing %d)\n", totalCount, count)
 
 	// ส่ง response กลับไปพร้อมข้อมูลธุรกรรมและข้อมูล pagination
-	utils.JSONResponse(w, map[string]interface{}{
-		"transactions": transactions,
-		"total":        totalCount,
-		"limit":        limit,
-		"offset":       offset,
-		"count":        count,
-		"success":      true,
-	}, http.StatusOK)
+	if transactions == nil {
+		transactions = []map[string]interface{}{}
+	}
+	utils.JSONResponse(w, paginatedResponse(transactions, totalCount, page), http.StatusOK)
 }
 
 // GET /admin/transactions/user/{userID} - ดึงประวัติธุรกรรมของผู้ใช้เฉพาะคน
@@ -840,7 +1415,7 @@ func getUserTransactions(w http.ResponseWriter, r *http.Request, userID int) {
 	err := db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "User not found", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Error checking user", http.StatusInternalServerError)
 		}
@@ -849,40 +1424,53 @@ func getUserTransactions(w http.ResponseWriter, r *http.Request, userID int) {
 
 	// รับ query parameters
 	query := r.URL.Query()
-	transactionType := query.Get("type")
 	limitStr := query.Get("limit")
-	offsetStr := query.Get("offset")
 
-	// ตั้งค่า default values
-	limit := 50
-	offset := 0
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	filters, filterArgs, err := transactionFilters(query, "t.")
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+
+	page, err := parsePage(query, 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	limit, offset := page.Limit, page.Offset
 
 	// สร้างคำสั่ง SQL
 	baseQuery := `
 		SELECT 
-			t.id, t.type, t.amount, t.description, 
-			DATE_FORMAT(t.created_at, '%Y-%m-%d %H:%i:%s') as created_at
+			t.id, t.type, t.amount, COALESCE(t.description, ''), 
+			t.created_at
 		FROM user_transactions t
 		WHERE t.user_id = ?
 	`
 	var args []interface{}
 	args = append(args, userID)
 
-	// เพิ่มเงื่อนไขประเภทธุรกรรมถ้ามี
-	if transactionType != "" {
-		baseQuery += " AND t.type = ?"
-		args = append(args, transactionType)
+	// เพิ่มเงื่อนไขการกรองถ้ามี
+	if len(filters) > 0 {
+		baseQuery += " AND " + strings.Join(filters, " AND ")
+		args = append(args, filterArgs...)
+	}
+
+	// ส่งออกเป็น CSV: ดึงทุกรายการ (ใช้ pagination เฉพาะเมื่อระบุ limit มา)
+	if wantsCSV(r) {
+		csvQuery := baseQuery + " ORDER BY t.created_at DESC"
+		csvArgs := append([]interface{}{}, args...)
+		if limitStr != "" {
+			csvQuery += " LIMIT ? OFFSET ?"
+			csvArgs = append(csvArgs, limit, offset)
+		}
+		loc, err := exportLocation(r)
+		if err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+		streamQueryCSV(w, loc, fmt.Sprintf("transactions_user_%d.csv", userID), csvQuery, csvArgs...)
+		return
 	}
 
 	// เพิ่มการเรียงลำดับและ pagination
@@ -904,13 +1492,15 @@ func getUserTransactions(w http.ResponseWriter, r *http.Request, userID int) {
 	// อ่านข้อมูลธุรกรรมทีละแถว
 	for rows.Next() {
 		var id int
-		var transactionType, description, createdAt string
+		var transactionType, description string
+		var createdAt utils.Timestamp
 		var amount float64
 
 		err := rows.Scan(&id, &transactionType, &amount, &description, &createdAt)
 		if err != nil {
 			fmt.Printf("❌ Error scanning user transaction row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching user transactions", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object ธุรกรรม
@@ -937,24 +1527,25 @@ func getUserTransactions(w http.ResponseWriter, r *http.Request, userID int) {
 
 	// ดึงจำนวน total สำหรับ pagination
 	var totalCount int
-	countQuery := "SELECT COUNT(*) FROM user_transactions WHERE user_id = ?"
-	if transactionType != "" {
-		countQuery += " AND type = ?"
-		err = db.QueryRow(countQuery, userID, transactionType).Scan(&totalCount)
-	} else {
-		err = db.QueryRow(countQuery, userID).Scan(&totalCount)
+	countQuery := "SELECT COUNT(*) FROM user_transactions t WHERE t.user_id = ?"
+	countArgs := []interface{}{userID}
+	if len(filters) > 0 {
+		countQuery += " AND " + strings.Join(filters, " AND ")
+		countArgs = append(countArgs, filterArgs...)
 	}
+	err = db.QueryRow(countQuery, countArgs...).Scan(&totalCount)
 	if err != nil {
 		fmt.Printf("❌ Error counting user transactions: %v\n", err)
 		totalCount = count
 	}
 
 	// ดึงข้อมูลผู้ใช้เพิ่มเติม
-	var userUsername, userEmail, userCreatedAt string
+	var userUsername, userEmail string
+	var userCreatedAt utils.Timestamp
 	var userWalletBalance float64
 
 	err = db.QueryRow(`
-		SELECT username, email, wallet_balance, DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') as created_at 
+		SELECT username, email, wallet_balance, created_at
 		FROM users WHERE id = ?
 	`, userID).Scan(&userUsername, &userEmail, &userWalletBalance, &userCreatedAt)
 
@@ -977,13 +1568,10 @@ func getUserTransactions(w http.ResponseWriter, r *http.Request, userID int) {
 	fmt.Printf("✅ Transactions found for user %s: %d (showing %d)\n", username, totalCount, count)
 
 	// ส่ง response กลับไปพร้อมข้อมูลธุรกรรมและข้อมูลผู้ใช้
-	utils.JSONResponse(w, map[string]interface{}{
-		"transactions": transactions,
-		"user":         userData,
-		"total":        totalCount,
-		"limit":        limit,
-		"offset":       offset,
-		"count":        count,
-		"success":      true,
-	}, http.StatusOK)
+	if transactions == nil {
+		transactions = []map[string]interface{}{}
+	}
+	response := paginatedResponse(transactions, totalCount, page)
+	response["user"] = userData
+	utils.JSONResponse(w, response, http.StatusOK)
 }
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// adminRequestMaxBody ขนาดสูงสุดของ request body ที่เก็บใน audit log (ส่วนที่เกินถูกตัดทิ้ง แต่ handler ยังได้ body ครบ)
+const adminRequestMaxBody = 8 << 10
+
+// adminRequestRedacted ค่าที่ใช้แทนข้อมูลลับใน body ที่บันทึก
+const adminRequestRedacted = "[REDACTED]"
+
+// secretFieldMarkers ชื่อ field (ตัวพิมพ์เล็ก) ที่มีคำเหล่านี้ถือเป็นข้อมูลลับ เช่น password, new_password, webhook secret, api_key
+var secretFieldMarkers = []string{"password", "secret", "token", "api_key", "apikey", "authorization", "card_number", "cvv", "ticket"}
+
+// statusWriter จำ status code ของ response (handler ที่ไม่เรียก WriteHeader ถือเป็น 200)
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush ส่งข้อมูลที่ค้างอยู่ออกไปทันที (SSE ของ /events และโค้ดที่ตรวจ http.Flusher ต้องใช้)
+func (sw *statusWriter) Flush() {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Unwrap ให้ http.ResponseController ใช้ Flush ของ writer เดิมได้ (เช่น export CSV)
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// serveAdminRequest เรียก handler ของ admin และบันทึก request ที่เปลี่ยนแปลงข้อมูล (POST/PUT/PATCH/DELETE)
+// ลงใน admin_audit_log เป็น action "api_request" พร้อม body ที่ลบข้อมูลลับแล้ว และ status code ของ response
+// เพื่อใช้ตรวจสอบย้อนหลังเมื่อมีข้อโต้แย้ง แม้ handler นั้นจะไม่ได้เรียก recordAudit เอง
+func serveAdminRequest(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// อ่าน body บางส่วนไว้บันทึก แล้วต่อกลับเพื่อให้ handler อ่านได้ครบเหมือนเดิม
+	var captured []byte
+	if r.Body != nil {
+		captured, _ = io.ReadAll(io.LimitReader(r.Body, adminRequestMaxBody+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(captured), r.Body), r.Body}
+	}
+
+	sw := &statusWriter{ResponseWriter: w}
+	start := time.Now()
+	next.ServeHTTP(sw, r)
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	truncated := len(captured) > adminRequestMaxBody
+	if truncated {
+		captured = captured[:adminRequestMaxBody]
+	}
+	entry := map[string]interface{}{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"status":      sw.status,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"body":        sanitizeRequestBody(r.Header.Get("Content-Type"), captured, truncated),
+	}
+	if r.URL.RawQuery != "" {
+		entry["query"] = redactValues(r.URL.Query())
+	}
+	if truncated {
+		entry["body_truncated"] = true
+	}
+
+	// target_id เก็บได้ 64 ตัวอักษร ส่วน path เต็มอยู่ใน after_data
+	target := r.Method + " " + r.URL.Path
+	if len(target) > 64 {
+		target = target[:64]
+	}
+	recordAudit(r, "api_request", "request", target, nil, entry)
+}
+
+// sanitizeRequestBody แปลง body เป็นข้อมูลที่เก็บลง audit log ได้ โดยลบค่าของ field ที่เป็นข้อมูลลับ
+// JSON และ form ถูกเก็บเป็น object ส่วนไฟล์ที่อัพโหลด (multipart) และข้อมูลชนิดอื่นเก็บแค่ชนิดและขนาด
+func sanitizeRequestBody(contentType string, body []byte, truncated bool) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(string(body)); err == nil && !truncated {
+			return redactValues(values)
+		}
+	case mediaType == "application/json" || mediaType == "" || strings.HasSuffix(mediaType, "+json"):
+		var parsed interface{}
+		if err := json.Unmarshal(body, &parsed); err == nil {
+			return redactJSON(parsed)
+		}
+		// JSON ที่ถูกตัดหรือไม่ถูกต้อง เก็บเป็นข้อความได้เฉพาะเมื่อไม่มีคำที่อาจเป็นข้อมูลลับ
+		if !containsSecretMarker(strings.ToLower(string(body))) {
+			return string(body)
+		}
+	}
+	if mediaType == "" {
+		mediaType = "unknown"
+	}
+	return fmt.Sprintf("[%s body omitted, %d bytes captured]", mediaType, len(body))
+}
+
+// redactJSON ลบค่าของ field ที่เป็นข้อมูลลับในทุกระดับของ JSON
+func redactJSON(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isSecretField(key) {
+				value[key] = adminRequestRedacted
+			} else {
+				value[key] = redactJSON(field)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJSON(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// redactValues แปลง query/form เป็น object และลบค่าของ field ที่เป็นข้อมูลลับ
+func redactValues(values url.Values) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		switch {
+		case isSecretField(key):
+			result[key] = adminRequestRedacted
+		case len(list) == 1:
+			result[key] = list[0]
+		default:
+			result[key] = list
+		}
+	}
+	return result
+}
+
+func isSecretField(name string) bool {
+	return containsSecretMarker(strings.ToLower(name))
+}
+
+func containsSecretMarker(s string) bool {
+	for _, marker := range secretFieldMarkers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
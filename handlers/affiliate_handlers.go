@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AffiliateCookieName cookie ที่จำรหัสพาร์ทเนอร์จาก ?ref= จนกว่าผู้ใช้จะสมัครหรือสั่งซื้อ
+const AffiliateCookieName = "affiliate_ref"
+
+// defaultAffiliateCookieDays อายุของ cookie (ปรับได้ด้วย AFFILIATE_COOKIE_DAYS)
+const defaultAffiliateCookieDays = 30
+
+// affiliateCodePattern รหัสพาร์ทเนอร์ที่ใช้ใน ?ref= (ตัวอักษร ตัวเลข ขีดกลาง และขีดล่าง)
+var affiliateCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+func affiliateCookieDays() int {
+	if n, err := strconv.Atoi(os.Getenv("AFFILIATE_COOKIE_DAYS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultAffiliateCookieDays
+}
+
+// activeAffiliate ดึง ID และอัตราค่าคอมมิชชัน (%) ของพาร์ทเนอร์ที่ยังใช้งานอยู่จากรหัส
+func activeAffiliate(exec sqlExecutor, code string) (int, float64, error) {
+	var id int
+	var rate float64
+	err := exec.QueryRow("SELECT id, commission_rate FROM affiliates WHERE code = ? AND active = 1", code).Scan(&id, &rate)
+	return id, rate, err
+}
+
+// requestAffiliateCode รหัสพาร์ทเนอร์ของ request (?ref= มาก่อน cookie)
+func requestAffiliateCode(r *http.Request) string {
+	if ref := r.URL.Query().Get("ref"); affiliateCodePattern.MatchString(ref) {
+		return ref
+	}
+	if cookie, err := r.Cookie(AffiliateCookieName); err == nil && affiliateCodePattern.MatchString(cookie.Value) {
+		return cookie.Value
+	}
+	return ""
+}
+
+// AffiliateRef middleware records partner referrals from ?ref=CODE
+// Middleware สำหรับบันทึกการเข้าชมจากลิงก์ของพาร์ทเนอร์ (?ref=CODE) และจำรหัสไว้ใน cookie
+// รหัสที่ไม่มีอยู่หรือถูกปิดใช้งานจะถูกเพิกเฉย
+func AffiliateRef(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("ref"); ref != "" && affiliateCodePattern.MatchString(ref) {
+			if affiliateID, _, err := activeAffiliate(db, ref); err == nil {
+				http.SetCookie(w, &http.Cookie{
+					Name:     AffiliateCookieName,
+					Value:    ref,
+					Path:     "/",
+					MaxAge:   affiliateCookieDays() * 24 * 60 * 60,
+					HttpOnly: true,
+					Secure:   cookieSecure(),
+					SameSite: cookieSameSite(),
+				})
+				if _, err := db.Exec("INSERT INTO affiliate_clicks (affiliate_id, path) VALUES (?, ?)", affiliateID, r.URL.Path); err != nil {
+					fmt.Printf("⚠️ Error recording affiliate click: %v\n", err)
+				}
+			} else if err != sql.ErrNoRows {
+				fmt.Printf("⚠️ Error looking up affiliate %q: %v\n", ref, err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signupAffiliateID พาร์ทเนอร์ที่แนะนำผู้ใช้ที่กำลังสมัคร (nil ถ้าไม่มี)
+func signupAffiliateID(r *http.Request) interface{} {
+	code := requestAffiliateCode(r)
+	if code == "" {
+		return nil
+	}
+	affiliateID, _, err := activeAffiliate(db, code)
+	if err != nil {
+		return nil
+	}
+	return affiliateID
+}
+
+// purchaseAffiliate พาร์ทเนอร์ที่ได้ค่าคอมมิชชันจากคำสั่งซื้อ: รหัสล่าสุดจาก ?ref=/cookie มาก่อน
+// ถ้าไม่มีใช้พาร์ทเนอร์ที่แนะนำผู้ใช้ตอนสมัคร (ผู้ใช้ที่ยังไม่มีพาร์ทเนอร์จะถูกผูกกับรหัสจาก cookie)
+func purchaseAffiliate(tx *sql.Tx, r *http.Request, userID int) (interface{}, float64) {
+	if code := requestAffiliateCode(r); code != "" {
+		if affiliateID, rate, err := activeAffiliate(tx, code); err == nil {
+			if _, err := tx.Exec("UPDATE users SET affiliate_id = ? WHERE id = ? AND affiliate_id IS NULL", affiliateID, userID); err != nil {
+				fmt.Printf("⚠️ Error saving affiliate for user %d: %v\n", userID, err)
+			}
+			return affiliateID, rate
+		}
+	}
+
+	var affiliateID int
+	var rate float64
+	err := tx.QueryRow(`
+		SELECT a.id, a.commission_rate FROM users u
+		JOIN affiliates a ON a.id = u.affiliate_id AND a.active = 1
+		WHERE u.id = ?
+	`, userID).Scan(&affiliateID, &rate)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("⚠️ Error loading affiliate for user %d: %v\n", userID, err)
+		}
+		return nil, 0
+	}
+	return affiliateID, rate
+}
+
+// affiliateRequest ข้อมูลพาร์ทเนอร์ที่ผู้ดูแลระบบสร้างหรือแก้ไข
+type affiliateRequest struct {
+	Code           string  `json:"code" validate:"required,min=3,max=32"`
+	Name           string  `json:"name" validate:"required,max=100"`
+	ContactEmail   string  `json:"contact_email" validate:"max=255"`
+	CommissionRate float64 `json:"commission_rate" validate:"min=0,max=100"` // เปอร์เซ็นต์ของยอดขายหลังหักส่วนลด (ไม่รวมภาษี)
+	Active         *bool   `json:"active"`
+}
+
+// AdminAffiliatesHandler handles partner management and commission reports
+// ฟังก์ชันสำหรับผู้ดูแลระบบจัดการพาร์ทเนอร์ (affiliate) และรายงานค่าคอมมิชชัน
+// GET    /admin/affiliates                 - รายการพาร์ทเนอร์พร้อมยอดรวม
+// POST   /admin/affiliates                 - เพิ่มพาร์ทเนอร์ {"code", "name", "contact_email", "commission_rate"}
+// GET    /admin/affiliates/report?from=&to=&group_by=day|week|month&affiliate_id=&format=csv
+// GET    /admin/affiliates/{id}            - ข้อมูลพาร์ทเนอร์
+// PUT    /admin/affiliates/{id}            - แก้ไขพาร์ทเนอร์ (รวมถึง active)
+// DELETE /admin/affiliates/{id}            - ปิดใช้งาน (เก็บประวัติไว้สำหรับรายงาน)
+func AdminAffiliatesHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/affiliates/3 → ["admin", "affiliates", "3"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && r.Method == "GET":
+		listAffiliates(w)
+	case len(pathParts) == 2 && r.Method == "POST":
+		createAffiliate(w, r)
+	case len(pathParts) == 3 && pathParts[2] == "report":
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		affiliateReport(w, r)
+	case len(pathParts) == 3:
+		affiliateID, err := strconv.Atoi(pathParts[2])
+		if err != nil || affiliateID <= 0 {
+			utils.JSONError(w, "Invalid affiliate ID", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			getAffiliate(w, affiliateID)
+		case "PUT", "PATCH":
+			updateAffiliate(w, r, affiliateID)
+		case "DELETE":
+			deactivateAffiliate(w, r, affiliateID)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(pathParts) == 2:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// affiliateSummaryQuery ข้อมูลพาร์ทเนอร์พร้อมยอดรวมตลอดเวลา (นับเฉพาะคำสั่งซื้อที่ส่งมอบแล้ว)
+const affiliateSummaryQuery = `
+	SELECT a.id, a.code, a.name, a.contact_email, a.commission_rate, a.active, a.created_at,
+	       (SELECT COUNT(*) FROM affiliate_clicks c WHERE c.affiliate_id = a.id),
+	       (SELECT COUNT(*) FROM users u WHERE u.affiliate_id = a.id),
+	       COUNT(p.id), COALESCE(SUM(p.final_amount), 0), COALESCE(SUM(p.affiliate_commission), 0)
+	FROM affiliates a
+	LEFT JOIN purchases p ON p.affiliate_id = a.id AND p.status = 'fulfilled'
+`
+
+// scanAffiliateSummaries อ่านผลลัพธ์ของ affiliateSummaryQuery
+func scanAffiliateSummaries(rows *sql.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+	affiliates := []map[string]interface{}{}
+	for rows.Next() {
+		var id, clicks, signups, orders int
+		var code, name string
+		var email sql.NullString
+		var rate, revenue, commission float64
+		var active bool
+		var createdAt utils.Timestamp
+		if err := rows.Scan(&id, &code, &name, &email, &rate, &active, &createdAt,
+			&clicks, &signups, &orders, &revenue, &commission); err != nil {
+			return nil, err
+		}
+		affiliates = append(affiliates, map[string]interface{}{
+			"id":              id,
+			"code":            code,
+			"name":            name,
+			"contact_email":   email.String,
+			"commission_rate": rate,
+			"active":          active,
+			"created_at":      createdAt,
+			"clicks":          clicks,
+			"signups":         signups,
+			"orders":          orders,
+			"revenue":         revenue,
+			"commission":      commission,
+		})
+	}
+	return affiliates, rows.Err()
+}
+
+// listAffiliates รายการพาร์ทเนอร์ทั้งหมด (ที่ใช้งานอยู่ก่อน)
+func listAffiliates(w http.ResponseWriter) {
+	rows, err := readQuery(affiliateSummaryQuery + `
+		GROUP BY a.id, a.code, a.name, a.contact_email, a.commission_rate, a.active, a.created_at
+		ORDER BY a.active DESC, a.name
+	`)
+	if err != nil {
+		fmt.Printf("❌ Error fetching affiliates: %v\n", err)
+		utils.JSONError(w, "Error fetching affiliates", http.StatusInternalServerError)
+		return
+	}
+	affiliates, err := scanAffiliateSummaries(rows)
+	if err != nil {
+		fmt.Printf("❌ Error reading affiliates: %v\n", err)
+		utils.JSONError(w, "Error fetching affiliates", http.StatusInternalServerError)
+		return
+	}
+	utils.JSONResponse(w, map[string]interface{}{
+		"affiliates":  affiliates,
+		"count":       len(affiliates),
+		"cookie_days": affiliateCookieDays(),
+	}, http.StatusOK)
+}
+
+// getAffiliate ข้อมูลพาร์ทเนอร์หนึ่งราย
+func getAffiliate(w http.ResponseWriter, affiliateID int) {
+	rows, err := readQuery(affiliateSummaryQuery+`
+		WHERE a.id = ?
+		GROUP BY a.id, a.code, a.name, a.contact_email, a.commission_rate, a.active, a.created_at
+	`, affiliateID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching affiliate %d: %v\n", affiliateID, err)
+		utils.JSONError(w, "Error fetching affiliates", http.StatusInternalServerError)
+		return
+	}
+	affiliates, err := scanAffiliateSummaries(rows)
+	if err != nil {
+		fmt.Printf("❌ Error reading affiliate %d: %v\n", affiliateID, err)
+		utils.JSONError(w, "Error fetching affiliates", http.StatusInternalServerError)
+		return
+	}
+	if len(affiliates) == 0 {
+		utils.JSONError(w, "Affiliate not found", http.StatusNotFound)
+		return
+	}
+	utils.JSONResponse(w, affiliates[0], http.StatusOK)
+}
+
+// decodeAffiliateRequest อ่านและตรวจสอบข้อมูลพาร์ทเนอร์ (คืนค่า false ถ้าส่ง error response ไปแล้ว)
+func decodeAffiliateRequest(w http.ResponseWriter, r *http.Request) (affiliateRequest, bool) {
+	var req affiliateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+	req.ContactEmail = utils.NormalizeEmail(req.ContactEmail)
+
+	errs := utils.Validate(req)
+	if req.Code != "" && !affiliateCodePattern.MatchString(req.Code) {
+		errs = append(errs, utils.FieldError{Field: "code", Rule: "code", Message: "code may only contain letters, numbers, hyphens and underscores"})
+	}
+	if errs != nil {
+		utils.JSONValidationError(w, errs)
+		return req, false
+	}
+	return req, true
+}
+
+// createAffiliate เพิ่มพาร์ทเนอร์ใหม่
+func createAffiliate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAffiliateRequest(w, r)
+	if !ok {
+		return
+	}
+	active := req.Active == nil || *req.Active
+
+	result, err := db.Exec(`
+		INSERT INTO affiliates (code, name, contact_email, commission_rate, active)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?)
+	`, req.Code, req.Name, req.ContactEmail, req.CommissionRate, active)
+	if err != nil {
+		if key, duplicate := duplicateKeyName(err); duplicate && key == "uniq_affiliates_code" {
+			utils.JSONError(w, "Affiliate code already exists", http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error creating affiliate: %v\n", err)
+		utils.JSONError(w, "Error creating affiliate", http.StatusInternalServerError)
+		return
+	}
+	affiliateID, _ := result.LastInsertId()
+
+	recordAudit(r, "affiliate_create", "affiliate", affiliateID, nil, snapshotRow("SELECT * FROM affiliates WHERE id = ?", affiliateID))
+	fmt.Printf("🤝 Affiliate created: %s (%.2f%%)\n", req.Code, req.CommissionRate)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Affiliate created",
+		"id":      affiliateID,
+		"code":    req.Code,
+	}, http.StatusCreated)
+}
+
+// updateAffiliate แก้ไขพาร์ทเนอร์ (อัตราใหม่มีผลกับคำสั่งซื้อหลังจากนี้เท่านั้น)
+func updateAffiliate(w http.ResponseWriter, r *http.Request, affiliateID int) {
+	req, ok := decodeAffiliateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	before := snapshotRow("SELECT * FROM affiliates WHERE id = ?", affiliateID)
+	if before == nil {
+		utils.JSONError(w, "Affiliate not found", http.StatusNotFound)
+		return
+	}
+
+	query := "UPDATE affiliates SET code = ?, name = ?, contact_email = NULLIF(?, ''), commission_rate = ?"
+	args := []interface{}{req.Code, req.Name, req.ContactEmail, req.CommissionRate}
+	if req.Active != nil {
+		query += ", active = ?"
+		args = append(args, *req.Active)
+	}
+	if _, err := db.Exec(query+" WHERE id = ?", append(args, affiliateID)...); err != nil {
+		if key, duplicate := duplicateKeyName(err); duplicate && key == "uniq_affiliates_code" {
+			utils.JSONError(w, "Affiliate code already exists", http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error updating affiliate %d: %v\n", affiliateID, err)
+		utils.JSONError(w, "Error updating affiliate", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "affiliate_update", "affiliate", affiliateID, before, snapshotRow("SELECT * FROM affiliates WHERE id = ?", affiliateID))
+	fmt.Printf("🤝 Affiliate %d updated\n", affiliateID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Affiliate updated",
+		"id":      affiliateID,
+	}, http.StatusOK)
+}
+
+// deactivateAffiliate ปิดใช้งานพาร์ทเนอร์ (ลิงก์ ?ref= เดิมจะไม่ถูกนับ แต่ยอดเดิมยังอยู่ในรายงาน)
+func deactivateAffiliate(w http.ResponseWriter, r *http.Request, affiliateID int) {
+	before := snapshotRow("SELECT * FROM affiliates WHERE id = ?", affiliateID)
+	if before == nil {
+		utils.JSONError(w, "Affiliate not found", http.StatusNotFound)
+		return
+	}
+	if _, err := db.Exec("UPDATE affiliates SET active = 0 WHERE id = ?", affiliateID); err != nil {
+		fmt.Printf("❌ Error deactivating affiliate %d: %v\n", affiliateID, err)
+		utils.JSONError(w, "Error updating affiliate", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "affiliate_deactivate", "affiliate", affiliateID, before, snapshotRow("SELECT * FROM affiliates WHERE id = ?", affiliateID))
+	fmt.Printf("🤝 Affiliate %d deactivated\n", affiliateID)
+	utils.JSONResponse(w, map[string]string{"message": "Affiliate deactivated"}, http.StatusOK)
+}
+
+// affiliateReport รายงานค่าคอมมิชชันของพาร์ทเนอร์แต่ละรายตามช่วงเวลา
+// นับเฉพาะคำสั่งซื้อที่ส่งมอบแล้ว (คำสั่งซื้อที่คืนเงินหรือรอตรวจสอบไม่ได้ค่าคอมมิชชัน)
+func affiliateReport(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseStatsDateRange(r)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "month"
+	}
+	bucketFormat, ok := statsBucketFormats[groupBy]
+	if !ok {
+		utils.JSONError(w, "Invalid group_by. Use day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	filter := ""
+	filterArgs := []interface{}{}
+	if raw := r.URL.Query().Get("affiliate_id"); raw != "" {
+		affiliateID, err := strconv.Atoi(raw)
+		if err != nil || affiliateID <= 0 {
+			utils.JSONError(w, "Invalid affiliate ID", http.StatusBadRequest)
+			return
+		}
+		filter = " AND a.id = ?"
+		filterArgs = append(filterArgs, affiliateID)
+	}
+
+	fmt.Printf("📊 Fetching affiliate report: from=%s to=%s group_by=%s\n",
+		from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"), groupBy)
+
+	ordersQuery := `
+		SELECT ` + dialect.DateFormat("p.purchase_date", bucketFormat) + ` as period,
+		       a.id as affiliate_id, a.code, a.name,
+		       COUNT(*) as orders, COALESCE(SUM(p.final_amount), 0) as revenue,
+		       COALESCE(SUM(p.affiliate_commission), 0) as commission
+		FROM purchases p
+		JOIN affiliates a ON a.id = p.affiliate_id
+		WHERE p.status = 'fulfilled' AND p.purchase_date >= ? AND p.purchase_date < ?` + filter + `
+		GROUP BY period, a.id, a.code, a.name
+		ORDER BY period, commission DESC
+	`
+	ordersArgs := append([]interface{}{from, to}, filterArgs...)
+
+	// ส่งออกเป็น CSV: ยอดขายและค่าคอมมิชชันต่อพาร์ทเนอร์ต่อช่วงเวลา
+	if wantsCSV(r) {
+		loc, err := exportLocation(r)
+		if err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+		streamQueryCSV(w, loc, "affiliate-commissions.csv", ordersQuery, ordersArgs...)
+		return
+	}
+
+	// แถวของรายงาน (key = ช่วงเวลา + พาร์ทเนอร์)
+	type reportKey struct {
+		period      string
+		affiliateID int
+	}
+	entries := map[reportKey]map[string]interface{}{}
+	getEntry := func(period string, affiliateID int, code, name string) map[string]interface{} {
+		key := reportKey{period, affiliateID}
+		if e, ok := entries[key]; ok {
+			return e
+		}
+		e := map[string]interface{}{
+			"period":       period,
+			"affiliate_id": affiliateID,
+			"code":         code,
+			"name":         name,
+			"clicks":       0,
+			"signups":      0,
+			"orders":       0,
+			"revenue":      0.0,
+			"commission":   0.0,
+		}
+		entries[key] = e
+		return e
+	}
+
+	// 1. คำสั่งซื้อ ยอดขาย และค่าคอมมิชชัน
+	rows, err := readQuery(ordersQuery, ordersArgs...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching affiliate orders: %v\n", err)
+		utils.JSONError(w, "Error fetching affiliate report", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var period, code, name string
+		var affiliateID, orders int
+		var revenue, commission float64
+		if err := rows.Scan(&period, &affiliateID, &code, &name, &orders, &revenue, &commission); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning affiliate orders: %v\n", err)
+			utils.JSONError(w, "Error fetching affiliate report", http.StatusInternalServerError)
+			return
+		}
+		e := getEntry(period, affiliateID, code, name)
+		e["orders"], e["revenue"], e["commission"] = orders, revenue, commission
+	}
+	rows.Close()
+
+	// 2. จำนวนคลิกและผู้ใช้ที่สมัครผ่านพาร์ทเนอร์
+	counts := []struct {
+		field, query string
+	}{
+		{"clicks", `
+			SELECT ` + dialect.DateFormat("c.created_at", bucketFormat) + ` as period, a.id, a.code, a.name, COUNT(*)
+			FROM affiliate_clicks c
+			JOIN affiliates a ON a.id = c.affiliate_id
+			WHERE c.created_at >= ? AND c.created_at < ?` + filter + `
+			GROUP BY period, a.id, a.code, a.name
+		`},
+		{"signups", `
+			SELECT ` + dialect.DateFormat("u.created_at", bucketFormat) + ` as period, a.id, a.code, a.name, COUNT(*)
+			FROM users u
+			JOIN affiliates a ON a.id = u.affiliate_id
+			WHERE u.created_at >= ? AND u.created_at < ?` + filter + `
+			GROUP BY period, a.id, a.code, a.name
+		`},
+	}
+	for _, c := range counts {
+		rows, err := readQuery(c.query, ordersArgs...)
+		if err != nil {
+			fmt.Printf("❌ Error fetching affiliate %s: %v\n", c.field, err)
+			utils.JSONError(w, "Error fetching affiliate report", http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			var period, code, name string
+			var affiliateID, count int
+			if err := rows.Scan(&period, &affiliateID, &code, &name, &count); err != nil {
+				rows.Close()
+				fmt.Printf("❌ Error scanning affiliate %s: %v\n", c.field, err)
+				utils.JSONError(w, "Error fetching affiliate report", http.StatusInternalServerError)
+				return
+			}
+			getEntry(period, affiliateID, code, name)[c.field] = count
+		}
+		rows.Close()
+	}
+
+	// เรียงตามช่วงเวลา แล้วตามค่าคอมมิชชันมากไปน้อย และรวมยอดทั้งหมด
+	report := make([]map[string]interface{}, 0, len(entries))
+	var totalOrders int
+	var totalRevenue, totalCommission float64
+	for _, e := range entries {
+		report = append(report, e)
+		totalOrders += e["orders"].(int)
+		totalRevenue += e["revenue"].(float64)
+		totalCommission += e["commission"].(float64)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i]["period"] != report[j]["period"] {
+			return report[i]["period"].(string) < report[j]["period"].(string)
+		}
+		return report[i]["commission"].(float64) > report[j]["commission"].(float64)
+	})
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"group_by": groupBy,
+		"report":   report,
+		"totals": map[string]interface{}{
+			"orders":     totalOrders,
+			"revenue":    roundCents(totalRevenue),
+			"commission": roundCents(totalCommission),
+		},
+		"generated_at": utils.FormatTimestamp(time.Now()),
+	}, http.StatusOK)
+}
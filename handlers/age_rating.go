@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ageRatings เรตติ้งอายุที่รองรับ (ระบบ ESRB และ PEGI) และอายุขั้นต่ำของแต่ละเรต
+var ageRatings = map[string]int{
+	"ESRB E":    0,
+	"ESRB E10+": 10,
+	"ESRB T":    13,
+	"ESRB M":    17,
+	"ESRB AO":   18,
+	"PEGI 3":    3,
+	"PEGI 7":    7,
+	"PEGI 12":   12,
+	"PEGI 16":   16,
+	"PEGI 18":   18,
+}
+
+// ageRatingNone ค่าที่ใช้ลบเรตติ้งของเกม (ผ่านการแก้ไขเกม)
+const ageRatingNone = "none"
+
+// normalizeAgeRating แปลงเรตติ้งให้อยู่ในรูปแบบมาตรฐาน (เช่น "pegi 18" → "PEGI 18") และคืนอายุขั้นต่ำ
+func normalizeAgeRating(raw string) (string, int, bool) {
+	rating := strings.ToUpper(strings.Join(strings.Fields(raw), " "))
+	minAge, ok := ageRatings[rating]
+	return rating, minAge, ok
+}
+
+// parseContentDescriptors แยกคำอธิบายเนื้อหา (เช่น "Violence, Blood") ตัดช่องว่างและค่าว่างออก
+func parseContentDescriptors(raw []string) string {
+	var descriptors []string
+	for _, value := range raw {
+		for _, d := range strings.Split(value, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				descriptors = append(descriptors, d)
+			}
+		}
+	}
+	return strings.Join(descriptors, ", ")
+}
+
+// addAgeRatingFields เพิ่มข้อมูลเรตติ้งอายุให้ object เกมใน catalog
+func addAgeRatingFields(game map[string]interface{}, rating, descriptors sql.NullString, minAge int) {
+	game["age_rating"] = nil
+	if rating.Valid && rating.String != "" {
+		game["age_rating"] = rating.String
+	}
+	game["min_age"] = minAge
+	game["content_descriptors"] = []string{}
+	if descriptors.Valid && descriptors.String != "" {
+		game["content_descriptors"] = strings.Split(descriptors.String, ", ")
+	}
+}
+
+// ageFilter เงื่อนไขกรองเกมตามอายุของผู้ชม (?max_age=13 แสดงเฉพาะเกมที่อายุขั้นต่ำไม่เกิน 13 ปี)
+// ใช้ query parameter แทนข้อมูลผู้ใช้ เพื่อให้ผลลัพธ์ยังเก็บใน cache ของ catalog ได้
+func ageFilter(r *http.Request) (string, []interface{}, error) {
+	raw := r.URL.Query().Get("max_age")
+	if raw == "" {
+		return "", nil, nil
+	}
+	maxAge, err := strconv.Atoi(raw)
+	if err != nil || maxAge < 0 {
+		return "", nil, fmt.Errorf("invalid max_age. Use a non-negative number of years")
+	}
+	return " AND g.min_age <= ?", []interface{}{maxAge}, nil
+}
+
+// ageOn คำนวณอายุ (ปีเต็ม) ณ วันที่ที่กำหนด
+func ageOn(birthdate, now time.Time) int {
+	age := now.Year() - birthdate.Year()
+	if now.Month() < birthdate.Month() || (now.Month() == birthdate.Month() && now.Day() < birthdate.Day()) {
+		age--
+	}
+	return age
+}
+
+// userAge ดึงอายุของผู้ใช้จากวันเกิด คืนค่า false ถ้าผู้ใช้ยังไม่ได้ระบุวันเกิด
+func userAge(exec sqlExecutor, userID int) (int, bool, error) {
+	var birthdate sql.NullString
+	err := exec.QueryRow("SELECT "+dialect.DateFormat("birthdate", "%Y-%m-%d")+" FROM users WHERE id = ?", userID).Scan(&birthdate)
+	if err != nil || !birthdate.Valid {
+		return 0, false, err
+	}
+	parsed, err := time.Parse("2006-01-02", birthdate.String)
+	if err != nil {
+		return 0, false, err
+	}
+	return ageOn(parsed, time.Now()), true, nil
+}
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader header ที่ใช้ส่งคีย์ API ของนักพัฒนา
+const APIKeyHeader = "X-API-Key"
+
+// scope ของคีย์ API (อ่านอย่างเดียวทั้งหมด)
+const (
+	ScopeReadCatalog = "read:catalog" // รายการเกม หมวดหมู่ ค้นหา และอันดับ
+	ScopeReadLibrary = "read:library" // คลังเกมของเจ้าของคีย์
+)
+
+// apiKeyScopes scope ทั้งหมดที่ออกคีย์ได้
+var apiKeyScopes = map[string]bool{
+	ScopeReadCatalog: true,
+	ScopeReadLibrary: true,
+}
+
+// apiKeyPrefix คำนำหน้าของคีย์ (ช่วยให้ระบบสแกน secret ที่หลุดจำคีย์ได้)
+const apiKeyPrefix = "gk_"
+
+// maxAPIKeysPerUser จำนวนคีย์ที่ยังใช้งานได้สูงสุดต่อผู้ใช้
+const maxAPIKeysPerUser = 10
+
+// apiKeyUsageDays จำนวนวันของสถิติการใช้งานที่แสดงใน GET /profile/api-keys/{id}
+const apiKeyUsageDays = 30
+
+// defaultAPIKeyDailyQuota จำนวน request ต่อวันของคีย์ (ปรับได้ด้วย API_KEY_DAILY_QUOTA)
+const defaultAPIKeyDailyQuota = 1000
+
+// apiKeyMaxDailyQuota โควตาสูงสุดที่ผู้ใช้ตั้งให้คีย์ได้
+func apiKeyMaxDailyQuota() int {
+	if n, err := strconv.Atoi(os.Getenv("API_KEY_DAILY_QUOTA")); err == nil && n > 0 {
+		return n
+	}
+	return defaultAPIKeyDailyQuota
+}
+
+// hashAPIKey เก็บเฉพาะ SHA-256 ของคีย์ในฐานข้อมูล (คีย์จริงแสดงครั้งเดียวตอนสร้าง)
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyContains ตรวจสอบว่า scope อยู่ในรายการ scope ของคีย์ (คั่นด้วยช่องว่าง)
+func apiKeyContains(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAPIKey ตรวจสอบคีย์ scope และโควตารายวัน (วันตาม UTC) แล้วแนบตัวตนของเจ้าของคีย์ไปกับ request
+// คืนค่า false ถ้าส่ง error response ไปแล้ว
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, key, scope string) (*http.Request, bool) {
+	var keyID, userID, quota int
+	var scopes, username, role string
+	var stale bool
+	err := db.QueryRow(`
+		SELECT k.id, k.user_id, k.scopes, k.daily_quota, u.username, u.role,
+		       (k.last_used_at IS NULL OR k.last_used_at < DATE_SUB(NOW(), INTERVAL ? SECOND))
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL
+	`, int(sessionTouchInterval.Seconds()), hashAPIKey(key)).Scan(&keyID, &userID, &scopes, &quota, &username, &role, &stale)
+	if err == sql.ErrNoRows {
+		utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid API key", http.StatusUnauthorized)
+		return r, false
+	}
+	if err != nil {
+		fmt.Printf("❌ Error checking API key: %v\n", err)
+		utils.JSONError(w, "Unable to verify API key", http.StatusInternalServerError)
+		return r, false
+	}
+
+	// ทุก scope เป็นแบบอ่านอย่างเดียว
+	if r.Method != "GET" && r.Method != "HEAD" {
+		utils.JSONError(w, "API keys are read-only", http.StatusForbidden)
+		return r, false
+	}
+	if !apiKeyContains(scopes, scope) {
+		utils.JSONErrorDetails(w, utils.ErrForbidden, "API key is missing the required scope",
+			map[string]string{"required_scope": scope}, http.StatusForbidden)
+		return r, false
+	}
+
+	// นับ request ของวันนี้ (request ที่เกินโควตาก็ถูกนับด้วย)
+	now := time.Now().UTC()
+	today := now.Format("2006-01-02")
+	var used int
+	if _, err := db.Exec(`
+		INSERT INTO api_key_usage (api_key_id, usage_date, requests) VALUES (?, ?, 1)
+		`+dialect.OnConflict("api_key_id", "usage_date")+` requests = api_key_usage.requests + 1
+	`, keyID, today); err != nil {
+		fmt.Printf("❌ Error recording API key usage: %v\n", err)
+		utils.JSONError(w, "Unable to verify API key", http.StatusInternalServerError)
+		return r, false
+	}
+	if err := db.QueryRow("SELECT requests FROM api_key_usage WHERE api_key_id = ? AND usage_date = ?", keyID, today).Scan(&used); err != nil {
+		fmt.Printf("❌ Error reading API key usage: %v\n", err)
+		utils.JSONError(w, "Unable to verify API key", http.StatusInternalServerError)
+		return r, false
+	}
+
+	reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(quota-used, 0)))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if used > quota {
+		w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+		utils.JSONErrorCode(w, utils.ErrTooManyRequests, "API key daily quota exceeded", http.StatusTooManyRequests)
+		return r, false
+	}
+
+	if stale {
+		if _, err := db.Exec("UPDATE api_keys SET last_used_at = NOW() WHERE id = ?", keyID); err != nil {
+			fmt.Printf("⚠️ Error updating API key last used: %v\n", err)
+		}
+	}
+
+	return withIdentity(r, Identity{
+		UserID:   userID,
+		Username: username,
+		Role:     role,
+		APIKeyID: keyID,
+	}), true
+}
+
+// APIKeyAuth middleware accepts an API key with the given scope in place of a login token
+// Middleware สำหรับ endpoint ที่ต้องยืนยันตัวตน: ใช้คีย์ API (X-API-Key) ที่มี scope ตามที่กำหนดแทน token ได้
+// request ที่ไม่มีคีย์จะตรวจสอบด้วย AuthMiddleware ตามปกติ
+func APIKeyAuth(scope string, next http.Handler) http.Handler {
+	withToken := AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			withToken.ServeHTTP(w, r)
+			return
+		}
+		if r, ok := authenticateAPIKey(w, r, key, scope); ok {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// APIKeyOptional middleware tracks API key quota on public endpoints
+// Middleware สำหรับ endpoint สาธารณะ: request ที่แนบคีย์ API ต้องเป็นคีย์ที่ถูกต้องและมี scope (นับโควตา)
+// request ที่ไม่มีคีย์ทำงานตามปกติ
+func APIKeyOptional(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r, ok := authenticateAPIKey(w, r, key, scope); ok {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// ProfileAPIKeysHandler handles developer API keys
+// ฟังก์ชันสำหรับจัดการคีย์ API ของนักพัฒนา (ใช้กับเครื่องมือภายนอกโดยไม่ต้องให้รหัสผ่าน)
+// GET    /profile/api-keys      - รายการคีย์ที่ยังใช้งานได้ พร้อมจำนวน request วันนี้
+// POST   /profile/api-keys      - สร้างคีย์ {"name", "scopes": ["read:catalog", "read:library"], "daily_quota"} (แสดงคีย์ครั้งเดียว)
+// GET    /profile/api-keys/{id} - ข้อมูลคีย์และจำนวน request รายวัน apiKeyUsageDays วันล่าสุด
+// DELETE /profile/api-keys/{id} - เพิกถอนคีย์
+func ProfileAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /profile/api-keys/4 → ["profile", "api-keys", "4"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && r.Method == "GET":
+		listAPIKeys(w, userID)
+	case len(pathParts) == 2 && r.Method == "POST":
+		createAPIKey(w, r, userID)
+	case len(pathParts) == 3:
+		keyID, err := strconv.Atoi(pathParts[2])
+		if err != nil || keyID <= 0 {
+			utils.JSONError(w, "Invalid API key ID", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			getAPIKey(w, userID, keyID)
+		case "DELETE":
+			revokeAPIKey(w, r, userID, keyID)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(pathParts) == 2:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// apiKeySelect คอลัมน์ของคีย์พร้อมจำนวน request วันนี้ (ใช้กับ scanAPIKey)
+const apiKeySelect = `
+	SELECT k.id, k.name, k.key_prefix, k.scopes, k.daily_quota, COALESCE(u.requests, 0),
+	       k.last_used_at, k.created_at
+	FROM api_keys k
+	LEFT JOIN api_key_usage u ON u.api_key_id = k.id AND u.usage_date = ?
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey อ่านคีย์หนึ่งแถวจาก apiKeySelect
+func scanAPIKey(row rowScanner) (map[string]interface{}, error) {
+	var id, quota, usedToday int
+	var name, prefix, scopes string
+	var lastUsedAt utils.NullTimestamp
+	var createdAt utils.Timestamp
+	if err := row.Scan(&id, &name, &prefix, &scopes, &quota, &usedToday, &lastUsedAt, &createdAt); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":           id,
+		"name":         name,
+		"prefix":       prefix,
+		"scopes":       strings.Fields(scopes),
+		"daily_quota":  quota,
+		"used_today":   usedToday,
+		"last_used_at": lastUsedAt,
+		"created_at":   createdAt,
+	}, nil
+}
+
+// GET /profile/api-keys - คีย์ที่ยังไม่ถูกเพิกถอน (ใหม่สุดก่อน)
+func listAPIKeys(w http.ResponseWriter, userID int) {
+	rows, err := db.Query(apiKeySelect+`
+		WHERE k.user_id = ? AND k.revoked_at IS NULL
+		ORDER BY k.created_at DESC, k.id DESC
+	`, time.Now().UTC().Format("2006-01-02"), userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching API keys: %v\n", err)
+		utils.JSONError(w, "Error fetching API keys", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	keys := []map[string]interface{}{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			fmt.Printf("❌ Error scanning API key row: %v\n", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"api_keys":             keys,
+		"count":                len(keys),
+		"max_keys":             maxAPIKeysPerUser,
+		"max_daily_quota":      apiKeyMaxDailyQuota(),
+		"available_scopes":     []string{ScopeReadCatalog, ScopeReadLibrary},
+		"quota_reset_timezone": "UTC",
+	}, http.StatusOK)
+}
+
+// GET /profile/api-keys/{id} - ข้อมูลคีย์และการใช้งานรายวัน
+func getAPIKey(w http.ResponseWriter, userID, keyID int) {
+	key, err := scanAPIKey(db.QueryRow(apiKeySelect+`
+		WHERE k.id = ? AND k.user_id = ? AND k.revoked_at IS NULL
+	`, time.Now().UTC().Format("2006-01-02"), keyID, userID))
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error fetching API key %d: %v\n", keyID, err)
+		utils.JSONError(w, "Error fetching API keys", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT usage_date, requests FROM api_key_usage
+		WHERE api_key_id = ? AND usage_date >= ?
+		ORDER BY usage_date
+	`, keyID, time.Now().UTC().AddDate(0, 0, -(apiKeyUsageDays-1)).Format("2006-01-02"))
+	if err != nil {
+		fmt.Printf("❌ Error fetching API key usage: %v\n", err)
+		utils.JSONError(w, "Error fetching API keys", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	usage := []map[string]interface{}{}
+	for rows.Next() {
+		var day time.Time
+		var requests int
+		if err := rows.Scan(&day, &requests); err != nil {
+			fmt.Printf("❌ Error scanning API key usage: %v\n", err)
+			utils.JSONError(w, "Error fetching API keys", http.StatusInternalServerError)
+			return
+		}
+		usage = append(usage, map[string]interface{}{
+			"date":     day.Format("2006-01-02"),
+			"requests": requests,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading API key usage rows: %v\n", err)
+		utils.JSONError(w, "Error fetching API keys", http.StatusInternalServerError)
+		return
+	}
+	key["usage"] = usage
+
+	utils.JSONResponse(w, key, http.StatusOK)
+}
+
+// POST /profile/api-keys - สร้างคีย์ใหม่
+func createAPIKey(w http.ResponseWriter, r *http.Request, userID int) {
+	var req struct {
+		Name       string   `json:"name" validate:"required,max=100"`
+		Scopes     []string `json:"scopes"`
+		DailyQuota int      `json:"daily_quota" validate:"min=0"` // 0 = โควตาสูงสุด (API_KEY_DAILY_QUOTA)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	errs := utils.Validate(req)
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if !apiKeyScopes[scope] {
+			errs = append(errs, utils.FieldError{Field: "scopes", Rule: "oneof", Message: "scopes must be read:catalog or read:library"})
+			break
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, utils.FieldError{Field: "scopes", Rule: "required", Message: "scopes is required"})
+	}
+	maxQuota := apiKeyMaxDailyQuota()
+	if req.DailyQuota > maxQuota {
+		errs = append(errs, utils.FieldError{Field: "daily_quota", Rule: "max", Message: fmt.Sprintf("daily_quota must be at most %d", maxQuota)})
+	}
+	if errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	if req.DailyQuota == 0 {
+		req.DailyQuota = maxQuota
+	}
+
+	var active int
+	if err := db.QueryRow("SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked_at IS NULL", userID).Scan(&active); err != nil {
+		fmt.Printf("❌ Error counting API keys: %v\n", err)
+		utils.JSONError(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	if active >= maxAPIKeysPerUser {
+		utils.JSONError(w, fmt.Sprintf("You can have at most %d API keys", maxAPIKeysPerUser), http.StatusConflict)
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		utils.JSONError(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	prefix := key[:len(apiKeyPrefix)+8]
+
+	result, err := db.Exec(`
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, daily_quota)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, req.Name, prefix, hashAPIKey(key), strings.Join(scopes, " "), req.DailyQuota)
+	if err != nil {
+		fmt.Printf("❌ Error creating API key: %v\n", err)
+		utils.JSONError(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	keyID, _ := result.LastInsertId()
+
+	recordAccountActivity(r, userID, ActivityAPIKeyCreated, fmt.Sprintf("%s (%s)", prefix, strings.Join(scopes, " ")))
+	fmt.Printf("🔑 API key %d created by user %d: %v\n", keyID, userID, scopes)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "API key created. Copy it now, it will not be shown again",
+		"id":          keyID,
+		"name":        req.Name,
+		"key":         key,
+		"prefix":      prefix,
+		"scopes":      scopes,
+		"daily_quota": req.DailyQuota,
+	}, http.StatusCreated)
+}
+
+// DELETE /profile/api-keys/{id} - เพิกถอนคีย์ (มีผลทันที)
+func revokeAPIKey(w http.ResponseWriter, r *http.Request, userID, keyID int) {
+	result, err := db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, keyID, userID)
+	if err != nil {
+		fmt.Printf("❌ Error revoking API key: %v\n", err)
+		utils.JSONError(w, "Error revoking API key", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.JSONError(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	recordAccountActivity(r, userID, ActivityAPIKeyRevoked, fmt.Sprintf("api key %d", keyID))
+	fmt.Printf("🔑 API key %d revoked by user %d\n", keyID, userID)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "API key revoked",
+		"id":      keyID,
+	}, http.StatusOK)
+}
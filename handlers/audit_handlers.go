@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// trustProxy เชื่อ X-Forwarded-For หรือไม่ (TRUST_PROXY=true เมื่อเซิร์ฟเวอร์อยู่หลัง reverse proxy ของเรา)
+// มีผลกับ IP ของทุก request ไม่ใช่เฉพาะ /admin/* ส่วน ADMIN_TRUST_PROXY เป็นชื่อเดิมที่ยังใช้ได้เมื่อไม่ได้ตั้ง TRUST_PROXY
+func trustProxy() bool {
+	value, ok := os.LookupEnv("TRUST_PROXY")
+	if !ok {
+		value = os.Getenv("ADMIN_TRUST_PROXY")
+	}
+	return value == "true"
+}
+
+// sourceIP IP ของผู้เรียกจาก hop ที่เชื่อถือได้ (ใช้ทั้งใน clientIP และการตรวจ ADMIN_ALLOWED_CIDRS)
+// ค่าเริ่มต้นใช้ IP ของ connection เท่านั้น เพราะ X-Forwarded-For ผู้เรียกตั้งเองได้
+// เมื่อตั้ง TRUST_PROXY=true จะใช้ IP สุดท้ายใน X-Forwarded-For (ที่ proxy ของเราเพิ่มเข้ามา)
+func sourceIP(r *http.Request) net.IP {
+	if trustProxy() {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return net.ParseIP(strings.TrimSpace(hops[len(hops)-1]))
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// clientIP ดึง IP ของผู้เรียก (ใช้ใน audit log, การประเมินความเสี่ยง, rate limit, session และประวัติการใช้งาน)
+// ไม่ใช้ค่าแรกของ X-Forwarded-For เพราะผู้เรียกตั้งเองได้ (หลบการตรวจ IP ซ้ำ หรือใส่ IP ของคนอื่น)
+func clientIP(r *http.Request) string {
+	if ip := sourceIP(r); ip != nil {
+		return ip.String()
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// snapshotRow ดึงข้อมูลหนึ่งแถวเป็น map (ใช้เก็บสถานะก่อน/หลังการแก้ไขใน audit log)
+// คืนค่า nil ถ้าไม่พบข้อมูล
+func snapshotRow(query string, args ...interface{}) map[string]interface{} {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		fmt.Printf("⚠️ Error taking audit snapshot: %v\n", err)
+		return nil
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil
+	}
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		fmt.Printf("⚠️ Error scanning audit snapshot: %v\n", err)
+		return nil
+	}
+
+	snapshot := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		// ไม่เก็บข้อมูลลับลงใน audit log
+		if column == "password_hash" {
+			continue
+		}
+		if values[i].Valid {
+			snapshot[column] = values[i].String
+		} else {
+			snapshot[column] = nil
+		}
+	}
+	return snapshot
+}
+
+// auditDiff คืนค่าเฉพาะฟิลด์ที่เปลี่ยนแปลงระหว่าง before และ after
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	diff := map[string]interface{}{}
+	for key, newValue := range after {
+		oldValue := before[key]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = map[string]interface{}{"from": oldValue, "to": newValue}
+		}
+	}
+	for key, oldValue := range before {
+		if _, ok := after[key]; !ok {
+			diff[key] = map[string]interface{}{"from": oldValue, "to": nil}
+		}
+	}
+	return diff
+}
+
+// recordAudit บันทึกการเปลี่ยนแปลงข้อมูลโดยผู้ดูแลระบบลงใน admin_audit_log
+// การบันทึกที่ล้มเหลวจะไม่กระทบผลลัพธ์ของ request (แค่แสดง log)
+func recordAudit(r *http.Request, action, targetType string, targetID interface{}, before, after map[string]interface{}) {
+	actorID, _ := requestUserID(r)
+	actorName := requestUsername(r)
+
+	// แปลงข้อมูลเป็น JSON (nil จะถูกเก็บเป็น NULL)
+	toJSON := func(v map[string]interface{}) interface{} {
+		if v == nil {
+			return nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return string(b)
+	}
+
+	var diff map[string]interface{}
+	if before != nil || after != nil {
+		diff = auditDiff(before, after)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO admin_audit_log
+		(actor_id, actor_username, action, target_type, target_id, before_data, after_data, diff, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, actorID, actorName, action, targetType, fmt.Sprint(targetID), toJSON(before), toJSON(after), toJSON(diff), clientIP(r))
+	if err != nil {
+		fmt.Printf("⚠️ Error recording audit log: %v\n", err)
+		return
+	}
+
+	fmt.Printf("📝 Audit: %s %s %s #%v by %s\n", actorName, action, targetType, targetID, clientIP(r))
+}
+
+// AdminAuditHandler handles listing the admin audit log
+// ฟังก์ชันสำหรับดึงประวัติการเปลี่ยนแปลงข้อมูลโดยผู้ดูแลระบบ
+// GET /admin/audit?actor_id=&action=&target_type=&target_id=&from=&to=&limit=&offset=|cursor=
+func AdminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := parsePage(query, 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// สร้างเงื่อนไขการกรอง
+	whereClauses := []string{}
+	args := []interface{}{}
+
+	if actorID := query.Get("actor_id"); actorID != "" {
+		whereClauses = append(whereClauses, "actor_id = ?")
+		args = append(args, actorID)
+	}
+	if action := query.Get("action"); action != "" {
+		whereClauses = append(whereClauses, "action = ?")
+		args = append(args, action)
+	}
+	if targetType := query.Get("target_type"); targetType != "" {
+		whereClauses = append(whereClauses, "target_type = ?")
+		args = append(args, targetType)
+	}
+	if targetID := query.Get("target_id"); targetID != "" {
+		whereClauses = append(whereClauses, "target_id = ?")
+		args = append(args, targetID)
+	}
+	if from := query.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			utils.JSONError(w, "Invalid from date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		whereClauses = append(whereClauses, "created_at >= ?")
+		args = append(args, date)
+	}
+	if to := query.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			utils.JSONError(w, "Invalid to date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		whereClauses = append(whereClauses, "created_at < ?")
+		args = append(args, date.AddDate(0, 0, 1))
+	}
+
+	whereSQL := ""
+	if len(whereClauses) > 0 {
+		whereSQL = " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+
+	// ดึงจำนวนทั้งหมดสำหรับ pagination
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM admin_audit_log"+whereSQL, args...).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting audit log: %v\n", err)
+		utils.JSONError(w, "Error fetching audit log", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, actor_id, actor_username, action, target_type, target_id,
+		       before_data, after_data, diff, ip_address, created_at
+		FROM admin_audit_log`+whereSQL+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching audit log: %v\n", err)
+		utils.JSONError(w, "Error fetching audit log", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// แปลง JSON ที่เก็บไว้กลับเป็น object
+	parseJSON := func(v sql.NullString) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		var out interface{}
+		if err := json.Unmarshal([]byte(v.String), &out); err != nil {
+			return v.String
+		}
+		return out
+	}
+
+	entries := []map[string]interface{}{}
+	for rows.Next() {
+		var id, actorID int
+		var actorName, action, targetType, targetID string
+		var createdAt utils.Timestamp
+		var before, after, diff, ip sql.NullString
+
+		if err := rows.Scan(&id, &actorID, &actorName, &action, &targetType, &targetID, &before, &after, &diff, &ip, &createdAt); err != nil {
+			fmt.Printf("❌ Error scanning audit row: %v\n", err)
+			utils.JSONError(w, "Error fetching audit log", http.StatusInternalServerError)
+			return
+		}
+
+		entries = append(entries, map[string]interface{}{
+			"id":             id,
+			"actor_id":       actorID,
+			"actor_username": actorName,
+			"action":         action,
+			"target_type":    targetType,
+			"target_id":      targetID,
+			"before":         parseJSON(before),
+			"after":          parseJSON(after),
+			"diff":           parseJSON(diff),
+			"ip_address":     ip.String,
+			"created_at":     createdAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading audit rows: %v\n", err)
+		utils.JSONError(w, "Error fetching audit log", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, paginatedResponse(entries, total, page), http.StatusOK)
+}
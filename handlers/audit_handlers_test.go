@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClientIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy string
+		forwarded  string
+		want       string
+	}{
+		{"no proxy header", "", "", "192.0.2.10"},
+		{"untrusted header ignored", "", "203.0.113.5", "192.0.2.10"},
+		{"trusted proxy uses last hop", "true", "203.0.113.5, 198.51.100.7", "198.51.100.7"},
+		{"trusted proxy without header", "true", "", "192.0.2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", tt.trustProxy)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "192.0.2.10:51234"
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TRUST_PROXY มีผลกับ request ทั่วไปด้วย (เช่นประวัติกิจกรรมของบัญชี) และ ADMIN_TRUST_PROXY ยังใช้ได้เมื่อไม่ได้ตั้ง TRUST_PROXY
+func TestTrustProxyAppliesToNonAdminRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"TRUST_PROXY", map[string]string{"TRUST_PROXY": "true"}, "198.51.100.7"},
+		{"ADMIN_TRUST_PROXY alias", map[string]string{"ADMIN_TRUST_PROXY": "true"}, "198.51.100.7"},
+		{"TRUST_PROXY overrides alias", map[string]string{"TRUST_PROXY": "false", "ADMIN_TRUST_PROXY": "true"}, "192.0.2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// เริ่มจากไม่ได้ตั้งทั้งสองตัวแปร (t.Setenv คืนค่าเดิมให้หลังจบ test)
+			t.Setenv("TRUST_PROXY", "")
+			os.Unsetenv("TRUST_PROXY")
+			t.Setenv("ADMIN_TRUST_PROXY", "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			mock := useMockDB(t)
+			mock.ExpectExec("INSERT INTO account_activity").
+				WithArgs(7, ActivityLogin, tt.want, sqlmock.AnyArg(), nil).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			r := httptest.NewRequest("POST", "/login", nil)
+			r.RemoteAddr = "192.0.2.10:51234"
+			r.Header.Set("X-Forwarded-For", "198.51.100.7")
+			recordAccountActivity(r, 7, ActivityLogin, "")
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// รายการ audit log และการแจ้งเตือนใช้รูปแบบ response และการตรวจ ?limit= เดียวกับ endpoint รายการอื่น
+func TestAuditAndNotificationListsArePaginated(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT COUNT").WithArgs("role.update").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("FROM admin_audit_log").WithArgs("role.update", 1, 1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "actor_id", "actor_username", "action", "target_type", "target_id",
+			"before_data", "after_data", "diff", "ip_address", "created_at"}).
+			AddRow(2, 1, "admin", "role.update", "user", "9", nil, nil, `{"role":"admin"}`, "192.0.2.10", time.Now()))
+	w := httptest.NewRecorder()
+	AdminAuditHandler(w, httptest.NewRequest("GET", "/admin/audit?action=role.update&limit=1&offset=1", nil))
+
+	var page struct {
+		Data       []map[string]interface{} `json:"data"`
+		Total      int                      `json:"total"`
+		Limit      int                      `json:"limit"`
+		Offset     int                      `json:"offset"`
+		NextCursor *string                  `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("audit response = %d %s", w.Code, w.Body)
+	}
+	if len(page.Data) != 1 || page.Total != 3 || page.Limit != 1 || page.Offset != 1 || page.NextCursor == nil {
+		t.Errorf("audit page = %+v", page)
+	}
+
+	mock.ExpectQuery("FROM notifications").WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"total", "unread"}).AddRow(1, 1))
+	mock.ExpectQuery("FROM notifications").WithArgs(7, 20, 0).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "type", "title", "message", "data", "read_at", "created_at"}).
+			AddRow(5, "system", "Hello", nil, nil, nil, time.Now()))
+	w = httptest.NewRecorder()
+	getNotifications(w, httptest.NewRequest("GET", "/notifications", nil), 7)
+	if !strings.Contains(w.Body.String(), `"next_cursor":null`) || !strings.Contains(w.Body.String(), `"unread_count":1`) {
+		t.Errorf("notifications response = %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	getNotifications(w, httptest.NewRequest("GET", "/notifications?limit=abc", nil), 7)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit status = %d, want 400", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
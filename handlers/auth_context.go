@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"go-api-game/tracing"
+	"net/http"
+)
+
+// contextKey ชนิดของ key ใน context (ป้องกันการชนกับ key ของ package อื่น)
+type contextKey int
+
+const (
+	identityContextKey  contextKey = iota
+	accessLogContextKey            // *accessLogEntry ของ request (ดู AccessLog)
+)
+
+// identityHeaders header ที่ handler รุ่นเก่าเคยใช้ส่งตัวตนผู้ใช้ ต้องไม่เชื่อค่าที่มาจาก client
+var identityHeaders = []string{"User-ID", "Username", "Role", "Token-ID"}
+
+// Identity ข้อมูลผู้ใช้ที่ยืนยันตัวตนแล้ว (ตั้งโดย AuthMiddleware)
+type Identity struct {
+	UserID   int
+	Username string
+	Role     string
+	TokenID  string // รหัส session ของ token (ว่างสำหรับ token รุ่นเก่า)
+	APIKeyID int    // คีย์ API ที่ใช้เรียก (0 = เข้าสู่ระบบด้วย token)
+}
+
+// withIdentity แนบตัวตนของผู้ใช้ไปกับ request
+func withIdentity(r *http.Request, identity Identity) *http.Request {
+	noteAccessLogUser(r, identity.UserID)
+	tracing.FromContext(r.Context()).SetAttribute("enduser.id", identity.UserID)
+	return r.WithContext(context.WithValue(r.Context(), identityContextKey, identity))
+}
+
+// IdentityFrom ดึงตัวตนของผู้ใช้จาก context (ok เป็น false ถ้า request ไม่ได้ผ่าน AuthMiddleware)
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey).(Identity)
+	return identity, ok
+}
+
+// requestUserID ID ของผู้ใช้ที่ยืนยันตัวตนแล้ว
+func requestUserID(r *http.Request) (int, bool) {
+	identity, ok := IdentityFrom(r.Context())
+	return identity.UserID, ok
+}
+
+// requestUsername ชื่อผู้ใช้ที่ยืนยันตัวตนแล้ว (ว่างถ้าไม่ได้ยืนยันตัวตน)
+func requestUsername(r *http.Request) string {
+	identity, _ := IdentityFrom(r.Context())
+	return identity.Username
+}
+
+// requestRole บทบาทของผู้ใช้ที่ยืนยันตัวตนแล้ว (ว่างถ้าไม่ได้ยืนยันตัวตน)
+func requestRole(r *http.Request) string {
+	identity, _ := IdentityFrom(r.Context())
+	return identity.Role
+}
+
+// requestTokenID รหัส session ของ token ที่ใช้เรียก
+func requestTokenID(r *http.Request) string {
+	identity, _ := IdentityFrom(r.Context())
+	return identity.TokenID
+}
+
+// StripIdentityHeaders middleware removes client-supplied identity headers
+// Middleware สำหรับลบ header ตัวตน (User-ID, Role, ...) ที่ client ส่งมาเอง ก่อนถึง handler ใดๆ
+func StripIdentityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range identityHeaders {
+			r.Header.Del(header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,107 +2,63 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"go-api-game/auth"
-	"go-api-game/config"
+	"go-api-game/mailer"
+	"go-api-game/storage"
 	"go-api-game/utils"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// saveAvatar handles avatar upload to Cloudinary with fallback to local storage
-func saveAvatar(file io.Reader, header *multipart.FileHeader, userID int) (string, error) {
-	// Read file bytes
-	fileBytes, err := io.ReadAll(file)
+// saveAvatar handles avatar upload to the configured storage backend with fallback to local storage
+func saveAvatar(ctx context.Context, file io.Reader, header *multipart.FileHeader, userID int) (string, error) {
+	// Read and validate file bytes (type is detected from content, not the filename)
+	fileBytes, ext, err := readImageUpload(file)
 	if err != nil {
-		return "", fmt.Errorf("error reading avatar file: %v", err)
+		return "", err
 	}
 
-	// Generate unique filename with user ID
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext == "" {
-		ext = ".dat"
-	}
-	filename := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().UnixNano(), ext)
-
-	// Try Cloudinary first
-	if config.Cld != nil {
-		imageURL, err := config.UploadImageFromBytes(fileBytes, filename)
-		if err != nil {
-			fmt.Printf("❌ Cloudinary upload failed, using local storage: %v\n", err)
-			// Fallback to local storage
-			return saveAvatarToLocalStorage(fileBytes, filename)
-		}
-		fmt.Printf("✅ Avatar uploaded to Cloudinary: %s\n", imageURL)
-		return imageURL, nil
-	}
-
-	// Use local storage if Cloudinary not configured
-	return saveAvatarToLocalStorage(fileBytes, filename)
-}
-
-// saveAvatarToLocalStorage saves avatar to local file system
-func saveAvatarToLocalStorage(fileBytes []byte, filename string) (string, error) {
-	// Create uploads directory if not exists
-	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
-		os.Mkdir("uploads", 0755)
-	}
-
-	filePath := filepath.Join("uploads", filename)
+	// ตั้งชื่อไฟล์ตามเนื้อหา (รูปใหม่ได้ URL ใหม่ จึงไม่ติด cache ของรูปเก่า)
+	filename := contentHashName(fmt.Sprintf("avatar_%d_", userID), fileBytes, ext)
 
-	err := os.WriteFile(filePath, fileBytes, 0644)
+	url, err := storage.SaveContext(ctx, filename, fileBytes)
 	if err != nil {
-		return "", fmt.Errorf("error saving avatar locally: %v", err)
+		return "", err
 	}
 
-	localURL := "/uploads/" + filename
-	fmt.Printf("✅ Avatar saved locally: %s\n", localURL)
-	return localURL, nil
+	// สร้างภาพย่อสำหรับหน้ารายการและอุปกรณ์มือถือ
+	createImageVariants(ctx, url, fileBytes, filename)
+	return url, nil
 }
 
-// deleteAvatar handles avatar deletion from both Cloudinary and local storage
+// deleteAvatar handles avatar deletion from whichever storage backend holds the avatar
+// รูปโปรไฟล์เริ่มต้นและรูปสำเร็จรูปใช้ร่วมกันทุกคน จึงไม่ลบ
+// ไฟล์ตั้งชื่อตามเนื้อหา รูปเดียวกันจึงอาจเป็นของหลายบัญชี (ลบเมื่อไม่มีบัญชีใดใช้แล้วเท่านั้น)
 func deleteAvatar(avatarURL string) error {
-	if avatarURL == "" {
+	if isDefaultAvatar(avatarURL) {
 		return nil
 	}
-
-	// Check if it's a Cloudinary URL
-	if strings.Contains(avatarURL, "cloudinary.com") {
-		// Delete from Cloudinary
-		err := config.DeleteImage(avatarURL)
-		if err != nil {
-			return fmt.Errorf("error deleting Cloudinary avatar: %v", err)
-		}
-		fmt.Printf("🗑️ Deleted Cloudinary avatar: %s\n", avatarURL)
-	} else {
-		// Delete from local storage
-		filePath := strings.TrimPrefix(avatarURL, "/")
-		if _, err := os.Stat(filePath); err == nil {
-			err := os.Remove(filePath)
-			if err != nil {
-				return fmt.Errorf("error deleting local avatar: %v", err)
-			}
-			fmt.Printf("🗑️ Deleted local avatar: %s\n", filePath)
-		}
+	var usedBy int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE avatar_url = ?", avatarURL).Scan(&usedBy); err != nil || usedBy > 0 {
+		return err
 	}
-	return nil
+	deleteImageVariants(avatarURL)
+	return storage.Remove(avatarURL)
 }
 
 // RegisterHandler handles user registration
 // ฟังก์ชันสำหรับการลงทะเบียนผู้ใช้ใหม่
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("🔍 Register Handler - Method: %s, Content-Type: %s\n", r.Method, r.Header.Get("Content-Type"))
-
 	// ตรวจสอบว่าเป็นเมธอด POST หรือไม่
 	if r.Method != "POST" {
 		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -111,9 +67,11 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Username string `json:"username" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+		// รูปโปรไฟล์สำเร็จรูปที่เลือก (ดู GET /avatars/defaults) ใช้เมื่อไม่ได้อัพโหลดรูป
+		DefaultAvatar string `json:"default_avatar"`
 	}
 	var avatarURL string // ตัวแปรเก็บ URL ของภาพ avatar
 
@@ -135,6 +93,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		req.Username = r.FormValue("username")
 		req.Email = r.FormValue("email")
 		req.Password = r.FormValue("password")
+		req.DefaultAvatar = r.FormValue("default_avatar")
 
 		// จัดการกับการอัพโหลดไฟล์ avatar
 		file, header, err := r.FormFile("avatar")
@@ -143,15 +102,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 			// ใช้ฟังก์ชันใหม่สำหรับอัพโหลด avatar (userID จะถูกกำหนดภายหลัง)
 			// ใช้ 0 เป็น temporary userID
-			avatarURL, err = saveAvatar(file, header, 0)
+			avatarURL, err = saveAvatar(r.Context(), file, header, 0)
 			if err != nil {
-				utils.JSONError(w, "Error uploading avatar: "+err.Error(), http.StatusInternalServerError)
+				utils.JSONError(w, "Error uploading avatar: "+err.Error(), uploadErrorStatus(err))
 				return
 			}
 		} else {
-			// ไม่มีไฟล์ avatar ส่งมา → ใช้ default avatar
-			avatarURL = "/uploads/default-avatar.png"
-			fmt.Printf("📝 No avatar uploaded, using default: %s\n", avatarURL)
+			fmt.Printf("📝 No avatar uploaded, using built-in avatar\n")
 		}
 
 		fmt.Printf("🔍 Form data - Username: %s, Email: %s, Password: %s, Avatar: %s\n",
@@ -180,8 +137,6 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// สำหรับ JSON request → ใช้ default avatar
-		avatarURL = "/uploads/default-avatar.png"
 		fmt.Printf("🔍 JSON data - Username: %s, Email: %s, Password: %s, Avatar: %s\n",
 			req.Username, req.Email, "***", avatarURL)
 	} else {
@@ -189,109 +144,117 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ตรวจสอบความถูกต้องของข้อมูลที่จำเป็น
-	if req.Username == "" || req.Email == "" || req.Password == "" {
-		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้าข้อมูลไม่ครบ
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
-			deleteAvatar(avatarURL)
+	// ไม่ได้อัพโหลดรูป → ใช้รูปสำเร็จรูปที่เลือก หรือเลือกให้จากชื่อผู้ใช้ (ผู้ใช้ใหม่จึงไม่ได้รูปเดียวกันทั้งหมด)
+	if avatarURL == "" {
+		if req.DefaultAvatar != "" {
+			avatar, ok := findBuiltinAvatar(req.DefaultAvatar)
+			if !ok {
+				utils.JSONError(w, "Avatar not found", http.StatusBadRequest)
+				return
+			}
+			avatarURL = avatar.URL()
+		} else {
+			avatarURL = defaultAvatarFor(strings.ToLower(req.Username))
 		}
-		utils.JSONError(w, "Username, email and password are required", http.StatusBadRequest)
-		return
 	}
 
-	// ตรวจสอบรูปแบบอีเมล
-	if !isValidEmail(req.Email) {
-		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้าอีเมลไม่ถูกต้อง
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
-			deleteAvatar(avatarURL)
+	// ทำให้อีเมลและชื่อผู้ใช้อยู่ในรูปแบบเดียวกันก่อนตรวจสอบและบันทึก
+	req.Email = utils.NormalizeEmail(req.Email)
+	req.Username = utils.NormalizeUsername(req.Username)
+
+	// ตรวจสอบความถูกต้องของข้อมูลตาม tag validate และนโยบายชื่อผู้ใช้/รหัสผ่าน
+	errs := utils.Validate(req)
+	if req.Username != "" {
+		if err := utils.ValidateUsername(req.Username); err != nil {
+			errs = append(errs, utils.FieldError{Field: "username", Rule: "username_policy", Message: err.Error()})
 		}
-		utils.JSONError(w, "Invalid email format", http.StatusBadRequest)
-		return
 	}
-
-	// ตรวจสอบความแข็งแรงของรหัสผ่าน
-	if len(req.Password) < 6 {
-		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้ารหัสผ่านสั้นเกินไป
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
-			deleteAvatar(avatarURL)
+	if req.Password != "" {
+		if err := utils.CheckPasswordPolicy(req.Password, req.Username, req.Email); err != nil {
+			errs = append(errs, utils.FieldError{Field: "password", Rule: "password_policy", Message: err.Error()})
 		}
-		utils.JSONError(w, "Password must be at least 6 characters", http.StatusBadRequest)
-		return
 	}
-
-	// ตรวจสอบว่าชื่อผู้ใช้หรืออีเมลมีอยู่แล้วหรือไม่
-	var count int
-	err := db.QueryRow(`
-        SELECT COUNT(*) 
-        FROM users 
-        WHERE username = ? OR email = ?
-    `, req.Username, req.Email).Scan(&count)
-
-	if err != nil {
-		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้ามีข้อผิดพลาด
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
+	if errs != nil {
+		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้าข้อมูลไม่ถูกต้อง
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
 			deleteAvatar(avatarURL)
 		}
-		utils.JSONError(w, "Error checking user existence", http.StatusInternalServerError)
+		utils.JSONValidationError(w, errs)
 		return
 	}
 
-	if count > 0 {
-		// ตรวจสอบว่าฟิลด์ใดซ้ำ
-		var existingUsername, existingEmail string
-		db.QueryRow(`
-            SELECT username, email 
-            FROM users 
-            WHERE username = ? OR email = ?
-            LIMIT 1
-        `, req.Username, req.Email).Scan(&existingUsername, &existingEmail)
-
-		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้าชื่อผู้ใช้หรืออีเมลซ้ำ
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
+	// ตรวจสอบว่าโดเมนของอีเมลรับอีเมลได้ (เมื่อเปิด EMAIL_MX_CHECK)
+	if !utils.EmailDomainAcceptsMail(req.Email) {
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
 			deleteAvatar(avatarURL)
 		}
-
-		if existingUsername == req.Username {
-			utils.JSONError(w, "Username already exists", http.StatusBadRequest)
-			return
-		}
-		if existingEmail == req.Email {
-			utils.JSONError(w, "Email already exists", http.StatusBadRequest)
-			return
-		}
+		utils.JSONErrorCode(w, utils.ErrValidation, "Email domain does not accept mail", http.StatusBadRequest)
+		return
 	}
 
 	// Hash รหัสผ่าน
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		// ลบไฟล์ avatar ที่อัพโหลดไว้ถ้า hash รหัสผ่านล้มเหลว
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
 			deleteAvatar(avatarURL)
 		}
 		utils.JSONError(w, "Error processing password", http.StatusInternalServerError)
 		return
 	}
 
+	// สร้างผู้ใช้และตะกร้าใน transaction เดียว ความซ้ำของชื่อผู้ใช้/อีเมลตรวจสอบจาก unique index
+	// (ไม่ตรวจสอบด้วย COUNT ก่อน INSERT เพราะการสมัครพร้อมกันจะผ่านการตรวจสอบได้ทั้งคู่)
+	tx, err := db.Begin()
+	if err != nil {
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
+			deleteAvatar(avatarURL)
+		}
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
 	// เพิ่มผู้ใช้ใหม่ลงฐานข้อมูล พร้อม avatar_url
-	result, err := db.Exec(`
-        INSERT INTO users (username, email, password_hash, role, avatar_url) 
-        VALUES (?, ?, ?, 'user', ?)
-    `, req.Username, req.Email, string(hashedPassword), avatarURL)
+	result, err := tx.Exec(`
+        INSERT INTO users (username, username_key, email, password_hash, role, avatar_url, avatar_status, avatar_updated_at, affiliate_id) 
+        VALUES (?, ?, ?, ?, 'user', ?, ?, NOW(), ?)
+    `, req.Username, utils.UsernameKey(req.Username), req.Email, string(hashedPassword), avatarURL, avatarStatusFor(avatarURL), signupAffiliateID(r))
 
 	if err != nil {
 		// ลบไฟล์ที่อัพโหลดไว้ถ้าเพิ่มข้อมูลในฐานข้อมูลล้มเหลว (เฉพาะไฟล์ที่อัปโหลดใหม่)
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
 			deleteAvatar(avatarURL)
 		}
-		utils.JSONError(w, "Error creating user: "+err.Error(), http.StatusInternalServerError)
+		if key, duplicate := duplicateKeyName(err); duplicate {
+			writeDuplicateUserError(w, key)
+			return
+		}
+		fmt.Printf("❌ Error creating user: %v\n", err)
+		utils.JSONError(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
 	// ดึง ID ของผู้ใช้ที่เพิ่งเพิ่ม
 	userID, _ := result.LastInsertId()
 
+	// สร้างตะกร้าสินค้าสำหรับผู้ใช้
+	_, err = tx.Exec("INSERT INTO carts (user_id) VALUES (?)", userID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		// ลบไฟล์ที่อัพโหลดไว้ถ้าสร้างตะกร้าล้มเหลว (เฉพาะไฟล์ที่อัปโหลดใหม่)
+		if avatarURL != "" && !isDefaultAvatar(avatarURL) {
+			deleteAvatar(avatarURL)
+		}
+		fmt.Printf("❌ Error creating cart: %v\n", err)
+		utils.JSONError(w, "Error creating cart", http.StatusInternalServerError)
+		return
+	}
+
 	// ถ้า avatar ถูกอัพโหลดและ userID ถูกกำหนดแล้ว ให้อัพเดทชื่อไฟล์
-	if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" && strings.Contains(avatarURL, "avatar_0_") {
+	if avatarURL != "" && !isDefaultAvatar(avatarURL) && strings.Contains(avatarURL, "avatar_0_") {
 		// สร้างชื่อไฟล์ใหม่ด้วย userID ที่ถูกต้อง
 		newFilename := fmt.Sprintf("avatar_%d_%s", userID, strings.Split(filepath.Base(avatarURL), "_")[2])
 		newAvatarURL := "/uploads/" + newFilename
@@ -309,20 +272,19 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// สร้างตะกร้าสินค้าสำหรับผู้ใช้
-	_, err = db.Exec("INSERT INTO carts (user_id) VALUES (?)", userID)
-	if err != nil {
-		// ลบไฟล์ที่อัพโหลดไว้ถ้าสร้างตะกร้าล้มเหลว (เฉพาะไฟล์ที่อัปโหลดใหม่)
-		if avatarURL != "" && avatarURL != "/uploads/default-avatar.png" {
-			deleteAvatar(avatarURL)
-		}
-		utils.JSONError(w, "Error creating cart", http.StatusInternalServerError)
-		return
-	}
-
 	fmt.Printf("✅ User registered successfully: ID=%d, Username=%s, Avatar: %s\n",
 		userID, req.Username, avatarURL)
 
+	emitWebhookEvent(WebhookUserRegistered, map[string]interface{}{
+		"user_id":  userID,
+		"username": req.Username,
+	})
+
+	// ส่งอีเมลต้อนรับ (async)
+	if err := mailer.SendTemplate(req.Email, mailer.TemplateWelcome, map[string]interface{}{"Username": req.Username}); err != nil {
+		fmt.Printf("⚠️ Error queueing welcome email: %v\n", err)
+	}
+
 	// ส่ง response กลับไปพร้อม avatar_url
 	response := map[string]interface{}{
 		"message":    "User registered successfully",
@@ -335,6 +297,18 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	utils.JSONResponse(w, response, http.StatusCreated)
 }
 
+// writeDuplicateUserError ตอบกลับ 409 ตามฟิลด์ที่ซ้ำ (จากชื่อ unique index ของตาราง users)
+func writeDuplicateUserError(w http.ResponseWriter, key string) {
+	switch key {
+	case "uniq_users_username", "uniq_users_username_key", "username":
+		utils.JSONErrorCode(w, utils.ErrUsernameTaken, "Username already exists", http.StatusConflict)
+	case "uniq_users_email", "email":
+		utils.JSONErrorCode(w, utils.ErrEmailTaken, "Email already exists", http.StatusConflict)
+	default:
+		utils.JSONErrorCode(w, utils.ErrConflict, "Username or email already exists", http.StatusConflict)
+	}
+}
+
 // LoginHandler handles user login with identifier (username or email)
 // ฟังก์ชันสำหรับการเข้าสู่ระบบด้วยชื่อผู้ใช้หรืออีเมล
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -348,6 +322,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Identifier string `json:"identifier"` // ชื่อผู้ใช้หรืออีเมล
 		Password   string `json:"password"`   // รหัสผ่าน
+		UseCookie  bool   `json:"use_cookie"` // เก็บ token ใน HttpOnly cookie แทนการใช้ Bearer token
 	}
 
 	// แปลง JSON request body เป็น struct
@@ -373,14 +348,14 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		SELECT id, username, email, password_hash, role, COALESCE(avatar_url, '') 
 		FROM users 
 		WHERE username = ? OR email = ?
-	`, req.Identifier, req.Identifier).Scan(
+	`, utils.NormalizeUsername(req.Identifier), req.Identifier).Scan(
 		&userID, &username, &email, &passwordHash, &role, &avatarURL,
 	)
 
 	if err != nil {
 		fmt.Printf("❌ Database error: %v\n", err)
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Invalid identifier or password", http.StatusUnauthorized)
+			utils.JSONErrorCode(w, utils.ErrInvalidCredentials, "Invalid identifier or password", http.StatusUnauthorized)
 		} else {
 			utils.JSONError(w, "Error during login: "+err.Error(), http.StatusInternalServerError)
 		}
@@ -394,23 +369,25 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password))
 	if err != nil {
 		fmt.Printf("❌ Password mismatch: %v\n", err)
-		utils.JSONError(w, "Invalid identifier or password", http.StatusUnauthorized)
+		recordAccountActivity(r, userID, ActivityLoginFailed, "")
+		utils.JSONErrorCode(w, utils.ErrInvalidCredentials, "Invalid identifier or password", http.StatusUnauthorized)
 		return
 	}
 
 	fmt.Printf("✅ Password correct!\n")
 
 	// สร้าง JWT token
-	token, err := auth.GenerateToken(userID, username, email, role)
+	token, err := createSession(r, userID, username, email, role)
 	if err != nil {
+		fmt.Printf("❌ Error creating session: %v\n", err)
 		utils.JSONError(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
 	fmt.Printf("🎉 Login successful for user: %s, role: %s\n", username, role)
+	recordAccountActivity(r, userID, ActivityLogin, "")
 
-	// ส่ง response การเข้าสู่ระบบสำเร็จ
-	utils.JSONResponse(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"message":    "Login successful",
 		"user_id":    userID,
 		"username":   username,
@@ -418,30 +395,36 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		"role":       role,
 		"avatar_url": avatarURL,
 		"token":      token,
-	}, http.StatusOK)
+	}
+
+	// โหมด cookie: ตั้ง session cookie และ CSRF token (ไม่ส่ง token กลับให้ JavaScript เห็น)
+	if req.UseCookie {
+		csrfToken, err := setAuthCookies(w, token)
+		if err != nil {
+			utils.JSONError(w, "Error generating CSRF token", http.StatusInternalServerError)
+			return
+		}
+		delete(response, "token")
+		response["csrf_token"] = csrfToken
+	}
+
+	// ส่ง response การเข้าสู่ระบบสำเร็จ
+	utils.JSONResponse(w, response, http.StatusOK)
 }
 
 // ProfileHandler handles user profile
 // ฟังก์ชันสำหรับดึงข้อมูลโปรไฟล์ผู้ใช้
 func ProfileHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
-	userIDStr := r.Header.Get("User-ID")
-
-	fmt.Printf("🔍 Profile request - User-ID header: '%s'\n", userIDStr)
-
-	// ตรวจสอบว่ามี User-ID หรือไม่
-	if userIDStr == "" {
-		utils.JSONError(w, "User ID not found in headers", http.StatusUnauthorized)
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
 		return
 	}
 
-	// แปลง User-ID เป็นตัวเลข
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		fmt.Printf("❌ Invalid User-ID format: %s\n", userIDStr)
-		utils.JSONError(w, "Invalid user ID format", http.StatusBadRequest)
-		return
-	}
+	fmt.Printf("🔍 Profile request - User-ID: %d\n", userID)
+
+	var err error
 
 	fmt.Printf("🔍 Querying database for user ID: %d\n", userID)
 
@@ -449,21 +432,23 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	var id int
 	var username, email string
 	var avatarURL sql.NullString // ใช้ NullString เพราะ avatar_url อาจเป็น NULL
+	var birthdate sql.NullString
 	var walletBalance float64
 
 	// ดึงข้อมูลผู้ใช้จากฐานข้อมูล
 	err = db.QueryRow(`
-		SELECT id, username, email, avatar_url, wallet_balance 
+		SELECT id, username, email, avatar_url, wallet_balance,
+		       `+dialect.DateFormat("birthdate", "%Y-%m-%d")+`
 		FROM users 
 		WHERE id = ?
-	`, userID).Scan(&id, &username, &email, &avatarURL, &walletBalance)
+	`, userID).Scan(&id, &username, &email, &avatarURL, &walletBalance, &birthdate)
 
 	if err != nil {
 		fmt.Printf("❌ Database error in ProfileHandler: %v\n", err)
 		fmt.Printf("❌ SQL Error details: %v\n", err)
 
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "User not found in database", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found in database", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		}
@@ -480,6 +465,12 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 		"email":          email,
 		"wallet_balance": walletBalance,
 		"avatar_url":     "", // ค่า default ถ้าไม่มี avatar
+		"birthdate":      nil,
+	}
+
+	// วันเกิด (ใช้ตรวจสอบอายุเมื่อซื้อเกมที่มีเรตติ้ง)
+	if birthdate.Valid {
+		profile["birthdate"] = birthdate.String
 	}
 
 	// ตั้งค่า avatar_url ถ้ามีค่า
@@ -500,23 +491,16 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
-
-	fmt.Printf("🔍 Update profile request for user ID: %s\n", userID)
-
-	// ตรวจสอบว่ามี User-ID หรือไม่
-	if userID == "" {
+	// ดึง User-ID จาก context
+	userIDInt, ok := requestUserID(r)
+	if !ok {
 		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
 		return
 	}
 
-	// แปลง User-ID เป็นตัวเลข
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
+	fmt.Printf("🔍 Update profile request for user ID: %d\n", userIDInt)
+
+	var err error
 
 	// ตรวจสอบ Content-Type
 	contentType := r.Header.Get("Content-Type")
@@ -526,12 +510,15 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		CurrentPassword string `json:"current_password"` // รหัสผ่านปัจจุบัน (สำหรับการเปลี่ยนรหัสผ่าน)
 		NewPassword     string `json:"new_password"`     // รหัสผ่านใหม่
 		ConfirmPassword string `json:"confirm_password"` // ยืนยันรหัสผ่านใหม่
+		Birthdate       string `json:"birthdate"`        // วันเกิด YYYY-MM-DD (ตั้งได้ครั้งเดียว)
+		DefaultAvatar   string `json:"default_avatar"`   // เปลี่ยนเป็นรูปโปรไฟล์สำเร็จรูป (ดู GET /avatars/defaults)
 	}
 	var avatarURL string
 
-	// ดึง avatar URL เดิมก่อนทำการอัพเดท
-	var oldAvatarURL sql.NullString
-	db.QueryRow("SELECT avatar_url FROM users WHERE id = ?", userIDInt).Scan(&oldAvatarURL)
+	// ดึง avatar URL อีเมล ชื่อผู้ใช้ และวันเกิดเดิมก่อนทำการอัพเดท
+	var oldAvatarURL, oldBirthdate sql.NullString
+	var oldEmail, oldUsername string
+	db.QueryRow("SELECT avatar_url, email, username, birthdate FROM users WHERE id = ?", userIDInt).Scan(&oldAvatarURL, &oldEmail, &oldUsername, &oldBirthdate)
 
 	// กรณีส่งข้อมูลแบบ Form-data (มีการอัพโหลดไฟล์ avatar)
 	if strings.Contains(contentType, "multipart/form-data") {
@@ -547,6 +534,8 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		req.CurrentPassword = r.FormValue("current_password")
 		req.NewPassword = r.FormValue("new_password")
 		req.ConfirmPassword = r.FormValue("confirm_password")
+		req.Birthdate = r.FormValue("birthdate")
+		req.DefaultAvatar = r.FormValue("default_avatar")
 
 		// จัดการกับการอัพโหลดไฟล์ avatar
 		file, header, err := r.FormFile("avatar")
@@ -554,9 +543,9 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 			defer file.Close()
 
 			// ใช้ฟังก์ชันใหม่สำหรับอัพโหลด avatar
-			avatarURL, err = saveAvatar(file, header, userIDInt)
+			avatarURL, err = saveAvatar(r.Context(), file, header, userIDInt)
 			if err != nil {
-				utils.JSONError(w, "Error uploading avatar: "+err.Error(), http.StatusInternalServerError)
+				utils.JSONError(w, "Error uploading avatar: "+err.Error(), uploadErrorStatus(err))
 				return
 			}
 		}
@@ -568,8 +557,22 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// เลือกรูปโปรไฟล์สำเร็จรูป (ใช้เมื่อไม่ได้อัพโหลดรูปมาด้วย)
+	if avatarURL == "" && req.DefaultAvatar != "" {
+		avatar, ok := findBuiltinAvatar(req.DefaultAvatar)
+		if !ok {
+			utils.JSONError(w, "Avatar not found", http.StatusBadRequest)
+			return
+		}
+		avatarURL = avatar.URL()
+	}
+
+	// ทำให้อีเมลและชื่อผู้ใช้อยู่ในรูปแบบเดียวกันก่อนตรวจสอบและบันทึก
+	req.Email = utils.NormalizeEmail(req.Email)
+	req.Username = utils.NormalizeUsername(req.Username)
+
 	// Validate input - ตรวจสอบว่ามี field ใดๆ ที่จะอัพเดตหรือไม่
-	if req.Username == "" && req.Email == "" && avatarURL == "" && req.NewPassword == "" {
+	if req.Username == "" && req.Email == "" && avatarURL == "" && req.NewPassword == "" && req.Birthdate == "" {
 		// ลบไฟล์ avatar ใหม่ถ้าไม่มี field ใดๆ ที่จะอัพเดท
 		if avatarURL != "" {
 			deleteAvatar(avatarURL)
@@ -578,6 +581,17 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ตรวจสอบชื่อผู้ใช้ตามนโยบายถ้ามีการเปลี่ยน (ชื่อเดิมที่ตั้งไว้ก่อนมีนโยบายยังใช้ต่อได้)
+	if req.Username != "" && req.Username != oldUsername {
+		if err := utils.ValidateUsername(req.Username); err != nil {
+			if avatarURL != "" {
+				deleteAvatar(avatarURL)
+			}
+			utils.JSONValidationError(w, utils.ValidationErrors{{Field: "username", Rule: "username_policy", Message: err.Error()}})
+			return
+		}
+	}
+
 	// ตรวจสอบรูปแบบอีเมลถ้ามีการส่งมา
 	if req.Email != "" && !isValidEmail(req.Email) {
 		// ลบไฟล์ avatar ใหม่ถ้าอีเมลไม่ถูกต้อง
@@ -587,6 +601,33 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		utils.JSONError(w, "Invalid email format", http.StatusBadRequest)
 		return
 	}
+	if req.Email != "" && !utils.EmailDomainAcceptsMail(req.Email) {
+		if avatarURL != "" {
+			deleteAvatar(avatarURL)
+		}
+		utils.JSONErrorCode(w, utils.ErrValidation, "Email domain does not accept mail", http.StatusBadRequest)
+		return
+	}
+
+	// ตรวจสอบวันเกิด (ตั้งได้ครั้งเดียว เพื่อไม่ให้เปลี่ยนเพื่อหลบการจำกัดอายุ การแก้ไขต้องติดต่อผู้ดูแลระบบ)
+	var birthdate time.Time
+	if req.Birthdate != "" {
+		birthdate, err = time.Parse("2006-01-02", req.Birthdate)
+		if err != nil || birthdate.After(time.Now()) || birthdate.Year() < 1900 {
+			if avatarURL != "" {
+				deleteAvatar(avatarURL)
+			}
+			utils.JSONValidationError(w, utils.ValidationErrors{{Field: "birthdate", Rule: "date", Message: "birthdate must be a past date in YYYY-MM-DD format"}})
+			return
+		}
+		if oldBirthdate.Valid {
+			if avatarURL != "" {
+				deleteAvatar(avatarURL)
+			}
+			utils.JSONErrorCode(w, utils.ErrConflict, "Birthdate has already been set", http.StatusConflict)
+			return
+		}
+	}
 
 	// ตรวจสอบการเปลี่ยนรหัสผ่านถ้ามีการส่งรหัสผ่านใหม่มา
 	if req.NewPassword != "" {
@@ -617,12 +658,20 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if len(req.NewPassword) < 6 {
-			// ลบไฟล์ avatar ใหม่ถ้ารหัสผ่านสั้นเกินไป
+		// ตรวจสอบรหัสผ่านใหม่ตามนโยบาย (เทียบกับชื่อผู้ใช้/อีเมลใหม่ถ้ามีการเปลี่ยนพร้อมกัน)
+		policyUsername, policyEmail := oldUsername, oldEmail
+		if req.Username != "" {
+			policyUsername = req.Username
+		}
+		if req.Email != "" {
+			policyEmail = req.Email
+		}
+		if err := utils.CheckPasswordPolicy(req.NewPassword, policyUsername, policyEmail); err != nil {
+			// ลบไฟล์ avatar ใหม่ถ้ารหัสผ่านไม่ผ่านนโยบาย
 			if avatarURL != "" {
 				deleteAvatar(avatarURL)
 			}
-			utils.JSONError(w, "New password must be at least 6 characters", http.StatusBadRequest)
+			utils.JSONValidationError(w, utils.ValidationErrors{{Field: "new_password", Rule: "password_policy", Message: err.Error()}})
 			return
 		}
 
@@ -636,26 +685,32 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// ตรวจสอบว่าชื่อผู้ใช้หรืออีเมลใหม่มีอยู่แล้วหรือไม่ (ถ้ามีการส่งมา)
+	// ตรวจสอบว่าชื่อผู้ใช้หรืออีเมลใหม่มีอยู่แล้วหรือไม่ (ถ้ามีการส่งมา) ชื่อผู้ใช้เทียบด้วย username_key
 	if req.Username != "" || req.Email != "" {
 		var existingUser string
+		usernameKey := utils.UsernameKey(req.Username)
 		checkQuery := `
 			SELECT 
 				CASE 
-					WHEN username = ? AND id != ? THEN 'username'
+					WHEN username_key = ? AND id != ? THEN 'username'
 					WHEN email = ? AND id != ? THEN 'email'
 				END as existing_field
 			FROM users 
-			WHERE (username = ? OR email = ?) AND id != ?
+			WHERE (username_key = ? OR email = ?) AND id != ?
+			LIMIT 1
 		`
-		err := db.QueryRow(checkQuery, req.Username, userIDInt, req.Email, userIDInt, req.Username, req.Email, userIDInt).Scan(&existingUser)
+		err := db.QueryRow(checkQuery, usernameKey, userIDInt, req.Email, userIDInt, usernameKey, req.Email, userIDInt).Scan(&existingUser)
 
 		if err == nil && existingUser != "" {
 			// ลบไฟล์ avatar ใหม่ถ้าชื่อผู้ใช้หรืออีเมลซ้ำ
 			if avatarURL != "" {
 				deleteAvatar(avatarURL)
 			}
-			utils.JSONError(w, fmt.Sprintf("%s already exists", existingUser), http.StatusBadRequest)
+			code := utils.ErrEmailTaken
+			if existingUser == "username" {
+				code = utils.ErrUsernameTaken
+			}
+			utils.JSONErrorCode(w, code, fmt.Sprintf("%s already exists", existingUser), http.StatusConflict)
 			return
 		} else if err != nil && err != sql.ErrNoRows {
 			// ลบไฟล์ avatar ใหม่ถ้ามีข้อผิดพลาด
@@ -679,7 +734,7 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 				if avatarURL != "" {
 					deleteAvatar(avatarURL)
 				}
-				utils.JSONError(w, "User not found", http.StatusNotFound)
+				utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
 			} else {
 				// ลบไฟล์ avatar ใหม่ถ้ามีข้อผิดพลาด
 				if avatarURL != "" {
@@ -698,7 +753,7 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 			if avatarURL != "" {
 				deleteAvatar(avatarURL)
 			}
-			utils.JSONError(w, "Current password is incorrect", http.StatusUnauthorized)
+			utils.JSONErrorCode(w, utils.ErrInvalidCredentials, "Current password is incorrect", http.StatusUnauthorized)
 			return
 		}
 
@@ -721,8 +776,8 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 	// ตรวจสอบแต่ละฟิลด์และเพิ่มลงใน query ถ้ามีค่า
 	if req.Username != "" {
-		updateFields = append(updateFields, "username = ?")
-		args = append(args, req.Username)
+		updateFields = append(updateFields, "username = ?", "username_key = ?")
+		args = append(args, req.Username, utils.UsernameKey(req.Username))
 	}
 
 	if req.Email != "" {
@@ -731,8 +786,9 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if avatarURL != "" {
-		updateFields = append(updateFields, "avatar_url = ?")
-		args = append(args, avatarURL)
+		// รูปที่อัพโหลดใหม่เข้าคิวตรวจสอบ (/admin/avatars)
+		updateFields = append(updateFields, "avatar_url = ?", "avatar_status = ?", "avatar_updated_at = NOW()")
+		args = append(args, avatarURL, avatarStatusFor(avatarURL))
 	}
 
 	if newPasswordHash != "" {
@@ -740,6 +796,11 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		args = append(args, newPasswordHash)
 	}
 
+	if req.Birthdate != "" {
+		updateFields = append(updateFields, "birthdate = ?")
+		args = append(args, birthdate.Format("2006-01-02"))
+	}
+
 	// ตรวจสอบว่ามีฟิลด์ที่จะอัพเดทหรือไม่
 	if len(updateFields) == 0 {
 		// ลบไฟล์ avatar ใหม่ถ้าไม่มี field ที่จะอัพเดท
@@ -762,6 +823,11 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		if avatarURL != "" {
 			deleteAvatar(avatarURL)
 		}
+		// ผู้ใช้อื่นอาจได้ชื่อผู้ใช้/อีเมลนี้ไประหว่างการตรวจสอบกับการบันทึก
+		if key, duplicate := duplicateKeyName(err); duplicate {
+			writeDuplicateUserError(w, key)
+			return
+		}
 		utils.JSONError(w, "Error updating profile: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -778,7 +844,7 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ลบไฟล์ avatar เก่าถ้ามีการอัพโหลด avatar ใหม่
-	if avatarURL != "" && oldAvatarURL.Valid && oldAvatarURL.String != "" && oldAvatarURL.String != "/uploads/default-avatar.png" {
+	if avatarURL != "" && oldAvatarURL.Valid && oldAvatarURL.String != "" && !isDefaultAvatar(oldAvatarURL.String) {
 		err := deleteAvatar(oldAvatarURL.String)
 		if err != nil {
 			fmt.Printf("⚠️ Error deleting old avatar: %v\n", err)
@@ -789,6 +855,14 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Printf("✅ Profile updated successfully for user ID: %d\n", userIDInt)
 
+	// บันทึกการเปลี่ยนแปลงที่เกี่ยวกับความปลอดภัยของบัญชี
+	if newPasswordHash != "" {
+		recordAccountActivity(r, userIDInt, ActivityPasswordChanged, "")
+	}
+	if req.Email != "" && !strings.EqualFold(req.Email, oldEmail) {
+		recordAccountActivity(r, userIDInt, ActivityEmailChanged, fmt.Sprintf("%s → %s", oldEmail, req.Email))
+	}
+
 	// ดึงข้อมูลผู้ใช้ที่อัพเดทแล้วเพื่อส่งกลับ
 	var updatedUser struct {
 		ID       int     `json:"id"`
@@ -834,22 +908,5 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 // isValidEmail checks if email format is valid
 // ฟังก์ชันสำหรับตรวจสอบความถูกต้องของรูปแบบอีเมล
 func isValidEmail(email string) bool {
-	// การตรวจสอบอีเมลอย่างง่าย
-	if len(email) < 3 || len(email) > 254 {
-		return false
-	}
-
-	// ตรวจสอบว่ามี @ หรือไม่
-	at := strings.Index(email, "@")
-	if at == -1 || at == 0 || at == len(email)-1 {
-		return false
-	}
-
-	// ตรวจสอบว่ามี . หลัง @ หรือไม่
-	dot := strings.LastIndex(email[at:], ".")
-	if dot == -1 || dot == 0 || dot == len(email[at:])-1 {
-		return false
-	}
-
-	return true
+	return utils.IsValidEmail(email)
 }
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// legacyDefaultAvatar รูปโปรไฟล์เริ่มต้นเดิม (ผู้ใช้เก่ายังใช้อยู่)
+const legacyDefaultAvatar = "/uploads/default-avatar.png"
+
+// builtinAvatarPrefix path ของรูปโปรไฟล์สำเร็จรูป (สร้างเป็น SVG โดย DefaultAvatarsHandler ไม่ได้อยู่ใน storage)
+const builtinAvatarPrefix = "/avatars/defaults/"
+
+// สถานะการตรวจสอบรูปโปรไฟล์ที่ผู้ใช้อัพโหลด (รูปแสดงได้ทันที admin ตรวจสอบภายหลังใน /admin/avatars)
+const (
+	AvatarStatusPending  = "pending"
+	AvatarStatusApproved = "approved"
+)
+
+// builtinAvatar รูปโปรไฟล์สำเร็จรูปที่ผู้ใช้เลือกได้แทนการอัพโหลด
+type builtinAvatar struct {
+	ID         string `json:"id"`
+	Label      string `json:"label"`
+	background string
+	foreground string
+	shape      string // element SVG ของรูปทรง (ใช้สี foreground)
+}
+
+// builtinAvatars รูปโปรไฟล์สำเร็จรูปทั้งหมด (ลำดับคงที่ ใช้เลือกรูปเริ่มต้นของผู้ใช้ใหม่)
+var builtinAvatars = []builtinAvatar{
+	{"circle-blue", "Blue circle", "#2563eb", "#dbeafe", `<circle cx="64" cy="64" r="36"/>`},
+	{"square-green", "Green square", "#16a34a", "#dcfce7", `<rect x="30" y="30" width="68" height="68" rx="8"/>`},
+	{"triangle-orange", "Orange triangle", "#ea580c", "#ffedd5", `<polygon points="64,24 104,100 24,100"/>`},
+	{"diamond-purple", "Purple diamond", "#7c3aed", "#ede9fe", `<polygon points="64,20 108,64 64,108 20,64"/>`},
+	{"hexagon-teal", "Teal hexagon", "#0d9488", "#ccfbf1", `<polygon points="64,22 100,43 100,85 64,106 28,85 28,43"/>`},
+	{"star-red", "Red star", "#dc2626", "#fee2e2", `<polygon points="64,18 76,50 110,50 83,70 93,104 64,84 35,104 45,70 18,50 52,50"/>`},
+	{"ring-pink", "Pink ring", "#db2777", "#fce7f3", `<circle cx="64" cy="64" r="34" fill="none" stroke-width="14" stroke="currentColor"/>`},
+	{"cross-slate", "Slate cross", "#475569", "#f1f5f9", `<path d="M52 24h24v28h28v24H76v28H52V76H24V52h28z"/>`},
+}
+
+func (a builtinAvatar) URL() string {
+	return builtinAvatarPrefix + a.ID + ".svg"
+}
+
+func (a builtinAvatar) svg() string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 128 128" width="128" height="128" color="%s" fill="%s">`+
+		`<rect width="128" height="128" fill="%s"/>%s</svg>`, a.foreground, a.foreground, a.background, a.shape)
+}
+
+// findBuiltinAvatar ค้นหารูปโปรไฟล์สำเร็จรูปจาก id
+func findBuiltinAvatar(id string) (builtinAvatar, bool) {
+	for _, avatar := range builtinAvatars {
+		if avatar.ID == id {
+			return avatar, true
+		}
+	}
+	return builtinAvatar{}, false
+}
+
+// defaultAvatarFor เลือกรูปโปรไฟล์สำเร็จรูปจาก seed (เช่นชื่อผู้ใช้) เพื่อให้ผู้ใช้ใหม่ไม่ได้รูปเดียวกันทั้งหมด
+func defaultAvatarFor(seed string) string {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	return builtinAvatars[h.Sum32()%uint32(len(builtinAvatars))].URL()
+}
+
+// isDefaultAvatar ตรวจสอบว่าเป็นรูปโปรไฟล์เริ่มต้นหรือสำเร็จรูป (ไม่ใช่ไฟล์ที่ผู้ใช้อัพโหลด ห้ามลบ)
+func isDefaultAvatar(avatarURL string) bool {
+	return avatarURL == legacyDefaultAvatar || strings.HasPrefix(avatarURL, builtinAvatarPrefix)
+}
+
+// avatarStatusFor สถานะการตรวจสอบของรูปโปรไฟล์ใหม่ (เฉพาะรูปที่อัพโหลดต้องรอตรวจสอบ)
+func avatarStatusFor(avatarURL string) string {
+	if isDefaultAvatar(avatarURL) {
+		return AvatarStatusApproved
+	}
+	return AvatarStatusPending
+}
+
+// DefaultAvatarsHandler serves the built-in avatar set
+// ฟังก์ชันสำหรับดึงรายการรูปโปรไฟล์สำเร็จรูป และไฟล์ SVG ของแต่ละรูป
+// GET /avatars/defaults, GET /avatars/defaults/{id}.svg
+// เลือกใช้ได้ด้วย field default_avatar ตอนสมัครสมาชิกหรือแก้ไขโปรไฟล์
+func DefaultAvatarsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/avatars/defaults"), "/")
+	if name == "" {
+		avatars := make([]map[string]string, 0, len(builtinAvatars))
+		for _, avatar := range builtinAvatars {
+			avatars = append(avatars, map[string]string{"id": avatar.ID, "label": avatar.Label, "url": avatar.URL()})
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		utils.JSONResponse(w, map[string]interface{}{"avatars": avatars}, http.StatusOK)
+		return
+	}
+
+	avatar, ok := findBuiltinAvatar(strings.TrimSuffix(name, ".svg"))
+	if !ok || !strings.HasSuffix(name, ".svg") {
+		utils.JSONError(w, "Avatar not found", http.StatusNotFound)
+		return
+	}
+	// URL ของรูปสำเร็จรูปไม่เปลี่ยนเมื่อแก้ไขรูป จึงให้ cache ได้ 1 วันและตรวจซ้ำด้วย ETag
+	svg := avatar.svg()
+	etag := fmt.Sprintf(`"%x"`, crc32.ChecksumIEEE([]byte(svg)))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	io.WriteString(w, svg)
+}
+
+// AdminAvatarHandler handles the avatar moderation queue
+// ฟังก์ชันสำหรับตรวจสอบรูปโปรไฟล์ที่ผู้ใช้อัพโหลด
+// GET  /admin/avatars?status=pending|approved&limit=&offset=
+// POST /admin/avatars/{user_id}/approve
+// POST /admin/avatars/{user_id}/reset {"avatar": "circle-blue"} - เปลี่ยนเป็นรูปสำเร็จรูป (ไม่ระบุ = เลือกให้) และลบไฟล์เดิม
+func AdminAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/avatars/7/reset → userID = 7, action = reset
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) == 2 {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getAvatarQueue(w, r)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil || userID <= 0 || len(pathParts) != 4 {
+		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch pathParts[3] {
+	case "approve":
+		approveAvatar(w, r, userID)
+	case "reset":
+		resetAvatar(w, r, userID)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getAvatarQueue รายการรูปโปรไฟล์ที่ผู้ใช้อัพโหลด (ค่าเริ่มต้นเฉพาะที่รอตรวจสอบ เก่าสุดก่อน) พร้อมจำนวนรายงานที่ยังเปิดอยู่
+func getAvatarQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status == "" {
+		status = AvatarStatusPending
+	}
+	if status != AvatarStatusPending && status != AvatarStatusApproved {
+		utils.JSONError(w, "Invalid status filter. Use pending or approved", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	rows, err := db.Query(`
+		SELECT u.id, u.username, u.avatar_url, u.avatar_hidden, u.avatar_updated_at,
+		       (SELECT COUNT(*) FROM content_reports cr
+		        WHERE cr.target_type = ? AND cr.target_id = u.id AND cr.status = 'open')
+		FROM users u
+		WHERE u.avatar_status = ? AND u.avatar_url IS NOT NULL AND u.avatar_url != ''
+		  AND u.avatar_url != ? AND u.avatar_url NOT LIKE ?
+		ORDER BY u.avatar_updated_at, u.id
+		LIMIT ? OFFSET ?
+	`, ReportTargetAvatar, status, legacyDefaultAvatar, builtinAvatarPrefix+"%", limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching avatar queue: %v\n", err)
+		utils.JSONError(w, "Error fetching avatars", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	avatars := []map[string]interface{}{}
+	for rows.Next() {
+		var userID, reportCount int
+		var username, avatarURL string
+		var hidden bool
+		var updatedAt utils.NullTimestamp
+		if err := rows.Scan(&userID, &username, &avatarURL, &hidden, &updatedAt, &reportCount); err != nil {
+			fmt.Printf("❌ Error scanning avatar queue: %v\n", err)
+			utils.JSONError(w, "Error fetching avatars", http.StatusInternalServerError)
+			return
+		}
+		avatars = append(avatars, map[string]interface{}{
+			"user_id":      userID,
+			"username":     username,
+			"avatar_url":   avatarURL,
+			"hidden":       hidden,
+			"uploaded_at":  updatedAt,
+			"open_reports": reportCount,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading avatar queue: %v\n", err)
+		utils.JSONError(w, "Error fetching avatars", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"status":  status,
+		"avatars": avatars,
+		"limit":   limit,
+		"offset":  offset,
+	}, http.StatusOK)
+}
+
+const avatarSnapshotQuery = "SELECT id, username, avatar_url, avatar_status, avatar_hidden FROM users WHERE id = ?"
+
+// approveAvatar ยืนยันว่ารูปโปรไฟล์เหมาะสม (นำออกจากคิว)
+func approveAvatar(w http.ResponseWriter, r *http.Request, userID int) {
+	before := snapshotRow(avatarSnapshotQuery, userID)
+	if before == nil {
+		utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		return
+	}
+	if _, err := db.Exec("UPDATE users SET avatar_status = ? WHERE id = ?", AvatarStatusApproved, userID); err != nil {
+		fmt.Printf("❌ Error approving avatar of user %d: %v\n", userID, err)
+		utils.JSONError(w, "Error updating avatar", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, "avatar_approve", "user", userID, before, snapshotRow(avatarSnapshotQuery, userID))
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Avatar approved",
+		"user_id": userID,
+	}, http.StatusOK)
+}
+
+// resetAvatar เปลี่ยนรูปโปรไฟล์ของผู้ใช้เป็นรูปสำเร็จรูปและลบไฟล์ที่อัพโหลดไว้
+func resetAvatar(w http.ResponseWriter, r *http.Request, userID int) {
+	var req struct {
+		Avatar string `json:"avatar"` // id ของรูปสำเร็จรูป (ไม่ระบุ = เลือกจากชื่อผู้ใช้)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before := snapshotRow(avatarSnapshotQuery, userID)
+	if before == nil {
+		utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		return
+	}
+	username, _ := before["username"].(string)
+	oldAvatarURL, _ := before["avatar_url"].(string)
+
+	newAvatarURL := defaultAvatarFor(username)
+	if req.Avatar != "" {
+		avatar, ok := findBuiltinAvatar(req.Avatar)
+		if !ok {
+			utils.JSONError(w, "Avatar not found", http.StatusBadRequest)
+			return
+		}
+		newAvatarURL = avatar.URL()
+	}
+
+	_, err := db.Exec(`
+		UPDATE users SET avatar_url = ?, avatar_status = ?, avatar_hidden = 0, avatar_updated_at = NOW() WHERE id = ?
+	`, newAvatarURL, AvatarStatusApproved, userID)
+	if err != nil {
+		fmt.Printf("❌ Error resetting avatar of user %d: %v\n", userID, err)
+		utils.JSONError(w, "Error updating avatar", http.StatusInternalServerError)
+		return
+	}
+	if oldAvatarURL != "" && !isDefaultAvatar(oldAvatarURL) {
+		if err := deleteAvatar(oldAvatarURL); err != nil {
+			fmt.Printf("⚠️ Error deleting avatar %s: %v\n", oldAvatarURL, err)
+		}
+	}
+	recordAudit(r, "avatar_reset", "user", userID, before, snapshotRow(avatarSnapshotQuery, userID))
+
+	fmt.Printf("🖼️ Avatar of user %d reset to %s\n", userID, newAvatarURL)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":    "Avatar reset to default",
+		"user_id":    userID,
+		"avatar_url": newAvatarURL,
+	}, http.StatusOK)
+}
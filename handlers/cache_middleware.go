@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-api-game/cache"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// catalogCachePrefix prefix ของ key ใน cache สำหรับข้อมูลร้านค้า (รายการเกม, หมวดหมู่, อันดับ)
+const catalogCachePrefix = "catalog:"
+
+// cachedResponse ข้อมูล response ที่เก็บไว้ใน cache
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// catalogCacheTTL อายุของ cache (ปรับได้ด้วย CACHE_TTL_SECONDS, ค่าเริ่มต้น 60 วินาที)
+func catalogCacheTTL() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("CACHE_TTL_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// responseRecorder เก็บ status และ body ของ response ไว้สำหรับบันทึกลง cache
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// CacheCatalog middleware เก็บ response ของ GET endpoint สาธารณะไว้ใน cache
+// ข้อมูลจะถูกล้างเมื่อผู้ดูแลระบบแก้ไขเกมหรือเมื่อมีการซื้อที่ทำให้อันดับเปลี่ยน
+func CacheCatalog(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			next(w, r)
+			return
+		}
+
+		// ชื่อและคำอธิบายเกมแปลตามภาษา จึงแยก cache ตามภาษาของ request
+		key := catalogCachePrefix + requestLocale(r) + ":" + r.URL.Path + "?" + r.URL.RawQuery
+
+		if data, ok := cache.Default.Get(key); ok {
+			var cached cachedResponse
+			if err := json.Unmarshal(data, &cached); err == nil {
+				w.Header().Set("Content-Type", cached.ContentType)
+				w.Header().Set("X-Cache", "HIT")
+				w.WriteHeader(http.StatusOK)
+				w.Write(cached.Body)
+				return
+			}
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// เก็บเฉพาะ response ที่สำเร็จ
+		if rec.status == http.StatusOK {
+			data, err := json.Marshal(cachedResponse{
+				ContentType: w.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+			if err == nil {
+				cache.Default.Set(key, data, catalogCacheTTL())
+			}
+		}
+	}
+}
+
+// invalidateCatalogCache ล้าง cache ของข้อมูลร้านค้าทั้งหมด
+func invalidateCatalogCache() {
+	cache.Default.DeletePrefix(catalogCachePrefix)
+	bumpCatalogVersion()
+	fmt.Println("🧹 Catalog cache invalidated")
+}
@@ -1,24 +1,25 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"go-api-game/mailer"
 	"go-api-game/utils"
 	"net/http"
-	"strconv"
-	"time"
+	"strings"
 )
 
 // CartHandler handles cart retrieval
 // ฟังก์ชันสำหรับดึงข้อมูลตะกร้าสินค้าของผู้ใช้
 func CartHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userID, _ := requestUserID(r)
 
 	// ดึงข้อมูลสินค้าในตะกร้าจากฐานข้อมูล
 	rows, err := db.Query(`
-		SELECT g.id, g.name, g.price, c.name as category, g.image_url, ci.quantity
+		SELECT g.id, g.name, g.price, c.name as category, COALESCE(g.image_url, ''), ci.quantity, ci.added_at
 		FROM cart_items ci
 		JOIN games g ON ci.game_id = g.id
 		JOIN categories c ON g.category_id = c.id
@@ -37,16 +38,19 @@ func CartHandler(w http.ResponseWriter, r *http.Request) {
 	// อ่านข้อมูลสินค้าในตะกร้าทีละแถว
 	for rows.Next() {
 		var item struct {
-			ID       int     `json:"id"`
-			Name     string  `json:"name"`
-			Price    float64 `json:"price"`
-			Category string  `json:"category"`
-			ImageURL string  `json:"image_url"`
-			Quantity int     `json:"quantity"`
+			ID       int             `json:"id"`
+			Name     string          `json:"name"`
+			Price    float64         `json:"price"`
+			Category string          `json:"category"`
+			ImageURL string          `json:"image_url"`
+			Quantity int             `json:"quantity"`
+			AddedAt  utils.Timestamp `json:"added_at"`
 		}
 
-		if err := rows.Scan(&item.ID, &item.Name, &item.Price, &item.Category, &item.ImageURL, &item.Quantity); err != nil {
-			continue
+		if err := rows.Scan(&item.ID, &item.Name, &item.Price, &item.Category, &item.ImageURL, &item.Quantity, &item.AddedAt); err != nil {
+			fmt.Printf("❌ Error scanning cart item: %v\n", err)
+			utils.JSONError(w, "Error fetching cart", http.StatusInternalServerError)
+			return
 		}
 
 		// คำนวณราคารวมสำหรับสินค้านี้
@@ -62,8 +66,14 @@ func CartHandler(w http.ResponseWriter, r *http.Request) {
 			"image_url": item.ImageURL,
 			"quantity":  item.Quantity,
 			"subtotal":  itemTotal,
+			"added_at":  item.AddedAt,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading cart items: %v\n", err)
+		utils.JSONError(w, "Error fetching cart", http.StatusInternalServerError)
+		return
+	}
 
 	// ส่ง response กลับไปพร้อมข้อมูลตะกร้า
 	utils.JSONResponse(w, map[string]interface{}{
@@ -82,8 +92,8 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context
+	userID, _ := requestUserID(r)
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
@@ -96,9 +106,30 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ตรวจสอบว่าเกมมีอยู่ ยังวางขายอยู่ และยังมีสต็อก
+	var gameStatus string
+	var stock sql.NullInt64
+	err := db.QueryRow("SELECT status, stock_count FROM games WHERE id = ?", req.GameID).Scan(&gameStatus, &stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error checking game", http.StatusInternalServerError)
+		}
+		return
+	}
+	if gameStatus != "published" {
+		utils.JSONErrorCode(w, utils.ErrGameUnavailable, "Game is not available for purchase", http.StatusBadRequest)
+		return
+	}
+	if stock.Valid && stock.Int64 <= 0 {
+		utils.JSONErrorCode(w, utils.ErrOutOfStock, "Game is out of stock", http.StatusConflict)
+		return
+	}
+
 	// ตรวจสอบว่าผู้ใช้เป็นเจ้าของเกมนี้อยู่แล้วหรือไม่
 	var owned bool
-	err := db.QueryRow(`
+	err = db.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?
 		)
@@ -109,7 +140,7 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if owned {
-		utils.JSONError(w, "You already own this game", http.StatusBadRequest)
+		utils.JSONErrorCode(w, utils.ErrAlreadyOwned, "You already own this game", http.StatusConflict)
 		return
 	}
 
@@ -122,17 +153,22 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// เพิ่มเกมลงในตะกร้า
-	// ใช้ ON DUPLICATE KEY UPDATE เพื่อเพิ่มจำนวนแทนการสร้างรายการใหม่ถ้ามีอยู่แล้ว
+	// ใช้ upsert (dialect.OnConflict) เพื่อเพิ่มจำนวนแทนการสร้างรายการใหม่ถ้ามีอยู่แล้ว
 	_, err = db.Exec(`
 		INSERT INTO cart_items (cart_id, game_id, quantity) 
 		VALUES (?, ?, 1)
-		ON DUPLICATE KEY UPDATE quantity = quantity + 1
+		`+dialect.OnConflict("cart_id", "game_id")+` quantity = cart_items.quantity + 1
 	`, cartID, req.GameID)
 	if err != nil {
 		utils.JSONError(w, "Error adding to cart", http.StatusInternalServerError)
 		return
 	}
 
+	// บันทึกประวัติการเพิ่มลงตะกร้าสำหรับสถิติ (ไม่กระทบผลลัพธ์ถ้าบันทึกไม่สำเร็จ)
+	if _, err := db.Exec("INSERT INTO cart_events (user_id, game_id) VALUES (?, ?)", userID, req.GameID); err != nil {
+		fmt.Printf("⚠️ Error recording cart event: %v\n", err)
+	}
+
 	// ส่ง response สำเร็จกลับไป
 	utils.JSONResponse(w, map[string]string{
 		"message": "Game added to cart",
@@ -148,8 +184,8 @@ func RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context
+	userID, _ := requestUserID(r)
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
@@ -192,23 +228,32 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ดึงและแปลง User-ID จาก header
-	userIDStr := r.Header.Get("User-ID")
-	userID, _ := strconv.Atoi(userIDStr)
-
-	// โครงสร้างสำหรับเก็บข้อมูลจาก request
-	var req struct {
-		DiscountCode string `json:"discount_code"` // รหัสส่วนลด (ถ้ามี)
-	}
+	// ดึง User-ID จาก context
+	userID, _ := requestUserID(r)
 
 	// แปลง JSON request body เป็น struct
+	var req checkoutRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	checkout(w, r, userID, req, false)
+}
+
+// checkoutRequest ข้อมูลจาก request ของ POST /checkout
+type checkoutRequest struct {
+	DiscountCode string `json:"discount_code"` // รหัสส่วนลด (ถ้ามี)
+	AutoApply    bool   `json:"auto_apply"`    // เลือกส่วนลดที่ดีที่สุดที่มีสิทธิ์ให้อัตโนมัติ (เมื่อไม่ได้กรอกรหัส)
+	Region       string `json:"region"`        // ภูมิภาคสำหรับคำนวณภาษี (ถ้าไม่ระบุใช้กฎเริ่มต้น)
+}
+
+// checkout ทำรายการซื้อทั้งหมดใน transaction เดียว
+// toppedUp = true เมื่อเติมเงินอัตโนมัติไปแล้ว (ทำรายการใหม่หลังเติมเงิน เติมได้ครั้งเดียวต่อการ checkout)
+func checkout(w http.ResponseWriter, r *http.Request, userID int, req checkoutRequest, toppedUp bool) {
 	// เริ่มต้น transaction เพื่อความปลอดภัยของข้อมูล
-	tx, err := db.Begin()
+	// ใช้ context ของ request เพื่อให้คำสั่ง SQL ทั้งหมดอยู่ใน trace ของ checkout (ไม่ยกเลิก transaction เมื่อ client ตัดการเชื่อมต่อ)
+	tx, err := db.BeginTx(context.WithoutCancel(r.Context()), nil)
 	if err != nil {
 		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
 		return
@@ -216,7 +261,7 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 
 	// ดึงข้อมูลสินค้าในตะกร้าและคำนวณราคารวม
 	rows, err := tx.Query(`
-		SELECT g.id, g.name, g.price, ci.quantity
+		SELECT g.id, g.name, g.price, ci.quantity, g.stock_count, g.min_age
 		FROM cart_items ci
 		JOIN games g ON ci.game_id = g.id
 		JOIN carts ca ON ci.cart_id = ca.id
@@ -235,6 +280,8 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		Name     string
 		Price    float64
 		Quantity int
+		Stock    sql.NullInt64
+		MinAge   int
 	}
 	total := 0.0
 
@@ -245,8 +292,10 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 			Name     string
 			Price    float64
 			Quantity int
+			Stock    sql.NullInt64
+			MinAge   int
 		}
-		if err := rows.Scan(&item.GameID, &item.Name, &item.Price, &item.Quantity); err != nil {
+		if err := rows.Scan(&item.GameID, &item.Name, &item.Price, &item.Quantity, &item.Stock, &item.MinAge); err != nil {
 			tx.Rollback()
 			utils.JSONError(w, "Error scanning cart items", http.StatusInternalServerError)
 			return
@@ -265,162 +314,170 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 	// ตรวจสอบว่าตะกร้าว่างหรือไม่
 	if len(cartItems) == 0 {
 		tx.Rollback()
-		utils.JSONError(w, "Cart is empty", http.StatusBadRequest)
+		utils.JSONErrorCode(w, utils.ErrCartEmpty, "Cart is empty", http.StatusBadRequest)
+		return
+	}
+
+	// ตรวจสอบว่าเกมในตะกร้ามีอยู่ในคลังเกมของผู้ใช้แล้วหรือไม่ (query เดียวสำหรับทุกเกมในตะกร้า)
+	ownedRows, err := tx.Query(`
+		SELECT g.name
+		FROM cart_items ci
+		JOIN carts ca ON ci.cart_id = ca.id
+		JOIN games g ON ci.game_id = g.id
+		JOIN purchased_games pg ON pg.user_id = ca.user_id AND pg.game_id = ci.game_id
+		WHERE ca.user_id = ?
+	`, userID)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+		return
+	}
+	var ownedNames []string
+	for ownedRows.Next() {
+		var name string
+		if err := ownedRows.Scan(&name); err != nil {
+			ownedRows.Close()
+			tx.Rollback()
+			fmt.Printf("❌ Error scanning owned cart item: %v\n", err)
+			utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+			return
+		}
+		ownedNames = append(ownedNames, name)
+	}
+	ownedRows.Close()
+	if err := ownedRows.Err(); err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error reading owned cart items: %v\n", err)
+		utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
 		return
 	}
 
-	// ตรวจสอบว่าเกมในตะกร้ามีอยู่ในคลังเกมของผู้ใช้แล้วหรือไม่
+	if len(ownedNames) > 0 {
+		tx.Rollback()
+		utils.JSONErrorCode(w, utils.ErrAlreadyOwned, fmt.Sprintf("You already own: %s", strings.Join(ownedNames, ", ")), http.StatusConflict)
+		return
+	}
+
+	// ตรวจสอบอายุของผู้ใช้กับเรตติ้งของเกมในตะกร้า (ต้องระบุวันเกิดก่อนซื้อเกมที่มีอายุขั้นต่ำ)
+	requiredAge := 0
 	for _, item := range cartItems {
-		var owned bool
-		err := tx.QueryRow(`
-			SELECT EXISTS(
-				SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?
-			)
-		`, userID, item.GameID).Scan(&owned)
+		if item.MinAge > requiredAge {
+			requiredAge = item.MinAge
+		}
+	}
+	if requiredAge > 0 {
+		age, known, err := userAge(tx, userID)
 		if err != nil {
 			tx.Rollback()
-			utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+			utils.JSONError(w, "Error checking age", http.StatusInternalServerError)
 			return
 		}
-		if owned {
+		if !known {
 			tx.Rollback()
-			utils.JSONError(w, fmt.Sprintf("You already own: %s", item.Name), http.StatusBadRequest)
+			utils.JSONErrorCode(w, utils.ErrBirthdateRequired, "Add your birthdate to your profile to buy age-rated games", http.StatusForbidden)
+			return
+		}
+		var restricted []string
+		for _, item := range cartItems {
+			if item.MinAge > age {
+				restricted = append(restricted, fmt.Sprintf("%s (%d+)", item.Name, item.MinAge))
+			}
+		}
+		if len(restricted) > 0 {
+			tx.Rollback()
+			utils.JSONErrorCode(w, utils.ErrAgeRestricted, fmt.Sprintf("You are not old enough to buy: %s", strings.Join(restricted, ", ")), http.StatusForbidden)
+			return
+		}
+	}
+
+	// ตัดสต็อกของเกมที่จำกัดจำนวน (ถ้าสต็อกไม่พอจะยกเลิกทั้งการซื้อ)
+	for _, item := range cartItems {
+		if !item.Stock.Valid {
+			continue
+		}
+		reserved, err := reserveStock(tx, item.GameID, item.Quantity)
+		if err != nil {
+			tx.Rollback()
+			utils.JSONError(w, "Error updating stock", http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			tx.Rollback()
+			utils.JSONErrorCode(w, utils.ErrOutOfStock, fmt.Sprintf("%s is out of stock", item.Name), http.StatusConflict)
 			return
 		}
 	}
 
-	// นำส่วนลดไปใช้ (ถ้ามี)
+	// นำส่วนลดไปใช้ (รหัสที่ผู้ใช้กรอก หรือส่วนลดที่ดีที่สุดที่มีสิทธิ์เมื่อเปิด auto_apply)
 	var discountCodeID *int
 	var discountValue float64
+	var appliedDiscount *discountRule
+	discountSource := ""
 	finalAmount := total
 
 	if req.DiscountCode != "" {
-		var discount struct {
-			ID               int
-			Type             string
-			Value            float64
-			MinTotal         float64
-			UsageLimit       *int
-			SingleUsePerUser bool
-			Active           bool
-		}
-
-		// ✅ ใช้ sql.NullString สำหรับรับค่า date จาก database
-		var startDateStr, endDateStr sql.NullString
-
-		err := tx.QueryRow(`
-			SELECT id, type, value, min_total, usage_limit, single_use_per_user, 
-			       active, start_date, end_date
-			FROM discount_codes 
-			WHERE code = ? AND active = 1
-		`, req.DiscountCode).Scan(
-			&discount.ID, &discount.Type, &discount.Value, &discount.MinTotal,
-			&discount.UsageLimit, &discount.SingleUsePerUser, &discount.Active,
-			&startDateStr, &endDateStr, // ✅ รับเป็น string ก่อน
-		)
-
+		rule, err := findDiscountRule(tx, req.DiscountCode)
 		if err == nil {
-			// ✅ Convert string date to time.Time
-			var startDate, endDate *time.Time
-
-			if startDateStr.Valid && startDateStr.String != "" {
-				parsedStart, err := time.Parse("2006-01-02", startDateStr.String)
-				if err == nil {
-					startDate = &parsedStart
-				}
-			}
-
-			if endDateStr.Valid && endDateStr.String != "" {
-				parsedEnd, err := time.Parse("2006-01-02", endDateStr.String)
-				if err == nil {
-					endDate = &parsedEnd
-				}
-			}
-
-			// ตรวจสอบความถูกต้องของรหัสส่วนลด
-			now := time.Now()
-			if startDate != nil && now.Before(*startDate) {
-				tx.Rollback()
-				utils.JSONError(w, "Discount code not yet valid", http.StatusBadRequest)
-				return
-			}
-			if endDate != nil && now.After(*endDate) {
+			ineligible, err := checkDiscountRule(tx, rule, userID, total)
+			if err != nil {
 				tx.Rollback()
-				utils.JSONError(w, "Discount code has expired", http.StatusBadRequest)
+				utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
 				return
 			}
-			if discount.MinTotal > 0 && total < discount.MinTotal {
+			if ineligible != nil {
 				tx.Rollback()
-				utils.JSONError(w, fmt.Sprintf("Minimum purchase of $%.2f required", discount.MinTotal), http.StatusBadRequest)
+				utils.JSONErrorCode(w, ineligible.Code, ineligible.Message, ineligible.Status)
 				return
 			}
-
-			// ตรวจสอบขีดจำกัดการใช้งาน
-			if discount.UsageLimit != nil {
-				var usageCount int
-				err := tx.QueryRow(`
-                SELECT COUNT(*) 
-                FROM user_discount_codes 
-                WHERE discount_code_id = ?
-            `, discount.ID).Scan(&usageCount)
-
-				if err == nil && usageCount >= *discount.UsageLimit {
-					// ❌ ตั้งค่า active = 0 เมื่อใช้ครบจำนวน
-					tx.Exec("UPDATE discount_codes SET active = 0 WHERE id = ?", discount.ID)
-					fmt.Printf("🚫 Discount code deactivated: ID=%d, usage reached limit\n", discount.ID)
-
-					tx.Rollback()
-					utils.JSONError(w, "Discount code usage limit reached", http.StatusBadRequest)
-					return
-				}
-			}
-
-			// ตรวจสอบว่าผู้ใช้ใช้รหัสส่วนลดนี้ไปแล้วหรือไม่
-			if discount.SingleUsePerUser {
-				var used bool
-				err := tx.QueryRow(`
-					SELECT EXISTS(
-						SELECT 1 FROM user_discount_codes 
-						WHERE user_id = ? AND discount_code_id = ?
-					)
-				`, userID, discount.ID).Scan(&used)
-				if err != nil {
-					tx.Rollback()
-					utils.JSONError(w, "Error checking discount usage", http.StatusInternalServerError)
-					return
-				}
-				if used {
-					tx.Rollback()
-					utils.JSONError(w, "Discount code already used", http.StatusBadRequest)
-					return
-				}
-			}
-
-			// นำส่วนลดไปใช้
-			if discount.Type == "percent" {
-				discountValue = total * (discount.Value / 100)
-			} else {
-				discountValue = discount.Value
-			}
-
-			finalAmount = total - discountValue
-			if finalAmount < 0 {
-				finalAmount = 0
-			}
-
-			discountCodeID = &discount.ID
-
-			fmt.Printf("✅ Discount applied in checkout: Code=%s, Discount=%.2f, Final=%.2f\n",
-				req.DiscountCode, discountValue, finalAmount)
+			appliedDiscount, discountSource = rule, "code"
 		} else if err != sql.ErrNoRows {
 			// ❌ Database error (ไม่ใช่แค่หาไม่เจอ)
 			tx.Rollback()
 			utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
 			return
+		} else {
+			// ถ้า err == sql.ErrNoRows ก็แค่ไม่ใช้ส่วนลด (บันทึกไว้ตรวจจับการสุ่มรหัส ครั้งเดียวต่อการ checkout)
+			if !toppedUp {
+				recordDiscountFailure(r, userID)
+			}
+		}
+	} else if req.AutoApply {
+		rule, _, err := bestDiscount(tx, userID, total)
+		if err != nil {
+			tx.Rollback()
+			fmt.Printf("❌ Error finding best discount: %v\n", err)
+			utils.JSONError(w, "Error checking discounts", http.StatusInternalServerError)
+			return
 		}
-		// ถ้า err == sql.ErrNoRows ก็แค่ไม่ใช้ส่วนลด (ไม่ต้องทำอะไร)
+		if rule != nil {
+			appliedDiscount, discountSource = rule, "auto"
+		}
+	}
+
+	if appliedDiscount != nil {
+		discountValue = appliedDiscount.amount(total)
+		finalAmount = total - discountValue
+		discountCodeID = &appliedDiscount.ID
+
+		fmt.Printf("✅ Discount applied in checkout (%s): Code=%s, Discount=%.2f, Final=%.2f\n",
+			discountSource, appliedDiscount.Code, discountValue, finalAmount)
 	}
 
+	// คำนวณภาษีจากราคาหลังหักส่วนลด (ยอดที่ต้องชำระ = finalAmount + taxAmount)
+	taxRule, err := resolveTaxRule(tx, req.Region)
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error resolving tax rule: %v\n", err)
+		utils.JSONError(w, "Error calculating tax", http.StatusInternalServerError)
+		return
+	}
+	taxLines := make([]taxLine, len(cartItems))
+	for i, item := range cartItems {
+		taxLines[i] = taxLine{GameID: item.GameID, Name: item.Name, Taxable: item.Price * float64(item.Quantity)}
+	}
+	taxLines, taxAmount := computeTaxLines(taxRule, taxLines, total, finalAmount)
+	amountDue := roundCents(finalAmount + taxAmount)
+
 	// ตรวจสอบยอดเงินในกระเป๋าเงิน
 	var walletBalance float64
 	err = tx.QueryRow("SELECT wallet_balance FROM users WHERE id = ?", userID).Scan(&walletBalance)
@@ -430,17 +487,45 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if walletBalance < finalAmount {
+	// ยอดเงินไม่พอ: เติมเงินอัตโนมัติถ้าผู้ใช้เปิดใช้งาน มิฉะนั้นแจ้งยอดที่ขาด
+	// ยกเลิก transaction ก่อนตัดเงินผ่านช่องทางชำระเงิน (ไม่ถือ lock ของสต็อกไว้ระหว่างรอ gateway)
+	// แล้วทำรายการใหม่ซึ่งตรวจสอบยอดเงินอีกครั้งหลังเติมเงินเข้ากระเป๋าแล้ว
+	if walletBalance < amountDue {
 		tx.Rollback()
-		utils.JSONError(w, "Insufficient wallet balance", http.StatusBadRequest)
+		var topUpErr error
+		if !toppedUp {
+			var topUp float64
+			topUp, topUpErr = topUpForCheckout(userID, amountDue-walletBalance)
+			if topUp > 0 {
+				checkout(w, r, userID, req, true)
+				return
+			}
+		}
+		if topUpErr != nil {
+			fmt.Printf("⚠️ Auto top-up at checkout failed for user %d: %v\n", userID, topUpErr)
+		}
+		utils.JSONErrorDetails(w, utils.ErrInsufficientBalance, "Insufficient wallet balance",
+			insufficientBalanceDetails(userID, walletBalance, amountDue, topUpErr), http.StatusBadRequest)
 		return
 	}
 
-	// สร้างบันทึกการซื้อ
+	// ประเมินความเสี่ยง: คำสั่งซื้อที่คะแนนถึง RISK_HOLD_SCORE จะถูกตัดเงินแต่ยังไม่ส่งมอบเกม (รอ admin ตรวจสอบ)
+	risk := assessRisk(r, userID)
+	held := risk.Score >= riskHoldScore()
+
+	// ค่าคอมมิชชันของพาร์ทเนอร์ที่แนะนำ (คิดจากยอดหลังหักส่วนลด ไม่รวมภาษี)
+	affiliateID, commissionRate := purchaseAffiliate(tx, r, userID)
+	commission := roundCents(finalAmount * commissionRate / 100)
+
+	// สร้างบันทึกการซื้อ (เริ่มที่สถานะ pending แล้วเปลี่ยนเป็น paid/fulfilled หรือ held ภายใน transaction นี้)
 	result, err := tx.Exec(`
-		INSERT INTO purchases (user_id, total_amount, discount_code_id, final_amount)
-		VALUES (?, ?, ?, ?)
-	`, userID, total, discountCodeID, finalAmount)
+		INSERT INTO purchases (user_id, total_amount, discount_code_id, final_amount,
+		                       tax_amount, tax_rate, tax_name, tax_region, status, status_updated_at,
+		                       affiliate_id, affiliate_commission)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), ?, ?)
+	`, userID, total, discountCodeID, finalAmount,
+		taxAmount, taxRule.Rate, taxRule.Name, taxRule.Region, PurchasePending,
+		affiliateID, commission)
 	if err != nil {
 		tx.Rollback()
 		utils.JSONError(w, "Error creating purchase record", http.StatusInternalServerError)
@@ -449,36 +534,51 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 
 	purchaseID, _ := result.LastInsertId()
 
-	// เพิ่มรายการสินค้าที่ซื้อและทำเครื่องหมายว่าเกมถูกซื้อแล้ว
-	for _, item := range cartItems {
-		// เพิ่มใน purchase_items
-		_, err := tx.Exec(`
-			INSERT INTO purchase_items (purchase_id, game_id, price_at_purchase)
-			VALUES (?, ?, ?)
-		`, purchaseID, item.GameID, item.Price)
-		if err != nil {
-			tx.Rollback()
-			utils.JSONError(w, "Error recording purchase items", http.StatusInternalServerError)
-			return
-		}
+	// เพิ่มรายการสินค้าที่ซื้อ, เพิ่มเกมเข้าคลัง และอัพเดทยอดขายแบบ batch (คำสั่งละหนึ่งครั้งต่อการซื้อ)
+	itemValues := make([]string, len(cartItems))
+	libraryValues := make([]string, len(cartItems))
+	rankingValues := make([]string, len(cartItems))
+	itemArgs := []interface{}{}
+	libraryArgs := []interface{}{}
+	rankingArgs := []interface{}{}
+	for i, item := range cartItems {
+		itemValues[i] = "(?, ?, ?, ?)"
+		itemArgs = append(itemArgs, purchaseID, item.GameID, item.Price, taxLines[i].Tax)
+
+		libraryValues[i] = "(?, ?)"
+		libraryArgs = append(libraryArgs, userID, item.GameID)
+
+		rankingValues[i] = "(?, 1)"
+		rankingArgs = append(rankingArgs, item.GameID)
+	}
+
+	// เพิ่มใน purchase_items
+	_, err = tx.Exec(`
+		INSERT INTO purchase_items (purchase_id, game_id, price_at_purchase, tax_amount)
+		VALUES `+strings.Join(itemValues, ", "), itemArgs...)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error recording purchase items", http.StatusInternalServerError)
+		return
+	}
 
-		// เพิ่มใน purchased_games (คลังเกมของผู้ใช้)
+	// เพิ่มใน purchased_games (คลังเกมของผู้ใช้) และอัพเดทจำนวนยอดขายใน ranking
+	// คำสั่งซื้อที่ถูกพักไว้จะทำขั้นตอนนี้เมื่อ admin อนุมัติ (ดู approveHeldPurchase)
+	if !held {
 		_, err = tx.Exec(`
-			INSERT INTO purchased_games (user_id, game_id) 
-			VALUES (?, ?)
-		`, userID, item.GameID)
+			INSERT INTO purchased_games (user_id, game_id)
+			VALUES `+strings.Join(libraryValues, ", "), libraryArgs...)
 		if err != nil {
 			tx.Rollback()
 			utils.JSONError(w, "Error adding to library", http.StatusInternalServerError)
 			return
 		}
 
-		// อัพเดทจำนวนยอดขายใน ranking
 		_, err = tx.Exec(`
-			INSERT INTO ranking (game_id, sales_count) 
-			VALUES (?, 1)
-			ON DUPLICATE KEY UPDATE sales_count = sales_count + 1
-		`, item.GameID)
+			INSERT INTO ranking (game_id, sales_count)
+			VALUES `+strings.Join(rankingValues, ", ")+`
+			`+dialect.OnConflict("game_id")+` sales_count = ranking.sales_count + 1
+		`, rankingArgs...)
 		if err != nil {
 			tx.Rollback()
 			utils.JSONError(w, "Error updating rankings", http.StatusInternalServerError)
@@ -486,22 +586,6 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// อัพเดทอันดับการจัดอันดับ
-	_, err = tx.Exec(`
-		UPDATE ranking 
-		SET rank_position = (
-			SELECT rnk FROM (
-				SELECT game_id, RANK() OVER (ORDER BY sales_count DESC) as rnk
-				FROM ranking
-			) r WHERE r.game_id = ranking.game_id
-		)
-	`)
-	if err != nil {
-		tx.Rollback()
-		utils.JSONError(w, "Error updating rank positions", http.StatusInternalServerError)
-		return
-	}
-
 	// บันทึกการใช้งานส่วนลด
 	if discountCodeID != nil {
 		_, err = tx.Exec(`
@@ -539,7 +623,7 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 
 	// อัพเดทยอดเงินในกระเป๋าเงิน
 	_, err = tx.Exec("UPDATE users SET wallet_balance = wallet_balance - ? WHERE id = ?",
-		finalAmount, userID)
+		amountDue, userID)
 	if err != nil {
 		tx.Rollback()
 		utils.JSONError(w, "Error updating wallet", http.StatusInternalServerError)
@@ -549,14 +633,28 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 	// บันทึกธุรกรรม
 	_, err = tx.Exec(`
 		INSERT INTO user_transactions (user_id, type, amount, description)
-		VALUES (?, 'purchase', ?, ?)
-	`, userID, finalAmount, fmt.Sprintf("Purchase #%d", purchaseID))
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionPurchase, amountDue, fmt.Sprintf("Purchase #%d", purchaseID))
 	if err != nil {
 		tx.Rollback()
 		utils.JSONError(w, "Error recording transaction", http.StatusInternalServerError)
 		return
 	}
 
+	// ชำระเงินจากกระเป๋าแล้ว และเกมถูกเพิ่มเข้าคลังแล้ว (หรือถูกพักไว้รอตรวจสอบ)
+	statuses := []string{PurchasePaid, PurchaseFulfilled}
+	if held {
+		statuses = []string{PurchasePaid, PurchaseHeld}
+	}
+	for _, status := range statuses {
+		if err := transitionPurchase(tx, purchaseID, status, "checkout"); err != nil {
+			tx.Rollback()
+			fmt.Printf("❌ Error updating purchase status: %v\n", err)
+			utils.JSONError(w, "Error updating purchase status", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// ล้างตะกร้าสินค้า
 	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id = (SELECT id FROM carts WHERE user_id = ?)", userID)
 	if err != nil {
@@ -571,17 +669,103 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("✅ Checkout completed: user_id=%d, purchase_id=%d, total=%.2f, final=%.2f\n",
-		userID, purchaseID, total, finalAmount)
+	recordRiskEvent(r, userID, RiskEventCheckout, risk, purchaseID)
+
+	if held {
+		fmt.Printf("🚨 Checkout held for review: user_id=%d, purchase_id=%d, score=%d\n", userID, purchaseID, risk.Score)
+		notify(userID, NotificationOrderHeld, "Order under review",
+			fmt.Sprintf("Purchase #%d is being reviewed. Your games will be added to your library once it is approved", purchaseID),
+			map[string]interface{}{"purchase_id": purchaseID, "amount_paid": amountDue})
+		publishToUser(userID, EventOrderStatus, map[string]interface{}{"purchase_id": purchaseID, "status": PurchaseHeld})
+		pushWalletBalance(userID)
+
+		utils.JSONResponse(w, map[string]interface{}{
+			"message":      "Order held for review",
+			"purchase_id":  purchaseID,
+			"status":       PurchaseHeld,
+			"total":        total,
+			"discount":     discountValue,
+			"final_amount": finalAmount,
+			"amount_paid":  amountDue,
+			"games_count":  len(cartItems),
+		}, http.StatusAccepted)
+		return
+	}
 
-	// ส่ง response การซื้อสำเร็จกลับไป
-	utils.JSONResponse(w, map[string]interface{}{
-		"message":      "Purchase completed successfully",
+	fmt.Printf("✅ Checkout completed: user_id=%d, purchase_id=%d, total=%.2f, final=%.2f, tax=%.2f\n",
+		userID, purchaseID, total, finalAmount, taxAmount)
+
+	// ยอดขายเปลี่ยน ต้องล้าง cache และคำนวณอันดับใหม่ (ทำงานเบื้องหลัง ไม่อยู่ใน transaction ของการซื้อ)
+	invalidateCatalogCache()
+	requestRankingRecompute()
+
+	gameNames := make([]string, len(cartItems))
+	for i, item := range cartItems {
+		gameNames[i] = item.Name
+	}
+	notify(userID, NotificationPurchaseCompleted, "Purchase completed",
+		fmt.Sprintf("Purchase #%d: %s ($%.2f)", purchaseID, strings.Join(gameNames, ", "), amountDue),
+		map[string]interface{}{"purchase_id": purchaseID, "final_amount": finalAmount, "amount_paid": amountDue})
+
+	receiptItems := make([]map[string]interface{}, len(cartItems))
+	for i, item := range cartItems {
+		receiptItems[i] = map[string]interface{}{"Name": item.Name, "Price": item.Price * float64(item.Quantity)}
+	}
+	publishToUser(userID, EventOrderStatus, map[string]interface{}{"purchase_id": purchaseID, "status": "completed"})
+	pushWalletBalance(userID)
+	publishSale(purchaseID)
+	go topUpBelowThreshold(userID, purchaseID, walletBalance-amountDue)
+	emitWebhookEvent(WebhookPurchaseCompleted, map[string]interface{}{
 		"purchase_id":  purchaseID,
+		"user_id":      userID,
+		"games":        gameNames,
 		"total":        total,
 		"discount":     discountValue,
 		"final_amount": finalAmount,
-		"games_count":  len(cartItems),
+		"tax":          taxAmount,
+		"amount_paid":  amountDue,
+	})
+	sendUserMail(userID, mailer.TemplateReceipt, map[string]interface{}{
+		"PurchaseID":  purchaseID,
+		"Items":       receiptItems,
+		"Total":       total,
+		"Discount":    discountValue,
+		"TaxName":     taxRule.Name,
+		"TaxRate":     taxRule.Rate,
+		"Tax":         taxAmount,
+		"FinalAmount": amountDue,
+	})
+
+	// ส่วนลดที่ใช้ (source = code เมื่อผู้ใช้กรอกรหัส, auto เมื่อระบบเลือกให้)
+	var discountInfo interface{}
+	if appliedDiscount != nil {
+		discountInfo = map[string]interface{}{
+			"id":     appliedDiscount.ID,
+			"code":   appliedDiscount.Code,
+			"type":   appliedDiscount.Type,
+			"value":  appliedDiscount.Value,
+			"source": discountSource,
+		}
+	}
+
+	// ส่ง response การซื้อสำเร็จกลับไป
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":       "Purchase completed successfully",
+		"purchase_id":   purchaseID,
+		"status":        PurchaseFulfilled,
+		"total":         total,
+		"discount":      discountValue,
+		"final_amount":  finalAmount,
+		"discount_rule": discountInfo,
+		"tax": map[string]interface{}{
+			"name":   taxRule.Name,
+			"region": taxRule.Region,
+			"rate":   taxRule.Rate,
+			"amount": taxAmount,
+			"lines":  taxLines,
+		},
+		"amount_paid": amountDue,
+		"games_count": len(cartItems),
 	}, http.StatusOK)
 }
 
@@ -594,11 +778,19 @@ func ApplyDiscountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ผู้ใช้มาจาก token เสมอ (ไม่เชื่อ user_id ที่ client ส่งมา)
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
 		Code        string  `json:"code"`         // รหัสส่วนลด
 		TotalAmount float64 `json:"total_amount"` // ราคารวมก่อนหักส่วนลด
-		UserID      int     `json:"user_id"`      // ID ผู้ใช้
+		// Deprecated: ไม่ต้องส่งแล้ว ใช้ผู้ใช้จาก token แทน (รับไว้ชั่วคราวเพื่อให้ client รุ่นเก่ายังใช้งานได้)
+		UserID *int `json:"user_id"`
 	}
 
 	// แปลง JSON request body เป็น struct
@@ -607,138 +799,56 @@ func ApplyDiscountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("🔍 Applying discount code: %s for user %d, total: %.2f\n", req.Code, req.UserID, req.TotalAmount)
-
-	// โครงสร้างสำหรับเก็บข้อมูลส่วนลดจากฐานข้อมูล
-	var discount struct {
-		ID               int
-		Type             string
-		Value            float64
-		MinTotal         float64
-		UsageLimit       *int
-		SingleUsePerUser bool
-		Active           bool
-		StartDate        *time.Time
-		EndDate          *time.Time
-	}
-
-	// ใช้ sql.NullString เพื่อรับค่า date จาก database
-	var startDateStr, endDateStr sql.NullString
-
-	// ค้นหารหัสส่วนลดในฐานข้อมูล
-	err := db.QueryRow(`
-        SELECT id, type, value, min_total, usage_limit, single_use_per_user, 
-               active, start_date, end_date
-        FROM discount_codes 
-        WHERE code = ? AND active = 1
-    `, req.Code).Scan(
-		&discount.ID, &discount.Type, &discount.Value, &discount.MinTotal,
-		&discount.UsageLimit, &discount.SingleUsePerUser, &discount.Active,
-		&startDateStr, &endDateStr, // รับเป็น string ก่อน
-	)
-
-	if err != nil {
-		fmt.Printf("❌ Database error: %v\n", err)
-		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Discount code not found or inactive", http.StatusBadRequest)
-		} else {
-			utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	// Convert string date to time.Time
-	if startDateStr.Valid && startDateStr.String != "" {
-		startDate, err := time.Parse("2006-01-02", startDateStr.String)
-		if err != nil {
-			fmt.Printf("❌ Error parsing start date: %v\n", err)
-		} else {
-			discount.StartDate = &startDate
-		}
-	}
-
-	if endDateStr.Valid && endDateStr.String != "" {
-		endDate, err := time.Parse("2006-01-02", endDateStr.String)
-		if err != nil {
-			fmt.Printf("❌ Error parsing end date: %v\n", err)
-		} else {
-			discount.EndDate = &endDate
+	// client รุ่นเก่ายังส่ง user_id มา: ยอมรับถ้าตรงกับผู้ใช้ใน token พร้อมแจ้งว่าฟิลด์นี้เลิกใช้แล้ว
+	// ถ้าไม่ตรงแสดงว่าพยายามตรวจสอบสิทธิ์ของผู้ใช้อื่น
+	if req.UserID != nil {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", `299 - "user_id is deprecated and must match the authenticated user; omit it"`)
+		if *req.UserID != userID {
+			fmt.Printf("⚠️ User %d tried to apply discount as user %d\n", userID, *req.UserID)
+			utils.JSONError(w, "user_id does not match the authenticated user", http.StatusForbidden)
+			return
 		}
 	}
 
-	fmt.Printf("✅ Discount found: ID=%d, StartDate=%v, EndDate=%v\n",
-		discount.ID, discount.StartDate, discount.EndDate)
-
-	// ตรวจสอบความถูกต้องของรหัสส่วนลด
-	now := time.Now()
+	fmt.Printf("🔍 Applying discount code: %s for user %d, total: %.2f\n", req.Code, userID, req.TotalAmount)
 
-	// ตรวจสอบความถูกต้องของวันที่
-	if discount.StartDate != nil && now.Before(*discount.StartDate) {
-		utils.JSONError(w, "Discount code not yet valid", http.StatusBadRequest)
+	// ตรวจสอบด้วยเงื่อนไขเดียวกับตอน checkout (ผู้ใช้เป้าหมาย ช่วงวันที่ ยอดขั้นต่ำ ขีดจำกัดการใช้งาน)
+	discount, err := findDiscountRule(db, req.Code)
+	if err == sql.ErrNoRows {
+		recordDiscountFailure(r, userID)
+		utils.JSONErrorCode(w, discountNotFound.Code, discountNotFound.Message, discountNotFound.Status)
 		return
 	}
-	if discount.EndDate != nil && now.After(*discount.EndDate) {
-		utils.JSONError(w, "Discount code has expired", http.StatusBadRequest)
+	if err != nil {
+		fmt.Printf("❌ Database error: %v\n", err)
+		utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
 		return
 	}
 
-	// ตรวจสอบยอดซื้อขั้นต่ำ
-	if discount.MinTotal > 0 && req.TotalAmount < discount.MinTotal {
-		utils.JSONError(w, fmt.Sprintf("Minimum purchase of $%.2f required", discount.MinTotal), http.StatusBadRequest)
+	ineligible, err := checkDiscountRule(db, discount, userID, req.TotalAmount)
+	if err != nil {
+		fmt.Printf("❌ Error checking discount code %d: %v\n", discount.ID, err)
+		utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
 		return
 	}
-
-	// ตรวจสอบขีดจำกัดการใช้งาน
-	if discount.UsageLimit != nil {
-		var usageCount int
-		err := db.QueryRow(`
-            SELECT COUNT(*) 
-            FROM user_discount_codes 
-            WHERE discount_code_id = ?
-        `, discount.ID).Scan(&usageCount)
-
-		if err == nil && usageCount >= *discount.UsageLimit {
+	if ineligible != nil {
+		switch ineligible.Code {
+		case utils.ErrCodeNotFound:
+			// รหัสที่กำหนดให้ผู้ใช้อื่นนับเป็นการเดารหัสผิดเหมือนรหัสที่ไม่มีอยู่
+			recordDiscountFailure(r, userID)
+		case utils.ErrCodeUsageLimit:
 			// ❌ ตั้งค่า active = 0 เมื่อใช้ครบจำนวน
 			db.Exec("UPDATE discount_codes SET active = 0 WHERE id = ?", discount.ID)
 			fmt.Printf("🚫 Discount code deactivated: ID=%d, usage reached limit\n", discount.ID)
-
-			utils.JSONError(w, "Discount code usage limit reached", http.StatusBadRequest)
-			return
 		}
+		utils.JSONErrorCode(w, ineligible.Code, ineligible.Message, ineligible.Status)
+		return
 	}
 
-	// ตรวจสอบว่าผู้ใช้ใช้รหัสส่วนลดนี้ไปแล้วหรือไม่ (สำหรับรหัสที่ใช้ได้ครั้งเดียว)
-	if discount.SingleUsePerUser {
-		var used bool
-		err := db.QueryRow(`
-            SELECT EXISTS(
-                SELECT 1 FROM user_discount_codes 
-                WHERE user_id = ? AND discount_code_id = ?
-            )
-        `, req.UserID, discount.ID).Scan(&used)
-
-		if err != nil {
-			fmt.Printf("❌ Error checking single use: %v\n", err)
-		} else if used {
-			utils.JSONError(w, "Discount code already used", http.StatusBadRequest)
-			return
-		}
-	}
-
-	// คำนวณจำนวนส่วนลด
-	var discountAmount float64
-	var finalAmount float64
-
-	if discount.Type == "percent" {
-		discountAmount = req.TotalAmount * (discount.Value / 100)
-	} else {
-		discountAmount = discount.Value
-	}
-
-	finalAmount = req.TotalAmount - discountAmount
-	if finalAmount < 0 {
-		finalAmount = 0
-	}
+	// คำนวณจำนวนส่วนลด (ไม่เกินยอดรวม)
+	discountAmount := discount.amount(req.TotalAmount)
+	finalAmount := req.TotalAmount - discountAmount
 
 	fmt.Printf("✅ Discount applied: Code=%s, Type=%s, Value=%.2f, Discount=%.2f, Final=%.2f\n",
 		req.Code, discount.Type, discount.Value, discountAmount, finalAmount)
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"go-api-game/storage"
+	"go-api-game/utils"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultCloudSaveMaxBytes ขนาดสูงสุดของไฟล์เซฟต่อเวอร์ชัน (ปรับได้ด้วย CLOUD_SAVE_MAX_BYTES)
+const defaultCloudSaveMaxBytes = 1 << 20 // 1 MB
+
+// cloudSaveVersionsKept จำนวนเวอร์ชันล่าสุดที่เก็บไว้ต่อเกม (เวอร์ชันที่เก่ากว่าจะถูกลบ)
+const cloudSaveVersionsKept = 5
+
+func cloudSaveMaxBytes() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("CLOUD_SAVE_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultCloudSaveMaxBytes
+}
+
+// cloudSave ข้อมูลของเซฟหนึ่งเวอร์ชัน (ตัวไฟล์อยู่ใน storage)
+type cloudSave struct {
+	Revision  int             `json:"revision"`
+	SizeBytes int             `json:"size_bytes"`
+	Checksum  string          `json:"checksum"` // SHA-256 ของข้อมูล
+	CreatedAt utils.Timestamp `json:"created_at"`
+	url       string
+}
+
+// CloudSaveHandler handles cloud save sync for owned games
+// ฟังก์ชันสำหรับเก็บและดึงไฟล์เซฟของเกมในคลัง (ตรวจจับการเขียนทับกันด้วยหมายเลข revision)
+//
+//	GET /library/{game_id}/saves[?revision=N]        ดาวน์โหลดเซฟล่าสุด (หรือเวอร์ชันที่ระบุ)
+//	GET /library/{game_id}/saves/versions            รายการเวอร์ชันที่เก็บไว้
+//	PUT /library/{game_id}/saves?base_revision=N     อัพโหลดเซฟใหม่ต่อจาก revision N (0 = เซฟแรก)
+func CloudSaveHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /library/12/saves → gameID = 12
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 3 || pathParts[2] != "saves" {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	gameID, err := strconv.Atoi(pathParts[1])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	// เซฟได้เฉพาะเกมที่อยู่ในคลังของผู้ใช้
+	var owned bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?)", userID, gameID).Scan(&owned); err != nil {
+		utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owned {
+		utils.JSONErrorCode(w, utils.ErrForbidden, "You do not own this game", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && len(pathParts) == 4 && pathParts[3] == "versions":
+		listCloudSaves(w, userID, gameID)
+	case r.Method == "GET" && len(pathParts) == 3:
+		downloadCloudSave(w, r, userID, gameID)
+	case r.Method == "PUT" && len(pathParts) == 3:
+		uploadCloudSave(w, r, userID, gameID)
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// latestCloudSave ดึงเซฟเวอร์ชันล่าสุด (revision = 0) หรือเวอร์ชันที่ระบุ
+func latestCloudSave(exec sqlExecutor, userID, gameID, revision int) (*cloudSave, error) {
+	query := `
+		SELECT revision, size_bytes, checksum, created_at, storage_url
+		FROM game_saves
+		WHERE user_id = ? AND game_id = ?`
+	args := []interface{}{userID, gameID}
+	if revision > 0 {
+		query += " AND revision = ?"
+		args = append(args, revision)
+	}
+	var save cloudSave
+	err := exec.QueryRow(query+" ORDER BY revision DESC LIMIT 1", args...).Scan(
+		&save.Revision, &save.SizeBytes, &save.Checksum, &save.CreatedAt, &save.url)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// listCloudSaves ส่งรายการเวอร์ชันของเซฟที่เก็บไว้ (ใหม่ไปเก่า)
+func listCloudSaves(w http.ResponseWriter, userID, gameID int) {
+	rows, err := db.Query(`
+		SELECT revision, size_bytes, checksum, created_at
+		FROM game_saves
+		WHERE user_id = ? AND game_id = ?
+		ORDER BY revision DESC
+	`, userID, gameID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching cloud saves: %v\n", err)
+		utils.JSONError(w, "Error fetching saves", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	versions := []cloudSave{}
+	for rows.Next() {
+		var save cloudSave
+		if err := rows.Scan(&save.Revision, &save.SizeBytes, &save.Checksum, &save.CreatedAt); err != nil {
+			fmt.Printf("❌ Error scanning cloud save: %v\n", err)
+			utils.JSONError(w, "Error fetching saves", http.StatusInternalServerError)
+			return
+		}
+		versions = append(versions, save)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading cloud save rows: %v\n", err)
+		utils.JSONError(w, "Error fetching saves", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"game_id":   gameID,
+		"versions":  versions,
+		"max_bytes": cloudSaveMaxBytes(),
+	}, http.StatusOK)
+}
+
+// downloadCloudSave ส่งข้อมูลของเซฟเป็น binary พร้อม revision และ checksum ใน header
+func downloadCloudSave(w http.ResponseWriter, r *http.Request, userID, gameID int) {
+	revision := 0
+	if raw := r.URL.Query().Get("revision"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			utils.JSONError(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+		revision = n
+	}
+
+	save, err := latestCloudSave(db, userID, gameID, revision)
+	if err != nil {
+		fmt.Printf("❌ Error fetching cloud save: %v\n", err)
+		utils.JSONError(w, "Error fetching save", http.StatusInternalServerError)
+		return
+	}
+	if save == nil {
+		utils.JSONError(w, "Save not found", http.StatusNotFound)
+		return
+	}
+
+	data, err := storage.Load(save.url)
+	if err != nil {
+		fmt.Printf("❌ Error loading cloud save from storage: %v\n", err)
+		utils.JSONError(w, "Error loading save", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Save-Revision", strconv.Itoa(save.Revision))
+	w.Header().Set("X-Save-Checksum", save.Checksum)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// uploadCloudSave บันทึกเซฟเวอร์ชันใหม่ ถ้า base_revision ไม่ตรงกับเวอร์ชันล่าสุด (มีอุปกรณ์อื่นเซฟไปก่อน)
+// จะตอบ 409 พร้อม revision ปัจจุบัน ให้ client ดึงเซฟล่าสุดไปรวมก่อนอัพโหลดใหม่
+func uploadCloudSave(w http.ResponseWriter, r *http.Request, userID, gameID int) {
+	baseRevision, err := strconv.Atoi(r.URL.Query().Get("base_revision"))
+	if err != nil || baseRevision < 0 {
+		utils.JSONError(w, "base_revision is required (use 0 for the first save)", http.StatusBadRequest)
+		return
+	}
+
+	maxBytes := cloudSaveMaxBytes()
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	if err != nil {
+		utils.JSONError(w, "Error reading save data", http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		utils.JSONError(w, fmt.Sprintf("Save data exceeds the %d byte limit", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		utils.JSONError(w, "Save data is empty", http.StatusBadRequest)
+		return
+	}
+
+	current, err := latestCloudSave(db, userID, gameID, 0)
+	if err != nil {
+		fmt.Printf("❌ Error fetching cloud save: %v\n", err)
+		utils.JSONError(w, "Error fetching save", http.StatusInternalServerError)
+		return
+	}
+	currentRevision := 0
+	if current != nil {
+		currentRevision = current.Revision
+	}
+	if baseRevision != currentRevision {
+		writeSaveConflict(w, current)
+		return
+	}
+
+	// ชื่อไฟล์มีส่วนสุ่มเพื่อไม่ให้เดา URL ของเซฟผู้อื่นได้
+	randomBytes := make([]byte, 8)
+	if _, err := rand.Read(randomBytes); err != nil {
+		utils.JSONError(w, "Error saving data", http.StatusInternalServerError)
+		return
+	}
+	revision := currentRevision + 1
+	key := fmt.Sprintf("save_%d_%d_r%d_%s.bin", userID, gameID, revision, hex.EncodeToString(randomBytes))
+	url, err := storage.SaveContext(r.Context(), key, data)
+	if err != nil {
+		fmt.Printf("❌ Error storing cloud save: %v\n", err)
+		utils.JSONError(w, "Error saving data", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	// unique key (user_id, game_id, revision) กันการอัพโหลดพร้อมกันจากสองอุปกรณ์
+	_, err = db.Exec(`
+		INSERT INTO game_saves (user_id, game_id, revision, storage_url, size_bytes, checksum)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, gameID, revision, url, len(data), checksum)
+	if err != nil {
+		storage.Remove(url)
+		if _, duplicate := duplicateKeyName(err); duplicate {
+			latest, _ := latestCloudSave(db, userID, gameID, 0)
+			writeSaveConflict(w, latest)
+			return
+		}
+		fmt.Printf("❌ Error recording cloud save: %v\n", err)
+		utils.JSONError(w, "Error saving data", http.StatusInternalServerError)
+		return
+	}
+
+	pruneCloudSaves(userID, gameID, revision)
+
+	fmt.Printf("💾 Cloud save stored: user %d, game %d, revision %d (%d bytes)\n", userID, gameID, revision, len(data))
+	save, err := latestCloudSave(db, userID, gameID, revision)
+	if err != nil || save == nil {
+		save = &cloudSave{Revision: revision, SizeBytes: len(data), Checksum: checksum}
+	}
+	utils.JSONResponse(w, save, http.StatusCreated)
+}
+
+// writeSaveConflict ตอบ 409 พร้อมข้อมูลเซฟล่าสุดบนเซิร์ฟเวอร์
+func writeSaveConflict(w http.ResponseWriter, current *cloudSave) {
+	details := map[string]interface{}{"current_revision": 0}
+	if current != nil {
+		details["current_revision"] = current.Revision
+		details["current_checksum"] = current.Checksum
+		details["updated_at"] = current.CreatedAt
+	}
+	utils.JSONErrorDetails(w, utils.ErrSaveConflict, "Save was updated from another device", details, http.StatusConflict)
+}
+
+// pruneCloudSaves ลบเวอร์ชันที่เก่ากว่า cloudSaveVersionsKept เวอร์ชันล่าสุด (ทั้งแถวและไฟล์ใน storage)
+func pruneCloudSaves(userID, gameID, latestRevision int) {
+	cutoff := latestRevision - cloudSaveVersionsKept
+	if cutoff <= 0 {
+		return
+	}
+	rows, err := db.Query(`
+		SELECT storage_url FROM game_saves WHERE user_id = ? AND game_id = ? AND revision <= ?
+	`, userID, gameID, cutoff)
+	if err != nil {
+		fmt.Printf("⚠️ Error fetching old cloud saves: %v\n", err)
+		return
+	}
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			// ไม่ลบแถวที่อ่าน URL ไม่ได้ ไม่เช่นนั้นไฟล์จะค้างอยู่ใน storage โดยไม่มีใครอ้างถึง
+			rows.Close()
+			fmt.Printf("⚠️ Error scanning old cloud save: %v\n", err)
+			return
+		}
+		urls = append(urls, url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("⚠️ Error reading old cloud saves: %v\n", err)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM game_saves WHERE user_id = ? AND game_id = ? AND revision <= ?", userID, gameID, cutoff); err != nil {
+		fmt.Printf("⚠️ Error deleting old cloud saves: %v\n", err)
+		return
+	}
+	for _, url := range urls {
+		if err := storage.Remove(url); err != nil {
+			fmt.Printf("⚠️ Error deleting old cloud save file: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ขนาด response ขั้นต่ำที่จะบีบอัด (ปรับได้ด้วย COMPRESSION_MIN_BYTES)
+const defaultCompressionMinBytes = 1024
+
+func compressionMinBytes() int {
+	if n, err := strconv.Atoi(os.Getenv("COMPRESSION_MIN_BYTES")); err == nil && n >= 0 {
+		return n
+	}
+	return defaultCompressionMinBytes
+}
+
+// negotiateEncoding เลือกวิธีบีบอัดจาก Accept-Encoding (gzip ก่อน deflate, ข้ามค่าที่ q=0)
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			accepted[name] = true
+		}
+	}
+
+	if accepted["gzip"] {
+		return "gzip"
+	}
+	if accepted["deflate"] {
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter เก็บข้อมูลไว้จนถึงขนาดขั้นต่ำแล้วจึงตัดสินใจว่าจะบีบอัดหรือไม่
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status  int
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser // nil หมายถึงส่งข้อมูลตรงโดยไม่บีบอัด
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.writer != nil {
+			return cw.writer.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minBytes {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide เริ่มส่ง response โดยเลือกบีบอัดเมื่อข้อมูลใหญ่พอและชนิดข้อมูลเหมาะสม
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	header := cw.Header()
+
+	compressible := len(cw.buf) >= cw.minBytes &&
+		header.Get("Content-Encoding") == "" &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified &&
+		!strings.HasPrefix(header.Get("Content-Type"), "image/") &&
+		!strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+
+	if compressible {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			cw.writer = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.writer, _ = flate.NewWriter(cw.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.writer != nil {
+		_, err := cw.writer.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush รองรับ response แบบ streaming (เช่น CSV export)
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.decide()
+	}
+	if gz, ok := cw.writer.(*gzip.Writer); ok {
+		gz.Flush()
+	} else if fl, ok := cw.writer.(*flate.Writer); ok {
+		fl.Flush()
+	}
+	// writer ด้านนอกอาจเป็น wrapper ของ middleware อื่น จึงใช้ ResponseController หา Flusher ตาม Unwrap
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close ส่งข้อมูลที่ค้างอยู่และปิดตัวบีบอัด
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// handler ไม่ได้เขียนอะไรเลย ปล่อยให้ net/http จัดการ
+			return
+		}
+		cw.decide()
+	}
+	if cw.writer != nil {
+		cw.writer.Close()
+	}
+}
+
+// Compress middleware บีบอัด response ด้วย gzip/deflate ตาม Accept-Encoding ของ client
+func Compress(next http.Handler) http.Handler {
+	minBytes := compressionMinBytes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+		// ไม่บีบอัด HEAD, range request และไฟล์ภาพ (บีบอัดมาแล้ว)
+		if encoding == "" || r.Method == "HEAD" || r.Header.Get("Range") != "" || strings.HasPrefix(r.URL.Path, "/uploads/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: minBytes}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/cache"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// catalogVersionKey key ใน cache ที่เก็บเวลาที่ข้อมูลร้านค้าเปลี่ยนล่าสุด
+// (ไม่ขึ้นต้นด้วย catalogCachePrefix เพื่อไม่ให้ถูกลบตอนล้าง cache)
+const catalogVersionKey = "catalog-version"
+
+// catalogLastModified คืนค่าเวลาที่ข้อมูลร้านค้าเปลี่ยนล่าสุด
+// ถ้ายังไม่เคยบันทึกไว้จะใช้เวลาปัจจุบัน (เช่น หลังรีสตาร์ทเซิร์ฟเวอร์ที่ใช้ in-memory cache)
+func catalogLastModified() time.Time {
+	if data, ok := cache.Default.Get(catalogVersionKey); ok {
+		if nanos, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			return time.Unix(0, nanos)
+		}
+	}
+	return bumpCatalogVersion()
+}
+
+// bumpCatalogVersion บันทึกว่าข้อมูลร้านค้าเพิ่งเปลี่ยน (ทำให้ ETag เดิมใช้ไม่ได้)
+func bumpCatalogVersion() time.Time {
+	now := time.Now()
+	cache.Default.Set(catalogVersionKey, []byte(strconv.FormatInt(now.UnixNano(), 10)), 365*24*time.Hour)
+	return now
+}
+
+// ConditionalCatalog middleware รองรับ ETag / Last-Modified สำหรับ endpoint สาธารณะของร้านค้า
+// client หรือ CDN ที่ส่ง If-None-Match / If-Modified-Since มาจะได้ 304 โดยไม่ต้อง query ฐานข้อมูล
+func ConditionalCatalog(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			next(w, r)
+			return
+		}
+
+		modified := catalogLastModified()
+		etag := fmt.Sprintf(`W/"%x-%x"`, modified.UnixNano(), crc32.ChecksumIEEE([]byte(requestLocale(r)+":"+r.URL.Path+"?"+r.URL.RawQuery)))
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", "public, max-age=0, must-revalidate")
+
+		// If-None-Match มีลำดับความสำคัญสูงกว่า If-Modified-Since
+		if match := r.Header.Get("If-None-Match"); match != "" {
+			if etagMatches(match, etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		} else if since := r.Header.Get("If-Modified-Since"); since != "" {
+			// HTTP date ละเอียดแค่ระดับวินาที
+			if t, err := http.ParseTime(since); err == nil && !modified.Truncate(time.Second).After(t) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// etagMatches ตรวจสอบ If-None-Match (รองรับหลายค่าและ *) แบบ weak comparison
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
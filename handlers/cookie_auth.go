@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"go-api-game/auth"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ชื่อ cookie และ header ที่ใช้ในโหมดยืนยันตัวตนด้วย cookie
+const (
+	SessionCookieName = "session_token" // JWT (HttpOnly - JavaScript อ่านไม่ได้)
+	CSRFCookieName    = "csrf_token"    // ค่า CSRF ที่ frontend อ่านแล้วส่งกลับมาใน header
+	CSRFHeaderName    = "X-CSRF-Token"
+)
+
+// cookieSameSite อ่านค่า SameSite จาก COOKIE_SAMESITE (lax, strict, none) ค่าเริ่มต้นคือ lax
+// ถ้า frontend อยู่คนละโดเมนกับ API ต้องใช้ none (และต้องเป็น HTTPS)
+func cookieSameSite() http.SameSite {
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// cookieSecure ส่ง cookie ผ่าน HTTPS เท่านั้น (COOKIE_SECURE=true หรือเมื่อ SameSite=None ซึ่ง browser บังคับ)
+func cookieSecure() bool {
+	return os.Getenv("COOKIE_SECURE") == "true" || cookieSameSite() == http.SameSiteNoneMode
+}
+
+// setAuthCookies ตั้ง cookie ของ session และ CSRF token หลังเข้าสู่ระบบ คืนค่า CSRF token ให้ส่งกลับใน response
+func setAuthCookies(w http.ResponseWriter, token string) (string, error) {
+	csrfBytes := make([]byte, 32)
+	if _, err := rand.Read(csrfBytes); err != nil {
+		return "", err
+	}
+	csrfToken := hex.EncodeToString(csrfBytes)
+	maxAge := int(auth.TokenTTL.Seconds())
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cookieSecure(),
+		SameSite: cookieSameSite(),
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: false, // frontend ต้องอ่านค่านี้เพื่อส่งใน header X-CSRF-Token
+		Secure:   cookieSecure(),
+		SameSite: cookieSameSite(),
+	})
+
+	return csrfToken, nil
+}
+
+// clearAuthCookies ลบ cookie ของ session และ CSRF token
+func clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{SessionCookieName, CSRFCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: name == SessionCookieName,
+			Secure:   cookieSecure(),
+			SameSite: cookieSameSite(),
+		})
+	}
+}
+
+// requestToken ดึง token จาก Authorization header ก่อน ถ้าไม่มีจึงใช้ cookie
+// fromCookie บอกว่า token มาจาก cookie (ต้องตรวจสอบ CSRF)
+func requestToken(r *http.Request) (token string, fromCookie bool) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1], false
+		}
+		return "", false
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+	return "", false
+}
+
+// validCSRF ตรวจสอบ CSRF token แบบ double-submit (header ต้องตรงกับ cookie)
+// เมธอดที่ไม่เปลี่ยนแปลงข้อมูล (GET, HEAD, OPTIONS) ไม่ต้องตรวจสอบ
+func validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeaderName)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
+
+// LogoutHandler ends the current session
+// ฟังก์ชันสำหรับออกจากระบบ (POST /logout) เพิกถอน session ปัจจุบันและลบ cookie
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenID := requestTokenID(r)
+	if tokenID != "" {
+		if _, err := db.Exec("UPDATE user_sessions SET revoked_at = NOW() WHERE token_id = ? AND revoked_at IS NULL", tokenID); err != nil {
+			fmt.Printf("❌ Error revoking session on logout: %v\n", err)
+			utils.JSONError(w, "Error logging out", http.StatusInternalServerError)
+			return
+		}
+	}
+	clearAuthCookies(w)
+
+	userID, _ := requestUserID(r)
+	fmt.Printf("👋 User %d logged out\n", userID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Logged out",
+	}, http.StatusOK)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+)
+
+// dashboardLowStockLimit จำนวนเกมสต็อกใกล้หมดที่แสดงใน dashboard (ดูทั้งหมดได้ที่ /admin/inventory/low-stock)
+const dashboardLowStockLimit = 10
+
+// AdminDashboardHandler returns the admin dashboard summary
+// ฟังก์ชันสำหรับดึงข้อมูลสรุปของ dashboard ผู้ดูแลระบบใน request เดียว
+// (แทนการเรียก /admin/stats, /admin/stats/revenue, /admin/discounts, /admin/inventory/low-stock แยกกันตอนเปิดหน้า)
+// GET /admin/dashboard
+//
+// ตัวเลขของวันนี้นับตั้งแต่เที่ยงคืนตามเวลาของฐานข้อมูล รายได้คิดแบบเดียวกับ /admin/stats/revenue (ไม่รวมภาษี)
+func AdminDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	threshold := lowStockThreshold()
+
+	// ตัวเลขทั้งหมดดึงใน query เดียว
+	var summary struct {
+		Revenue         float64
+		Orders          int
+		Signups         int
+		ActiveDiscounts int
+		LowStock        int
+		PendingOrders   int
+		RefundsToday    int
+		RefundedAmount  float64
+	}
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COALESCE(SUM(p.final_amount), 0) FROM purchases p WHERE p.purchase_date >= CURDATE()),
+			(SELECT COUNT(*) FROM purchases p WHERE p.purchase_date >= CURDATE()),
+			(SELECT COUNT(*) FROM users u WHERE u.created_at >= CURDATE()),
+			(SELECT COUNT(*) FROM discount_codes dc
+			 WHERE dc.archived_at IS NULL AND dc.active = 1
+			   AND (dc.start_date IS NULL OR dc.start_date <= CURDATE())
+			   AND (dc.end_date IS NULL OR dc.end_date >= CURDATE())),
+			(SELECT COUNT(*) FROM games g
+			 WHERE g.status = 'published' AND g.stock_count IS NOT NULL AND g.stock_count <= ?),
+			(SELECT COUNT(*) FROM purchases p WHERE p.status IN (?, ?)),
+			(SELECT COUNT(*) FROM purchase_status_history h WHERE h.to_status = ? AND h.created_at >= CURDATE()),
+			(SELECT COALESCE(SUM(p.final_amount), 0) FROM purchases p
+			 WHERE p.id IN (SELECT h.purchase_id FROM purchase_status_history h
+			                WHERE h.to_status = ? AND h.created_at >= CURDATE()))
+	`, threshold, PurchasePending, PurchasePaid, PurchaseRefunded, PurchaseRefunded).Scan(
+		&summary.Revenue, &summary.Orders, &summary.Signups, &summary.ActiveDiscounts,
+		&summary.LowStock, &summary.PendingOrders, &summary.RefundsToday, &summary.RefundedAmount)
+	if err != nil {
+		fmt.Printf("❌ Error fetching dashboard summary: %v\n", err)
+		utils.JSONError(w, "Error fetching dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	lowStock, err := lowStockGames(threshold, false)
+	if err != nil {
+		fmt.Printf("❌ Error fetching low stock games: %v\n", err)
+		utils.JSONError(w, "Error fetching dashboard", http.StatusInternalServerError)
+		return
+	}
+	if len(lowStock) > dashboardLowStockLimit {
+		lowStock = lowStock[:dashboardLowStockLimit]
+	}
+
+	averageOrder := 0.0
+	if summary.Orders > 0 {
+		averageOrder = summary.Revenue / float64(summary.Orders)
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"today": map[string]interface{}{
+			"revenue":             roundCents(summary.Revenue),
+			"orders":              summary.Orders,
+			"average_order_value": roundCents(averageOrder),
+			"new_signups":         summary.Signups,
+			"refunds":             summary.RefundsToday,
+			"refunded_amount":     roundCents(summary.RefundedAmount),
+		},
+		"active_discounts": summary.ActiveDiscounts,
+		"pending_orders":   summary.PendingOrders,
+		"low_stock": map[string]interface{}{
+			"threshold": threshold,
+			"count":     summary.LowStock,
+			"games":     lowStock,
+		},
+	}, http.StatusOK)
+}
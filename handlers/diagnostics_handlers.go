@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-api-game/tracing"
+	"go-api-game/utils"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// defaultDiagnosticsLimit จำนวน endpoint และคำสั่ง SQL ที่ช้าที่สุดที่แสดง (ปรับด้วย ?limit= สูงสุด 100)
+const defaultDiagnosticsLimit = 10
+
+// poolDiagnostics สถานะของ connection pool พร้อมสัดส่วนการใช้งาน
+func poolDiagnostics(stats sql.DBStats) map[string]interface{} {
+	// saturation = connection ที่ใช้อยู่ / จำนวนสูงสุด (ไม่จำกัดจำนวน = null)
+	var saturation interface{}
+	if stats.MaxOpenConnections > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+	return map[string]interface{}{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"saturation":           saturation,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+	}
+}
+
+// AdminDiagnosticsHandler handles runtime diagnostics for operators
+// ฟังก์ชันสำหรับดูข้อมูลวินิจฉัยระบบ: endpoint และคำสั่ง SQL ที่ช้าที่สุดจากตัวอย่างล่าสุดในหน่วยความจำ
+// จำนวน goroutine และสัดส่วนการใช้ connection pool ของฐานข้อมูล (ต้องได้รับสิทธิ์ diagnostics)
+// GET /admin/diagnostics?limit=10
+func AdminDiagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultDiagnosticsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 100 {
+			utils.JSONError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	endpoints, requestSamples := tracing.SlowestRequests(limit)
+	statements, statementSamples := tracing.SlowestStatements(limit)
+
+	pools := map[string]interface{}{"primary": poolDiagnostics(db.Stats())}
+	if replica != nil {
+		pools["replica"] = poolDiagnostics(replica.Stats())
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"generated_at": time.Now().UTC(),
+		"slowest_endpoints": map[string]interface{}{
+			"samples": requestSamples,
+			"items":   endpoints,
+		},
+		"slowest_queries": map[string]interface{}{
+			"samples": statementSamples,
+			"items":   statements,
+		},
+		"runtime": map[string]interface{}{
+			"goroutines":      runtime.NumGoroutine(),
+			"heap_alloc_mb":   float64(memory.HeapAlloc) / (1 << 20),
+			"gc_cycles":       memory.NumGC,
+			"go_version":      runtime.Version(),
+			"tracing_enabled": tracing.Enabled(),
+		},
+		"db_pools": pools,
+	}, http.StatusOK)
+}
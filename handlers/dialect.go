@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/tracing"
+	"strings"
+)
+
+// Dialect ส่วนของ SQL ที่เขียนต่างกันระหว่างฐานข้อมูล
+// ระบบรองรับเฉพาะ MySQL: การรองรับ PostgreSQL ถูกตัดออกจากขอบเขต เพราะ schema.go และ query จำนวนมาก
+// (placeholder ?, INSERT IGNORE, IF(), CURDATE(), DATE_SUB/INTERVAL) เป็นของ MySQL และไม่มีการทดสอบกับ PostgreSQL
+// interface นี้ใช้รวมจุดที่ต่างกันไว้ที่เดียว ถ้าจะเพิ่มฐานข้อมูลอื่นต้องแปลง query เหล่านั้นและทดสอบกับทั้งสองฐานข้อมูลก่อน
+type Dialect interface {
+	// Name ชื่อ dialect (ค่าของ DB_DIALECT)
+	Name() string
+	// DriverName ชื่อ driver ที่ใช้กับ sql.Open
+	DriverName() string
+	// DateFormat แปลงวันที่เป็น string ตามรูปแบบของ MySQL DATE_FORMAT (เช่น %Y-%m-%d)
+	DateFormat(expr, format string) string
+	// ConfigureDSN เพิ่มค่าที่ระบบต้องการใน DSN (scan วันเวลาเป็น time.Time และใช้ time zone UTC ใน session)
+	ConfigureDSN(dsn string) string
+	// OnConflict ส่วนต้นของ upsert ตามด้วยรายการ col = ค่า (keys คือคอลัมน์ของ primary/unique key)
+	OnConflict(keys ...string) string
+	// Excluded ค่าใหม่ของคอลัมน์ที่ insert ไม่สำเร็จเพราะซ้ำ (ใช้ใน upsert)
+	Excluded(column string) string
+}
+
+// dialect dialect ที่ใช้อยู่ (ค่าเริ่มต้น MySQL)
+var dialect Dialect = mysqlDialect{}
+
+// dialects dialect ที่รองรับ (DB_DIALECT)
+var dialects = map[string]Dialect{
+	"mysql": mysqlDialect{},
+}
+
+// SetDialect selects the SQL dialect used by the handlers
+// ฟังก์ชันสำหรับเลือก dialect ตามชื่อ (ว่าง = mysql) ต้องเรียกก่อน sql.Open และ InitDB
+func SetDialect(name string) error {
+	if name == "" {
+		name = "mysql"
+	}
+	d, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unsupported DB_DIALECT %q (only mysql is supported)", name)
+	}
+	dialect = d
+	return nil
+}
+
+// DatabaseDriver returns the database/sql driver name of the selected dialect
+// ฟังก์ชันสำหรับดึงชื่อ driver ของ dialect ที่เลือก (driver ที่สร้าง span ของคำสั่ง SQL เมื่อเปิด tracing)
+func DatabaseDriver() string {
+	return tracing.Driver(dialect.DriverName())
+}
+
+// DatabaseDialect returns the name of the selected dialect
+// ฟังก์ชันสำหรับดึงชื่อ dialect ที่เลือก (ใช้แสดงใน log ตอนเริ่มระบบ)
+func DatabaseDialect() string {
+	return dialect.Name()
+}
+
+// upsertColumns รายการ col = ค่าใหม่ สำหรับต่อท้าย OnConflict
+func upsertColumns(columns ...string) string {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = column + " = " + dialect.Excluded(column)
+	}
+	return strings.Join(assignments, ", ")
+}
+
+// DatabaseDSN returns the DSN with the parameters the handlers rely on
+// ฟังก์ชันสำหรับเตรียม DSN ก่อน sql.Open (วันเวลาทั้งหมดเก็บและอ่านเป็น UTC)
+func DatabaseDSN(dsn string) string {
+	return dialect.ConfigureDSN(dsn)
+}
+
+// appendQueryParams เพิ่ม key=value ที่ยังไม่มีใน DSN แบบ query string (...?a=1&b=2)
+func appendQueryParams(dsn string, params [][2]string) string {
+	for _, param := range params {
+		if strings.Contains(dsn, param[0]+"=") {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&"
+		} else {
+			dsn += "?"
+		}
+		dsn += param[0] + "=" + param[1]
+	}
+	return dsn
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string       { return "mysql" }
+func (mysqlDialect) DriverName() string { return "mysql" }
+
+func (mysqlDialect) DateFormat(expr, format string) string {
+	return "DATE_FORMAT(" + expr + ", '" + format + "')"
+}
+
+// parseTime=true ให้ driver คืน DATETIME เป็น time.Time, loc=UTC ตีความค่าเป็น UTC
+// และ time_zone='+00:00' ให้ NOW()/CURRENT_TIMESTAMP ของ session เป็น UTC ตรงกับค่า time.Time ที่ driver ส่งไป
+// ค่าที่ตั้งไว้ใน DB_DSN แล้วจะไม่ถูกแทนที่
+func (mysqlDialect) ConfigureDSN(dsn string) string {
+	return appendQueryParams(dsn, [][2]string{
+		{"parseTime", "true"},
+		{"loc", "UTC"},
+		{"time_zone", "%27%2B00%3A00%27"},
+	})
+}
+
+func (mysqlDialect) OnConflict(keys ...string) string { return "ON DUPLICATE KEY UPDATE" }
+
+func (mysqlDialect) Excluded(column string) string { return "VALUES(" + column + ")" }
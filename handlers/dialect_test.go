@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestSetDialectSupportsOnlyMySQL(t *testing.T) {
+	t.Cleanup(func() { dialect = mysqlDialect{} })
+
+	for _, name := range []string{"", "mysql", "MySQL"} {
+		if err := SetDialect(name); err != nil || DatabaseDialect() != "mysql" {
+			t.Errorf("SetDialect(%q) = %v, dialect %s", name, err, DatabaseDialect())
+		}
+	}
+	for _, name := range []string{"postgres", "pgx", "sqlite"} {
+		if err := SetDialect(name); err == nil {
+			t.Errorf("SetDialect(%q) succeeded, want unsupported", name)
+		}
+	}
+}
+
+func TestMySQLConfigureDSNKeepsExplicitParams(t *testing.T) {
+	got := DatabaseDSN("user:pw@tcp(db:3306)/store?loc=Asia%2FBangkok")
+	want := "user:pw@tcp(db:3306)/store?loc=Asia%2FBangkok&parseTime=true&time_zone=%27%2B00%3A00%27"
+	if got != want {
+		t.Errorf("DatabaseDSN = %q, want %q", got, want)
+	}
+}
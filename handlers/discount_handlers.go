@@ -14,8 +14,6 @@ import (
 // AdminDiscountHandler handles discount code management
 // ฟังก์ชันหลักสำหรับจัดการรหัสส่วนลดโดยผู้ดูแลระบบ
 func AdminDiscountHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("🎯 AdminDiscountHandler: %s %s\n", r.Method, r.URL.Path)
-
 	// Extract ID จาก URL ถ้ามี
 	// ตัวอย่าง URL: /admin/discounts/123 → id = 123
 	var id int
@@ -29,13 +27,19 @@ func AdminDiscountHandler(w http.ResponseWriter, r *http.Request) {
 	// กำหนดการทำงานตาม HTTP Method
 	switch r.Method {
 	case "GET":
-		if id > 0 {
+		if id > 0 && len(pathParts) >= 4 && pathParts[3] == "usages" {
+			getDiscountUsages(w, r, id) // ดึงประวัติการใช้ส่วนลด
+		} else if id > 0 {
 			getDiscountByID(w, r, id) // ดึงส่วนลดเฉพาะ ID
 		} else {
 			getAllDiscounts(w, r) // ดึงส่วนลดทั้งหมด
 		}
 	case "POST":
-		createDiscount(w, r) // สร้างส่วนลดใหม่
+		if len(pathParts) >= 3 && pathParts[2] == "purge" {
+			purgeArchivedDiscounts(w, r) // ลบส่วนลดที่อยู่ในคลังออกถาวร
+		} else {
+			createDiscount(w, r) // สร้างส่วนลดใหม่
+		}
 	case "PUT":
 		if id > 0 {
 			updateDiscountWithReset(w, r, id) // อัพเดทส่วนลด + รีเซ็ตการใช้งาน
@@ -44,7 +48,7 @@ func AdminDiscountHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	case "DELETE":
 		if id > 0 {
-			deleteDiscountWithCleanup(w, r, id) // ลบส่วนลด + ลบประวัติการใช้งาน
+			archiveDiscount(w, r, id) // เก็บส่วนลดเข้าคลัง (ไม่ลบประวัติการใช้งาน)
 		} else {
 			utils.JSONError(w, "Discount ID required", http.StatusBadRequest)
 		}
@@ -53,24 +57,49 @@ func AdminDiscountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// discountStatusFilters เงื่อนไข SQL สำหรับกรองส่วนลดตามสถานะ (?status=)
+var discountStatusFilters = map[string]string{
+	"":         "dc.archived_at IS NULL",
+	"active":   "dc.archived_at IS NULL AND dc.active = 1",
+	"inactive": "dc.archived_at IS NULL AND dc.active = 0",
+	"archived": "dc.archived_at IS NOT NULL",
+	"all":      "1=1",
+}
+
+// discountStatus คืนค่าสถานะของส่วนลดสำหรับแสดงผล
+func discountStatus(active bool, archivedAt utils.NullTimestamp) string {
+	if archivedAt.Valid {
+		return "archived"
+	}
+	if active {
+		return "active"
+	}
+	return "inactive"
+}
+
 // GET /admin/discounts - ดึงส่วนลดทั้งหมด
 func getAllDiscounts(w http.ResponseWriter, r *http.Request) {
-	// เรียกตรวจสอบอัตโนมัติก่อนดึงข้อมูล (รันใน goroutine เพื่อไม่ให้ block request)
-	go autoDeactivateDiscounts()
-	go autoDeleteAllExpiredAndInactiveDiscounts()
-	fmt.Println("🔍 Fetching all discount codes")
+	// กรองตามสถานะ: active, inactive, archived หรือ all (ค่าเริ่มต้นคือทุกอันที่ยังไม่ archive)
+	status := r.URL.Query().Get("status")
+	statusFilter, ok := discountStatusFilters[status]
+	if !ok {
+		utils.JSONError(w, "Invalid status. Use active, inactive, archived or all", http.StatusBadRequest)
+		return
+	}
+	fmt.Printf("🔍 Fetching discount codes (status: '%s')\n", status)
 
 	// ดึงข้อมูลส่วนลดทั้งหมดพร้อมจำนวนการใช้งาน
 	rows, err := db.Query(`
 		SELECT 
 			dc.id, dc.code, dc.type, dc.value, dc.min_total, 
-			DATE_FORMAT(dc.start_date, '%Y-%m-%d') as start_date,
-			DATE_FORMAT(dc.end_date, '%Y-%m-%d') as end_date,
+			` + dialect.DateFormat("dc.start_date", "%Y-%m-%d") + ` as start_date,
+			` + dialect.DateFormat("dc.end_date", "%Y-%m-%d") + ` as end_date,
 			dc.usage_limit, dc.single_use_per_user, dc.active,
-			dc.created_at,
-			COUNT(udc.id) as usage_count
+			dc.created_at, dc.archived_at,
+			COUNT(udc.id) as usage_count, dc.auto_apply
 		FROM discount_codes dc
 		LEFT JOIN user_discount_codes udc ON dc.id = udc.discount_code_id
+		WHERE ` + statusFilter + `
 		GROUP BY dc.id
 		ORDER BY dc.created_at DESC
 	`)
@@ -89,15 +118,18 @@ func getAllDiscounts(w http.ResponseWriter, r *http.Request) {
 		var id int
 		var code, discountType string
 		var value, minTotal float64
-		var startDate, endDate, createdAt sql.NullString
+		var startDate, endDate sql.NullString
+		var createdAt utils.Timestamp
+		var archivedAt utils.NullTimestamp
 		var usageLimit sql.NullInt64
-		var singleUsePerUser, active bool
+		var singleUsePerUser, active, autoApply bool
 		var usageCount int
 
-		err := rows.Scan(&id, &code, &discountType, &value, &minTotal, &startDate, &endDate, &usageLimit, &singleUsePerUser, &active, &createdAt, &usageCount)
+		err := rows.Scan(&id, &code, &discountType, &value, &minTotal, &startDate, &endDate, &usageLimit, &singleUsePerUser, &active, &createdAt, &archivedAt, &usageCount, &autoApply)
 		if err != nil {
 			fmt.Printf("❌ Error scanning discount row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching discount codes", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object ส่วนลด
@@ -110,8 +142,10 @@ func getAllDiscounts(w http.ResponseWriter, r *http.Request) {
 			"usage_limit":         usageLimit.Int64,
 			"single_use_per_user": singleUsePerUser,
 			"active":              active,
-			"created_at":          createdAt.String,
+			"created_at":          createdAt,
 			"usage_count":         usageCount, // เพิ่มจำนวนการใช้งาน
+			"status":              discountStatus(active, archivedAt),
+			"auto_apply":          autoApply,
 		}
 
 		// ตั้งค่าวันที่ถ้ามีค่า
@@ -121,6 +155,9 @@ func getAllDiscounts(w http.ResponseWriter, r *http.Request) {
 		if endDate.Valid {
 			discount["end_date"] = endDate.String
 		}
+		if archivedAt.Valid {
+			discount["archived_at"] = archivedAt
+		}
 
 		discounts = append(discounts, discount)
 		count++
@@ -149,34 +186,44 @@ func getDiscountByID(w http.ResponseWriter, r *http.Request, id int) {
 	// ตัวแปรสำหรับเก็บข้อมูลส่วนลด
 	var code, discountType string
 	var value, minTotal float64
-	var startDate, endDate, createdAt sql.NullString
+	var startDate, endDate sql.NullString
+	var createdAt utils.Timestamp
+	var archivedAt utils.NullTimestamp
 	var usageLimit sql.NullInt64
-	var singleUsePerUser, active bool
+	var singleUsePerUser, active, autoApply bool
 	var usageCount int
 
 	// ดึงข้อมูลส่วนลดจากฐานข้อมูล
 	err := db.QueryRow(`
 		SELECT 
 			dc.code, dc.type, dc.value, dc.min_total, 
-			DATE_FORMAT(dc.start_date, '%Y-%m-%d') as start_date,
-			DATE_FORMAT(dc.end_date, '%Y-%m-%d') as end_date,
-			dc.usage_limit, dc.single_use_per_user, dc.active, dc.created_at,
-			COUNT(udc.id) as usage_count
+			`+dialect.DateFormat("dc.start_date", "%Y-%m-%d")+` as start_date,
+			`+dialect.DateFormat("dc.end_date", "%Y-%m-%d")+` as end_date,
+			dc.usage_limit, dc.single_use_per_user, dc.active, dc.created_at, dc.archived_at,
+			COUNT(udc.id) as usage_count, dc.auto_apply
 		FROM discount_codes dc
 		LEFT JOIN user_discount_codes udc ON dc.id = udc.discount_code_id
 		WHERE dc.id = ?
 		GROUP BY dc.id
-	`, id).Scan(&code, &discountType, &value, &minTotal, &startDate, &endDate, &usageLimit, &singleUsePerUser, &active, &createdAt, &usageCount)
+	`, id).Scan(&code, &discountType, &value, &minTotal, &startDate, &endDate, &usageLimit, &singleUsePerUser, &active, &createdAt, &archivedAt, &usageCount, &autoApply)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Discount code not found", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Discount code not found", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Error fetching discount code", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	// รายชื่อผู้ใช้เป้าหมายที่ไม่ครบทำให้ admin เข้าใจผิดว่ารหัสใช้ได้กับทุกคน จึงตอบ error แทน
+	targets, err := discountTargets(id)
+	if err != nil {
+		fmt.Printf("❌ Error fetching discount targets: %v\n", err)
+		utils.JSONError(w, "Error fetching discount code", http.StatusInternalServerError)
+		return
+	}
+
 	// สร้าง object ส่วนลด
 	discount := map[string]interface{}{
 		"id":                  id,
@@ -187,8 +234,11 @@ func getDiscountByID(w http.ResponseWriter, r *http.Request, id int) {
 		"usage_limit":         usageLimit.Int64,
 		"single_use_per_user": singleUsePerUser,
 		"active":              active,
-		"created_at":          createdAt.String,
+		"created_at":          createdAt,
 		"usage_count":         usageCount, // เพิ่มจำนวนการใช้งาน
+		"status":              discountStatus(active, archivedAt),
+		"auto_apply":          autoApply,
+		"target_user_ids":     targets,
 	}
 
 	// ตั้งค่าวันที่ถ้ามีค่า
@@ -198,26 +248,163 @@ func getDiscountByID(w http.ResponseWriter, r *http.Request, id int) {
 	if endDate.Valid {
 		discount["end_date"] = endDate.String
 	}
+	if archivedAt.Valid {
+		discount["archived_at"] = archivedAt
+	}
 
 	fmt.Printf("✅ Discount code found: ID=%d, Code=%s, Usage Count=%d\n", id, code, usageCount)
 	utils.JSONResponse(w, discount, http.StatusOK)
 }
 
+// GET /admin/discounts/{id}/usages - ดึงประวัติการใช้ส่วนลด (รองรับ ?format=csv)
+// แสดงผู้ใช้ที่ใช้รหัส เวลาที่ใช้ การซื้อที่เกี่ยวข้อง และผลกระทบต่อรายได้
+func getDiscountUsages(w http.ResponseWriter, r *http.Request, id int) {
+	fmt.Printf("🔍 Fetching usages for discount code: ID=%d\n", id)
+
+	// ตรวจสอบว่ามีส่วนลดนี้อยู่จริง
+	var code string
+	err := db.QueryRow("SELECT code FROM discount_codes WHERE id = ?", id).Scan(&code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Discount code not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching discount code", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// ดึงการซื้อทั้งหมดที่ใช้ส่วนลดนี้
+	rows, err := db.Query(`
+		SELECT p.id, p.user_id, COALESCE(u.username, ''), COALESCE(u.email, ''),
+		       p.total_amount, p.final_amount,
+		       p.purchase_date
+		FROM purchases p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE p.discount_code_id = ?
+		ORDER BY p.purchase_date DESC
+	`, id)
+	if err != nil {
+		fmt.Printf("❌ Error fetching discount usages: %v\n", err)
+		utils.JSONError(w, "Error fetching discount usages", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// ส่งออกเป็นไฟล์ CSV (เขียนทีละแถวโดยไม่โหลดทั้งหมดเข้าหน่วยความจำ)
+	if r.URL.Query().Get("format") == "csv" {
+		cw := utils.CSVWriter(w, fmt.Sprintf("discount_%s_usages.csv", code))
+		cw.Write([]string{"purchase_id", "user_id", "username", "email", "order_total", "discount_amount", "final_amount", "used_at"})
+		for rows.Next() {
+			var purchaseID, userID int
+			var username, email string
+			var usedAt utils.Timestamp
+			var totalAmount, finalAmount float64
+			if err := rows.Scan(&purchaseID, &userID, &username, &email, &totalAmount, &finalAmount, &usedAt); err != nil {
+				fmt.Printf("❌ Error scanning discount usage row: %v\n", err)
+				abortStream()
+			}
+			cw.Write([]string{
+				strconv.Itoa(purchaseID),
+				strconv.Itoa(userID),
+				username,
+				email,
+				strconv.FormatFloat(totalAmount, 'f', 2, 64),
+				strconv.FormatFloat(totalAmount-finalAmount, 'f', 2, 64),
+				strconv.FormatFloat(finalAmount, 'f', 2, 64),
+				usedAt.String(),
+			})
+		}
+		if err := rows.Err(); err != nil {
+			fmt.Printf("❌ Error reading discount usage rows: %v\n", err)
+			abortStream()
+		}
+		cw.Flush()
+		return
+	}
+
+	var usages []map[string]interface{}
+	var orderTotal, discountTotal, finalTotal float64
+
+	// อ่านข้อมูลการใช้งานทีละแถว
+	for rows.Next() {
+		var purchaseID, userID int
+		var username, email string
+		var usedAt utils.Timestamp
+		var totalAmount, finalAmount float64
+
+		if err := rows.Scan(&purchaseID, &userID, &username, &email, &totalAmount, &finalAmount, &usedAt); err != nil {
+			fmt.Printf("❌ Error scanning discount usage row: %v\n", err)
+			utils.JSONError(w, "Error fetching discount usages", http.StatusInternalServerError)
+			return
+		}
+
+		usages = append(usages, map[string]interface{}{
+			"purchase_id":     purchaseID,
+			"user_id":         userID,
+			"username":        username,
+			"email":           email,
+			"order_total":     totalAmount,
+			"discount_amount": totalAmount - finalAmount,
+			"final_amount":    finalAmount,
+			"used_at":         usedAt,
+		})
+
+		// รวมยอดสำหรับสรุปผลกระทบต่อรายได้
+		orderTotal += totalAmount
+		finalTotal += finalAmount
+		discountTotal += totalAmount - finalAmount
+	}
+
+	// ตรวจสอบข้อผิดพลาดระหว่างการอ่านข้อมูล
+	if err = rows.Err(); err != nil {
+		fmt.Printf("❌ Error during rows iteration: %v\n", err)
+		utils.JSONError(w, "Error processing discount usages", http.StatusInternalServerError)
+		return
+	}
+
+	if usages == nil {
+		usages = []map[string]interface{}{}
+	}
+
+	// คำนวณสัดส่วนส่วนลดเทียบกับยอดสั่งซื้อ
+	discountRate := 0.0
+	if orderTotal > 0 {
+		discountRate = discountTotal / orderTotal * 100
+	}
+
+	fmt.Printf("✅ Discount usages found: ID=%d, Count=%d, Discount given=%.2f\n", id, len(usages), discountTotal)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"discount_id": id,
+		"code":        code,
+		"usages":      usages,
+		"summary": map[string]interface{}{
+			"usage_count":          len(usages),
+			"order_total":          orderTotal,
+			"total_discount_given": discountTotal,
+			"net_revenue":          finalTotal,
+			"discount_rate":        discountRate, // เปอร์เซ็นต์ส่วนลดเทียบกับยอดสั่งซื้อ
+		},
+	}, http.StatusOK)
+}
+
 // POST /admin/discounts - สร้างส่วนลดใหม่
 func createDiscount(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("➕ Creating new discount code")
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
-		Code             string  `json:"code"`                // รหัสส่วนลด
-		Type             string  `json:"type"`                // ประเภท (percent/fixed)
-		Value            float64 `json:"value"`               // ค่าส่วนลด
-		MinTotal         float64 `json:"min_total"`           // ยอดซื้อขั้นต่ำ
-		StartDate        *string `json:"start_date"`          // วันที่เริ่มใช้งาน
-		EndDate          *string `json:"end_date"`            // วันที่สิ้นสุด
-		UsageLimit       *int    `json:"usage_limit"`         // จำนวนครั้งที่ใช้ได้
-		SingleUsePerUser bool    `json:"single_use_per_user"` // ใช้ได้คนละครั้งเดียว
-		Active           bool    `json:"active"`              // สถานะใช้งาน
+		Code             string  `json:"code" validate:"required"`            // รหัสส่วนลด
+		Type             string  `json:"type" validate:"oneof=percent fixed"` // ประเภท (percent/fixed)
+		Value            float64 `json:"value" validate:"gt=0"`               // ค่าส่วนลด
+		MinTotal         float64 `json:"min_total" validate:"min=0"`          // ยอดซื้อขั้นต่ำ
+		StartDate        *string `json:"start_date" validate:"date"`          // วันที่เริ่มใช้งาน
+		EndDate          *string `json:"end_date" validate:"date"`            // วันที่สิ้นสุด
+		UsageLimit       *int    `json:"usage_limit"`                         // จำนวนครั้งที่ใช้ได้
+		SingleUsePerUser bool    `json:"single_use_per_user"`                 // ใช้ได้คนละครั้งเดียว
+		Active           bool    `json:"active"`                              // สถานะใช้งาน
+		AutoApply        bool    `json:"auto_apply"`                          // ให้ checkout เลือกใช้อัตโนมัติ (รหัสสาธารณะ)
+		TargetUserIDs    []int   `json:"target_user_ids"`                     // จำกัดเฉพาะผู้ใช้เหล่านี้ (targeted promo)
 	}
 
 	// แปลง JSON request body เป็น struct
@@ -226,56 +413,45 @@ func createDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validation ข้อมูล
-	if req.Code == "" {
-		utils.JSONError(w, "Discount code is required", http.StatusBadRequest)
-		return
-	}
-	if req.Value <= 0 {
-		utils.JSONError(w, "Discount value must be greater than 0", http.StatusBadRequest)
-		return
-	}
-	if req.Type != "percent" && req.Type != "fixed" {
-		utils.JSONError(w, "Discount type must be 'percent' or 'fixed'", http.StatusBadRequest)
+	// ตรวจสอบความถูกต้องของข้อมูลตาม tag validate
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
 		return
 	}
 
-	// Parse dates จาก string เป็น time.Time
+	// Parse dates จาก string เป็น time.Time (รูปแบบถูกตรวจสอบแล้ว)
 	var startDate, endDate interface{}
 	if req.StartDate != nil && *req.StartDate != "" {
-		if date, err := time.Parse("2006-01-02", *req.StartDate); err == nil {
-			startDate = date
-		} else {
-			utils.JSONError(w, "Invalid start date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
+		startDate, _ = time.Parse("2006-01-02", *req.StartDate)
 	}
 	if req.EndDate != nil && *req.EndDate != "" {
-		if date, err := time.Parse("2006-01-02", *req.EndDate); err == nil {
-			endDate = date
-		} else {
-			utils.JSONError(w, "Invalid end date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
+		endDate, _ = time.Parse("2006-01-02", *req.EndDate)
 	}
 
 	// ตรวจสอบว่า code ซ้ำหรือไม่
 	var existingCode string
 	err := db.QueryRow("SELECT code FROM discount_codes WHERE code = ?", req.Code).Scan(&existingCode)
 	if err == nil {
-		utils.JSONError(w, "Discount code already exists", http.StatusConflict)
+		utils.JSONErrorCode(w, utils.ErrCodeAlreadyExists, "Discount code already exists", http.StatusConflict)
 		return
 	} else if err != sql.ErrNoRows {
 		utils.JSONError(w, "Error checking discount code", http.StatusInternalServerError)
 		return
 	}
 
-	// สร้าง discount code ใหม่
-	result, err := db.Exec(`
+	// สร้าง discount code ใหม่พร้อมผู้ใช้เป้าหมาย
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
 		INSERT INTO discount_codes 
-		(code, type, value, min_total, start_date, end_date, usage_limit, single_use_per_user, active)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, req.Code, req.Type, req.Value, req.MinTotal, startDate, endDate, req.UsageLimit, req.SingleUsePerUser, req.Active)
+		(code, type, value, min_total, start_date, end_date, usage_limit, single_use_per_user, active, auto_apply)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.Code, req.Type, req.Value, req.MinTotal, startDate, endDate, req.UsageLimit, req.SingleUsePerUser, req.Active, req.AutoApply)
 
 	if err != nil {
 		fmt.Printf("❌ Error creating discount code: %v\n", err)
@@ -284,7 +460,18 @@ func createDiscount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, _ := result.LastInsertId()
+	if err := setDiscountTargets(tx, int(id), req.TargetUserIDs); err != nil {
+		fmt.Printf("❌ Error saving discount targets: %v\n", err)
+		utils.JSONError(w, "Error creating discount code", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error creating discount code", http.StatusInternalServerError)
+		return
+	}
+
 	fmt.Printf("✅ Discount code created: ID=%d, Code=%s\n", id, req.Code)
+	recordAudit(r, "create", "discount", id, nil, snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id))
 
 	// ส่ง response สำเร็จกลับ
 	utils.JSONResponse(w, map[string]interface{}{
@@ -299,15 +486,17 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
-		Code             string  `json:"code"`
-		Type             string  `json:"type"`
-		Value            float64 `json:"value"`
-		MinTotal         float64 `json:"min_total"`
-		StartDate        *string `json:"start_date"`
-		EndDate          *string `json:"end_date"`
+		Code             string  `json:"code" validate:"required"`
+		Type             string  `json:"type" validate:"oneof=percent fixed"`
+		Value            float64 `json:"value" validate:"gt=0"`
+		MinTotal         float64 `json:"min_total" validate:"min=0"`
+		StartDate        *string `json:"start_date" validate:"date"`
+		EndDate          *string `json:"end_date" validate:"date"`
 		UsageLimit       *int    `json:"usage_limit"`
 		SingleUsePerUser bool    `json:"single_use_per_user"`
 		Active           bool    `json:"active"`
+		AutoApply        bool    `json:"auto_apply"`
+		TargetUserIDs    *[]int  `json:"target_user_ids"` // ไม่ส่งมา = คงผู้ใช้เป้าหมายเดิม, [] = ยกเลิกการจำกัด
 	}
 
 	// แปลง JSON request body เป็น struct
@@ -316,20 +505,15 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 
-	// Validation ข้อมูล
-	if req.Code == "" {
-		utils.JSONError(w, "Discount code is required", http.StatusBadRequest)
-		return
-	}
-	if req.Value <= 0 {
-		utils.JSONError(w, "Discount value must be greater than 0", http.StatusBadRequest)
-		return
-	}
-	if req.Type != "percent" && req.Type != "fixed" {
-		utils.JSONError(w, "Discount type must be 'percent' or 'fixed'", http.StatusBadRequest)
+	// ตรวจสอบความถูกต้องของข้อมูลตาม tag validate
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
 		return
 	}
 
+	// เก็บข้อมูลก่อนแก้ไขสำหรับ audit log
+	before := snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id)
+
 	// เริ่ม transaction เพื่อความปลอดภัยของข้อมูล
 	tx, err := db.Begin()
 	if err != nil {
@@ -343,7 +527,7 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 	if err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Discount code not found", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Discount code not found", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Error checking current discount status", http.StatusInternalServerError)
 		}
@@ -363,25 +547,13 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 		fmt.Printf("✅ Reset usage history for discount ID: %d (reactivated)\n", id)
 	}
 
-	// Parse dates จาก string เป็น time.Time
+	// Parse dates จาก string เป็น time.Time (รูปแบบถูกตรวจสอบแล้ว)
 	var startDate, endDate interface{}
 	if req.StartDate != nil && *req.StartDate != "" {
-		if date, err := time.Parse("2006-01-02", *req.StartDate); err == nil {
-			startDate = date
-		} else {
-			tx.Rollback()
-			utils.JSONError(w, "Invalid start date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
+		startDate, _ = time.Parse("2006-01-02", *req.StartDate)
 	}
 	if req.EndDate != nil && *req.EndDate != "" {
-		if date, err := time.Parse("2006-01-02", *req.EndDate); err == nil {
-			endDate = date
-		} else {
-			tx.Rollback()
-			utils.JSONError(w, "Invalid end date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
+		endDate, _ = time.Parse("2006-01-02", *req.EndDate)
 	}
 
 	// ตรวจสอบว่า code ซ้ำหรือไม่ (ไม่รวมตัวเอง)
@@ -390,7 +562,7 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 	err = tx.QueryRow("SELECT id, code FROM discount_codes WHERE code = ? AND id != ?", req.Code, id).Scan(&existingID, &existingCode)
 	if err == nil {
 		tx.Rollback()
-		utils.JSONError(w, "Discount code already exists", http.StatusConflict)
+		utils.JSONErrorCode(w, utils.ErrCodeAlreadyExists, "Discount code already exists", http.StatusConflict)
 		return
 	} else if err != sql.ErrNoRows {
 		tx.Rollback()
@@ -398,13 +570,14 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 
-	// อัพเดต discount code
+	// อัพเดต discount code (ถ้าเปิดใช้งานจะนำออกจากคลังด้วย)
 	result, err := tx.Exec(`
 		UPDATE discount_codes 
 		SET code = ?, type = ?, value = ?, min_total = ?, start_date = ?, end_date = ?, 
-		    usage_limit = ?, single_use_per_user = ?, active = ?
+		    usage_limit = ?, single_use_per_user = ?, active = ?, auto_apply = ?,
+		    archived_at = IF(?, NULL, archived_at)
 		WHERE id = ?
-	`, req.Code, req.Type, req.Value, req.MinTotal, startDate, endDate, req.UsageLimit, req.SingleUsePerUser, req.Active, id)
+	`, req.Code, req.Type, req.Value, req.MinTotal, startDate, endDate, req.UsageLimit, req.SingleUsePerUser, req.Active, req.AutoApply, req.Active, id)
 
 	if err != nil {
 		tx.Rollback()
@@ -417,10 +590,19 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
 		tx.Rollback()
-		utils.JSONError(w, "Discount code not found", http.StatusNotFound)
+		utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Discount code not found", http.StatusNotFound)
 		return
 	}
 
+	if req.TargetUserIDs != nil {
+		if err := setDiscountTargets(tx, id, *req.TargetUserIDs); err != nil {
+			tx.Rollback()
+			fmt.Printf("❌ Error saving discount targets: %v\n", err)
+			utils.JSONError(w, "Error updating discount code", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// ยืนยัน transaction
 	if err := tx.Commit(); err != nil {
 		utils.JSONError(w, "Error completing update", http.StatusInternalServerError)
@@ -428,6 +610,7 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 	}
 
 	fmt.Printf("✅ Discount code updated: ID=%d, Code=%s, Active=%t\n", id, req.Code, req.Active)
+	recordAudit(r, "update", "discount", id, before, snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id))
 
 	// ส่ง response สำเร็จกลับ
 	utils.JSONResponse(w, map[string]interface{}{
@@ -438,243 +621,196 @@ func updateDiscountWithReset(w http.ResponseWriter, r *http.Request, id int) {
 	}, http.StatusOK)
 }
 
-// DELETE /admin/discounts/{id} - ลบส่วนลด + ลบประวัติการใช้งานทั้งหมด
-func deleteDiscountWithCleanup(w http.ResponseWriter, r *http.Request, id int) {
-	fmt.Printf("🗑️ Deleting discount code with cleanup: ID=%d\n", id)
+// DELETE /admin/discounts/{id} - เก็บส่วนลดเข้าคลัง (archive) โดยไม่ลบประวัติการใช้งาน
+func archiveDiscount(w http.ResponseWriter, r *http.Request, id int) {
+	fmt.Printf("📦 Archiving discount code: ID=%d\n", id)
+	before := snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id)
 
-	// เริ่ม transaction เพื่อความปลอดภัยของข้อมูล
-	tx, err := db.Begin()
+	// ปิดการใช้งานและบันทึกเวลาที่เก็บเข้าคลัง (ถ้าเคย archive แล้วจะคงเวลาเดิมไว้)
+	result, err := db.Exec(`
+		UPDATE discount_codes
+		SET active = 0, archived_at = COALESCE(archived_at, NOW())
+		WHERE id = ?
+	`, id)
 	if err != nil {
-		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		fmt.Printf("❌ Error archiving discount code: %v\n", err)
+		utils.JSONError(w, "Error archiving discount code", http.StatusInternalServerError)
 		return
 	}
 
-	// 1. ลบข้อมูลใน purchases ที่ใช้ discount นี้ก่อน
-	_, err = tx.Exec("UPDATE purchases SET discount_code_id = NULL WHERE discount_code_id = ?", id)
-	if err != nil {
-		tx.Rollback()
-		fmt.Printf("❌ Error updating purchases: %v\n", err)
-		utils.JSONError(w, "Error updating related purchases", http.StatusInternalServerError)
+	// ตรวจสอบว่ามีแถวถูกอัพเดทจริงหรือไม่
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Discount code not found", http.StatusNotFound)
 		return
 	}
-	fmt.Printf("✅ Updated purchases for discount ID: %d\n", id)
 
-	// 2. ลบประวัติการใช้งานใน user_discount_codes
-	_, err = tx.Exec("DELETE FROM user_discount_codes WHERE discount_code_id = ?", id)
-	if err != nil {
-		tx.Rollback()
-		fmt.Printf("❌ Error deleting discount usage history: %v\n", err)
-		utils.JSONError(w, "Error deleting discount usage history", http.StatusInternalServerError)
-		return
-	}
-	fmt.Printf("✅ Deleted usage history for discount ID: %d\n", id)
+	fmt.Printf("✅ Discount code archived: ID=%d\n", id)
+	recordAudit(r, "archive", "discount", id, before, snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id))
 
-	// 3. ลบ discount code
-	result, err := tx.Exec("DELETE FROM discount_codes WHERE id = ?", id)
-	if err != nil {
-		tx.Rollback()
-		fmt.Printf("❌ Error deleting discount code: %v\n", err)
-		utils.JSONError(w, "Error deleting discount code", http.StatusInternalServerError)
-		return
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Discount code archived successfully",
+		"id":      id,
+		"status":  "archived",
+	}, http.StatusOK)
+}
+
+// POST /admin/discounts/purge - ลบส่วนลดที่อยู่ในคลังออกถาวร (ย้อนกลับไม่ได้)
+func purgeArchivedDiscounts(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("🗑️ Purging archived discount codes")
+
+	// โครงสร้างสำหรับเก็บข้อมูลจาก request
+	var req struct {
+		IDs           []int `json:"ids"`             // ID ที่ต้องการลบ (ถ้าไม่ส่งจะลบทั้งหมดที่ตรงเงื่อนไข)
+		OlderThanDays int   `json:"older_than_days"` // ลบเฉพาะที่ archive มานานกว่ากี่วัน
+		Confirm       bool  `json:"confirm"`         // ต้องเป็น true เพื่อยืนยันการลบถาวร
 	}
 
-	// ตรวจสอบว่ามีแถวถูกลบจริงหรือไม่
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		tx.Rollback()
-		utils.JSONError(w, "Discount code not found", http.StatusNotFound)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// ยืนยัน transaction
-	if err := tx.Commit(); err != nil {
-		utils.JSONError(w, "Error completing deletion", http.StatusInternalServerError)
+	if !req.Confirm {
+		utils.JSONError(w, "Purge is irreversible; set confirm to true", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Printf("✅ Discount code deleted: ID=%d\n", id)
+	// สร้างเงื่อนไขสำหรับเลือกส่วนลดที่จะลบ (ลบได้เฉพาะที่ archive แล้วเท่านั้น)
+	whereClauses := []string{"archived_at IS NOT NULL"}
+	args := []interface{}{}
 
-	// ส่ง response สำเร็จกลับ
-	utils.JSONResponse(w, map[string]interface{}{
-		"message":      "Discount code deleted successfully",
-		"id":           id,
-		"cleanup_done": true,
-	}, http.StatusOK)
-}
-
-// ฟังก์ชันสำหรับตรวจสอบและลบส่วนลดที่ inactive อัตโนมัติ
-func autoDeactivateDiscounts() {
-	fmt.Println("🔄 Checking for inactive discount codes to delete...")
+	if len(req.IDs) > 0 {
+		placeholders := make([]string, len(req.IDs))
+		for i, id := range req.IDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		whereClauses = append(whereClauses, "id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if req.OlderThanDays > 0 {
+		whereClauses = append(whereClauses, "archived_at < DATE_SUB(NOW(), INTERVAL ? DAY)")
+		args = append(args, req.OlderThanDays)
+	}
 
-	// ค้นหาส่วนลดที่ inactive (active = 0)
-	rows, err := db.Query(`
-        SELECT dc.id, dc.code, dc.usage_limit, COUNT(udc.id) as usage_count
-        FROM discount_codes dc
-        LEFT JOIN user_discount_codes udc ON dc.id = udc.discount_code_id
-        WHERE dc.active = 0
-        GROUP BY dc.id
-    `)
+	tx, err := db.Begin()
 	if err != nil {
-		fmt.Printf("❌ Error checking inactive discounts: %v\n", err)
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	var deletedCount int
+	// ดึง ID ของส่วนลดที่จะลบ
+	rows, err := tx.Query("SELECT id FROM discount_codes WHERE "+strings.Join(whereClauses, " AND "), args...)
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error selecting discounts to purge: %v\n", err)
+		utils.JSONError(w, "Error selecting discounts to purge", http.StatusInternalServerError)
+		return
+	}
 
-	// อ่านข้อมูลส่วนลดที่ inactive และลบทิ้ง
+	var ids []int
 	for rows.Next() {
-		var discountID int
-		var discountCode string
-		var usageLimit sql.NullInt64
-		var usageCount int
-
-		err := rows.Scan(&discountID, &discountCode, &usageLimit, &usageCount)
-		if err != nil {
-			continue
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			fmt.Printf("❌ Error scanning discount to purge: %v\n", err)
+			utils.JSONError(w, "Error selecting discounts to purge", http.StatusInternalServerError)
+			return
 		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error reading discounts to purge: %v\n", err)
+		utils.JSONError(w, "Error selecting discounts to purge", http.StatusInternalServerError)
+		return
+	}
 
-		// เริ่ม transaction สำหรับการลบ
-		tx, err := db.Begin()
-		if err != nil {
-			fmt.Printf("❌ Error starting transaction for discount ID %d: %v\n", discountID, err)
-			continue
-		}
+	// ลบทีละรายการตามลำดับเพื่อป้องกัน foreign key constraint violations
+	snapshots := make(map[int]map[string]interface{}, len(ids))
+	for _, id := range ids {
+		snapshots[id] = snapshotRow("SELECT * FROM discount_codes WHERE id = ?", id)
 
-		// 1. อัพเดท purchases ที่ใช้ discount นี้ให้เป็น NULL
-		_, err = tx.Exec("UPDATE purchases SET discount_code_id = NULL WHERE discount_code_id = ?", discountID)
-		if err != nil {
+		// 1. ยกเลิกการอ้างอิงใน purchases
+		if _, err := tx.Exec("UPDATE purchases SET discount_code_id = NULL WHERE discount_code_id = ?", id); err != nil {
 			tx.Rollback()
-			fmt.Printf("❌ Error updating purchases for discount ID %d: %v\n", discountID, err)
-			continue
+			utils.JSONError(w, "Error updating related purchases", http.StatusInternalServerError)
+			return
 		}
 
-		// 2. ลบประวัติการใช้งานใน user_discount_codes
-		_, err = tx.Exec("DELETE FROM user_discount_codes WHERE discount_code_id = ?", discountID)
-		if err != nil {
+		// 2. ลบประวัติการใช้งาน
+		if _, err := tx.Exec("DELETE FROM user_discount_codes WHERE discount_code_id = ?", id); err != nil {
 			tx.Rollback()
-			fmt.Printf("❌ Error deleting usage history for discount ID %d: %v\n", discountID, err)
-			continue
+			utils.JSONError(w, "Error deleting discount usage history", http.StatusInternalServerError)
+			return
 		}
 
 		// 3. ลบ discount code
-		_, err = tx.Exec("DELETE FROM discount_codes WHERE id = ?", discountID)
-		if err != nil {
+		if _, err := tx.Exec("DELETE FROM discount_codes WHERE id = ?", id); err != nil {
 			tx.Rollback()
-			fmt.Printf("❌ Error deleting discount code ID %d: %v\n", discountID, err)
-			continue
+			utils.JSONError(w, "Error deleting discount code", http.StatusInternalServerError)
+			return
 		}
+	}
 
-		// ยืนยัน transaction
-		if err := tx.Commit(); err != nil {
-			fmt.Printf("❌ Error committing transaction for discount ID %d: %v\n", discountID, err)
-			continue
-		}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error completing purge", http.StatusInternalServerError)
+		return
+	}
 
-		fmt.Printf("🗑️ Auto-deleted inactive discount: ID=%d, Code=%s, Usage=%d\n",
-			discountID, discountCode, usageCount)
-		deletedCount++
+	fmt.Printf("✅ Purged %d archived discount codes\n", len(ids))
+	for _, id := range ids {
+		recordAudit(r, "purge", "discount", id, snapshots[id], nil)
 	}
 
-	if deletedCount > 0 {
-		fmt.Printf("✅ Auto-deleted %d inactive discount codes\n", deletedCount)
-	} else {
-		fmt.Println("✅ No inactive discount codes to delete")
+	if ids == nil {
+		ids = []int{}
 	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":    "Archived discount codes purged successfully",
+		"purged_ids": ids,
+		"count":      len(ids),
+	}, http.StatusOK)
 }
 
-// ฟังก์ชันสำหรับลบส่วนลดทั้งหมดที่ควรลบ (inactive, หมดอายุ, ใช้ครบ)
-func autoDeleteAllExpiredAndInactiveDiscounts() {
-	fmt.Println("🔄 Checking for all discount codes to delete...")
+// ฟังก์ชันสำหรับเก็บส่วนลดที่หมดอายุหรือใช้ครบจำนวนเข้าคลังอัตโนมัติ (ทำงานตามรอบผ่านคิวงาน)
+// ประวัติการใช้งานและการอ้างอิงใน purchases ยังคงอยู่ครบสำหรับการทำรายงาน
+func autoArchiveDiscounts() error {
+	fmt.Println("🔄 Checking for discount codes to archive...")
 
-	// ค้นหาส่วนลดที่ควรลบทั้งหมด (inactive, หมดอายุ, หรือใช้ครบ)
-	rows, err := db.Query(`
-        SELECT dc.id, dc.code, dc.active, 
-               DATE_FORMAT(dc.end_date, '%Y-%m-%d') as end_date,
-               dc.usage_limit, COUNT(udc.id) as usage_count
-        FROM discount_codes dc
-        LEFT JOIN user_discount_codes udc ON dc.id = udc.discount_code_id
-        WHERE dc.active = 0 
-           OR (dc.end_date IS NOT NULL AND dc.end_date < CURDATE())
-           OR (dc.usage_limit IS NOT NULL AND dc.active = 1)
-        GROUP BY dc.id
-        HAVING dc.active = 0 
-           OR (dc.end_date IS NOT NULL AND dc.end_date < CURDATE())
-           OR (dc.usage_limit IS NOT NULL AND usage_count >= dc.usage_limit)
-    `)
+	// 1. ส่วนลดที่หมดอายุแล้ว
+	result, err := db.Exec(`
+		UPDATE discount_codes
+		SET active = 0, archived_at = NOW()
+		WHERE archived_at IS NULL
+		  AND end_date IS NOT NULL AND end_date < CURDATE()
+	`)
 	if err != nil {
-		fmt.Printf("❌ Error checking discounts to delete: %v\n", err)
-		return
+		return fmt.Errorf("error archiving expired discounts: %v", err)
 	}
-	defer rows.Close()
-
-	var deletedCount int
-	var inactiveCount int
-	var expiredCount int
-	var usageLimitCount int
-
-	// อ่านข้อมูลส่วนลดที่ต้องลบ
-	for rows.Next() {
-		var discountID int
-		var discountCode string
-		var active bool
-		var endDate sql.NullString
-		var usageLimit sql.NullInt64
-		var usageCount int
-
-		err := rows.Scan(&discountID, &discountCode, &active, &endDate, &usageLimit, &usageCount)
-		if err != nil {
-			continue
-		}
-
-		// ตรวจสอบเหตุผลที่ต้องลบ
-		reason := ""
-		if !active {
-			reason = "inactive"
-			inactiveCount++
-		} else if endDate.Valid {
-			if endTime, _ := time.Parse("2006-01-02", endDate.String); endTime.Before(time.Now()) {
-				reason = "expired"
-				expiredCount++
-			}
-		} else if usageLimit.Valid && usageCount >= int(usageLimit.Int64) {
-			reason = "usage limit reached"
-			usageLimitCount++
-		}
-
-		// เริ่ม transaction สำหรับการลบ
-		tx, err := db.Begin()
-		if err != nil {
-			continue
-		}
-
-		// 1. อัพเดท purchases ที่ใช้ discount นี้ให้เป็น NULL
-		tx.Exec("UPDATE purchases SET discount_code_id = NULL WHERE discount_code_id = ?", discountID)
-
-		// 2. ลบประวัติการใช้งานใน user_discount_codes
-		tx.Exec("DELETE FROM user_discount_codes WHERE discount_code_id = ?", discountID)
+	expiredCount, _ := result.RowsAffected()
 
-		// 3. ลบ discount code
-		_, err = tx.Exec("DELETE FROM discount_codes WHERE id = ?", discountID)
-		if err != nil {
-			tx.Rollback()
-			continue
-		}
-
-		// ยืนยัน transaction
-		if err := tx.Commit(); err != nil {
-			continue
-		}
-
-		fmt.Printf("🗑️ Auto-deleted discount: ID=%d, Code=%s, Reason=%s\n",
-			discountID, discountCode, reason)
-		deletedCount++
+	// 2. ส่วนลดที่ใช้ครบจำนวนแล้ว
+	result, err = db.Exec(`
+		UPDATE discount_codes dc
+		SET dc.active = 0, dc.archived_at = NOW()
+		WHERE dc.archived_at IS NULL
+		  AND dc.usage_limit IS NOT NULL
+		  AND (SELECT COUNT(*) FROM user_discount_codes udc WHERE udc.discount_code_id = dc.id) >= dc.usage_limit
+	`)
+	if err != nil {
+		return fmt.Errorf("error archiving exhausted discounts: %v", err)
 	}
+	usageLimitCount, _ := result.RowsAffected()
 
-	if deletedCount > 0 {
-		fmt.Printf("✅ Auto-deleted %d discount codes (inactive: %d, expired: %d, usage limit: %d)\n",
-			deletedCount, inactiveCount, expiredCount, usageLimitCount)
+	if expiredCount+usageLimitCount > 0 {
+		fmt.Printf("📦 Auto-archived %d discount codes (expired: %d, usage limit: %d)\n",
+			expiredCount+usageLimitCount, expiredCount, usageLimitCount)
 	} else {
-		fmt.Println("✅ No discount codes to delete")
+		fmt.Println("✅ No discount codes to archive")
 	}
+	return nil
 }
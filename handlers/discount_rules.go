@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"time"
+)
+
+// discountRule เงื่อนไขของรหัสส่วนลดที่ใช้ตรวจสอบตอน checkout
+type discountRule struct {
+	ID               int
+	Code             string
+	Type             string
+	Value            float64
+	MinTotal         float64
+	UsageLimit       *int
+	SingleUsePerUser bool
+	StartDate        sql.NullString
+	EndDate          sql.NullString
+	AutoApply        bool
+}
+
+// discountRuleColumns คอลัมน์ที่ scanDiscountRule อ่าน (ตาราง discount_codes alias dc)
+func discountRuleColumns() string {
+	return `dc.id, dc.code, dc.type, dc.value, dc.min_total, dc.usage_limit, dc.single_use_per_user,
+	` + dialect.DateFormat("dc.start_date", "%Y-%m-%d") + `, ` + dialect.DateFormat("dc.end_date", "%Y-%m-%d") + `, dc.auto_apply`
+}
+
+func scanDiscountRule(scan func(dest ...interface{}) error) (*discountRule, error) {
+	var rule discountRule
+	err := scan(&rule.ID, &rule.Code, &rule.Type, &rule.Value, &rule.MinTotal, &rule.UsageLimit,
+		&rule.SingleUsePerUser, &rule.StartDate, &rule.EndDate, &rule.AutoApply)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// amount ส่วนลดที่ได้จากยอดรวม total (ไม่เกินยอดรวม)
+func (rule *discountRule) amount(total float64) float64 {
+	value := rule.Value
+	if rule.Type == "percent" {
+		value = total * (rule.Value / 100)
+	}
+	if value > total {
+		value = total
+	}
+	return value
+}
+
+// discountIneligible เหตุผลที่ผู้ใช้ใช้รหัสส่วนลดไม่ได้ (ส่งกลับเป็น error response)
+type discountIneligible struct {
+	Code    utils.ErrorCode
+	Message string
+	Status  int
+}
+
+// discountNotFound ตอบเหมือนกันทั้งรหัสที่ไม่มีอยู่และรหัสที่กำหนดให้ผู้ใช้อื่น เพื่อไม่ให้สุ่มหารหัสเฉพาะบุคคลได้
+var discountNotFound = discountIneligible{utils.ErrCodeNotFound, "Discount code not found", http.StatusBadRequest}
+
+// discountValidFrom เวลาที่รหัสส่วนลดเริ่มใช้ได้ (ต้นวันของ start_date ตามเวลาท้องถิ่น)
+func discountValidFrom(startDate string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", startDate, time.Local)
+}
+
+// discountEndsAt เวลาที่รหัสส่วนลดหมดอายุ: ใช้ได้ถึงสิ้นวันของ end_date (ตรงกับ end_date >= CURDATE() ใน SQL)
+func discountEndsAt(endDate string) (time.Time, error) {
+	end, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return end.AddDate(0, 0, 1), nil
+}
+
+// findDiscountRule ดึงรหัสส่วนลดที่เปิดใช้งานและยังไม่เก็บเข้าคลัง
+func findDiscountRule(exec sqlExecutor, code string) (*discountRule, error) {
+	return scanDiscountRule(exec.QueryRow(`
+		SELECT `+discountRuleColumns()+`
+		FROM discount_codes dc
+		WHERE dc.code = ? AND dc.active = 1 AND dc.archived_at IS NULL
+	`, code).Scan)
+}
+
+// checkDiscountRule ตรวจสอบว่าผู้ใช้ใช้รหัสส่วนลดกับยอดรวมนี้ได้หรือไม่ (nil = ใช้ได้)
+func checkDiscountRule(exec sqlExecutor, rule *discountRule, userID int, total float64) (*discountIneligible, error) {
+	// รหัสที่กำหนดผู้ใช้เป้าหมายไว้ ใช้ได้เฉพาะผู้ใช้เหล่านั้น
+	var targeted, isTarget bool
+	err := exec.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM discount_code_targets WHERE discount_code_id = ?),
+		       EXISTS(SELECT 1 FROM discount_code_targets WHERE discount_code_id = ? AND user_id = ?)
+	`, rule.ID, rule.ID, userID).Scan(&targeted, &isTarget)
+	if err != nil {
+		return nil, err
+	}
+	if targeted && !isTarget {
+		ineligible := discountNotFound
+		return &ineligible, nil
+	}
+
+	now := time.Now()
+	if rule.StartDate.Valid {
+		if startsAt, err := discountValidFrom(rule.StartDate.String); err == nil && now.Before(startsAt) {
+			return &discountIneligible{utils.ErrCodeNotYetValid, "Discount code not yet valid", http.StatusBadRequest}, nil
+		}
+	}
+	if rule.EndDate.Valid {
+		if endsAt, err := discountEndsAt(rule.EndDate.String); err == nil && !now.Before(endsAt) {
+			return &discountIneligible{utils.ErrCodeExpired, "Discount code has expired", http.StatusBadRequest}, nil
+		}
+	}
+	if rule.MinTotal > 0 && total < rule.MinTotal {
+		return &discountIneligible{utils.ErrMinimumNotMet, fmt.Sprintf("Minimum purchase of $%.2f required", rule.MinTotal), http.StatusBadRequest}, nil
+	}
+
+	// ตรวจสอบขีดจำกัดการใช้งาน
+	if rule.UsageLimit != nil {
+		var usageCount int
+		if err := exec.QueryRow("SELECT COUNT(*) FROM user_discount_codes WHERE discount_code_id = ?", rule.ID).Scan(&usageCount); err != nil {
+			return nil, err
+		}
+		if usageCount >= *rule.UsageLimit {
+			return &discountIneligible{utils.ErrCodeUsageLimit, "Discount code usage limit reached", http.StatusBadRequest}, nil
+		}
+	}
+
+	// ตรวจสอบว่าผู้ใช้ใช้รหัสส่วนลดนี้ไปแล้วหรือไม่
+	if rule.SingleUsePerUser {
+		var used bool
+		err := exec.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM user_discount_codes WHERE user_id = ? AND discount_code_id = ?)
+		`, userID, rule.ID).Scan(&used)
+		if err != nil {
+			return nil, err
+		}
+		if used {
+			return &discountIneligible{utils.ErrCodeAlreadyUsed, "Discount code already used", http.StatusConflict}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// bestDiscount หาส่วนลดที่ดีที่สุดที่ผู้ใช้มีสิทธิ์ใช้โดยอัตโนมัติ
+// (รหัสสาธารณะที่เปิด auto_apply และรหัสที่กำหนดให้ผู้ใช้คนนี้) คืน nil ถ้าไม่มีรหัสที่ใช้ได้
+func bestDiscount(exec sqlExecutor, userID int, total float64) (*discountRule, float64, error) {
+	rows, err := exec.Query(`
+		SELECT `+discountRuleColumns()+`
+		FROM discount_codes dc
+		WHERE dc.active = 1 AND dc.archived_at IS NULL
+		  AND (dc.auto_apply = 1 OR dc.id IN (SELECT discount_code_id FROM discount_code_targets WHERE user_id = ?))
+	`, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var candidates []*discountRule
+	for rows.Next() {
+		rule, err := scanDiscountRule(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		candidates = append(candidates, rule)
+	}
+	rows.Close()
+
+	var best *discountRule
+	var bestAmount float64
+	for _, rule := range candidates {
+		ineligible, err := checkDiscountRule(exec, rule, userID, total)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ineligible != nil {
+			continue
+		}
+		if amount := rule.amount(total); amount > bestAmount {
+			best, bestAmount = rule, amount
+		}
+	}
+	return best, bestAmount, nil
+}
+
+// setDiscountTargets แทนที่รายชื่อผู้ใช้เป้าหมายของรหัสส่วนลด (ว่าง = ใช้ได้ทุกคน)
+func setDiscountTargets(exec sqlExecutor, discountID int, userIDs []int) error {
+	if _, err := exec.Exec("DELETE FROM discount_code_targets WHERE discount_code_id = ?", discountID); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		_, err := exec.Exec("INSERT IGNORE INTO discount_code_targets (discount_code_id, user_id) VALUES (?, ?)", discountID, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discountTargets รายชื่อผู้ใช้เป้าหมายของรหัสส่วนลด
+func discountTargets(discountID int) ([]int, error) {
+	userIDs := []int{}
+	rows, err := db.Query("SELECT user_id FROM discount_code_targets WHERE discount_code_id = ? ORDER BY user_id", discountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
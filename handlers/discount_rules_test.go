@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var discountRuleRowColumns = []string{"id", "code", "type", "value", "min_total", "usage_limit",
+	"single_use_per_user", "start_date", "end_date", "auto_apply"}
+
+// applyDiscount POST /discounts/apply ในนามของผู้ใช้ userID
+func applyDiscount(userID int, code string, total float64) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]interface{}{"code": code, "total_amount": total})
+	req := httptest.NewRequest("POST", "/discounts/apply", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	ApplyDiscountHandler(w, withIdentity(req, Identity{UserID: userID, Role: "user"}))
+	return w
+}
+
+// รหัสที่กำหนดให้ผู้ใช้อื่นต้องตอบเหมือนรหัสที่ไม่มีอยู่ จึงใช้ endpoint ตรวจรหัสสุ่มหารหัสเฉพาะบุคคลไม่ได้
+func TestApplyDiscountHidesTargetedCodes(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("FROM discount_codes dc").WithArgs("NOPE").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO risk_events").WillReturnResult(sqlmock.NewResult(1, 1))
+	unknown := applyDiscount(7, "NOPE", 100)
+
+	mock.ExpectQuery("FROM discount_codes dc").WithArgs("VIP50").WillReturnRows(
+		sqlmock.NewRows(discountRuleRowColumns).AddRow(3, "VIP50", "percent", 50.0, 0.0, nil, false, nil, nil, false))
+	mock.ExpectQuery("FROM discount_code_targets").WithArgs(3, 3, 7).WillReturnRows(
+		sqlmock.NewRows([]string{"targeted", "is_target"}).AddRow(true, false))
+	mock.ExpectExec("INSERT INTO risk_events").WillReturnResult(sqlmock.NewResult(2, 1))
+	targeted := applyDiscount(7, "VIP50", 100)
+
+	if targeted.Code != http.StatusBadRequest {
+		t.Fatalf("targeted code status = %d: %s", targeted.Code, targeted.Body)
+	}
+	if targeted.Code != unknown.Code || targeted.Body.String() != unknown.Body.String() {
+		t.Errorf("targeted code = %d %s, unknown code = %d %s", targeted.Code, targeted.Body, unknown.Code, unknown.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// end_date ใช้ได้ถึงสิ้นวัน ทั้งตอนตรวจรหัส ตอน checkout และใน countdown ของ endingDeals
+func TestDiscountEndDateIsInclusive(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	tests := []struct {
+		endDate string
+		status  int
+	}{
+		{today, http.StatusOK},
+		{yesterday, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endDate, func(t *testing.T) {
+			mock := useMockDB(t)
+			mock.ExpectQuery("FROM discount_codes dc").WithArgs("SALE").WillReturnRows(
+				sqlmock.NewRows(discountRuleRowColumns).AddRow(1, "SALE", "fixed", 10.0, 0.0, nil, false, nil, tt.endDate, false))
+			mock.ExpectQuery("FROM discount_code_targets").WithArgs(1, 1, 7).WillReturnRows(
+				sqlmock.NewRows([]string{"targeted", "is_target"}).AddRow(false, false))
+
+			w := applyDiscount(7, "SALE", 25)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), `"final_amount":15`) {
+				t.Errorf("body = %s", w.Body)
+			}
+			if tt.status != http.StatusOK && !strings.Contains(w.Body.String(), "Discount code has expired") {
+				t.Errorf("body = %s", w.Body)
+			}
+		})
+	}
+
+	endsAt, err := discountEndsAt("2024-05-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local); !endsAt.Equal(want) {
+		t.Errorf("discountEndsAt = %v, want %v", endsAt, want)
+	}
+}
+
+// user_id ที่เลิกใช้แล้วยังรับได้ถ้าตรงกับผู้ใช้ใน token และ Warning ต้องบอกเงื่อนไขนี้ตรงกับที่ทำจริง
+func TestApplyDiscountDeprecatedUserID(t *testing.T) {
+	mock := useMockDB(t)
+	apply := func(userID int) *httptest.ResponseRecorder {
+		body := `{"code":"SALE","total_amount":25,"user_id":` + strconv.Itoa(userID) + `}`
+		w := httptest.NewRecorder()
+		ApplyDiscountHandler(w, withIdentity(httptest.NewRequest("POST", "/discounts/apply", strings.NewReader(body)), Identity{UserID: 7, Role: "user"}))
+		return w
+	}
+
+	mismatch := apply(8)
+	if mismatch.Code != http.StatusForbidden {
+		t.Fatalf("mismatched user_id status = %d: %s", mismatch.Code, mismatch.Body)
+	}
+	if warning := mismatch.Header().Get("Warning"); !strings.Contains(warning, "must match the authenticated user") {
+		t.Errorf("Warning = %q", warning)
+	}
+
+	mock.ExpectQuery("FROM discount_codes dc").WithArgs("SALE").WillReturnRows(
+		sqlmock.NewRows(discountRuleRowColumns).AddRow(1, "SALE", "fixed", 10.0, 0.0, nil, false, nil, nil, false))
+	mock.ExpectQuery("FROM discount_code_targets").WithArgs(1, 1, 7).WillReturnRows(
+		sqlmock.NewRows([]string{"targeted", "is_target"}).AddRow(false, false))
+	match := apply(7)
+	if match.Code != http.StatusOK || match.Header().Get("Deprecation") != "true" {
+		t.Errorf("matching user_id = %d %v: %s", match.Code, match.Header(), match.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/cache"
+	"go-api-game/utils"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ชื่อเหตุการณ์ที่ส่งผ่าน /events
+const (
+	EventWalletBalance = "wallet.balance" // ยอดเงินในกระเป๋าเปลี่ยน
+	EventOrderStatus   = "order.status"   // สถานะคำสั่งซื้อเปลี่ยน
+	EventDealCountdown = "deal.countdown" // ส่วนลดที่ใกล้หมดเวลา
+	EventAdminMetrics  = "admin.metrics"  // ตัวเลขสรุปสำหรับหน้า dashboard ของ admin
+	EventAdminSale     = "admin.sale"     // คำสั่งซื้อที่ส่งมอบเกมแล้ว (live sales ของ dashboard admin)
+	EventHeartbeat     = "heartbeat"      // ป้องกัน proxy ตัดการเชื่อมต่อที่เงียบนานเกินไป
+)
+
+const (
+	eventsTicketTTL         = time.Minute // อายุของ ticket สำหรับเปิด /events (ใช้ได้ครั้งเดียว)
+	eventsTicketPrefix      = "events:ticket:"
+	eventsHeartbeatInterval = 25 * time.Second
+	eventsMetricsInterval   = 10 * time.Second
+	eventsDealsInterval     = 30 * time.Second
+	eventsClientBuffer      = 16
+)
+
+// eventClient การเชื่อมต่อ SSE หนึ่งรายการ (ผู้ใช้หนึ่งคนเปิดได้หลายแท็บ)
+type eventClient struct {
+	userID int
+	admin  bool
+	ch     chan []byte
+}
+
+// eventHub เก็บการเชื่อมต่อทั้งหมด แยกตามผู้ใช้
+var eventHub = struct {
+	mu      sync.RWMutex
+	clients map[*eventClient]struct{}
+}{clients: map[*eventClient]struct{}{}}
+
+// formatEvent แปลงข้อมูลเป็นรูปแบบ text/event-stream
+func formatEvent(event string, data interface{}) []byte {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload = []byte("{}")
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload))
+}
+
+// publishEvent ส่งเหตุการณ์ไปยัง client ที่ตรงเงื่อนไข (client ที่ค้างจะถูกข้ามแทนการ block)
+func publishEvent(match func(c *eventClient) bool, event string, data interface{}) {
+	msg := formatEvent(event, data)
+
+	eventHub.mu.RLock()
+	defer eventHub.mu.RUnlock()
+	for c := range eventHub.clients {
+		if !match(c) {
+			continue
+		}
+		select {
+		case c.ch <- msg:
+		default:
+		}
+	}
+}
+
+// publishToUser ส่งเหตุการณ์ไปยังทุกการเชื่อมต่อของผู้ใช้คนหนึ่ง
+func publishToUser(userID int, event string, data interface{}) {
+	publishEvent(func(c *eventClient) bool { return c.userID == userID }, event, data)
+}
+
+// publishToAdmins ส่งเหตุการณ์ไปยังผู้ดูแลระบบที่เชื่อมต่ออยู่
+func publishToAdmins(event string, data interface{}) {
+	publishEvent(func(c *eventClient) bool { return c.admin }, event, data)
+}
+
+// hasEventClients ตรวจสอบว่ามี client ที่ตรงเงื่อนไขเชื่อมต่ออยู่หรือไม่ (ใช้ข้ามการ query ที่ไม่จำเป็น)
+func hasEventClients(match func(c *eventClient) bool) bool {
+	eventHub.mu.RLock()
+	defer eventHub.mu.RUnlock()
+	for c := range eventHub.clients {
+		if match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// pushWalletBalance ส่งยอดเงินล่าสุดไปยังผู้ใช้ที่เชื่อมต่ออยู่
+func pushWalletBalance(userID int) {
+	if !hasEventClients(func(c *eventClient) bool { return c.userID == userID }) {
+		return
+	}
+	var balance float64
+	if err := db.QueryRow("SELECT wallet_balance FROM users WHERE id = ?", userID).Scan(&balance); err != nil {
+		fmt.Printf("⚠️ Error loading wallet balance for event: %v\n", err)
+		return
+	}
+	publishToUser(userID, EventWalletBalance, map[string]interface{}{"balance": balance})
+}
+
+// publishSale ส่งรายละเอียดคำสั่งซื้อที่ส่งมอบเกมแล้วไปยัง admin ที่เชื่อมต่ออยู่ (เกม ยอดเงิน และภูมิภาคภาษี)
+// เรียกหลัง commit เมื่อ checkout สำเร็จหรือ admin อนุมัติคำสั่งซื้อที่ถูกพักไว้
+func publishSale(purchaseID int64) {
+	if !hasEventClients(func(c *eventClient) bool { return c.admin }) {
+		return
+	}
+
+	var finalAmount, taxAmount float64
+	var region sql.NullString
+	err := db.QueryRow(`
+		SELECT final_amount, tax_amount, tax_region FROM purchases WHERE id = ?
+	`, purchaseID).Scan(&finalAmount, &taxAmount, &region)
+	if err != nil {
+		fmt.Printf("⚠️ Error loading purchase #%d for sale event: %v\n", purchaseID, err)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, pi.price_at_purchase
+		FROM purchase_items pi
+		JOIN games g ON g.id = pi.game_id
+		WHERE pi.purchase_id = ?
+		ORDER BY g.name
+	`, purchaseID)
+	if err != nil {
+		fmt.Printf("⚠️ Error loading purchase #%d items for sale event: %v\n", purchaseID, err)
+		return
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var gameID int
+		var name string
+		var price float64
+		if err := rows.Scan(&gameID, &name, &price); err != nil {
+			fmt.Printf("⚠️ Error scanning sale event item: %v\n", err)
+			return
+		}
+		games = append(games, map[string]interface{}{"game_id": gameID, "name": name, "price": price})
+	}
+
+	var regionValue interface{}
+	if region.Valid && region.String != "" {
+		regionValue = region.String
+	}
+	publishToAdmins(EventAdminSale, map[string]interface{}{
+		"purchase_id":  purchaseID,
+		"games":        games,
+		"amount":       finalAmount,
+		"tax_amount":   taxAmount,
+		"amount_paid":  roundCents(finalAmount + taxAmount),
+		"region":       regionValue,
+		"purchased_at": utils.FormatTimestamp(time.Now()),
+	})
+}
+
+// eventsTicket ผู้ใช้ที่ ticket ของ /events อ้างถึง
+type eventsTicket struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// EventsTicketHandler handles issuing a short-lived ticket for the events stream
+// ฟังก์ชันสำหรับออก ticket ใช้ครั้งเดียว อายุ eventsTicketTTL สำหรับเปิด /events?ticket=
+// EventSource ของ browser ส่ง header ไม่ได้ จึงใช้ ticket แทนการใส่ JWT ใน URL (ซึ่งจะไปอยู่ในประวัติ browser, Referer และ log ของ proxy)
+// POST /events/ticket
+func EventsTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	identity, ok := IdentityFrom(r.Context())
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	ticketBytes := make([]byte, 32)
+	if _, err := rand.Read(ticketBytes); err != nil {
+		utils.JSONError(w, "Error creating stream ticket", http.StatusInternalServerError)
+		return
+	}
+	ticket := hex.EncodeToString(ticketBytes)
+	data, _ := json.Marshal(eventsTicket{UserID: identity.UserID, Role: identity.Role})
+	cache.Default.Set(eventsTicketPrefix+hashAPIKey(ticket), data, eventsTicketTTL)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"ticket":     ticket,
+		"expires_in": int(eventsTicketTTL.Seconds()),
+	}, http.StatusCreated)
+}
+
+// redeemEventsTicket ตรวจสอบ ticket และลบทิ้งทันที (ใช้ได้ครั้งเดียว)
+func redeemEventsTicket(ticket string) (eventsTicket, bool) {
+	key := eventsTicketPrefix + hashAPIKey(ticket)
+	data, ok := cache.Default.Get(key)
+	if !ok {
+		return eventsTicket{}, false
+	}
+	cache.Default.Delete(key)
+
+	var t eventsTicket
+	if err := json.Unmarshal(data, &t); err != nil || t.UserID <= 0 {
+		return eventsTicket{}, false
+	}
+	return t, true
+}
+
+// EventsHandler streams real-time updates using Server-Sent Events
+// ฟังก์ชันสำหรับส่งข้อมูลแบบ real-time (GET /events)
+// ยืนยันตัวตนด้วย cookie/Authorization header หรือ ?ticket= จาก POST /events/ticket (ไม่รับ JWT ใน URL)
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var client *eventClient
+	if tokenString, _ := requestToken(r); tokenString != "" {
+		claims, err := validateSessionToken(tokenString)
+		if err != nil {
+			utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		client = &eventClient{userID: claims.UserID, admin: claims.Role == "admin"}
+	} else if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		t, ok := redeemEventsTicket(ticket)
+		if !ok {
+			utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid or expired stream ticket", http.StatusUnauthorized)
+			return
+		}
+		client = &eventClient{userID: t.UserID, admin: t.Role == "admin"}
+	} else {
+		utils.JSONError(w, "Authorization required", http.StatusUnauthorized)
+		return
+	}
+	client.ch = make(chan []byte, eventsClientBuffer)
+
+	// ใช้ ResponseController แทน w.(http.Flusher) เพราะ w มักถูกห่อด้วย writer ของ middleware (Recover, AccessLog, Compress)
+	rc := http.NewResponseController(w)
+
+	eventHub.mu.Lock()
+	eventHub.clients[client] = struct{}{}
+	eventHub.mu.Unlock()
+
+	defer func() {
+		eventHub.mu.Lock()
+		delete(eventHub.clients, client)
+		eventHub.mu.Unlock()
+		fmt.Printf("📴 Events client disconnected: user_id=%d\n", client.userID)
+	}()
+
+	fmt.Printf("📡 Events client connected: user_id=%d, admin=%v\n", client.userID, client.admin)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// ส่งยอดเงินปัจจุบันทันทีที่เชื่อมต่อ
+	w.Write([]byte("retry: 5000\n\n"))
+	var balance float64
+	if err := db.QueryRow("SELECT wallet_balance FROM users WHERE id = ?", client.userID).Scan(&balance); err == nil {
+		w.Write(formatEvent(EventWalletBalance, map[string]interface{}{"balance": balance}))
+	}
+	if err := rc.Flush(); err != nil {
+		fmt.Printf("❌ Events stream cannot flush: %v\n", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-client.ch:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			if rc.Flush() != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": " + EventHeartbeat + "\n\n")); err != nil {
+				return
+			}
+			if rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// StartEventsWorker เริ่ม goroutine ที่ส่งข้อมูลตามรอบเวลา (ตัวเลข dashboard และส่วนลดที่ใกล้หมดเวลา)
+func StartEventsWorker() {
+	go func() {
+		metrics := time.NewTicker(eventsMetricsInterval)
+		deals := time.NewTicker(eventsDealsInterval)
+		for {
+			select {
+			case <-metrics.C:
+				if hasEventClients(func(c *eventClient) bool { return c.admin }) {
+					publishToAdmins(EventAdminMetrics, liveAdminMetrics())
+				}
+			case <-deals.C:
+				if hasEventClients(func(c *eventClient) bool { return true }) {
+					publishEvent(func(c *eventClient) bool { return true }, EventDealCountdown, endingDeals())
+				}
+			}
+		}
+	}()
+	fmt.Println("✅ Events worker started")
+}
+
+// liveAdminMetrics ตัวเลขสรุปของวันนี้สำหรับ dashboard
+func liveAdminMetrics() map[string]interface{} {
+	var revenueToday float64
+	var purchasesToday, signupsToday int
+	err := db.QueryRow(`
+		SELECT
+			(SELECT COALESCE(SUM(final_amount), 0) FROM purchases WHERE purchase_date >= CURDATE()),
+			(SELECT COUNT(*) FROM purchases WHERE purchase_date >= CURDATE()),
+			(SELECT COUNT(*) FROM users WHERE created_at >= CURDATE())
+	`).Scan(&revenueToday, &purchasesToday, &signupsToday)
+	if err != nil {
+		fmt.Printf("⚠️ Error loading live metrics: %v\n", err)
+	}
+
+	eventHub.mu.RLock()
+	online := map[int]bool{}
+	for c := range eventHub.clients {
+		online[c.userID] = true
+	}
+	eventHub.mu.RUnlock()
+
+	return map[string]interface{}{
+		"revenue_today":   revenueToday,
+		"purchases_today": purchasesToday,
+		"signups_today":   signupsToday,
+		"users_online":    len(online),
+		"timestamp":       utils.FormatTimestamp(time.Now()),
+	}
+}
+
+// endingDeals ส่วนลดที่ยังใช้งานได้และจะหมดอายุภายในวันนี้ พร้อมเวลาที่เหลือ
+func endingDeals() map[string]interface{} {
+	rows, err := db.Query(`
+		SELECT code, type, value, ` + dialect.DateFormat("end_date", "%Y-%m-%d") + `
+		FROM discount_codes
+		WHERE active = 1 AND archived_at IS NULL AND end_date IS NOT NULL
+		  AND (start_date IS NULL OR start_date <= CURDATE())
+		  AND end_date >= CURDATE() AND end_date < DATE_ADD(CURDATE(), INTERVAL 1 DAY)
+		ORDER BY end_date
+	`)
+	if err != nil {
+		fmt.Printf("⚠️ Error loading ending deals: %v\n", err)
+		return map[string]interface{}{"deals": []interface{}{}}
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	deals := []map[string]interface{}{}
+	for rows.Next() {
+		var code, discountType, endDate string
+		var value float64
+		if err := rows.Scan(&code, &discountType, &value, &endDate); err != nil {
+			fmt.Printf("⚠️ Error scanning ending deal: %v\n", err)
+			return map[string]interface{}{"deals": []interface{}{}}
+		}
+		endsAt, err := discountEndsAt(endDate)
+		if err != nil {
+			continue
+		}
+		deals = append(deals, map[string]interface{}{
+			"code":         code,
+			"type":         discountType,
+			"value":        value,
+			"ends_at":      utils.FormatTimestamp(endsAt),
+			"seconds_left": int(endsAt.Sub(now).Seconds()),
+		})
+	}
+
+	return map[string]interface{}{"deals": deals}
+}
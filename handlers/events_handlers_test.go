@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventsTicketIsSingleUse(t *testing.T) {
+	r := withIdentity(httptest.NewRequest("POST", "/events/ticket", nil), Identity{UserID: 7, Role: "admin"})
+	w := httptest.NewRecorder()
+	EventsTicketHandler(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusCreated, w.Body)
+	}
+	var resp struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Ticket == "" {
+		t.Fatalf("invalid response %s: %v", w.Body, err)
+	}
+
+	ticket, ok := redeemEventsTicket(resp.Ticket)
+	if !ok || ticket.UserID != 7 || ticket.Role != "admin" {
+		t.Fatalf("redeemEventsTicket = %+v, %v", ticket, ok)
+	}
+	if _, ok := redeemEventsTicket(resp.Ticket); ok {
+		t.Fatal("ticket redeemed twice")
+	}
+}
+
+func TestEventsRejectsAccessTokenQuery(t *testing.T) {
+	w := httptest.NewRecorder()
+	EventsHandler(w, httptest.NewRequest("GET", "/events?access_token=header.payload.signature", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
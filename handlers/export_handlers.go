@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// wantsCSV ตรวจสอบว่า client ขอผลลัพธ์เป็นไฟล์ CSV หรือไม่ (?format=csv)
+func wantsCSV(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "csv"
+}
+
+// formatAmount แปลงจำนวนเงินเป็นข้อความทศนิยม 2 ตำแหน่งสำหรับ CSV
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// streamQueryCSV รัน query แล้วเขียนผลลัพธ์เป็น CSV ทีละแถวโดยตรงจาก cursor
+// ใช้ชื่อคอลัมน์จาก query เป็น header จึงไม่ต้องโหลดข้อมูลทั้งหมดเข้าหน่วยความจำ
+// คอลัมน์วันเวลา (time.Time) แสดงตาม time zone loc (ดู exportLocation)
+func streamQueryCSV(w http.ResponseWriter, loc *time.Location, filename, query string, args ...interface{}) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		fmt.Printf("❌ Error running export query: %v\n", err)
+		utils.JSONError(w, "Error exporting data", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		utils.JSONError(w, "Error exporting data", http.StatusInternalServerError)
+		return
+	}
+
+	// เตรียมตัวแปรสำหรับ scan (ค่าตามชนิดที่ driver คืนมา แล้วแปลงเป็นข้อความใน exportValue)
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	cw := utils.CSVWriter(w, filename)
+	cw.Write(columns)
+
+	count := 0
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			fmt.Printf("❌ Error scanning export row: %v\n", err)
+			abortStream()
+		}
+		for i, v := range values {
+			record[i] = exportValue(v, loc)
+		}
+		cw.Write(record)
+		count++
+
+		// ส่งข้อมูลออกไปเป็นช่วงๆ เพื่อไม่ให้ค้างอยู่ใน buffer
+		if count%500 == 0 {
+			cw.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error during export rows iteration: %v\n", err)
+		abortStream()
+	}
+	cw.Flush()
+
+	fmt.Printf("✅ Exported %d rows to %s\n", count, filename)
+}
+
+// abortStream ยกเลิก response ที่ส่ง status 200 ไปแล้ว (เช่น CSV แบบ streaming) เมื่ออ่านข้อมูลไม่สำเร็จกลางทาง
+// ตอบ 500 ไม่ได้แล้ว จึงตัดการเชื่อมต่อโดยไม่ปิด response ให้สมบูรณ์ client จะเห็นว่าดาวน์โหลดล้มเหลวแทนการได้ไฟล์ที่ขาดแถวไป
+// (Recover ส่ง http.ErrAbortHandler ต่อให้ net/http โดยไม่รายงานเป็น panic)
+func abortStream() {
+	panic(http.ErrAbortHandler)
+}
+
+// exportValue แปลงค่าหนึ่งคอลัมน์เป็นข้อความใน CSV (NULL = ว่าง)
+func exportValue(value interface{}, loc *time.Location) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return formatExportTime(v, loc)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feature flag ที่ระบบใช้ (flag ที่ยังไม่ได้สร้างใน /admin/flags ใช้ค่าเริ่มต้นใน flagDefaults)
+const (
+	FlagGiftCards = "gift_cards"        // แลกบัตรของขวัญ (POST /giftcards/redeem)
+	FlagAutoTopUp = "wallet_auto_topup" // เติมเงินอัตโนมัติผ่าน payment gateway
+	FlagGraphQL   = "graphql"           // endpoint /graphql (ยังปิดได้ทั้งหมดด้วย GRAPHQL_ENABLED=false)
+)
+
+// flagDefaults ค่าของ flag ที่ยังไม่มีในฐานข้อมูล (ฟีเจอร์เดิมเปิดอยู่แล้ว จึงเริ่มต้นเป็นเปิด)
+var flagDefaults = map[string]bool{
+	FlagGiftCards: true,
+	FlagAutoTopUp: true,
+	FlagGraphQL:   true,
+}
+
+// flagCacheTTL ระยะเวลาที่ใช้ค่า flag ในหน่วยความจำ (instance อื่นเห็นการแก้ไขภายในเวลานี้)
+const flagCacheTTL = 30 * time.Second
+
+// flagKeyPattern รูปแบบชื่อ flag เช่น new_payment_gateway
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9_]{2,64}$`)
+
+// featureFlag การตั้งค่าของ flag หนึ่งตัว
+// Enabled=false ปิดสำหรับทุกคน (kill switch), RolloutPercent เปิดให้ผู้ใช้บางส่วนแบบคงที่ต่อผู้ใช้,
+// UserIDs เปิดให้ผู้ใช้ที่ระบุเสมอเมื่อ flag เปิดอยู่ (เช่นทีมทดสอบ)
+type featureFlag struct {
+	Key            string              `json:"key" validate:"required,max=64"`
+	Description    string              `json:"description" validate:"max=255"`
+	Enabled        bool                `json:"enabled"`
+	RolloutPercent int                 `json:"rollout_percent" validate:"min=0,max=100"`
+	UserIDs        []int               `json:"user_ids"`
+	UpdatedAt      utils.NullTimestamp `json:"updated_at"`
+}
+
+// flagCache ค่า flag ทั้งหมดในหน่วยความจำ
+var flagCache struct {
+	sync.RWMutex
+	flags    map[string]featureFlag
+	loadedAt time.Time
+}
+
+// currentFlags คืนค่า flag จากหน่วยความจำ และโหลดใหม่เมื่อหมดอายุ
+// ถ้าโหลดไม่สำเร็จจะใช้ค่าเดิมต่อ (ฐานข้อมูลล่มไม่ควรทำให้ฟีเจอร์ถูกปิดหรือเปิดเอง)
+func currentFlags() map[string]featureFlag {
+	flagCache.RLock()
+	flags, fresh := flagCache.flags, time.Since(flagCache.loadedAt) < flagCacheTTL
+	flagCache.RUnlock()
+	if fresh {
+		return flags
+	}
+
+	loaded, err := loadFlags()
+	flagCache.Lock()
+	defer flagCache.Unlock()
+	if err != nil {
+		fmt.Printf("⚠️ Error loading feature flags: %v\n", err)
+	} else {
+		flagCache.flags = loaded
+	}
+	// ลองใหม่หลังครบ TTL แม้โหลดไม่สำเร็จ เพื่อไม่ให้ทุก request ไปถามฐานข้อมูล
+	flagCache.loadedAt = time.Now()
+	return flagCache.flags
+}
+
+// invalidateFlags ให้ request ถัดไปโหลด flag ใหม่ (เรียกหลังแก้ไขผ่าน /admin/flags)
+func invalidateFlags() {
+	flagCache.Lock()
+	flagCache.loadedAt = time.Time{}
+	flagCache.Unlock()
+}
+
+func loadFlags() (map[string]featureFlag, error) {
+	rows, err := db.Query(`
+		SELECT flag_key, COALESCE(description, ''), enabled, rollout_percent, COALESCE(user_ids, ''), updated_at
+		FROM feature_flags
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := map[string]featureFlag{}
+	for rows.Next() {
+		var f featureFlag
+		var userIDs string
+		if err := rows.Scan(&f.Key, &f.Description, &f.Enabled, &f.RolloutPercent, &userIDs, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		f.UserIDs = parseFlagUserIDs(userIDs)
+		flags[f.Key] = f
+	}
+	return flags, rows.Err()
+}
+
+// parseFlagUserIDs แปลงรายการ user id ที่เก็บเป็น string คั่นด้วย comma
+func parseFlagUserIDs(raw string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(raw, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// featureEnabled ตรวจสอบว่าฟีเจอร์เปิดสำหรับผู้ใช้นี้หรือไม่ (userID = 0 คือผู้ใช้ที่ไม่ได้เข้าสู่ระบบ)
+// flag ที่ไม่มีในฐานข้อมูลใช้ค่าจาก flagDefaults (ไม่มีค่าเริ่มต้น = ปิด)
+func featureEnabled(key string, userID int) bool {
+	f, ok := currentFlags()[key]
+	if !ok {
+		return flagDefaults[key]
+	}
+	return f.enabledFor(userID)
+}
+
+func (f featureFlag) enabledFor(userID int) bool {
+	if !f.Enabled {
+		return false
+	}
+	if f.RolloutPercent >= 100 {
+		return true
+	}
+	if userID <= 0 {
+		return false
+	}
+	for _, id := range f.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return flagBucket(f.Key, userID) < f.RolloutPercent
+}
+
+// flagBucket กลุ่ม 0-99 ของผู้ใช้สำหรับ flag นี้ (คงที่ต่อผู้ใช้ และต่างกันในแต่ละ flag)
+// การเพิ่ม rollout_percent จึงเปิดให้ผู้ใช้เพิ่มโดยไม่ปิดผู้ใช้ที่เปิดอยู่แล้ว
+func flagBucket(key string, userID int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + strconv.Itoa(userID)))
+	return int(h.Sum32() % 100)
+}
+
+// FeatureFlagsHandler returns the feature flags evaluated for the current user
+// ฟังก์ชันสำหรับให้ frontend ตรวจสอบว่าฟีเจอร์ใดเปิดอยู่ (ใช้ซ่อน/แสดงเมนู)
+// GET /flags
+func FeatureFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := requestUserID(r)
+
+	result := map[string]bool{}
+	for key := range flagDefaults {
+		result[key] = featureEnabled(key, userID)
+	}
+	for key, f := range currentFlags() {
+		result[key] = f.enabledFor(userID)
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	utils.JSONResponse(w, map[string]interface{}{"flags": result}, http.StatusOK)
+}
+
+// AdminFeatureFlagHandler handles feature flag management
+// ฟังก์ชันสำหรับจัดการ feature flag (เปิดทีละส่วนหรือปิดฟีเจอร์ได้โดยไม่ต้อง deploy ใหม่)
+// GET /admin/flags, POST /admin/flags, GET/PUT/DELETE /admin/flags/{key}
+func AdminFeatureFlagHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/flags/gift_cards → key = gift_cards
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/flags"), "/")
+
+	switch {
+	case r.Method == "GET" && key == "":
+		getFeatureFlags(w)
+	case r.Method == "GET":
+		getFeatureFlag(w, key)
+	case r.Method == "POST" && key == "":
+		saveFeatureFlag(w, r, "")
+	case r.Method == "PUT" && key != "":
+		saveFeatureFlag(w, r, key)
+	case r.Method == "DELETE" && key != "":
+		deleteFeatureFlag(w, r, key)
+	case r.Method == "PUT" || r.Method == "DELETE":
+		utils.JSONError(w, "Flag key required", http.StatusBadRequest)
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getFeatureFlags ดึง flag ทั้งหมดจากฐานข้อมูล พร้อม flag ที่ระบบใช้แต่ยังไม่ได้สร้าง (ค่าเริ่มต้น)
+func getFeatureFlags(w http.ResponseWriter) {
+	stored, err := loadFlags()
+	if err != nil {
+		fmt.Printf("❌ Error fetching feature flags: %v\n", err)
+		utils.JSONError(w, "Error fetching feature flags", http.StatusInternalServerError)
+		return
+	}
+
+	flags := []map[string]interface{}{}
+	for _, f := range stored {
+		flags = append(flags, map[string]interface{}{
+			"key": f.Key, "description": f.Description, "enabled": f.Enabled,
+			"rollout_percent": f.RolloutPercent, "user_ids": f.UserIDs, "updated_at": f.UpdatedAt,
+			"stored": true,
+		})
+	}
+	for key, enabled := range flagDefaults {
+		if _, ok := stored[key]; ok {
+			continue
+		}
+		flags = append(flags, map[string]interface{}{
+			"key": key, "description": "", "enabled": enabled,
+			"rollout_percent": 100, "user_ids": []int{}, "updated_at": nil,
+			"stored": false,
+		})
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i]["key"].(string) < flags[j]["key"].(string) })
+
+	utils.JSONResponse(w, map[string]interface{}{"flags": flags}, http.StatusOK)
+}
+
+func getFeatureFlag(w http.ResponseWriter, key string) {
+	stored, err := loadFlags()
+	if err != nil {
+		fmt.Printf("❌ Error fetching feature flag %s: %v\n", key, err)
+		utils.JSONError(w, "Error fetching feature flags", http.StatusInternalServerError)
+		return
+	}
+	f, ok := stored[key]
+	if !ok {
+		utils.JSONError(w, "Feature flag not found", http.StatusNotFound)
+		return
+	}
+	utils.JSONResponse(w, f, http.StatusOK)
+}
+
+// saveFeatureFlag สร้าง flag ใหม่ (key ว่าง ใช้ key จาก body) หรือแก้ไข flag เดิม
+// PUT ของ flag ที่ระบบใช้แต่ยังไม่ได้สร้าง (มีใน flagDefaults) จะสร้างให้
+func saveFeatureFlag(w http.ResponseWriter, r *http.Request, key string) {
+	req := featureFlag{Enabled: true, RolloutPercent: 100}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if key != "" {
+		req.Key = key
+	}
+	req.Key = strings.ToLower(strings.TrimSpace(req.Key))
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	if !flagKeyPattern.MatchString(req.Key) {
+		utils.JSONErrorDetails(w, utils.ErrValidation, "Invalid flag key", map[string]string{
+			"key": "use 2-64 lowercase letters, digits or underscores",
+		}, http.StatusBadRequest)
+		return
+	}
+
+	userIDs := make([]string, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		userIDs = append(userIDs, strconv.Itoa(id))
+	}
+
+	const snapshot = "SELECT flag_key, description, enabled, rollout_percent, user_ids FROM feature_flags WHERE flag_key = ?"
+	before := snapshotRow(snapshot, req.Key)
+	var err error
+	switch {
+	case before == nil && key != "" && !hasFlagDefault(key):
+		utils.JSONError(w, "Feature flag not found", http.StatusNotFound)
+		return
+	case before == nil:
+		_, err = db.Exec(`
+			INSERT INTO feature_flags (flag_key, description, enabled, rollout_percent, user_ids)
+			VALUES (?, NULLIF(?, ''), ?, ?, NULLIF(?, ''))
+		`, req.Key, req.Description, req.Enabled, req.RolloutPercent, strings.Join(userIDs, ","))
+	case key == "":
+		utils.JSONErrorCode(w, utils.ErrConflict, fmt.Sprintf("Feature flag %s already exists", req.Key), http.StatusConflict)
+		return
+	default:
+		_, err = db.Exec(`
+			UPDATE feature_flags
+			SET description = NULLIF(?, ''), enabled = ?, rollout_percent = ?, user_ids = NULLIF(?, '')
+			WHERE flag_key = ?
+		`, req.Description, req.Enabled, req.RolloutPercent, strings.Join(userIDs, ","), req.Key)
+	}
+	if err != nil {
+		if _, ok := duplicateKeyName(err); ok {
+			utils.JSONErrorCode(w, utils.ErrConflict, fmt.Sprintf("Feature flag %s already exists", req.Key), http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error saving feature flag %s: %v\n", req.Key, err)
+		utils.JSONError(w, "Error saving feature flag", http.StatusInternalServerError)
+		return
+	}
+	invalidateFlags()
+
+	action, status := "feature_flag_update", http.StatusOK
+	if before == nil {
+		action, status = "feature_flag_create", http.StatusCreated
+	}
+	recordAudit(r, action, "feature_flag", req.Key, before, snapshotRow(snapshot, req.Key))
+
+	fmt.Printf("🚩 Feature flag saved: %s enabled=%t rollout=%d%%\n", req.Key, req.Enabled, req.RolloutPercent)
+	if req.UserIDs == nil {
+		req.UserIDs = []int{}
+	}
+	utils.JSONResponse(w, req, status)
+}
+
+// deleteFeatureFlag ลบ flag (flag ที่ระบบใช้จะกลับไปใช้ค่าเริ่มต้น)
+func deleteFeatureFlag(w http.ResponseWriter, r *http.Request, key string) {
+	const snapshot = "SELECT flag_key, description, enabled, rollout_percent, user_ids FROM feature_flags WHERE flag_key = ?"
+	before := snapshotRow(snapshot, key)
+	if before == nil {
+		utils.JSONError(w, "Feature flag not found", http.StatusNotFound)
+		return
+	}
+	if _, err := db.Exec("DELETE FROM feature_flags WHERE flag_key = ?", key); err != nil {
+		fmt.Printf("❌ Error deleting feature flag %s: %v\n", key, err)
+		utils.JSONError(w, "Error deleting feature flag", http.StatusInternalServerError)
+		return
+	}
+	invalidateFlags()
+	recordAudit(r, "feature_flag_delete", "feature_flag", key, before, nil)
+
+	utils.JSONResponse(w, map[string]string{"message": "Feature flag deleted"}, http.StatusOK)
+}
+
+func hasFlagDefault(key string) bool {
+	_, ok := flagDefaults[key]
+	return ok
+}
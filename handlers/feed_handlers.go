@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go-api-game/cache"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// key ใน cache ของไฟล์ที่สร้างไว้ล่วงหน้า (ไม่ขึ้นต้นด้วย catalogCachePrefix เพื่อไม่ให้ถูกล้างทุกครั้งที่แก้เกม)
+const (
+	sitemapCacheKey   = "feed:sitemap.xml"
+	gamesFeedCacheKey = "feed:games.json"
+)
+
+// feedRefreshInterval รอบการสร้าง sitemap และ feed ใหม่ (งาน feed-regenerate)
+const feedRefreshInterval = time.Hour
+
+// sitemapMaxURLs จำนวน URL สูงสุดต่อ sitemap ตามข้อกำหนดของ sitemaps.org
+const sitemapMaxURLs = 50000
+
+// gamesFeedDays และ gamesFeedLimit ช่วงเวลาและจำนวนเกมที่วางจำหน่ายใหม่ใน feed
+const (
+	gamesFeedDays  = 30
+	gamesFeedLimit = 100
+)
+
+// appBaseURL URL สาธารณะของร้านค้า (APP_BASE_URL ค่าเริ่มต้น http://localhost:8080)
+func appBaseURL() string {
+	if base := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/"); base != "" {
+		return base
+	}
+	return "http://localhost:8080"
+}
+
+// gamePageURL ลิงก์ของหน้าเกม (ใช้ slug ถ้ามี)
+func gamePageURL(base string, id int, slug string) string {
+	if slug != "" {
+		return base + "/games/slug/" + slug
+	}
+	return base + "/games/" + strconv.Itoa(id)
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// buildSitemap สร้าง sitemap ของหน้าร้าน เกมที่เผยแพร่ และรายการเกมสาธารณะของผู้ใช้
+func buildSitemap() ([]byte, error) {
+	base := appBaseURL()
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, path := range []string{"/games", "/storefront", "/ranking", "/categories", "/games/new", "/games/upcoming", "/lists/popular"} {
+		set.URLs = append(set.URLs, sitemapURL{Loc: base + path, ChangeFreq: "daily"})
+	}
+
+	rows, err := db.Query("SELECT id, slug FROM games WHERE status = 'published' ORDER BY id LIMIT ?", sitemapMaxURLs)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching games for sitemap: %v", err)
+	}
+	for rows.Next() {
+		var id int
+		var slug sql.NullString
+		if err := rows.Scan(&id, &slug); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning game for sitemap: %v", err)
+		}
+		set.URLs = append(set.URLs, sitemapURL{Loc: gamePageURL(base, id, slug.String), ChangeFreq: "weekly"})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading games for sitemap: %v", err)
+	}
+
+	// รายการเกมสาธารณะ (ไม่รวมรายการที่ถูกซ่อนจากการรายงาน)
+	if remaining := sitemapMaxURLs - len(set.URLs); remaining > 0 {
+		rows, err = db.Query(`
+			SELECT slug, updated_at FROM user_lists
+			WHERE visibility = 'public' AND hidden = 0
+			ORDER BY updated_at DESC
+			LIMIT ?
+		`, remaining)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching lists for sitemap: %v", err)
+		}
+		for rows.Next() {
+			var slug string
+			var updatedAt utils.Timestamp
+			if err := rows.Scan(&slug, &updatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning list for sitemap: %v", err)
+			}
+			set.URLs = append(set.URLs, sitemapURL{Loc: base + "/lists/" + slug, LastMod: updatedAt.String()})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error reading lists for sitemap: %v", err)
+		}
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// gamesFeedItem เกมหนึ่งรายการใน feed (รูปแบบ JSON Feed 1.1 พร้อมข้อมูลราคาใน _game)
+type gamesFeedItem struct {
+	ID            string                 `json:"id"`
+	URL           string                 `json:"url"`
+	Title         string                 `json:"title"`
+	ContentText   string                 `json:"content_text"`
+	Image         string                 `json:"image,omitempty"`
+	DatePublished string                 `json:"date_published"`
+	Tags          []string               `json:"tags,omitempty"`
+	Game          map[string]interface{} `json:"_game"`
+}
+
+// buildGamesFeed สร้าง feed ของเกมที่วางจำหน่ายใน gamesFeedDays วันล่าสุด (ใหม่สุดก่อน)
+func buildGamesFeed() ([]byte, error) {
+	games, err := queryGameList(`
+		AND g.release_date <= CURDATE()
+		AND g.release_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+	`, "g.release_date DESC, g.id DESC", gamesFeedLimit, gamesFeedDays)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching games for feed: %v", err)
+	}
+
+	base := appBaseURL()
+	items := []gamesFeedItem{}
+	for _, game := range games {
+		id, _ := game["id"].(int)
+		slug, _ := game["slug"].(string)
+		released, _ := game["release_date"].(string)
+		item := gamesFeedItem{
+			ID:          strconv.Itoa(id),
+			URL:         gamePageURL(base, id, slug),
+			Title:       fmt.Sprint(game["name"]),
+			ContentText: fmt.Sprint(game["description"]),
+			Game: map[string]interface{}{
+				"id":               id,
+				"slug":             slug,
+				"price":            game["price"],
+				"on_sale":          game["on_sale"],
+				"lowest_price_30d": game["lowest_price_30d"],
+				"out_of_stock":     game["out_of_stock"],
+				"age_rating":       game["age_rating"],
+			},
+		}
+		// ภาพที่อัพโหลดไว้ในเซิร์ฟเวอร์เป็น path ต้องเติม URL ของร้านค้า
+		if image, _ := game["image_url"].(string); strings.HasPrefix(image, "/") {
+			item.Image = base + image
+		} else {
+			item.Image = image
+		}
+		if t, err := time.Parse("2006-01-02", released); err == nil {
+			item.DatePublished = utils.FormatTimestamp(t)
+		}
+		if category, _ := game["category"].(string); category != "" {
+			item.Tags = []string{category}
+		}
+		items = append(items, item)
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"version":       "https://jsonfeed.org/version/1.1",
+		"title":         "New releases",
+		"home_page_url": base + "/games/new",
+		"feed_url":      base + "/feed/games.json",
+		"items":         items,
+	})
+}
+
+// regenerateFeeds สร้าง sitemap และ feed ใหม่แล้วเก็บใน cache (เก็บไว้นานกว่ารอบการสร้าง เผื่องานทำงานช้า)
+func regenerateFeeds() error {
+	sitemap, err := buildSitemap()
+	if err != nil {
+		return err
+	}
+	feed, err := buildGamesFeed()
+	if err != nil {
+		return err
+	}
+	cache.Default.Set(sitemapCacheKey, sitemap, 3*feedRefreshInterval)
+	cache.Default.Set(gamesFeedCacheKey, feed, 3*feedRefreshInterval)
+	fmt.Printf("🗺️ Sitemap and games feed regenerated (%d bytes, %d bytes)\n", len(sitemap), len(feed))
+	return nil
+}
+
+// serveGeneratedFeed ส่งไฟล์จาก cache (สร้างทันทีถ้ายังไม่มี เช่น หลังรีสตาร์ทเซิร์ฟเวอร์ที่ใช้ in-memory cache)
+func serveGeneratedFeed(w http.ResponseWriter, r *http.Request, key, contentType string) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, ok := cache.Default.Get(key)
+	if !ok {
+		if err := regenerateFeeds(); err != nil {
+			fmt.Printf("❌ Error generating feeds: %v\n", err)
+			utils.JSONError(w, "Error generating feed", http.StatusInternalServerError)
+			return
+		}
+		data, _ = cache.Default.Get(key)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedRefreshInterval.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "GET" {
+		w.Write(data)
+	}
+}
+
+// SitemapHandler serves the sitemap for search engines
+// ฟังก์ชันสำหรับส่ง sitemap.xml (สร้างใหม่ทุก feedRefreshInterval)
+// GET /sitemap.xml
+func SitemapHandler(w http.ResponseWriter, r *http.Request) {
+	serveGeneratedFeed(w, r, sitemapCacheKey, "application/xml; charset=utf-8")
+}
+
+// GamesFeedHandler serves the new releases feed for partners and feed readers
+// ฟังก์ชันสำหรับส่ง feed ของเกมที่วางจำหน่ายใหม่ (JSON Feed สร้างใหม่ทุก feedRefreshInterval)
+// GET /feed/games.json
+func GamesFeedHandler(w http.ResponseWriter, r *http.Request) {
+	serveGeneratedFeed(w, r, gamesFeedCacheKey, "application/feed+json; charset=utf-8")
+}
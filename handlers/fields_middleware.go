@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"go-api-game/utils"
+	"net/http"
+	"strings"
+)
+
+// fieldsWriter ลบ header ภายในของ ?fields= ก่อนส่ง response ที่ไม่ได้ผ่าน JSONResponse (เช่น response จาก cache หรือ CSV)
+type fieldsWriter struct {
+	http.ResponseWriter
+}
+
+func (fw *fieldsWriter) WriteHeader(status int) {
+	fw.Header().Del(utils.FieldsHeader)
+	fw.ResponseWriter.WriteHeader(status)
+}
+
+func (fw *fieldsWriter) Write(b []byte) (int, error) {
+	fw.Header().Del(utils.FieldsHeader)
+	return fw.ResponseWriter.Write(b)
+}
+
+func (fw *fieldsWriter) Flush() {
+	fw.Header().Del(utils.FieldsHeader)
+	http.NewResponseController(fw.ResponseWriter).Flush()
+}
+
+func (fw *fieldsWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
+
+// SparseFields middleware enables ?fields= on JSON responses
+// Middleware สำหรับเลือกเฉพาะ field ที่ต้องการใน response (?fields=id,name,price) เพื่อลดขนาดข้อมูล
+// การตัด field ทำใน utils.JSONResponse จึงใช้ได้กับทุก endpoint ที่ตอบเป็น JSON
+// (รายการเกม, /search, /ranking, รายการของ admin ฯลฯ) โดยไม่ต้องแก้ handler
+func SparseFields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("fields")
+		if raw == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fields, err := utils.ParseFields(raw)
+		if err != nil {
+			utils.JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(utils.FieldsHeader, strings.Join(fields, ","))
+		next.ServeHTTP(&fieldsWriter{w}, r)
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminDuplicateGameHandler handles cloning a game into a new draft
+// ฟังก์ชันสำหรับคัดลอกเกมเป็นร่างใหม่ (ใช้สร้าง edition หรือเวอร์ชันตามภูมิภาคได้เร็วขึ้น)
+// POST /admin/games/{id}/duplicate  body (ไม่บังคับ): {"name": "...", "price": 39.99, "category_id": 2}
+// คัดลอกข้อมูลเกม ภาพ (อ้างอิงภาพเดิมในคลังภาพ) เรตติ้งอายุ และคำแปล
+// ไม่คัดลอกยอดขาย อันดับ และสต็อก (เกมใหม่เริ่มเป็นร่างที่ไม่จำกัดจำนวน)
+func AdminDuplicateGameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ตัวอย่าง URL: /admin/games/12/duplicate → sourceID = 12
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	sourceID, err := strconv.Atoi(pathParts[2])
+	if err != nil || sourceID <= 0 {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name       string  `json:"name" validate:"max=255"`
+		Price      float64 `json:"price" validate:"min=0"`
+		CategoryID int     `json:"category_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	var name string
+	var price float64
+	var categoryID sql.NullInt64
+	err = db.QueryRow("SELECT name, price, category_id FROM games WHERE id = ?", sourceID).Scan(&name, &price, &categoryID)
+	if err == sql.ErrNoRows {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error loading game to duplicate: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+
+	// ค่าที่ส่งมาแทนค่าของเกมต้นฉบับ
+	if req.Name == "" {
+		req.Name = name + " (Copy)"
+	}
+	if req.Price <= 0 {
+		req.Price = price
+	}
+	if req.CategoryID > 0 {
+		var exists bool
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = ?)", req.CategoryID).Scan(&exists)
+		if !exists {
+			utils.JSONError(w, "Invalid category ID", http.StatusBadRequest)
+			return
+		}
+		categoryID = sql.NullInt64{Int64: int64(req.CategoryID), Valid: true}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO games (name, price, category_id, image_url, description, release_date, status,
+		                   age_rating, min_age, content_descriptors, system_requirements)
+		SELECT ?, ?, ?, image_url, description, release_date, ?, age_rating, min_age, content_descriptors,
+		       system_requirements
+		FROM games WHERE id = ?
+	`, req.Name, req.Price, categoryID, GameStatusDraft, sourceID)
+	if err != nil {
+		fmt.Printf("❌ Error duplicating game: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+	gameID, _ := result.LastInsertId()
+
+	if _, err := tx.Exec(`
+		INSERT INTO game_translations (game_id, locale, name, description)
+		SELECT ?, locale, name, description FROM game_translations WHERE game_id = ?
+	`, gameID, sourceID); err != nil {
+		fmt.Printf("❌ Error duplicating game translations: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+	if _, err := assignGameSlug(tx, gameID, req.Name); err != nil {
+		fmt.Printf("❌ Error assigning game slug: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("INSERT INTO ranking (game_id, sales_count) VALUES (?, 0)", gameID); err != nil {
+		fmt.Printf("⚠️ Error initializing ranking: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+	if err := recordPriceChange(tx, r, gameID, -1, req.Price, PriceSourceCreate); err != nil {
+		fmt.Printf("❌ Error recording price history: %v\n", err)
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error duplicating game", http.StatusInternalServerError)
+		return
+	}
+
+	after := snapshotRow("SELECT * FROM games WHERE id = ?", gameID)
+	if after != nil {
+		after["duplicated_from"] = sourceID
+	}
+	recordAudit(r, "duplicate", "game", gameID, nil, after)
+	emitWebhookEvent(WebhookGameCreated, map[string]interface{}{
+		"game_id": gameID,
+		"name":    req.Name,
+		"price":   req.Price,
+		"status":  GameStatusDraft,
+	})
+
+	fmt.Printf("✅ Game %d duplicated as draft %d (%s)\n", sourceID, gameID, req.Name)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":         "Game duplicated",
+		"game_id":         gameID,
+		"duplicated_from": sourceID,
+		"name":            req.Name,
+		"status":          GameStatusDraft,
+	}, http.StatusCreated)
+}
@@ -20,17 +20,27 @@ func GamesHandler(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Printf("🔍 Fetching all games\n")
 
-	// ใช้ DATE_FORMAT เพื่อแปลง DATE เป็น string โดยตรง
-	rows, err := db.Query(`
-		SELECT g.id, g.name, g.price, c.name as category, g.image_url, 
+	// กรองตามอายุของผู้ชม (ถ้ามี ?max_age=)
+	ageWhere, ageArgs, err := ageFilter(r)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ใช้ dialect.DateFormat เพื่อแปลง DATE เป็น string โดยตรง
+	rows, err := readQuery(`
+		SELECT g.id, g.name, g.price, COALESCE(c.name, '') as category, g.image_url, 
 		       g.description, 
-		       DATE_FORMAT(g.release_date, '%Y-%m-%d') as release_date,
-		       r.rank_position
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+` as release_date,
+		       r.rank_position, COALESCE(tv.url, g.image_url) as thumbnail_url, g.stock_count,
+		       g.age_rating, g.content_descriptors, g.min_age, g.slug
 		FROM games g
 		LEFT JOIN categories c ON g.category_id = c.id
 		LEFT JOIN ranking r ON g.id = r.game_id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published'`+ageWhere+`
 		ORDER BY g.id
-	`)
+	`, ageArgs...)
 	if err != nil {
 		fmt.Printf("❌ Error fetching games: %v\n", err)
 		utils.JSONError(w, "Error fetching games: "+err.Error(), http.StatusInternalServerError)
@@ -47,26 +57,34 @@ func GamesHandler(w http.ResponseWriter, r *http.Request) {
 		var name string
 		var price float64
 		var category string
-		var imageURL, description sql.NullString
+		var imageURL, description, thumbnailURL sql.NullString
 		var releaseDate sql.NullString // เปลี่ยนเป็น string
-		var rank sql.NullInt64
+		var rank, stock sql.NullInt64
+		var ageRating, descriptors, slug sql.NullString
+		var minAge int
 
-		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &rank)
+		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &rank, &thumbnailURL, &stock,
+			&ageRating, &descriptors, &minAge, &slug)
 		if err != nil {
 			fmt.Printf("❌ Error scanning game row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error processing games", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object เกม
 		game := map[string]interface{}{
-			"id":          id,
-			"name":        name,
-			"price":       price,
-			"category":    category,
-			"image_url":   imageURL.String,
-			"description": description.String,
-			"rank":        rank.Int64,
+			"id":            id,
+			"slug":          slug.String,
+			"name":          name,
+			"price":         price,
+			"category":      category,
+			"image_url":     imageURL.String,
+			"thumbnail_url": thumbnailURL.String,
+			"description":   description.String,
+			"rank":          rank.Int64,
 		}
+		addStockFields(game, stock)
+		addAgeRatingFields(game, ageRating, descriptors, minAge)
 
 		// จัดการวันที่วางจำหน่าย
 		if releaseDate.Valid && releaseDate.String != "" {
@@ -94,10 +112,28 @@ func GamesHandler(w http.ResponseWriter, r *http.Request) {
 	if games == nil {
 		games = []map[string]interface{}{}
 	}
+	localizeGames(r, games)
+	addPriceLabels(games)
 
 	utils.JSONResponse(w, games, http.StatusOK)
 }
 
+// gameByID ข้อมูลเกมและประวัติราคา (ผ่าน ConditionalCatalog เพราะเปลี่ยนตามข้อมูลร้านค้าเท่านั้น)
+var gameByID = ConditionalCatalog(GameByIDHandler)
+
+// GameRoutesHandler dispatches /games/{id} sub-routes
+// ฟังก์ชันสำหรับแยกเส้นทางย่อยของ /games/{id}
+// /games/{id}/questions ไม่ผ่าน ConditionalCatalog เพราะคำถามใหม่ไม่ได้เปลี่ยน catalog version
+func GameRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /games/12/questions → ["games", "12", "questions"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) >= 3 && pathParts[1] != "slug" && pathParts[2] == "questions" {
+		GameQuestionsHandler(w, r)
+		return
+	}
+	gameByID(w, r)
+}
+
 // GameByIDHandler returns a specific game by ID
 // ฟังก์ชันสำหรับดึงข้อมูลเกมเฉพาะตาม ID
 func GameByIDHandler(w http.ResponseWriter, r *http.Request) {
@@ -108,12 +144,34 @@ func GameByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ดึง game_id จาก URL path
-	// ตัวอย่าง URL: /games/123 → gameID = 123
-	pathParts := strings.Split(r.URL.Path, "/")
-	idStr := pathParts[len(pathParts)-1]
-	gameID, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+	// ตัวอย่าง URL: /games/123 → gameID = 123, /games/slug/elden-ring → ค้นหา ID จาก slug
+	path, priceHistory := strings.CutSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/price-history")
+	var gameID int
+	var err error
+	if slug, ok := strings.CutPrefix(path, "/games/slug/"); ok {
+		err = readQueryRow("SELECT id FROM games WHERE slug = ? AND status = 'published'", slug).Scan(&gameID)
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ Error resolving game slug %q: %v\n", slug, err)
+			utils.JSONError(w, "Error fetching game", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		pathParts := strings.Split(path, "/")
+		idStr := pathParts[len(pathParts)-1]
+		gameID, err = strconv.Atoi(idStr)
+		if err != nil {
+			utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// GET /games/{id}/price-history → ประวัติราคาสำหรับกราฟราคา
+	if priceHistory {
+		GamePriceHistoryHandler(w, r, gameID)
 		return
 	}
 
@@ -121,33 +179,41 @@ func GameByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	// โครงสร้างสำหรับเก็บข้อมูลเกม
 	var game struct {
-		ID          int
-		Name        string
-		Price       float64
-		Category    string
-		ImageURL    sql.NullString
-		Description sql.NullString
-		ReleaseDate sql.NullString
-		Rank        sql.NullInt64
-	}
-
-	// ใช้ DATE_FORMAT เพื่อแปลง DATE เป็น string โดยตรง
-	err = db.QueryRow(`
-		SELECT g.id, g.name, g.price, c.name as category, g.image_url, 
+		ID           int
+		Name         string
+		Price        float64
+		Category     string
+		ImageURL     sql.NullString
+		Description  sql.NullString
+		ReleaseDate  sql.NullString
+		Rank         sql.NullInt64
+		Stock        sql.NullInt64
+		AgeRating    sql.NullString
+		Descriptors  sql.NullString
+		MinAge       int
+		Requirements sql.NullString
+		Slug         sql.NullString
+	}
+
+	// ใช้ dialect.DateFormat เพื่อแปลง DATE เป็น string โดยตรง
+	err = queryRowRetry(`
+		SELECT g.id, g.name, g.price, COALESCE(c.name, '') as category, g.image_url, 
 		       g.description, 
-		       DATE_FORMAT(g.release_date, '%Y-%m-%d') as release_date,
-		       r.rank_position
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+` as release_date,
+		       r.rank_position, g.stock_count,
+		       g.age_rating, g.content_descriptors, g.min_age, g.system_requirements, g.slug
 		FROM games g
 		LEFT JOIN categories c ON g.category_id = c.id
 		LEFT JOIN ranking r ON g.id = r.game_id
-		WHERE g.id = ?
+		WHERE g.id = ? AND g.status = 'published'
 	`, gameID).Scan(&game.ID, &game.Name, &game.Price, &game.Category,
-		&game.ImageURL, &game.Description, &game.ReleaseDate, &game.Rank)
+		&game.ImageURL, &game.Description, &game.ReleaseDate, &game.Rank, &game.Stock,
+		&game.AgeRating, &game.Descriptors, &game.MinAge, &game.Requirements, &game.Slug)
 
 	if err != nil {
 		fmt.Printf("❌ Error fetching game ID %d: %v\n", gameID, err)
 		if err == sql.ErrNoRows {
-			utils.JSONError(w, "Game not found", http.StatusNotFound)
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
 		} else {
 			utils.JSONError(w, "Error fetching game: "+err.Error(), http.StatusInternalServerError)
 		}
@@ -159,13 +225,18 @@ func GameByIDHandler(w http.ResponseWriter, r *http.Request) {
 	// สร้าง object เกมสำหรับ response
 	gameMap := map[string]interface{}{
 		"id":          game.ID,
+		"slug":        game.Slug.String,
 		"name":        game.Name,
 		"price":       game.Price,
 		"category":    game.Category,
 		"image_url":   game.ImageURL.String,
+		"images":      imageVariantsFor(game.ImageURL.String),
 		"description": game.Description.String,
 		"rank":        game.Rank.Int64,
 	}
+	addStockFields(gameMap, game.Stock)
+	addAgeRatingFields(gameMap, game.AgeRating, game.Descriptors, game.MinAge)
+	addSystemRequirementsField(gameMap, game.Requirements)
 
 	// จัดการวันที่วางจำหน่าย
 	if game.ReleaseDate.Valid && game.ReleaseDate.String != "" {
@@ -174,6 +245,9 @@ func GameByIDHandler(w http.ResponseWriter, r *http.Request) {
 		gameMap["release_date"] = nil
 	}
 
+	localizeGames(r, []map[string]interface{}{gameMap})
+	addPriceLabels([]map[string]interface{}{gameMap})
+
 	utils.JSONResponse(w, gameMap, http.StatusOK)
 }
 
@@ -201,13 +275,20 @@ func CategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		var id int
 		var name string
 		if err := rows.Scan(&id, &name); err != nil {
-			continue
+			fmt.Printf("❌ Error scanning category: %v\n", err)
+			utils.JSONError(w, "Error fetching categories", http.StatusInternalServerError)
+			return
 		}
 		categories = append(categories, map[string]interface{}{
 			"id":   id,
 			"name": name,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading categories: %v\n", err)
+		utils.JSONError(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
 
 	utils.JSONResponse(w, categories, http.StatusOK)
 }
@@ -229,22 +310,26 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 
 	// สร้างคำสั่ง SQL พื้นฐาน
 	sqlQuery := `
-		SELECT g.id, g.name, g.price, c.name as category, g.image_url, 
+		SELECT g.id, g.name, g.price, COALESCE(c.name, '') as category, g.image_url, 
 		       g.description, 
-		       DATE_FORMAT(g.release_date, '%Y-%m-%d') as release_date,
-		       r.rank_position
+		       ` + dialect.DateFormat("g.release_date", "%Y-%m-%d") + ` as release_date,
+		       r.rank_position, COALESCE(tv.url, g.image_url) as thumbnail_url, g.stock_count,
+		       g.age_rating, g.content_descriptors, g.min_age, g.slug
 		FROM games g
 		LEFT JOIN categories c ON g.category_id = c.id
 		LEFT JOIN ranking r ON g.id = r.game_id
-		WHERE 1=1
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published'
 	`
 	args := []interface{}{}
 
 	// เพิ่มเงื่อนไขการค้นหาตามคำค้นหา
 	if query != "" {
-		sqlQuery += " AND (g.name LIKE ? OR g.description LIKE ?)"
+		// ค้นหาทั้งข้อมูลหลักและคำแปลของเกม
+		sqlQuery += ` AND (g.name LIKE ? OR g.description LIKE ?
+			OR g.id IN (SELECT game_id FROM game_translations WHERE name LIKE ? OR description LIKE ?))`
 		searchTerm := "%" + query + "%"
-		args = append(args, searchTerm, searchTerm)
+		args = append(args, searchTerm, searchTerm, searchTerm, searchTerm)
 	}
 
 	// เพิ่มเงื่อนไขการค้นหาตามหมวดหมู่ (รองรับทั้ง ID และชื่อ)
@@ -261,13 +346,22 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// กรองตามอายุของผู้ชม (ถ้ามี ?max_age=)
+	ageWhere, ageArgs, err := ageFilter(r)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sqlQuery += ageWhere
+	args = append(args, ageArgs...)
+
 	sqlQuery += " ORDER BY g.name"
 
 	fmt.Printf("🔍 Executing search query: %s\n", sqlQuery)
 	fmt.Printf("🔍 Query parameters: %v\n", args)
 
 	// Execute query
-	rows, err := db.Query(sqlQuery, args...)
+	rows, err := readQuery(sqlQuery, args...)
 	if err != nil {
 		fmt.Printf("❌ Error searching games: %v\n", err)
 		utils.JSONError(w, "Error searching games: "+err.Error(), http.StatusInternalServerError)
@@ -284,26 +378,34 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		var name string
 		var price float64
 		var category string
-		var imageURL, description sql.NullString
+		var imageURL, description, thumbnailURL sql.NullString
 		var releaseDate sql.NullString
-		var rank sql.NullInt64
+		var rank, stock sql.NullInt64
+		var ageRating, descriptors, slug sql.NullString
+		var minAge int
 
-		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &rank)
+		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &rank, &thumbnailURL, &stock,
+			&ageRating, &descriptors, &minAge, &slug)
 		if err != nil {
 			fmt.Printf("❌ Error scanning search result row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error processing search results", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object เกม
 		game := map[string]interface{}{
-			"id":          id,
-			"name":        name,
-			"price":       price,
-			"category":    category,
-			"image_url":   imageURL.String,
-			"description": description.String,
-			"rank":        rank.Int64,
+			"id":            id,
+			"slug":          slug.String,
+			"name":          name,
+			"price":         price,
+			"category":      category,
+			"image_url":     imageURL.String,
+			"thumbnail_url": thumbnailURL.String,
+			"description":   description.String,
+			"rank":          rank.Int64,
 		}
+		addStockFields(game, stock)
+		addAgeRatingFields(game, ageRating, descriptors, minAge)
 
 		// จัดการวันที่วางจำหน่าย
 		if releaseDate.Valid && releaseDate.String != "" {
@@ -330,6 +432,8 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	if games == nil {
 		games = []map[string]interface{}{}
 	}
+	localizeGames(r, games)
+	addPriceLabels(games)
 
 	utils.JSONResponse(w, games, http.StatusOK)
 }
@@ -343,19 +447,108 @@ func RankingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("🔍 Fetching game rankings\n")
+	query := r.URL.Query()
 
-	// ใช้ sql.NullInt64 สำหรับ rank_position
-	rows, err := db.Query(`
+	// จำนวนเกมที่ต้องการ (ค่าเริ่มต้น 5 สูงสุด 50)
+	limit := 5
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+
+	// ช่วงเวลาของยอดขาย: 7d, 30d หรือ all (ค่าเริ่มต้น)
+	period := query.Get("period")
+	periodDays := map[string]int{"7d": 7, "30d": 30}
+	if period == "" {
+		period = "all"
+	}
+	if _, ok := periodDays[period]; !ok && period != "all" {
+		utils.JSONError(w, "Invalid period. Use 7d, 30d or all", http.StatusBadRequest)
+		return
+	}
+
+	// trending = เรียงตามความเร็วของยอดขายล่าสุด (7 วันล่าสุดเทียบกับค่าเฉลี่ยรายสัปดาห์ของ 4 สัปดาห์ก่อนหน้า)
+	trending := query.Get("sort") == "trending"
+
+	fmt.Printf("🔍 Fetching game rankings: limit=%d, period=%s, trending=%t\n", limit, period, trending)
+
+	// เงื่อนไขหมวดหมู่ (รองรับทั้ง ID และชื่อ)
+	categorySQL := ""
+	args := []interface{}{}
+	if category := query.Get("category"); category != "" {
+		if categoryID, err := strconv.Atoi(category); err == nil {
+			categorySQL = " AND g.category_id = ?"
+			args = append(args, categoryID)
+		} else {
+			categorySQL = " AND c.name = ?"
+			args = append(args, category)
+		}
+	}
+
+	// คอลัมน์ที่ทุกโหมดใช้ร่วมกัน: ..., sales_count, rank_position, release_date, thumbnail_url, score
+	var sqlQuery string
+	switch {
+	case trending:
+		sqlQuery = `
+		SELECT g.id, g.name, g.price, c.name as category, g.image_url,
+		       s.recent_sales, NULL,
+		       ` + dialect.DateFormat("g.release_date", "%Y-%m-%d") + ` as release_date,
+		       COALESCE(tv.url, g.image_url) as thumbnail_url,
+		       s.recent_sales - s.previous_sales / 4 as score
+		FROM (
+			SELECT pi.game_id,
+			       SUM(p.purchase_date >= DATE_SUB(NOW(), INTERVAL 7 DAY)) as recent_sales,
+			       SUM(p.purchase_date < DATE_SUB(NOW(), INTERVAL 7 DAY)) as previous_sales
+			FROM purchase_items pi
+			JOIN purchases p ON pi.purchase_id = p.id
+			WHERE p.purchase_date >= DATE_SUB(NOW(), INTERVAL 35 DAY)
+			GROUP BY pi.game_id
+		) s
+		JOIN games g ON s.game_id = g.id
+		JOIN categories c ON g.category_id = c.id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published' AND s.recent_sales > 0` + categorySQL + `
+		ORDER BY score DESC, s.recent_sales DESC, g.id
+		LIMIT ?`
+	case period != "all":
+		sqlQuery = `
+		SELECT g.id, g.name, g.price, c.name as category, g.image_url,
+		       s.sales, NULL,
+		       ` + dialect.DateFormat("g.release_date", "%Y-%m-%d") + ` as release_date,
+		       COALESCE(tv.url, g.image_url) as thumbnail_url,
+		       s.sales as score
+		FROM (
+			SELECT pi.game_id, COUNT(*) as sales
+			FROM purchase_items pi
+			JOIN purchases p ON pi.purchase_id = p.id
+			WHERE p.purchase_date >= DATE_SUB(NOW(), INTERVAL ? DAY)
+			GROUP BY pi.game_id
+		) s
+		JOIN games g ON s.game_id = g.id
+		JOIN categories c ON g.category_id = c.id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published'` + categorySQL + `
+		ORDER BY s.sales DESC, g.id
+		LIMIT ?`
+		args = append([]interface{}{periodDays[period]}, args...)
+	default:
+		// ใช้ sql.NullInt64 สำหรับ rank_position
+		sqlQuery = `
 		SELECT g.id, g.name, g.price, c.name as category, g.image_url, 
 		       r.sales_count, r.rank_position,
-		       DATE_FORMAT(g.release_date, '%Y-%m-%d') as release_date
+		       ` + dialect.DateFormat("g.release_date", "%Y-%m-%d") + ` as release_date,
+		       COALESCE(tv.url, g.image_url) as thumbnail_url,
+		       r.sales_count as score
 		FROM ranking r
 		JOIN games g ON r.game_id = g.id
 		JOIN categories c ON g.category_id = c.id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published'` + categorySQL + `
 		ORDER BY COALESCE(r.rank_position, 999), r.sales_count DESC
-		LIMIT 5
-	`)
+		LIMIT ?`
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(sqlQuery, args...)
 	if err != nil {
 		fmt.Printf("❌ Error fetching rankings: %v\n", err)
 		utils.JSONError(w, "Error fetching rankings: "+err.Error(), http.StatusInternalServerError)
@@ -372,21 +565,25 @@ func RankingHandler(w http.ResponseWriter, r *http.Request) {
 		var name string
 		var price float64
 		var category string
-		var imageURL sql.NullString
+		var imageURL, thumbnailURL sql.NullString
 		var salesCount int
 		var rank sql.NullInt64 // เปลี่ยนเป็น sql.NullInt64
 		var releaseDate sql.NullString
+		var score float64
 
-		err := rows.Scan(&id, &name, &price, &category, &imageURL, &salesCount, &rank, &releaseDate)
+		err := rows.Scan(&id, &name, &price, &category, &imageURL, &salesCount, &rank, &releaseDate, &thumbnailURL, &score)
 		if err != nil {
 			fmt.Printf("❌ Error scanning ranking row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error processing rankings", http.StatusInternalServerError)
+			return
 		}
 
-		// จัดการ NULL rank_position
+		// จัดการ NULL rank_position (โหมดช่วงเวลา/trending ใช้ลำดับในผลลัพธ์)
 		rankValue := 0
 		if rank.Valid {
 			rankValue = int(rank.Int64)
+		} else if trending || period != "all" {
+			rankValue = count + 1
 		}
 
 		// สร้าง object อันดับ
@@ -396,9 +593,13 @@ func RankingHandler(w http.ResponseWriter, r *http.Request) {
 			"price":         price,
 			"category":      category,
 			"image_url":     imageURL.String,
+			"thumbnail_url": thumbnailURL.String,
 			"sales_count":   salesCount,
 			"rank_position": rankValue,
 		}
+		if trending {
+			ranking["trend_score"] = score
+		}
 
 		// จัดการวันที่วางจำหน่าย
 		if releaseDate.Valid && releaseDate.String != "" {
@@ -425,45 +626,194 @@ func RankingHandler(w http.ResponseWriter, r *http.Request) {
 	if rankings == nil {
 		rankings = []map[string]interface{}{}
 	}
+	localizeGames(r, rankings)
+	addPriceLabels(rankings)
 
 	utils.JSONResponse(w, rankings, http.StatusOK)
 }
 
+// NewGamesHandler returns recently released games
+// ฟังก์ชันสำหรับดึงเกมที่เพิ่งวางจำหน่าย (GET /games/new?days=30&limit=20)
+func NewGamesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 30
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+
+	games, err := queryGameList(`
+		AND g.release_date <= CURDATE()
+		AND g.release_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+	`, "g.release_date DESC, g.id DESC", railLimit(r), days)
+	if err != nil {
+		fmt.Printf("❌ Error fetching new games: %v\n", err)
+		utils.JSONError(w, "Error fetching new games", http.StatusInternalServerError)
+		return
+	}
+	localizeGames(r, games)
+
+	utils.JSONResponse(w, games, http.StatusOK)
+}
+
+// UpcomingGamesHandler returns games with a future release date
+// ฟังก์ชันสำหรับดึงเกมที่กำลังจะวางจำหน่าย (GET /games/upcoming?limit=20)
+func UpcomingGamesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	games, err := queryGameList("AND g.release_date > CURDATE()", "g.release_date ASC, g.id", railLimit(r))
+	if err != nil {
+		fmt.Printf("❌ Error fetching upcoming games: %v\n", err)
+		utils.JSONError(w, "Error fetching upcoming games", http.StatusInternalServerError)
+		return
+	}
+	localizeGames(r, games)
+
+	utils.JSONResponse(w, games, http.StatusOK)
+}
+
+// railLimit อ่านจำนวนเกมสำหรับแถบแสดงผลหน้าแรก (ค่าเริ่มต้น 20 สูงสุด 100)
+func railLimit(r *http.Request) int {
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		return l
+	}
+	return 20
+}
+
+// queryGameList ดึงรายการเกมที่เผยแพร่แล้วในรูปแบบเดียวกับ /games พร้อมเงื่อนไขและการเรียงลำดับเพิ่มเติม
+func queryGameList(extraWhere, orderBy string, limit int, args ...interface{}) ([]map[string]interface{}, error) {
+	return queryGameListPage(extraWhere, orderBy, limit, 0, args...)
+}
+
+// queryGameListPage เหมือน queryGameList แต่ข้ามเกม offset รายการแรก (สำหรับแบ่งหน้า)
+func queryGameListPage(extraWhere, orderBy string, limit, offset int, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, c.name as category, g.image_url,
+		       g.description,
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+` as release_date,
+		       r.rank_position, COALESCE(tv.url, g.image_url) as thumbnail_url, g.stock_count,
+		       g.age_rating, g.content_descriptors, g.min_age, g.slug
+		FROM games g
+		LEFT JOIN categories c ON g.category_id = c.id
+		LEFT JOIN ranking r ON g.id = r.game_id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE g.status = 'published' `+extraWhere+`
+		ORDER BY `+orderBy+`
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name string
+		var price float64
+		var category, imageURL, description, releaseDate, thumbnailURL sql.NullString
+		var rank, stock sql.NullInt64
+		var ageRating, descriptors, slug sql.NullString
+		var minAge int
+
+		if err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &rank, &thumbnailURL, &stock,
+			&ageRating, &descriptors, &minAge, &slug); err != nil {
+			return nil, err
+		}
+
+		game := map[string]interface{}{
+			"id":            id,
+			"slug":          slug.String,
+			"name":          name,
+			"price":         price,
+			"category":      category.String,
+			"image_url":     imageURL.String,
+			"thumbnail_url": thumbnailURL.String,
+			"description":   description.String,
+			"rank":          rank.Int64,
+			"release_date":  nil,
+		}
+		addStockFields(game, stock)
+		addAgeRatingFields(game, ageRating, descriptors, minAge)
+		if releaseDate.Valid && releaseDate.String != "" {
+			game["release_date"] = releaseDate.String
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	addPriceLabels(games)
+	return games, nil
+}
+
 // LibraryHandler handles user game library
 // ฟังก์ชันสำหรับดึงคลังเกมของผู้ใช้
 func LibraryHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userIDInt, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
 
-	fmt.Printf("🔍 Library request for user ID: %s\n", userID)
+	fmt.Printf("🔍 Library request for user ID: %d\n", userIDInt)
 
-	// ตรวจสอบว่ามี User-ID หรือไม่
-	if userID == "" {
-		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+	fmt.Printf("🔍 Querying library for user ID: %d\n", userIDInt)
+
+	page, err := parsePage(r.URL.Query(), 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// แปลง User-ID เป็นตัวเลข
-	userIDInt, err := strconv.Atoi(userID)
+	// กรองตามเกมโปรด เกมที่ซ่อน และแท็ก (ดู libraryFilters)
+	filters, filterArgs, err := libraryFilters(r.URL.Query())
 	if err != nil {
-		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	orderBy, err := libraryOrderBy(r.URL.Query())
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	args := append([]interface{}{userIDInt}, filterArgs...)
 
-	fmt.Printf("🔍 Querying library for user ID: %d\n", userIDInt)
+	var total int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM purchased_games pg
+		JOIN games g ON pg.game_id = g.id
+		LEFT JOIN library_settings ls ON ls.user_id = pg.user_id AND ls.game_id = pg.game_id
+		WHERE pg.user_id = ?`+filters, args...).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting library: %v\n", err)
+		utils.JSONError(w, "Error fetching library", http.StatusInternalServerError)
+		return
+	}
 
-	// ใช้ DATE_FORMAT เพื่อแปลง DATE เป็น string โดยตรง
+	// ใช้ dialect.DateFormat เพื่อแปลง DATE เป็น string โดยตรง
 	rows, err := db.Query(`
-		SELECT g.id, g.name, g.price, c.name as category, g.image_url, 
+		SELECT g.id, g.name, g.price, COALESCE(c.name, '') as category, g.image_url, 
 		       g.description, 
-		       DATE_FORMAT(g.release_date, '%Y-%m-%d') as release_date,
-		       DATE_FORMAT(pg.purchased_at, '%Y-%m-%d %H:%i:%s') as purchased_date
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+` as release_date,
+		       pg.purchased_at as purchased_date,
+		       g.status = 'delisted' as delisted,
+		       COALESCE(ls.favorite, 0), COALESCE(ls.hidden, 0),
+		       COALESCE(ls.installed, 0), COALESCE(ls.playtime_minutes, 0), ls.last_played_at
 		FROM purchased_games pg
 		JOIN games g ON pg.game_id = g.id
 		JOIN categories c ON g.category_id = c.id
-		WHERE pg.user_id = ?
-		ORDER BY pg.purchased_at DESC
-	`, userIDInt)
+		LEFT JOIN library_settings ls ON ls.user_id = pg.user_id AND ls.game_id = pg.game_id
+		WHERE pg.user_id = ?`+filters+orderBy+`
+		LIMIT ? OFFSET ?
+	`, append(args, page.Limit, page.Offset)...)
 
 	if err != nil {
 		fmt.Printf("❌ Error fetching library: %v\n", err)
@@ -483,23 +833,34 @@ func LibraryHandler(w http.ResponseWriter, r *http.Request) {
 		var category string
 		var imageURL, description sql.NullString
 		var releaseDate sql.NullString
-		var purchasedDate string
+		var purchasedDate utils.Timestamp
+		var delisted, favorite, hidden, installed bool
+		var playtime int
+		var lastPlayed utils.NullTimestamp
 
-		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &purchasedDate)
+		err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &purchasedDate, &delisted,
+			&favorite, &hidden, &installed, &playtime, &lastPlayed)
 		if err != nil {
 			fmt.Printf("❌ Error scanning library row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching library", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object เกมในคลัง
 		game := map[string]interface{}{
-			"id":           id,
-			"name":         name,
-			"price":        price,
-			"category":     category,
-			"image_url":    imageURL.String,
-			"description":  description.String,
-			"purchased_at": purchasedDate,
+			"id":               id,
+			"name":             name,
+			"price":            price,
+			"category":         category,
+			"image_url":        imageURL.String,
+			"description":      description.String,
+			"purchased_at":     purchasedDate,
+			"delisted":         delisted, // เกมถูกถอดออกจากร้านแล้วแต่ยังเล่นได้
+			"favorite":         favorite,
+			"hidden":           hidden,
+			"installed":        installed,
+			"playtime_minutes": playtime,
+			"last_played_at":   lastPlayed,
 		}
 
 		// จัดการวันที่วางจำหน่าย
@@ -521,16 +882,14 @@ func LibraryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("✅ Total library games found: %d\n", count)
+	fmt.Printf("✅ Total library games found: %d (showing %d)\n", total, count)
 
 	// Always return games array, even if empty
 	if games == nil {
 		games = []map[string]interface{}{}
 	}
+	addLibraryTags(userIDInt, games)
 
 	// ส่ง response กลับพร้อมข้อมูลคลังเกม
-	utils.JSONResponse(w, map[string]interface{}{
-		"total_games": count,
-		"games":       games,
-	}, http.StatusOK)
+	utils.JSONResponse(w, paginatedResponse(games, total, page), http.StatusOK)
 }
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxGameSlugBase ความยาวสูงสุดของ slug ก่อนเติมเลขกันชื่อซ้ำ (คอลัมน์ games.slug ยาว 120)
+const maxGameSlugBase = 100
+
+// gameSlugPattern slug ที่ผู้ดูแลระบบกำหนดเอง (ตัวพิมพ์เล็ก ตัวเลข และขีดกลางคั่น)
+var gameSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// gameSlugBase สร้าง slug จากชื่อเกม (เช่น "Elden Ring: Nightreign" → "elden-ring-nightreign")
+func gameSlugBase(name string) string {
+	base := strings.Trim(slugUnsafeChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(base) > maxGameSlugBase {
+		base = strings.TrimRight(base[:maxGameSlugBase], "-")
+	}
+	if base == "" {
+		base = "game"
+	}
+	return base
+}
+
+// validGameSlug ตรวจสอบ slug ที่ผู้ดูแลระบบส่งมา
+func validGameSlug(slug string) bool {
+	return len(slug) <= maxGameSlugBase && gameSlugPattern.MatchString(slug)
+}
+
+// assignGameSlug กำหนด slug ให้เกมจากชื่อ ถ้าซ้ำกับเกมอื่นจะเติมเลขต่อท้าย (elden-ring, elden-ring-2, ...)
+// slug ไม่เปลี่ยนเมื่อแก้ไขชื่อเกม เพื่อให้ลิงก์เดิมยังใช้ได้
+func assignGameSlug(exec sqlExecutor, gameID int64, name string) (string, error) {
+	base := gameSlugBase(name)
+	for n := 1; n <= 20; n++ {
+		slug := base
+		if n > 1 {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		var taken int
+		if err := exec.QueryRow("SELECT COUNT(*) FROM games WHERE slug = ? AND id != ?", slug, gameID).Scan(&taken); err != nil {
+			return "", err
+		}
+		if taken > 0 {
+			continue
+		}
+		if _, err := exec.Exec("UPDATE games SET slug = ? WHERE id = ?", slug, gameID); err != nil {
+			// เกมอื่นได้ slug นี้ไปพร้อมกัน ลองเลขถัดไป
+			if key, ok := duplicateKeyName(err); ok && key == "uniq_games_slug" {
+				continue
+			}
+			return "", err
+		}
+		return slug, nil
+	}
+
+	// ชื่อซ้ำกันมาก ใช้ ID ของเกมต่อท้ายแทน (ไม่ซ้ำแน่นอน)
+	slug := fmt.Sprintf("%s-%d", base, gameID)
+	if _, err := exec.Exec("UPDATE games SET slug = ? WHERE id = ?", slug, gameID); err != nil {
+		return "", err
+	}
+	return slug, nil
+}
+
+// backfillGameSlugs กำหนด slug ให้เกมเดิมที่ยังไม่มี (ทำตอนเริ่มเซิร์ฟเวอร์)
+func backfillGameSlugs() {
+	rows, err := db.Query("SELECT id, name FROM games WHERE slug IS NULL ORDER BY id")
+	if err != nil {
+		fmt.Printf("⚠️ Error loading games without slug: %v\n", err)
+		return
+	}
+	type pendingGame struct {
+		id   int64
+		name string
+	}
+	var games []pendingGame
+	for rows.Next() {
+		var g pendingGame
+		if err := rows.Scan(&g.id, &g.name); err != nil {
+			rows.Close()
+			fmt.Printf("⚠️ Error scanning game without slug: %v\n", err)
+			return
+		}
+		games = append(games, g)
+	}
+	rows.Close()
+
+	for _, g := range games {
+		if _, err := assignGameSlug(db, g.id, g.name); err != nil {
+			fmt.Printf("⚠️ Error assigning slug to game %d: %v\n", g.id, err)
+		}
+	}
+	if len(games) > 0 {
+		fmt.Printf("🔗 Assigned slugs to %d games\n", len(games))
+	}
+}
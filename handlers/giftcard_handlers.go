@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/mailer"
+	"go-api-game/utils"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ขีดจำกัดสำหรับป้องกันการทุจริตในการแลกบัตรของขวัญ
+const (
+	giftCardMaxFailedAttemptsPerHour = 5    // จำนวนครั้งที่แลกผิดได้สูงสุดต่อชั่วโมง
+	giftCardMaxRedeemPerDay          = 1000 // ยอดเงินสูงสุดที่แลกได้ต่อวัน
+	giftCardMaxValue                 = 500  // มูลค่าสูงสุดของบัตรหนึ่งใบ
+	giftCardMaxBatch                 = 100  // จำนวนบัตรสูงสุดที่สร้างได้ต่อครั้ง
+)
+
+// ตัวอักษรที่ใช้สร้างรหัสบัตร (ตัด 0, O, 1, I ออกเพื่อไม่ให้สับสน)
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateGiftCardCode สร้างรหัสบัตรของขวัญแบบสุ่ม รูปแบบ GIFT-XXXX-XXXX-XXXX
+func generateGiftCardCode() (string, error) {
+	groups := make([]string, 3)
+	for g := range groups {
+		var sb strings.Builder
+		for i := 0; i < 4; i++ {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(giftCardAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(giftCardAlphabet[n.Int64()])
+		}
+		groups[g] = sb.String()
+	}
+	return "GIFT-" + strings.Join(groups, "-"), nil
+}
+
+// AdminGiftCardHandler handles gift card management
+// ฟังก์ชันหลักสำหรับจัดการบัตรของขวัญโดยผู้ดูแลระบบ
+func AdminGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract ID จาก URL ถ้ามี
+	// ตัวอย่าง URL: /admin/gift-cards/123 → id = 123
+	var id int
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) >= 3 {
+		if parsedID, err := strconv.Atoi(pathParts[2]); err == nil {
+			id = parsedID
+		}
+	}
+
+	// กำหนดการทำงานตาม HTTP Method
+	switch r.Method {
+	case "GET":
+		getAllGiftCards(w, r) // ดึงบัตรของขวัญทั้งหมด
+	case "POST":
+		createGiftCards(w, r) // สร้างบัตรของขวัญใหม่
+	case "DELETE":
+		if id > 0 {
+			deactivateGiftCard(w, r, id) // ปิดการใช้งานบัตร
+		} else {
+			utils.JSONError(w, "Gift card ID required", http.StatusBadRequest)
+		}
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /admin/gift-cards - ดึงบัตรของขวัญทั้งหมด
+func getAllGiftCards(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("🔍 Fetching all gift cards")
+
+	rows, err := db.Query(`
+		SELECT gc.id, gc.code, gc.value, gc.active,
+		       ` + dialect.DateFormat("gc.expires_at", "%Y-%m-%d") + ` as expires_at,
+		       gc.created_at, gc.redeemed_by, u.username, gc.redeemed_at
+		FROM gift_cards gc
+		LEFT JOIN users u ON gc.redeemed_by = u.id
+		ORDER BY gc.created_at DESC
+	`)
+	if err != nil {
+		fmt.Printf("❌ Error fetching gift cards: %v\n", err)
+		utils.JSONError(w, "Error fetching gift cards", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var giftCards []map[string]interface{}
+
+	// อ่านข้อมูลบัตรของขวัญทีละแถว
+	for rows.Next() {
+		var id int
+		var code string
+		var value float64
+		var active bool
+		var createdAt utils.Timestamp
+		var redeemedAt utils.NullTimestamp
+		var expiresAt, redeemedByName sql.NullString
+		var redeemedBy sql.NullInt64
+
+		if err := rows.Scan(&id, &code, &value, &active, &expiresAt, &createdAt, &redeemedBy, &redeemedByName, &redeemedAt); err != nil {
+			fmt.Printf("❌ Error scanning gift card row: %v\n", err)
+			utils.JSONError(w, "Error fetching gift cards", http.StatusInternalServerError)
+			return
+		}
+
+		giftCard := map[string]interface{}{
+			"id":          id,
+			"code":        code,
+			"value":       value,
+			"active":      active,
+			"created_at":  createdAt,
+			"redeemed":    redeemedBy.Valid,
+			"expires_at":  nil,
+			"redeemed_by": nil,
+			"redeemed_at": redeemedAt,
+		}
+
+		// ตั้งค่าข้อมูลที่อาจเป็น NULL
+		if expiresAt.Valid {
+			giftCard["expires_at"] = expiresAt.String
+		}
+		if redeemedBy.Valid {
+			giftCard["redeemed_by"] = map[string]interface{}{
+				"user_id":  redeemedBy.Int64,
+				"username": redeemedByName.String,
+			}
+		}
+
+		giftCards = append(giftCards, giftCard)
+	}
+
+	// ตรวจสอบข้อผิดพลาดระหว่างการอ่านข้อมูล
+	if err = rows.Err(); err != nil {
+		fmt.Printf("❌ Error during rows iteration: %v\n", err)
+		utils.JSONError(w, "Error processing gift cards", http.StatusInternalServerError)
+		return
+	}
+
+	if giftCards == nil {
+		giftCards = []map[string]interface{}{}
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"gift_cards": giftCards,
+		"total":      len(giftCards),
+	}, http.StatusOK)
+}
+
+// POST /admin/gift-cards - สร้างบัตรของขวัญใหม่ (สร้างได้ครั้งละหลายใบ)
+func createGiftCards(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("➕ Creating gift cards")
+
+	// โครงสร้างสำหรับเก็บข้อมูลจาก request
+	var req struct {
+		Code      string  `json:"code"`       // รหัสบัตร (ถ้าไม่ส่งจะสุ่มให้)
+		Value     float64 `json:"value"`      // มูลค่าบัตร
+		Quantity  int     `json:"quantity"`   // จำนวนบัตรที่ต้องการสร้าง (ค่าเริ่มต้น 1)
+		ExpiresAt *string `json:"expires_at"` // วันหมดอายุ (YYYY-MM-DD)
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validation ข้อมูล
+	if req.Value <= 0 {
+		utils.JSONError(w, "Gift card value must be greater than 0", http.StatusBadRequest)
+		return
+	}
+	if req.Value > giftCardMaxValue {
+		utils.JSONError(w, fmt.Sprintf("Gift card value cannot exceed $%d", giftCardMaxValue), http.StatusBadRequest)
+		return
+	}
+	if req.Quantity <= 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity > giftCardMaxBatch {
+		utils.JSONError(w, fmt.Sprintf("Cannot create more than %d gift cards at once", giftCardMaxBatch), http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.Code != "" && req.Quantity > 1 {
+		utils.JSONError(w, "Custom code can only be used when quantity is 1", http.StatusBadRequest)
+		return
+	}
+
+	// Parse วันหมดอายุ
+	var expiresAt interface{}
+	if req.ExpiresAt != nil && *req.ExpiresAt != "" {
+		date, err := time.Parse("2006-01-02", *req.ExpiresAt)
+		if err != nil {
+			utils.JSONError(w, "Invalid expiry date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		expiresAt = date
+	}
+
+	// ดึง ID ของ admin ที่สร้างบัตร
+	adminID, _ := requestUserID(r)
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var codes []string
+	var ids []int64
+	for i := 0; i < req.Quantity; i++ {
+		code := req.Code
+		if code == "" {
+			code, err = generateGiftCardCode()
+			if err != nil {
+				tx.Rollback()
+				utils.JSONError(w, "Error generating gift card code", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO gift_cards (code, value, expires_at, created_by)
+			VALUES (?, ?, ?, ?)
+		`, code, req.Value, expiresAt, adminID)
+		if err != nil {
+			tx.Rollback()
+			if strings.Contains(err.Error(), "Duplicate entry") {
+				utils.JSONErrorCode(w, utils.ErrCodeAlreadyExists, "Gift card code already exists", http.StatusConflict)
+				return
+			}
+			fmt.Printf("❌ Error creating gift card: %v\n", err)
+			utils.JSONError(w, "Error creating gift card", http.StatusInternalServerError)
+			return
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+		codes = append(codes, code)
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error completing gift card creation", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("✅ Created %d gift cards worth $%.2f each\n", len(codes), req.Value)
+	for _, id := range ids {
+		recordAudit(r, "create", "gift_card", id, nil, snapshotRow("SELECT * FROM gift_cards WHERE id = ?", id))
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Gift cards created successfully",
+		"codes":   codes,
+		"value":   req.Value,
+		"count":   len(codes),
+	}, http.StatusCreated)
+}
+
+// DELETE /admin/gift-cards/{id} - ปิดการใช้งานบัตรของขวัญ (เก็บประวัติไว้)
+func deactivateGiftCard(w http.ResponseWriter, r *http.Request, id int) {
+	fmt.Printf("🚫 Deactivating gift card: ID=%d\n", id)
+	before := snapshotRow("SELECT * FROM gift_cards WHERE id = ?", id)
+
+	result, err := db.Exec("UPDATE gift_cards SET active = 0 WHERE id = ? AND redeemed_by IS NULL", id)
+	if err != nil {
+		utils.JSONError(w, "Error deactivating gift card", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Gift card not found or already redeemed", http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "deactivate", "gift_card", id, before, snapshotRow("SELECT * FROM gift_cards WHERE id = ?", id))
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Gift card deactivated successfully",
+		"id":      id,
+	}, http.StatusOK)
+}
+
+// RedeemGiftCardHandler handles gift card redemption into the wallet
+// ฟังก์ชันสำหรับแลกบัตรของขวัญเพื่อเติมเงินเข้ากระเป๋า
+func RedeemGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	// ตรวจสอบว่าเป็นเมธอด POST หรือไม่
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ดึง User-ID จาก context
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+	if !featureEnabled(FlagGiftCards, userID) {
+		utils.JSONError(w, "Feature not available", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"` // รหัสบัตรของขวัญ
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.Code == "" {
+		utils.JSONError(w, "Gift card code is required", http.StatusBadRequest)
+		return
+	}
+
+	ip := clientIP(r)
+
+	// ตรวจสอบจำนวนครั้งที่แลกผิดในชั่วโมงที่ผ่านมา
+	var failedAttempts int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM gift_card_redemptions
+		WHERE user_id = ? AND success = 0 AND created_at >= DATE_SUB(NOW(), INTERVAL 1 HOUR)
+	`, userID).Scan(&failedAttempts)
+	if err != nil {
+		utils.JSONError(w, "Error checking redemption history", http.StatusInternalServerError)
+		return
+	}
+	if failedAttempts >= giftCardMaxFailedAttemptsPerHour {
+		fmt.Printf("🚨 Gift card redemption blocked: user_id=%d, failed attempts=%d\n", userID, failedAttempts)
+		utils.JSONError(w, "Too many failed redemption attempts. Please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// recordFailure บันทึกการแลกที่ล้มเหลวลงใน ledger
+	recordFailure := func(giftCardID interface{}, reason string) {
+		_, err := db.Exec(`
+			INSERT INTO gift_card_redemptions (gift_card_id, user_id, code, success, reason, ip_address)
+			VALUES (?, ?, ?, 0, ?, ?)
+		`, giftCardID, userID, req.Code, reason, ip)
+		if err != nil {
+			fmt.Printf("❌ Error recording failed redemption: %v\n", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// ล็อกแถวบัตรเพื่อป้องกันการแลกซ้ำพร้อมกัน
+	var giftCard struct {
+		ID         int
+		Value      float64
+		Active     bool
+		ExpiresAt  sql.NullString
+		RedeemedBy sql.NullInt64
+	}
+	err = tx.QueryRow(`
+		SELECT id, value, active, `+dialect.DateFormat("expires_at", "%Y-%m-%d")+`, redeemed_by
+		FROM gift_cards
+		WHERE code = ?
+		FOR UPDATE
+	`, req.Code).Scan(&giftCard.ID, &giftCard.Value, &giftCard.Active, &giftCard.ExpiresAt, &giftCard.RedeemedBy)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			recordFailure(nil, "not found")
+			utils.JSONErrorCode(w, utils.ErrCodeNotFound, "Invalid gift card code", http.StatusBadRequest)
+		} else {
+			utils.JSONError(w, "Error checking gift card", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// ตรวจสอบสถานะบัตร
+	if giftCard.RedeemedBy.Valid {
+		tx.Rollback()
+		recordFailure(giftCard.ID, "already redeemed")
+		utils.JSONErrorCode(w, utils.ErrCodeAlreadyUsed, "Gift card has already been redeemed", http.StatusConflict)
+		return
+	}
+	if !giftCard.Active {
+		tx.Rollback()
+		recordFailure(giftCard.ID, "inactive")
+		utils.JSONErrorCode(w, utils.ErrCodeInactive, "Gift card is no longer active", http.StatusBadRequest)
+		return
+	}
+	if giftCard.ExpiresAt.Valid {
+		if expiresAt, err := time.Parse("2006-01-02", giftCard.ExpiresAt.String); err == nil && time.Now().After(expiresAt.AddDate(0, 0, 1)) {
+			tx.Rollback()
+			recordFailure(giftCard.ID, "expired")
+			utils.JSONErrorCode(w, utils.ErrCodeExpired, "Gift card has expired", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// ตรวจสอบยอดแลกรวมของวันนี้ไม่เกินขีดจำกัด
+	var redeemedToday float64
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM gift_card_redemptions
+		WHERE user_id = ? AND success = 1 AND created_at >= CURDATE()
+	`, userID).Scan(&redeemedToday)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error checking redemption history", http.StatusInternalServerError)
+		return
+	}
+	if redeemedToday+giftCard.Value > giftCardMaxRedeemPerDay {
+		tx.Rollback()
+		recordFailure(giftCard.ID, "daily limit exceeded")
+		utils.JSONErrorCode(w, utils.ErrRedeemLimitExceeded, fmt.Sprintf("Daily gift card redemption limit of $%d exceeded", giftCardMaxRedeemPerDay), http.StatusBadRequest)
+		return
+	}
+
+	// ทำเครื่องหมายว่าบัตรถูกแลกแล้ว
+	_, err = tx.Exec("UPDATE gift_cards SET redeemed_by = ?, redeemed_at = NOW() WHERE id = ?", userID, giftCard.ID)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error redeeming gift card", http.StatusInternalServerError)
+		return
+	}
+
+	// เติมเงินเข้ากระเป๋า
+	_, err = tx.Exec("UPDATE users SET wallet_balance = wallet_balance + ? WHERE id = ?", giftCard.Value, userID)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error updating wallet", http.StatusInternalServerError)
+		return
+	}
+
+	// บันทึกประวัติธุรกรรม
+	_, err = tx.Exec(`
+		INSERT INTO user_transactions (user_id, type, amount, description)
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionGiftReceived, giftCard.Value, fmt.Sprintf("Gift card redeemed: %s", req.Code))
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error recording transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// บันทึกลง ledger การแลกบัตร
+	_, err = tx.Exec(`
+		INSERT INTO gift_card_redemptions (gift_card_id, user_id, code, amount, success, ip_address)
+		VALUES (?, ?, ?, ?, 1, ?)
+	`, giftCard.ID, userID, req.Code, giftCard.Value, ip)
+	if err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error recording redemption", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error completing redemption", http.StatusInternalServerError)
+		return
+	}
+
+	// ดึงยอดเงินล่าสุด
+	var balance float64
+	db.QueryRow("SELECT wallet_balance FROM users WHERE id = ?", userID).Scan(&balance)
+
+	fmt.Printf("✅ Gift card redeemed: user_id=%d, code=%s, value=%.2f\n", userID, req.Code, giftCard.Value)
+	pushWalletBalance(userID)
+	notify(userID, NotificationGiftReceived, "Gift card redeemed",
+		fmt.Sprintf("$%.2f has been added to your wallet", giftCard.Value),
+		map[string]interface{}{"gift_card_id": giftCard.ID, "amount": giftCard.Value})
+	sendUserMail(userID, mailer.TemplateGift, map[string]interface{}{
+		"Message": fmt.Sprintf("Your gift card has been redeemed and $%.2f was added to your wallet.", giftCard.Value),
+	})
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Gift card redeemed successfully",
+		"amount":  giftCard.Value,
+		"balance": balance,
+	}, http.StatusOK)
+}
@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api-game/graphql"
+	"go-api-game/utils"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// จำกัดขนาดคำขอ GraphQL เพื่อป้องกัน query ที่ใหญ่เกินไป
+const (
+	graphQLMaxBodyBytes = 64 << 10
+	graphQLMaxBatch     = 10
+	graphQLMaxLimit     = 100
+)
+
+var errGraphQLAuth = errors.New("Authentication required")
+
+// graphQLEnabled เปิด/ปิด endpoint ด้วย GRAPHQL_ENABLED (ค่าเริ่มต้นเปิด ตั้ง "false" เพื่อปิด)
+func graphQLEnabled() bool {
+	return os.Getenv("GRAPHQL_ENABLED") != "false"
+}
+
+// GraphQLHandler handles GraphQL queries
+// ฟังก์ชันสำหรับรับ query GraphQL (POST /graphql หรือ GET /graphql?query=)
+// client เลือกเฉพาะ field ที่ต้องการได้ และส่งหลายคำขอในครั้งเดียวได้ด้วย JSON array (batching)
+// field cart, library และ purchases ต้องยืนยันตัวตน (Bearer token หรือ cookie + CSRF)
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := requestUserID(r)
+	if !graphQLEnabled() || !featureEnabled(FlagGraphQL, userID) {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var requests []graphql.Request
+	batched := false
+	switch r.Method {
+	case "GET":
+		// GET รองรับคำขอเดียว (ใช้กับ cache ของ browser/CDN ได้)
+		query := r.URL.Query()
+		req := graphql.Request{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if raw := query.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				utils.JSONError(w, "variables must be a JSON object", http.StatusBadRequest)
+				return
+			}
+		}
+		requests = []graphql.Request{req}
+	case "POST":
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, graphQLMaxBodyBytes))
+		if err != nil {
+			utils.JSONError(w, fmt.Sprintf("Request body too large (max %d KB)", graphQLMaxBodyBytes>>10), http.StatusRequestEntityTooLarge)
+			return
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			batched = true
+			err = json.Unmarshal(body, &requests)
+		} else {
+			var req graphql.Request
+			err = json.Unmarshal(body, &req)
+			requests = []graphql.Request{req}
+		}
+		if err != nil {
+			utils.JSONError(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(requests) == 0 {
+		utils.JSONError(w, "Batch must contain at least one query", http.StatusBadRequest)
+		return
+	}
+	if len(requests) > graphQLMaxBatch {
+		utils.JSONError(w, fmt.Sprintf("Too many queries in batch (max %d)", graphQLMaxBatch), http.StatusBadRequest)
+		return
+	}
+
+	schema := graphQLSchema(r)
+	responses := make([]graphql.Response, len(requests))
+	for i, req := range requests {
+		responses[i] = schema.Execute(req)
+	}
+
+	// ข้อมูลของผู้ใช้ (cart/library/purchases) ห้าม cache ร่วมกัน
+	w.Header().Set("Cache-Control", "private, no-store")
+	if batched {
+		utils.JSONResponse(w, responses, http.StatusOK)
+		return
+	}
+	utils.JSONResponse(w, responses[0], http.StatusOK)
+}
+
+// graphQLSchema root fields ของ Query สำหรับ request นี้ (resolver ใช้ผู้ใช้และภาษาของ request)
+func graphQLSchema(r *http.Request) *graphql.Schema {
+	return &graphql.Schema{Query: map[string]graphql.RootField{
+		"games": {
+			Args: []string{"limit", "offset", "category", "search", "max_age"},
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLGames(r, args)
+			},
+		},
+		"game": {
+			Args: []string{"id"},
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLGame(r, args)
+			},
+		},
+		"categories": {
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLCategories()
+			},
+		},
+		"cart": {
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLCart(r)
+			},
+		},
+		"library": {
+			Args: []string{"limit", "offset"},
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLLibrary(r, args)
+			},
+		},
+		"purchases": {
+			Args: []string{"limit", "offset"},
+			Resolve: func(args map[string]interface{}) (interface{}, error) {
+				return resolveGraphQLPurchases(r, args)
+			},
+		},
+	}}
+}
+
+// graphQLPage อ่าน limit/offset (ค่าเริ่มต้น 20 สูงสุด graphQLMaxLimit)
+func graphQLPage(args map[string]interface{}) (int, int, error) {
+	limit, err := graphql.IntArg(args, "limit", 20)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := graphql.IntArg(args, "offset", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit < 1 || limit > graphQLMaxLimit {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", graphQLMaxLimit)
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset must not be negative")
+	}
+	return limit, offset, nil
+}
+
+// resolveGraphQLGames รายการเกมที่เผยแพร่แล้ว (ตัวกรองเหมือน /search)
+func resolveGraphQLGames(r *http.Request, args map[string]interface{}) (interface{}, error) {
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+
+	where := ""
+	var params []interface{}
+	search, err := graphql.StringArg(args, "search")
+	if err != nil {
+		return nil, err
+	}
+	if search != "" {
+		where += ` AND (g.name LIKE ? OR g.description LIKE ?
+			OR g.id IN (SELECT game_id FROM game_translations WHERE name LIKE ? OR description LIKE ?))`
+		term := "%" + search + "%"
+		params = append(params, term, term, term, term)
+	}
+
+	// หมวดหมู่รับเป็น ID หรือชื่อ
+	switch category := args["category"].(type) {
+	case nil:
+	case int, float64:
+		categoryID, err := graphql.IntArg(args, "category", 0)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND g.category_id = ?"
+		params = append(params, categoryID)
+	case string:
+		if categoryID, err := strconv.Atoi(category); err == nil {
+			where += " AND g.category_id = ?"
+			params = append(params, categoryID)
+		} else {
+			where += " AND c.name = ?"
+			params = append(params, category)
+		}
+	default:
+		return nil, fmt.Errorf("Argument \"category\" must be an ID or a name")
+	}
+
+	if args["max_age"] != nil {
+		maxAge, err := graphql.IntArg(args, "max_age", 0)
+		if err != nil || maxAge < 0 {
+			return nil, fmt.Errorf("invalid max_age. Use a non-negative number of years")
+		}
+		where += " AND g.min_age <= ?"
+		params = append(params, maxAge)
+	}
+
+	games, err := queryGameListPage(where, "g.name ASC, g.id ASC", limit, offset, params...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching games for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching games")
+	}
+	localizeGames(r, games)
+	return games, nil
+}
+
+// resolveGraphQLGame เกมเดียวตาม ID (null ถ้าไม่พบหรือยังไม่เผยแพร่)
+func resolveGraphQLGame(r *http.Request, args map[string]interface{}) (interface{}, error) {
+	var id int
+	var err error
+	if raw, ok := args["id"].(string); ok {
+		id, err = strconv.Atoi(raw)
+	} else {
+		id, err = graphql.IntArg(args, "id", 0)
+	}
+	if err != nil || id <= 0 {
+		return nil, errors.New("Invalid game ID")
+	}
+
+	games, err := queryGameList(" AND g.id = ?", "g.id", 1, id)
+	if err != nil {
+		fmt.Printf("❌ Error fetching game %d for GraphQL: %v\n", id, err)
+		return nil, errors.New("Error fetching game")
+	}
+	if len(games) == 0 {
+		return nil, nil
+	}
+	localizeGames(r, games)
+	return games[0], nil
+}
+
+// resolveGraphQLCategories หมวดหมู่ทั้งหมด
+func resolveGraphQLCategories() (interface{}, error) {
+	rows, err := db.Query("SELECT id, name FROM categories ORDER BY name")
+	if err != nil {
+		fmt.Printf("❌ Error fetching categories for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching categories")
+	}
+	defer rows.Close()
+
+	categories := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			fmt.Printf("❌ Error scanning category for GraphQL: %v\n", err)
+			return nil, errors.New("Error fetching categories")
+		}
+		categories = append(categories, map[string]interface{}{"id": id, "name": name})
+	}
+	return categories, rows.Err()
+}
+
+// resolveGraphQLCart ตะกร้าของผู้ใช้ (รูปแบบเดียวกับ GET /cart)
+func resolveGraphQLCart(r *http.Request) (interface{}, error) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		return nil, errGraphQLAuth
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, c.name as category, g.image_url, ci.quantity
+		FROM cart_items ci
+		JOIN games g ON ci.game_id = g.id
+		JOIN categories c ON g.category_id = c.id
+		JOIN carts ca ON ci.cart_id = ca.id
+		WHERE ca.user_id = ?
+	`, userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching cart for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching cart")
+	}
+	defer rows.Close()
+
+	items := []map[string]interface{}{}
+	total := 0.0
+	for rows.Next() {
+		var id, quantity int
+		var name, category string
+		var price float64
+		var imageURL sql.NullString
+		if err := rows.Scan(&id, &name, &price, &category, &imageURL, &quantity); err != nil {
+			fmt.Printf("❌ Error scanning cart item for GraphQL: %v\n", err)
+			return nil, errors.New("Error fetching cart")
+		}
+		subtotal := price * float64(quantity)
+		total += subtotal
+		items = append(items, map[string]interface{}{
+			"id":        id,
+			"game_id":   id,
+			"name":      name,
+			"price":     price,
+			"category":  category,
+			"image_url": imageURL.String,
+			"quantity":  quantity,
+			"subtotal":  subtotal,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("Error fetching cart")
+	}
+	localizeGames(r, items)
+
+	return map[string]interface{}{
+		"items":      items,
+		"total":      total,
+		"item_count": len(items),
+	}, nil
+}
+
+// resolveGraphQLLibrary เกมในคลังของผู้ใช้ (ใหม่สุดก่อน)
+func resolveGraphQLLibrary(r *http.Request, args map[string]interface{}) (interface{}, error) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		return nil, errGraphQLAuth
+	}
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, c.name as category, g.image_url, g.description,
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+` as release_date,
+		       pg.purchased_at,
+		       g.status = 'delisted' as delisted
+		FROM purchased_games pg
+		JOIN games g ON pg.game_id = g.id
+		JOIN categories c ON g.category_id = c.id
+		WHERE pg.user_id = ?
+		ORDER BY pg.purchased_at DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching library for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching library")
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name, category string
+		var purchasedAt utils.Timestamp
+		var price float64
+		var imageURL, description, releaseDate sql.NullString
+		var delisted bool
+		if err := rows.Scan(&id, &name, &price, &category, &imageURL, &description, &releaseDate, &purchasedAt, &delisted); err != nil {
+			fmt.Printf("❌ Error scanning library game for GraphQL: %v\n", err)
+			return nil, errors.New("Error fetching library")
+		}
+		game := map[string]interface{}{
+			"id":           id,
+			"name":         name,
+			"price":        price,
+			"category":     category,
+			"image_url":    imageURL.String,
+			"description":  description.String,
+			"release_date": nil,
+			"purchased_at": purchasedAt.String(),
+			"delisted":     delisted,
+		}
+		if releaseDate.Valid && releaseDate.String != "" {
+			game["release_date"] = releaseDate.String
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("Error fetching library")
+	}
+	localizeGames(r, games)
+	return games, nil
+}
+
+// resolveGraphQLPurchases ประวัติการซื้อของผู้ใช้
+// รายการเกมในคำสั่งซื้อ (items) โหลดเมื่อถูกเลือกเท่านั้น และโหลดของทุกคำสั่งซื้อใน query เดียว
+func resolveGraphQLPurchases(r *http.Request, args map[string]interface{}) (interface{}, error) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		return nil, errGraphQLAuth
+	}
+	limit, offset, err := graphQLPage(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.total_amount, p.final_amount, p.status,
+		       p.purchase_date,
+		       dc.code as discount_code, p.tax_amount, p.tax_rate, p.tax_name
+		FROM purchases p
+		LEFT JOIN discount_codes dc ON p.discount_code_id = dc.id
+		WHERE p.user_id = ?
+		ORDER BY p.purchase_date DESC, p.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching purchases for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching purchase history")
+	}
+	defer rows.Close()
+
+	purchases := []map[string]interface{}{}
+	var ids []string
+	for rows.Next() {
+		var id int
+		var totalAmount, finalAmount, taxAmount, taxRate float64
+		var status string
+		var purchaseDate utils.Timestamp
+		var discountCode, taxName sql.NullString
+		if err := rows.Scan(&id, &totalAmount, &finalAmount, &status, &purchaseDate, &discountCode,
+			&taxAmount, &taxRate, &taxName); err != nil {
+			fmt.Printf("❌ Error scanning purchase for GraphQL: %v\n", err)
+			return nil, errors.New("Error fetching purchase history")
+		}
+		purchase := map[string]interface{}{
+			"id":             id,
+			"total_amount":   totalAmount,
+			"final_amount":   finalAmount,
+			"status":         status,
+			"purchase_date":  purchaseDate.String(),
+			"discount_saved": totalAmount - finalAmount,
+			"discount_code":  nil,
+			"tax_amount":     taxAmount,
+			"tax_rate":       taxRate,
+			"tax_name":       taxName.String,
+			"amount_paid":    finalAmount + taxAmount,
+		}
+		if discountCode.Valid {
+			purchase["discount_code"] = discountCode.String
+		}
+		purchases = append(purchases, purchase)
+		ids = append(ids, strconv.Itoa(id))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("Error fetching purchase history")
+	}
+
+	// โหลดรายการเกมของทุกคำสั่งซื้อครั้งเดียว เมื่อ field items ถูกเลือกครั้งแรก
+	var itemsByPurchase map[int][]map[string]interface{}
+	var loadErr error
+	loaded := false
+	loadItems := func() {
+		if loaded {
+			return
+		}
+		loaded = true
+		itemsByPurchase, loadErr = purchaseItemsFor(r, ids)
+	}
+	for _, purchase := range purchases {
+		purchaseID := purchase["id"].(int)
+		purchase["items"] = graphql.Lazy(func() (interface{}, error) {
+			loadItems()
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			items := itemsByPurchase[purchaseID]
+			if items == nil {
+				items = []map[string]interface{}{}
+			}
+			return items, nil
+		})
+	}
+	return purchases, nil
+}
+
+// purchaseItemsFor รายการเกมของคำสั่งซื้อหลายรายการ จัดกลุ่มตาม purchase_id
+func purchaseItemsFor(r *http.Request, purchaseIDs []string) (map[int][]map[string]interface{}, error) {
+	byPurchase := map[int][]map[string]interface{}{}
+	if len(purchaseIDs) == 0 {
+		return byPurchase, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT pi.purchase_id, g.id, g.name, g.image_url, pi.price_at_purchase, pi.tax_amount
+		FROM purchase_items pi
+		JOIN games g ON pi.game_id = g.id
+		WHERE pi.purchase_id IN (` + strings.Join(purchaseIDs, ",") + `)
+		ORDER BY pi.purchase_id, g.name
+	`)
+	if err != nil {
+		fmt.Printf("❌ Error fetching purchase items for GraphQL: %v\n", err)
+		return nil, errors.New("Error fetching purchase items")
+	}
+	defer rows.Close()
+
+	var all []map[string]interface{}
+	for rows.Next() {
+		var purchaseID, gameID int
+		var name string
+		var imageURL sql.NullString
+		var price, taxAmount float64
+		if err := rows.Scan(&purchaseID, &gameID, &name, &imageURL, &price, &taxAmount); err != nil {
+			fmt.Printf("❌ Error scanning purchase item for GraphQL: %v\n", err)
+			return nil, errors.New("Error fetching purchase items")
+		}
+		item := map[string]interface{}{
+			"id":                gameID,
+			"game_id":           gameID,
+			"name":              name,
+			"image_url":         imageURL.String,
+			"price_at_purchase": price,
+			"tax_amount":        taxAmount,
+		}
+		byPurchase[purchaseID] = append(byPurchase[purchaseID], item)
+		all = append(all, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("Error fetching purchase items")
+	}
+	localizeGames(r, all)
+	return byPurchase, nil
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"go-api-game/grpc"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// gRPC service สำหรับระบบภายใน (game server, launcher backend) ตรวจสอบข้อมูลเกมและสิทธิ์ของผู้ใช้
+// โดยไม่ต้องผ่าน REST/JSON (นิยาม message อยู่ที่ grpc/game_service.proto)
+
+// grpcServicePrefix ชื่อ service ตามไฟล์ .proto
+const grpcServicePrefix = "/gamestore.v1.GameService/"
+
+// StartGRPCServer เปิด gRPC service ที่ GRPC_ADDR (ค่าเริ่มต้น :9090)
+// ต้องตั้ง GRPC_API_TOKEN (client ส่งเป็น metadata "authorization: Bearer <token>") มิฉะนั้นจะไม่เปิด service
+func StartGRPCServer() {
+	token := os.Getenv("GRPC_API_TOKEN")
+	if token == "" {
+		fmt.Println("⚠️  GRPC_API_TOKEN is not set, gRPC service is disabled")
+		return
+	}
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+
+	server := grpc.NewServer()
+	server.Authorize = func(r *http.Request) error {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return grpc.Errorf(grpc.Unauthenticated, "invalid or missing token")
+		}
+		return nil
+	}
+	server.Handle(grpcServicePrefix+"GetGame", grpcGetGame)
+	server.Handle(grpcServicePrefix+"CheckOwnership", grpcCheckOwnership)
+	server.Handle(grpcServicePrefix+"GrantLicense", grpcGrantLicense)
+
+	go func() {
+		if err := server.ListenAndServe(addr); err != nil {
+			fmt.Printf("❌ gRPC server stopped: %v\n", err)
+		}
+	}()
+	fmt.Printf("✅ gRPC service listening on %s (h2c)\n", addr)
+}
+
+// validateUserGame ตรวจสอบ user_id และ game_id ของ CheckOwnershipRequest/GrantLicenseRequest
+func validateUserGame(userID, gameID int64) error {
+	if userID <= 0 {
+		return grpc.Errorf(grpc.InvalidArgument, "user_id is required")
+	}
+	if gameID <= 0 {
+		return grpc.Errorf(grpc.InvalidArgument, "game_id is required")
+	}
+	return nil
+}
+
+// grpcGetGame GetGame(GetGameRequest) returns (Game)
+func grpcGetGame(r *http.Request, req []byte) ([]byte, error) {
+	var in grpc.GetGameRequest
+	if err := in.Unmarshal(req); err != nil {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	gameID := in.GameID
+	if gameID <= 0 {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "game_id is required")
+	}
+
+	var name, status string
+	var price float64
+	var minAge int
+	var category, imageURL, description, releaseDate, ageRating sql.NullString
+	err := db.QueryRow(`
+		SELECT g.name, g.price, c.name, g.image_url, g.description,
+		       `+dialect.DateFormat("g.release_date", "%Y-%m-%d")+`, g.status, g.min_age, g.age_rating
+		FROM games g
+		LEFT JOIN categories c ON g.category_id = c.id
+		WHERE g.id = ?
+	`, gameID).Scan(&name, &price, &category, &imageURL, &description, &releaseDate, &status, &minAge, &ageRating)
+	if err == sql.ErrNoRows {
+		return nil, grpc.Errorf(grpc.NotFound, "game %d not found", gameID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	game := []map[string]interface{}{{"id": int(gameID), "name": name, "description": description.String}}
+	if lang := utils.NormalizeLanguage(in.Locale); lang != "" {
+		localizeGamesTo(lang, game)
+	}
+
+	out := grpc.Game{
+		ID:          gameID,
+		Name:        game[0]["name"].(string),
+		Price:       price,
+		Category:    category.String,
+		ImageURL:    imageURL.String,
+		Description: game[0]["description"].(string),
+		ReleaseDate: releaseDate.String,
+		Status:      status,
+		MinAge:      int32(minAge),
+		AgeRating:   ageRating.String,
+	}
+	return out.Marshal(), nil
+}
+
+// grpcCheckOwnership CheckOwnership(CheckOwnershipRequest) returns (CheckOwnershipResponse)
+func grpcCheckOwnership(r *http.Request, req []byte) ([]byte, error) {
+	var in grpc.CheckOwnershipRequest
+	if err := in.Unmarshal(req); err != nil {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	userID, gameID := in.UserID, in.GameID
+	if err := validateUserGame(userID, gameID); err != nil {
+		return nil, err
+	}
+
+	var purchasedAt utils.Timestamp
+	var delisted bool
+	err := db.QueryRow(`
+		SELECT MIN(pg.purchased_at), MAX(g.status = 'delisted')
+		FROM purchased_games pg
+		JOIN games g ON pg.game_id = g.id
+		WHERE pg.user_id = ? AND pg.game_id = ?
+		GROUP BY pg.game_id
+	`, userID, gameID).Scan(&purchasedAt, &delisted)
+	if err == sql.ErrNoRows {
+		return nil, nil // owned = false (message ว่าง)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := grpc.CheckOwnershipResponse{Owned: true, PurchasedAt: purchasedAt.String(), Delisted: delisted}
+	return out.Marshal(), nil
+}
+
+// grpcGrantLicense GrantLicense(GrantLicenseRequest) returns (GrantLicenseResponse)
+// เพิ่มเกมเข้าคลังโดยไม่สร้างคำสั่งซื้อ และบันทึกใน audit log (actor = "grpc")
+func grpcGrantLicense(r *http.Request, req []byte) ([]byte, error) {
+	var in grpc.GrantLicenseRequest
+	if err := in.Unmarshal(req); err != nil {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	userID, gameID, reason := in.UserID, in.GameID, strings.TrimSpace(in.Reason)
+	if err := validateUserGame(userID, gameID); err != nil {
+		return nil, err
+	}
+	if len(reason) > 255 {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "reason must be at most 255 characters")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// ล็อกแถวของผู้ใช้ ป้องกันการเพิ่มเกมซ้ำเมื่อมี call พร้อมกัน
+	var lockedID int64
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&lockedID); err == sql.ErrNoRows {
+		return nil, grpc.Errorf(grpc.NotFound, "user %d not found", userID)
+	} else if err != nil {
+		return nil, err
+	}
+	var gameExists bool
+	tx.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE id = ?)", gameID).Scan(&gameExists)
+	if !gameExists {
+		return nil, grpc.Errorf(grpc.NotFound, "game %d not found", gameID)
+	}
+
+	var purchasedAt utils.Timestamp
+	err = tx.QueryRow(`
+		SELECT MIN(purchased_at) FROM purchased_games
+		WHERE user_id = ? AND game_id = ? GROUP BY game_id
+	`, userID, gameID).Scan(&purchasedAt)
+	if err == nil {
+		out := grpc.GrantLicenseResponse{AlreadyOwned: true, PurchasedAt: purchasedAt.String()}
+		return out.Marshal(), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("INSERT INTO purchased_games (user_id, game_id) VALUES (?, ?)", userID, gameID); err != nil {
+		return nil, err
+	}
+	tx.QueryRow(`
+		SELECT MIN(purchased_at) FROM purchased_games WHERE user_id = ? AND game_id = ?
+	`, userID, gameID).Scan(&purchasedAt)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	recordAudit(withIdentity(r, Identity{Username: "grpc"}), "license_grant", "user", userID, nil,
+		map[string]interface{}{"game_id": gameID, "reason": reason})
+	fmt.Printf("🎟️ License granted via gRPC: user %d, game %d\n", userID, gameID)
+
+	out := grpc.GrantLicenseResponse{Granted: true, PurchasedAt: purchasedAt.String()}
+	return out.Marshal(), nil
+}
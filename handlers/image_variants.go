@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-api-game/storage"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	_ "image/gif" // ลงทะเบียนตัวถอดรหัส GIF สำหรับ image.Decode
+)
+
+// imageVariantSizes ขนาดภาพย่อที่สร้างเมื่ออัพโหลด (ความกว้างสูงสุด, ไม่ขยายภาพที่เล็กกว่า)
+var imageVariantSizes = []struct {
+	Name  string
+	Width int
+}{
+	{"thumbnail", 200},
+	{"medium", 600},
+	{"large", 1200},
+}
+
+// createImageVariants สร้างภาพย่อทุกขนาดของภาพที่อัพโหลดและบันทึก URL ลงตาราง image_variants
+// Cloudinary ใช้ transformation URL ส่วนที่เก็บอื่นจะย่อภาพในเครื่องแล้วอัพโหลดแยกไฟล์
+// ข้อผิดพลาดจะไม่ทำให้การอัพโหลดล้มเหลว (catalog จะใช้ภาพต้นฉบับแทน)
+func createImageVariants(ctx context.Context, originalURL string, data []byte, filename string) {
+	if originalURL == "" {
+		return
+	}
+
+	variants := map[string]string{}
+
+	if backend := storage.ForURL(originalURL); backend.Name() == "cloudinary" {
+		for _, size := range imageVariantSizes {
+			variants[size.Name] = cloudinaryTransformURL(originalURL, fmt.Sprintf("c_limit,w_%d,q_auto,f_auto", size.Width))
+		}
+	} else {
+		// WebP/AVIF ถอดรหัสด้วย standard library ไม่ได้ จึงข้ามไป
+		src, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			fmt.Printf("⚠️ Skipping image variants for %s: %v\n", filename, err)
+			return
+		}
+
+		base := strings.TrimSuffix(filename, filepath.Ext(filename))
+		for _, size := range imageVariantSizes {
+			resized := resizeImage(src, size.Width)
+
+			var buf bytes.Buffer
+			ext := ".jpg"
+			if format == "png" || format == "gif" {
+				// เก็บความโปร่งใสไว้
+				ext = ".png"
+				err = png.Encode(&buf, resized)
+			} else {
+				err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+			}
+			if err != nil {
+				fmt.Printf("⚠️ Error encoding %s variant: %v\n", size.Name, err)
+				continue
+			}
+
+			url, err := storage.SaveContext(ctx, fmt.Sprintf("%s_%s%s", base, size.Name, ext), buf.Bytes())
+			if err != nil {
+				fmt.Printf("⚠️ Error saving %s variant: %v\n", size.Name, err)
+				continue
+			}
+			variants[size.Name] = url
+		}
+	}
+
+	for name, url := range variants {
+		_, err := db.Exec(`
+			INSERT INTO image_variants (original_url, variant, url) VALUES (?, ?, ?)
+			`+dialect.OnConflict("original_url", "variant")+` `+upsertColumns("url")+`
+		`, originalURL, name, url)
+		if err != nil {
+			fmt.Printf("⚠️ Error recording image variant: %v\n", err)
+		}
+	}
+
+	fmt.Printf("✅ Created %d image variants for %s\n", len(variants), originalURL)
+}
+
+// deleteImageVariants ลบไฟล์ภาพย่อทั้งหมดของภาพต้นฉบับ (Cloudinary ไม่มีไฟล์แยกจึงลบแค่ข้อมูล)
+func deleteImageVariants(originalURL string) {
+	rows, err := db.Query("SELECT url FROM image_variants WHERE original_url = ?", originalURL)
+	if err != nil {
+		return
+	}
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			// เก็บแถวไว้ให้ลบใหม่ภายหลัง ไม่เช่นนั้นไฟล์ที่อ่าน URL ไม่ได้จะค้างอยู่ใน storage
+			rows.Close()
+			fmt.Printf("⚠️ Error scanning image variant of %s: %v\n", originalURL, err)
+			return
+		}
+		urls = append(urls, url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("⚠️ Error loading image variants of %s: %v\n", originalURL, err)
+		return
+	}
+
+	if storage.ForURL(originalURL).Name() != "cloudinary" {
+		for _, url := range urls {
+			if err := storage.Remove(url); err != nil {
+				fmt.Printf("⚠️ Error deleting image variant: %v\n", err)
+			}
+		}
+	}
+
+	db.Exec("DELETE FROM image_variants WHERE original_url = ?", originalURL)
+}
+
+// imageVariantsFor ดึง URL ภาพย่อทุกขนาดของภาพ (ขนาดที่ไม่มีจะใช้ภาพต้นฉบับแทน)
+func imageVariantsFor(originalURL string) map[string]string {
+	variants := map[string]string{"original": originalURL}
+	for _, size := range imageVariantSizes {
+		variants[size.Name] = originalURL
+	}
+	if originalURL == "" {
+		return variants
+	}
+
+	rows, err := db.Query("SELECT variant, url FROM image_variants WHERE original_url = ?", originalURL)
+	if err != nil {
+		return variants
+	}
+	defer rows.Close()
+
+	// อ่านไม่สำเร็จก็ยังแสดงภาพได้ (ขนาดที่อ่านไม่ได้ใช้ภาพต้นฉบับแทน)
+	for rows.Next() {
+		var name, url string
+		if err := rows.Scan(&name, &url); err != nil {
+			fmt.Printf("⚠️ Error scanning image variant of %s: %v\n", originalURL, err)
+			continue
+		}
+		variants[name] = url
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("⚠️ Error loading image variants of %s: %v\n", originalURL, err)
+	}
+	return variants
+}
+
+// cloudinaryTransformURL แทรก transformation หลัง /upload/ ของ Cloudinary URL
+func cloudinaryTransformURL(url, transformation string) string {
+	parts := strings.SplitN(url, "/upload/", 2)
+	if len(parts) != 2 {
+		return url
+	}
+	return parts[0] + "/upload/" + transformation + "/" + parts[1]
+}
+
+// resizeImage ย่อภาพให้กว้างไม่เกิน maxWidth โดยคงอัตราส่วน (เฉลี่ยสีของพิกเซลในแต่ละช่อง)
+func resizeImage(src image.Image, maxWidth int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxWidth || srcW == 0 {
+		return src
+	}
+
+	dstW := maxWidth
+	dstH := srcH * maxWidth / srcW
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := bounds.Min.Y + (y+1)*srcH/dstH
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := bounds.Min.X + (x+1)*srcW/dstW
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,590 @@
+//go:build integration
+
+// integration test ของ HTTP API กับฐานข้อมูล MySQL จริง (ไม่รันใน go test ปกติ รันใน job integration ของ CI)
+// ต้องตั้ง TEST_MYSQL_DSN เป็นผู้ใช้ที่สร้างและลบฐานข้อมูลได้ แต่ละ test สร้างฐานข้อมูลชั่วคราวของตัวเอง
+// จาก testdata/base_schema.sql แล้วให้ InitDB (ensureSchema) เพิ่มตารางและคอลัมน์ที่เหลือ
+//
+//	docker compose -f docker-compose.test.yml up -d --wait
+//	TEST_MYSQL_DSN="root:test@tcp(localhost:3307)/" go test -tags integration -count=1 ./handlers/
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const integrationPassword = "Str0ng!Passw0rd"
+
+// integrationAPI เซิร์ฟเวอร์ทดสอบที่ต่อกับฐานข้อมูลชั่วคราว
+type integrationAPI struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+// newIntegrationAPI สร้างฐานข้อมูลชั่วคราวและเปิดเซิร์ฟเวอร์ด้วย route และ middleware เดียวกับ main.go
+func newIntegrationAPI(t *testing.T) *integrationAPI {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		// ใน CI ต้องรันจริง ไม่เช่นนั้น job จะผ่านโดยไม่ได้ทดสอบอะไรเลย
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_MYSQL_DSN is not set")
+		}
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("invalid TEST_MYSQL_DSN: %v", err)
+	}
+
+	server, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	name := fmt.Sprintf("gamestore_it_%d", time.Now().UnixNano())
+	if _, err := server.Exec("CREATE DATABASE " + name + " CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() { server.Exec("DROP DATABASE " + name) })
+
+	cfg.DBName = name
+	database, err := sql.Open(DatabaseDriver(), DatabaseDSN(cfg.FormatDSN()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	schema, err := os.ReadFile("testdata/base_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range strings.Split(string(schema), ";") {
+		if strings.Contains(stmt, "CREATE TABLE") {
+			if _, err := database.Exec(stmt); err != nil {
+				t.Fatalf("base schema: %v\n%s", err, stmt)
+			}
+		}
+	}
+
+	previous := db
+	InitDB(database)
+	t.Cleanup(func() { db = previous })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/register", RegisterHandler)
+	mux.HandleFunc("/login", LoginHandler)
+	mux.Handle("/profile", AuthMiddleware(http.HandlerFunc(ProfileHandler)))
+	mux.Handle("/profile/update", AuthMiddleware(http.HandlerFunc(UpdateProfileHandler)))
+	mux.Handle("/cart", AuthMiddleware(http.HandlerFunc(CartHandler)))
+	mux.Handle("/cart/add", AuthMiddleware(http.HandlerFunc(AddToCartHandler)))
+	mux.Handle("/cart/remove", AuthMiddleware(http.HandlerFunc(RemoveFromCartHandler)))
+	mux.Handle("/discounts/apply", AuthMiddleware(http.HandlerFunc(ApplyDiscountHandler)))
+	mux.Handle("/checkout", AuthMiddleware(http.HandlerFunc(CheckoutHandler)))
+	mux.Handle("/admin/games", AuthMiddleware(AdminOnly(http.HandlerFunc(AdminAddGameHandler))))
+	mux.Handle("/admin/games/", AuthMiddleware(AdminOnly(http.HandlerFunc(AdminUpdateGameHandler))))
+	mux.Handle("/admin/games/delete/", AuthMiddleware(AdminOnly(http.HandlerFunc(AdminDeleteGameHandler))))
+
+	api := &integrationAPI{t: t, server: httptest.NewServer(mux)}
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+// do ส่ง request (body เป็น JSON) และคืน status กับ body ที่แปลงแล้ว
+func (api *integrationAPI) do(method, path, token string, body interface{}) (int, map[string]interface{}) {
+	api.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			api.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, api.server.URL+path, &payload)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := api.server.Client().Do(req)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	result := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		api.t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	return resp.StatusCode, result
+}
+
+// expect ตรวจสอบ status ของ response
+func (api *integrationAPI) expect(method, path, token string, body interface{}, status int) map[string]interface{} {
+	api.t.Helper()
+	got, result := api.do(method, path, token, body)
+	if got != status {
+		api.t.Fatalf("%s %s: status = %d, want %d (body %v)", method, path, got, status, result)
+	}
+	return result
+}
+
+// signUp ลงทะเบียนและเข้าสู่ระบบ คืน user ID และ token
+func (api *integrationAPI) signUp(username string) (int, string) {
+	api.t.Helper()
+	created := api.expect("POST", "/register", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": integrationPassword,
+	}, http.StatusCreated)
+	return int(created["user_id"].(float64)), api.login(username)
+}
+
+func (api *integrationAPI) login(identifier string) string {
+	api.t.Helper()
+	result := api.expect("POST", "/login", "", map[string]string{
+		"identifier": identifier,
+		"password":   integrationPassword,
+	}, http.StatusOK)
+	return result["token"].(string)
+}
+
+// exec รันคำสั่ง SQL สำหรับเตรียมข้อมูล
+func (api *integrationAPI) exec(query string, args ...interface{}) int64 {
+	api.t.Helper()
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		api.t.Fatalf("%s: %v", query, err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// scalar อ่านค่าเดียวจากฐานข้อมูล
+func (api *integrationAPI) scalar(dest interface{}, query string, args ...interface{}) {
+	api.t.Helper()
+	if err := db.QueryRow(query, args...).Scan(dest); err != nil {
+		api.t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// seedGame เพิ่มเกมที่วางขายแล้วพร้อมแถวใน ranking
+func (api *integrationAPI) seedGame(name string, price float64) int64 {
+	api.t.Helper()
+	categoryID := api.exec("INSERT INTO categories (name) VALUES (?)", name+" category")
+	gameID := api.exec("INSERT INTO games (name, price, category_id, release_date) VALUES (?, ?, ?, CURDATE())", name, price, categoryID)
+	api.exec("INSERT INTO ranking (game_id, sales_count) VALUES (?, 0)", gameID)
+	return gameID
+}
+
+func errorCode(result map[string]interface{}) string {
+	code, _ := result["code"].(string)
+	return code
+}
+
+func assertAmount(t *testing.T, name string, got interface{}, want float64) {
+	t.Helper()
+	value, ok := got.(float64)
+	if !ok || math.Abs(value-want) > 0.001 {
+		t.Errorf("%s = %v, want %.2f", name, got, want)
+	}
+}
+
+func TestIntegrationRegisterAndLogin(t *testing.T) {
+	api := newIntegrationAPI(t)
+
+	created := api.expect("POST", "/register", "", map[string]string{
+		"username": "player_one",
+		"email":    "Player.One@Example.com",
+		"password": integrationPassword,
+	}, http.StatusCreated)
+	userID := int(created["user_id"].(float64))
+
+	var carts int
+	api.scalar(&carts, "SELECT COUNT(*) FROM carts WHERE user_id = ?", userID)
+	if carts != 1 {
+		t.Errorf("carts for new user = %d, want 1", carts)
+	}
+
+	// ชื่อผู้ใช้ซ้ำ (ไม่สนตัวพิมพ์) ถูกปฏิเสธโดย unique index ที่ ensureSchema เพิ่ม
+	_, duplicate := api.do("POST", "/register", "", map[string]string{
+		"username": "Player_One",
+		"email":    "someone.else@example.com",
+		"password": integrationPassword,
+	})
+	if errorCode(duplicate) != string(utils.ErrUsernameTaken) {
+		t.Errorf("duplicate username code = %q, want %q", errorCode(duplicate), utils.ErrUsernameTaken)
+	}
+
+	wrong := api.expect("POST", "/login", "", map[string]string{
+		"identifier": "player_one",
+		"password":   "Wr0ng!Password",
+	}, http.StatusUnauthorized)
+	if errorCode(wrong) != string(utils.ErrInvalidCredentials) {
+		t.Errorf("wrong password code = %q, want %q", errorCode(wrong), utils.ErrInvalidCredentials)
+	}
+
+	// เข้าสู่ระบบด้วยอีเมลได้ และ token ใช้กับ route ที่ต้องยืนยันตัวตนได้
+	token := api.login(created["email"].(string))
+	profile := api.expect("GET", "/profile", token, nil, http.StatusOK)
+	if profile["username"] != "player_one" {
+		t.Errorf("profile username = %v, want player_one", profile["username"])
+	}
+
+	api.expect("GET", "/profile", "", nil, http.StatusUnauthorized)
+}
+
+// ชื่อที่ต่างกันแค่ case folding ของ Unicode ซ้ำกันทั้งตอนสมัครและตอนเปลี่ยนชื่อ (unique index ของ username_key)
+func TestIntegrationUsernameKeyIsUnique(t *testing.T) {
+	api := newIntegrationAPI(t)
+
+	created := api.expect("POST", "/register", "", map[string]string{
+		"username": "Straße",
+		"email":    "strasse@example.com",
+		"password": integrationPassword,
+	}, http.StatusCreated)
+	var key string
+	api.scalar(&key, "SELECT username_key FROM users WHERE id = ?", int(created["user_id"].(float64)))
+	if key != "strasse" {
+		t.Errorf("username_key = %q, want strasse", key)
+	}
+
+	_, duplicate := api.do("POST", "/register", "", map[string]string{
+		"username": "STRASSE",
+		"email":    "shouting@example.com",
+		"password": integrationPassword,
+	})
+	if errorCode(duplicate) != string(utils.ErrUsernameTaken) {
+		t.Errorf("register STRASSE code = %q, want %q", errorCode(duplicate), utils.ErrUsernameTaken)
+	}
+
+	_, token := api.signUp("someone_else")
+	renamed := api.expect("PUT", "/profile/update", token, map[string]string{"username": "STRASSE"}, http.StatusConflict)
+	if errorCode(renamed) != string(utils.ErrUsernameTaken) {
+		t.Errorf("rename to STRASSE code = %q, want %q", errorCode(renamed), utils.ErrUsernameTaken)
+	}
+	api.expect("PUT", "/profile/update", token, map[string]string{"username": "Someone_Else2"}, http.StatusOK)
+}
+
+func TestIntegrationCheckoutWithDiscountAndTax(t *testing.T) {
+	api := newIntegrationAPI(t)
+	first := api.seedGame("Iron Rift", 40)
+	second := api.seedGame("Neon Echoes", 20)
+	api.exec("INSERT INTO discount_codes (code, type, value, usage_limit, single_use_per_user, active) VALUES ('SAVE10', 'percent', 10, 1, 1, 1)")
+	api.exec("INSERT INTO tax_rules (region, name, rate) VALUES ('TH', 'VAT', 7)")
+
+	userID, token := api.signUp("buyer")
+	api.exec("UPDATE users SET wallet_balance = 100 WHERE id = ?", userID)
+	api.expect("POST", "/cart/add", token, map[string]int64{"game_id": first}, http.StatusOK)
+	api.expect("POST", "/cart/add", token, map[string]int64{"game_id": second}, http.StatusOK)
+
+	// ยอดรวม 60 ส่วนลด 10% = 54 ภาษี 7% คิดรายสินค้า (36 → 2.52, 18 → 1.26) รวม 3.78
+	result := api.expect("POST", "/checkout", token, map[string]string{
+		"discount_code": "SAVE10",
+		"region":        "th",
+	}, http.StatusOK)
+	assertAmount(t, "total", result["total"], 60)
+	assertAmount(t, "discount", result["discount"], 6)
+	assertAmount(t, "final_amount", result["final_amount"], 54)
+	assertAmount(t, "tax.amount", result["tax"].(map[string]interface{})["amount"], 3.78)
+	assertAmount(t, "amount_paid", result["amount_paid"], 57.78)
+
+	var balance, charged float64
+	api.scalar(&balance, "SELECT wallet_balance FROM users WHERE id = ?", userID)
+	if math.Abs(balance-42.22) > 0.001 {
+		t.Errorf("wallet_balance = %.2f, want 42.22", balance)
+	}
+	api.scalar(&charged, "SELECT amount FROM user_transactions WHERE user_id = ? AND type = ?", userID, TransactionPurchase)
+	if math.Abs(charged-57.78) > 0.001 {
+		t.Errorf("purchase transaction = %.2f, want 57.78", charged)
+	}
+
+	var owned, cartItems, sales, active int
+	var status string
+	api.scalar(&owned, "SELECT COUNT(*) FROM purchased_games WHERE user_id = ?", userID)
+	api.scalar(&cartItems, "SELECT COUNT(*) FROM cart_items ci JOIN carts c ON ci.cart_id = c.id WHERE c.user_id = ?", userID)
+	api.scalar(&sales, "SELECT sales_count FROM ranking WHERE game_id = ?", first)
+	api.scalar(&status, "SELECT status FROM purchases WHERE id = ?", int64(result["purchase_id"].(float64)))
+	api.scalar(&active, "SELECT active FROM discount_codes WHERE code = 'SAVE10'")
+	if owned != 2 || cartItems != 0 || sales != 1 || status != PurchaseFulfilled {
+		t.Errorf("after checkout: owned=%d cart_items=%d sales=%d status=%s", owned, cartItems, sales, status)
+	}
+	// รหัสที่ใช้ครบ usage_limit ถูกปิดอัตโนมัติ
+	if active != 0 {
+		t.Errorf("discount code active = %d, want 0 after reaching usage limit", active)
+	}
+
+	empty := api.expect("POST", "/checkout", token, map[string]string{}, http.StatusBadRequest)
+	if errorCode(empty) != string(utils.ErrCartEmpty) {
+		t.Errorf("empty cart code = %q, want %q", errorCode(empty), utils.ErrCartEmpty)
+	}
+}
+
+func TestIntegrationCheckoutInsufficientBalance(t *testing.T) {
+	t.Setenv("TAX_RATE", "")
+	api := newIntegrationAPI(t)
+	gameID := api.seedGame("Silent Forge", 30)
+
+	userID, token := api.signUp("short_on_cash")
+	api.exec("UPDATE users SET wallet_balance = 10 WHERE id = ?", userID)
+	api.expect("POST", "/cart/add", token, map[string]int64{"game_id": gameID}, http.StatusOK)
+
+	result := api.expect("POST", "/checkout", token, map[string]string{}, http.StatusBadRequest)
+	if errorCode(result) != string(utils.ErrInsufficientBalance) {
+		t.Errorf("code = %q, want %q", errorCode(result), utils.ErrInsufficientBalance)
+	}
+
+	// ไม่มีการตัดเงินหรือสร้างคำสั่งซื้อ และเกมยังอยู่ในตะกร้า
+	var balance float64
+	var purchases, cartItems int
+	api.scalar(&balance, "SELECT wallet_balance FROM users WHERE id = ?", userID)
+	api.scalar(&purchases, "SELECT COUNT(*) FROM purchases WHERE user_id = ?", userID)
+	api.scalar(&cartItems, "SELECT COUNT(*) FROM cart_items ci JOIN carts c ON ci.cart_id = c.id WHERE c.user_id = ?", userID)
+	if balance != 10 || purchases != 0 || cartItems != 1 {
+		t.Errorf("after failed checkout: balance=%.2f purchases=%d cart_items=%d", balance, purchases, cartItems)
+	}
+}
+
+// รหัสส่วนลดผ่านการตรวจเงื่อนไขชุดเดียวกันทั้งตอนตรวจรหัส (/discounts/apply) และตอน checkout
+func TestIntegrationDiscountRules(t *testing.T) {
+	t.Setenv("TAX_RATE", "")
+	api := newIntegrationAPI(t)
+	gameID := api.seedGame("Frost Hollow", 50)
+	now := time.Now()
+	today, yesterday, tomorrow := now.Format("2006-01-02"), now.AddDate(0, 0, -1).Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02")
+
+	aliceID, alice := api.signUp("alice")
+	bobID, bob := api.signUp("bob")
+
+	vip := api.exec("INSERT INTO discount_codes (code, type, value) VALUES ('VIP20', 'percent', 20)")
+	api.exec("INSERT INTO discount_code_targets (discount_code_id, user_id) VALUES (?, ?)", vip, aliceID)
+	api.exec("INSERT INTO discount_codes (code, type, value, end_date) VALUES ('LASTDAY', 'fixed', 5, ?)", today)
+	api.exec("INSERT INTO discount_codes (code, type, value, end_date) VALUES ('ENDED', 'fixed', 5, ?)", yesterday)
+	api.exec("INSERT INTO discount_codes (code, type, value, start_date) VALUES ('SOON', 'fixed', 5, ?)", tomorrow)
+	api.exec("INSERT INTO discount_codes (code, type, value, min_total) VALUES ('BIGSPEND', 'fixed', 10, 100)")
+	limited := api.exec("INSERT INTO discount_codes (code, type, value, usage_limit) VALUES ('FIRST1', 'fixed', 5, 1)")
+	api.exec("INSERT INTO user_discount_codes (user_id, discount_code_id) VALUES (?, ?)", aliceID, limited)
+	once := api.exec("INSERT INTO discount_codes (code, type, value, single_use_per_user) VALUES ('ONCE', 'fixed', 5, 1)")
+	api.exec("INSERT INTO user_discount_codes (user_id, discount_code_id) VALUES (?, ?)", aliceID, once)
+
+	apply := func(token, code string) (int, map[string]interface{}) {
+		return api.do("POST", "/discounts/apply", token, map[string]interface{}{"code": code, "total_amount": 50})
+	}
+
+	// รหัสเฉพาะบุคคลใช้ได้เฉพาะผู้ใช้เป้าหมาย ผู้ใช้อื่นได้คำตอบเดียวกับรหัสที่ไม่มีอยู่
+	if status, result := apply(alice, "VIP20"); status != http.StatusOK {
+		t.Errorf("VIP20 for target: status = %d (%v)", status, result)
+	} else {
+		assertAmount(t, "VIP20 final_amount", result["final_amount"], 40)
+	}
+	unknownStatus, unknown := apply(bob, "NOSUCH")
+	targetedStatus, targeted := apply(bob, "VIP20")
+	if targetedStatus != http.StatusBadRequest || targetedStatus != unknownStatus || !reflect.DeepEqual(targeted, unknown) {
+		t.Errorf("VIP20 for non-target = %d %v, unknown code = %d %v", targetedStatus, targeted, unknownStatus, unknown)
+	}
+
+	// end_date ใช้ได้ถึงสิ้นวัน
+	if status, result := apply(bob, "LASTDAY"); status != http.StatusOK {
+		t.Errorf("LASTDAY: status = %d (%v)", status, result)
+	}
+
+	rejected := []struct {
+		token  string
+		code   string
+		status int
+		want   utils.ErrorCode
+	}{
+		{bob, "ENDED", http.StatusBadRequest, utils.ErrCodeExpired},
+		{bob, "SOON", http.StatusBadRequest, utils.ErrCodeNotYetValid},
+		{bob, "BIGSPEND", http.StatusBadRequest, utils.ErrMinimumNotMet},
+		{bob, "FIRST1", http.StatusBadRequest, utils.ErrCodeUsageLimit},
+		{alice, "ONCE", http.StatusConflict, utils.ErrCodeAlreadyUsed},
+	}
+	for _, tc := range rejected {
+		status, result := apply(tc.token, tc.code)
+		if status != tc.status || errorCode(result) != string(tc.want) {
+			t.Errorf("%s: %d %q, want %d %q", tc.code, status, errorCode(result), tc.status, tc.want)
+		}
+	}
+	// รหัสที่ใช้ครั้งเดียวต่อคน ผู้ใช้อื่นยังใช้ได้
+	if status, result := apply(bob, "ONCE"); status != http.StatusOK {
+		t.Errorf("ONCE for another user: status = %d (%v)", status, result)
+	}
+	// รหัสที่ใช้ครบ usage_limit ถูกปิด
+	var active int
+	api.scalar(&active, "SELECT active FROM discount_codes WHERE id = ?", limited)
+	if active != 0 {
+		t.Errorf("FIRST1 active = %d, want 0", active)
+	}
+
+	// checkout ใช้กฎเดียวกัน: รหัสของผู้อื่นถูกปฏิเสธโดยไม่ตัดเงิน รหัสที่หมดวันนี้ยังใช้ได้
+	api.exec("UPDATE users SET wallet_balance = 100 WHERE id = ?", bobID)
+	api.expect("POST", "/cart/add", bob, map[string]int64{"game_id": gameID}, http.StatusOK)
+	denied := api.expect("POST", "/checkout", bob, map[string]string{"discount_code": "VIP20"}, http.StatusBadRequest)
+	if errorCode(denied) != string(utils.ErrCodeNotFound) {
+		t.Errorf("checkout with VIP20 code = %q, want %q", errorCode(denied), utils.ErrCodeNotFound)
+	}
+	var balance float64
+	api.scalar(&balance, "SELECT wallet_balance FROM users WHERE id = ?", bobID)
+	if balance != 100 {
+		t.Errorf("wallet_balance after rejected code = %.2f, want 100", balance)
+	}
+
+	result := api.expect("POST", "/checkout", bob, map[string]string{"discount_code": "LASTDAY"}, http.StatusOK)
+	assertAmount(t, "final_amount", result["final_amount"], 45)
+	var uses int
+	api.scalar(&uses, "SELECT COUNT(*) FROM user_discount_codes udc JOIN discount_codes dc ON dc.id = udc.discount_code_id WHERE dc.code = 'LASTDAY' AND udc.user_id = ?", bobID)
+	if uses != 1 {
+		t.Errorf("LASTDAY uses = %d, want 1", uses)
+	}
+}
+
+func TestIntegrationCartAddRemove(t *testing.T) {
+	api := newIntegrationAPI(t)
+	first := api.seedGame("Ashen Tide", 40)
+	second := api.seedGame("Glass Harbor", 20)
+	delisted := api.seedGame("Old Relic", 10)
+	api.exec("UPDATE games SET status = ? WHERE id = ?", GameStatusDelisted, delisted)
+	soldOut := api.seedGame("Limited Run", 15)
+	api.exec("UPDATE games SET stock_count = 0 WHERE id = ?", soldOut)
+	owned := api.seedGame("Already Mine", 5)
+
+	userID, token := api.signUp("shopper")
+	api.exec("INSERT INTO purchased_games (user_id, game_id) VALUES (?, ?)", userID, owned)
+
+	cartOf := func(token string) (int, float64, []interface{}) {
+		cart := api.expect("GET", "/cart", token, nil, http.StatusOK)
+		items, _ := cart["items"].([]interface{})
+		return int(cart["item_count"].(float64)), cart["total"].(float64), items
+	}
+
+	api.expect("POST", "/cart/add", token, map[string]int64{"game_id": first}, http.StatusOK)
+	api.expect("POST", "/cart/add", token, map[string]int64{"game_id": second}, http.StatusOK)
+	if count, total, _ := cartOf(token); count != 2 || math.Abs(total-60) > 0.001 {
+		t.Errorf("cart after adding = %d items, total %.2f", count, total)
+	}
+
+	rejected := []struct {
+		name   string
+		gameID int64
+		status int
+		want   utils.ErrorCode
+	}{
+		{"missing", 999999, http.StatusNotFound, utils.ErrGameNotFound},
+		{"delisted", delisted, http.StatusBadRequest, utils.ErrGameUnavailable},
+		{"sold out", soldOut, http.StatusConflict, utils.ErrOutOfStock},
+		{"owned", owned, http.StatusConflict, utils.ErrAlreadyOwned},
+	}
+	for _, tc := range rejected {
+		status, result := api.do("POST", "/cart/add", token, map[string]int64{"game_id": tc.gameID})
+		if status != tc.status || errorCode(result) != string(tc.want) {
+			t.Errorf("add %s game: %d %q, want %d %q", tc.name, status, errorCode(result), tc.status, tc.want)
+		}
+	}
+	if count, _, _ := cartOf(token); count != 2 {
+		t.Errorf("rejected games were added to the cart: %d items", count)
+	}
+
+	api.expect("POST", "/cart/remove", token, map[string]int64{"game_id": first}, http.StatusOK)
+	count, total, items := cartOf(token)
+	if count != 1 || math.Abs(total-20) > 0.001 || items[0].(map[string]interface{})["game_id"] != float64(second) {
+		t.Errorf("cart after removing = %d items, total %.2f: %v", count, total, items)
+	}
+	// ลบซ้ำหรือลบเกมที่ไม่อยู่ในตะกร้าไม่ถือเป็น error
+	api.expect("POST", "/cart/remove", token, map[string]int64{"game_id": first}, http.StatusOK)
+
+	// ลบเกมออกจากตะกร้าของตัวเองไม่กระทบตะกร้าของผู้ใช้อื่น
+	_, other := api.signUp("other_shopper")
+	api.expect("POST", "/cart/add", other, map[string]int64{"game_id": second}, http.StatusOK)
+	api.expect("POST", "/cart/remove", token, map[string]int64{"game_id": second}, http.StatusOK)
+	if count, _, _ := cartOf(token); count != 0 {
+		t.Errorf("cart after removing everything = %d items", count)
+	}
+	if count, _, _ := cartOf(other); count != 1 {
+		t.Errorf("other user's cart = %d items, want 1", count)
+	}
+
+	var events int
+	api.scalar(&events, "SELECT COUNT(*) FROM cart_events WHERE user_id = ?", userID)
+	if events != 2 {
+		t.Errorf("cart events = %d, want 2 (rejected adds are not recorded)", events)
+	}
+}
+
+func TestIntegrationAdminGameCRUD(t *testing.T) {
+	api := newIntegrationAPI(t)
+	categoryID := api.exec("INSERT INTO categories (name) VALUES ('Strategy')")
+
+	adminID, _ := api.signUp("store_admin")
+	api.exec("UPDATE users SET role = 'admin' WHERE id = ?", adminID)
+	token := api.login("store_admin") // role อยู่ใน token จึงต้องเข้าสู่ระบบใหม่หลังเปลี่ยน role
+
+	game := map[string]interface{}{
+		"name":         "Star Forge",
+		"price":        29.99,
+		"category_id":  categoryID,
+		"description":  "Build a fleet",
+		"release_date": "2024-05-01",
+		"status":       "draft",
+	}
+
+	// ผู้ใช้ทั่วไปเพิ่มเกมไม่ได้
+	_, userToken := api.signUp("regular_user")
+	api.expect("POST", "/admin/games", userToken, game, http.StatusForbidden)
+
+	created := api.expect("POST", "/admin/games", token, game, http.StatusCreated)
+	gameID := int64(created["game_id"].(float64))
+	if created["slug"] != "star-forge" {
+		t.Errorf("slug = %v, want star-forge", created["slug"])
+	}
+	path := fmt.Sprintf("/admin/games/%d", gameID)
+
+	fetched := api.expect("GET", path, token, nil, http.StatusOK)
+	if fetched["name"] != "Star Forge" || fetched["status"] != GameStatusDraft || fetched["category"] != "Strategy" {
+		t.Errorf("created game = %v", fetched)
+	}
+	assertAmount(t, "price", fetched["price"], 29.99)
+
+	api.expect("PUT", path, token, map[string]interface{}{"price": 19.99, "status": "published"}, http.StatusOK)
+	fetched = api.expect("GET", path, token, nil, http.StatusOK)
+	if fetched["status"] != GameStatusPublished || fetched["release_date"] != "2024-05-01" {
+		t.Errorf("updated game = %v", fetched)
+	}
+	assertAmount(t, "price", fetched["price"], 19.99)
+
+	var priceChanges int
+	api.scalar(&priceChanges, "SELECT COUNT(*) FROM game_price_history WHERE game_id = ?", gameID)
+	if priceChanges < 2 {
+		t.Errorf("price history rows = %d, want at least 2", priceChanges)
+	}
+
+	deletePath := fmt.Sprintf("/admin/games/delete/%d", gameID)
+	api.expect("DELETE", deletePath, token, nil, http.StatusOK)
+	api.expect("DELETE", deletePath, token, nil, http.StatusConflict)
+	fetched = api.expect("GET", path, token, nil, http.StatusOK)
+	if fetched["status"] != GameStatusDelisted {
+		t.Errorf("status after delete = %v, want %s", fetched["status"], GameStatusDelisted)
+	}
+
+	api.expect("DELETE", "/admin/games/delete/999999", token, nil, http.StatusNotFound)
+
+	var audited int
+	api.scalar(&audited, "SELECT COUNT(*) FROM admin_audit_log WHERE target_type = 'game' AND target_id = ?", fmt.Sprint(gameID))
+	if audited == 0 {
+		t.Error("admin game changes were not written to the audit log")
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultLowStockThreshold จำนวนคงเหลือที่ถือว่าใกล้หมด (ปรับได้ด้วย LOW_STOCK_THRESHOLD)
+const defaultLowStockThreshold = 5
+
+func lowStockThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("LOW_STOCK_THRESHOLD")); err == nil && n >= 0 {
+		return n
+	}
+	return defaultLowStockThreshold
+}
+
+// addStockFields เพิ่มข้อมูลสต็อกให้ object เกมใน catalog
+// stock_count เป็น null สำหรับเกมที่ไม่จำกัดจำนวน (เช่นเกมดิจิทัลทั่วไป)
+func addStockFields(game map[string]interface{}, stock sql.NullInt64) {
+	if stock.Valid {
+		game["stock_count"] = stock.Int64
+		game["out_of_stock"] = stock.Int64 <= 0
+	} else {
+		game["stock_count"] = nil
+		game["out_of_stock"] = false
+	}
+}
+
+// reserveStock ตัดสต็อกของเกมแบบ atomic (ใช้ภายใน transaction ของการ checkout)
+// คืนค่า false ถ้าสต็อกไม่พอ เกมที่ไม่จำกัดจำนวน (stock_count IS NULL) จะไม่ถูกตัด
+func reserveStock(tx *sql.Tx, gameID, quantity int) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE games SET stock_count = stock_count - ?
+		WHERE id = ? AND stock_count IS NOT NULL AND stock_count >= ?
+	`, quantity, gameID, quantity)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// AdminRestockHandler handles stock updates for a game
+// ฟังก์ชันสำหรับเติมหรือกำหนดจำนวนสต็อกของเกม
+// POST /admin/games/{id}/restock
+//
+//	{"quantity": 10}            เพิ่มสต็อก 10 ชิ้น
+//	{"stock_count": 25}         กำหนดสต็อกเป็น 25 ชิ้น
+//	{"unlimited": true}         ยกเลิกการจำกัดจำนวน
+func AdminRestockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ตัวอย่าง URL: /admin/games/123/restock → gameID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 4 {
+		utils.JSONError(w, "Game ID required", http.StatusBadRequest)
+		return
+	}
+	gameID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Quantity   *int `json:"quantity"`
+		StockCount *int `json:"stock_count" validate:"min=0"`
+		Unlimited  bool `json:"unlimited"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	before := snapshotRow("SELECT id, name, stock_count FROM games WHERE id = ?", gameID)
+	if before == nil {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	// เติมสต็อกแล้วล้างสถานะการแจ้งเตือนสต็อกใกล้หมด (แจ้งเตือนใหม่ได้เมื่อสต็อกลดลงอีกครั้ง)
+	var query string
+	var args []interface{}
+	switch {
+	case req.Unlimited:
+		query = "UPDATE games SET stock_count = NULL, low_stock_alerted_at = NULL WHERE id = ?"
+		args = []interface{}{gameID}
+	case req.StockCount != nil:
+		query = "UPDATE games SET stock_count = ?, low_stock_alerted_at = NULL WHERE id = ?"
+		args = []interface{}{*req.StockCount, gameID}
+	case req.Quantity != nil && *req.Quantity > 0:
+		query = "UPDATE games SET stock_count = COALESCE(stock_count, 0) + ?, low_stock_alerted_at = NULL WHERE id = ?"
+		args = []interface{}{*req.Quantity, gameID}
+	default:
+		utils.JSONErrorCode(w, utils.ErrValidation, "Provide a positive quantity, a stock_count, or unlimited", http.StatusBadRequest)
+		return
+	}
+	if _, err := db.Exec(query, args...); err != nil {
+		fmt.Printf("❌ Error updating stock: %v\n", err)
+		utils.JSONError(w, "Error updating stock", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "restock", "game", gameID, before, snapshotRow("SELECT id, name, stock_count FROM games WHERE id = ?", gameID))
+	invalidateCatalogCache()
+
+	response := map[string]interface{}{
+		"message": "Stock updated",
+		"game_id": gameID,
+	}
+	var stock sql.NullInt64
+	if err := db.QueryRow("SELECT stock_count FROM games WHERE id = ?", gameID).Scan(&stock); err == nil {
+		addStockFields(response, stock)
+	}
+
+	fmt.Printf("📦 Stock updated for game %d: %v\n", gameID, response["stock_count"])
+	utils.JSONResponse(w, response, http.StatusOK)
+}
+
+// AdminLowStockHandler handles listing games with low stock
+// ฟังก์ชันสำหรับดึงรายการเกมที่สต็อกใกล้หมดหรือหมดแล้ว
+// GET /admin/inventory/low-stock?threshold=5
+func AdminLowStockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	threshold := lowStockThreshold()
+	if raw := r.URL.Query().Get("threshold"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			threshold = n
+		}
+	}
+
+	games, err := lowStockGames(threshold, false)
+	if err != nil {
+		fmt.Printf("❌ Error fetching low stock games: %v\n", err)
+		utils.JSONError(w, "Error fetching low stock games", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"threshold": threshold,
+		"games":     games,
+		"count":     len(games),
+	}, http.StatusOK)
+}
+
+// lowStockGames ดึงเกมที่วางขายอยู่และมีสต็อกไม่เกิน threshold
+// unalertedOnly ใช้กับงานแจ้งเตือนเพื่อไม่ให้แจ้งเกมเดิมซ้ำ
+func lowStockGames(threshold int, unalertedOnly bool) ([]map[string]interface{}, error) {
+	query := `
+		SELECT id, name, stock_count FROM games
+		WHERE status = 'published' AND stock_count IS NOT NULL AND stock_count <= ?`
+	if unalertedOnly {
+		query += " AND low_stock_alerted_at IS NULL"
+	}
+	rows, err := db.Query(query+" ORDER BY stock_count ASC, id ASC", threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id, stock int
+		var name string
+		if err := rows.Scan(&id, &name, &stock); err != nil {
+			return nil, err
+		}
+		games = append(games, map[string]interface{}{
+			"id":           id,
+			"name":         name,
+			"stock_count":  stock,
+			"out_of_stock": stock <= 0,
+		})
+	}
+	return games, rows.Err()
+}
+
+// alertLowStock แจ้งเตือนผู้ดูแลระบบทุกคนเมื่อมีเกมสต็อกใกล้หมด (แจ้งครั้งเดียวจนกว่าจะเติมสต็อก)
+func alertLowStock() error {
+	games, err := lowStockGames(lowStockThreshold(), true)
+	if err != nil {
+		return fmt.Errorf("error fetching low stock games: %v", err)
+	}
+	if len(games) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query("SELECT id FROM users WHERE role = 'admin'")
+	if err != nil {
+		return fmt.Errorf("error fetching admins: %v", err)
+	}
+	var adminIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning admin: %v", err)
+		}
+		adminIDs = append(adminIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error fetching admins: %v", err)
+	}
+
+	for _, game := range games {
+		for _, adminID := range adminIDs {
+			notify(adminID, NotificationLowStock, "Low stock",
+				fmt.Sprintf("%s has %d left in stock", game["name"], game["stock_count"]),
+				map[string]interface{}{"game_id": game["id"], "stock_count": game["stock_count"]})
+		}
+		if _, err := db.Exec("UPDATE games SET low_stock_alerted_at = NOW() WHERE id = ?", game["id"]); err != nil {
+			fmt.Printf("⚠️ Error marking low stock alert for game %v: %v\n", game["id"], err)
+		}
+	}
+
+	fmt.Printf("📦 Low stock alert sent for %d games\n", len(games))
+	return nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/jobs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ประเภทงานเบื้องหลังของ handlers
+const (
+	JobDiscountArchive  = "discount.archive"
+	JobRankingRecompute = "ranking.recompute"
+	JobUploadsGC        = "uploads.gc"
+	JobSessionsCleanup  = "sessions.cleanup"
+	JobLowStockAlert    = "inventory.low_stock"
+	JobCartExpiry       = "cart.expire"
+	JobCartReminders    = "cart.reminders"
+	JobFeedRegenerate   = "feed.regenerate"
+)
+
+// uploadsGCMinAge ไฟล์ที่ใหม่กว่านี้จะไม่ถูกลบ (อาจกำลังอัพโหลดอยู่และยังไม่ได้บันทึกลงฐานข้อมูล)
+const uploadsGCMinAge = 24 * time.Hour
+
+// RegisterJobs ลงทะเบียนงานเบื้องหลังและตารางเวลาของ handlers
+// ต้องเรียกก่อน jobs.Start
+func RegisterJobs() {
+	jobs.Register(JobDiscountArchive, func([]byte) error {
+		return autoArchiveDiscounts()
+	})
+	jobs.Register(JobRankingRecompute, runRankingRecompute)
+	jobs.Register(JobUploadsGC, func([]byte) error {
+		return collectUploadGarbage()
+	})
+	jobs.Register(JobSessionsCleanup, func([]byte) error {
+		return cleanupExpiredSessions()
+	})
+	jobs.Register(JobLowStockAlert, func([]byte) error {
+		return alertLowStock()
+	})
+	jobs.Register(JobCartExpiry, func([]byte) error {
+		return expireStaleCartItems()
+	})
+	jobs.Register(JobCartReminders, func([]byte) error {
+		return sendCartReminders()
+	})
+	jobs.Register(JobFeedRegenerate, func([]byte) error {
+		return regenerateFeeds()
+	})
+	jobs.Register(JobWishlistPriceDrop, runPriceDropAlert)
+	jobs.Register(JobNewsletterSend, runNewsletterSend)
+	jobs.Register(JobWishlistDigest, func([]byte) error {
+		return sendPriceDropDigests()
+	})
+
+	jobs.Schedule("discount-archive", 5*time.Minute, JobDiscountArchive)
+	jobs.Schedule("ranking-recompute", time.Hour, JobRankingRecompute)
+	jobs.Schedule("uploads-gc", 24*time.Hour, JobUploadsGC)
+	jobs.Schedule("sessions-cleanup", 24*time.Hour, JobSessionsCleanup)
+	jobs.Schedule("low-stock-alert", 15*time.Minute, JobLowStockAlert)
+	jobs.Schedule("wishlist-digest", 24*time.Hour, JobWishlistDigest)
+	jobs.Schedule("cart-expiry", time.Hour, JobCartExpiry)
+	jobs.Schedule("cart-reminders", time.Hour, JobCartReminders)
+	jobs.Schedule("feed-regenerate", feedRefreshInterval, JobFeedRegenerate)
+}
+
+// collectUploadGarbage ลบไฟล์ใน uploads/ ที่ไม่มีการอ้างอิงจากเกม ผู้ใช้ คลังภาพ หรือภาพย่อ
+// (เช่น avatar เก่าที่ถูกเปลี่ยนแล้ว หรือไฟล์ที่อัพโหลดค้างจาก request ที่ล้มเหลว)
+func collectUploadGarbage() error {
+	referenced := map[string]bool{}
+	rows, err := db.Query(`
+		SELECT image_url FROM games WHERE image_url LIKE '/uploads/%'
+		UNION SELECT avatar_url FROM users WHERE avatar_url LIKE '/uploads/%'
+		UNION SELECT url FROM media_assets WHERE url LIKE '/uploads/%'
+		UNION SELECT url FROM image_variants WHERE url LIKE '/uploads/%'
+	`)
+	if err != nil {
+		return fmt.Errorf("error loading referenced uploads: %v", err)
+	}
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			// ไฟล์ที่อ่านไม่ได้อาจยังถูกใช้อยู่ หยุดเก็บกวาดแทนการลบไฟล์ที่ยังอ้างถึง
+			rows.Close()
+			return fmt.Errorf("error scanning referenced upload: %v", err)
+		}
+		referenced[filepath.Base(url)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error loading referenced uploads: %v", err)
+	}
+
+	entries, err := os.ReadDir("uploads")
+	if err != nil {
+		return fmt.Errorf("error reading uploads directory: %v", err)
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-uploadsGCMinAge)
+	for _, entry := range entries {
+		if entry.IsDir() || referenced[entry.Name()] || entry.Name() == "default-avatar.png" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join("uploads", entry.Name())); err != nil {
+			fmt.Printf("⚠️ Error removing orphaned upload %s: %v\n", entry.Name(), err)
+			continue
+		}
+		removed++
+	}
+
+	fmt.Printf("🧹 Upload GC removed %d orphaned files\n", removed)
+	return nil
+}
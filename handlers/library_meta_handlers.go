@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// จำนวนแท็กสูงสุดต่อเกมและความยาวสูงสุดของแท็ก
+const (
+	maxLibraryTags      = 20
+	maxLibraryTagLength = 32
+)
+
+// normalizeLibraryTags ตัดช่องว่าง แปลงเป็นตัวพิมพ์เล็ก และตัดแท็กซ้ำ (เรียงตามตัวอักษร)
+func normalizeLibraryTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > maxLibraryTagLength {
+			return nil, fmt.Errorf("tags must be at most %d characters", maxLibraryTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxLibraryTags {
+		return nil, fmt.Errorf("a game can have at most %d tags", maxLibraryTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// librarySorts คอลัมน์ที่ใช้เรียงคลังเกม (?sort=) และทิศทางเริ่มต้น
+var librarySorts = map[string]struct{ column, order string }{
+	"purchased_at": {"pg.purchased_at", "DESC"},
+	"name":         {"g.name", "ASC"},
+	"playtime":     {"COALESCE(ls.playtime_minutes, 0)", "DESC"},
+	"last_played":  {"ls.last_played_at", "DESC"},
+}
+
+// libraryOrderBy คำสั่ง ORDER BY ของคลังเกมจาก ?sort= (ค่าเริ่มต้น purchased_at) และ ?order=asc|desc
+func libraryOrderBy(query url.Values) (string, error) {
+	sortKey := query.Get("sort")
+	if sortKey == "" {
+		sortKey = "purchased_at"
+	}
+	sortBy, ok := librarySorts[sortKey]
+	if !ok {
+		return "", fmt.Errorf("invalid sort. Use purchased_at, name, playtime or last_played")
+	}
+
+	order := sortBy.order
+	switch strings.ToLower(query.Get("order")) {
+	case "":
+	case "asc":
+		order = "ASC"
+	case "desc":
+		order = "DESC"
+	default:
+		return "", fmt.Errorf("invalid order. Use asc or desc")
+	}
+
+	// g.id ทำให้ลำดับคงที่เมื่อค่าที่ใช้เรียงเท่ากัน (pagination ไม่ซ้ำหรือข้ามเกม)
+	return " ORDER BY " + sortBy.column + " " + order + ", g.id " + order, nil
+}
+
+// libraryFilters เงื่อนไขกรองคลังเกมจาก query (ใช้กับ alias pg = purchased_games, g = games, ls = library_settings)
+// ?favorite=true       - เฉพาะเกมโปรด
+// ?hidden=true|all     - เฉพาะเกมที่ซ่อน หรือแสดงทั้งหมด (ค่าเริ่มต้นไม่แสดงเกมที่ซ่อน)
+// ?tag=rpg             - เฉพาะเกมที่มีแท็กนี้
+// ?category=           - หมวดหมู่ (รับเป็น ID หรือชื่อ)
+// ?installed=true      - เฉพาะเกมที่ติดตั้งอยู่ (หรือ false = ยังไม่ได้ติดตั้ง)
+func libraryFilters(query url.Values) (string, []interface{}, error) {
+	var clauses []string
+	var args []interface{}
+
+	switch query.Get("favorite") {
+	case "":
+	case "true":
+		clauses = append(clauses, "COALESCE(ls.favorite, 0) = 1")
+	case "false":
+		clauses = append(clauses, "COALESCE(ls.favorite, 0) = 0")
+	default:
+		return "", nil, fmt.Errorf("invalid favorite filter. Use true or false")
+	}
+
+	switch query.Get("hidden") {
+	case "", "false":
+		clauses = append(clauses, "COALESCE(ls.hidden, 0) = 0")
+	case "true":
+		clauses = append(clauses, "COALESCE(ls.hidden, 0) = 1")
+	case "all":
+	default:
+		return "", nil, fmt.Errorf("invalid hidden filter. Use true, false or all")
+	}
+
+	switch query.Get("installed") {
+	case "":
+	case "true":
+		clauses = append(clauses, "COALESCE(ls.installed, 0) = 1")
+	case "false":
+		clauses = append(clauses, "COALESCE(ls.installed, 0) = 0")
+	default:
+		return "", nil, fmt.Errorf("invalid installed filter. Use true or false")
+	}
+
+	if category := query.Get("category"); category != "" {
+		if categoryID, err := strconv.Atoi(category); err == nil {
+			clauses = append(clauses, "g.category_id = ?")
+			args = append(args, categoryID)
+		} else {
+			clauses = append(clauses, "g.category_id IN (SELECT id FROM categories WHERE name = ?)")
+			args = append(args, category)
+		}
+	}
+
+	if tag := strings.ToLower(strings.TrimSpace(query.Get("tag"))); tag != "" {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1 FROM library_tags lt
+			WHERE lt.user_id = pg.user_id AND lt.game_id = pg.game_id AND lt.tag = ?
+		)`)
+		args = append(args, tag)
+	}
+
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return " AND " + strings.Join(clauses, " AND "), args, nil
+}
+
+// addLibraryTags เพิ่มแท็กของผู้ใช้ให้เกมในคลัง (query เดียวสำหรับทั้งหน้า)
+func addLibraryTags(userID int, games []map[string]interface{}) {
+	ids := make([]string, 0, len(games))
+	for _, game := range games {
+		game["tags"] = []string{}
+		if id, ok := game["id"].(int); ok {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT game_id, tag FROM library_tags
+		WHERE user_id = ? AND game_id IN (`+strings.Join(ids, ",")+`)
+		ORDER BY tag
+	`, userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching library tags: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var gameID int
+		var tag string
+		if err := rows.Scan(&gameID, &tag); err != nil {
+			fmt.Printf("❌ Error scanning library tag: %v\n", err)
+			return
+		}
+		tags[gameID] = append(tags[gameID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading library tags: %v\n", err)
+		return
+	}
+
+	for _, game := range games {
+		if gameTags, ok := tags[game["id"].(int)]; ok {
+			game["tags"] = gameTags
+		}
+	}
+}
+
+// ownsGame ตรวจสอบว่าผู้ใช้มีเกมนี้ในคลัง
+func ownsGame(userID, gameID int) (bool, error) {
+	var owned bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?)
+	`, userID, gameID).Scan(&owned)
+	return owned, err
+}
+
+// UpdateLibraryItemHandler handles per-user organization of an owned game
+// ฟังก์ชันสำหรับจัดระเบียบเกมในคลัง (เกมโปรด ซ่อนเกม แท็กที่ผู้ใช้ตั้งเอง และสถานะการติดตั้ง)
+// PATCH /library/{game_id} {"favorite": true, "hidden": false, "installed": true, "tags": ["rpg", "co-op"]}
+// ฟิลด์ที่ไม่ได้ส่งมาจะไม่เปลี่ยน ส่วน tags จะแทนที่แท็กเดิมทั้งหมด
+func UpdateLibraryItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PATCH" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /library/12 → gameID = 12
+	gameID, err := strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Favorite  *bool    `json:"favorite"`
+		Hidden    *bool    `json:"hidden"`
+		Installed *bool    `json:"installed"`
+		Tags      []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var tags []string
+	if req.Tags != nil {
+		if tags, err = normalizeLibraryTags(req.Tags); err != nil {
+			utils.JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	owned, err := ownsGame(userID, gameID)
+	if err != nil {
+		utils.JSONError(w, "Error checking ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owned {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found in your library", http.StatusNotFound)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// ค่าปัจจุบัน (ผู้ใช้ที่ยังไม่เคยตั้งค่าถือว่าไม่ใช่เกมโปรด ไม่ได้ซ่อน และยังไม่ได้ติดตั้ง)
+	var favorite, hidden, installed bool
+	err = tx.QueryRow(`
+		SELECT favorite, hidden, installed FROM library_settings WHERE user_id = ? AND game_id = ?
+	`, userID, gameID).Scan(&favorite, &hidden, &installed)
+	if err != nil && err != sql.ErrNoRows {
+		utils.JSONError(w, "Error fetching library settings", http.StatusInternalServerError)
+		return
+	}
+	if req.Favorite != nil {
+		favorite = *req.Favorite
+	}
+	if req.Hidden != nil {
+		hidden = *req.Hidden
+	}
+	if req.Installed != nil {
+		installed = *req.Installed
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO library_settings (user_id, game_id, favorite, hidden, installed)
+		VALUES (?, ?, ?, ?, ?)
+		`+dialect.OnConflict("user_id", "game_id")+` `+upsertColumns("favorite", "hidden", "installed")+`
+	`, userID, gameID, favorite, hidden, installed)
+	if err != nil {
+		fmt.Printf("❌ Error saving library settings: %v\n", err)
+		utils.JSONError(w, "Error saving library settings", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Tags != nil {
+		if _, err := tx.Exec("DELETE FROM library_tags WHERE user_id = ? AND game_id = ?", userID, gameID); err != nil {
+			utils.JSONError(w, "Error saving library settings", http.StatusInternalServerError)
+			return
+		}
+		for _, tag := range tags {
+			if _, err := tx.Exec("INSERT INTO library_tags (user_id, game_id, tag) VALUES (?, ?, ?)", userID, gameID, tag); err != nil {
+				fmt.Printf("❌ Error saving library tag: %v\n", err)
+				utils.JSONError(w, "Error saving library settings", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	item := map[string]interface{}{"id": gameID}
+	addLibraryTags(userID, []map[string]interface{}{item})
+
+	fmt.Printf("🗂️ Library item updated: user %d, game %d (favorite=%t hidden=%t installed=%t)\n", userID, gameID, favorite, hidden, installed)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":   "Library updated",
+		"game_id":   gameID,
+		"favorite":  favorite,
+		"hidden":    hidden,
+		"installed": installed,
+		"tags":      item["tags"],
+	}, http.StatusOK)
+}
+
+// LibraryPlaytimeHandler records a play session reported by the launcher
+// ฟังก์ชันสำหรับบันทึกเวลาเล่นเกม (บวกเพิ่มจากเวลาเดิมและอัพเดทเวลาที่เล่นล่าสุด)
+// POST /library/{game_id}/playtime {"minutes": 45}
+func LibraryPlaytimeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /library/12/playtime → gameID = 12
+	gameID, err := strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Minutes int `json:"minutes" validate:"min=1,max=1440"` // เวลาเล่นของ session นี้ (ไม่เกิน 1 วัน)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	owned, err := ownsGame(userID, gameID)
+	if err != nil {
+		utils.JSONError(w, "Error checking ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owned {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found in your library", http.StatusNotFound)
+		return
+	}
+
+	// เกมที่เล่นได้ต้องติดตั้งอยู่แล้ว จึงตั้ง installed ไปพร้อมกัน
+	_, err = db.Exec(`
+		INSERT INTO library_settings (user_id, game_id, playtime_minutes, installed, last_played_at)
+		VALUES (?, ?, ?, 1, NOW())
+		`+dialect.OnConflict("user_id", "game_id")+`
+		playtime_minutes = library_settings.playtime_minutes + `+dialect.Excluded("playtime_minutes")+`,
+		`+upsertColumns("installed", "last_played_at")+`
+	`, userID, gameID, req.Minutes)
+	if err != nil {
+		fmt.Printf("❌ Error recording playtime: %v\n", err)
+		utils.JSONError(w, "Error saving library settings", http.StatusInternalServerError)
+		return
+	}
+
+	var playtime int
+	var lastPlayed utils.NullTimestamp
+	if err := db.QueryRow(`
+		SELECT playtime_minutes, last_played_at FROM library_settings WHERE user_id = ? AND game_id = ?
+	`, userID, gameID).Scan(&playtime, &lastPlayed); err != nil {
+		utils.JSONError(w, "Error fetching library settings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"game_id":          gameID,
+		"playtime_minutes": playtime,
+		"last_played_at":   lastPlayed,
+	}, http.StatusOK)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultShareLinkHours อายุเริ่มต้นของลิงก์แชร์เกมในคลัง (ชั่วโมง สูงสุด 720 = 30 วัน)
+const defaultShareLinkHours = 72
+
+// shareSecret คีย์สำหรับเซ็นลิงก์แชร์เกมในคลัง (SHARE_LINK_SECRET)
+// การเปลี่ยนคีย์ทำให้ลิงก์ที่แชร์ไปแล้วทั้งหมดใช้ไม่ได้
+func shareSecret() string {
+	return os.Getenv("SHARE_LINK_SECRET")
+}
+
+// shareSignature ลายเซ็นของลิงก์แชร์ (ผูกกับผู้ใช้ เกม และเวลาหมดอายุ จึงแก้ค่าใดค่าหนึ่งในลิงก์ไม่ได้)
+func shareSignature(secret string, userID, gameID int, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("library-share:%d:%d:%d", userID, gameID, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// shareURL ลิงก์สาธารณะของการ์ดเกม (APP_BASE_URL ค่าเริ่มต้น http://localhost:8080)
+func shareURL(secret string, userID, gameID int, expires int64) string {
+	base := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	query := url.Values{
+		"u":   {strconv.Itoa(userID)},
+		"g":   {strconv.Itoa(gameID)},
+		"exp": {strconv.FormatInt(expires, 10)},
+		"sig": {shareSignature(secret, userID, gameID, expires)},
+	}
+	return base + "/share/library?" + query.Encode()
+}
+
+// LibraryItemHandler routes /library/{game_id}/... to cloud saves, sharing and library organization
+// ฟังก์ชันสำหรับแยก route ของเกมในคลัง (/saves ไปที่ CloudSaveHandler, /share ไปที่ ShareLibraryItemHandler,
+// /library/owns ไปที่ LibraryOwnershipHandler)
+func LibraryItemHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) >= 2 && pathParts[1] == "owns" {
+		LibraryOwnershipHandler(w, r)
+		return
+	}
+	if len(pathParts) == 3 && pathParts[2] == "share" {
+		ShareLibraryItemHandler(w, r)
+		return
+	}
+	if len(pathParts) == 3 && pathParts[2] == "playtime" {
+		LibraryPlaytimeHandler(w, r)
+		return
+	}
+	if len(pathParts) == 2 {
+		UpdateLibraryItemHandler(w, r)
+		return
+	}
+	CloudSaveHandler(w, r)
+}
+
+// ShareLibraryItemHandler creates a signed, expiring public link for an owned game
+// ฟังก์ชันสำหรับสร้างลิงก์แชร์การ์ดเกมในคลัง ("I own this!") ที่ผู้อื่นเปิดดูได้โดยไม่ต้องเข้าสู่ระบบ
+// POST /library/{game_id}/share {"expires_in_hours": 72} (ค่าเริ่มต้น 72 ชั่วโมง สูงสุด 30 วัน)
+func ShareLibraryItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /library/12/share → gameID = 12
+	gameID, err := strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	req := struct {
+		ExpiresInHours int `json:"expires_in_hours" validate:"min=1,max=720"`
+	}{ExpiresInHours: defaultShareLinkHours}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	secret := shareSecret()
+	if secret == "" {
+		utils.JSONError(w, "Sharing is not configured (SHARE_LINK_SECRET)", http.StatusServiceUnavailable)
+		return
+	}
+
+	// แชร์ได้เฉพาะเกมที่อยู่ในคลังของผู้ใช้
+	var owned bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?)", userID, gameID).Scan(&owned); err != nil {
+		utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owned {
+		utils.JSONErrorCode(w, utils.ErrForbidden, "You do not own this game", http.StatusForbidden)
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour).Truncate(time.Second)
+	link := shareURL(secret, userID, gameID, expiresAt.Unix())
+
+	fmt.Printf("🔗 Library share link: user %d game %d until %s\n", userID, gameID, expiresAt.Format(time.RFC3339))
+	utils.JSONResponse(w, map[string]interface{}{
+		"url":        link,
+		"game_id":    gameID,
+		"expires_at": utils.FormatTimestamp(expiresAt),
+	}, http.StatusCreated)
+}
+
+// sharedGameCard ข้อมูลที่แสดงในการ์ดเกมที่แชร์ (เฉพาะข้อมูลสาธารณะ ไม่มีราคาที่จ่ายหรือข้อมูลบัญชี)
+type sharedGameCard struct {
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url,omitempty"`
+	GameID     int    `json:"game_id"`
+	GameName   string `json:"game_name"`
+	ImageURL   string `json:"image_url"`
+	Category   string `json:"category"`
+	OwnedSince string `json:"owned_since"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+var sharedGameCardTemplate = htmltemplate.Must(htmltemplate.New("card").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Username}} owns {{.GameName}}</title>
+<meta property="og:title" content="{{.Username}} owns {{.GameName}}">
+<meta property="og:image" content="{{.ImageURL}}">
+<meta name="robots" content="noindex">
+</head>
+<body style="font-family:sans-serif;background:#f4f4f4;padding:24px">
+<div style="max-width:360px;margin:0 auto;background:#fff;border-radius:8px;overflow:hidden">
+<img src="{{.ImageURL}}" alt="{{.GameName}}" style="width:100%">
+<div style="padding:16px">
+<h1 style="font-size:20px;margin:0 0 8px">{{.GameName}}</h1>
+<p style="margin:0 0 8px;color:#666">{{.Category}}</p>
+<p style="margin:0"><strong>{{.Username}}</strong>: I own this! (since {{.OwnedSince}})</p>
+</div>
+</div>
+</body>
+</html>
+`))
+
+// SharedLibraryItemHandler renders a shared game card after validating the signed link
+// ฟังก์ชันสำหรับแสดงการ์ดเกมจากลิงก์ที่แชร์ (สาธารณะ อ่านอย่างเดียว)
+// GET /share/library?u=&g=&exp=&sig= - ตอบเป็น HTML เมื่อเปิดจาก browser และเป็น JSON ในกรณีอื่น
+// ลิงก์ใช้ไม่ได้เมื่อหมดอายุ ลายเซ็นไม่ตรง หรือเกมไม่อยู่ในคลังแล้ว (เช่นคืนเงิน)
+func SharedLibraryItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	userID, errUser := strconv.Atoi(query.Get("u"))
+	gameID, errGame := strconv.Atoi(query.Get("g"))
+	expires, errExp := strconv.ParseInt(query.Get("exp"), 10, 64)
+	secret := shareSecret()
+	if secret == "" || errUser != nil || errGame != nil || errExp != nil ||
+		!hmac.Equal([]byte(query.Get("sig")), []byte(shareSignature(secret, userID, gameID, expires))) {
+		utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid share link", http.StatusNotFound)
+		return
+	}
+	if time.Now().Unix() > expires {
+		utils.JSONErrorCode(w, utils.ErrInvalidToken, "Share link has expired", http.StatusGone)
+		return
+	}
+
+	card := sharedGameCard{GameID: gameID, ExpiresAt: utils.FormatTimestamp(time.Unix(expires, 0))}
+	var avatarURL sql.NullString
+	err := db.QueryRow(`
+		SELECT u.username, CASE WHEN u.avatar_hidden = 1 THEN NULL ELSE u.avatar_url END,
+		       g.name, COALESCE(g.image_url, ''), c.name,
+		       `+dialect.DateFormat("MIN(pg.purchased_at)", "%Y-%m-%d")+`
+		FROM purchased_games pg
+		JOIN users u ON u.id = pg.user_id
+		JOIN games g ON g.id = pg.game_id
+		JOIN categories c ON c.id = g.category_id
+		WHERE pg.user_id = ? AND pg.game_id = ?
+		GROUP BY u.username, u.avatar_hidden, u.avatar_url, g.name, g.image_url, c.name
+	`, userID, gameID).Scan(&card.Username, &avatarURL, &card.GameName, &card.ImageURL, &card.Category, &card.OwnedSince)
+	if err == sql.ErrNoRows {
+		utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid share link", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error fetching shared game card: %v\n", err)
+		utils.JSONError(w, "Error fetching shared game", http.StatusInternalServerError)
+		return
+	}
+	card.AvatarURL = avatarURL.String
+
+	// ให้ cache ได้ไม่เกินเวลาที่ลิงก์หมดอายุ
+	maxAge := min(expires-time.Now().Unix(), 300)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := sharedGameCardTemplate.Execute(w, card); err != nil {
+			fmt.Printf("❌ Error rendering shared game card: %v\n", err)
+		}
+		return
+	}
+	utils.JSONResponse(w, card, http.StatusOK)
+}
@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ขีดจำกัดของรายการเกมที่ผู้ใช้สร้าง
+const (
+	userListMaxItems       = 100 // จำนวนเกมสูงสุดต่อรายการ
+	userListMaxPerUser     = 50  // จำนวนรายการสูงสุดต่อผู้ใช้
+	userListTrendingWindow = 7   // จำนวนวันที่นับผู้ติดตามใหม่สำหรับ /lists/popular
+)
+
+// userListItem เกมหนึ่งรายการในคำขอสร้าง/แก้ไขรายการ
+type userListItem struct {
+	GameID int    `json:"game_id" validate:"gt=0"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+// userListRequest ข้อมูลสำหรับสร้าง/แก้ไขรายการ (items ไม่ส่งมาตอนแก้ไข = คงรายการเกมเดิม)
+type userListRequest struct {
+	Name        string          `json:"name" validate:"required,max=100"`
+	Description string          `json:"description" validate:"max=1000"`
+	Visibility  string          `json:"visibility" validate:"oneof=public private"`
+	Items       *[]userListItem `json:"items"`
+}
+
+var slugUnsafeChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// generateListSlug สร้าง slug จากชื่อรายการพร้อมส่วนท้ายแบบสุ่ม (เช่น "best-rpgs-3f9a1c")
+// slug ไม่เปลี่ยนเมื่อแก้ไขชื่อ เพื่อให้ลิงก์ที่แชร์ไปแล้วยังใช้ได้
+func generateListSlug(name string) (string, error) {
+	base := strings.Trim(slugUnsafeChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(base) > 100 {
+		base = strings.TrimRight(base[:100], "-")
+	}
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	if base == "" {
+		base = "list"
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
+
+// ListsHandler handles user-curated game lists
+// ฟังก์ชันสำหรับจัดการรายการเกมที่ผู้ใช้สร้าง (ดูรายการสาธารณะได้โดยไม่ต้องเข้าสู่ระบบ)
+// GET /lists (รายการของฉัน, ?following=1 รายการที่ติดตาม), POST /lists, GET /lists/popular,
+// GET/PUT/DELETE /lists/{slug}, POST/DELETE /lists/{slug}/follow
+func ListsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /lists/best-rpgs-3f9a1c/follow → slug = best-rpgs-3f9a1c, action = follow
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	userID, authenticated := requestUserID(r)
+
+	if len(pathParts) == 2 && pathParts[1] == "popular" {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		popularLists(w, r)
+		return
+	}
+
+	// ส่วนที่เหลือ ยกเว้นการดูรายการ ต้องเข้าสู่ระบบ
+	if !authenticated && !(len(pathParts) == 2 && r.Method == "GET") {
+		utils.JSONErrorCode(w, utils.ErrUnauthorized, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case len(pathParts) == 1:
+		switch r.Method {
+		case "GET":
+			myLists(w, r, userID)
+		case "POST":
+			createUserList(w, r, userID)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(pathParts) == 2:
+		switch r.Method {
+		case "GET":
+			getUserList(w, pathParts[1], userID)
+		case "PUT":
+			updateUserList(w, r, pathParts[1], userID)
+		case "DELETE":
+			deleteUserList(w, pathParts[1], userID)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(pathParts) == 3 && pathParts[2] == "follow":
+		switch r.Method {
+		case "POST", "DELETE":
+			followUserList(w, r, pathParts[1], userID)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// userListSummaryQuery ข้อมูลสรุปของรายการ (เจ้าของ จำนวนเกม และจำนวนผู้ติดตาม)
+func userListSummaryQuery() string {
+	return `
+	SELECT l.id, l.slug, l.name, l.description, l.visibility, u.username,
+	       (SELECT COUNT(*) FROM user_list_items i WHERE i.list_id = l.id) AS game_count,
+	       (SELECT COUNT(*) FROM user_list_follows f WHERE f.list_id = l.id) AS follower_count,
+	       l.created_at, l.updated_at
+	FROM user_lists l
+	JOIN users u ON l.user_id = u.id
+`
+}
+
+// scanUserListSummaries อ่านผลลัพธ์ของ userListSummaryQuery
+func scanUserListSummaries(rows *sql.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+
+	lists := []map[string]interface{}{}
+	for rows.Next() {
+		var id, gameCount, followerCount int
+		var slug, name, visibility, owner string
+		var createdAt, updatedAt utils.Timestamp
+		var description sql.NullString
+		if err := rows.Scan(&id, &slug, &name, &description, &visibility, &owner, &gameCount, &followerCount,
+			&createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, map[string]interface{}{
+			"id":             id,
+			"slug":           slug,
+			"name":           name,
+			"description":    description.String,
+			"visibility":     visibility,
+			"owner":          owner,
+			"game_count":     gameCount,
+			"follower_count": followerCount,
+			"created_at":     createdAt,
+			"updated_at":     updatedAt,
+		})
+	}
+	return lists, rows.Err()
+}
+
+// myLists รายการที่ผู้ใช้สร้าง หรือรายการที่ติดตาม (?following=1)
+func myLists(w http.ResponseWriter, r *http.Request, userID int) {
+	where := "WHERE l.user_id = ? ORDER BY l.updated_at DESC"
+	if r.URL.Query().Get("following") == "1" {
+		where = `WHERE l.visibility = 'public' AND l.hidden = 0
+			AND l.id IN (SELECT list_id FROM user_list_follows WHERE user_id = ?)
+			ORDER BY l.updated_at DESC`
+	}
+
+	rows, err := db.Query(userListSummaryQuery()+where, userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching lists: %v\n", err)
+		utils.JSONError(w, "Error fetching lists", http.StatusInternalServerError)
+		return
+	}
+	lists, err := scanUserListSummaries(rows)
+	if err != nil {
+		fmt.Printf("❌ Error scanning lists: %v\n", err)
+		utils.JSONError(w, "Error fetching lists", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, lists, http.StatusOK)
+}
+
+// popularLists รายการสาธารณะที่กำลังมาแรง (ผู้ติดตามใหม่ในช่วง 7 วัน แล้วตามจำนวนผู้ติดตามทั้งหมด)
+// GET /lists/popular?limit=20
+func popularLists(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(userListSummaryQuery()+`
+		WHERE l.visibility = 'public' AND l.hidden = 0
+		  AND EXISTS (SELECT 1 FROM user_list_items i WHERE i.list_id = l.id)
+		ORDER BY (SELECT COUNT(*) FROM user_list_follows f
+		          WHERE f.list_id = l.id AND f.created_at >= DATE_SUB(NOW(), INTERVAL ? DAY)) DESC,
+		         follower_count DESC, l.updated_at DESC
+		LIMIT ?
+	`, userListTrendingWindow, railLimit(r))
+	if err != nil {
+		fmt.Printf("❌ Error fetching popular lists: %v\n", err)
+		utils.JSONError(w, "Error fetching lists", http.StatusInternalServerError)
+		return
+	}
+	lists, err := scanUserListSummaries(rows)
+	if err != nil {
+		fmt.Printf("❌ Error scanning lists: %v\n", err)
+		utils.JSONError(w, "Error fetching lists", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, lists, http.StatusOK)
+}
+
+// findUserList ดึง id และเจ้าของรายการจาก slug (รายการที่ถูกซ่อนจากการรายงานถือเป็นรายการส่วนตัว)
+func findUserList(slug string) (id, ownerID int, visibility string, err error) {
+	err = db.QueryRow("SELECT id, user_id, IF(hidden = 1, 'private', visibility) FROM user_lists WHERE slug = ?", slug).Scan(&id, &ownerID, &visibility)
+	return
+}
+
+// getUserList ดึงรายการพร้อมเกมตามลำดับ (รายการส่วนตัวเห็นเฉพาะเจ้าของ)
+func getUserList(w http.ResponseWriter, slug string, viewerID int) {
+	listID, ownerID, visibility, err := findUserList(slug)
+	if err == sql.ErrNoRows || (err == nil && visibility != VisibilityPublic && ownerID != viewerID) {
+		utils.JSONError(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error fetching list: %v\n", err)
+		utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(userListSummaryQuery()+"WHERE l.id = ?", listID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching list: %v\n", err)
+		utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+	summaries, err := scanUserListSummaries(rows)
+	if err != nil || len(summaries) == 0 {
+		utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+	list := summaries[0]
+
+	itemRows, err := db.Query(`
+		SELECT g.id, g.name, g.price, COALESCE(tv.url, g.image_url), i.note, g.status = 'published'
+		FROM user_list_items i
+		JOIN games g ON i.game_id = g.id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE i.list_id = ?
+		ORDER BY i.position, i.game_id
+	`, listID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching list items: %v\n", err)
+		utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+	defer itemRows.Close()
+
+	items := []map[string]interface{}{}
+	for itemRows.Next() {
+		var id int
+		var name string
+		var price float64
+		var thumbnailURL, note sql.NullString
+		var available bool
+		if err := itemRows.Scan(&id, &name, &price, &thumbnailURL, &note, &available); err != nil {
+			fmt.Printf("❌ Error scanning list item: %v\n", err)
+			utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, map[string]interface{}{
+			"game_id":       id,
+			"name":          name,
+			"price":         price,
+			"thumbnail_url": thumbnailURL.String,
+			"note":          note.String,
+			"available":     available,
+		})
+	}
+	if err := itemRows.Err(); err != nil {
+		fmt.Printf("❌ Error reading list item rows: %v\n", err)
+		utils.JSONError(w, "Error fetching list", http.StatusInternalServerError)
+		return
+	}
+	list["items"] = items
+
+	var following bool
+	if viewerID > 0 {
+		db.QueryRow("SELECT EXISTS(SELECT 1 FROM user_list_follows WHERE list_id = ? AND user_id = ?)", listID, viewerID).Scan(&following)
+	}
+	list["following"] = following
+	list["is_owner"] = viewerID == ownerID
+
+	utils.JSONResponse(w, list, http.StatusOK)
+}
+
+// decodeUserListRequest อ่านและตรวจสอบข้อมูลรายการ
+func decodeUserListRequest(w http.ResponseWriter, r *http.Request) (*userListRequest, bool) {
+	req := userListRequest{Visibility: VisibilityPublic}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return nil, false
+	}
+	if req.Items != nil {
+		if len(*req.Items) > userListMaxItems {
+			utils.JSONError(w, fmt.Sprintf("A list can contain at most %d games", userListMaxItems), http.StatusBadRequest)
+			return nil, false
+		}
+		for i, item := range *req.Items {
+			if errs := utils.Validate(item); errs != nil {
+				for j := range errs {
+					errs[j].Field = "items[" + strconv.Itoa(i) + "]." + errs[j].Field
+				}
+				utils.JSONValidationError(w, errs)
+				return nil, false
+			}
+		}
+	}
+	return &req, true
+}
+
+// setUserListItems แทนที่เกมในรายการตามลำดับที่ส่งมา (ข้ามเกมที่ไม่มีอยู่และเกมที่ซ้ำ)
+func setUserListItems(exec sqlExecutor, listID int, items []userListItem) error {
+	if _, err := exec.Exec("DELETE FROM user_list_items WHERE list_id = ?", listID); err != nil {
+		return err
+	}
+	for position, item := range items {
+		_, err := exec.Exec(`
+			INSERT IGNORE INTO user_list_items (list_id, game_id, position, note)
+			SELECT ?, id, ?, ? FROM games WHERE id = ?
+		`, listID, position, nullIfEmpty(item.Note), item.GameID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createUserList สร้างรายการใหม่
+func createUserList(w http.ResponseWriter, r *http.Request, userID int) {
+	req, ok := decodeUserListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM user_lists WHERE user_id = ?", userID).Scan(&count)
+	if count >= userListMaxPerUser {
+		utils.JSONError(w, fmt.Sprintf("You can create at most %d lists", userListMaxPerUser), http.StatusBadRequest)
+		return
+	}
+
+	slug, err := generateListSlug(req.Name)
+	if err != nil {
+		utils.JSONError(w, "Error creating list", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO user_lists (user_id, slug, name, description, visibility) VALUES (?, ?, ?, ?, ?)
+	`, userID, slug, req.Name, nullIfEmpty(req.Description), req.Visibility)
+	if err != nil {
+		fmt.Printf("❌ Error creating list: %v\n", err)
+		utils.JSONError(w, "Error creating list", http.StatusInternalServerError)
+		return
+	}
+	listID, _ := result.LastInsertId()
+
+	if req.Items != nil {
+		if err := setUserListItems(tx, int(listID), *req.Items); err != nil {
+			fmt.Printf("❌ Error saving list items: %v\n", err)
+			utils.JSONError(w, "Error creating list", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error creating list", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("📝 List created: %s by user %d\n", slug, userID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "List created",
+		"id":      listID,
+		"slug":    slug,
+	}, http.StatusCreated)
+}
+
+// updateUserList แก้ไขรายการ (เฉพาะเจ้าของ)
+func updateUserList(w http.ResponseWriter, r *http.Request, slug string, userID int) {
+	listID, ownerID, _, err := findUserList(slug)
+	if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+		utils.JSONError(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error updating list", http.StatusInternalServerError)
+		return
+	}
+
+	req, ok := decodeUserListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE user_lists SET name = ?, description = ?, visibility = ?, updated_at = NOW() WHERE id = ?
+	`, req.Name, nullIfEmpty(req.Description), req.Visibility, listID)
+	if err == nil && req.Items != nil {
+		err = setUserListItems(tx, listID, *req.Items)
+	}
+	if err != nil {
+		fmt.Printf("❌ Error updating list: %v\n", err)
+		utils.JSONError(w, "Error updating list", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error updating list", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "List updated",
+		"slug":    slug,
+	}, http.StatusOK)
+}
+
+// deleteUserList ลบรายการพร้อมเกมและผู้ติดตาม (เฉพาะเจ้าของ)
+func deleteUserList(w http.ResponseWriter, slug string, userID int) {
+	listID, ownerID, _, err := findUserList(slug)
+	if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+		utils.JSONError(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error deleting list", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"DELETE FROM user_list_items WHERE list_id = ?",
+		"DELETE FROM user_list_follows WHERE list_id = ?",
+		"DELETE FROM user_lists WHERE id = ?",
+	} {
+		if _, err := tx.Exec(query, listID); err != nil {
+			fmt.Printf("❌ Error deleting list: %v\n", err)
+			utils.JSONError(w, "Error deleting list", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error deleting list", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]string{"message": "List deleted"}, http.StatusOK)
+}
+
+// followUserList ติดตาม (POST) หรือเลิกติดตาม (DELETE) รายการสาธารณะของผู้ใช้อื่น
+func followUserList(w http.ResponseWriter, r *http.Request, slug string, userID int) {
+	listID, ownerID, visibility, err := findUserList(slug)
+	if err == sql.ErrNoRows || (err == nil && visibility != VisibilityPublic && ownerID != userID) {
+		utils.JSONError(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error updating follow", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "DELETE" {
+		if _, err := db.Exec("DELETE FROM user_list_follows WHERE list_id = ? AND user_id = ?", listID, userID); err != nil {
+			fmt.Printf("❌ Error unfollowing list: %v\n", err)
+			utils.JSONError(w, "Error updating follow", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, map[string]interface{}{"message": "List unfollowed", "following": false}, http.StatusOK)
+		return
+	}
+
+	if ownerID == userID {
+		utils.JSONError(w, "You cannot follow your own list", http.StatusBadRequest)
+		return
+	}
+	if _, err := db.Exec("INSERT IGNORE INTO user_list_follows (list_id, user_id) VALUES (?, ?)", listID, userID); err != nil {
+		fmt.Printf("❌ Error following list: %v\n", err)
+		utils.JSONError(w, "Error updating follow", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{"message": "List followed", "following": true}, http.StatusOK)
+}
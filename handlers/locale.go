@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// requestLocale ภาษาของ request (?lang= มีลำดับความสำคัญสูงกว่า Accept-Language)
+func requestLocale(r *http.Request) string {
+	return utils.NegotiateLanguage(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+}
+
+// Locale middleware selects the response language
+// Middleware สำหรับเลือกภาษาของ response จาก ?lang= หรือ Accept-Language และตั้ง Content-Language
+// utils.JSONResponse/JSONError ใช้ header นี้แปลข้อความ error/success
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(utils.ContentLanguageHeader, requestLocale(r))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// localizeGames แทนชื่อและคำอธิบายของเกมด้วยคำแปลตามภาษาของ request (เกมที่ไม่มีคำแปลใช้ข้อมูลเดิม)
+func localizeGames(r *http.Request, games []map[string]interface{}) {
+	localizeGamesTo(requestLocale(r), games)
+}
+
+// localizeGamesTo แทนชื่อและคำอธิบายของเกมด้วยคำแปลของภาษาที่ระบุ
+func localizeGamesTo(lang string, games []map[string]interface{}) {
+	if lang == utils.DefaultLanguage || len(games) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(games))
+	for _, game := range games {
+		if id, ok := game["id"].(int); ok {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT game_id, name, description FROM game_translations
+		WHERE locale = ? AND game_id IN (`+strings.Join(ids, ",")+`)
+	`, lang)
+	if err != nil {
+		fmt.Printf("❌ Error fetching game translations: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type translation struct{ name, description string }
+	translated := map[int]translation{}
+	for rows.Next() {
+		var gameID int
+		var name, description sql.NullString
+		if err := rows.Scan(&gameID, &name, &description); err != nil {
+			// แสดงข้อมูลภาษาอังกฤษแทนคำแปลที่อ่านไม่ครบ
+			fmt.Printf("❌ Error scanning game translation: %v\n", err)
+			return
+		}
+		translated[gameID] = translation{name.String, description.String}
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading game translations: %v\n", err)
+		return
+	}
+
+	for _, game := range games {
+		id, _ := game["id"].(int)
+		t, ok := translated[id]
+		if !ok {
+			continue
+		}
+		if t.name != "" {
+			game["name"] = t.name
+		}
+		if _, hasDescription := game["description"]; hasDescription && t.description != "" {
+			game["description"] = t.description
+		}
+	}
+}
+
+// AdminGameTranslationsHandler handles localized game metadata
+// ฟังก์ชันสำหรับจัดการคำแปลชื่อและคำอธิบายของเกม
+// GET /admin/games/{id}/translations, PUT /admin/games/{id}/translations/{locale}, DELETE /admin/games/{id}/translations/{locale}
+func AdminGameTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/games/12/translations/th → gameID = 12, locale = th
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 4 {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	gameID, err := strconv.Atoi(pathParts[2])
+	if err != nil || gameID <= 0 {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	db.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE id = ?)", gameID).Scan(&exists)
+	if !exists {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	if len(pathParts) == 4 {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getGameTranslations(w, gameID)
+		return
+	}
+
+	// คำแปลภาษาอังกฤษคือข้อมูลหลักของเกม แก้ไขผ่าน PUT /admin/games/{id}
+	locale := utils.NormalizeLanguage(pathParts[4])
+	if locale == "" || locale == utils.DefaultLanguage || len(pathParts) > 5 {
+		utils.JSONError(w, fmt.Sprintf("Unsupported locale. Translations can be added for: %s",
+			strings.Join(utils.SupportedLanguages[1:], ", ")), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		saveGameTranslation(w, r, gameID, locale)
+	case "DELETE":
+		deleteGameTranslation(w, r, gameID, locale)
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getGameTranslations คำแปลทั้งหมดของเกม
+func getGameTranslations(w http.ResponseWriter, gameID int) {
+	rows, err := db.Query(`
+		SELECT locale, name, description, updated_at
+		FROM game_translations WHERE game_id = ? ORDER BY locale
+	`, gameID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching game translations: %v\n", err)
+		utils.JSONError(w, "Error fetching translations", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	translations := []map[string]interface{}{}
+	for rows.Next() {
+		var locale string
+		var updatedAt utils.Timestamp
+		var name, description sql.NullString
+		if err := rows.Scan(&locale, &name, &description, &updatedAt); err != nil {
+			fmt.Printf("❌ Error scanning game translation: %v\n", err)
+			utils.JSONError(w, "Error fetching translations", http.StatusInternalServerError)
+			return
+		}
+		translations = append(translations, map[string]interface{}{
+			"locale":      locale,
+			"name":        name.String,
+			"description": description.String,
+			"updated_at":  updatedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading game translations: %v\n", err)
+		utils.JSONError(w, "Error fetching translations", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"game_id":      gameID,
+		"translations": translations,
+	}, http.StatusOK)
+}
+
+// saveGameTranslation เพิ่มหรือแก้ไขคำแปลของเกม (ฟิลด์ว่าง = ใช้ข้อมูลภาษาอังกฤษ)
+func saveGameTranslation(w http.ResponseWriter, r *http.Request, gameID int, locale string) {
+	var req struct {
+		Name        string `json:"name" validate:"max=255"`
+		Description string `json:"description" validate:"max=5000"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	if req.Name == "" && req.Description == "" {
+		utils.JSONError(w, "name or description is required", http.StatusBadRequest)
+		return
+	}
+
+	before := snapshotRow("SELECT * FROM game_translations WHERE game_id = ? AND locale = ?", gameID, locale)
+	_, err := db.Exec(`
+		INSERT INTO game_translations (game_id, locale, name, description) VALUES (?, ?, ?, ?)
+		`+dialect.OnConflict("game_id", "locale")+` `+upsertColumns("name", "description")+`
+	`, gameID, locale, nullIfEmpty(req.Name), nullIfEmpty(req.Description))
+	if err != nil {
+		fmt.Printf("❌ Error saving game translation: %v\n", err)
+		utils.JSONError(w, "Error saving translation", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "translation_save", "game", gameID, before,
+		snapshotRow("SELECT * FROM game_translations WHERE game_id = ? AND locale = ?", gameID, locale))
+	invalidateCatalogCache()
+
+	fmt.Printf("🌐 Game %d translation saved: %s\n", gameID, locale)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Translation saved",
+		"game_id": gameID,
+		"locale":  locale,
+	}, http.StatusOK)
+}
+
+// deleteGameTranslation ลบคำแปลของเกม
+func deleteGameTranslation(w http.ResponseWriter, r *http.Request, gameID int, locale string) {
+	before := snapshotRow("SELECT * FROM game_translations WHERE game_id = ? AND locale = ?", gameID, locale)
+	if before == nil {
+		utils.JSONError(w, "Translation not found", http.StatusNotFound)
+		return
+	}
+	if _, err := db.Exec("DELETE FROM game_translations WHERE game_id = ? AND locale = ?", gameID, locale); err != nil {
+		fmt.Printf("❌ Error deleting game translation: %v\n", err)
+		utils.JSONError(w, "Error deleting translation", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "translation_delete", "game", gameID, before, nil)
+	invalidateCatalogCache()
+
+	utils.JSONResponse(w, map[string]string{"message": "Translation deleted"}, http.StatusOK)
+}
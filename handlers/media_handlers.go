@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/storage"
+	"go-api-game/utils"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mediaStorage คืนค่าชื่อที่เก็บไฟล์จาก URL (cloudinary, s3 หรือ local)
+func mediaStorage(url string) string {
+	return storage.ForURL(url).Name()
+}
+
+// uploadMediaAsset อัพโหลดภาพไปยังที่เก็บไฟล์ที่ตั้งค่าไว้ แล้วบันทึกลงคลังภาพเพื่อให้นำกลับมาใช้ซ้ำได้
+// created = false เมื่อภาพเดียวกันมีอยู่ในคลังแล้ว (ชื่อไฟล์ตามเนื้อหา) ผู้เรียกจึงไม่ควรลบภาพเมื่อบันทึกข้อมูลล้มเหลว
+func uploadMediaAsset(r *http.Request, file multipart.File, header *multipart.FileHeader) (imageURL string, created bool, err error) {
+	imageURL, err = saveImage(r.Context(), file, header)
+	if err != nil {
+		return "", false, err
+	}
+
+	uploadedBy, _ := requestUserID(r)
+	result, err := db.Exec(`
+		INSERT IGNORE INTO media_assets (url, storage, filename, size_bytes, uploaded_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, imageURL, mediaStorage(imageURL), header.Filename, header.Size, uploadedBy)
+	if err != nil {
+		fmt.Printf("⚠️ Error recording media asset: %v\n", err)
+		return imageURL, true, nil
+	}
+	rows, _ := result.RowsAffected()
+	return imageURL, rows > 0, nil
+}
+
+// discardMediaAsset ลบภาพที่เพิ่งอัพโหลดออกทั้งไฟล์และรายการในคลังภาพ (ใช้เมื่อบันทึกข้อมูลล้มเหลว)
+func discardMediaAsset(imageURL string) {
+	if imageURL == "" {
+		return
+	}
+	if err := deleteImage(imageURL); err != nil {
+		fmt.Printf("⚠️ Error deleting image: %v\n", err)
+	}
+	db.Exec("DELETE FROM media_assets WHERE url = ?", imageURL)
+}
+
+// mediaURLByID ดึง URL ของภาพในคลังเพื่อนำไปผูกกับเกม
+func mediaURLByID(id int) (string, error) {
+	var url string
+	err := db.QueryRow("SELECT url FROM media_assets WHERE id = ?", id).Scan(&url)
+	return url, err
+}
+
+// syncMediaLibrary นำภาพที่อัพโหลดไว้ก่อนมีคลังภาพ (ภาพของเกมและไฟล์ใน uploads/) เข้าสู่คลัง
+func syncMediaLibrary() {
+	_, err := db.Exec(`
+		INSERT IGNORE INTO media_assets (url, storage, filename)
+		SELECT DISTINCT image_url,
+		       IF(image_url LIKE '%cloudinary.com%', 'cloudinary', 'local'),
+		       SUBSTRING_INDEX(image_url, '/', -1)
+		FROM games
+		WHERE image_url IS NOT NULL AND image_url != ''
+	`)
+	if err != nil {
+		fmt.Printf("⚠️ Error syncing game images to media library: %v\n", err)
+	}
+
+	// ไฟล์ภาพเกมที่เก็บไว้ในเครื่อง (ไม่รวม avatar ของผู้ใช้)
+	files, err := filepath.Glob(filepath.Join("uploads", "game_*"))
+	if err != nil {
+		return
+	}
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		db.Exec(`
+			INSERT IGNORE INTO media_assets (url, storage, filename, size_bytes, created_at)
+			VALUES (?, 'local', ?, ?, ?)
+		`, "/uploads/"+info.Name(), info.Name(), info.Size(), info.ModTime())
+	}
+}
+
+// AdminMediaHandler handles the admin image library
+// ฟังก์ชันสำหรับจัดการคลังภาพของผู้ดูแลระบบ (นำภาพที่เคยอัพโหลดกลับมาใช้กับเกมอื่นได้)
+func AdminMediaHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	// /admin/media
+	if path == "/admin/media" {
+		switch r.Method {
+		case "GET":
+			getMediaAssets(w, r)
+		case "POST":
+			uploadMedia(w, r)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	// /admin/media/{id}
+	id, err := strconv.Atoi(strings.TrimPrefix(path, "/admin/media/"))
+	if err != nil {
+		utils.JSONError(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != "DELETE" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	deleteMediaAsset(w, r, id)
+}
+
+// GET /admin/media?storage=&unused=true&limit=&offset= - รายการภาพในคลัง
+func getMediaAssets(w http.ResponseWriter, r *http.Request) {
+	syncMediaLibrary()
+
+	query := r.URL.Query()
+
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	whereClauses := []string{}
+	args := []interface{}{}
+	if backend := query.Get("storage"); backend != "" {
+		if backend != "cloudinary" && backend != "s3" && backend != "local" {
+			utils.JSONError(w, "Invalid storage filter. Use cloudinary, s3 or local", http.StatusBadRequest)
+			return
+		}
+		whereClauses = append(whereClauses, "m.storage = ?")
+		args = append(args, backend)
+	}
+	if query.Get("unused") == "true" {
+		whereClauses = append(whereClauses, "NOT EXISTS (SELECT 1 FROM games g WHERE g.image_url = m.url)")
+	}
+
+	whereSQL := ""
+	if len(whereClauses) > 0 {
+		whereSQL = " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM media_assets m"+whereSQL, args...).Scan(&total); err != nil {
+		utils.JSONError(w, "Error fetching media", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.url, m.storage, m.filename, m.size_bytes, m.uploaded_by, m.created_at,
+		       (SELECT COUNT(*) FROM games g WHERE g.image_url = m.url) AS used_by
+		FROM media_assets m`+whereSQL+`
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching media: %v\n", err)
+		utils.JSONError(w, "Error fetching media", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	assets := []map[string]interface{}{}
+	for rows.Next() {
+		var id, usedBy int
+		var url, backend, filename string
+		var createdAt utils.Timestamp
+		var size, uploadedBy sql.NullInt64
+
+		if err := rows.Scan(&id, &url, &backend, &filename, &size, &uploadedBy, &createdAt, &usedBy); err != nil {
+			fmt.Printf("❌ Error scanning media: %v\n", err)
+			utils.JSONError(w, "Error fetching media", http.StatusInternalServerError)
+			return
+		}
+
+		assets = append(assets, map[string]interface{}{
+			"id":          id,
+			"url":         url,
+			"storage":     backend,
+			"filename":    filename,
+			"size_bytes":  size.Int64,
+			"uploaded_by": uploadedBy.Int64,
+			"created_at":  createdAt,
+			"used_by":     usedBy,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading media rows: %v\n", err)
+		utils.JSONError(w, "Error fetching media", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"media":  assets,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(assets),
+	}, http.StatusOK)
+}
+
+// POST /admin/media - อัพโหลดภาพเข้าคลังโดยไม่ต้องผูกกับเกม
+func uploadMedia(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		utils.JSONError(w, "Error parsing form data", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		utils.JSONError(w, "Image file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	imageURL, _, err := uploadMediaAsset(r, file, header)
+	if err != nil {
+		utils.JSONError(w, "Error uploading image: "+err.Error(), uploadErrorStatus(err))
+		return
+	}
+
+	var id int
+	db.QueryRow("SELECT id FROM media_assets WHERE url = ?", imageURL).Scan(&id)
+
+	fmt.Printf("✅ Media uploaded: ID=%d, URL=%s\n", id, imageURL)
+	recordAudit(r, "create", "media", id, nil, snapshotRow("SELECT * FROM media_assets WHERE id = ?", id))
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Image uploaded successfully",
+		"id":      id,
+		"url":     imageURL,
+		"storage": mediaStorage(imageURL),
+	}, http.StatusCreated)
+}
+
+// DELETE /admin/media/{id} - ลบภาพออกจากคลัง (ลบได้เฉพาะภาพที่ไม่มีเกมใช้งานอยู่)
+func deleteMediaAsset(w http.ResponseWriter, r *http.Request, id int) {
+	url, err := mediaURLByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrMediaNotFound, "Media not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching media", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var usedBy int
+	db.QueryRow("SELECT COUNT(*) FROM games WHERE image_url = ?", url).Scan(&usedBy)
+	if usedBy > 0 {
+		utils.JSONError(w, fmt.Sprintf("Image is used by %d game(s)", usedBy), http.StatusConflict)
+		return
+	}
+
+	before := snapshotRow("SELECT * FROM media_assets WHERE id = ?", id)
+	discardMediaAsset(url)
+	recordAudit(r, "delete", "media", id, before, nil)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Image deleted successfully",
+		"id":      id,
+	}, http.StatusOK)
+}
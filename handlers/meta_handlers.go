@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// storeCurrency สกุลเงินของร้าน (ราคาและกระเป๋าเงินใช้สกุลเดียว) ตั้งค่าได้ด้วย
+// STORE_CURRENCY (รหัส ISO 4217 ค่าเริ่มต้น USD) และ STORE_CURRENCY_SYMBOL
+func storeCurrency() map[string]interface{} {
+	code := strings.ToUpper(strings.TrimSpace(os.Getenv("STORE_CURRENCY")))
+	if code == "" {
+		code = "USD"
+	}
+	symbol := os.Getenv("STORE_CURRENCY_SYMBOL")
+	if symbol == "" {
+		symbol = map[string]string{"USD": "$", "THB": "฿", "EUR": "€", "GBP": "£", "JPY": "¥"}[code]
+	}
+	if symbol == "" {
+		symbol = code
+	}
+	return map[string]interface{}{
+		"code":            code,
+		"symbol":          symbol,
+		"decimals":        2,
+		"symbol_position": "before",
+	}
+}
+
+// MetaLocaleHandler returns formatting metadata for frontends
+// ฟังก์ชันสำหรับส่งข้อมูลสกุลเงิน ภาษา การแสดงภาษี และรูปแบบวันที่ที่ backend ใช้
+// เพื่อให้ทุก frontend แสดงผลตรงกับการตั้งค่าของเซิร์ฟเวอร์ (GET /meta/locale)
+func MetaLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	locales := make([]utils.LocaleInfo, 0, len(utils.SupportedLanguages))
+	for _, lang := range utils.SupportedLanguages {
+		locales = append(locales, utils.Locales[lang])
+	}
+
+	// กฎภาษีที่เปิดใช้งาน (ภูมิภาค "*" คืออัตราเริ่มต้น)
+	regions := []map[string]interface{}{}
+	rows, err := db.Query("SELECT region, name, rate FROM tax_rules WHERE active = 1 ORDER BY region = ? DESC, region ASC", defaultTaxRegion)
+	if err != nil {
+		fmt.Printf("❌ Error fetching tax rules: %v\n", err)
+		utils.JSONError(w, "Error fetching locale metadata", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var region, name string
+		var rate float64
+		if err := rows.Scan(&region, &name, &rate); err != nil {
+			fmt.Printf("❌ Error scanning tax rule: %v\n", err)
+			utils.JSONError(w, "Error fetching locale metadata", http.StatusInternalServerError)
+			return
+		}
+		regions = append(regions, map[string]interface{}{"region": region, "name": name, "rate": rate})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading tax rules: %v\n", err)
+		utils.JSONError(w, "Error fetching locale metadata", http.StatusInternalServerError)
+		return
+	}
+	defaultRule, err := resolveTaxRule(db, defaultTaxRegion)
+	if err != nil {
+		fmt.Printf("❌ Error resolving default tax rule: %v\n", err)
+		utils.JSONError(w, "Error fetching locale metadata", http.StatusInternalServerError)
+		return
+	}
+
+	currency := storeCurrency()
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.JSONResponse(w, map[string]interface{}{
+		"currency":             currency,
+		"supported_currencies": []interface{}{currency},
+		"default_locale":       utils.DefaultLanguage,
+		"locale":               requestLocale(r),
+		"locales":              locales,
+		"tax": map[string]interface{}{
+			// ราคาในร้านไม่รวมภาษี ภาษีคิดเพิ่มตอน checkout ตามภูมิภาค (ส่ง "region" ใน /checkout)
+			"prices_include_tax": false,
+			"display":            "exclusive",
+			"calculated_at":      "checkout",
+			"default":            map[string]interface{}{"name": defaultRule.Name, "rate": defaultRule.Rate, "active": defaultRule.Active},
+			"regions":            regions,
+		},
+		// รูปแบบวันที่ที่ API ใช้รับและส่งข้อมูล (Go layout) วันเวลาเป็น UTC เสมอ
+		// (แปลงเป็นเวลาท้องถิ่นด้วย time zone ใน /profile/preferences)
+		"date_formats": map[string]string{
+			"date":      "2006-01-02",
+			"datetime":  "2006-01-02T15:04:05Z",
+			"month":     "2006-01",
+			"timestamp": "RFC3339",
+		},
+	}, http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// MetricsHandler exposes runtime metrics in Prometheus text format
+// ฟังก์ชันสำหรับส่งสถิติ connection pool ของฐานข้อมูล (หลักและ read replica) ให้ Prometheus
+// GET /metrics (Authorization: Bearer <METRICS_TOKEN>) ไม่ตั้ง METRICS_TOKEN = ปิด endpoint
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("METRICS_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pools := map[string]sql.DBStats{"primary": db.Stats()}
+	if replica != nil {
+		pools["replica"] = replica.Stats()
+	}
+
+	var b strings.Builder
+	writeMetric := func(name, kind, help string, value func(s sql.DBStats) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, pool := range []string{"primary", "replica"} {
+			if stats, ok := pools[pool]; ok {
+				fmt.Fprintf(&b, "%s{pool=%q} %g\n", name, pool, value(stats))
+			}
+		}
+	}
+	writeMetric("db_pool_max_open_connections", "gauge", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	writeMetric("db_pool_open_connections", "gauge", "Number of established connections, in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	writeMetric("db_pool_in_use_connections", "gauge", "Number of connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	writeMetric("db_pool_idle_connections", "gauge", "Number of idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	writeMetric("db_pool_wait_count_total", "counter", "Total number of connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	writeMetric("db_pool_wait_duration_seconds_total", "counter", "Total time blocked waiting for a new connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	writeMetric("db_pool_max_idle_closed_total", "counter", "Total connections closed due to SetMaxIdleConns.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	writeMetric("db_pool_max_idle_time_closed_total", "counter", "Total connections closed due to SetConnMaxIdleTime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	writeMetric("db_pool_max_lifetime_closed_total", "counter", "Total connections closed due to SetConnMaxLifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
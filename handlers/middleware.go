@@ -3,67 +3,102 @@ package handlers
 
 import (
 	"fmt"
-	"go-api-game/auth"
 	"go-api-game/utils"
 	"net/http"
-	"strconv"
-	"strings"
 )
 
 // AuthMiddleware verifies user authentication using JWT
 // Middleware สำหรับตรวจสอบการยืนยันตัวตนของผู้ใช้โดยใช้ JWT
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// ดึง Authorization header จาก request
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			utils.JSONError(w, "Authorization header required", http.StatusUnauthorized)
+		// ดึง token จาก Authorization header หรือ cookie (โหมด cookie)
+		tokenString, fromCookie := requestToken(r)
+		if tokenString == "" {
+			if r.Header.Get("Authorization") != "" {
+				utils.JSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+			} else {
+				utils.JSONError(w, "Authorization header required", http.StatusUnauthorized)
+			}
 			return
 		}
 
-		// แยก token จากรูปแบบ "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			utils.JSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+		// request ที่ยืนยันตัวตนด้วย cookie ต้องแนบ CSRF token ใน header
+		if fromCookie && !validCSRF(r) {
+			utils.JSONErrorCode(w, utils.ErrCSRFTokenInvalid, "Invalid or missing CSRF token", http.StatusForbidden)
 			return
 		}
 
-		tokenString := parts[1]
-		fmt.Printf("🔐 Token received: %s...\n", tokenString[:20])
-
-		// ตรวจสอบความถูกต้องของ JWT token
-		claims, err := auth.ValidateToken(tokenString)
+		// ตรวจสอบความถูกต้องของ JWT token และ session (token ที่ถูกเพิกถอนใช้ไม่ได้)
+		claims, err := validateSessionToken(tokenString)
 		if err != nil {
 			fmt.Printf("❌ Token validation failed: %v\n", err)
-			utils.JSONError(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+			utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid token: "+err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		fmt.Printf("✅ Token valid: UserID=%d, Username=%s, Role=%s\n",
-			claims.UserID, claims.Username, claims.Role)
-
-		// เพิ่มข้อมูลผู้ใช้ลงใน headers เพื่อให้ handler ต่อไปใช้ได้
-		r.Header.Set("User-ID", strconv.Itoa(claims.UserID))
-		r.Header.Set("Username", claims.Username)
-		r.Header.Set("Role", claims.Role)
+		// แนบข้อมูลผู้ใช้ไปกับ context เพื่อให้ handler ต่อไปใช้ได้ (ดู requestUserID, requestRole)
+		r = withIdentity(r, Identity{
+			UserID:   claims.UserID,
+			Username: claims.Username,
+			Role:     claims.Role,
+			TokenID:  claims.ID,
+		})
+		applyLocalePreference(w, r, claims.UserID)
 
 		// เรียก handler ต่อไปใน chain
 		next.ServeHTTP(w, r)
 	})
 }
 
+// OptionalAuth middleware attaches the user identity when a valid token is present
+// Middleware สำหรับ endpoint สาธารณะที่แสดงข้อมูลเพิ่มเติมเมื่อผู้ใช้เข้าสู่ระบบ
+// token ที่ไม่ถูกต้องจะถูกข้ามไป (ทำงานแบบผู้ใช้ที่ไม่ได้เข้าสู่ระบบ) แทนการตอบ 401
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, fromCookie := requestToken(r)
+		if tokenString != "" && (!fromCookie || validCSRF(r)) {
+			if claims, err := validateSessionToken(tokenString); err == nil {
+				r = withIdentity(r, Identity{
+					UserID:   claims.UserID,
+					Username: claims.Username,
+					Role:     claims.Role,
+					TokenID:  claims.ID,
+				})
+				applyLocalePreference(w, r, claims.UserID)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // AdminOnly middleware restricts access to admin users
 // Middleware สำหรับจำกัดการเข้าถึงเฉพาะผู้ใช้ที่เป็น admin
 func AdminOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// ดึง Role จาก header (ถูกตั้งค่าโดย AuthMiddleware)
-		role := r.Header.Get("Role")
+		// จำกัด IP ที่เรียก route ของ admin ได้ (ADMIN_ALLOWED_CIDRS)
+		if !adminIPAllowed(r) {
+			fmt.Printf("🚫 Admin request from disallowed address %s: %s %s\n", r.RemoteAddr, r.Method, r.URL.Path)
+			utils.JSONErrorCode(w, utils.ErrForbidden, "Access denied from this network", http.StatusForbidden)
+			return
+		}
+
+		// ดึง Role จาก context (ถูกตั้งค่าโดย AuthMiddleware)
+		role := requestRole(r)
 		if role != "admin" {
 			utils.JSONError(w, "Admin access required", http.StatusForbidden)
 			return
 		}
 
-		// เรียก handler ต่อไปใน chain (เฉพาะ admin)
-		next.ServeHTTP(w, r)
+		// เรียก handler ต่อไปใน chain (เฉพาะ admin) และบันทึก request ที่เปลี่ยนแปลงข้อมูลลง audit log
+		serveAdminRequest(next, w, r)
 	})
 }
+
+// Chain wraps the application routes with the server-wide middleware stack
+// ห่อ route ทั้งหมดด้วย middleware ของ server (ด้านนอกสุดก่อน): request ID, tracing span, access log, ดักจับ panic,
+// ลบ header ตัวตนที่ client ส่งมา (User-ID, Role, ...), บีบอัด response, CORS, ภาษาของ response (Accept-Language / ?lang=),
+// ติดตาม partner referral (?ref=) และเลือก field (?fields=)
+// writer ที่ middleware ห่อต้องส่งต่อ Flush (และ Unwrap) ได้ ไม่เช่นนั้น SSE ของ /events จะไม่ทำงาน
+func Chain(routes http.Handler, cors func(http.Handler) http.Handler) http.Handler {
+	return RequestID(Tracing(AccessLog(Recover(StripIdentityHeaders(Compress(cors(Locale(AffiliateRef(SparseFields(routes))))))))))
+}
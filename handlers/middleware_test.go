@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/cors"
+)
+
+// newEventsTicket ออก ticket ของ /events ให้ผู้ใช้ userID ผ่าน EventsTicketHandler
+func newEventsTicket(t *testing.T, userID int) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	EventsTicketHandler(w, withIdentity(httptest.NewRequest("POST", "/events/ticket", nil), Identity{UserID: userID, Role: "user"}))
+	var resp struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Ticket == "" {
+		t.Fatalf("cannot issue ticket: %s", w.Body)
+	}
+	return resp.Ticket
+}
+
+// readEvent อ่าน event ถัดไปของ SSE (บรรทัดจนถึงบรรทัดว่าง) ภายในเวลาที่กำหนด
+func readEvent(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	var event []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed after %q", event)
+			}
+			if line == "" {
+				return strings.Join(event, "\n")
+			}
+			event = append(event, line)
+		case <-timeout:
+			t.Fatalf("no event flushed within 5s (got %q)", event)
+		}
+	}
+}
+
+// SSE ต้องส่งถึง client ทันทีเมื่อผ่าน middleware ทั้งชุดของ server (writer ทุกตัวต้องส่งต่อ Flush)
+func TestEventsStreamThroughMiddlewareChain(t *testing.T) {
+	// เปิด access log เพื่อให้ accessLogWriter อยู่ใน chain ด้วย
+	accessLog.Lock()
+	previousOut, previousFormat := accessLog.out, accessLog.format
+	accessLog.out, accessLog.format = io.Discard, "common"
+	accessLog.Unlock()
+	t.Cleanup(func() {
+		accessLog.Lock()
+		accessLog.out, accessLog.format = previousOut, previousFormat
+		accessLog.Unlock()
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", EventsHandler)
+	server := httptest.NewServer(Chain(mux, cors.New(cors.Options{}).Handler))
+	defer server.Close()
+
+	for _, encoding := range []string{"", "gzip", "deflate"} {
+		t.Run("encoding="+encoding, func(t *testing.T) {
+			mock := useMockDB(t)
+			mock.ExpectQuery("SELECT wallet_balance FROM users").WithArgs(42).
+				WillReturnRows(sqlmock.NewRows([]string{"wallet_balance"}).AddRow(150.5))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/events?ticket="+newEventsTicket(t, 42), nil)
+			if encoding != "" {
+				req.Header.Set("Accept-Encoding", encoding)
+			}
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("status = %d: %s", resp.StatusCode, body)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("content-type = %q", ct)
+			}
+
+			var body io.Reader = resp.Body
+			switch resp.Header.Get("Content-Encoding") {
+			case "gzip":
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			case "deflate":
+				body = flate.NewReader(resp.Body)
+			}
+			lines := make(chan string)
+			go func() {
+				defer close(lines)
+				scanner := bufio.NewScanner(body)
+				for scanner.Scan() {
+					select {
+					case lines <- scanner.Text():
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			if got := readEvent(t, lines); got != "retry: 5000" {
+				t.Fatalf("first event = %q", got)
+			}
+			if got := readEvent(t, lines); !strings.Contains(got, "event: "+EventWalletBalance) || !strings.Contains(got, "150.5") {
+				t.Fatalf("balance event = %q", got)
+			}
+
+			// event ที่เกิดหลังเชื่อมต่อต้องมาถึงโดยไม่ต้องรอ heartbeat
+			publishToUser(42, EventWalletBalance, map[string]interface{}{"balance": 99})
+			if got := readEvent(t, lines); !strings.Contains(got, `"balance":99`) {
+				t.Fatalf("published event = %q", got)
+			}
+		})
+	}
+}
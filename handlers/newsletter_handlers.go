@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/jobs"
+	"go-api-game/mailer"
+	"go-api-game/utils"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// กลุ่มผู้รับจดหมายข่าว (ผู้รับทุกกลุ่มต้องสมัครรับข่าวสารไว้)
+const (
+	SegmentAll                = "all"                 // ผู้สมัครรับข่าวสารทั้งหมด
+	SegmentCategoryPurchasers = "category_purchasers" // ผู้ที่เคยซื้อเกมในหมวดหมู่ category_id
+)
+
+// JobNewsletterSend งานส่งจดหมายข่าวหนึ่งฉบับให้ผู้รับทั้งกลุ่ม
+const JobNewsletterSend = "newsletter.send"
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// newsletterSecret คีย์สำหรับเซ็นลิงก์ยกเลิกการรับข่าวสาร (NEWSLETTER_SECRET)
+// ต้องตั้งค่าก่อนส่งจดหมายข่าว เพื่อให้ลิงก์ที่ส่งไปแล้วยังใช้ได้หลัง restart
+func newsletterSecret() string {
+	return os.Getenv("NEWSLETTER_SECRET")
+}
+
+// unsubscribeToken ลายเซ็นของอีเมลสำหรับลิงก์ยกเลิกการรับข่าวสาร
+func unsubscribeToken(secret, email string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("newsletter-unsubscribe:" + email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL ลิงก์ยกเลิกการรับข่าวสารที่ใส่ในอีเมล (APP_BASE_URL ค่าเริ่มต้น http://localhost:8080)
+func unsubscribeURL(secret, email string) string {
+	base := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	query := url.Values{"email": {email}, "token": {unsubscribeToken(secret, email)}}
+	return base + "/newsletter/unsubscribe?" + query.Encode()
+}
+
+// NewsletterSubscribeHandler handles newsletter sign-up
+// ฟังก์ชันสำหรับสมัครรับข่าวสาร
+// POST /newsletter/subscribe {"email": "..."} (ผู้ใช้ที่เข้าสู่ระบบไม่ต้องส่ง email จะใช้อีเมลของบัญชี)
+func NewsletterSubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	email := utils.NormalizeEmail(req.Email)
+
+	var userID sql.NullInt64
+	if id, ok := requestUserID(r); ok && email == "" {
+		var accountEmail string
+		if err := db.QueryRow("SELECT email FROM users WHERE id = ?", id).Scan(&accountEmail); err != nil {
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		email = utils.NormalizeEmail(accountEmail)
+	}
+	if !utils.IsValidEmail(email) {
+		utils.JSONError(w, "Invalid email format", http.StatusBadRequest)
+		return
+	}
+
+	// ผูกกับบัญชีที่ใช้อีเมลนี้ (ใช้แบ่งกลุ่มตามประวัติการซื้อ)
+	var accountID int64
+	if err := db.QueryRow("SELECT id FROM users WHERE email = ?", email).Scan(&accountID); err == nil {
+		userID = sql.NullInt64{Int64: accountID, Valid: true}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO newsletter_subscriptions (email, user_id, status) VALUES (?, ?, 'subscribed')
+		`+dialect.OnConflict("email")+`
+			subscribed_at = CASE WHEN newsletter_subscriptions.status = 'subscribed'
+			                     THEN newsletter_subscriptions.subscribed_at ELSE NOW() END,
+			status = 'subscribed',
+			unsubscribed_at = NULL,
+			user_id = COALESCE(`+dialect.Excluded("user_id")+`, newsletter_subscriptions.user_id)
+	`, email, userID)
+	if err != nil {
+		fmt.Printf("❌ Error subscribing %s to newsletter: %v\n", email, err)
+		utils.JSONError(w, "Error updating subscription", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("📰 Newsletter subscription: %s\n", email)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Subscribed to newsletter",
+		"email":   email,
+	}, http.StatusOK)
+}
+
+// NewsletterUnsubscribeHandler handles newsletter opt-out
+// ฟังก์ชันสำหรับยกเลิกการรับข่าวสาร
+// GET/POST /newsletter/unsubscribe?email=&token= - ลิงก์ที่เซ็นแล้วจากอีเมล (POST รองรับ one-click ตาม RFC 8058)
+// POST     /newsletter/unsubscribe              - ผู้ใช้ที่เข้าสู่ระบบยกเลิกอีเมลของบัญชีตัวเอง
+func NewsletterUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	email := utils.NormalizeEmail(query.Get("email"))
+	token := query.Get("token")
+
+	switch {
+	case token != "":
+		secret := newsletterSecret()
+		if secret == "" || email == "" || !hmac.Equal([]byte(token), []byte(unsubscribeToken(secret, email))) {
+			utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+	case r.Method == "POST":
+		userID, ok := requestUserID(r)
+		if !ok {
+			utils.JSONError(w, "Authorization required", http.StatusUnauthorized)
+			return
+		}
+		if err := db.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email); err != nil {
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		email = utils.NormalizeEmail(email)
+	default:
+		utils.JSONErrorCode(w, utils.ErrInvalidToken, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+
+	// อีเมลที่ไม่ได้สมัครไว้ก็ตอบสำเร็จเหมือนกัน (ไม่เปิดเผยว่าอีเมลใดสมัครไว้)
+	_, err := db.Exec(`
+		UPDATE newsletter_subscriptions SET status = 'unsubscribed', unsubscribed_at = NOW()
+		WHERE email = ? AND status = 'subscribed'
+	`, email)
+	if err != nil {
+		fmt.Printf("❌ Error unsubscribing %s: %v\n", email, err)
+		utils.JSONError(w, "Error updating subscription", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("📭 Newsletter unsubscribe: %s\n", email)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Unsubscribed from newsletter",
+		"email":   email,
+	}, http.StatusOK)
+}
+
+// newsletterAudience เงื่อนไขของผู้รับในแต่ละกลุ่ม (ใช้กับ newsletter_subscriptions s)
+func newsletterAudience(segment string, categoryID int) (string, []interface{}, error) {
+	where := "s.status = 'subscribed'"
+	switch segment {
+	case SegmentAll:
+		return where, nil, nil
+	case SegmentCategoryPurchasers:
+		if categoryID <= 0 {
+			return "", nil, fmt.Errorf("category_id is required for segment %s", segment)
+		}
+		return where + ` AND s.user_id IN (
+			SELECT pg.user_id FROM purchased_games pg JOIN games g ON g.id = pg.game_id WHERE g.category_id = ?
+		)`, []interface{}{categoryID}, nil
+	}
+	return "", nil, fmt.Errorf("segment must be %s or %s", SegmentAll, SegmentCategoryPurchasers)
+}
+
+// AdminNewsletterHandler handles newsletter campaigns
+// ฟังก์ชันหลักสำหรับจัดการจดหมายข่าว
+// GET  /admin/newsletters       - รายการจดหมายข่าว
+// GET  /admin/newsletters/{id}  - รายละเอียดจดหมายข่าว
+// POST /admin/newsletters       - สร้างและส่ง (dry_run = นับผู้รับอย่างเดียว)
+func AdminNewsletterHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/newsletters/3 → ["admin", "newsletters", "3"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && r.Method == "GET":
+		listNewsletters(w, r)
+	case len(pathParts) == 2 && r.Method == "POST":
+		createNewsletter(w, r)
+	case len(pathParts) == 3 && r.Method == "GET":
+		id, err := strconv.Atoi(pathParts[2])
+		if err != nil {
+			utils.JSONError(w, "Invalid newsletter ID", http.StatusBadRequest)
+			return
+		}
+		getNewsletter(w, id)
+	case len(pathParts) <= 3:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// POST /admin/newsletters
+// {"subject": "...", "html": "<p>...</p>", "text": "...", "segment": "category_purchasers", "category_id": 2, "dry_run": false}
+func createNewsletter(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Subject    string `json:"subject" validate:"required,max=255"`
+		HTML       string `json:"html" validate:"required"`
+		Text       string `json:"text"`
+		Segment    string `json:"segment" validate:"required"`
+		CategoryID int    `json:"category_id"`
+		DryRun     bool   `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	where, args, err := newsletterAudience(req.Segment, req.CategoryID)
+	if err != nil {
+		utils.JSONErrorCode(w, utils.ErrValidation, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// ไม่ส่งฉบับข้อความมา ใช้ HTML ที่ตัดแท็กออกแทน
+	if strings.TrimSpace(req.Text) == "" {
+		req.Text = strings.TrimSpace(htmlTagPattern.ReplaceAllString(req.HTML, ""))
+	}
+
+	var recipients int
+	if err := db.QueryRow("SELECT COUNT(*) FROM newsletter_subscriptions s WHERE "+where, args...).Scan(&recipients); err != nil {
+		fmt.Printf("❌ Error counting newsletter audience: %v\n", err)
+		utils.JSONError(w, "Error creating newsletter", http.StatusInternalServerError)
+		return
+	}
+
+	if req.DryRun {
+		utils.JSONResponse(w, map[string]interface{}{
+			"dry_run":    true,
+			"segment":    req.Segment,
+			"recipients": recipients,
+		}, http.StatusOK)
+		return
+	}
+	if newsletterSecret() == "" {
+		utils.JSONError(w, "Newsletter sending is not configured (NEWSLETTER_SECRET)", http.StatusServiceUnavailable)
+		return
+	}
+
+	var categoryID interface{}
+	if req.Segment == SegmentCategoryPurchasers {
+		categoryID = req.CategoryID
+	}
+	var createdBy interface{}
+	if adminID, ok := requestUserID(r); ok {
+		createdBy = adminID
+	}
+	result, err := db.Exec(`
+		INSERT INTO newsletter_campaigns (subject, html, text, segment, category_id, recipients, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, req.Subject, req.HTML, req.Text, req.Segment, categoryID, recipients, createdBy)
+	if err != nil {
+		fmt.Printf("❌ Error creating newsletter: %v\n", err)
+		utils.JSONError(w, "Error creating newsletter", http.StatusInternalServerError)
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	if err := jobs.Enqueue(JobNewsletterSend, map[string]interface{}{"campaign_id": id}); err != nil {
+		fmt.Printf("❌ Error queueing newsletter %d: %v\n", id, err)
+		db.Exec("UPDATE newsletter_campaigns SET status = 'failed' WHERE id = ?", id)
+		utils.JSONError(w, "Error queueing newsletter", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "create", "newsletter", id, nil, map[string]interface{}{
+		"subject":     req.Subject,
+		"segment":     req.Segment,
+		"category_id": categoryID,
+		"recipients":  recipients,
+	})
+
+	fmt.Printf("📰 Newsletter %d queued for %d recipients (%s)\n", id, recipients, req.Segment)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":    "Newsletter queued",
+		"id":         id,
+		"status":     "queued",
+		"recipients": recipients,
+	}, http.StatusAccepted)
+}
+
+// newsletterColumns คอลัมน์ที่ใช้แสดงจดหมายข่าว (sent_count นับจากผู้รับที่เข้าคิวส่งแล้ว)
+func newsletterColumns() string {
+	return `
+	c.id, c.subject, c.segment, c.category_id, c.status, c.recipients,
+	(SELECT COUNT(*) FROM newsletter_deliveries d WHERE d.campaign_id = c.id) as sent_count,
+	c.created_at, c.sent_at`
+}
+
+func scanNewsletter(scan func(dest ...interface{}) error) (map[string]interface{}, error) {
+	var id, recipients, sentCount int
+	var subject, segment, status string
+	var categoryID sql.NullInt64
+	var createdAt utils.Timestamp
+	var sentAt utils.NullTimestamp
+	if err := scan(&id, &subject, &segment, &categoryID, &status, &recipients, &sentCount, &createdAt, &sentAt); err != nil {
+		return nil, err
+	}
+	newsletter := map[string]interface{}{
+		"id":          id,
+		"subject":     subject,
+		"segment":     segment,
+		"category_id": nil,
+		"status":      status,
+		"recipients":  recipients,
+		"sent_count":  sentCount,
+		"created_at":  createdAt,
+		"sent_at":     sentAt,
+	}
+	if categoryID.Valid {
+		newsletter["category_id"] = categoryID.Int64
+	}
+	return newsletter, nil
+}
+
+// GET /admin/newsletters - รายการจดหมายข่าว (ใหม่สุดก่อน)
+func listNewsletters(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r.URL.Query(), 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM newsletter_campaigns").Scan(&total); err != nil {
+		utils.JSONError(w, "Error fetching newsletters", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query("SELECT "+newsletterColumns()+" FROM newsletter_campaigns c ORDER BY c.id DESC LIMIT ? OFFSET ?",
+		page.Limit, page.Offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching newsletters: %v\n", err)
+		utils.JSONError(w, "Error fetching newsletters", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	newsletters := []map[string]interface{}{}
+	for rows.Next() {
+		newsletter, err := scanNewsletter(rows.Scan)
+		if err != nil {
+			fmt.Printf("❌ Error scanning newsletter row: %v\n", err)
+			continue
+		}
+		newsletters = append(newsletters, newsletter)
+	}
+
+	utils.JSONResponse(w, paginatedResponse(newsletters, total, page), http.StatusOK)
+}
+
+// GET /admin/newsletters/{id} - รายละเอียดจดหมายข่าวพร้อมเนื้อหา
+func getNewsletter(w http.ResponseWriter, id int) {
+	var html, text string
+	row := db.QueryRow("SELECT "+newsletterColumns()+", c.html, c.text FROM newsletter_campaigns c WHERE c.id = ?", id)
+	newsletter, err := scanNewsletter(func(dest ...interface{}) error {
+		return row.Scan(append(dest, &html, &text)...)
+	})
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Newsletter not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error fetching newsletter", http.StatusInternalServerError)
+		return
+	}
+	newsletter["html"] = html
+	newsletter["text"] = text
+
+	utils.JSONResponse(w, newsletter, http.StatusOK)
+}
+
+// runNewsletterSend ส่งจดหมายข่าวให้ผู้รับในกลุ่ม ผ่านคิวงานส่งอีเมลของ mailer (คนละหนึ่งงาน)
+// ผู้รับที่เข้าคิวไปแล้วถูกบันทึกใน newsletter_deliveries จึงลองใหม่ได้โดยไม่ส่งซ้ำ
+func runNewsletterSend(payload []byte) error {
+	var p struct {
+		CampaignID int `json:"campaign_id"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid newsletter payload: %v", err)
+	}
+	secret := newsletterSecret()
+	if secret == "" {
+		return fmt.Errorf("NEWSLETTER_SECRET is not set")
+	}
+
+	var subject, html, text, segment, status string
+	var categoryID sql.NullInt64
+	err := db.QueryRow(`
+		SELECT subject, html, text, segment, category_id, status FROM newsletter_campaigns WHERE id = ?
+	`, p.CampaignID).Scan(&subject, &html, &text, &segment, &categoryID, &status)
+	if err == sql.ErrNoRows || status == "sent" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading newsletter %d: %v", p.CampaignID, err)
+	}
+
+	where, args, err := newsletterAudience(segment, int(categoryID.Int64))
+	if err != nil {
+		return fmt.Errorf("newsletter %d: %v", p.CampaignID, err)
+	}
+	db.Exec("UPDATE newsletter_campaigns SET status = 'sending' WHERE id = ?", p.CampaignID)
+
+	rows, err := db.Query("SELECT s.email FROM newsletter_subscriptions s WHERE "+where+" ORDER BY s.id", args...)
+	if err != nil {
+		return fmt.Errorf("error loading newsletter recipients: %v", err)
+	}
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning newsletter recipient: %v", err)
+		}
+		emails = append(emails, email)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error loading newsletter recipients: %v", err)
+	}
+
+	queued := 0
+	for _, email := range emails {
+		link := unsubscribeURL(secret, email)
+		msg, err := mailer.Render(mailer.TemplateNewsletter, map[string]interface{}{
+			"Subject":        subject,
+			"Content":        htmltemplate.HTML(html), // เนื้อหาจากผู้ดูแลระบบ ไม่ escape
+			"Text":           text,
+			"UnsubscribeURL": link,
+		})
+		if err != nil {
+			return fmt.Errorf("error rendering newsletter %d: %v", p.CampaignID, err)
+		}
+		msg.To = email
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + link + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+
+		result, err := db.Exec("INSERT IGNORE INTO newsletter_deliveries (campaign_id, email) VALUES (?, ?)", p.CampaignID, email)
+		if err != nil {
+			return fmt.Errorf("error recording newsletter delivery: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue // เข้าคิวไปแล้วในรอบก่อน
+		}
+		mailer.Enqueue(msg)
+		queued++
+	}
+
+	db.Exec("UPDATE newsletter_campaigns SET status = 'sent', sent_at = NOW() WHERE id = ?", p.CampaignID)
+	fmt.Printf("📰 Newsletter %d: %d emails queued\n", p.CampaignID, queued)
+	return nil
+}
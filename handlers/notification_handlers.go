@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/mailer"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ประเภทของการแจ้งเตือน
+const (
+	NotificationPurchaseCompleted = "purchase_completed" // ซื้อเกมสำเร็จ
+	NotificationGiftReceived      = "gift_received"      // ได้รับของขวัญ (เช่น แลกบัตรของขวัญ)
+	NotificationRefundDecision    = "refund_decision"    // ผลการพิจารณาคืนเงิน
+	NotificationPriceDrop         = "price_drop"         // เกมใน wishlist ลดราคา
+	NotificationWalletAdjusted    = "wallet_adjusted"    // ผู้ดูแลระบบปรับยอดเงินในกระเป๋า
+	NotificationLowStock          = "low_stock"          // สต็อกเกมใกล้หมด (แจ้งผู้ดูแลระบบ)
+	NotificationCartReminder      = "cart_reminder"      // มีสินค้าค้างอยู่ในตะกร้า
+	NotificationOrderHeld         = "order_held"         // คำสั่งซื้อถูกพักไว้รอตรวจสอบ
+	NotificationQuestionAnswered  = "question_answered"  // มีคนตอบคำถามเกี่ยวกับเกมที่ผู้ใช้ถาม
+)
+
+// notify สร้างการแจ้งเตือนให้ผู้ใช้
+// การบันทึกที่ล้มเหลวจะไม่กระทบผลลัพธ์ของ request (แค่แสดง log)
+func notify(userID int, notificationType, title, message string, data map[string]interface{}) {
+	var dataJSON interface{}
+	if data != nil {
+		if b, err := json.Marshal(data); err == nil {
+			dataJSON = string(b)
+		}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO notifications (user_id, type, title, message, data)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, notificationType, title, message, dataJSON)
+	if err != nil {
+		fmt.Printf("⚠️ Error creating notification: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🔔 Notification %s for user %d\n", notificationType, userID)
+}
+
+// sendUserMail ส่งอีเมลตาม template ไปยังผู้ใช้ (ดึงอีเมลและชื่อผู้ใช้จากฐานข้อมูล)
+// อีเมลถูกส่งแบบ async ผ่านคิวของ mailer จึงไม่ทำให้ request ช้าลง
+func sendUserMail(userID int, templateName string, data map[string]interface{}) {
+	var username, email string
+	if err := db.QueryRow("SELECT username, email FROM users WHERE id = ?", userID).Scan(&username, &email); err != nil {
+		fmt.Printf("⚠️ Error loading user %d for email: %v\n", userID, err)
+		return
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["Username"] = username
+
+	if err := mailer.SendTemplate(email, templateName, data); err != nil {
+		fmt.Printf("⚠️ Error queueing %s email for user %d: %v\n", templateName, userID, err)
+	}
+}
+
+// NotificationsHandler handles the user notification center
+// ฟังก์ชันหลักสำหรับศูนย์การแจ้งเตือนของผู้ใช้
+// GET   /notifications?unread=true&limit=&offset= - ดึงการแจ้งเตือน
+// PATCH /notifications/{id}/read                  - ทำเครื่องหมายว่าอ่านแล้ว
+func NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /notifications/123/read → ["notifications", "123", "read"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 1 && r.Method == "GET":
+		getNotifications(w, r, userID)
+	case len(pathParts) == 3 && pathParts[2] == "read" && r.Method == "PATCH":
+		id, err := strconv.Atoi(pathParts[1])
+		if err != nil {
+			utils.JSONError(w, "Invalid notification ID", http.StatusBadRequest)
+			return
+		}
+		markNotificationRead(w, userID, id)
+	case len(pathParts) == 1 || len(pathParts) == 3:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// GET /notifications - ดึงการแจ้งเตือนของผู้ใช้ (ใหม่สุดก่อน)
+func getNotifications(w http.ResponseWriter, r *http.Request, userID int) {
+	query := r.URL.Query()
+	page, err := parsePage(query, 20)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	whereSQL := "WHERE user_id = ?"
+	if query.Get("unread") == "true" {
+		whereSQL += " AND read_at IS NULL"
+	}
+
+	var total, unreadCount int
+	err = db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN read_at IS NULL THEN 1 ELSE 0 END), 0)
+		FROM notifications
+		`+whereSQL, userID).Scan(&total, &unreadCount)
+	if err != nil {
+		fmt.Printf("❌ Error counting notifications: %v\n", err)
+		utils.JSONError(w, "Error fetching notifications", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, type, title, message, data, read_at, created_at
+		FROM notifications
+		`+whereSQL+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, userID, page.Limit, page.Offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching notifications: %v\n", err)
+		utils.JSONError(w, "Error fetching notifications", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notifications := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var notificationType, title string
+		var message, data sql.NullString
+		var readAt utils.NullTimestamp
+		var createdAt utils.Timestamp
+
+		if err := rows.Scan(&id, &notificationType, &title, &message, &data, &readAt, &createdAt); err != nil {
+			fmt.Printf("❌ Error scanning notification row: %v\n", err)
+			utils.JSONError(w, "Error fetching notifications", http.StatusInternalServerError)
+			return
+		}
+
+		notification := map[string]interface{}{
+			"id":         id,
+			"type":       notificationType,
+			"title":      title,
+			"message":    message.String,
+			"data":       nil,
+			"read":       readAt.Valid,
+			"read_at":    readAt,
+			"created_at": createdAt,
+		}
+		if data.Valid {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(data.String), &parsed); err == nil {
+				notification["data"] = parsed
+			}
+		}
+
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading notification rows: %v\n", err)
+		utils.JSONError(w, "Error fetching notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := paginatedResponse(notifications, total, page)
+	response["unread_count"] = unreadCount
+	utils.JSONResponse(w, response, http.StatusOK)
+}
+
+// PATCH /notifications/{id}/read - ทำเครื่องหมายว่าอ่านแล้ว (เฉพาะการแจ้งเตือนของตัวเอง)
+func markNotificationRead(w http.ResponseWriter, userID, id int) {
+	var readAt sql.NullString
+	err := db.QueryRow("SELECT read_at FROM notifications WHERE id = ? AND user_id = ?", id, userID).Scan(&readAt)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error checking notification", http.StatusInternalServerError)
+		return
+	}
+
+	if !readAt.Valid {
+		_, err = db.Exec("UPDATE notifications SET read_at = NOW() WHERE id = ? AND user_id = ?", id, userID)
+		if err != nil {
+			utils.JSONError(w, "Error updating notification", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Notification marked as read",
+		"id":      id,
+	}, http.StatusOK)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ownershipAssertionTTL อายุของ assertion (สั้นเพื่อให้เกมที่ถูกคืนเงินหมดสิทธิ์เร็ว)
+const ownershipAssertionTTL = 10 * time.Minute
+
+// maxOwnershipBatch จำนวนเกมสูงสุดต่อการตรวจสอบแบบ batch
+const maxOwnershipBatch = 100
+
+// ownershipSecret คีย์ที่ใช้เซ็น assertion (OWNERSHIP_SECRET) ต้องแชร์ให้ launcher/game server ที่ตรวจสอบ
+func ownershipSecret() string {
+	return os.Getenv("OWNERSHIP_SECRET")
+}
+
+// ownershipResult สถานะการเป็นเจ้าของเกมหนึ่งเกม
+type ownershipResult struct {
+	GameID     int                 `json:"game_id"`
+	Owned      bool                `json:"owned"`
+	OwnedSince utils.NullTimestamp `json:"owned_since"`
+}
+
+// ownershipClaims ข้อมูลใน assertion (iat/exp เป็น Unix seconds)
+type ownershipClaims struct {
+	UserID    int               `json:"user_id"`
+	Games     []ownershipResult `json:"games"`
+	IssuedAt  int64             `json:"iat"`
+	ExpiresAt int64             `json:"exp"`
+}
+
+// signOwnershipAssertion สร้าง assertion รูปแบบ base64url(JSON ของ claims) + "." + hex(HMAC-SHA256 ของ "library-owns:" + ส่วนแรก)
+// ผู้ตรวจสอบคำนวณ HMAC ด้วย OWNERSHIP_SECRET เทียบกับส่วนหลัง แล้วตรวจสอบ exp
+func signOwnershipAssertion(secret string, claims ownershipClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("library-owns:" + encoded))
+	return encoded + "." + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// lookupOwnership ตรวจสอบว่าผู้ใช้เป็นเจ้าของเกมแต่ละเกมหรือไม่ (query เดียว เรียงตามลำดับที่ขอ)
+func lookupOwnership(userID int, gameIDs []int) ([]ownershipResult, error) {
+	ids := make([]string, len(gameIDs))
+	for i, id := range gameIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	rows, err := db.Query(`
+		SELECT game_id, MIN(purchased_at) FROM purchased_games
+		WHERE user_id = ? AND game_id IN (`+strings.Join(ids, ",")+`)
+		GROUP BY game_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owned := map[int]utils.Timestamp{}
+	for rows.Next() {
+		var gameID int
+		var since utils.Timestamp
+		if err := rows.Scan(&gameID, &since); err != nil {
+			return nil, err
+		}
+		owned[gameID] = since
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]ownershipResult, len(gameIDs))
+	for i, gameID := range gameIDs {
+		results[i] = ownershipResult{GameID: gameID}
+		if since, ok := owned[gameID]; ok {
+			results[i].Owned = true
+			results[i].OwnedSince = utils.NullTimestamp{Time: since.Time, Valid: true}
+		}
+	}
+	return results, nil
+}
+
+// LibraryOwnershipHandler returns a signed ownership assertion for launcher and game-server integrations
+// ฟังก์ชันสำหรับตรวจสอบสิทธิ์การเป็นเจ้าของเกมโดยไม่ต้องดึงคลังเกมทั้งหมด
+// GET  /library/owns/{game_id}
+// POST /library/owns {"game_ids": [1, 2, 3]} (สูงสุด 100 เกม)
+// response มี assertion ที่เซ็นด้วย OWNERSHIP_SECRET ให้ client ส่งต่อให้ game server ตรวจสอบเองได้
+func LibraryOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /library/owns/12 → ["library", "owns", "12"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var gameIDs []int
+	switch {
+	case len(pathParts) == 3 && r.Method == "GET":
+		gameID, err := strconv.Atoi(pathParts[2])
+		if err != nil || gameID <= 0 {
+			utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+			return
+		}
+		gameIDs = []int{gameID}
+	case len(pathParts) == 2 && r.Method == "POST":
+		var req struct {
+			GameIDs []int `json:"game_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.GameIDs) == 0 || len(req.GameIDs) > maxOwnershipBatch {
+			utils.JSONError(w, fmt.Sprintf("game_ids must contain 1 to %d games", maxOwnershipBatch), http.StatusBadRequest)
+			return
+		}
+		// ตัดเกมที่ขอซ้ำ (คงลำดับเดิม)
+		seen := map[int]bool{}
+		for _, id := range req.GameIDs {
+			if id <= 0 {
+				utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+				return
+			}
+			if !seen[id] {
+				seen[id] = true
+				gameIDs = append(gameIDs, id)
+			}
+		}
+	case len(pathParts) == 2 || len(pathParts) == 3:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	secret := ownershipSecret()
+	if secret == "" {
+		utils.JSONError(w, "Ownership assertions are not configured (OWNERSHIP_SECRET)", http.StatusServiceUnavailable)
+		return
+	}
+
+	results, err := lookupOwnership(userID, gameIDs)
+	if err != nil {
+		fmt.Printf("❌ Error checking ownership for user %d: %v\n", userID, err)
+		utils.JSONError(w, "Error checking game ownership", http.StatusInternalServerError)
+		return
+	}
+
+	issuedAt := time.Now().Truncate(time.Second)
+	expiresAt := issuedAt.Add(ownershipAssertionTTL)
+	assertion, err := signOwnershipAssertion(secret, ownershipClaims{
+		UserID:    userID,
+		Games:     results,
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		utils.JSONError(w, "Error signing ownership assertion", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("🎫 Ownership assertion for user %d: %d games\n", userID, len(results))
+
+	// ห้าม cache เพราะผลลัพธ์เปลี่ยนได้ทันทีเมื่อซื้อหรือคืนเงิน
+	w.Header().Set("Cache-Control", "no-store")
+	response := map[string]interface{}{
+		"user_id":    userID,
+		"games":      results,
+		"assertion":  assertion,
+		"issued_at":  utils.FormatTimestamp(issuedAt),
+		"expires_at": utils.FormatTimestamp(expiresAt),
+	}
+	if len(pathParts) == 3 {
+		response["game_id"] = results[0].GameID
+		response["owned"] = results[0].Owned
+		response["owned_since"] = results[0].OwnedSince
+	}
+	utils.JSONResponse(w, response, http.StatusOK)
+}
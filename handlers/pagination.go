@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ค่าเริ่มต้นและค่าสูงสุดของ ?limit= สำหรับ endpoint ที่คืนรายการ
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageParams ตำแหน่งของหน้าที่ขอ อ่านจาก ?limit=&offset= หรือ ?cursor= (ค่า next_cursor ของหน้าก่อน)
+type pageParams struct {
+	Limit  int
+	Offset int
+}
+
+// parsePage อ่านค่าการแบ่งหน้า (limit เกินค่าสูงสุดจะถูกปรับเป็น maxPageLimit)
+// defaultLimit = 0 ใช้ defaultPageLimit
+func parsePage(query url.Values, defaultLimit int) (pageParams, error) {
+	page := pageParams{Limit: defaultLimit}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageLimit
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return page, fmt.Errorf("invalid limit. Use a number between 1 and %d", maxPageLimit)
+		}
+		page.Limit = min(limit, maxPageLimit)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		offset, ok := decodePageCursor(cursor)
+		if !ok {
+			return page, fmt.Errorf("invalid cursor")
+		}
+		page.Offset = offset
+	} else if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return page, fmt.Errorf("invalid offset. Use a non-negative number")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+// encodePageCursor สร้าง cursor แบบ opaque ของหน้าถัดไป (client ไม่ควรแปลความหมายเอง)
+func encodePageCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodePageCursor(cursor string) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	value, ok := strings.CutPrefix(string(raw), "o:")
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(value)
+	return offset, err == nil && offset >= 0
+}
+
+// paginatedResponse รูปแบบ response มาตรฐานของ endpoint ที่คืนรายการ
+// {data, total, limit, offset, next_cursor} (next_cursor เป็น null เมื่อเป็นหน้าสุดท้าย)
+func paginatedResponse(data interface{}, total int, page pageParams) map[string]interface{} {
+	var next interface{}
+	if page.Offset+page.Limit < total {
+		next = encodePageCursor(page.Offset + page.Limit)
+	}
+	return map[string]interface{}{
+		"data":        data,
+		"total":       total,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": next,
+	}
+}
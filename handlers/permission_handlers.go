@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// สิทธิ์เพิ่มเติมของ admin (admin ทุกคนใช้ route ของ admin ได้ แต่ route ที่เปิดเผยข้อมูลภายในของระบบต้องได้รับสิทธิ์แยก)
+const (
+	PermissionDiagnostics = "diagnostics" // ดูข้อมูลวินิจฉัยระบบ (/admin/diagnostics)
+)
+
+// adminPermissions สิทธิ์ทั้งหมดที่มอบให้ admin ได้ พร้อมคำอธิบาย
+var adminPermissions = map[string]string{
+	PermissionDiagnostics: "View slow endpoints, slow SQL, goroutines and database pool saturation",
+}
+
+// permissionGranter ตรวจสอบว่าผู้ใช้อยู่ใน ADMIN_PERMISSION_GRANTERS (ID ของ admin คั่นด้วย comma)
+// admin ในรายการนี้มอบสิทธิ์ใดก็ได้ให้ admin คนอื่น ใช้เริ่มต้นระบบเมื่อยังไม่มีใครได้รับสิทธิ์
+func permissionGranter(userID int) bool {
+	for _, entry := range strings.Split(os.Getenv("ADMIN_PERMISSION_GRANTERS"), ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(entry)); err == nil && id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermission ตรวจสอบว่าผู้ใช้ได้รับสิทธิ์นี้หรือไม่
+func hasPermission(userID int, permission string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM admin_permissions WHERE user_id = ? AND permission = ?", userID, permission).Scan(&count)
+	return count > 0, err
+}
+
+// userPermissions สิทธิ์ทั้งหมดของผู้ใช้ (เรียงตามชื่อ)
+func userPermissions(userID int) ([]string, error) {
+	rows, err := db.Query("SELECT permission FROM admin_permissions WHERE user_id = ? ORDER BY permission", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// RequirePermission middleware restricts a route to admins granted a permission
+// Middleware สำหรับจำกัด route ให้เฉพาะ admin ที่ได้รับสิทธิ์ (ใช้ต่อจาก AdminOnly)
+func RequirePermission(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requestUserID(r)
+		if !ok {
+			utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+			return
+		}
+		allowed, err := hasPermission(userID, permission)
+		if err != nil {
+			fmt.Printf("❌ Error checking permission %s for user %d: %v\n", permission, userID, err)
+			utils.JSONError(w, "Error fetching permissions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			utils.JSONErrorDetails(w, utils.ErrForbidden, "Permission required", map[string]interface{}{
+				"permission": permission,
+			}, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminUserPermissionsHandler handles viewing and replacing an admin's permissions
+// ฟังก์ชันสำหรับดูและกำหนดสิทธิ์เพิ่มเติมของ admin (แทนที่รายการเดิมทั้งหมด)
+// GET /admin/users/{id}/permissions
+// PUT /admin/users/{id}/permissions  body: {"permissions": ["diagnostics"]}
+func AdminUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/users/123/permissions → userID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[3] != "permissions" {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var role string
+	if err := db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		permissions, err := userPermissions(userID)
+		if err != nil {
+			fmt.Printf("❌ Error fetching permissions of user %d: %v\n", userID, err)
+			utils.JSONError(w, "Error fetching permissions", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, map[string]interface{}{
+			"user_id":               userID,
+			"permissions":           permissions,
+			"available_permissions": adminPermissions,
+		}, http.StatusOK)
+	case "PUT":
+		setUserPermissions(w, r, userID, role)
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT /admin/users/{id}/permissions - แทนที่สิทธิ์ทั้งหมดของ admin
+// ห้ามแก้สิทธิ์ของตัวเอง และเปลี่ยนได้เฉพาะสิทธิ์ที่ตัวเองมีอยู่ (ยกเว้น admin ใน ADMIN_PERMISSION_GRANTERS)
+func setUserPermissions(w http.ResponseWriter, r *http.Request, userID int, role string) {
+	adminID, _ := requestUserID(r)
+	if adminID == userID {
+		utils.JSONErrorCode(w, utils.ErrForbidden, "You cannot change your own permissions", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, permission := range req.Permissions {
+		permission = strings.TrimSpace(permission)
+		if _, ok := adminPermissions[permission]; !ok {
+			utils.JSONErrorDetails(w, utils.ErrValidation, "Unknown permission", map[string]interface{}{
+				"permission": permission,
+			}, http.StatusBadRequest)
+			return
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	sort.Strings(permissions)
+	if len(permissions) > 0 && role != "admin" {
+		utils.JSONError(w, "Permissions can only be granted to admins", http.StatusBadRequest)
+		return
+	}
+
+	before, err := userPermissions(userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching permissions of user %d: %v\n", userID, err)
+		utils.JSONError(w, "Error fetching permissions", http.StatusInternalServerError)
+		return
+	}
+
+	// สิทธิ์ที่ถูกเพิ่มหรือถอน ผู้แก้ต้องมีสิทธิ์นั้นอยู่แล้ว
+	if !permissionGranter(adminID) {
+		current := map[string]bool{}
+		changed := []string{}
+		for _, permission := range before {
+			current[permission] = true
+			if !seen[permission] {
+				changed = append(changed, permission)
+			}
+		}
+		for _, permission := range permissions {
+			if !current[permission] {
+				changed = append(changed, permission)
+			}
+		}
+		for _, permission := range changed {
+			allowed, err := hasPermission(adminID, permission)
+			if err != nil {
+				fmt.Printf("❌ Error checking permission %s for user %d: %v\n", permission, adminID, err)
+				utils.JSONError(w, "Error fetching permissions", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				utils.JSONErrorDetails(w, utils.ErrForbidden, "You can only grant permissions you hold", map[string]interface{}{
+					"permission": permission,
+				}, http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM admin_permissions WHERE user_id = ?", userID); err != nil {
+		fmt.Printf("❌ Error clearing permissions of user %d: %v\n", userID, err)
+		utils.JSONError(w, "Error updating permissions", http.StatusInternalServerError)
+		return
+	}
+	for _, permission := range permissions {
+		if _, err := tx.Exec("INSERT INTO admin_permissions (user_id, permission, granted_by) VALUES (?, ?, ?)", userID, permission, adminID); err != nil {
+			fmt.Printf("❌ Error granting permission %s to user %d: %v\n", permission, userID, err)
+			utils.JSONError(w, "Error updating permissions", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "permissions_update", "user", userID,
+		map[string]interface{}{"permissions": before},
+		map[string]interface{}{"permissions": permissions})
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "Permissions updated",
+		"user_id":     userID,
+		"permissions": permissions,
+	}, http.StatusOK)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// useMockDB แทน db ของ package ด้วย sqlmock ระหว่างการทดสอบ
+func useMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	previous := db
+	db = mockDB
+	t.Cleanup(func() {
+		db = previous
+		mockDB.Close()
+	})
+	return mock
+}
+
+// permissionsRequest PUT /admin/users/{id}/permissions ในนามของ admin callerID
+func permissionsRequest(callerID int, path, body string) *http.Request {
+	r := httptest.NewRequest("PUT", path, strings.NewReader(body))
+	return withIdentity(r, Identity{UserID: callerID, Username: "admin", Role: "admin"})
+}
+
+func TestSetUserPermissionsRejectsSelfGrant(t *testing.T) {
+	t.Setenv("ADMIN_PERMISSION_GRANTERS", "")
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT role FROM users").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+
+	w := httptest.NewRecorder()
+	AdminUserPermissionsHandler(w, permissionsRequest(5, "/admin/users/5/permissions", `{"permissions": ["diagnostics"]}`))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusForbidden, w.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetUserPermissionsSelfGrantRejectedForGranter(t *testing.T) {
+	t.Setenv("ADMIN_PERMISSION_GRANTERS", "5")
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT role FROM users").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+
+	w := httptest.NewRecorder()
+	AdminUserPermissionsHandler(w, permissionsRequest(5, "/admin/users/5/permissions", `{"permissions": ["diagnostics"]}`))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusForbidden, w.Body)
+	}
+}
+
+func TestSetUserPermissionsRequiresHeldPermission(t *testing.T) {
+	t.Setenv("ADMIN_PERMISSION_GRANTERS", "")
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT role FROM users").WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+	mock.ExpectQuery("SELECT permission FROM admin_permissions").WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM admin_permissions").WithArgs(5, PermissionDiagnostics).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	w := httptest.NewRecorder()
+	AdminUserPermissionsHandler(w, permissionsRequest(5, "/admin/users/6/permissions", `{"permissions": ["diagnostics"]}`))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusForbidden, w.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetUserPermissionsGranterCanGrant(t *testing.T) {
+	t.Setenv("ADMIN_PERMISSION_GRANTERS", "1, 5")
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT role FROM users").WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+	mock.ExpectQuery("SELECT permission FROM admin_permissions").WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM admin_permissions").WithArgs(6).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO admin_permissions").WithArgs(6, PermissionDiagnostics, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	AdminUserPermissionsHandler(w, permissionsRequest(5, "/admin/users/6/permissions", `{"permissions": ["diagnostics"]}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
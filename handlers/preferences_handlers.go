@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"time"
+
+	// ฐานข้อมูล time zone ใน binary (container ที่ไม่มี /usr/share/zoneinfo ก็ใช้ ?tz= ได้)
+	_ "time/tzdata"
+)
+
+// exportTimeLayout รูปแบบวันเวลาที่อ่านง่ายในไฟล์ส่งออก (มี offset กำกับ จึงไม่กำกวม)
+const exportTimeLayout = "2006-01-02 15:04:05 -07:00"
+
+// userPreferences การตั้งค่าภาษาและ time zone ของผู้ใช้
+// API ส่งวันเวลาเป็น ISO-8601 UTC เสมอ time zone ใช้กับเอกสารที่คนอ่าน (CSV/PDF) และให้ frontend แปลงเวลาแสดงผล
+type userPreferences struct {
+	Locale   string `json:"locale" validate:"max=10"`   // ภาษาเมื่อ client ไม่ได้ระบุ ?lang= หรือ Accept-Language (ว่าง = ค่าเริ่มต้นของระบบ)
+	Timezone string `json:"timezone" validate:"max=64"` // ชื่อ IANA เช่น Asia/Bangkok
+}
+
+// getUserPreferences ดึงการตั้งค่าของผู้ใช้ (ผู้ใช้ที่ยังไม่ตั้งค่าใช้ภาษาเริ่มต้นและ UTC)
+func getUserPreferences(userID int) (userPreferences, error) {
+	prefs := userPreferences{Timezone: "UTC"}
+	var locale sql.NullString
+	err := db.QueryRow("SELECT locale, timezone FROM user_preferences WHERE user_id = ?", userID).Scan(&locale, &prefs.Timezone)
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	}
+	prefs.Locale = locale.String
+	return prefs, err
+}
+
+// loadTimezone แปลงชื่อ time zone เป็น *time.Location (ไม่รับ "Local" เพราะขึ้นกับเครื่องที่รัน server)
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// PreferencesHandler handles the locale and time zone preferences of the current user
+// ฟังก์ชันสำหรับดูและแก้ไขภาษาและ time zone ของผู้ใช้
+// GET /profile/preferences, PUT /profile/preferences {"locale": "th", "timezone": "Asia/Bangkok"}
+func PreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := getUserPreferences(userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching preferences: %v\n", err)
+		utils.JSONError(w, "Error fetching preferences", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		utils.JSONResponse(w, map[string]interface{}{"preferences": prefs}, http.StatusOK)
+
+	case "PUT", "PATCH":
+		// ฟิลด์ที่ไม่ได้ส่งมาจะคงค่าเดิมไว้
+		req := prefs
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+
+		details := map[string]string{}
+		if req.Locale != "" {
+			if normalized := utils.NormalizeLanguage(req.Locale); normalized != "" {
+				req.Locale = normalized
+			} else {
+				details["locale"] = fmt.Sprintf("must be one of %v", utils.SupportedLanguages)
+			}
+		}
+		if _, err := loadTimezone(req.Timezone); err != nil {
+			details["timezone"] = "must be an IANA time zone such as UTC or Asia/Bangkok"
+		}
+		if len(details) > 0 {
+			utils.JSONErrorDetails(w, utils.ErrValidation, "Invalid preferences", details, http.StatusBadRequest)
+			return
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO user_preferences (user_id, locale, timezone)
+			VALUES (?, NULLIF(?, ''), ?)
+			`+dialect.OnConflict("user_id")+` `+upsertColumns("locale", "timezone")+`
+		`, userID, req.Locale, req.Timezone)
+		if err != nil {
+			fmt.Printf("❌ Error saving preferences: %v\n", err)
+			utils.JSONError(w, "Error saving preferences", http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Printf("🌐 Preferences updated for user %d: locale=%q timezone=%s\n", userID, req.Locale, req.Timezone)
+		utils.JSONResponse(w, map[string]interface{}{
+			"message":     "Preferences saved",
+			"preferences": req,
+		}, http.StatusOK)
+
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// applyLocalePreference ใช้ภาษาที่ผู้ใช้ตั้งไว้เมื่อ request ไม่ได้ระบุภาษาเอง (?lang= หรือ Accept-Language)
+// ตั้ง Accept-Language ของ request ด้วย เพื่อให้ requestLocale ใน handler ได้ภาษาเดียวกัน
+func applyLocalePreference(w http.ResponseWriter, r *http.Request, userID int) {
+	if r.URL.Query().Get("lang") != "" || r.Header.Get("Accept-Language") != "" {
+		return
+	}
+	var locale sql.NullString
+	if err := db.QueryRow("SELECT locale FROM user_preferences WHERE user_id = ?", userID).Scan(&locale); err != nil || !locale.Valid {
+		return
+	}
+	r.Header.Set("Accept-Language", locale.String)
+	w.Header().Set(utils.ContentLanguageHeader, requestLocale(r))
+}
+
+// exportLocation time zone ของไฟล์ส่งออก: ?tz= ถ้าระบุ ไม่เช่นนั้นใช้ time zone ที่ผู้ใช้ตั้งไว้ (ค่าเริ่มต้น UTC)
+func exportLocation(r *http.Request) (*time.Location, error) {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		return loadTimezone(tz)
+	}
+	if userID, ok := requestUserID(r); ok {
+		if prefs, err := getUserPreferences(userID); err == nil {
+			if loc, err := loadTimezone(prefs.Timezone); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return time.UTC, nil
+}
+
+// formatExportTime แปลงวันเวลาเป็นเวลาใน loc สำหรับไฟล์ส่งออก
+func formatExportTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(exportTimeLayout)
+}
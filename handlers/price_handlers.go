@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBulkPriceItems จำนวนเกมสูงสุดต่อการแก้ราคาหนึ่งครั้งแบบระบุรายการ
+const maxBulkPriceItems = 500
+
+// priceChange การเปลี่ยนราคาของเกมหนึ่งเกม
+type priceChange struct {
+	GameID   int     `json:"game_id"`
+	Name     string  `json:"name"`
+	OldPrice float64 `json:"old_price"`
+	NewPrice float64 `json:"new_price"`
+}
+
+// AdminBulkPriceHandler handles bulk repricing of games
+// ฟังก์ชันสำหรับแก้ราคาเกมหลายเกมพร้อมกัน (เช่น ปรับราคาตามฤดูกาล) ทำใน transaction เดียว
+// PATCH /admin/games/prices
+//
+//	{"prices": [{"game_id": 1, "price": 19.99}, ...]}           กำหนดราคาใหม่รายเกม
+//	{"percent": -20, "category_id": 3}                            ปรับราคาเป็นเปอร์เซ็นต์ตามหมวดหมู่
+//	{"percent": 10, "all": true}                                  ปรับราคาทุกเกม
+//
+// ส่ง "dry_run": true (หรือ ?dry_run=1) เพื่อดูผลลัพธ์ก่อนโดยไม่บันทึก
+func AdminBulkPriceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PATCH" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Prices []struct {
+			GameID int     `json:"game_id"`
+			Price  float64 `json:"price"`
+		} `json:"prices"`
+		Percent    *float64 `json:"percent" validate:"gt=-100,max=1000"`
+		CategoryID int      `json:"category_id"`
+		All        bool     `json:"all"`
+		DryRun     bool     `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+	if dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry_run")); err == nil && dryRun {
+		req.DryRun = true
+	}
+
+	// ตรวจสอบรูปแบบคำขอ: ระบุรายการราคาหรือเปอร์เซ็นต์อย่างใดอย่างหนึ่ง
+	byList := len(req.Prices) > 0
+	if byList == (req.Percent != nil) {
+		utils.JSONErrorCode(w, utils.ErrValidation, "Provide either prices or percent", http.StatusBadRequest)
+		return
+	}
+
+	var where string
+	var args []interface{}
+	newPrices := map[int]float64{}
+	if byList {
+		if len(req.Prices) > maxBulkPriceItems {
+			utils.JSONErrorCode(w, utils.ErrValidation, fmt.Sprintf("At most %d prices per request", maxBulkPriceItems), http.StatusBadRequest)
+			return
+		}
+		ids := make([]string, 0, len(req.Prices))
+		for _, item := range req.Prices {
+			if item.GameID <= 0 || item.Price <= 0 {
+				utils.JSONErrorCode(w, utils.ErrValidation, "Each price needs a game_id and a price greater than 0", http.StatusBadRequest)
+				return
+			}
+			if _, dup := newPrices[item.GameID]; dup {
+				utils.JSONErrorCode(w, utils.ErrValidation, fmt.Sprintf("Duplicate game_id %d", item.GameID), http.StatusBadRequest)
+				return
+			}
+			newPrices[item.GameID] = math.Round(item.Price*100) / 100
+			ids = append(ids, strconv.Itoa(item.GameID))
+		}
+		where = "WHERE g.id IN (" + strings.Join(ids, ",") + ")"
+	} else {
+		if *req.Percent == 0 {
+			utils.JSONErrorCode(w, utils.ErrValidation, "percent must not be 0", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case req.CategoryID > 0:
+			where = "WHERE g.category_id = ?"
+			args = append(args, req.CategoryID)
+		case req.All:
+			where = ""
+		default:
+			// ป้องกันการปรับราคาทั้งร้านโดยไม่ตั้งใจ
+			utils.JSONErrorCode(w, utils.ErrValidation, "Provide category_id, or all: true to reprice every game", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// ล็อกแถวของเกมที่จะแก้ราคาจนกว่าจะ commit
+	rows, err := tx.Query("SELECT g.id, g.name, g.price FROM games g "+where+" ORDER BY g.id FOR UPDATE", args...)
+	if err != nil {
+		fmt.Printf("❌ Error loading games for repricing: %v\n", err)
+		utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+		return
+	}
+	var changes []priceChange
+	found := map[int]bool{}
+	unchanged := 0
+	for rows.Next() {
+		var c priceChange
+		if err := rows.Scan(&c.GameID, &c.Name, &c.OldPrice); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning game for repricing: %v\n", err)
+			utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+			return
+		}
+		found[c.GameID] = true
+		if byList {
+			c.NewPrice = newPrices[c.GameID]
+		} else {
+			c.NewPrice = math.Round(c.OldPrice*(100+*req.Percent)) / 100
+		}
+		if c.NewPrice <= 0 {
+			rows.Close()
+			utils.JSONErrorCode(w, utils.ErrValidation, fmt.Sprintf("New price for game %d would be 0 or less", c.GameID), http.StatusBadRequest)
+			return
+		}
+		if c.NewPrice == c.OldPrice {
+			unchanged++
+			continue
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+		return
+	}
+
+	// เกมที่ไม่พบทำให้ยกเลิกทั้งหมด (ไม่แก้ราคาบางส่วน)
+	if byList {
+		var missing []int
+		for _, item := range req.Prices {
+			if !found[item.GameID] {
+				missing = append(missing, item.GameID)
+			}
+		}
+		if len(missing) > 0 {
+			utils.JSONErrorDetails(w, utils.ErrGameNotFound, "Game not found", map[string]interface{}{"game_ids": missing}, http.StatusNotFound)
+			return
+		}
+	}
+	if changes == nil {
+		changes = []priceChange{}
+	}
+
+	response := map[string]interface{}{
+		"dry_run":   req.DryRun,
+		"changes":   changes,
+		"updated":   len(changes),
+		"unchanged": unchanged,
+	}
+	if req.DryRun {
+		response["message"] = "Preview only. No prices were changed"
+		utils.JSONResponse(w, response, http.StatusOK)
+		return
+	}
+
+	for _, c := range changes {
+		if _, err := tx.Exec("UPDATE games SET price = ? WHERE id = ?", c.NewPrice, c.GameID); err != nil {
+			fmt.Printf("❌ Error updating price of game %d: %v\n", c.GameID, err)
+			utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+			return
+		}
+		if err := recordPriceChange(tx, r, int64(c.GameID), c.OldPrice, c.NewPrice, PriceSourceBulk); err != nil {
+			fmt.Printf("❌ Error recording price history of game %d: %v\n", c.GameID, err)
+			utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error updating prices", http.StatusInternalServerError)
+		return
+	}
+
+	for _, c := range changes {
+		recordAudit(r, "price_update", "game", c.GameID,
+			map[string]interface{}{"price": c.OldPrice}, map[string]interface{}{"price": c.NewPrice})
+		enqueuePriceDrop(int64(c.GameID), c.OldPrice, c.NewPrice)
+	}
+	if len(changes) > 0 {
+		invalidateCatalogCache()
+	}
+
+	fmt.Printf("💲 Bulk price update: %d games changed, %d unchanged\n", len(changes), unchanged)
+	response["message"] = "Prices updated"
+	utils.JSONResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ที่มาของการเปลี่ยนราคาใน game_price_history
+const (
+	PriceSourceCreate = "create" // ราคาเริ่มต้นตอนเพิ่มหรือคัดลอกเกม
+	PriceSourceUpdate = "update" // แก้ไขเกมทาง PUT /admin/games/{id}
+	PriceSourceBulk   = "bulk"   // แก้ราคาหลายเกมทาง PATCH /admin/games/prices
+)
+
+// lowestPriceWindow ช่วงเวลาก่อนลดราคาที่ใช้หาราคาต่ำสุด (ป้าย "ราคาต่ำสุดใน 30 วัน")
+const lowestPriceWindow = 30 * 24 * time.Hour
+
+// ช่วงเวลาของกราฟราคา (?days=)
+const (
+	defaultPriceHistoryDays = 365
+	maxPriceHistoryDays     = 730
+)
+
+// recordPriceChange บันทึกการเปลี่ยนราคาหนึ่งครั้ง (oldPrice < 0 คือราคาเริ่มต้นที่ไม่มีราคาก่อนหน้า)
+// ส่ง tx มาเพื่อให้ประวัติถูกบันทึกพร้อมกับการแก้ราคา ผู้แก้ไขอ่านจาก request (ถ้ามี)
+func recordPriceChange(exec sqlExecutor, r *http.Request, gameID int64, oldPrice, newPrice float64, source string) error {
+	var old interface{}
+	if oldPrice >= 0 {
+		old = oldPrice
+	}
+	var actor interface{}
+	if actorID, ok := requestUserID(r); ok && actorID > 0 {
+		actor = actorID
+	}
+	_, err := exec.Exec(`
+		INSERT INTO game_price_history (game_id, old_price, new_price, source, changed_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, gameID, old, newPrice, source, actor)
+	return err
+}
+
+// pricePoint การเปลี่ยนราคาหนึ่งครั้งในประวัติราคา
+type pricePoint struct {
+	OldPrice  sql.NullFloat64
+	NewPrice  float64
+	ChangedAt time.Time
+}
+
+// saleLabel คำนวณป้ายลดราคาจากประวัติราคา (เรียงตามเวลา)
+// เกมถือว่าลดราคาเมื่อการเปลี่ยนราคาครั้งล่าสุดเป็นการลดราคาภายใน 30 วันที่ผ่านมา
+// lowest คือราคาต่ำสุดที่ใช้อยู่ในช่วง 30 วันก่อนลดราคา (ไม่รวมราคาลดปัจจุบัน)
+func saleLabel(points []pricePoint, now time.Time) (onSale bool, lowest float64) {
+	if len(points) == 0 {
+		return false, 0
+	}
+	last := points[len(points)-1]
+	if !last.OldPrice.Valid || last.NewPrice >= last.OldPrice.Float64 || now.Sub(last.ChangedAt) > lowestPriceWindow {
+		return false, 0
+	}
+
+	// ราคาที่ใช้อยู่ในช่วงเวลานั้นคือราคาก่อนหน้าของการเปลี่ยนแต่ละครั้งในช่วง
+	lowest = last.OldPrice.Float64
+	windowStart := last.ChangedAt.Add(-lowestPriceWindow)
+	for _, p := range points[:len(points)-1] {
+		if p.ChangedAt.Before(windowStart) || !p.OldPrice.Valid {
+			continue
+		}
+		lowest = min(lowest, p.OldPrice.Float64)
+	}
+	return true, lowest
+}
+
+// addPriceLabels เพิ่ม on_sale และ lowest_price_30d ให้กับรายการเกม (ดึงประวัติราคาด้วย query เดียว)
+// lowest_price_30d เป็น null เมื่อเกมไม่ได้ลดราคา
+func addPriceLabels(games []map[string]interface{}) {
+	if len(games) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(games))
+	for _, game := range games {
+		game["on_sale"] = false
+		game["lowest_price_30d"] = nil
+		if id, ok := game["id"].(int); ok {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// ต้องใช้ประวัติย้อนหลัง 60 วัน: การลดราคาใน 30 วัน และช่วง 30 วันก่อนหน้านั้น
+	rows, err := db.Query(`
+		SELECT game_id, old_price, new_price, changed_at
+		FROM game_price_history
+		WHERE game_id IN (` + strings.Join(ids, ",") + `)
+		  AND changed_at >= NOW() - INTERVAL 60 DAY
+		ORDER BY game_id, changed_at, id
+	`)
+	if err != nil {
+		fmt.Printf("⚠️ Error loading price history: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	history := map[int][]pricePoint{}
+	for rows.Next() {
+		var gameID int
+		var p pricePoint
+		var changedAt utils.Timestamp
+		if err := rows.Scan(&gameID, &p.OldPrice, &p.NewPrice, &changedAt); err != nil {
+			// ไม่แสดงป้ายลดราคาดีกว่าแสดงจากประวัติที่ไม่ครบ
+			fmt.Printf("⚠️ Error scanning price history: %v\n", err)
+			return
+		}
+		p.ChangedAt = changedAt.Time
+		history[gameID] = append(history[gameID], p)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("⚠️ Error reading price history: %v\n", err)
+		return
+	}
+
+	now := time.Now()
+	for _, game := range games {
+		id, _ := game["id"].(int)
+		if onSale, lowest := saleLabel(history[id], now); onSale {
+			game["on_sale"] = true
+			game["lowest_price_30d"] = lowest
+		}
+	}
+}
+
+// GamePriceHistoryHandler returns the price chart of a game
+// ฟังก์ชันสำหรับดึงประวัติราคาของเกม (ใช้แสดงกราฟราคา)
+// GET /games/{id}/price-history?days=365
+func GamePriceHistoryHandler(w http.ResponseWriter, r *http.Request, gameID int) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultPriceHistoryDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d < 1 || d > maxPriceHistoryDays {
+			utils.JSONError(w, fmt.Sprintf("Invalid days. Use a number between 1 and %d", maxPriceHistoryDays), http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	var price float64
+	err := db.QueryRow("SELECT price FROM games WHERE id = ? AND status = 'published'", gameID).Scan(&price)
+	if err == sql.ErrNoRows {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error fetching price history", http.StatusInternalServerError)
+		return
+	}
+
+	// ดึงย้อนหลังเพิ่ม 30 วันเพื่อคำนวณราคาต่ำสุดก่อนลดราคา
+	rows, err := db.Query(`
+		SELECT old_price, new_price, changed_at
+		FROM game_price_history
+		WHERE game_id = ? AND changed_at >= NOW() - INTERVAL ? DAY
+		ORDER BY changed_at, id
+	`, gameID, days+30)
+	if err != nil {
+		fmt.Printf("❌ Error fetching price history: %v\n", err)
+		utils.JSONError(w, "Error fetching price history", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var points []pricePoint
+	history := []map[string]interface{}{}
+	since := time.Now().AddDate(0, 0, -days)
+	for rows.Next() {
+		var p pricePoint
+		var changedAt utils.Timestamp
+		if err := rows.Scan(&p.OldPrice, &p.NewPrice, &changedAt); err != nil {
+			fmt.Printf("❌ Error scanning price history: %v\n", err)
+			utils.JSONError(w, "Error fetching price history", http.StatusInternalServerError)
+			return
+		}
+		p.ChangedAt = changedAt.Time
+		points = append(points, p)
+		if p.ChangedAt.Before(since) {
+			continue
+		}
+		var oldPrice interface{}
+		if p.OldPrice.Valid {
+			oldPrice = p.OldPrice.Float64
+		}
+		history = append(history, map[string]interface{}{
+			"price":          p.NewPrice,
+			"previous_price": oldPrice,
+			"changed_at":     changedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading price history: %v\n", err)
+		utils.JSONError(w, "Error fetching price history", http.StatusInternalServerError)
+		return
+	}
+
+	onSale, lowest := saleLabel(points, time.Now())
+	var lowest30d interface{}
+	if onSale {
+		lowest30d = lowest
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"game_id":          gameID,
+		"price":            price,
+		"days":             days,
+		"on_sale":          onSale,
+		"lowest_price_30d": lowest30d,
+		"history":          history,
+	}, http.StatusOK)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strings"
+)
+
+// ระดับการมองเห็นข้อมูลในโปรไฟล์สาธารณะ
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// publicActivityLimit จำนวนกิจกรรมล่าสุดที่แสดงในโปรไฟล์สาธารณะ
+const publicActivityLimit = 10
+
+// privacySettings การตั้งค่าความเป็นส่วนตัวของผู้ใช้ (ผู้ใช้ที่ยังไม่ตั้งค่าถือว่าเป็นส่วนตัวทั้งหมด)
+type privacySettings struct {
+	LibraryVisibility  string `json:"library_visibility" validate:"oneof=public private"`
+	ActivityVisibility string `json:"activity_visibility" validate:"oneof=public private"`
+}
+
+// getPrivacySettings ดึงการตั้งค่าความเป็นส่วนตัวของผู้ใช้
+func getPrivacySettings(userID int) (privacySettings, error) {
+	settings := privacySettings{LibraryVisibility: VisibilityPrivate, ActivityVisibility: VisibilityPrivate}
+	err := db.QueryRow(`
+		SELECT library_visibility, activity_visibility FROM user_privacy_settings WHERE user_id = ?
+	`, userID).Scan(&settings.LibraryVisibility, &settings.ActivityVisibility)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// ProfileSettingsHandler handles the privacy settings of the current user
+// ฟังก์ชันสำหรับดูและแก้ไขการตั้งค่าความเป็นส่วนตัว (คลังเกมและกิจกรรมในโปรไฟล์สาธารณะ)
+// GET /profile/settings, PUT /profile/settings {"library_visibility": "public", "activity_visibility": "private"}
+func ProfileSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := getPrivacySettings(userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching privacy settings: %v\n", err)
+		utils.JSONError(w, "Error fetching privacy settings", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		utils.JSONResponse(w, map[string]interface{}{"privacy": settings}, http.StatusOK)
+
+	case "PUT", "PATCH":
+		// ฟิลด์ที่ไม่ได้ส่งมาจะคงค่าเดิมไว้
+		req := settings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO user_privacy_settings (user_id, library_visibility, activity_visibility)
+			VALUES (?, ?, ?)
+			`+dialect.OnConflict("user_id")+` `+upsertColumns("library_visibility", "activity_visibility")+`
+		`, userID, req.LibraryVisibility, req.ActivityVisibility)
+		if err != nil {
+			fmt.Printf("❌ Error saving privacy settings: %v\n", err)
+			utils.JSONError(w, "Error saving privacy settings", http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Printf("🔒 Privacy settings updated for user %d: library=%s activity=%s\n",
+			userID, req.LibraryVisibility, req.ActivityVisibility)
+		utils.JSONResponse(w, map[string]interface{}{
+			"message": "Privacy settings saved",
+			"privacy": req,
+		}, http.StatusOK)
+
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PublicProfileHandler handles public user profiles
+// ฟังก์ชันสำหรับดูโปรไฟล์สาธารณะของผู้ใช้ (คลังเกมและกิจกรรมแสดงเฉพาะเมื่อเจ้าของเปิดเป็นสาธารณะ)
+// GET /users/{username}
+func PublicProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ตัวอย่าง URL: /users/alice → username = alice
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 2 || pathParts[1] == "" {
+		utils.JSONError(w, "Username required", http.StatusBadRequest)
+		return
+	}
+	username := utils.NormalizeUsername(pathParts[1])
+
+	var userID int
+	var avatarURL sql.NullString
+	var memberSince string
+	err := db.QueryRow(`
+		SELECT id, username, IF(avatar_hidden = 1, NULL, avatar_url), `+dialect.DateFormat("created_at", "%Y-%m-%d")+`
+		FROM users
+		WHERE LOWER(username) = LOWER(?)
+	`, username).Scan(&userID, &username, &avatarURL, &memberSince)
+	if err == sql.ErrNoRows {
+		utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error fetching public profile: %v\n", err)
+		utils.JSONError(w, "Error fetching profile", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := getPrivacySettings(userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching privacy settings: %v\n", err)
+		utils.JSONError(w, "Error fetching profile", http.StatusInternalServerError)
+		return
+	}
+
+	var gamesOwned int
+	db.QueryRow("SELECT COUNT(*) FROM purchased_games WHERE user_id = ?", userID).Scan(&gamesOwned)
+
+	profile := map[string]interface{}{
+		"username":     username,
+		"avatar_url":   avatarURL.String,
+		"member_since": memberSince,
+		"games_owned":  gamesOwned,
+		"privacy":      settings,
+	}
+
+	// เจ้าของโปรไฟล์เห็นข้อมูลของตัวเองเสมอ
+	viewerID, _ := requestUserID(r)
+	isOwner := viewerID == userID
+
+	if isOwner || settings.LibraryVisibility == VisibilityPublic {
+		library, err := publicLibrary(userID)
+		if err != nil {
+			fmt.Printf("❌ Error fetching public library: %v\n", err)
+			utils.JSONError(w, "Error fetching profile", http.StatusInternalServerError)
+			return
+		}
+		profile["library"] = library
+	}
+
+	if isOwner || settings.ActivityVisibility == VisibilityPublic {
+		activity, err := publicActivity(userID)
+		if err != nil {
+			fmt.Printf("❌ Error fetching public activity: %v\n", err)
+			utils.JSONError(w, "Error fetching profile", http.StatusInternalServerError)
+			return
+		}
+		profile["recent_activity"] = activity
+	}
+
+	utils.JSONResponse(w, profile, http.StatusOK)
+}
+
+// publicLibrary รายการเกมในคลังสำหรับโปรไฟล์สาธารณะ (ไม่มีราคาหรือวันที่ซื้อ)
+func publicLibrary(userID int) ([]map[string]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.name, COALESCE(tv.url, g.image_url)
+		FROM purchased_games pg
+		JOIN games g ON pg.game_id = g.id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE pg.user_id = ?
+		ORDER BY g.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name string
+		var thumbnailURL sql.NullString
+		if err := rows.Scan(&id, &name, &thumbnailURL); err != nil {
+			return nil, err
+		}
+		games = append(games, map[string]interface{}{
+			"id":            id,
+			"name":          name,
+			"thumbnail_url": thumbnailURL.String,
+		})
+	}
+	return games, rows.Err()
+}
+
+// publicActivity กิจกรรมล่าสุดสำหรับโปรไฟล์สาธารณะ (เกมที่เพิ่มเข้าคลังล่าสุด)
+func publicActivity(userID int) ([]map[string]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.name, pg.purchased_at
+		FROM purchased_games pg
+		JOIN games g ON pg.game_id = g.id
+		WHERE pg.user_id = ?
+		ORDER BY pg.purchased_at DESC
+		LIMIT ?
+	`, userID, publicActivityLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name string
+		var at utils.Timestamp
+		if err := rows.Scan(&id, &name, &at); err != nil {
+			return nil, err
+		}
+		activity = append(activity, map[string]interface{}{
+			"type":      "game_acquired",
+			"game_id":   id,
+			"game_name": name,
+			"at":        at,
+		})
+	}
+	return activity, rows.Err()
+}
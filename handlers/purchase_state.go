@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// สถานะของคำสั่งซื้อ (purchases.status)
+const (
+	PurchasePending   = "pending"   // สร้างคำสั่งซื้อแล้ว รอชำระเงิน
+	PurchasePaid      = "paid"      // ชำระเงินแล้ว รอส่งมอบเกม
+	PurchaseFulfilled = "fulfilled" // เพิ่มเกมเข้าคลังแล้ว
+	PurchaseRefunded  = "refunded"  // คืนเงินแล้ว
+	PurchaseFailed    = "failed"    // ชำระเงินหรือส่งมอบล้มเหลว
+	PurchaseHeld      = "held"      // ชำระเงินแล้ว แต่ความเสี่ยงสูง รอ admin ตรวจสอบก่อนส่งมอบ (/admin/risk/queue)
+)
+
+// purchaseTransitions การเปลี่ยนสถานะที่อนุญาต (สถานะปัจจุบัน → สถานะถัดไป)
+// refunded และ failed เป็นสถานะสุดท้าย
+var purchaseTransitions = map[string][]string{
+	PurchasePending:   {PurchasePaid, PurchaseFailed},
+	PurchasePaid:      {PurchaseFulfilled, PurchaseHeld, PurchaseRefunded, PurchaseFailed},
+	PurchaseHeld:      {PurchaseFulfilled, PurchaseRefunded},
+	PurchaseFulfilled: {PurchaseRefunded},
+}
+
+// sqlExecutor ส่วนที่ใช้ร่วมกันของ *sql.DB และ *sql.Tx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// canTransitionPurchase ตรวจสอบว่าเปลี่ยนสถานะจาก from เป็น to ได้หรือไม่
+func canTransitionPurchase(from, to string) bool {
+	for _, next := range purchaseTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionPurchase เปลี่ยนสถานะคำสั่งซื้อและบันทึกประวัติ (ใช้ภายใน transaction ได้)
+// อ่านสถานะด้วย FOR UPDATE และคืน error ถ้าการเปลี่ยนสถานะไม่ถูกต้อง
+func transitionPurchase(exec sqlExecutor, purchaseID int64, to, reason string) error {
+	var from string
+	err := exec.QueryRow("SELECT status FROM purchases WHERE id = ? FOR UPDATE", purchaseID).Scan(&from)
+	if err != nil {
+		return fmt.Errorf("purchase #%d: %w", purchaseID, err)
+	}
+	if !canTransitionPurchase(from, to) {
+		return fmt.Errorf("purchase #%d: cannot change status from %s to %s", purchaseID, from, to)
+	}
+
+	if _, err := exec.Exec("UPDATE purchases SET status = ?, status_updated_at = NOW() WHERE id = ?", to, purchaseID); err != nil {
+		return err
+	}
+	_, err = exec.Exec(`
+		INSERT INTO purchase_status_history (purchase_id, from_status, to_status, reason)
+		VALUES (?, ?, ?, ?)
+	`, purchaseID, from, to, reason)
+	return err
+}
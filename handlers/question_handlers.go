@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gameAnswer คำตอบของคำถามหนึ่งข้อ
+type gameAnswer struct {
+	ID          int             `json:"id"`
+	UserID      int             `json:"user_id"`
+	Username    string          `json:"username"`
+	Body        string          `json:"body"`
+	Official    bool            `json:"official"`
+	Highlighted bool            `json:"highlighted"`
+	CreatedAt   utils.Timestamp `json:"created_at"`
+}
+
+// gameQuestion คำถามเกี่ยวกับเกมพร้อมคำตอบ (คำตอบที่ปักหมุดและคำตอบจากผู้ดูแลระบบอยู่ก่อน)
+type gameQuestion struct {
+	ID          int             `json:"id"`
+	GameID      int             `json:"game_id"`
+	UserID      int             `json:"user_id"`
+	Username    string          `json:"username"`
+	Body        string          `json:"body"`
+	AnswerCount int             `json:"answer_count"`
+	Answers     []gameAnswer    `json:"answers"`
+	CreatedAt   utils.Timestamp `json:"created_at"`
+}
+
+// GameQuestionsHandler handles the Q&A section of a game
+// ฟังก์ชันสำหรับคำถามและคำตอบของเกม (ดูได้โดยไม่ต้องเข้าสู่ระบบ ถามและตอบต้องเข้าสู่ระบบ)
+// GET  /games/{id}/questions?limit=&offset=            - คำถามล่าสุดพร้อมคำตอบ
+// POST /games/{id}/questions {"body": "..."}           - ถามคำถาม
+// POST /games/{id}/questions/{qid}/answers {"body": "..."} - ตอบคำถาม (แจ้งเตือนผู้ถาม)
+func GameQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /games/12/questions/5/answers → ["games", "12", "questions", "5", "answers"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	gameID, err := strconv.Atoi(pathParts[1])
+	if err != nil || gameID <= 0 {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(pathParts) == 3 && r.Method == "GET":
+		listGameQuestions(w, r, gameID)
+	case len(pathParts) == 3 && r.Method == "POST":
+		postGameQuestion(w, r, gameID)
+	case len(pathParts) == 5 && pathParts[4] == "answers" && r.Method == "POST":
+		questionID, err := strconv.Atoi(pathParts[3])
+		if err != nil || questionID <= 0 {
+			utils.JSONError(w, "Invalid question ID", http.StatusBadRequest)
+			return
+		}
+		postGameAnswer(w, r, gameID, questionID)
+	case len(pathParts) == 3 || (len(pathParts) == 5 && pathParts[4] == "answers"):
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// publishedGameExists ตรวจสอบว่าเกมเปิดขายอยู่ (ถามคำถามได้เฉพาะเกมที่แสดงในร้านค้า)
+func publishedGameExists(gameID int) (bool, error) {
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM games WHERE id = ? AND status = 'published'", gameID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// listGameQuestions คืนคำถามของเกม (ใหม่สุดก่อน) พร้อมคำตอบทั้งหมดของคำถามในหน้านั้น
+func listGameQuestions(w http.ResponseWriter, r *http.Request, gameID int) {
+	page, err := parsePage(r.URL.Query(), 20)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var total int
+	if err := readQueryRow("SELECT COUNT(*) FROM game_questions WHERE game_id = ?", gameID).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting questions for game %d: %v\n", gameID, err)
+		utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := readQuery(`
+		SELECT q.id, q.game_id, q.user_id, u.username, q.body, q.created_at
+		FROM game_questions q
+		JOIN users u ON u.id = q.user_id
+		WHERE q.game_id = ?
+		ORDER BY q.created_at DESC, q.id DESC
+		LIMIT ? OFFSET ?
+	`, gameID, page.Limit, page.Offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching questions for game %d: %v\n", gameID, err)
+		utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+		return
+	}
+	questions := []gameQuestion{}
+	index := map[int]int{}
+	var ids []string
+	for rows.Next() {
+		q := gameQuestion{Answers: []gameAnswer{}}
+		if err := rows.Scan(&q.ID, &q.GameID, &q.UserID, &q.Username, &q.Body, &q.CreatedAt); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning question: %v\n", err)
+			utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+			return
+		}
+		index[q.ID] = len(questions)
+		ids = append(ids, strconv.Itoa(q.ID))
+		questions = append(questions, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading questions: %v\n", err)
+		utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+		return
+	}
+
+	// ดึงคำตอบของทุกคำถามในหน้านี้ใน query เดียว
+	if len(ids) > 0 {
+		rows, err = readQuery(`
+			SELECT a.id, a.question_id, a.user_id, u.username, a.body, a.official, a.highlighted, a.created_at
+			FROM game_answers a
+			JOIN users u ON u.id = a.user_id
+			WHERE a.question_id IN (` + strings.Join(ids, ",") + `)
+			ORDER BY a.highlighted DESC, a.official DESC, a.created_at ASC, a.id ASC
+		`)
+		if err != nil {
+			fmt.Printf("❌ Error fetching answers for game %d: %v\n", gameID, err)
+			utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			var a gameAnswer
+			var questionID int
+			if err := rows.Scan(&a.ID, &questionID, &a.UserID, &a.Username, &a.Body, &a.Official, &a.Highlighted, &a.CreatedAt); err != nil {
+				rows.Close()
+				fmt.Printf("❌ Error scanning answer: %v\n", err)
+				utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+				return
+			}
+			q := &questions[index[questionID]]
+			q.Answers = append(q.Answers, a)
+			q.AnswerCount++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			fmt.Printf("❌ Error reading answers: %v\n", err)
+			utils.JSONError(w, "Error fetching questions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.JSONResponse(w, paginatedResponse(questions, total, page), http.StatusOK)
+}
+
+// postGameQuestion บันทึกคำถามใหม่ของผู้ใช้
+func postGameQuestion(w http.ResponseWriter, r *http.Request, gameID int) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Body string `json:"body" validate:"required,max=1000"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	exists, err := publishedGameExists(gameID)
+	if err != nil {
+		fmt.Printf("❌ Error checking game %d: %v\n", gameID, err)
+		utils.JSONError(w, "Error posting question", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := db.Exec("INSERT INTO game_questions (game_id, user_id, body) VALUES (?, ?, ?)", gameID, userID, req.Body)
+	if err != nil {
+		fmt.Printf("❌ Error posting question for game %d: %v\n", gameID, err)
+		utils.JSONError(w, "Error posting question", http.StatusInternalServerError)
+		return
+	}
+	questionID, _ := result.LastInsertId()
+
+	fmt.Printf("❓ Question %d posted on game %d by user %d\n", questionID, gameID, userID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Question posted",
+		"id":      questionID,
+		"game_id": gameID,
+	}, http.StatusCreated)
+}
+
+// postGameAnswer บันทึกคำตอบ (คำตอบจากผู้ดูแลระบบถูกทำเครื่องหมายเป็น official) และแจ้งเตือนผู้ถาม
+func postGameAnswer(w http.ResponseWriter, r *http.Request, gameID, questionID int) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Body string `json:"body" validate:"required,max=2000"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	var askerID int
+	var gameName string
+	err := db.QueryRow(`
+		SELECT q.user_id, g.name
+		FROM game_questions q
+		JOIN games g ON g.id = q.game_id
+		WHERE q.id = ? AND q.game_id = ? AND g.status = 'published'
+	`, questionID, gameID).Scan(&askerID, &gameName)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error loading question %d: %v\n", questionID, err)
+		utils.JSONError(w, "Error posting answer", http.StatusInternalServerError)
+		return
+	}
+
+	official := requestRole(r) == "admin"
+	result, err := db.Exec(`
+		INSERT INTO game_answers (question_id, user_id, body, official)
+		VALUES (?, ?, ?, ?)
+	`, questionID, userID, req.Body, official)
+	if err != nil {
+		fmt.Printf("❌ Error posting answer for question %d: %v\n", questionID, err)
+		utils.JSONError(w, "Error posting answer", http.StatusInternalServerError)
+		return
+	}
+	answerID, _ := result.LastInsertId()
+
+	// ไม่แจ้งเตือนเมื่อผู้ถามตอบคำถามของตัวเอง
+	if askerID != userID {
+		notify(askerID, NotificationQuestionAnswered, "Your question was answered",
+			fmt.Sprintf("%s answered your question about %s", requestUsername(r), gameName),
+			map[string]interface{}{"game_id": gameID, "question_id": questionID, "answer_id": answerID, "official": official})
+	}
+
+	fmt.Printf("💬 Answer %d posted on question %d by user %d\n", answerID, questionID, userID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "Answer posted",
+		"id":          answerID,
+		"question_id": questionID,
+		"official":    official,
+	}, http.StatusCreated)
+}
+
+// AdminQuestionsHandler handles moderation of game questions and answers
+// ฟังก์ชันสำหรับผู้ดูแลระบบจัดการคำถามและคำตอบของเกม
+// DELETE /admin/questions/{id}                        - ลบคำถามพร้อมคำตอบทั้งหมด
+// DELETE /admin/questions/answers/{id}                - ลบคำตอบ
+// POST   /admin/questions/answers/{id}/highlight      - ปักหมุดคำตอบ (DELETE เพื่อยกเลิก)
+func AdminQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/questions/answers/7/highlight → ["admin", "questions", "answers", "7", "highlight"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 3 && r.Method == "DELETE":
+		questionID, err := strconv.Atoi(pathParts[2])
+		if err != nil || questionID <= 0 {
+			utils.JSONError(w, "Invalid question ID", http.StatusBadRequest)
+			return
+		}
+		adminDeleteQuestion(w, r, questionID)
+	case len(pathParts) == 4 && pathParts[2] == "answers" && r.Method == "DELETE":
+		answerID, err := strconv.Atoi(pathParts[3])
+		if err != nil || answerID <= 0 {
+			utils.JSONError(w, "Invalid answer ID", http.StatusBadRequest)
+			return
+		}
+		adminDeleteAnswer(w, r, answerID)
+	case len(pathParts) == 5 && pathParts[2] == "answers" && pathParts[4] == "highlight" && (r.Method == "POST" || r.Method == "DELETE"):
+		answerID, err := strconv.Atoi(pathParts[3])
+		if err != nil || answerID <= 0 {
+			utils.JSONError(w, "Invalid answer ID", http.StatusBadRequest)
+			return
+		}
+		adminHighlightAnswer(w, r, answerID, r.Method == "POST")
+	case len(pathParts) >= 3 && len(pathParts) <= 5:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// adminDeleteQuestion ลบคำถามและคำตอบทั้งหมดของคำถามนั้น
+func adminDeleteQuestion(w http.ResponseWriter, r *http.Request, questionID int) {
+	before := snapshotRow("SELECT id, game_id, user_id, body FROM game_questions WHERE id = ?", questionID)
+	if before == nil {
+		utils.JSONError(w, "Question not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM game_answers WHERE question_id = ?", questionID); err != nil {
+		fmt.Printf("❌ Error deleting answers of question %d: %v\n", questionID, err)
+		utils.JSONError(w, "Error deleting question", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM game_questions WHERE id = ?", questionID); err != nil {
+		fmt.Printf("❌ Error deleting question %d: %v\n", questionID, err)
+		utils.JSONError(w, "Error deleting question", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error deleting question", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "question_delete", "game_question", questionID, before, nil)
+	fmt.Printf("🗑️ Question %d deleted\n", questionID)
+	utils.JSONResponse(w, map[string]string{"message": "Question deleted"}, http.StatusOK)
+}
+
+// adminDeleteAnswer ลบคำตอบหนึ่งข้อ
+func adminDeleteAnswer(w http.ResponseWriter, r *http.Request, answerID int) {
+	before := snapshotRow("SELECT id, question_id, user_id, body, official, highlighted FROM game_answers WHERE id = ?", answerID)
+	if before == nil {
+		utils.JSONError(w, "Answer not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM game_answers WHERE id = ?", answerID); err != nil {
+		fmt.Printf("❌ Error deleting answer %d: %v\n", answerID, err)
+		utils.JSONError(w, "Error deleting answer", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "answer_delete", "game_answer", answerID, before, nil)
+	fmt.Printf("🗑️ Answer %d deleted\n", answerID)
+	utils.JSONResponse(w, map[string]string{"message": "Answer deleted"}, http.StatusOK)
+}
+
+// adminHighlightAnswer ปักหมุดหรือยกเลิกการปักหมุดคำตอบ (ปักหมุดได้หลายคำตอบต่อคำถาม)
+func adminHighlightAnswer(w http.ResponseWriter, r *http.Request, answerID int, highlighted bool) {
+	result, err := db.Exec("UPDATE game_answers SET highlighted = ? WHERE id = ?", highlighted, answerID)
+	if err != nil {
+		fmt.Printf("❌ Error highlighting answer %d: %v\n", answerID, err)
+		utils.JSONError(w, "Error updating answer", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var exists int
+		if err := db.QueryRow("SELECT 1 FROM game_answers WHERE id = ?", answerID).Scan(&exists); err == sql.ErrNoRows {
+			utils.JSONError(w, "Answer not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	recordAudit(r, "answer_highlight", "game_answer", answerID, nil, map[string]interface{}{"highlighted": highlighted})
+	message := "Answer highlighted"
+	if !highlighted {
+		message = "Answer unhighlighted"
+	}
+	fmt.Printf("📌 Answer %d highlighted=%v\n", answerID, highlighted)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     message,
+		"id":          answerID,
+		"highlighted": highlighted,
+	}, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/jobs"
+	"time"
+)
+
+// rankingRecomputeDelay ระยะเวลารอรวมคำขอคำนวณอันดับหลายครั้งให้เหลือครั้งเดียว
+const rankingRecomputeDelay = 2 * time.Second
+
+// requestRankingRecompute ขอให้คำนวณ rank_position ใหม่แบบ async ผ่านคิวงาน
+// ใช้แทนการ UPDATE ทั้งตารางภายใน transaction ของ checkout
+// คำขอที่เกิดขึ้นระหว่างที่ยังมีงานรออยู่จะถูกรวมเป็นงานเดียว
+func requestRankingRecompute() {
+	if err := jobs.EnqueueUnique(JobRankingRecompute, rankingRecomputeDelay); err != nil {
+		fmt.Printf("❌ Error requesting ranking recompute: %v\n", err)
+	}
+}
+
+// runRankingRecompute งานคำนวณอันดับใหม่ (ล้าง cache เมื่อสำเร็จ)
+func runRankingRecompute([]byte) error {
+	if err := recomputeRankPositions(); err != nil {
+		return err
+	}
+	invalidateCatalogCache()
+	return nil
+}
+
+// recomputeRankPositions คำนวณ rank_position ของทุกเกมจากยอดขาย
+func recomputeRankPositions() error {
+	_, err := db.Exec(`
+		UPDATE ranking
+		SET rank_position = (
+			SELECT rnk FROM (
+				SELECT game_id, RANK() OVER (ORDER BY sales_count DESC) as rnk
+				FROM ranking
+			) r WHERE r.game_id = ranking.game_id
+		)
+	`)
+	if err == nil {
+		fmt.Println("🏆 Rank positions recomputed")
+	}
+	return err
+}
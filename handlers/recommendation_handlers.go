@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+)
+
+// RecommendationsHandler returns personalized game recommendations
+// ฟังก์ชันสำหรับแนะนำเกมให้ผู้ใช้ (GET /recommendations?limit=10)
+// - co-purchase: "ผู้ที่ซื้อเกม X ก็ซื้อเกม Y ด้วย" จากเกมในคลังของผู้ใช้
+// - category affinity: หมวดหมู่ที่ผู้ใช้มีเกมอยู่มาก
+// - ผู้ใช้ใหม่ที่ยังไม่มีเกม จะได้เกมขายดีแทน
+func RecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _ := requestUserID(r)
+
+	limit := 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+
+	fmt.Printf("🔍 Fetching recommendations for user %d\n", userID)
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, c.name, g.image_url,
+		       COALESCE(tv.url, g.image_url) as thumbnail_url,
+		       COALESCE(cp.co_purchases, 0) as co_purchases,
+		       COALESCE(ca.affinity, 0) as affinity,
+		       COALESCE(r.sales_count, 0) as sales_count
+		FROM games g
+		LEFT JOIN categories c ON g.category_id = c.id
+		LEFT JOIN ranking r ON g.id = r.game_id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		LEFT JOIN (
+			-- เกมที่ผู้ใช้คนอื่นซึ่งมีเกมเดียวกับเราซื้อไว้
+			SELECT other.game_id, COUNT(*) as co_purchases
+			FROM purchased_games mine
+			JOIN purchased_games peer ON peer.game_id = mine.game_id AND peer.user_id != mine.user_id
+			JOIN purchased_games other ON other.user_id = peer.user_id
+			WHERE mine.user_id = ?
+			GROUP BY other.game_id
+		) cp ON cp.game_id = g.id
+		LEFT JOIN (
+			-- จำนวนเกมในคลังของผู้ใช้ในแต่ละหมวดหมู่
+			SELECT owned.category_id, COUNT(*) as affinity
+			FROM purchased_games pg
+			JOIN games owned ON pg.game_id = owned.id
+			WHERE pg.user_id = ?
+			GROUP BY owned.category_id
+		) ca ON ca.category_id = g.category_id
+		WHERE g.status = 'published'
+		  AND g.id NOT IN (SELECT game_id FROM purchased_games WHERE user_id = ?)
+		  AND (cp.co_purchases > 0 OR ca.affinity > 0)
+		ORDER BY COALESCE(cp.co_purchases, 0) * 2 + COALESCE(ca.affinity, 0) DESC, sales_count DESC, g.id
+		LIMIT ?
+	`, userID, userID, userID, limit)
+	if err != nil {
+		fmt.Printf("❌ Error fetching recommendations: %v\n", err)
+		utils.JSONError(w, "Error fetching recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	recommendations, err := scanRecommendations(rows)
+	if err != nil {
+		fmt.Printf("❌ Error reading recommendations: %v\n", err)
+		utils.JSONError(w, "Error fetching recommendations", http.StatusInternalServerError)
+		return
+	}
+	source := "personalized"
+
+	// ผู้ใช้ใหม่หรือไม่มีข้อมูลพอ → เกมขายดีที่ผู้ใช้ยังไม่มี
+	if len(recommendations) == 0 {
+		rows, err = db.Query(`
+			SELECT g.id, g.name, g.price, c.name, g.image_url,
+			       COALESCE(tv.url, g.image_url) as thumbnail_url,
+			       0, 0, COALESCE(r.sales_count, 0) as sales_count
+			FROM games g
+			LEFT JOIN categories c ON g.category_id = c.id
+			LEFT JOIN ranking r ON g.id = r.game_id
+			LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+			WHERE g.status = 'published'
+			  AND g.id NOT IN (SELECT game_id FROM purchased_games WHERE user_id = ?)
+			ORDER BY sales_count DESC, g.id DESC
+			LIMIT ?
+		`, userID, limit)
+		if err != nil {
+			fmt.Printf("❌ Error fetching top sellers: %v\n", err)
+			utils.JSONError(w, "Error fetching recommendations", http.StatusInternalServerError)
+			return
+		}
+		if recommendations, err = scanRecommendations(rows); err != nil {
+			fmt.Printf("❌ Error reading top sellers: %v\n", err)
+			utils.JSONError(w, "Error fetching recommendations", http.StatusInternalServerError)
+			return
+		}
+		source = "top_sellers"
+	}
+
+	fmt.Printf("✅ %d recommendations (%s) for user %d\n", len(recommendations), source, userID)
+	localizeGames(r, recommendations)
+	addPriceLabels(recommendations)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"recommendations": recommendations,
+		"source":          source,
+		"count":           len(recommendations),
+	}, http.StatusOK)
+}
+
+// scanRecommendations อ่านผลลัพธ์และระบุเหตุผลที่แนะนำแต่ละเกม
+func scanRecommendations(rows *sql.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+
+	recommendations := []map[string]interface{}{}
+	for rows.Next() {
+		var id, coPurchases, affinity, salesCount int
+		var name string
+		var price float64
+		var category, imageURL, thumbnailURL sql.NullString
+
+		if err := rows.Scan(&id, &name, &price, &category, &imageURL, &thumbnailURL, &coPurchases, &affinity, &salesCount); err != nil {
+			return nil, err
+		}
+
+		reason := "top_seller"
+		if coPurchases > 0 {
+			reason = "bought_together"
+		} else if affinity > 0 {
+			reason = "category"
+		}
+
+		recommendations = append(recommendations, map[string]interface{}{
+			"id":            id,
+			"name":          name,
+			"price":         price,
+			"category":      category.String,
+			"image_url":     imageURL.String,
+			"thumbnail_url": thumbnailURL.String,
+			"reason":        reason,
+		})
+	}
+	return recommendations, rows.Err()
+}
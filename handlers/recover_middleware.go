@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"go-api-game/errreport"
+	"go-api-game/tracing"
+	"go-api-game/utils"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// panicStackDepth จำนวน frame สูงสุดของ stack ที่ส่งไปกับรายงาน
+const panicStackDepth = 64
+
+// Recover middleware turns handler panics into a 500 JSON error
+// Middleware สำหรับดักจับ panic ของ handler: ตอบ 500 ในรูปแบบ error response มาตรฐาน
+// บันทึก stack ลง log และส่งรายงานไปยังระบบรายงานข้อผิดพลาด (SENTRY_DSN) แทนการตัดการเชื่อมต่อ
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		// ใช้ entry ของ access log เพื่อรู้ผู้ใช้ที่ยืนยันตัวตนใน handler ด้านใน (withIdentity บันทึกลง entry นี้)
+		entry, ok := r.Context().Value(accessLogContextKey).(*accessLogEntry)
+		if !ok {
+			entry = &accessLogEntry{}
+			r = r.WithContext(context.WithValue(r.Context(), accessLogContextKey, entry))
+		}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.ErrAbortHandler ใช้ยกเลิก response โดยตั้งใจ (ไม่ใช่ข้อผิดพลาด)
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			message := fmt.Sprint(rec)
+			fmt.Printf("❌ Panic in %s %s: %s\n%s", r.Method, r.URL.Path, message, debug.Stack())
+
+			pcs := make([]uintptr, panicStackDepth)
+			// ข้าม runtime.Callers, ฟังก์ชันนี้ และ runtime.gopanic
+			n := runtime.Callers(3, pcs)
+			frames := runtime.CallersFrames(pcs[:n])
+			var stack []runtime.Frame
+			for {
+				frame, more := frames.Next()
+				stack = append(stack, frame)
+				if !more {
+					break
+				}
+			}
+
+			headers := map[string]string{}
+			for name := range r.Header {
+				if isSecretField(name) || name == "Cookie" || name == APIKeyHeader {
+					continue
+				}
+				headers[name] = r.Header.Get(name)
+			}
+			tags := map[string]string{"request_id": r.Header.Get(utils.RequestIDHeader)}
+			if span := tracing.FromContext(r.Context()); span != nil {
+				tags["trace_id"] = span.TraceID()
+				span.SetError(fmt.Errorf("panic: %s", message))
+			}
+			url := r.URL.Path
+			if query := loggedQuery(r); query != "" {
+				url += "?" + query
+			}
+			errreport.Capture(errreport.Event{
+				Message: message,
+				Frames:  stack,
+				Method:  r.Method,
+				URL:     url,
+				Headers: headers,
+				UserID:  entry.userID,
+				Tags:    tags,
+			})
+
+			// ส่ง error response ได้เฉพาะเมื่อ handler ยังไม่ได้เริ่มส่ง response
+			if sw.status == 0 {
+				utils.JSONErrorCode(w, utils.ErrInternal, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
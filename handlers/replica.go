@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// replicaHealthInterval ความถี่ในการตรวจสอบว่า read replica ใช้งานได้
+const replicaHealthInterval = 10 * time.Second
+
+// replicaPingTimeout เวลาสูงสุดของการ ping replica แต่ละครั้ง
+const replicaPingTimeout = 2 * time.Second
+
+// replica connection ของ read replica (nil เมื่อไม่ได้ตั้ง DB_READ_DSN)
+var replica *sql.DB
+
+// replicaUp สถานะล่าสุดของ replica (false = ส่ง query ไปที่ฐานข้อมูลหลักแทน)
+var replicaUp atomic.Bool
+
+// InitReadReplica sets the read-only replica connection
+// ฟังก์ชันสำหรับกำหนด read replica ให้ endpoint ที่อ่านข้อมูลจำนวนมาก (/games, /search, /admin/stats)
+// ถ้า replica ล่มจะใช้ฐานข้อมูลหลักแทนจนกว่าการตรวจสอบครั้งถัดไปจะพบว่ากลับมาใช้ได้
+func InitReadReplica(database *sql.DB) {
+	replica = database
+	replicaUp.Store(pingReplica() == nil)
+	if replicaUp.Load() {
+		fmt.Println("✅ Read replica connected")
+	} else {
+		fmt.Println("⚠️ Read replica unavailable, reads use the primary database")
+	}
+
+	go func() {
+		ticker := time.NewTicker(replicaHealthInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := pingReplica()
+			if was := replicaUp.Swap(err == nil); was != (err == nil) {
+				if err == nil {
+					fmt.Println("✅ Read replica is back, routing reads to replica")
+				} else {
+					fmt.Printf("⚠️ Read replica down, routing reads to primary: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+func pingReplica() error {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+	defer cancel()
+	return replica.PingContext(ctx)
+}
+
+// readDB connection สำหรับ query อ่านอย่างเดียวที่ยอมรับข้อมูลช้ากว่าฐานข้อมูลหลักเล็กน้อย (replication lag)
+// ห้ามใช้กับการอ่านที่ตามหลังการเขียนใน request เดียวกัน
+func readDB() *sql.DB {
+	if replica != nil && replicaUp.Load() {
+		return replica
+	}
+	return db
+}
+
+// readQuery เหมือน readDB().Query แต่ถ้า replica เชื่อมต่อไม่ได้จะทำเครื่องหมายว่าล่มและลองใหม่ที่ฐานข้อมูลหลัก
+// error การเชื่อมต่อของฐานข้อมูลหลักลองใหม่ด้วย withRetry
+func readQuery(query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := withRetry(func() (err error) {
+		conn := readDB()
+		rows, err = conn.Query(query, args...)
+		if conn != db && err != nil && isConnectionError(err) {
+			markReplicaDown(err)
+			rows, err = db.Query(query, args...)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// markReplicaDown ทำเครื่องหมายว่า replica ล่ม (การตรวจสอบครั้งถัดไปจะเปิดใช้อีกครั้งเมื่อกลับมา)
+func markReplicaDown(err error) {
+	if replicaUp.Swap(false) {
+		fmt.Printf("⚠️ Read replica query failed, routing reads to primary: %v\n", err)
+	}
+}
+
+// isConnectionError ตรวจสอบว่า error เกิดจากการเชื่อมต่อ (ไม่ใช่ error ของ SQL เช่น syntax)
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
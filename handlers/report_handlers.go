@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ประเภทเนื้อหาที่ผู้ใช้รายงานได้ (avatar ใช้ target_id เป็น id ของผู้ใช้)
+const (
+	ReportTargetGame   = "game"
+	ReportTargetAvatar = "avatar"
+	ReportTargetList   = "list"
+)
+
+// สถานะของรายงาน
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"  // ยืนยันว่าเนื้อหาไม่เหมาะสม และนำเนื้อหาออก
+	ReportStatusDismissed = "dismissed" // เนื้อหาไม่ผิด แสดงตามปกติ
+)
+
+// defaultReportHideThreshold จำนวนรายงานที่ยังไม่ได้ตรวจสอบที่ทำให้เนื้อหาถูกซ่อนอัตโนมัติ (ปรับได้ด้วย REPORT_HIDE_THRESHOLD)
+const defaultReportHideThreshold = 5
+
+func reportHideThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("REPORT_HIDE_THRESHOLD")); err == nil && n > 0 {
+		return n
+	}
+	return defaultReportHideThreshold
+}
+
+// ReportHandler handles content reports from users
+// ฟังก์ชันสำหรับรายงานเนื้อหาไม่เหมาะสม (เกม, รูปโปรไฟล์ หรือรายการเกมของผู้ใช้)
+// POST /reports {"target_type": "game", "target_id": 12, "reason": "offensive", "details": "..."}
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TargetType string `json:"target_type" validate:"required,oneof=game avatar list"`
+		TargetID   int    `json:"target_id" validate:"gt=0"`
+		Reason     string `json:"reason" validate:"required,oneof=spam offensive inappropriate copyright other"`
+		Details    string `json:"details" validate:"max=1000"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	ownerID, err := reportTargetOwner(req.TargetType, req.TargetID)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Reported content not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error checking report target: %v\n", err)
+		utils.JSONError(w, "Error submitting report", http.StatusInternalServerError)
+		return
+	}
+	if ownerID == userID {
+		utils.JSONError(w, "You cannot report your own content", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO content_reports (reporter_id, target_type, target_id, reason, details)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, req.TargetType, req.TargetID, req.Reason, nullIfEmpty(req.Details))
+	if err != nil {
+		if _, dup := duplicateKeyName(err); dup {
+			utils.JSONErrorCode(w, utils.ErrConflict, "You have already reported this content", http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error saving report: %v\n", err)
+		utils.JSONError(w, "Error submitting report", http.StatusInternalServerError)
+		return
+	}
+	reportID, _ := result.LastInsertId()
+	fmt.Printf("🚩 Report #%d: %s %d (%s) by user %d\n", reportID, req.TargetType, req.TargetID, req.Reason, userID)
+
+	// ซ่อนเนื้อหาอัตโนมัติเมื่อมีรายงานที่ยังไม่ได้ตรวจสอบถึงจำนวนที่กำหนด
+	var openReports int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM content_reports WHERE target_type = ? AND target_id = ? AND status = ?
+	`, req.TargetType, req.TargetID, ReportStatusOpen).Scan(&openReports)
+	if openReports >= reportHideThreshold() {
+		if err := setReportTargetHidden(db, req.TargetType, req.TargetID, true); err != nil {
+			fmt.Printf("❌ Error hiding reported content: %v\n", err)
+		} else {
+			fmt.Printf("🙈 %s %d hidden after %d reports\n", req.TargetType, req.TargetID, openReports)
+			if req.TargetType == ReportTargetGame {
+				invalidateCatalogCache()
+			}
+		}
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Report submitted. Thank you for helping keep the store safe",
+		"id":      reportID,
+	}, http.StatusCreated)
+}
+
+// reportTargetOwner ตรวจสอบว่าเนื้อหาที่ถูกรายงานมีอยู่จริง และคืน id ของเจ้าของ (เกมไม่มีเจ้าของ = 0)
+func reportTargetOwner(targetType string, targetID int) (int, error) {
+	var ownerID int
+	var err error
+	switch targetType {
+	case ReportTargetGame:
+		err = db.QueryRow("SELECT 0 FROM games WHERE id = ? AND status IN (?, ?)", targetID, GameStatusPublished, GameStatusHidden).Scan(&ownerID)
+	case ReportTargetAvatar:
+		err = db.QueryRow("SELECT id FROM users WHERE id = ? AND avatar_url IS NOT NULL AND avatar_url != ''", targetID).Scan(&ownerID)
+	case ReportTargetList:
+		err = db.QueryRow("SELECT user_id FROM user_lists WHERE id = ? AND visibility = ?", targetID, VisibilityPublic).Scan(&ownerID)
+	default:
+		err = sql.ErrNoRows
+	}
+	return ownerID, err
+}
+
+// setReportTargetHidden ซ่อน (หรือเลิกซ่อน) เนื้อหาที่ถูกรายงานระหว่างรอ admin ตรวจสอบ
+func setReportTargetHidden(exec sqlExecutor, targetType string, targetID int, hidden bool) error {
+	var err error
+	switch targetType {
+	case ReportTargetGame:
+		from, to := GameStatusPublished, GameStatusHidden
+		if !hidden {
+			from, to = GameStatusHidden, GameStatusPublished
+		}
+		_, err = exec.Exec("UPDATE games SET status = ? WHERE id = ? AND status = ?", to, targetID, from)
+	case ReportTargetAvatar:
+		_, err = exec.Exec("UPDATE users SET avatar_hidden = ? WHERE id = ?", hidden, targetID)
+	case ReportTargetList:
+		_, err = exec.Exec("UPDATE user_lists SET hidden = ? WHERE id = ?", hidden, targetID)
+	}
+	return err
+}
+
+// removeReportTarget นำเนื้อหาที่ยืนยันว่าไม่เหมาะสมออก
+// (เกม → ถอดออกจากร้าน, รูปโปรไฟล์ → เปลี่ยนเป็นรูปสำเร็จรูป, รายการเกม → ซ่อนถาวร)
+func removeReportTarget(exec sqlExecutor, targetType string, targetID int) error {
+	var err error
+	switch targetType {
+	case ReportTargetGame:
+		_, err = exec.Exec(`
+			UPDATE games SET status = ?, delisted_at = NOW() WHERE id = ? AND status IN (?, ?)
+		`, GameStatusDelisted, targetID, GameStatusPublished, GameStatusHidden)
+		if err == nil {
+			_, err = exec.Exec("DELETE FROM cart_items WHERE game_id = ?", targetID)
+		}
+	case ReportTargetAvatar:
+		_, err = exec.Exec(`
+			UPDATE users SET avatar_url = ?, avatar_status = ?, avatar_hidden = 0, avatar_updated_at = NOW() WHERE id = ?
+		`, defaultAvatarFor(strconv.Itoa(targetID)), AvatarStatusApproved, targetID)
+	case ReportTargetList:
+		_, err = exec.Exec("UPDATE user_lists SET hidden = 1 WHERE id = ?", targetID)
+	}
+	return err
+}
+
+// AdminReportHandler handles the content moderation queue
+// ฟังก์ชันสำหรับคิวตรวจสอบรายงานเนื้อหา (จัดกลุ่มตามเนื้อหาที่ถูกรายงาน)
+// GET /admin/reports?status=open&target_type=game, GET /admin/reports/{id},
+// POST /admin/reports/{id}/resolve, POST /admin/reports/{id}/dismiss {"note": "..."}
+func AdminReportHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/reports/5/resolve → id = 5, action = resolve
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) == 2 {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getReportQueue(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[2])
+	if err != nil || id <= 0 {
+		utils.JSONError(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(pathParts) == 3 && r.Method == "GET":
+		getReportDetail(w, id)
+	case len(pathParts) == 4 && r.Method == "POST" && (pathParts[3] == "resolve" || pathParts[3] == "dismiss"):
+		closeReports(w, r, id, pathParts[3])
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getReportQueue รายการเนื้อหาที่ถูกรายงาน เรียงตามจำนวนรายงานมากที่สุด
+func getReportQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status == "" {
+		status = ReportStatusOpen
+	}
+	if status != ReportStatusOpen && status != ReportStatusResolved && status != ReportStatusDismissed {
+		utils.JSONError(w, "Invalid status filter. Use open, resolved or dismissed", http.StatusBadRequest)
+		return
+	}
+
+	where := "WHERE status = ?"
+	args := []interface{}{status}
+	if targetType := query.Get("target_type"); targetType != "" {
+		where += " AND target_type = ?"
+		args = append(args, targetType)
+	}
+
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	rows, err := db.Query(`
+		SELECT MIN(id), target_type, target_id, COUNT(*), GROUP_CONCAT(DISTINCT reason ORDER BY reason),
+		       MIN(created_at), MAX(created_at)
+		FROM content_reports
+		`+where+`
+		GROUP BY target_type, target_id
+		ORDER BY COUNT(*) DESC, MIN(created_at)
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching report queue: %v\n", err)
+		utils.JSONError(w, "Error fetching reports", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	queue := []map[string]interface{}{}
+	for rows.Next() {
+		var reportID, targetID, count int
+		var targetType, reasons string
+		var firstAt, lastAt utils.Timestamp
+		if err := rows.Scan(&reportID, &targetType, &targetID, &count, &reasons, &firstAt, &lastAt); err != nil {
+			fmt.Printf("❌ Error scanning report: %v\n", err)
+			utils.JSONError(w, "Error fetching reports", http.StatusInternalServerError)
+			return
+		}
+		queue = append(queue, map[string]interface{}{
+			"report_id":         reportID,
+			"target_type":       targetType,
+			"target_id":         targetID,
+			"report_count":      count,
+			"reasons":           strings.Split(reasons, ","),
+			"first_reported_at": firstAt,
+			"last_reported_at":  lastAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading report rows: %v\n", err)
+		utils.JSONError(w, "Error fetching reports", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"status":         status,
+		"hide_threshold": reportHideThreshold(),
+		"items":          queue,
+		"limit":          limit,
+		"offset":         offset,
+	}, http.StatusOK)
+}
+
+// getReportDetail รายงานทั้งหมดของเนื้อหาเดียวกับรายงานที่ระบุ พร้อมชื่อผู้รายงาน
+func getReportDetail(w http.ResponseWriter, id int) {
+	var targetType string
+	var targetID int
+	err := db.QueryRow("SELECT target_type, target_id FROM content_reports WHERE id = ?", id).Scan(&targetType, &targetID)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error fetching report", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT cr.id, u.username, cr.reason, cr.details, cr.status, cr.resolution_note,
+		       cr.created_at, cr.resolved_at
+		FROM content_reports cr
+		LEFT JOIN users u ON cr.reporter_id = u.id
+		WHERE cr.target_type = ? AND cr.target_id = ?
+		ORDER BY cr.created_at DESC
+	`, targetType, targetID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching reports: %v\n", err)
+		utils.JSONError(w, "Error fetching report", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	reports := []map[string]interface{}{}
+	for rows.Next() {
+		var reportID int
+		var reason, status string
+		var reporter, details, note sql.NullString
+		var createdAt utils.Timestamp
+		var resolvedAt utils.NullTimestamp
+		if err := rows.Scan(&reportID, &reporter, &reason, &details, &status, &note, &createdAt, &resolvedAt); err != nil {
+			fmt.Printf("❌ Error scanning report: %v\n", err)
+			utils.JSONError(w, "Error fetching report", http.StatusInternalServerError)
+			return
+		}
+		report := map[string]interface{}{
+			"id":              reportID,
+			"reporter":        reporter.String,
+			"reason":          reason,
+			"details":         details.String,
+			"status":          status,
+			"resolution_note": note.String,
+			"created_at":      createdAt,
+			"resolved_at":     resolvedAt,
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading report rows: %v\n", err)
+		utils.JSONError(w, "Error fetching report", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"target_type": targetType,
+		"target_id":   targetID,
+		"reports":     reports,
+	}, http.StatusOK)
+}
+
+// closeReports ปิดรายงานที่เปิดอยู่ทั้งหมดของเนื้อหาเดียวกัน
+// resolve = นำเนื้อหาออก, dismiss = เลิกซ่อนเนื้อหาที่ถูกซ่อนอัตโนมัติ
+func closeReports(w http.ResponseWriter, r *http.Request, id int, action string) {
+	var req struct {
+		Note string `json:"note" validate:"max=500"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+	}
+
+	var targetType string
+	var targetID int
+	err := db.QueryRow("SELECT target_type, target_id FROM content_reports WHERE id = ?", id).Scan(&targetType, &targetID)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error updating report", http.StatusInternalServerError)
+		return
+	}
+
+	adminID, _ := requestUserID(r)
+	status := ReportStatusResolved
+	if action == "dismiss" {
+		status = ReportStatusDismissed
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE content_reports SET status = ?, resolved_by = ?, resolution_note = ?, resolved_at = NOW()
+		WHERE target_type = ? AND target_id = ? AND status = ?
+	`, status, adminID, nullIfEmpty(req.Note), targetType, targetID, ReportStatusOpen)
+	if err != nil {
+		fmt.Printf("❌ Error closing reports: %v\n", err)
+		utils.JSONError(w, "Error updating report", http.StatusInternalServerError)
+		return
+	}
+	closed, _ := result.RowsAffected()
+	if closed == 0 {
+		utils.JSONError(w, "No open reports for this content", http.StatusConflict)
+		return
+	}
+
+	if status == ReportStatusResolved {
+		err = removeReportTarget(tx, targetType, targetID)
+	} else {
+		err = setReportTargetHidden(tx, targetType, targetID, false)
+	}
+	if err != nil {
+		fmt.Printf("❌ Error applying moderation action: %v\n", err)
+		utils.JSONError(w, "Error updating report", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error updating report", http.StatusInternalServerError)
+		return
+	}
+	if targetType == ReportTargetGame {
+		invalidateCatalogCache()
+	}
+
+	recordAudit(r, "report_"+action, targetType, targetID, nil, map[string]interface{}{
+		"reports_closed": closed,
+		"note":           req.Note,
+	})
+
+	fmt.Printf("🚩 %d report(s) on %s %d %s\n", closed, targetType, targetID, status)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":        "Reports " + status,
+		"target_type":    targetType,
+		"target_id":      targetID,
+		"reports_closed": closed,
+	}, http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"go-api-game/utils"
+	"net/http"
+)
+
+// validRequestID รับ X-Request-ID จาก client/proxy เฉพาะค่าที่สั้นและเป็นตัวอักษรที่ปลอดภัย
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID middleware assigns an ID to every request
+// Middleware สำหรับกำหนดรหัสให้ทุก request (ใช้ค่าจาก X-Request-ID ถ้ามี) และส่งกลับใน response header
+// error response จะแนบรหัสนี้ไว้ใน field request_id เพื่อใช้ค้นหาใน log
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(utils.RequestIDHeader)
+		if !validRequestID(id) {
+			idBytes := make([]byte, 8)
+			rand.Read(idBytes)
+			id = hex.EncodeToString(idBytes)
+			r.Header.Set(utils.RequestIDHeader, id)
+		}
+
+		w.Header().Set(utils.RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
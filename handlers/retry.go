@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// การลองใหม่ของการอ่านข้อมูลเมื่อการเชื่อมต่อฐานข้อมูลมีปัญหาชั่วคราว (เช่น connection ถูกตัดตอน MySQL restart)
+const (
+	readRetryAttempts = 3
+	readRetryBaseWait = 50 * time.Millisecond
+)
+
+// withRetry เรียก fn ซ้ำเมื่อเกิด error การเชื่อมต่อ (isConnectionError) โดยรอแบบ exponential backoff พร้อมสุ่ม (jitter)
+// error ของ SQL และ sql.ErrNoRows คืนกลับทันที
+// ใช้กับการอ่านที่เรียกซ้ำได้อย่างปลอดภัยเท่านั้น ห้ามใช้กับ INSERT/UPDATE/DELETE หรือใน transaction
+func withRetry(fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !isConnectionError(err) || attempt == readRetryAttempts {
+			return err
+		}
+		// รอ 50ms, 100ms, ... แบบสุ่มระหว่างครึ่งหนึ่งถึงเต็มค่า เพื่อไม่ให้ทุก request ลองใหม่พร้อมกัน
+		wait := readRetryBaseWait << (attempt - 1)
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		fmt.Printf("⚠️ Database read failed (attempt %d/%d), retrying in %s: %v\n", attempt, readRetryAttempts, wait, err)
+		time.Sleep(wait)
+	}
+}
+
+// retryRow ผลลัพธ์ของ readQueryRow/queryRowRetry (ส่ง query ตอน Scan เพื่อให้ลองใหม่ได้)
+type retryRow struct {
+	replica bool
+	query   string
+	args    []interface{}
+}
+
+// readQueryRow เหมือน readDB().QueryRow แต่ลองใหม่เมื่อการเชื่อมต่อมีปัญหา
+// ถ้า replica เชื่อมต่อไม่ได้จะทำเครื่องหมายว่าล่ม ครั้งที่ลองใหม่จึงใช้ฐานข้อมูลหลัก
+func readQueryRow(query string, args ...interface{}) retryRow {
+	return retryRow{replica: true, query: query, args: args}
+}
+
+// queryRowRetry เหมือน db.QueryRow บนฐานข้อมูลหลัก แต่ลองใหม่เมื่อการเชื่อมต่อมีปัญหา
+func queryRowRetry(query string, args ...interface{}) retryRow {
+	return retryRow{query: query, args: args}
+}
+
+func (r retryRow) Scan(dest ...interface{}) error {
+	return withRetry(func() error {
+		conn := db
+		if r.replica {
+			conn = readDB()
+		}
+		err := conn.QueryRow(r.query, r.args...).Scan(dest...)
+		if conn != db && err != nil && isConnectionError(err) {
+			markReplicaDown(err)
+		}
+		return err
+	})
+}
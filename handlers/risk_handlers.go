@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ประเภทของเหตุการณ์ที่บันทึกในตาราง risk_events
+const (
+	RiskEventDeposit        = "deposit"         // เติมเงินเข้ากระเป๋า
+	RiskEventCheckout       = "checkout"        // สั่งซื้อเกม
+	RiskEventDiscountFailed = "discount_failed" // กรอกรหัสส่วนลดที่ไม่มีอยู่
+)
+
+// สัญญาณความเสี่ยงและคะแนนของแต่ละสัญญาณ (คะแนนรวมสูงสุด 100)
+const (
+	RiskRapidDeposits   = "rapid_deposits"   // เติมเงินหลายครั้งในหนึ่งชั่วโมง
+	RiskDiscountGuesses = "discount_guesses" // กรอกรหัสส่วนลดผิดหลายครั้งในหนึ่งชั่วโมง
+	RiskSharedIP        = "shared_ip"        // หลายบัญชีใช้ IP เดียวกันภายใน 24 ชั่วโมง
+)
+
+var riskSignalScores = map[string]int{
+	RiskRapidDeposits:   50,
+	RiskDiscountGuesses: 40,
+	RiskSharedIP:        40,
+}
+
+// riskSetting อ่านค่าตั้งของการตรวจความเสี่ยงจาก environment (ค่าที่ไม่ถูกต้องใช้ค่าเริ่มต้น)
+func riskSetting(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+// riskHoldScore คะแนนความเสี่ยงที่ทำให้คำสั่งซื้อถูกพักไว้รอ admin ตรวจสอบ (RISK_HOLD_SCORE, ค่าเริ่มต้น 50)
+func riskHoldScore() int {
+	return riskSetting("RISK_HOLD_SCORE", 50)
+}
+
+// riskAssessment ผลการประเมินความเสี่ยงของ request หนึ่งครั้ง
+type riskAssessment struct {
+	Score   int
+	Reasons []string
+}
+
+func (a *riskAssessment) add(signal string) {
+	a.Reasons = append(a.Reasons, signal)
+	if a.Score += riskSignalScores[signal]; a.Score > 100 {
+		a.Score = 100
+	}
+}
+
+// assessRisk ประเมินความเสี่ยงของผู้ใช้จากพฤติกรรมล่าสุด
+// เกณฑ์ปรับได้ด้วย RISK_DEPOSITS_PER_HOUR (5), RISK_DISCOUNT_FAILURES_PER_HOUR (5) และ RISK_ACCOUNTS_PER_IP (3)
+// ถ้าตรวจสอบสัญญาณใดไม่ได้จะข้ามสัญญาณนั้น (แค่แสดง log) เพื่อไม่ให้ผู้ใช้ทั่วไปซื้อไม่ได้
+func assessRisk(r *http.Request, userID int) riskAssessment {
+	var assessment riskAssessment
+
+	var deposits int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM user_transactions
+		WHERE user_id = ? AND type = ? AND created_at >= DATE_SUB(NOW(), INTERVAL 1 HOUR)
+	`, userID, TransactionDeposit).Scan(&deposits)
+	if err != nil {
+		fmt.Printf("⚠️ Error checking recent deposits of user %d: %v\n", userID, err)
+	} else if deposits >= riskSetting("RISK_DEPOSITS_PER_HOUR", 5) {
+		assessment.add(RiskRapidDeposits)
+	}
+
+	var failures int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM risk_events
+		WHERE user_id = ? AND event = ? AND created_at >= DATE_SUB(NOW(), INTERVAL 1 HOUR)
+	`, userID, RiskEventDiscountFailed).Scan(&failures)
+	if err != nil {
+		fmt.Printf("⚠️ Error checking discount failures of user %d: %v\n", userID, err)
+	} else if failures >= riskSetting("RISK_DISCOUNT_FAILURES_PER_HOUR", 5) {
+		assessment.add(RiskDiscountGuesses)
+	}
+
+	// บัญชีที่เข้าสู่ระบบหรือทำรายการจาก IP เดียวกันภายใน 24 ชั่วโมง (รวมผู้ใช้คนนี้)
+	ip := clientIP(r)
+	var accounts int
+	err = db.QueryRow(`
+		SELECT COUNT(DISTINCT user_id) FROM (
+			SELECT user_id FROM account_activity
+			WHERE ip_address = ? AND created_at >= DATE_SUB(NOW(), INTERVAL 1 DAY)
+			UNION
+			SELECT user_id FROM risk_events
+			WHERE ip_address = ? AND created_at >= DATE_SUB(NOW(), INTERVAL 1 DAY)
+			UNION
+			SELECT ? AS user_id
+		) t
+	`, ip, ip, userID).Scan(&accounts)
+	if err != nil {
+		fmt.Printf("⚠️ Error checking accounts sharing IP %s: %v\n", ip, err)
+	} else if accounts > riskSetting("RISK_ACCOUNTS_PER_IP", 3) {
+		assessment.add(RiskSharedIP)
+	}
+
+	return assessment
+}
+
+// recordRiskEvent บันทึกผลการประเมินความเสี่ยง (purchaseID = 0 เมื่อไม่เกี่ยวกับคำสั่งซื้อ)
+// การบันทึกที่ล้มเหลวจะไม่กระทบผลลัพธ์ของ request (แค่แสดง log)
+func recordRiskEvent(r *http.Request, userID int, event string, assessment riskAssessment, purchaseID int64) {
+	var reasons, purchase interface{}
+	if len(assessment.Reasons) > 0 {
+		reasons = strings.Join(assessment.Reasons, ",")
+	}
+	if purchaseID > 0 {
+		purchase = purchaseID
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO risk_events (user_id, event, score, reasons, ip_address, purchase_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, event, assessment.Score, reasons, clientIP(r), purchase)
+	if err != nil {
+		fmt.Printf("⚠️ Error recording risk event: %v\n", err)
+		return
+	}
+
+	if assessment.Score > 0 {
+		fmt.Printf("🚨 Risk %s for user %d: score=%d reasons=%v\n", event, userID, assessment.Score, assessment.Reasons)
+	}
+}
+
+// recordDiscountFailure บันทึกการกรอกรหัสส่วนลดที่ไม่มีอยู่ (ใช้ตรวจจับการสุ่มรหัส)
+func recordDiscountFailure(r *http.Request, userID int) {
+	recordRiskEvent(r, userID, RiskEventDiscountFailed, riskAssessment{}, 0)
+}
+
+// AdminRiskQueueHandler handles the review queue of orders held by risk checks
+// ฟังก์ชันสำหรับตรวจสอบคำสั่งซื้อที่ถูกพักไว้เพราะมีความเสี่ยงสูง
+// GET  /admin/risk/queue?limit=&offset=
+// POST /admin/risk/queue/{purchase_id}/approve - ส่งมอบเกมเข้าคลัง
+// POST /admin/risk/queue/{purchase_id}/reject  - คืนเงินเข้ากระเป๋าและคืนสต็อก
+func AdminRiskQueueHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/risk/queue/12/approve → purchaseID = 12, action = approve
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) == 3 {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getRiskQueue(w, r)
+		return
+	}
+
+	purchaseID, err := strconv.ParseInt(pathParts[3], 10, 64)
+	if err != nil || purchaseID <= 0 || len(pathParts) != 5 {
+		utils.JSONError(w, "Invalid purchase ID", http.StatusBadRequest)
+		return
+	}
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch pathParts[4] {
+	case "approve":
+		approveHeldPurchase(w, r, purchaseID)
+	case "reject":
+		rejectHeldPurchase(w, r, purchaseID)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getRiskQueue รายการคำสั่งซื้อที่ถูกพักไว้ (เก่าสุดก่อน) พร้อมคะแนนและเหตุผลของการประเมินความเสี่ยง
+func getRiskQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.user_id, u.username, u.email, p.final_amount + p.tax_amount, p.purchase_date,
+		       COALESCE(re.score, 0), re.reasons, re.ip_address
+		FROM purchases p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN risk_events re ON re.purchase_id = p.id AND re.event = ?
+		WHERE p.status = ?
+		ORDER BY p.purchase_date, p.id
+		LIMIT ? OFFSET ?
+	`, RiskEventCheckout, PurchaseHeld, limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching risk queue: %v\n", err)
+		utils.JSONError(w, "Error fetching held orders", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	orders := []map[string]interface{}{}
+	for rows.Next() {
+		var purchaseID int64
+		var userID, score int
+		var username, email string
+		var amount float64
+		var purchasedAt utils.Timestamp
+		var reasons, ip sql.NullString
+		if err := rows.Scan(&purchaseID, &userID, &username, &email, &amount, &purchasedAt, &score, &reasons, &ip); err != nil {
+			fmt.Printf("❌ Error scanning risk queue: %v\n", err)
+			utils.JSONError(w, "Error fetching held orders", http.StatusInternalServerError)
+			return
+		}
+		reasonList := []string{}
+		if reasons.Valid && reasons.String != "" {
+			reasonList = strings.Split(reasons.String, ",")
+		}
+		orders = append(orders, map[string]interface{}{
+			"purchase_id":  purchaseID,
+			"user_id":      userID,
+			"username":     username,
+			"email":        email,
+			"amount_paid":  amount,
+			"purchased_at": purchasedAt,
+			"risk_score":   score,
+			"reasons":      reasonList,
+			"ip_address":   ip.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading risk queue: %v\n", err)
+		utils.JSONError(w, "Error fetching held orders", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"orders":     orders,
+		"hold_score": riskHoldScore(),
+		"limit":      limit,
+		"offset":     offset,
+	}, http.StatusOK)
+}
+
+const heldPurchaseSnapshotQuery = "SELECT id, user_id, status, final_amount, tax_amount FROM purchases WHERE id = ?"
+
+// lockHeldPurchase อ่านคำสั่งซื้อด้วย FOR UPDATE และตรวจสอบว่ายังถูกพักอยู่
+// เขียน error response และคืน ok = false ถ้าดำเนินการต่อไม่ได้
+func lockHeldPurchase(w http.ResponseWriter, tx *sql.Tx, purchaseID int64) (userID int, amountPaid float64, ok bool) {
+	var status string
+	err := tx.QueryRow(`
+		SELECT user_id, status, final_amount + tax_amount FROM purchases WHERE id = ? FOR UPDATE
+	`, purchaseID).Scan(&userID, &status, &amountPaid)
+	if err == sql.ErrNoRows {
+		utils.JSONError(w, "Order not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	if err != nil {
+		fmt.Printf("❌ Error loading purchase #%d: %v\n", purchaseID, err)
+		utils.JSONError(w, "Error loading order", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if status != PurchaseHeld {
+		utils.JSONError(w, "Order is not on hold", http.StatusConflict)
+		return 0, 0, false
+	}
+	return userID, amountPaid, true
+}
+
+// approveHeldPurchase ส่งมอบเกมของคำสั่งซื้อที่ถูกพักไว้เข้าคลังของผู้ใช้
+func approveHeldPurchase(w http.ResponseWriter, r *http.Request, purchaseID int64) {
+	before := snapshotRow(heldPurchaseSnapshotQuery, purchaseID)
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	userID, _, ok := lockHeldPurchase(w, tx, purchaseID)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	// เพิ่มเกมเข้าคลังและอัพเดทยอดขาย (ขั้นตอนที่ข้ามไปตอน checkout)
+	if _, err := tx.Exec(`
+		INSERT INTO purchased_games (user_id, game_id)
+		SELECT ?, game_id FROM purchase_items WHERE purchase_id = ?
+	`, userID, purchaseID); err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error adding held purchase #%d to library: %v\n", purchaseID, err)
+		utils.JSONError(w, "Error adding to library", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO ranking (game_id, sales_count)
+		SELECT game_id, 1 FROM purchase_items WHERE purchase_id = ?
+		`+dialect.OnConflict("game_id")+` sales_count = ranking.sales_count + 1
+	`, purchaseID); err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error updating rankings", http.StatusInternalServerError)
+		return
+	}
+	if err := transitionPurchase(tx, purchaseID, PurchaseFulfilled, "risk review approved"); err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error updating purchase status: %v\n", err)
+		utils.JSONError(w, "Error updating purchase status", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "risk_approve", "purchase", purchaseID, before, snapshotRow(heldPurchaseSnapshotQuery, purchaseID))
+	invalidateCatalogCache()
+	requestRankingRecompute()
+	notify(userID, NotificationPurchaseCompleted, "Purchase completed",
+		fmt.Sprintf("Purchase #%d has been approved and added to your library", purchaseID),
+		map[string]interface{}{"purchase_id": purchaseID})
+	publishToUser(userID, EventOrderStatus, map[string]interface{}{"purchase_id": purchaseID, "status": "completed"})
+	publishSale(purchaseID)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "Order approved",
+		"purchase_id": purchaseID,
+		"status":      PurchaseFulfilled,
+	}, http.StatusOK)
+}
+
+// rejectHeldPurchase ยกเลิกคำสั่งซื้อที่ถูกพักไว้ คืนเงินเข้ากระเป๋าและคืนสต็อกของเกมที่จำกัดจำนวน
+func rejectHeldPurchase(w http.ResponseWriter, r *http.Request, purchaseID int64) {
+	before := snapshotRow(heldPurchaseSnapshotQuery, purchaseID)
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	userID, amountPaid, ok := lockHeldPurchase(w, tx, purchaseID)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE users SET wallet_balance = wallet_balance + ? WHERE id = ?", amountPaid, userID); err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error updating wallet", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO user_transactions (user_id, type, amount, description)
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionRefund, amountPaid, fmt.Sprintf("Refund: purchase #%d rejected in review", purchaseID)); err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error recording transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`
+		UPDATE games SET stock_count = stock_count + 1
+		WHERE stock_count IS NOT NULL AND id IN (SELECT game_id FROM purchase_items WHERE purchase_id = ?)
+	`, purchaseID); err != nil {
+		tx.Rollback()
+		utils.JSONError(w, "Error updating stock", http.StatusInternalServerError)
+		return
+	}
+	if err := transitionPurchase(tx, purchaseID, PurchaseRefunded, "risk review rejected"); err != nil {
+		tx.Rollback()
+		fmt.Printf("❌ Error updating purchase status: %v\n", err)
+		utils.JSONError(w, "Error updating purchase status", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "risk_reject", "purchase", purchaseID, before, snapshotRow(heldPurchaseSnapshotQuery, purchaseID))
+	notify(userID, NotificationRefundDecision, "Order cancelled",
+		fmt.Sprintf("Purchase #%d could not be completed. $%.2f was returned to your wallet", purchaseID, amountPaid),
+		map[string]interface{}{"purchase_id": purchaseID, "amount": amountPaid})
+	publishToUser(userID, EventOrderStatus, map[string]interface{}{"purchase_id": purchaseID, "status": PurchaseRefunded})
+	pushWalletBalance(userID)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":     "Order rejected and refunded",
+		"purchase_id": purchaseID,
+		"status":      PurchaseRefunded,
+		"refunded":    amountPaid,
+	}, http.StatusOK)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// แถวที่อ่านไม่ได้ต้องทำให้ request ล้มเหลว ไม่ใช่ถูกตัดทิ้งจากผลลัพธ์เงียบๆ
+func TestWishlistFailsOnUnreadableRows(t *testing.T) {
+	columns := []string{"id", "name", "price", "status", "thumbnail_url", "added_at"}
+	tests := map[string]func() *sqlmock.Rows{
+		"scan error": func() *sqlmock.Rows {
+			return sqlmock.NewRows(columns).
+				AddRow(1, "A", 10.0, GameStatusPublished, nil, time.Now()).
+				AddRow("not-a-number", "B", 20.0, GameStatusPublished, nil, time.Now())
+		},
+		"rows.Err": func() *sqlmock.Rows {
+			return sqlmock.NewRows(columns).
+				AddRow(1, "A", 10.0, GameStatusPublished, nil, time.Now()).
+				AddRow(2, "B", 20.0, GameStatusPublished, nil, time.Now()).
+				RowError(1, errors.New("connection reset"))
+		},
+	}
+	for name, rows := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := useMockDB(t)
+			mock.ExpectQuery("SELECT COUNT").WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			mock.ExpectQuery("FROM wishlist_items w").WillReturnRows(rows())
+
+			w := httptest.NewRecorder()
+			WishlistHandler(w, withIdentity(httptest.NewRequest("GET", "/wishlist", nil), Identity{UserID: 7, Role: "user"}))
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// CSV ส่ง status 200 ไปก่อนแล้ว จึงต้องตัดการเชื่อมต่อแทนการส่งไฟล์ที่ขาดแถว
+func TestStreamQueryCSVAbortsOnRowError(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT id FROM games").WillReturnRows(
+		sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).RowError(1, errors.New("connection reset")))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", recovered)
+		}
+	}()
+	streamQueryCSV(httptest.NewRecorder(), time.UTC, "games.csv", "SELECT id FROM games")
+	t.Fatal("export finished despite a row error")
+}
@@ -0,0 +1,713 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// schemaStatements คำสั่งสร้างตารางเพิ่มเติมที่ระบบต้องใช้
+// ตารางหลัก (users, games, purchases, ...) มีอยู่แล้วในฐานข้อมูล
+// ส่วนนี้ใช้สำหรับตารางของฟีเจอร์ใหม่ โดยใช้ CREATE TABLE IF NOT EXISTS เพื่อให้รันซ้ำได้
+var schemaStatements = []string{
+	// บัตรของขวัญ (gift card) สำหรับเติมเงินเข้ากระเป๋า
+	`CREATE TABLE IF NOT EXISTS gift_cards (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		code VARCHAR(64) NOT NULL UNIQUE,
+		value DECIMAL(10,2) NOT NULL,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		expires_at DATE NULL,
+		created_by INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		redeemed_by INT NULL,
+		redeemed_at DATETIME NULL
+	)`,
+	// บันทึกการแลกบัตรของขวัญ (ทั้งที่สำเร็จและล้มเหลว ใช้สำหรับตรวจสอบการทุจริต)
+	`CREATE TABLE IF NOT EXISTS gift_card_redemptions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		gift_card_id INT NULL,
+		user_id INT NOT NULL,
+		code VARCHAR(64) NOT NULL,
+		amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		success TINYINT(1) NOT NULL DEFAULT 0,
+		reason VARCHAR(255) NULL,
+		ip_address VARCHAR(64) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_gcr_user_created (user_id, created_at)
+	)`,
+	// ประวัติการเพิ่มเกมลงตะกร้า (ใช้คำนวณอัตราการเปลี่ยนจากตะกร้าเป็นการซื้อ)
+	`CREATE TABLE IF NOT EXISTS cart_events (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_cart_events_game (game_id, created_at)
+	)`,
+	// บันทึกการเปลี่ยนแปลงข้อมูลโดยผู้ดูแลระบบ
+	`CREATE TABLE IF NOT EXISTS admin_audit_log (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		actor_id INT NOT NULL,
+		actor_username VARCHAR(100) NOT NULL DEFAULT '',
+		action VARCHAR(50) NOT NULL,
+		target_type VARCHAR(50) NOT NULL,
+		target_id VARCHAR(64) NOT NULL DEFAULT '',
+		before_data TEXT NULL,
+		after_data TEXT NULL,
+		diff TEXT NULL,
+		ip_address VARCHAR(64) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_audit_target (target_type, target_id),
+		INDEX idx_audit_created (created_at)
+	)`,
+	// คลังภาพที่อัพโหลดไว้ (นำกลับมาใช้กับเกมอื่นได้)
+	`CREATE TABLE IF NOT EXISTS media_assets (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		url VARCHAR(512) NOT NULL UNIQUE,
+		storage VARCHAR(20) NOT NULL,
+		filename VARCHAR(255) NOT NULL DEFAULT '',
+		size_bytes BIGINT NULL,
+		uploaded_by INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	// ภาพย่อแต่ละขนาดของภาพที่อัพโหลด (ใช้ได้ทั้งภาพเกมและ avatar)
+	`CREATE TABLE IF NOT EXISTS image_variants (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		original_url VARCHAR(512) NOT NULL,
+		variant VARCHAR(20) NOT NULL,
+		url VARCHAR(512) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_image_variant (original_url, variant)
+	)`,
+	// การแจ้งเตือนของผู้ใช้ (ศูนย์การแจ้งเตือน)
+	`CREATE TABLE IF NOT EXISTS notifications (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		type VARCHAR(50) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		message TEXT NULL,
+		data TEXT NULL,
+		read_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_notifications_user (user_id, read_at, created_at)
+	)`,
+	// webhook ที่ผู้ดูแลระบบลงทะเบียนไว้รับเหตุการณ์ของร้าน
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		url VARCHAR(512) NOT NULL,
+		secret VARCHAR(128) NOT NULL,
+		events VARCHAR(512) NOT NULL,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		created_by INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	// ประวัติการส่ง webhook (แต่ละครั้งที่พยายามส่ง)
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		webhook_id INT NOT NULL,
+		event VARCHAR(100) NOT NULL,
+		payload TEXT NOT NULL,
+		attempt INT NOT NULL DEFAULT 1,
+		status_code INT NULL,
+		success TINYINT(1) NOT NULL DEFAULT 0,
+		error VARCHAR(512) NULL,
+		duration_ms INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_webhook_deliveries (webhook_id, created_at)
+	)`,
+	// คิวงานเบื้องหลัง (ใช้โดย package jobs เก็บในฐานข้อมูลเพื่อไม่ให้งานหายเมื่อ restart)
+	`CREATE TABLE IF NOT EXISTS jobs (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		type VARCHAR(100) NOT NULL,
+		payload TEXT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		max_attempts INT NOT NULL DEFAULT 5,
+		run_at DATETIME NOT NULL,
+		unique_key VARCHAR(100) NULL,
+		locked_at DATETIME NULL,
+		finished_at DATETIME NULL,
+		last_error VARCHAR(512) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_jobs_pending (unique_key),
+		INDEX idx_jobs_status_run (status, run_at)
+	)`,
+	// งานที่ทำซ้ำตามรอบเวลา (เก็บเวลาที่ทำล่าสุดไว้ข้าม restart)
+	`CREATE TABLE IF NOT EXISTS job_schedules (
+		name VARCHAR(100) PRIMARY KEY,
+		job_type VARCHAR(100) NOT NULL,
+		interval_seconds INT NOT NULL,
+		last_run_at DATETIME NULL,
+		next_run_at DATETIME NOT NULL
+	)`,
+	// ประวัติกิจกรรมของบัญชี (เข้าสู่ระบบ, เปลี่ยนรหัสผ่าน, เปลี่ยนอีเมล) ให้ผู้ใช้ตรวจสอบการเข้าถึงที่น่าสงสัย
+	`CREATE TABLE IF NOT EXISTS account_activity (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		event VARCHAR(50) NOT NULL,
+		ip_address VARCHAR(64) NULL,
+		user_agent VARCHAR(512) NULL,
+		details VARCHAR(255) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_account_activity_user (user_id, created_at)
+	)`,
+	// session ของแต่ละอุปกรณ์ที่เข้าสู่ระบบ (token_id ตรงกับ claim "jti" ของ JWT) ใช้เพิกถอน token รายอุปกรณ์
+	// ประวัติการเปลี่ยนสถานะของคำสั่งซื้อ
+	`CREATE TABLE IF NOT EXISTS purchase_status_history (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		purchase_id INT NOT NULL,
+		from_status VARCHAR(20) NULL,
+		to_status VARCHAR(20) NOT NULL,
+		reason VARCHAR(255) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_psh_purchase (purchase_id)
+	)`,
+	// การตั้งค่าเติมเงินอัตโนมัติของผู้ใช้ (ตัดเงินผ่านช่องทางชำระเงินเมื่อยอดไม่พอ)
+	`CREATE TABLE IF NOT EXISTS wallet_auto_topup (
+		user_id INT PRIMARY KEY,
+		enabled TINYINT(1) NOT NULL DEFAULT 0,
+		threshold DECIMAL(10,2) NOT NULL DEFAULT 0,
+		amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		last_topup_at DATETIME NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// การเติมเงินอัตโนมัติหลังการซื้อ (หนึ่งครั้งต่อคำสั่งซื้อ ป้องกันการตัดเงินซ้ำ)
+	`CREATE TABLE IF NOT EXISTS auto_topup_triggers (
+		purchase_id INT PRIMARY KEY,
+		user_id INT NOT NULL,
+		amount DECIMAL(10,2) NOT NULL,
+		reference VARCHAR(100) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_sessions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		token_id VARCHAR(64) NOT NULL,
+		ip_address VARCHAR(64) NULL,
+		user_agent VARCHAR(512) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME NULL,
+		UNIQUE KEY uniq_user_sessions_token (token_id),
+		INDEX idx_user_sessions_user (user_id, revoked_at)
+	)`,
+	// การตั้งค่าความเป็นส่วนตัวของโปรไฟล์สาธารณะ (ผู้ใช้ที่ไม่มีแถวถือว่าเป็นส่วนตัวทั้งหมด)
+	`CREATE TABLE IF NOT EXISTS user_privacy_settings (
+		user_id INT PRIMARY KEY,
+		library_visibility VARCHAR(10) NOT NULL DEFAULT 'private',
+		activity_visibility VARCHAR(10) NOT NULL DEFAULT 'private',
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// ไฟล์เซฟของเกมบนคลาวด์ (หนึ่งแถวต่อ revision ตัวไฟล์เก็บใน storage)
+	`CREATE TABLE IF NOT EXISTS game_saves (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		revision INT NOT NULL,
+		storage_url VARCHAR(512) NOT NULL,
+		size_bytes INT NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_game_saves_revision (user_id, game_id, revision)
+	)`,
+	// ผู้ใช้เป้าหมายของรหัสส่วนลด (รหัสที่มีเป้าหมายใช้ได้เฉพาะผู้ใช้เหล่านี้ และถูกเลือกให้อัตโนมัติตอน checkout)
+	`CREATE TABLE IF NOT EXISTS discount_code_targets (
+		discount_code_id INT NOT NULL,
+		user_id INT NOT NULL,
+		PRIMARY KEY (discount_code_id, user_id),
+		INDEX idx_discount_code_targets_user (user_id)
+	)`,
+	// กฎภาษีตามภูมิภาค (region "*" = อัตราเริ่มต้น) อัตราเป็นเปอร์เซ็นต์
+	`CREATE TABLE IF NOT EXISTS tax_rules (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		region VARCHAR(10) NOT NULL,
+		name VARCHAR(50) NOT NULL,
+		rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_tax_rules_region (region)
+	)`,
+	// แบนเนอร์หน้าแรก (เรียงตาม position แสดงเฉพาะช่วงเวลาที่กำหนด)
+	`CREATE TABLE IF NOT EXISTS storefront_banners (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		title VARCHAR(150) NOT NULL,
+		subtitle VARCHAR(255) NULL,
+		image_url VARCHAR(500) NOT NULL,
+		link_url VARCHAR(500) NULL,
+		game_id INT NULL,
+		position INT NOT NULL DEFAULT 0,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		starts_at DATETIME NULL,
+		ends_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_storefront_banners_position (position)
+	)`,
+	// คอลเลกชันเกมที่คัดสรรโดยแอดมิน (เช่น "Staff picks")
+	`CREATE TABLE IF NOT EXISTS storefront_collections (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		slug VARCHAR(100) NOT NULL,
+		title VARCHAR(150) NOT NULL,
+		description VARCHAR(500) NULL,
+		position INT NOT NULL DEFAULT 0,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_storefront_collections_slug (slug)
+	)`,
+	// เกมในแต่ละคอลเลกชัน (เรียงตาม position)
+	`CREATE TABLE IF NOT EXISTS storefront_collection_games (
+		collection_id INT NOT NULL,
+		game_id INT NOT NULL,
+		position INT NOT NULL DEFAULT 0,
+		PRIMARY KEY (collection_id, game_id)
+	)`,
+	// รายการเกมที่ผู้ใช้สร้างเอง (แชร์ผ่าน slug)
+	`CREATE TABLE IF NOT EXISTS user_lists (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		slug VARCHAR(120) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		description VARCHAR(1000) NULL,
+		visibility ENUM('public','private') NOT NULL DEFAULT 'public',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_user_lists_slug (slug),
+		INDEX idx_user_lists_user (user_id)
+	)`,
+	// เกมในรายการของผู้ใช้ (เรียงตาม position)
+	`CREATE TABLE IF NOT EXISTS user_list_items (
+		list_id INT NOT NULL,
+		game_id INT NOT NULL,
+		position INT NOT NULL DEFAULT 0,
+		note VARCHAR(255) NULL,
+		PRIMARY KEY (list_id, game_id)
+	)`,
+	// ผู้ติดตามรายการเกม
+	`CREATE TABLE IF NOT EXISTS user_list_follows (
+		list_id INT NOT NULL,
+		user_id INT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (list_id, user_id),
+		INDEX idx_user_list_follows_user (user_id),
+		INDEX idx_user_list_follows_created (created_at)
+	)`,
+	// รายงานเนื้อหาไม่เหมาะสมจากผู้ใช้ (ผู้ใช้หนึ่งคนรายงานเนื้อหาเดียวกันได้ครั้งเดียว)
+	`CREATE TABLE IF NOT EXISTS content_reports (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		reporter_id INT NOT NULL,
+		target_type VARCHAR(20) NOT NULL,
+		target_id INT NOT NULL,
+		reason VARCHAR(30) NOT NULL,
+		details VARCHAR(1000) NULL,
+		status ENUM('open','resolved','dismissed') NOT NULL DEFAULT 'open',
+		resolved_by INT NULL,
+		resolution_note VARCHAR(500) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		resolved_at DATETIME NULL,
+		UNIQUE KEY uniq_content_reports_reporter (reporter_id, target_type, target_id),
+		INDEX idx_content_reports_target (target_type, target_id, status)
+	)`,
+	// คำแปลชื่อและคำอธิบายของเกม (ข้อมูลในตาราง games เป็นภาษาอังกฤษ)
+	`CREATE TABLE IF NOT EXISTS game_translations (
+		game_id INT NOT NULL,
+		locale VARCHAR(10) NOT NULL,
+		name VARCHAR(255) NULL,
+		description TEXT NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		PRIMARY KEY (game_id, locale)
+	)`,
+	`CREATE TABLE IF NOT EXISTS game_price_history (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		game_id INT NOT NULL,
+		old_price DECIMAL(10,2) NULL,
+		new_price DECIMAL(10,2) NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		changed_by INT NULL,
+		changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_price_history_game (game_id, changed_at)
+	)`,
+	// เกมที่ผู้ใช้อยากได้ (ใช้แจ้งเตือนเมื่อลดราคา)
+	`CREATE TABLE IF NOT EXISTS wishlist_items (
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		added_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, game_id),
+		INDEX idx_wishlist_items_game (game_id)
+	)`,
+	// การตั้งค่าแจ้งเตือนลดราคาของ wishlist (ผู้ใช้ที่ไม่มีแถวใช้ค่าเริ่มต้น: แจ้งทันทีพร้อมอีเมล)
+	`CREATE TABLE IF NOT EXISTS wishlist_alert_settings (
+		user_id INT PRIMARY KEY,
+		enabled TINYINT(1) NOT NULL DEFAULT 1,
+		email TINYINT(1) NOT NULL DEFAULT 1,
+		mode VARCHAR(10) NOT NULL DEFAULT 'instant',
+		min_discount_percent INT NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// การลดราคาที่รอส่งในสรุปรายวันของผู้ใช้ที่เลือกโหมด digest
+	`CREATE TABLE IF NOT EXISTS wishlist_alert_queue (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		old_price DECIMAL(10,2) NOT NULL,
+		new_price DECIMAL(10,2) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME NULL,
+		INDEX idx_wishlist_alert_queue_user (user_id, sent_at)
+	)`,
+	// ผู้รับข่าวสาร (สมัครได้ทั้งผู้ใช้และอีเมลที่ยังไม่มีบัญชี)
+	`CREATE TABLE IF NOT EXISTS newsletter_subscriptions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		email VARCHAR(254) NOT NULL,
+		user_id INT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'subscribed',
+		subscribed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		unsubscribed_at DATETIME NULL,
+		UNIQUE KEY uniq_newsletter_email (email),
+		INDEX idx_newsletter_user (user_id)
+	)`,
+	// จดหมายข่าวที่ผู้ดูแลระบบสร้าง (segment กำหนดกลุ่มผู้รับ)
+	`CREATE TABLE IF NOT EXISTS newsletter_campaigns (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		subject VARCHAR(255) NOT NULL,
+		html MEDIUMTEXT NOT NULL,
+		text MEDIUMTEXT NOT NULL,
+		segment VARCHAR(30) NOT NULL,
+		category_id INT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'queued',
+		recipients INT NOT NULL DEFAULT 0,
+		created_by INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME NULL
+	)`,
+	// ผู้รับที่ส่งจดหมายข่าวแล้ว (กันการส่งซ้ำเมื่องานถูกลองใหม่)
+	`CREATE TABLE IF NOT EXISTS newsletter_deliveries (
+		campaign_id INT NOT NULL,
+		email VARCHAR(254) NOT NULL,
+		queued_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (campaign_id, email)
+	)`,
+	// feature flag สำหรับเปิดฟีเจอร์ทีละส่วนหรือปิดฟีเจอร์โดยไม่ต้อง deploy ใหม่ (user_ids คั่นด้วย comma)
+	`CREATE TABLE IF NOT EXISTS feature_flags (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		flag_key VARCHAR(64) NOT NULL,
+		description VARCHAR(255) NULL,
+		enabled TINYINT(1) NOT NULL DEFAULT 1,
+		rollout_percent INT NOT NULL DEFAULT 100,
+		user_ids TEXT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_feature_flag_key (flag_key)
+	)`,
+	// ผลการประเมินความเสี่ยงของการเติมเงินและการสั่งซื้อ (score 0-100 และสัญญาณที่พบ) รวมถึงการกรอกรหัสส่วนลดที่ไม่มีอยู่
+	`CREATE TABLE IF NOT EXISTS risk_events (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		event VARCHAR(30) NOT NULL,
+		score INT NOT NULL DEFAULT 0,
+		reasons VARCHAR(255) NULL,
+		ip_address VARCHAR(64) NULL,
+		purchase_id INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_risk_events_user (user_id, event, created_at),
+		INDEX idx_risk_events_ip (ip_address, created_at),
+		INDEX idx_risk_events_purchase (purchase_id)
+	)`,
+	// การจัดระเบียบคลังเกมของผู้ใช้ (เกมที่ไม่มีแถวถือว่าไม่ใช่เกมโปรดและไม่ได้ซ่อน)
+	`CREATE TABLE IF NOT EXISTS library_settings (
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		favorite TINYINT(1) NOT NULL DEFAULT 0,
+		hidden TINYINT(1) NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, game_id)
+	)`,
+	// แท็กที่ผู้ใช้ตั้งให้เกมในคลัง (ตัวพิมพ์เล็ก)
+	`CREATE TABLE IF NOT EXISTS library_tags (
+		user_id INT NOT NULL,
+		game_id INT NOT NULL,
+		tag VARCHAR(32) NOT NULL,
+		PRIMARY KEY (user_id, game_id, tag),
+		INDEX idx_library_tags_tag (user_id, tag)
+	)`,
+	// คำถามเกี่ยวกับเกมจากผู้ใช้
+	`CREATE TABLE IF NOT EXISTS game_questions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		game_id INT NOT NULL,
+		user_id INT NOT NULL,
+		body VARCHAR(1000) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_game_questions_game (game_id, created_at)
+	)`,
+	// คำตอบของคำถาม (highlighted = ผู้ดูแลระบบปักหมุดเป็นคำตอบแนะนำ, official = ตอบโดยผู้ดูแลระบบ)
+	`CREATE TABLE IF NOT EXISTS game_answers (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		question_id INT NOT NULL,
+		user_id INT NOT NULL,
+		body VARCHAR(2000) NOT NULL,
+		official TINYINT(1) NOT NULL DEFAULT 0,
+		highlighted TINYINT(1) NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_game_answers_question (question_id, created_at)
+	)`,
+	// พาร์ทเนอร์ (affiliate) ที่แนะนำผู้ใช้ผ่าน ?ref=CODE (commission_rate เป็นเปอร์เซ็นต์ของ final_amount)
+	`CREATE TABLE IF NOT EXISTS affiliates (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		code VARCHAR(32) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		contact_email VARCHAR(255) NULL,
+		commission_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+		active TINYINT(1) NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_affiliates_code (code)
+	)`,
+	// คีย์ API ของนักพัฒนา (เก็บเฉพาะ SHA-256 ของคีย์, scopes คั่นด้วยช่องว่าง)
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		key_prefix VARCHAR(16) NOT NULL,
+		key_hash CHAR(64) NOT NULL,
+		scopes VARCHAR(255) NOT NULL,
+		daily_quota INT NOT NULL,
+		last_used_at DATETIME NULL,
+		revoked_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uniq_api_keys_hash (key_hash),
+		INDEX idx_api_keys_user (user_id, revoked_at)
+	)`,
+	// จำนวน request ต่อวัน (UTC) ของคีย์ API ใช้ตรวจสอบโควตา
+	`CREATE TABLE IF NOT EXISTS api_key_usage (
+		api_key_id INT NOT NULL,
+		usage_date DATE NOT NULL,
+		requests INT NOT NULL DEFAULT 0,
+		PRIMARY KEY (api_key_id, usage_date)
+	)`,
+	// สิทธิ์เพิ่มเติมของ admin (ดู adminPermissions)
+	`CREATE TABLE IF NOT EXISTS admin_permissions (
+		user_id INT NOT NULL,
+		permission VARCHAR(50) NOT NULL,
+		granted_by INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, permission)
+	)`,
+	// การเข้าชมจากลิงก์ของพาร์ทเนอร์ (ใช้ในรายงานค่าคอมมิชชัน)
+	`CREATE TABLE IF NOT EXISTS affiliate_clicks (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		affiliate_id INT NOT NULL,
+		path VARCHAR(255) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_affiliate_clicks_affiliate (affiliate_id, created_at)
+	)`,
+	// ภาษาและ time zone ที่ผู้ใช้เลือก (ผู้ใช้ที่ไม่มีแถวใช้ภาษาเริ่มต้นและ UTC)
+	`CREATE TABLE IF NOT EXISTS user_preferences (
+		user_id INT PRIMARY KEY,
+		locale VARCHAR(10) NULL,
+		timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+}
+
+// schemaColumns คอลัมน์ที่เพิ่มเข้าไปในตารางเดิม
+var schemaColumns = []struct {
+	Table      string
+	Column     string
+	Definition string
+}{
+	// วันที่เก็บเข้าคลัง (archive) ของรหัสส่วนลด แทนการลบทิ้ง
+	{"discount_codes", "archived_at", "DATETIME NULL"},
+	// รหัสส่วนลดสาธารณะที่ checkout เลือกให้อัตโนมัติได้ (auto_apply)
+	{"discount_codes", "auto_apply", "TINYINT(1) NOT NULL DEFAULT 0"},
+	// ซ่อนเนื้อหาที่ถูกรายงานเกินจำนวนที่กำหนด (รอ admin ตรวจสอบ)
+	{"users", "avatar_hidden", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"user_lists", "hidden", "TINYINT(1) NOT NULL DEFAULT 0"},
+	// สถานะการแสดงผลของเกม (published, delisted) แทนการลบทิ้ง
+	{"games", "status", "VARCHAR(20) NOT NULL DEFAULT 'published'"},
+	{"games", "delisted_at", "DATETIME NULL"},
+	// สถานะของคำสั่งซื้อ (ดู purchaseTransitions) คำสั่งซื้อเดิมถือว่าส่งมอบแล้ว
+	{"purchases", "status", "VARCHAR(20) NOT NULL DEFAULT 'fulfilled'"},
+	{"purchases", "status_updated_at", "DATETIME NULL"},
+	// สต็อกของเกม (NULL = ไม่จำกัดจำนวน) และเวลาที่แจ้งเตือนสต็อกใกล้หมดล่าสุด
+	{"games", "stock_count", "INT NULL"},
+	{"games", "low_stock_alerted_at", "DATETIME NULL"},
+	// ภาษีของคำสั่งซื้อ (final_amount ไม่รวมภาษี ยอดที่ชำระจริงคือ final_amount + tax_amount)
+	{"purchases", "tax_amount", "DECIMAL(10,2) NOT NULL DEFAULT 0"},
+	{"purchases", "tax_rate", "DECIMAL(5,2) NOT NULL DEFAULT 0"},
+	{"purchases", "tax_name", "VARCHAR(50) NULL"},
+	{"purchases", "tax_region", "VARCHAR(10) NULL"},
+	{"purchase_items", "tax_amount", "DECIMAL(10,2) NOT NULL DEFAULT 0"},
+	// เรตติ้งอายุของเกม (ESRB/PEGI) อายุขั้นต่ำที่คำนวณจากเรตติ้ง และคำอธิบายเนื้อหา
+	{"games", "age_rating", "VARCHAR(20) NULL"},
+	{"games", "min_age", "INT NOT NULL DEFAULT 0"},
+	{"games", "content_descriptors", "VARCHAR(255) NULL"},
+	// ความต้องการของระบบขั้นต่ำและที่แนะนำ (JSON: minimum/recommended แต่ละระดับมี os, cpu, ram, gpu, storage)
+	{"games", "system_requirements", "TEXT NULL"},
+	// slug ของเกมสำหรับ URL ที่อ่านง่าย (/games/slug/{slug}) สร้างจากชื่อตอนเพิ่มเกม
+	{"games", "slug", "VARCHAR(120) NULL"},
+	// วันเกิดของผู้ใช้ (ใช้ตรวจสอบอายุเมื่อซื้อเกมที่มีเรตติ้ง)
+	{"users", "birthdate", "DATE NULL"},
+	// สถานะการตรวจสอบรูปโปรไฟล์ที่อัพโหลด (/admin/avatars) และเวลาที่เปลี่ยนรูปล่าสุด
+	{"users", "avatar_status", "VARCHAR(20) NOT NULL DEFAULT 'approved'"},
+	{"users", "avatar_updated_at", "DATETIME NULL"},
+	// ผู้ดูแลระบบที่มอบเกมให้ผู้ใช้ (คำสั่งซื้อมูลค่า 0 จาก /admin/users/{id}/grant-game, NULL = ผู้ใช้ซื้อเอง)
+	{"purchases", "granted_by", "INT NULL"},
+	// เวลาเล่นสะสม สถานะการติดตั้ง และเวลาที่เล่นล่าสุดของเกมในคลัง (รายงานโดย launcher ผ่าน /library/{game_id})
+	{"library_settings", "playtime_minutes", "INT NOT NULL DEFAULT 0"},
+	{"library_settings", "installed", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"library_settings", "last_played_at", "DATETIME NULL"},
+	// เวลาที่เพิ่ม/แก้ไขสินค้าในตะกร้า (ใช้ลบสินค้าที่ค้างนานและรายงานตะกร้าที่ถูกทิ้ง) และเวลาที่แจ้งเตือนตะกร้าล่าสุด
+	{"cart_items", "added_at", "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+	{"cart_items", "updated_at", "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"},
+	{"carts", "reminded_at", "DATETIME NULL"},
+	// พาร์ทเนอร์ที่แนะนำผู้ใช้ และพาร์ทเนอร์กับค่าคอมมิชชันของคำสั่งซื้อ (คำนวณจากอัตรา ณ เวลาที่สั่งซื้อ)
+	{"users", "affiliate_id", "INT NULL"},
+	{"purchases", "affiliate_id", "INT NULL"},
+	// ชื่อผู้ใช้แบบ case folding (utils.UsernameKey) ใช้ตรวจความซ้ำ เทียบแบบ binary เพื่อให้ตรงกับที่ Go คำนวณ
+	// (collation ปกติไม่รู้ว่า ß = ss แต่ถือว่า é = e) แถวเดิมเป็น NULL จนกว่า backfillUsernameKeys จะเติมให้
+	{"users", "username_key", "VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NULL"},
+	{"purchases", "affiliate_commission", "DECIMAL(10,2) NOT NULL DEFAULT 0"},
+}
+
+// schemaColumnTypes คอลัมน์ของตารางเดิมที่ต้องเปลี่ยนชนิดข้อมูล (MODIFY เมื่อชนิดปัจจุบันไม่ตรงกัน)
+var schemaColumnTypes = []struct {
+	Table      string
+	Column     string
+	Type       string // ชนิดตามที่ information_schema.columns.column_type แสดง
+	Definition string
+}{
+	// ประเภทธุรกรรมทั้งหมด (ดู transactionTypes)
+	{"user_transactions", "type", transactionTypeEnum(), transactionTypeEnum() + " NOT NULL"},
+}
+
+// schemaUniqueIndexes unique index ที่ต้องมีในตารางเดิม (ใช้ป้องกันข้อมูลซ้ำแทนการตรวจสอบก่อน INSERT)
+// ชื่อ index ใช้แยกว่าฟิลด์ใดซ้ำเมื่อเกิด duplicate key (ดู duplicateKeyName)
+var schemaUniqueIndexes = []struct {
+	Table   string
+	Name    string
+	Columns string
+}{
+	// collation ของ MySQL ไม่สนตัวพิมพ์เล็ก/ใหญ่ ชื่อผู้ใช้ที่ต่างกันแค่ตัวพิมพ์จึงซ้ำกัน
+	{"users", "uniq_users_username", "username"},
+	// ชื่อที่ต่างกันแค่ case folding ของ Unicode (เช่น Straße กับ STRASSE) ซ้ำกันด้วย
+	{"users", "uniq_users_username_key", "username_key"},
+	{"users", "uniq_users_email", "email"},
+	// ผู้ใช้หนึ่งคนมีตะกร้าได้ใบเดียว
+	{"carts", "uniq_carts_user", "user_id"},
+	// slug ของเกมไม่ซ้ำกัน (เกมที่ยังไม่มี slug เป็น NULL ได้หลายแถว)
+	{"games", "uniq_games_slug", "slug"},
+}
+
+// ensureSchema สร้างตารางที่ยังไม่มีในฐานข้อมูล
+// ฟังก์ชันนี้ถูกเรียกจาก InitDB และจะไม่หยุดการทำงานถ้าคำสั่งใดล้มเหลว (แค่แสดง log)
+func ensureSchema() {
+	for _, stmt := range schemaStatements {
+		if _, err := db.Exec(stmt); err != nil {
+			fmt.Printf("❌ Error applying schema statement: %v\n", err)
+		}
+	}
+	for _, col := range schemaColumns {
+		ensureColumn(col.Table, col.Column, col.Definition)
+	}
+	for _, col := range schemaColumnTypes {
+		ensureColumnType(col.Table, col.Column, col.Type, col.Definition)
+	}
+	for _, idx := range schemaUniqueIndexes {
+		ensureUniqueIndex(idx.Table, idx.Name, idx.Columns)
+	}
+	fmt.Println("✅ Database schema checked")
+}
+
+// ensureColumn เพิ่มคอลัมน์ให้ตารางถ้ายังไม่มี
+// MySQL ไม่รองรับ ADD COLUMN IF NOT EXISTS จึงต้องตรวจสอบจาก information_schema ก่อน
+func ensureColumn(table, column, definition string) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+		)
+	`, table, column).Scan(&exists)
+	if err != nil {
+		fmt.Printf("❌ Error checking column %s.%s: %v\n", table, column, err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		fmt.Printf("❌ Error adding column %s.%s: %v\n", table, column, err)
+		return
+	}
+	fmt.Printf("✅ Added column %s.%s\n", table, column)
+}
+
+// ensureColumnType เปลี่ยนชนิดของคอลัมน์ถ้ายังไม่ตรงกับที่ต้องการ
+func ensureColumnType(table, column, columnType, definition string) {
+	var current string
+	err := db.QueryRow(`
+		SELECT column_type FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+	`, table, column).Scan(&current)
+	if err != nil {
+		fmt.Printf("❌ Error checking column %s.%s: %v\n", table, column, err)
+		return
+	}
+	if strings.EqualFold(current, columnType) {
+		return
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, definition))
+	if err != nil {
+		fmt.Printf("❌ Error changing column %s.%s to %s: %v\n", table, column, columnType, err)
+		return
+	}
+	fmt.Printf("✅ Changed column %s.%s to %s\n", table, column, columnType)
+}
+
+// ensureUniqueIndex เพิ่ม unique index ให้ตารางถ้ายังไม่มี
+// ถ้ามีข้อมูลซ้ำอยู่แล้วการเพิ่มจะล้มเหลว (แสดง log ให้ผู้ดูแลแก้ข้อมูลก่อน)
+func ensureUniqueIndex(table, name, columns string) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?
+		)
+	`, table, name).Scan(&exists)
+	if err != nil {
+		fmt.Printf("❌ Error checking index %s.%s: %v\n", table, name, err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD UNIQUE INDEX %s (%s)", table, name, columns))
+	if err != nil {
+		fmt.Printf("❌ Error adding unique index %s.%s: %v\n", table, name, err)
+		return
+	}
+	fmt.Printf("✅ Added unique index %s.%s\n", table, name)
+}
+
+// duplicateKeyName คืนชื่อ index ที่ทำให้เกิด duplicate key error (MySQL error 1062)
+// ok เป็น false ถ้า err ไม่ใช่ duplicate key
+func duplicateKeyName(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+		return "", false
+	}
+	// ข้อความมีรูปแบบ: Duplicate entry 'x' for key 'users.uniq_users_email' (MySQL 8 มีชื่อตารางนำหน้า)
+	message := mysqlErr.Message
+	start := strings.LastIndex(message, "for key '")
+	if start < 0 {
+		return "", true
+	}
+	key := strings.TrimSuffix(message[start+len("for key '"):], "'")
+	if dot := strings.LastIndex(key, "."); dot >= 0 {
+		key = key[dot+1:]
+	}
+	return key, true
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api-game/auth"
+	"go-api-game/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionTouchInterval อัพเดท last_seen_at ไม่บ่อยกว่านี้ (ลดการเขียนฐานข้อมูลทุก request)
+const sessionTouchInterval = time.Minute
+
+// createSession บันทึก session ของอุปกรณ์ที่เข้าสู่ระบบ และสร้าง token ที่ผูกกับ session นั้น
+func createSession(r *http.Request, userID int, username, email, role string) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	tokenID := hex.EncodeToString(idBytes)
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO user_sessions (user_id, token_id, ip_address, user_agent, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, NOW(), DATE_ADD(NOW(), INTERVAL ? SECOND))
+	`, userID, tokenID, clientIP(r), userAgent, int(auth.TokenTTL.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return auth.GenerateSessionToken(userID, username, email, role, tokenID)
+}
+
+// validateSessionToken ตรวจสอบ JWT และสถานะ session ของ token
+// token รุ่นเก่าที่ไม่มี jti ยังใช้ได้จนหมดอายุ
+func validateSessionToken(tokenString string) (*auth.Claims, error) {
+	claims, err := auth.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return claims, nil
+	}
+
+	var sessionID int
+	var revoked, stale bool
+	err = db.QueryRow(`
+		SELECT id, revoked_at IS NOT NULL, last_seen_at < DATE_SUB(NOW(), INTERVAL ? SECOND)
+		FROM user_sessions
+		WHERE token_id = ? AND user_id = ?
+	`, int(sessionTouchInterval.Seconds()), claims.ID, claims.UserID).Scan(&sessionID, &revoked, &stale)
+	if err == sql.ErrNoRows || (err == nil && revoked) {
+		return nil, errors.New("session has been revoked")
+	}
+	if err != nil {
+		fmt.Printf("❌ Error checking session: %v\n", err)
+		return nil, errors.New("unable to verify session")
+	}
+
+	if stale {
+		if _, err := db.Exec("UPDATE user_sessions SET last_seen_at = NOW() WHERE id = ?", sessionID); err != nil {
+			fmt.Printf("⚠️ Error updating session last seen: %v\n", err)
+		}
+	}
+
+	return claims, nil
+}
+
+// ProfileSessionsHandler handles device session management
+// ฟังก์ชันหลักสำหรับจัดการอุปกรณ์ที่เข้าสู่ระบบอยู่
+// GET    /profile/sessions      - ดึงรายการ session ที่ยังใช้งานได้
+// DELETE /profile/sessions      - ออกจากระบบจากอุปกรณ์อื่นทั้งหมด (ยกเว้นอุปกรณ์ปัจจุบัน)
+// DELETE /profile/sessions/{id} - ออกจากระบบจากอุปกรณ์ที่ระบุ
+func ProfileSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+	currentTokenID := requestTokenID(r)
+
+	// ตัวอย่าง URL: /profile/sessions/12 → ["profile", "sessions", "12"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && r.Method == "GET":
+		getSessions(w, userID, currentTokenID)
+	case len(pathParts) == 2 && r.Method == "DELETE":
+		revokeOtherSessions(w, r, userID, currentTokenID)
+	case len(pathParts) == 3 && r.Method == "DELETE":
+		id, err := strconv.Atoi(pathParts[2])
+		if err != nil {
+			utils.JSONError(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+		revokeSession(w, r, userID, id)
+	case len(pathParts) == 2 || len(pathParts) == 3:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// GET /profile/sessions - ดึง session ที่ยังไม่ถูกเพิกถอนและยังไม่หมดอายุ (ใช้งานล่าสุดก่อน)
+func getSessions(w http.ResponseWriter, userID int, currentTokenID string) {
+	rows, err := db.Query(`
+		SELECT id, token_id, ip_address, user_agent, created_at, last_seen_at, expires_at
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching sessions: %v\n", err)
+		utils.JSONError(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	sessions := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var tokenID string
+		var createdAt, lastSeenAt, expiresAt utils.Timestamp
+		var ipAddress, userAgent sql.NullString
+
+		if err := rows.Scan(&id, &tokenID, &ipAddress, &userAgent, &createdAt, &lastSeenAt, &expiresAt); err != nil {
+			fmt.Printf("❌ Error scanning session row: %v\n", err)
+			utils.JSONError(w, "Error fetching sessions", http.StatusInternalServerError)
+			return
+		}
+
+		sessions = append(sessions, map[string]interface{}{
+			"id":           id,
+			"ip_address":   ipAddress.String,
+			"user_agent":   userAgent.String,
+			"current":      tokenID == currentTokenID,
+			"created_at":   createdAt,
+			"last_seen_at": lastSeenAt,
+			"expires_at":   expiresAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading session rows: %v\n", err)
+		utils.JSONError(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"sessions": sessions,
+		"count":    len(sessions),
+	}, http.StatusOK)
+}
+
+// DELETE /profile/sessions/{id} - เพิกถอน session ของตัวเอง
+func revokeSession(w http.ResponseWriter, r *http.Request, userID, id int) {
+	result, err := db.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		fmt.Printf("❌ Error revoking session: %v\n", err)
+		utils.JSONError(w, "Error revoking session", http.StatusInternalServerError)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.JSONError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	recordAccountActivity(r, userID, ActivitySessionRevoked, fmt.Sprintf("session %d", id))
+	fmt.Printf("🚪 Session %d revoked by user %d\n", id, userID)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Session revoked",
+		"id":      id,
+	}, http.StatusOK)
+}
+
+// DELETE /profile/sessions - เพิกถอนทุก session ยกเว้น session ของ token ที่ใช้เรียก
+func revokeOtherSessions(w http.ResponseWriter, r *http.Request, userID int, currentTokenID string) {
+	result, err := db.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = ? AND token_id != ? AND revoked_at IS NULL
+	`, userID, currentTokenID)
+	if err != nil {
+		fmt.Printf("❌ Error revoking sessions: %v\n", err)
+		utils.JSONError(w, "Error revoking sessions", http.StatusInternalServerError)
+		return
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		recordAccountActivity(r, userID, ActivitySessionRevoked, fmt.Sprintf("%d other sessions", affected))
+	}
+	fmt.Printf("🚪 User %d logged out %d other sessions\n", userID, affected)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Logged out from other devices",
+		"revoked": affected,
+	}, http.StatusOK)
+}
+
+// cleanupExpiredSessions ลบ session ที่หมดอายุหรือถูกเพิกถอนมานานแล้ว
+func cleanupExpiredSessions() error {
+	result, err := db.Exec(`
+		DELETE FROM user_sessions
+		WHERE expires_at < DATE_SUB(NOW(), INTERVAL 7 DAY)
+		   OR revoked_at < DATE_SUB(NOW(), INTERVAL 7 DAY)
+	`)
+	if err != nil {
+		return fmt.Errorf("error cleaning up sessions: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		fmt.Printf("🧹 Removed %d expired sessions\n", affected)
+	}
+	return nil
+}
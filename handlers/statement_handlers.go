@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// statementEntry รายการธุรกรรมในใบแจ้งยอด
+type statementEntry struct {
+	Date        utils.Timestamp `json:"date"`
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Amount      float64         `json:"amount"` // มีเครื่องหมายตามผลต่อยอดเงิน (ลบ = เงินออก)
+	Balance     float64         `json:"balance"`
+}
+
+// statement ใบแจ้งยอดรายเดือนของกระเป๋าเงิน
+type statement struct {
+	Month          string             `json:"month"`
+	Username       string             `json:"username"`
+	OpeningBalance float64            `json:"opening_balance"`
+	Deposits       float64            `json:"deposits"`
+	Purchases      float64            `json:"purchases"`
+	Refunds        float64            `json:"refunds"`
+	Adjustments    float64            `json:"adjustments"`
+	Gifts          float64            `json:"gifts"`
+	ClosingBalance float64            `json:"closing_balance"`
+	Transactions   []statementEntry   `json:"transactions"`
+	Totals         map[string]float64 `json:"totals_by_type"`
+}
+
+// StatementHandler handles monthly wallet statements
+// ฟังก์ชันสำหรับสร้างใบแจ้งยอดรายเดือน (ยอดยกมา, เงินเข้า-ออกแยกประเภท, ยอดยกไป)
+// GET /transactions/statement?month=YYYY-MM&format=json|csv|pdf
+func StatementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// เดือนที่ต้องการ (ค่าเริ่มต้นคือเดือนปัจจุบัน)
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if month := r.URL.Query().Get("month"); month != "" {
+		parsed, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			utils.JSONError(w, "Invalid month format. Use YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		if parsed.After(now) {
+			utils.JSONError(w, "Month must not be in the future", http.StatusBadRequest)
+			return
+		}
+		monthStart = parsed
+	}
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	st, err := buildStatement(userID, monthStart, monthEnd)
+	if err != nil {
+		fmt.Printf("❌ Error building statement: %v\n", err)
+		utils.JSONError(w, "Error generating statement", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("statement_%s", st.Month)
+	format := r.URL.Query().Get("format")
+	// ไฟล์ที่คนอ่านแสดงเวลาตาม time zone ของผู้ใช้ (หรือ ?tz=) ส่วน JSON เป็น UTC
+	loc := time.UTC
+	if format == "csv" || format == "pdf" {
+		if loc, err = exportLocation(r); err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+	}
+	switch format {
+	case "csv":
+		cw := utils.CSVWriter(w, filename+".csv")
+		cw.Write([]string{"date", "type", "description", "amount", "balance"})
+		cw.Write([]string{st.Month + "-01", "opening_balance", "Opening balance", "", formatAmount(st.OpeningBalance)})
+		for _, entry := range st.Transactions {
+			cw.Write([]string{formatExportTime(entry.Date.Time, loc), entry.Type, entry.Description, formatAmount(entry.Amount), formatAmount(entry.Balance)})
+		}
+		cw.Write([]string{"", "closing_balance", "Closing balance", "", formatAmount(st.ClosingBalance)})
+		cw.Flush()
+	case "pdf":
+		utils.PDFResponse(w, filename+".pdf", utils.TextPDF(statementLines(st, loc)))
+	default:
+		utils.JSONResponse(w, st, http.StatusOK)
+	}
+}
+
+// buildStatement คำนวณใบแจ้งยอดของช่วง [monthStart, monthEnd)
+// ยอดยกไปคำนวณย้อนจากยอดเงินปัจจุบันลบด้วยธุรกรรมหลังสิ้นเดือน ยอดยกมาคำนวณย้อนจากยอดยกไป
+func buildStatement(userID int, monthStart, monthEnd time.Time) (*statement, error) {
+	st := &statement{
+		Month:        monthStart.Format("2006-01"),
+		Transactions: []statementEntry{},
+		Totals:       make(map[string]float64, len(transactionTypes)),
+	}
+	for _, t := range transactionTypes {
+		st.Totals[t] = 0
+	}
+
+	var balance float64
+	if err := db.QueryRow("SELECT username, wallet_balance FROM users WHERE id = ?", userID).Scan(&st.Username, &balance); err != nil {
+		return nil, err
+	}
+
+	// ผลรวมของธุรกรรมหลังสิ้นเดือน (แยกตามประเภท เพื่อใช้ทิศทางของแต่ละประเภท)
+	rows, err := db.Query(`
+		SELECT type, COALESCE(SUM(amount), 0) FROM user_transactions
+		WHERE user_id = ? AND created_at >= ?
+		GROUP BY type
+	`, userID, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+	var after float64
+	for rows.Next() {
+		var txType string
+		var total float64
+		if err := rows.Scan(&txType, &total); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		after += transactionSign(txType) * total
+	}
+	rows.Close()
+	st.ClosingBalance = balance - after
+
+	// ธุรกรรมภายในเดือน
+	rows, err = db.Query(`
+		SELECT type, amount, description, created_at
+		FROM user_transactions
+		WHERE user_id = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC, id ASC
+	`, userID, monthStart, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var net float64
+	for rows.Next() {
+		var entry statementEntry
+		var amount float64
+		if err := rows.Scan(&entry.Type, &amount, &entry.Description, &entry.Date); err != nil {
+			return nil, err
+		}
+		entry.Amount = transactionSign(entry.Type) * amount
+		net += entry.Amount
+		st.Totals[entry.Type] += amount
+
+		switch entry.Type {
+		case TransactionDeposit:
+			st.Deposits += amount
+		case TransactionPurchase:
+			st.Purchases += amount
+		case TransactionRefund:
+			st.Refunds += amount
+		case TransactionAdjustment:
+			st.Adjustments += amount
+		case TransactionGiftReceived:
+			st.Gifts += amount
+		}
+		st.Transactions = append(st.Transactions, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// ยอดคงเหลือหลังแต่ละรายการ
+	st.OpeningBalance = st.ClosingBalance - net
+	running := st.OpeningBalance
+	for i := range st.Transactions {
+		running += st.Transactions[i].Amount
+		st.Transactions[i].Balance = running
+	}
+
+	return st, nil
+}
+
+// statementLines จัดรูปแบบใบแจ้งยอดเป็นบรรทัดข้อความสำหรับ PDF
+func statementLines(st *statement, loc *time.Location) []string {
+	lines := []string{
+		"GAME SHOP - WALLET STATEMENT",
+		fmt.Sprintf("Account: %s", st.Username),
+		fmt.Sprintf("Period:  %s", st.Month),
+		strings.Repeat("=", 80),
+		fmt.Sprintf("%-28s %12s", "Opening balance", formatAmount(st.OpeningBalance)),
+		fmt.Sprintf("%-28s %12s", "Deposits", formatAmount(st.Deposits)),
+		fmt.Sprintf("%-28s %12s", "Purchases", formatAmount(-st.Purchases)),
+		fmt.Sprintf("%-28s %12s", "Refunds", formatAmount(st.Refunds)),
+		fmt.Sprintf("%-28s %12s", "Adjustments", formatAmount(st.Adjustments)),
+		fmt.Sprintf("%-28s %12s", "Gifts", formatAmount(st.Gifts)),
+		fmt.Sprintf("%-28s %12s", "Closing balance", formatAmount(st.ClosingBalance)),
+		strings.Repeat("=", 80),
+		fmt.Sprintf("%-26s %-13s %-15s %11s %11s", "Date", "Type", "Description", "Amount", "Balance"),
+		strings.Repeat("-", 80),
+	}
+	for _, entry := range st.Transactions {
+		description := entry.Description
+		if len(description) > 15 {
+			description = description[:14] + "~"
+		}
+		lines = append(lines, fmt.Sprintf("%-26s %-13s %-15s %11s %11s",
+			formatExportTime(entry.Date.Time, loc), entry.Type, description, formatAmount(entry.Amount), formatAmount(entry.Balance)))
+	}
+	if len(st.Transactions) == 0 {
+		lines = append(lines, "No transactions in this period")
+	}
+	return lines
+}
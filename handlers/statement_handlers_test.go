@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// เงินจากการแลกบัตรของขวัญ (gift_received) เข้ากระเป๋า จึงต้องนับเป็นยอดบวกในใบแจ้งยอด
+func TestStatementCountsGiftCardCredits(t *testing.T) {
+	mock := useMockDB(t)
+	monthStart := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	mock.ExpectQuery("SELECT username, wallet_balance FROM users").WithArgs(7).WillReturnRows(
+		sqlmock.NewRows([]string{"username", "wallet_balance"}).AddRow("player", 45.0))
+	mock.ExpectQuery("GROUP BY type").WithArgs(7, monthEnd).WillReturnRows(
+		sqlmock.NewRows([]string{"type", "total"}).AddRow(TransactionGiftReceived, 25.0))
+	mock.ExpectQuery("ORDER BY created_at ASC").WithArgs(7, monthStart, monthEnd).WillReturnRows(
+		sqlmock.NewRows([]string{"type", "amount", "description", "created_at"}).
+			AddRow(TransactionGiftReceived, 10.0, "Gift card redeemed: GIFT-A", monthStart.Add(time.Hour)).
+			AddRow(TransactionPurchase, 5.0, "Purchase", monthStart.Add(2*time.Hour)))
+
+	st, err := buildStatement(7, monthStart, monthEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ClosingBalance != 20 || st.OpeningBalance != 15 || st.Gifts != 10 || st.Deposits != 0 {
+		t.Errorf("statement = opening %.2f, gifts %.2f, deposits %.2f, closing %.2f",
+			st.OpeningBalance, st.Gifts, st.Deposits, st.ClosingBalance)
+	}
+	if got := st.Transactions[0].Amount; got != 10 {
+		t.Errorf("gift card entry amount = %.2f, want +10", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,654 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsBucketFormats รูปแบบวันที่ (แบบ DATE_FORMAT ของ MySQL ดู dialect.DateFormat) สำหรับจัดกลุ่มข้อมูลตามช่วงเวลา (?group_by=)
+var statsBucketFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%x-W%v", // ISO week เช่น 2025-W07
+	"month": "%Y-%m",
+}
+
+// parseStatsDateRange อ่านช่วงวันที่จาก query (?from=&to=) รูปแบบ YYYY-MM-DD
+// ค่าเริ่มต้นคือ 30 วันล่าสุด, ค่า to ที่คืนกลับเป็นวันถัดไปเพื่อใช้เงื่อนไข < to (รวมวัน to ทั้งวัน)
+func parseStatsDateRange(r *http.Request) (from, to time.Time, err error) {
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	from = today.AddDate(0, 0, -29)
+	to = today
+
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return from, to, fmt.Errorf("invalid from date format. Use YYYY-MM-DD")
+		}
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return from, to, fmt.Errorf("invalid to date format. Use YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("to date must not be before from date")
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// AdminRevenueStatsHandler handles revenue analytics grouped by period
+// ฟังก์ชันสำหรับดึงสถิติรายได้ตามช่วงเวลา (สำหรับกราฟใน dashboard)
+// GET /admin/stats/revenue?from=YYYY-MM-DD&to=YYYY-MM-DD&group_by=day|week|month&top=5
+func AdminRevenueStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// อ่านช่วงวันที่
+	from, to, err := parseStatsDateRange(r)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// อ่านรูปแบบการจัดกลุ่ม
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+	bucketFormat, ok := statsBucketFormats[groupBy]
+	if !ok {
+		utils.JSONError(w, "Invalid group_by. Use day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	// จำนวนเกมขายดีที่จะแสดงต่อช่วงเวลา
+	topN := 5
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		if t, err := strconv.Atoi(topStr); err == nil && t > 0 && t <= 50 {
+			topN = t
+		}
+	}
+
+	fmt.Printf("📊 Fetching revenue stats: from=%s to=%s group_by=%s\n",
+		from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"), groupBy)
+
+	// เก็บข้อมูลแต่ละช่วงเวลา (key = ชื่อ bucket)
+	buckets := map[string]map[string]interface{}{}
+	getBucket := func(key string) map[string]interface{} {
+		if b, ok := buckets[key]; ok {
+			return b
+		}
+		b := map[string]interface{}{
+			"period":              key,
+			"revenue":             0.0,
+			"order_count":         0,
+			"average_order_value": 0.0,
+			"tax":                 0.0,
+			"new_users":           0,
+			"top_games":           []map[string]interface{}{},
+		}
+		buckets[key] = b
+		return b
+	}
+
+	// 1. รายได้ (ไม่รวมภาษี) จำนวนคำสั่งซื้อ มูลค่าเฉลี่ยต่อคำสั่งซื้อ และภาษีที่เก็บได้
+	rows, err := readQuery(`
+		SELECT `+dialect.DateFormat("p.purchase_date", bucketFormat)+` as period,
+		       COALESCE(SUM(p.final_amount), 0), COUNT(*), COALESCE(AVG(p.final_amount), 0),
+		       COALESCE(SUM(p.tax_amount), 0)
+		FROM purchases p
+		WHERE p.purchase_date >= ? AND p.purchase_date < ?
+		GROUP BY period
+	`, from, to)
+	if err != nil {
+		fmt.Printf("❌ Error fetching revenue stats: %v\n", err)
+		utils.JSONError(w, "Error fetching revenue stats", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var period string
+		var revenue, avgOrder, tax float64
+		var orderCount int
+		if err := rows.Scan(&period, &revenue, &orderCount, &avgOrder, &tax); err != nil {
+			fmt.Printf("❌ Error scanning revenue row: %v\n", err)
+			utils.JSONError(w, "Error fetching revenue stats", http.StatusInternalServerError)
+			return
+		}
+		b := getBucket(period)
+		b["revenue"] = revenue
+		b["order_count"] = orderCount
+		b["average_order_value"] = avgOrder
+		b["tax"] = tax
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading revenue rows: %v\n", err)
+		utils.JSONError(w, "Error fetching revenue stats", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	// 2. จำนวนผู้ใช้ใหม่
+	rows, err = readQuery(`
+		SELECT `+dialect.DateFormat("u.created_at", bucketFormat)+` as period, COUNT(*)
+		FROM users u
+		WHERE u.created_at >= ? AND u.created_at < ?
+		GROUP BY period
+	`, from, to)
+	if err != nil {
+		fmt.Printf("❌ Error fetching new user stats: %v\n", err)
+		utils.JSONError(w, "Error fetching new user stats", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var period string
+		var newUsers int
+		if err := rows.Scan(&period, &newUsers); err != nil {
+			fmt.Printf("❌ Error scanning new user row: %v\n", err)
+			utils.JSONError(w, "Error fetching new user stats", http.StatusInternalServerError)
+			return
+		}
+		getBucket(period)["new_users"] = newUsers
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading new user rows: %v\n", err)
+		utils.JSONError(w, "Error fetching new user stats", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	// 3. เกมขายดีในแต่ละช่วงเวลา (เรียงตามจำนวนที่ขายได้มากที่สุด)
+	rows, err = readQuery(`
+		SELECT `+dialect.DateFormat("p.purchase_date", bucketFormat)+` as period,
+		       g.id, g.name, COUNT(*) as units_sold, COALESCE(SUM(pi.price_at_purchase), 0) as revenue
+		FROM purchase_items pi
+		JOIN purchases p ON pi.purchase_id = p.id
+		JOIN games g ON pi.game_id = g.id
+		WHERE p.purchase_date >= ? AND p.purchase_date < ?
+		GROUP BY period, g.id, g.name
+		ORDER BY period, units_sold DESC, revenue DESC
+	`, from, to)
+	if err != nil {
+		fmt.Printf("❌ Error fetching top games stats: %v\n", err)
+		utils.JSONError(w, "Error fetching top games stats", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var period, name string
+		var gameID, unitsSold int
+		var revenue float64
+		if err := rows.Scan(&period, &gameID, &name, &unitsSold, &revenue); err != nil {
+			fmt.Printf("❌ Error scanning top game row: %v\n", err)
+			utils.JSONError(w, "Error fetching top games stats", http.StatusInternalServerError)
+			return
+		}
+		b := getBucket(period)
+		topGames := b["top_games"].([]map[string]interface{})
+		if len(topGames) < topN {
+			b["top_games"] = append(topGames, map[string]interface{}{
+				"game_id":    gameID,
+				"name":       name,
+				"units_sold": unitsSold,
+				"revenue":    revenue,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading top game rows: %v\n", err)
+		utils.JSONError(w, "Error fetching top games stats", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	// เรียงช่วงเวลาจากเก่าไปใหม่ (รูปแบบ bucket เรียงตามตัวอักษรได้ถูกต้อง)
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	series := make([]map[string]interface{}, 0, len(keys))
+	var totalRevenue, totalTax float64
+	var totalOrders, totalNewUsers int
+	for _, key := range keys {
+		b := buckets[key]
+		series = append(series, b)
+		totalRevenue += b["revenue"].(float64)
+		totalTax += b["tax"].(float64)
+		totalOrders += b["order_count"].(int)
+		totalNewUsers += b["new_users"].(int)
+	}
+
+	// คำนวณมูลค่าเฉลี่ยต่อคำสั่งซื้อของทั้งช่วง
+	averageOrderValue := 0.0
+	if totalOrders > 0 {
+		averageOrderValue = totalRevenue / float64(totalOrders)
+	}
+
+	fmt.Printf("✅ Revenue stats loaded: %d periods, revenue=%.2f\n", len(series), totalRevenue)
+
+	// ส่งออกเป็น CSV (หนึ่งแถวต่อช่วงเวลา)
+	if wantsCSV(r) {
+		cw := utils.CSVWriter(w, fmt.Sprintf("revenue_%s.csv", groupBy))
+		cw.Write([]string{"period", "revenue", "order_count", "average_order_value", "tax", "new_users"})
+		for _, b := range series {
+			cw.Write([]string{
+				b["period"].(string),
+				formatAmount(b["revenue"].(float64)),
+				strconv.Itoa(b["order_count"].(int)),
+				formatAmount(b["average_order_value"].(float64)),
+				formatAmount(b["tax"].(float64)),
+				strconv.Itoa(b["new_users"].(int)),
+			})
+		}
+		cw.Flush()
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"group_by": groupBy,
+		"series":   series,
+		"totals": map[string]interface{}{
+			"revenue":             totalRevenue,
+			"order_count":         totalOrders,
+			"average_order_value": averageOrderValue,
+			"tax":                 totalTax,
+			"new_users":           totalNewUsers,
+		},
+		"success": true,
+	}, http.StatusOK)
+}
+
+// AdminGameStatsHandler handles sales analytics for a single game
+// ฟังก์ชันสำหรับดึงสถิติการขายของเกมเฉพาะเกม
+// GET /admin/games/{id}/stats?from=&to=&group_by=day|week|month
+func AdminGameStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ดึง game_id จาก URL path
+	// ตัวอย่าง URL: /admin/games/123/stats → gameID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 4 {
+		utils.JSONError(w, "Game ID required", http.StatusBadRequest)
+		return
+	}
+	gameID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	// อ่านช่วงวันที่และรูปแบบการจัดกลุ่ม
+	from, to, err := parseStatsDateRange(r)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+	bucketFormat, ok := statsBucketFormats[groupBy]
+	if !ok {
+		utils.JSONError(w, "Invalid group_by. Use day, week or month", http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("📊 Fetching stats for game ID: %d\n", gameID)
+
+	// ข้อมูลพื้นฐานของเกมและอันดับปัจจุบัน
+	var name string
+	var price float64
+	var salesCount, rankPosition sql.NullInt64
+	err = db.QueryRow(`
+		SELECT g.name, g.price, r.sales_count, r.rank_position
+		FROM games g
+		LEFT JOIN ranking r ON g.id = r.game_id
+		WHERE g.id = ?
+	`, gameID).Scan(&name, &price, &salesCount, &rankPosition)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching game", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// ยอดขายตลอดอายุ
+	var lifetimeUnits int
+	var lifetimeRevenue float64
+	err = db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(price_at_purchase), 0)
+		FROM purchase_items WHERE game_id = ?
+	`, gameID).Scan(&lifetimeUnits, &lifetimeRevenue)
+	if err != nil {
+		fmt.Printf("❌ Error fetching lifetime sales: %v\n", err)
+		utils.JSONError(w, "Error fetching game sales", http.StatusInternalServerError)
+		return
+	}
+
+	// ยอดขายตามช่วงเวลา
+	rows, err := db.Query(`
+		SELECT `+dialect.DateFormat("p.purchase_date", bucketFormat)+` as period,
+		       COUNT(*), COALESCE(SUM(pi.price_at_purchase), 0)
+		FROM purchase_items pi
+		JOIN purchases p ON pi.purchase_id = p.id
+		WHERE pi.game_id = ? AND p.purchase_date >= ? AND p.purchase_date < ?
+		GROUP BY period
+		ORDER BY period
+	`, gameID, from, to)
+	if err != nil {
+		fmt.Printf("❌ Error fetching game sales series: %v\n", err)
+		utils.JSONError(w, "Error fetching game sales", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	series := []map[string]interface{}{}
+	for rows.Next() {
+		var period string
+		var units int
+		var revenue float64
+		if err := rows.Scan(&period, &units, &revenue); err != nil {
+			fmt.Printf("❌ Error scanning game sales row: %v\n", err)
+			utils.JSONError(w, "Error fetching game sales", http.StatusInternalServerError)
+			return
+		}
+		series = append(series, map[string]interface{}{
+			"period":     period,
+			"units_sold": units,
+			"revenue":    revenue,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading game sales rows: %v\n", err)
+		utils.JSONError(w, "Error fetching game sales", http.StatusInternalServerError)
+		return
+	}
+
+	// อัตราการเปลี่ยนจากการเพิ่มลงตะกร้าเป็นการซื้อ (นับตามจำนวนผู้ใช้)
+	var cartAdders, cartConverted int
+	err = db.QueryRow(`
+		SELECT COUNT(DISTINCT ce.user_id),
+		       COUNT(DISTINCT CASE WHEN pg.user_id IS NOT NULL THEN ce.user_id END)
+		FROM cart_events ce
+		LEFT JOIN purchased_games pg ON pg.user_id = ce.user_id AND pg.game_id = ce.game_id
+		WHERE ce.game_id = ?
+	`, gameID).Scan(&cartAdders, &cartConverted)
+	if err != nil {
+		fmt.Printf("⚠️ Error fetching cart conversion: %v\n", err)
+	}
+	conversionRate := 0.0
+	if cartAdders > 0 {
+		conversionRate = float64(cartConverted) / float64(cartAdders) * 100
+	}
+
+	// ระบบยังไม่มีการคืนเงิน จึงยังไม่มีรายการคืนเงินให้นับ
+	refundCount := 0
+	refundRate := 0.0
+
+	fmt.Printf("✅ Game stats loaded: ID=%d, Units=%d, Revenue=%.2f\n", gameID, lifetimeUnits, lifetimeRevenue)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"game_id":  gameID,
+		"name":     name,
+		"price":    price,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"group_by": groupBy,
+		"series":   series,
+		"lifetime": map[string]interface{}{
+			"units_sold": lifetimeUnits,
+			"revenue":    lifetimeRevenue,
+		},
+		"refunds": map[string]interface{}{
+			"count": refundCount,
+			"rate":  refundRate,
+		},
+		"conversion": map[string]interface{}{
+			"cart_adders":     cartAdders,
+			"purchased":       cartConverted,
+			"conversion_rate": conversionRate, // เปอร์เซ็นต์
+		},
+		"ranking": map[string]interface{}{
+			"rank_position": rankPosition.Int64,
+			"sales_count":   salesCount.Int64,
+		},
+		"success": true,
+	}, http.StatusOK)
+}
+
+// AdminCustomerStatsHandler handles customer analytics (top spenders, repeat rate, cohorts)
+// ฟังก์ชันสำหรับดึงสถิติลูกค้า: ผู้ใช้ที่ใช้จ่ายสูงสุด อัตราการซื้อซ้ำ และ cohort ตามเดือนที่สมัคร
+// GET /admin/stats/customers?limit=20&offset=0&months=6
+func AdminCustomerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// รับ query parameters สำหรับ pagination และจำนวนเดือนของ cohort
+	query := r.URL.Query()
+	limit := 20
+	offset := 0
+	months := 6
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	if m, err := strconv.Atoi(query.Get("months")); err == nil && m > 0 && m <= 24 {
+		months = m
+	}
+
+	fmt.Printf("📊 Fetching customer stats: limit=%d offset=%d months=%d\n", limit, offset, months)
+
+	// ส่งออกเป็น CSV: รายชื่อผู้ใช้ที่ใช้จ่ายสูงสุด (อ่านจาก cursor โดยตรง)
+	if wantsCSV(r) {
+		csvQuery := `
+			SELECT u.id as user_id, u.username, u.email, COUNT(p.id) as order_count,
+			       COALESCE(SUM(p.final_amount), 0) as total_spent,
+			       MAX(p.purchase_date) as last_purchase
+			FROM purchases p
+			JOIN users u ON p.user_id = u.id
+			GROUP BY u.id, u.username, u.email
+			ORDER BY total_spent DESC, order_count DESC
+		`
+		var csvArgs []interface{}
+		if query.Get("limit") != "" {
+			csvQuery += " LIMIT ? OFFSET ?"
+			csvArgs = append(csvArgs, limit, offset)
+		}
+		loc, err := exportLocation(r)
+		if err != nil {
+			utils.JSONError(w, "Invalid timezone", http.StatusBadRequest)
+			return
+		}
+		streamQueryCSV(w, loc, "customers.csv", csvQuery, csvArgs...)
+		return
+	}
+
+	// 1. ผู้ใช้ที่ใช้จ่ายสูงสุด
+	rows, err := readQuery(`
+		SELECT u.id, u.username, u.email, COUNT(p.id) as order_count,
+		       COALESCE(SUM(p.final_amount), 0) as total_spent,
+		       MAX(p.purchase_date) as last_purchase
+		FROM purchases p
+		JOIN users u ON p.user_id = u.id
+		GROUP BY u.id, u.username, u.email
+		ORDER BY total_spent DESC, order_count DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching top spenders: %v\n", err)
+		utils.JSONError(w, "Error fetching top spenders", http.StatusInternalServerError)
+		return
+	}
+	topSpenders := []map[string]interface{}{}
+	for rows.Next() {
+		var id, orderCount int
+		var username, email string
+		var lastPurchase utils.Timestamp
+		var totalSpent float64
+		if err := rows.Scan(&id, &username, &email, &orderCount, &totalSpent, &lastPurchase); err != nil {
+			fmt.Printf("❌ Error scanning top spender row: %v\n", err)
+			utils.JSONError(w, "Error fetching top spenders", http.StatusInternalServerError)
+			return
+		}
+		topSpenders = append(topSpenders, map[string]interface{}{
+			"user_id":       id,
+			"username":      username,
+			"email":         email,
+			"order_count":   orderCount,
+			"total_spent":   totalSpent,
+			"last_purchase": lastPurchase,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading top spender rows: %v\n", err)
+		utils.JSONError(w, "Error fetching top spenders", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	// 2. อัตราการซื้อซ้ำ (ผู้ซื้อที่ซื้อมากกว่า 1 ครั้ง / ผู้ซื้อทั้งหมด)
+	var buyers, repeatBuyers int
+	err = readQueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN order_count >= 2 THEN 1 ELSE 0 END), 0)
+		FROM (SELECT user_id, COUNT(*) as order_count FROM purchases GROUP BY user_id) t
+	`).Scan(&buyers, &repeatBuyers)
+	if err != nil {
+		fmt.Printf("❌ Error fetching repeat purchase rate: %v\n", err)
+		utils.JSONError(w, "Error fetching repeat purchase rate", http.StatusInternalServerError)
+		return
+	}
+	repeatRate := 0.0
+	if buyers > 0 {
+		repeatRate = float64(repeatBuyers) / float64(buyers) * 100
+	}
+
+	// 3. Cohort ตามเดือนที่สมัคร
+	now := time.Now()
+	cohortStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -(months - 1), 0)
+
+	cohorts := map[string]map[string]interface{}{}
+	rows, err = readQuery(`
+		SELECT `+dialect.DateFormat("created_at", "%Y-%m")+` as cohort, COUNT(*)
+		FROM users
+		WHERE role != 'admin' AND created_at >= ?
+		GROUP BY cohort
+	`, cohortStart)
+	if err != nil {
+		fmt.Printf("❌ Error fetching signup cohorts: %v\n", err)
+		utils.JSONError(w, "Error fetching signup cohorts", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var cohort string
+		var size int
+		if err := rows.Scan(&cohort, &size); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning signup cohort: %v\n", err)
+			utils.JSONError(w, "Error fetching signup cohorts", http.StatusInternalServerError)
+			return
+		}
+		cohorts[cohort] = map[string]interface{}{
+			"cohort":    cohort,
+			"signups":   size,
+			"retention": []map[string]interface{}{},
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading signup cohorts: %v\n", err)
+		utils.JSONError(w, "Error fetching signup cohorts", http.StatusInternalServerError)
+		return
+	}
+
+	// จำนวนผู้ใช้ใน cohort ที่ซื้อในแต่ละเดือนหลังสมัคร (month_offset 0 = เดือนที่สมัคร)
+	rows, err = readQuery(`
+		SELECT `+dialect.DateFormat("u.created_at", "%Y-%m")+` as cohort,
+		       TIMESTAMPDIFF(MONTH, `+dialect.DateFormat("u.created_at", "%Y-%m-01")+`, `+dialect.DateFormat("p.purchase_date", "%Y-%m-01")+`) as month_offset,
+		       COUNT(DISTINCT p.user_id) as active_buyers
+		FROM users u
+		JOIN purchases p ON p.user_id = u.id
+		WHERE u.role != 'admin' AND u.created_at >= ?
+		GROUP BY cohort, month_offset
+		ORDER BY cohort, month_offset
+	`, cohortStart)
+	if err != nil {
+		fmt.Printf("❌ Error fetching cohort retention: %v\n", err)
+		utils.JSONError(w, "Error fetching cohort retention", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var cohort string
+		var monthOffset, activeBuyers int
+		if err := rows.Scan(&cohort, &monthOffset, &activeBuyers); err != nil {
+			rows.Close()
+			fmt.Printf("❌ Error scanning cohort retention: %v\n", err)
+			utils.JSONError(w, "Error fetching cohort retention", http.StatusInternalServerError)
+			return
+		}
+		c, ok := cohorts[cohort]
+		if !ok {
+			continue
+		}
+		retentionRate := 0.0
+		if size := c["signups"].(int); size > 0 {
+			retentionRate = float64(activeBuyers) / float64(size) * 100
+		}
+		c["retention"] = append(c["retention"].([]map[string]interface{}), map[string]interface{}{
+			"month_offset":   monthOffset,
+			"active_buyers":  activeBuyers,
+			"retention_rate": retentionRate,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading cohort retention: %v\n", err)
+		utils.JSONError(w, "Error fetching cohort retention", http.StatusInternalServerError)
+		return
+	}
+
+	// เรียง cohort จากเก่าไปใหม่
+	cohortKeys := make([]string, 0, len(cohorts))
+	for key := range cohorts {
+		cohortKeys = append(cohortKeys, key)
+	}
+	sort.Strings(cohortKeys)
+	cohortList := make([]map[string]interface{}, 0, len(cohortKeys))
+	for _, key := range cohortKeys {
+		cohortList = append(cohortList, cohorts[key])
+	}
+
+	fmt.Printf("✅ Customer stats loaded: buyers=%d, repeat=%d\n", buyers, repeatBuyers)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"top_spenders": topSpenders,
+		"limit":        limit,
+		"offset":       offset,
+		"total_buyers": buyers,
+		"repeat_purchase": map[string]interface{}{
+			"buyers":        buyers,
+			"repeat_buyers": repeatBuyers,
+			"rate":          repeatRate, // เปอร์เซ็นต์
+		},
+		"cohorts": cohortList,
+		"success": true,
+	}, http.StatusOK)
+}
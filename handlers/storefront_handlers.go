@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// storefrontCollectionLimit จำนวนเกมสูงสุดที่แสดงต่อคอลเลกชันในหน้าแรก
+const storefrontCollectionLimit = 50
+
+// slugPattern รูปแบบ slug ของคอลเลกชัน (a-z, 0-9 และ -)
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// storefrontBanner แบนเนอร์หน้าแรก (starts_at/ends_at รูปแบบ RFC3339 ไม่ระบุ = ไม่จำกัดเวลา)
+type storefrontBanner struct {
+	ID       int     `json:"id"`
+	Title    string  `json:"title" validate:"required,max=150"`
+	Subtitle string  `json:"subtitle" validate:"max=255"`
+	ImageURL string  `json:"image_url" validate:"required,max=500"`
+	LinkURL  string  `json:"link_url" validate:"max=500"`
+	GameID   *int    `json:"game_id"`
+	Position int     `json:"position"`
+	Active   bool    `json:"active"`
+	StartsAt *string `json:"starts_at"`
+	EndsAt   *string `json:"ends_at"`
+}
+
+// storefrontCollection คอลเลกชันเกมที่คัดสรร GameIDs เรียงตามลำดับที่แสดง
+type storefrontCollection struct {
+	ID          int    `json:"id"`
+	Slug        string `json:"slug" validate:"required,max=100"`
+	Title       string `json:"title" validate:"required,max=150"`
+	Description string `json:"description" validate:"max=500"`
+	Position    int    `json:"position"`
+	Active      bool   `json:"active"`
+	GameIDs     []int  `json:"game_ids"`
+}
+
+func storefrontBannerColumns() string {
+	return `id, title, subtitle, image_url, link_url, game_id, position, active, starts_at, ends_at`
+}
+
+// StorefrontHandler returns the homepage layout
+// ฟังก์ชันสำหรับดึงหน้าแรกของร้าน: แบนเนอร์ที่อยู่ในช่วงเวลาแสดงผล และคอลเลกชันพร้อมรายการเกม
+// GET /storefront
+func StorefrontHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	banners, err := loadStorefrontBanners(`WHERE active = 1
+		AND (starts_at IS NULL OR starts_at <= UTC_TIMESTAMP())
+		AND (ends_at IS NULL OR ends_at > UTC_TIMESTAMP())`)
+	if err != nil {
+		fmt.Printf("❌ Error fetching storefront banners: %v\n", err)
+		utils.JSONError(w, "Error fetching storefront", http.StatusInternalServerError)
+		return
+	}
+
+	collections, err := loadStorefrontCollections("WHERE active = 1")
+	if err != nil {
+		fmt.Printf("❌ Error fetching storefront collections: %v\n", err)
+		utils.JSONError(w, "Error fetching storefront", http.StatusInternalServerError)
+		return
+	}
+
+	sections := []map[string]interface{}{}
+	for _, collection := range collections {
+		games, err := queryGameList(`
+			AND g.id IN (SELECT game_id FROM storefront_collection_games WHERE collection_id = ?)
+		`, "(SELECT position FROM storefront_collection_games WHERE collection_id = ? AND game_id = g.id), g.id",
+			storefrontCollectionLimit, collection.ID, collection.ID)
+		if err != nil {
+			fmt.Printf("❌ Error fetching collection %s: %v\n", collection.Slug, err)
+			utils.JSONError(w, "Error fetching storefront", http.StatusInternalServerError)
+			return
+		}
+		// ไม่แสดงคอลเลกชันที่ไม่มีเกมที่เผยแพร่อยู่
+		if len(games) == 0 {
+			continue
+		}
+		localizeGames(r, games)
+		sections = append(sections, map[string]interface{}{
+			"id":          collection.ID,
+			"slug":        collection.Slug,
+			"title":       collection.Title,
+			"description": collection.Description,
+			"games":       games,
+		})
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"banners":     banners,
+		"collections": sections,
+	}, http.StatusOK)
+}
+
+// loadStorefrontBanners ดึงแบนเนอร์ตามเงื่อนไข เรียงตาม position
+func loadStorefrontBanners(where string) ([]storefrontBanner, error) {
+	rows, err := db.Query("SELECT " + storefrontBannerColumns() + " FROM storefront_banners " + where + " ORDER BY position, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	banners := []storefrontBanner{}
+	for rows.Next() {
+		var b storefrontBanner
+		var subtitle, linkURL sql.NullString
+		var startsAt, endsAt utils.NullTimestamp
+		var gameID sql.NullInt64
+		if err := rows.Scan(&b.ID, &b.Title, &subtitle, &b.ImageURL, &linkURL, &gameID, &b.Position, &b.Active,
+			&startsAt, &endsAt); err != nil {
+			return nil, err
+		}
+		b.Subtitle, b.LinkURL = subtitle.String, linkURL.String
+		if gameID.Valid {
+			id := int(gameID.Int64)
+			b.GameID = &id
+		}
+		b.StartsAt, b.EndsAt = startsAt.Ptr(), endsAt.Ptr()
+		banners = append(banners, b)
+	}
+	return banners, rows.Err()
+}
+
+// loadStorefrontCollections ดึงคอลเลกชันตามเงื่อนไข พร้อม ID ของเกมตามลำดับ
+func loadStorefrontCollections(where string) ([]storefrontCollection, error) {
+	rows, err := db.Query("SELECT id, slug, title, description, position, active FROM storefront_collections " + where + " ORDER BY position, id")
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []storefrontCollection{}
+	for rows.Next() {
+		var c storefrontCollection
+		var description sql.NullString
+		if err := rows.Scan(&c.ID, &c.Slug, &c.Title, &description, &c.Position, &c.Active); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Description = description.String
+		collections = append(collections, c)
+	}
+	rows.Close()
+
+	for i := range collections {
+		gameIDs, err := collectionGameIDs(collections[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		collections[i].GameIDs = gameIDs
+	}
+	return collections, nil
+}
+
+// collectionGameIDs ID ของเกมในคอลเลกชันตามลำดับ
+func collectionGameIDs(collectionID int) ([]int, error) {
+	rows, err := db.Query("SELECT game_id FROM storefront_collection_games WHERE collection_id = ? ORDER BY position, game_id", collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gameIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		gameIDs = append(gameIDs, id)
+	}
+	return gameIDs, rows.Err()
+}
+
+// AdminStorefrontHandler handles storefront layout management
+// ฟังก์ชันสำหรับจัดการหน้าแรกของร้าน (แบนเนอร์, คอลเลกชัน และลำดับการแสดงผล)
+// GET /admin/storefront, PUT /admin/storefront/order,
+// POST /admin/storefront/banners, PUT/DELETE /admin/storefront/banners/{id},
+// POST /admin/storefront/collections, PUT/DELETE /admin/storefront/collections/{id}
+func AdminStorefrontHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/storefront/banners/3 → section = banners, id = 3
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	section := ""
+	if len(pathParts) >= 3 {
+		section = pathParts[2]
+	}
+	var id int
+	if len(pathParts) >= 4 {
+		parsedID, err := strconv.Atoi(pathParts[3])
+		if err != nil || parsedID <= 0 {
+			utils.JSONError(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		id = parsedID
+	}
+
+	switch {
+	case section == "" && r.Method == "GET":
+		getStorefrontLayout(w)
+	case section == "order" && r.Method == "PUT":
+		reorderStorefront(w, r)
+	case section == "banners" || section == "collections":
+		switch {
+		case r.Method == "POST" && id == 0:
+			saveStorefrontItem(w, r, section, 0)
+		case r.Method == "PUT" && id > 0:
+			saveStorefrontItem(w, r, section, id)
+		case r.Method == "DELETE" && id > 0:
+			deleteStorefrontItem(w, r, section, id)
+		case r.Method == "PUT" || r.Method == "DELETE":
+			utils.JSONError(w, "ID required", http.StatusBadRequest)
+		default:
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case section == "" || section == "order":
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getStorefrontLayout ดึงโครงสร้างหน้าแรกทั้งหมด รวมรายการที่ปิดใช้งานหรือหมดช่วงเวลาแสดงผล
+func getStorefrontLayout(w http.ResponseWriter) {
+	banners, err := loadStorefrontBanners("")
+	if err != nil {
+		fmt.Printf("❌ Error fetching storefront banners: %v\n", err)
+		utils.JSONError(w, "Error fetching storefront", http.StatusInternalServerError)
+		return
+	}
+	collections, err := loadStorefrontCollections("")
+	if err != nil {
+		fmt.Printf("❌ Error fetching storefront collections: %v\n", err)
+		utils.JSONError(w, "Error fetching storefront", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"banners":     banners,
+		"collections": collections,
+	}, http.StatusOK)
+}
+
+// parseStorefrontTime แปลงเวลา RFC3339 เป็น UTC (nil หรือค่าว่าง = ไม่จำกัดเวลา)
+func parseStorefrontTime(value *string) (interface{}, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, err
+	}
+	return t.UTC(), nil
+}
+
+// nullIfEmpty แปลงสตริงว่างเป็น NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// saveStorefrontItem สร้าง (id = 0) หรือแก้ไขแบนเนอร์/คอลเลกชัน
+func saveStorefrontItem(w http.ResponseWriter, r *http.Request, section string, id int) {
+	table, entity := "storefront_banners", "storefront_banner"
+	if section == "collections" {
+		table, entity = "storefront_collections", "storefront_collection"
+	}
+
+	var before map[string]interface{}
+	if id > 0 {
+		before = snapshotRow("SELECT * FROM "+table+" WHERE id = ?", id)
+		if before == nil {
+			utils.JSONError(w, "Not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// ไม่ระบุ position = ต่อท้ายลำดับ (สร้างใหม่) หรือคงลำดับเดิม (แก้ไข)
+	var position int
+	if id == 0 {
+		tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM " + table).Scan(&position)
+	} else {
+		tx.QueryRow("SELECT position FROM "+table+" WHERE id = ?", id).Scan(&position)
+	}
+
+	if section == "banners" {
+		req := storefrontBanner{Active: true, Position: position}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+		startsAt, err := parseStorefrontTime(req.StartsAt)
+		if err != nil {
+			utils.JSONError(w, "Invalid starts_at. Use RFC3339 format", http.StatusBadRequest)
+			return
+		}
+		endsAt, err := parseStorefrontTime(req.EndsAt)
+		if err != nil {
+			utils.JSONError(w, "Invalid ends_at. Use RFC3339 format", http.StatusBadRequest)
+			return
+		}
+		if start, ok := startsAt.(time.Time); ok {
+			if end, ok := endsAt.(time.Time); ok && !end.After(start) {
+				utils.JSONError(w, "ends_at must be after starts_at", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if id == 0 {
+			var result sql.Result
+			result, err = tx.Exec(`
+				INSERT INTO storefront_banners (title, subtitle, image_url, link_url, game_id, position, active, starts_at, ends_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, req.Title, nullIfEmpty(req.Subtitle), req.ImageURL, nullIfEmpty(req.LinkURL), req.GameID, req.Position, req.Active, startsAt, endsAt)
+			if err == nil {
+				newID, _ := result.LastInsertId()
+				id = int(newID)
+			}
+		} else {
+			_, err = tx.Exec(`
+				UPDATE storefront_banners
+				SET title = ?, subtitle = ?, image_url = ?, link_url = ?, game_id = ?, position = ?, active = ?, starts_at = ?, ends_at = ?
+				WHERE id = ?
+			`, req.Title, nullIfEmpty(req.Subtitle), req.ImageURL, nullIfEmpty(req.LinkURL), req.GameID, req.Position, req.Active, startsAt, endsAt, id)
+		}
+	} else {
+		req := storefrontCollection{Active: true, Position: position}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+		if !slugPattern.MatchString(req.Slug) {
+			utils.JSONValidationError(w, []utils.FieldError{{Field: "slug", Message: "slug may only contain a-z, 0-9 and single dashes"}})
+			return
+		}
+
+		if id == 0 {
+			var result sql.Result
+			result, err = tx.Exec(`
+				INSERT INTO storefront_collections (slug, title, description, position, active)
+				VALUES (?, ?, ?, ?, ?)
+			`, req.Slug, req.Title, nullIfEmpty(req.Description), req.Position, req.Active)
+			if err == nil {
+				newID, _ := result.LastInsertId()
+				id = int(newID)
+			}
+		} else {
+			_, err = tx.Exec(`
+				UPDATE storefront_collections SET slug = ?, title = ?, description = ?, position = ?, active = ?
+				WHERE id = ?
+			`, req.Slug, req.Title, nullIfEmpty(req.Description), req.Position, req.Active, id)
+		}
+		// game_ids ที่ส่งมาแทนที่รายการเดิมทั้งหมด (ไม่ส่งมา = คงรายการเดิมเมื่อแก้ไข)
+		if err == nil && (req.GameIDs != nil || before == nil) {
+			err = setCollectionGames(tx, id, req.GameIDs)
+		}
+	}
+
+	if err != nil {
+		if _, ok := duplicateKeyName(err); ok {
+			utils.JSONErrorCode(w, utils.ErrConflict, "A collection with this slug already exists", http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error saving %s: %v\n", entity, err)
+		utils.JSONError(w, "Error saving storefront", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error saving storefront", http.StatusInternalServerError)
+		return
+	}
+
+	action, status := "create", http.StatusCreated
+	if before != nil {
+		action, status = "update", http.StatusOK
+	}
+	recordAudit(r, action, entity, id, before, snapshotRow("SELECT * FROM "+table+" WHERE id = ?", id))
+	invalidateCatalogCache()
+
+	fmt.Printf("🏠 Storefront %s saved: ID=%d\n", entity, id)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Storefront updated",
+		"id":      id,
+	}, status)
+}
+
+// setCollectionGames แทนที่รายการเกมในคอลเลกชันตามลำดับที่ส่งมา (ID ซ้ำจะถูกข้าม)
+func setCollectionGames(exec sqlExecutor, collectionID int, gameIDs []int) error {
+	if _, err := exec.Exec("DELETE FROM storefront_collection_games WHERE collection_id = ?", collectionID); err != nil {
+		return err
+	}
+	for position, gameID := range gameIDs {
+		_, err := exec.Exec(`
+			INSERT IGNORE INTO storefront_collection_games (collection_id, game_id, position) VALUES (?, ?, ?)
+		`, collectionID, gameID, position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteStorefrontItem ลบแบนเนอร์หรือคอลเลกชัน (รวมรายการเกมในคอลเลกชัน)
+func deleteStorefrontItem(w http.ResponseWriter, r *http.Request, section string, id int) {
+	table, entity := "storefront_banners", "storefront_banner"
+	if section == "collections" {
+		table, entity = "storefront_collections", "storefront_collection"
+	}
+
+	before := snapshotRow("SELECT * FROM "+table+" WHERE id = ?", id)
+	if before == nil {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if section == "collections" {
+		if _, err := tx.Exec("DELETE FROM storefront_collection_games WHERE collection_id = ?", id); err != nil {
+			fmt.Printf("❌ Error deleting collection games: %v\n", err)
+			utils.JSONError(w, "Error deleting storefront item", http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM "+table+" WHERE id = ?", id); err != nil {
+		fmt.Printf("❌ Error deleting %s: %v\n", entity, err)
+		utils.JSONError(w, "Error deleting storefront item", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error deleting storefront item", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "delete", entity, id, before, nil)
+	invalidateCatalogCache()
+
+	utils.JSONResponse(w, map[string]string{"message": "Storefront item deleted"}, http.StatusOK)
+}
+
+// reorderStorefront จัดลำดับแบนเนอร์และคอลเลกชันใหม่ตามลำดับ ID ที่ส่งมา
+// PUT /admin/storefront/order {"banners": [3, 1, 2], "collections": [2, 1]}
+func reorderStorefront(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Banners     []int `json:"banners"`
+		Collections []int `json:"collections"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for table, ids := range map[string][]int{"storefront_banners": req.Banners, "storefront_collections": req.Collections} {
+		for position, id := range ids {
+			if _, err := tx.Exec("UPDATE "+table+" SET position = ? WHERE id = ?", position, id); err != nil {
+				fmt.Printf("❌ Error reordering %s: %v\n", table, err)
+				utils.JSONError(w, "Error reordering storefront", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error reordering storefront", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, "reorder", "storefront", 0, nil, map[string]interface{}{
+		"banners":     req.Banners,
+		"collections": req.Collections,
+	})
+	invalidateCatalogCache()
+
+	utils.JSONResponse(w, map[string]string{"message": "Storefront order updated"}, http.StatusOK)
+}
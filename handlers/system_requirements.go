@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxRequirementLength ความยาวสูงสุดของแต่ละช่องในสเปกเครื่อง (เช่น "Intel Core i5-8400 / AMD Ryzen 5 2600")
+const maxRequirementLength = 200
+
+// requirementSpec สเปกเครื่องหนึ่งระดับ (ช่องที่ว่างคือไม่ได้ระบุ)
+type requirementSpec struct {
+	OS      string `json:"os"`
+	CPU     string `json:"cpu"`
+	RAM     string `json:"ram"`
+	GPU     string `json:"gpu"`
+	Storage string `json:"storage"`
+}
+
+// systemRequirements ความต้องการของระบบขั้นต่ำและที่แนะนำ (เก็บเป็น JSON ใน games.system_requirements)
+type systemRequirements struct {
+	Minimum     *requirementSpec `json:"minimum"`
+	Recommended *requirementSpec `json:"recommended"`
+}
+
+// normalizeSpec ตัดช่องว่างและตรวจสอบความยาว คืนค่า nil ถ้าไม่ได้ระบุช่องใดเลย
+func normalizeSpec(level string, spec *requirementSpec) (*requirementSpec, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"os", &spec.OS}, {"cpu", &spec.CPU}, {"ram", &spec.RAM}, {"gpu", &spec.GPU}, {"storage", &spec.Storage},
+	}
+	empty := true
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		if len(*f.value) > maxRequirementLength {
+			return nil, fmt.Errorf("system_requirements.%s.%s must be at most %d characters", level, f.name, maxRequirementLength)
+		}
+		if *f.value != "" {
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	return spec, nil
+}
+
+// encodeSystemRequirements ตรวจสอบและแปลงเป็น JSON สำหรับบันทึก (คืนค่าว่างเมื่อไม่มีข้อมูล ซึ่งบันทึกเป็น NULL)
+func encodeSystemRequirements(req *systemRequirements) (string, error) {
+	if req == nil {
+		return "", nil
+	}
+	minimum, err := normalizeSpec("minimum", req.Minimum)
+	if err != nil {
+		return "", err
+	}
+	recommended, err := normalizeSpec("recommended", req.Recommended)
+	if err != nil {
+		return "", err
+	}
+	if minimum == nil && recommended == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(systemRequirements{Minimum: minimum, Recommended: recommended})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseSystemRequirementsForm อ่าน system_requirements จาก form-data (ส่งเป็น JSON string)
+// คืนค่า nil ถ้าไม่ได้ส่งมา
+func parseSystemRequirementsForm(raw string) (*systemRequirements, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var req systemRequirements
+	if err := json.Unmarshal([]byte(raw), &req); err != nil {
+		return nil, fmt.Errorf("system_requirements must be a JSON object with minimum and recommended")
+	}
+	return &req, nil
+}
+
+// addSystemRequirementsField เพิ่มความต้องการของระบบให้ object เกม (null ถ้าไม่ได้ระบุ)
+func addSystemRequirementsField(game map[string]interface{}, raw sql.NullString) {
+	game["system_requirements"] = nil
+	if !raw.Valid || raw.String == "" {
+		return
+	}
+	var req systemRequirements
+	if err := json.Unmarshal([]byte(raw.String), &req); err != nil {
+		fmt.Printf("⚠️ Invalid system requirements for game %v: %v\n", game["id"], err)
+		return
+	}
+	game["system_requirements"] = req
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultTaxRegion รหัสภูมิภาคของกฎภาษีที่ใช้เมื่อไม่มีกฎของภูมิภาคที่ระบุ
+const defaultTaxRegion = "*"
+
+// taxRule กฎภาษี (อัตราเป็นเปอร์เซ็นต์ คิดเพิ่มจากราคาหลังหักส่วนลด)
+type taxRule struct {
+	ID     int     `json:"id,omitempty"`
+	Region string  `json:"region" validate:"required,max=10"`
+	Name   string  `json:"name" validate:"required,max=50"`
+	Rate   float64 `json:"rate" validate:"min=0,max=100"`
+	Active bool    `json:"active"`
+}
+
+// taxLine ภาษีของสินค้าแต่ละรายการในคำสั่งซื้อ
+type taxLine struct {
+	GameID  int     `json:"game_id"`
+	Name    string  `json:"name"`
+	Taxable float64 `json:"taxable_amount"`
+	Tax     float64 `json:"tax_amount"`
+}
+
+// roundCents ปัดเศษจำนวนเงินเป็นทศนิยม 2 ตำแหน่ง
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// flatTaxRule กฎภาษีอัตราเดียวจาก environment (TAX_RATE เป็นเปอร์เซ็นต์, TAX_NAME ค่าเริ่มต้น VAT)
+func flatTaxRule() taxRule {
+	rule := taxRule{Region: defaultTaxRegion, Name: os.Getenv("TAX_NAME")}
+	if rule.Name == "" {
+		rule.Name = "VAT"
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("TAX_RATE"), 64); err == nil && rate > 0 && rate <= 100 {
+		rule.Rate = rate
+		rule.Active = true
+	}
+	return rule
+}
+
+// resolveTaxRule เลือกกฎภาษีของภูมิภาค → กฎเริ่มต้น (*) → อัตราเดียวจาก TAX_RATE ตามลำดับ
+func resolveTaxRule(exec sqlExecutor, region string) (taxRule, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	var rule taxRule
+	err := exec.QueryRow(`
+		SELECT id, region, name, rate, active FROM tax_rules
+		WHERE active = 1 AND region IN (?, ?)
+		ORDER BY region = ? DESC
+		LIMIT 1
+	`, region, defaultTaxRegion, defaultTaxRegion).Scan(&rule.ID, &rule.Region, &rule.Name, &rule.Rate, &rule.Active)
+	if err == sql.ErrNoRows {
+		return flatTaxRule(), nil
+	}
+	return rule, err
+}
+
+// computeTaxLines คำนวณภาษีรายสินค้า โดยกระจายส่วนลดตามสัดส่วนราคา (subtotal = ราคารวมก่อนหักส่วนลด)
+// ภาษีรวมคือผลรวมของแต่ละรายการ เพื่อให้ยอดในใบเสร็จตรงกับรายการเสมอ
+func computeTaxLines(rule taxRule, lines []taxLine, subtotal, finalAmount float64) ([]taxLine, float64) {
+	var totalTax float64
+	for i := range lines {
+		taxable := lines[i].Taxable
+		if subtotal > 0 {
+			taxable = roundCents(taxable * finalAmount / subtotal)
+		}
+		lines[i].Taxable = taxable
+		lines[i].Tax = 0
+		if rule.Active && rule.Rate > 0 {
+			lines[i].Tax = roundCents(taxable * rule.Rate / 100)
+		}
+		totalTax += lines[i].Tax
+	}
+	return lines, roundCents(totalTax)
+}
+
+// AdminTaxRuleHandler handles tax rule management
+// ฟังก์ชันสำหรับจัดการกฎภาษี (ตามภูมิภาค หรือ region "*" สำหรับอัตราเริ่มต้น)
+// GET /admin/tax-rules, POST /admin/tax-rules, PUT /admin/tax-rules/{id}, DELETE /admin/tax-rules/{id}
+func AdminTaxRuleHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/tax-rules/3 → id = 3
+	var id int
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) >= 3 {
+		if parsedID, err := strconv.Atoi(pathParts[2]); err == nil {
+			id = parsedID
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+		getTaxRules(w)
+	case "POST":
+		saveTaxRule(w, r, 0)
+	case "PUT":
+		if id > 0 {
+			saveTaxRule(w, r, id)
+		} else {
+			utils.JSONError(w, "Tax rule ID required", http.StatusBadRequest)
+		}
+	case "DELETE":
+		if id > 0 {
+			deleteTaxRule(w, r, id)
+		} else {
+			utils.JSONError(w, "Tax rule ID required", http.StatusBadRequest)
+		}
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getTaxRules ดึงกฎภาษีทั้งหมด พร้อมอัตราเดียวจาก environment ที่ใช้เมื่อไม่มีกฎตรงกัน
+func getTaxRules(w http.ResponseWriter) {
+	rows, err := db.Query("SELECT id, region, name, rate, active FROM tax_rules ORDER BY region = ? DESC, region ASC", defaultTaxRegion)
+	if err != nil {
+		fmt.Printf("❌ Error fetching tax rules: %v\n", err)
+		utils.JSONError(w, "Error fetching tax rules", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rules := []taxRule{}
+	for rows.Next() {
+		var rule taxRule
+		if err := rows.Scan(&rule.ID, &rule.Region, &rule.Name, &rule.Rate, &rule.Active); err != nil {
+			fmt.Printf("❌ Error scanning tax rule: %v\n", err)
+			utils.JSONError(w, "Error fetching tax rules", http.StatusInternalServerError)
+			return
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading tax rule rows: %v\n", err)
+		utils.JSONError(w, "Error fetching tax rules", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"rules":         rules,
+		"fallback_rule": flatTaxRule(),
+	}, http.StatusOK)
+}
+
+// saveTaxRule สร้างกฎภาษีใหม่ (id = 0) หรือแก้ไขกฎเดิม ภูมิภาคหนึ่งมีได้เพียงกฎเดียว
+func saveTaxRule(w http.ResponseWriter, r *http.Request, id int) {
+	req := taxRule{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	var before map[string]interface{}
+	var err error
+	if id == 0 {
+		var result sql.Result
+		result, err = db.Exec("INSERT INTO tax_rules (region, name, rate, active) VALUES (?, ?, ?, ?)",
+			req.Region, req.Name, req.Rate, req.Active)
+		if err == nil {
+			newID, _ := result.LastInsertId()
+			id = int(newID)
+		}
+	} else {
+		before = snapshotRow("SELECT id, region, name, rate, active FROM tax_rules WHERE id = ?", id)
+		if before == nil {
+			utils.JSONError(w, "Tax rule not found", http.StatusNotFound)
+			return
+		}
+		_, err = db.Exec("UPDATE tax_rules SET region = ?, name = ?, rate = ?, active = ? WHERE id = ?",
+			req.Region, req.Name, req.Rate, req.Active, id)
+	}
+	if err != nil {
+		if _, ok := duplicateKeyName(err); ok {
+			utils.JSONErrorCode(w, utils.ErrConflict, fmt.Sprintf("A tax rule for region %s already exists", req.Region), http.StatusConflict)
+			return
+		}
+		fmt.Printf("❌ Error saving tax rule: %v\n", err)
+		utils.JSONError(w, "Error saving tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	action := "tax_rule_create"
+	status := http.StatusCreated
+	if before != nil {
+		action = "tax_rule_update"
+		status = http.StatusOK
+	}
+	recordAudit(r, action, "tax_rule", id, before, snapshotRow("SELECT id, region, name, rate, active FROM tax_rules WHERE id = ?", id))
+
+	req.ID = id
+	fmt.Printf("🧾 Tax rule saved: %s %s %.2f%%\n", req.Region, req.Name, req.Rate)
+	utils.JSONResponse(w, req, status)
+}
+
+// deleteTaxRule ลบกฎภาษี (คำสั่งซื้อเดิมเก็บอัตราภาษีไว้แล้ว จึงไม่ได้รับผลกระทบ)
+func deleteTaxRule(w http.ResponseWriter, r *http.Request, id int) {
+	before := snapshotRow("SELECT id, region, name, rate, active FROM tax_rules WHERE id = ?", id)
+	if before == nil {
+		utils.JSONError(w, "Tax rule not found", http.StatusNotFound)
+		return
+	}
+	if _, err := db.Exec("DELETE FROM tax_rules WHERE id = ?", id); err != nil {
+		fmt.Printf("❌ Error deleting tax rule: %v\n", err)
+		utils.JSONError(w, "Error deleting tax rule", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, "tax_rule_delete", "tax_rule", id, before, nil)
+
+	utils.JSONResponse(w, map[string]string{"message": "Tax rule deleted"}, http.StatusOK)
+}
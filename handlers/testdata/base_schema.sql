@@ -0,0 +1,108 @@
+-- ตารางหลักของระบบที่มีอยู่ก่อน ensureSchema (ensureSchema สร้างเฉพาะตารางและคอลัมน์ของฟีเจอร์ที่เพิ่มภายหลัง)
+-- ใช้โดย integration test (go test -tags integration) เพื่อสร้างฐานข้อมูลเปล่า แล้วให้ InitDB เพิ่มส่วนที่เหลือ
+-- คอลัมน์ที่อยู่ใน schemaColumns (เช่น games.status, purchases.tax_amount) ไม่ต้องใส่ที่นี่
+
+CREATE TABLE users (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(50) NOT NULL,
+	email VARCHAR(100) NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	role VARCHAR(20) NOT NULL DEFAULT 'user',
+	avatar_url VARCHAR(255) NULL,
+	wallet_balance DECIMAL(10,2) NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE categories (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE games (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(150) NOT NULL,
+	price DECIMAL(10,2) NOT NULL,
+	category_id INT NULL,
+	image_url VARCHAR(255) NULL,
+	description TEXT NULL,
+	release_date DATE NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_games_category (category_id)
+);
+
+CREATE TABLE ranking (
+	game_id INT PRIMARY KEY,
+	sales_count INT NOT NULL DEFAULT 0,
+	rank_position INT NULL
+);
+
+CREATE TABLE carts (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE cart_items (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	cart_id INT NOT NULL,
+	game_id INT NOT NULL,
+	quantity INT NOT NULL DEFAULT 1,
+	UNIQUE KEY uniq_cart_items_game (cart_id, game_id)
+);
+
+CREATE TABLE discount_codes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	code VARCHAR(50) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	value DECIMAL(10,2) NOT NULL,
+	min_total DECIMAL(10,2) NOT NULL DEFAULT 0,
+	start_date DATE NULL,
+	end_date DATE NULL,
+	usage_limit INT NULL,
+	single_use_per_user TINYINT(1) NOT NULL DEFAULT 0,
+	active TINYINT(1) NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_discount_codes_code (code)
+);
+
+CREATE TABLE user_discount_codes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	discount_code_id INT NOT NULL,
+	used_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_user_discount_codes_code (discount_code_id)
+);
+
+CREATE TABLE purchases (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	total_amount DECIMAL(10,2) NOT NULL,
+	discount_code_id INT NULL,
+	final_amount DECIMAL(10,2) NOT NULL,
+	purchase_date DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_purchases_user (user_id)
+);
+
+CREATE TABLE purchase_items (
+	purchase_id INT NOT NULL,
+	game_id INT NOT NULL,
+	price_at_purchase DECIMAL(10,2) NOT NULL,
+	PRIMARY KEY (purchase_id, game_id)
+);
+
+CREATE TABLE purchased_games (
+	user_id INT NOT NULL,
+	game_id INT NOT NULL,
+	purchased_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, game_id)
+);
+
+CREATE TABLE user_transactions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	type VARCHAR(30) NOT NULL,
+	amount DECIMAL(10,2) NOT NULL,
+	description VARCHAR(255) NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_user_transactions_user (user_id)
+);
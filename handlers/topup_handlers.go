@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/payments"
+	"go-api-game/utils"
+	"math"
+	"net/http"
+)
+
+// maxAutoTopUpAmount จำนวนเงินสูงสุดที่ตัดอัตโนมัติได้ต่อครั้ง
+const maxAutoTopUpAmount = 1000
+
+// autoTopUpSettings การตั้งค่าเติมเงินอัตโนมัติของผู้ใช้
+type autoTopUpSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Threshold float64 `json:"threshold" validate:"min=0,max=1000"` // เติมเงินเมื่อยอดคงเหลือต่ำกว่าค่านี้หลังการซื้อ
+	Amount    float64 `json:"amount" validate:"min=0,max=1000"`    // จำนวนเงินที่เติมแต่ละครั้ง
+}
+
+// getAutoTopUpSettings ดึงการตั้งค่าเติมเงินอัตโนมัติ (ผู้ใช้ที่ยังไม่ตั้งค่าถือว่าปิดอยู่)
+func getAutoTopUpSettings(userID int) (autoTopUpSettings, error) {
+	var settings autoTopUpSettings
+	err := db.QueryRow(`
+		SELECT enabled, threshold, amount FROM wallet_auto_topup WHERE user_id = ?
+	`, userID).Scan(&settings.Enabled, &settings.Threshold, &settings.Amount)
+	if err == sql.ErrNoRows {
+		return autoTopUpSettings{}, nil
+	}
+	return settings, err
+}
+
+// AutoTopUpHandler handles the auto top-up setting of the current user
+// ฟังก์ชันสำหรับดูและตั้งค่าการเติมเงินอัตโนมัติ (ตัดเงินผ่านช่องทางชำระเงินที่ตั้งค่าไว้)
+// GET /wallet/auto-topup, PUT /wallet/auto-topup {"enabled": true, "threshold": 10, "amount": 50}
+func AutoTopUpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		settings, err := getAutoTopUpSettings(userID)
+		if err != nil {
+			fmt.Printf("❌ Error fetching auto top-up settings: %v\n", err)
+			utils.JSONError(w, "Error fetching auto top-up settings", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, map[string]interface{}{
+			"settings":          settings,
+			"gateway_available": payments.Enabled(),
+		}, http.StatusOK)
+
+	case "PUT":
+		var req autoTopUpSettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		errs := utils.Validate(req)
+		if req.Enabled && req.Amount <= 0 {
+			errs = append(errs, utils.FieldError{Field: "amount", Rule: "gt", Message: "amount must be greater than 0"})
+		}
+		if errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+		if req.Enabled && !payments.Enabled() {
+			utils.JSONErrorCode(w, utils.ErrBadRequest, "Automatic top-up is not available: no payment gateway configured", http.StatusBadRequest)
+			return
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO wallet_auto_topup (user_id, enabled, threshold, amount)
+			VALUES (?, ?, ?, ?)
+			`+dialect.OnConflict("user_id")+` `+upsertColumns("enabled", "threshold", "amount")+`
+		`, userID, req.Enabled, req.Threshold, req.Amount)
+		if err != nil {
+			fmt.Printf("❌ Error saving auto top-up settings: %v\n", err)
+			utils.JSONError(w, "Error saving auto top-up settings", http.StatusInternalServerError)
+			return
+		}
+
+		utils.JSONResponse(w, map[string]interface{}{
+			"message":  "Auto top-up settings saved",
+			"settings": req,
+		}, http.StatusOK)
+
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// autoTopUp ตัดเงินผ่านช่องทางชำระเงินแล้วเติมเข้ากระเป๋า (commit แยกจาก transaction อื่น
+// เพื่อไม่ให้เงินที่ตัดไปแล้วหายถ้าการซื้อที่ตามมาล้มเหลว)
+func autoTopUp(userID int, amount float64, reason string) (string, error) {
+	reference, err := payments.Default.Charge(userID, amount, "Wallet auto top-up")
+	if err != nil {
+		return "", err
+	}
+	if err := creditAutoTopUp(userID, amount, reason, reference); err != nil {
+		// ตัดเงินแล้วแต่เติมเข้ากระเป๋าไม่สำเร็จ ต้องตรวจสอบด้วย reference ของ gateway
+		fmt.Printf("❌ Auto top-up charged user %d $%.2f (ref %s) but the wallet credit failed: %v\n", userID, amount, reference, err)
+		return reference, err
+	}
+
+	fmt.Printf("💳 Auto top-up: user %d +%.2f (%s, ref %s)\n", userID, amount, reason, reference)
+	pushWalletBalance(userID)
+	return reference, nil
+}
+
+// creditAutoTopUp เติมเงินที่ตัดผ่าน gateway แล้วเข้ากระเป๋าพร้อมบันทึกธุรกรรม
+func creditAutoTopUp(userID int, amount float64, reason, reference string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE users SET wallet_balance = wallet_balance + ? WHERE id = ?", amount, userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO user_transactions (user_id, type, amount, description)
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionDeposit, amount, fmt.Sprintf("Auto top-up (%s): %s", reason, reference))
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE wallet_auto_topup SET last_topup_at = NOW() WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// topUpForCheckout เติมเงินอัตโนมัติเมื่อยอดเงินไม่พอชำระ (ถ้าผู้ใช้เปิดใช้งาน)
+// เติมเท่ากับค่าที่ตั้งไว้หรือยอดที่ขาด (แล้วแต่ค่าใดมากกว่า) คืนค่าจำนวนที่เติม (0 = ไม่ได้เติม)
+// ต้องเรียกนอก transaction ของ checkout เพื่อไม่ให้ถือ lock ไว้ระหว่างรอ gateway
+func topUpForCheckout(userID int, shortfall float64) (float64, error) {
+	settings, err := getAutoTopUpSettings(userID)
+	if err != nil || !settings.Enabled || !featureEnabled(FlagAutoTopUp, userID) {
+		return 0, err
+	}
+	amount := math.Max(settings.Amount, math.Ceil(shortfall*100)/100)
+	if amount > maxAutoTopUpAmount {
+		return 0, fmt.Errorf("shortfall of $%.2f exceeds the auto top-up limit", shortfall)
+	}
+	if _, err := autoTopUp(userID, amount, "checkout"); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// topUpBelowThreshold เติมเงินอัตโนมัติเมื่อยอดคงเหลือหลังการซื้อต่ำกว่าเกณฑ์ที่ตั้งไว้ (ทำงานเบื้องหลัง)
+// เติมได้ครั้งเดียวต่อคำสั่งซื้อ: จองแถวใน auto_topup_triggers ด้วย purchase_id ก่อนตัดเงิน
+func topUpBelowThreshold(userID int, purchaseID int64, balance float64) {
+	settings, err := getAutoTopUpSettings(userID)
+	if err != nil || !settings.Enabled || !featureEnabled(FlagAutoTopUp, userID) || settings.Amount <= 0 || balance >= settings.Threshold {
+		return
+	}
+
+	result, err := db.Exec(`
+		INSERT IGNORE INTO auto_topup_triggers (purchase_id, user_id, amount) VALUES (?, ?, ?)
+	`, purchaseID, userID, settings.Amount)
+	if err != nil {
+		fmt.Printf("⚠️ Error reserving auto top-up for purchase #%d: %v\n", purchaseID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return // เติมเงินของคำสั่งซื้อนี้ไปแล้ว
+	}
+
+	reference, err := autoTopUp(userID, settings.Amount, "low balance")
+	if err != nil {
+		fmt.Printf("⚠️ Auto top-up for user %d failed: %v\n", userID, err)
+	}
+	if reference != "" {
+		if _, err := db.Exec("UPDATE auto_topup_triggers SET reference = ? WHERE purchase_id = ?", reference, purchaseID); err != nil {
+			fmt.Printf("⚠️ Error saving auto top-up reference for purchase #%d: %v\n", purchaseID, err)
+		}
+	}
+}
+
+// insufficientBalanceDetails ข้อมูลประกอบ error ยอดเงินไม่พอ ให้ frontend แสดงยอดที่ขาดและแนะนำการเติมเงิน
+func insufficientBalanceDetails(userID int, balance, required float64, topUpErr error) map[string]interface{} {
+	shortfall := math.Ceil((required-balance)*100) / 100
+	details := map[string]interface{}{
+		"balance":          balance,
+		"required":         required,
+		"shortfall":        shortfall,
+		"suggested_top_up": math.Ceil(shortfall), // ปัดขึ้นเป็นจำนวนเต็มเพื่อให้เติมครั้งเดียวพอ
+	}
+	if settings, err := getAutoTopUpSettings(userID); err == nil {
+		details["auto_top_up_enabled"] = settings.Enabled
+	}
+	if topUpErr != nil {
+		details["auto_top_up_error"] = topUpErr.Error()
+	}
+	return details
+}
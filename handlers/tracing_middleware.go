@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"go-api-game/tracing"
+	"go-api-game/utils"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// spanIDSegment ส่วนของ path ที่เป็น ID หรือ token (ตัวเลข หรือ hex ยาว) แทนด้วย {id} ในชื่อ span
+var spanIDSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{16,})$`)
+
+// spanRoute path ที่แทน ID ด้วย {id} (ชื่อ span ไม่แยกตาม ID ของเกมหรือผู้ใช้ เช่น /games/{id}/questions)
+func spanRoute(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if spanIDSegment.MatchString(part) {
+			parts[i] = "{id}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Tracing middleware starts a server span for every request
+// Middleware สำหรับสร้าง span ของ request (ต่อจาก trace ของ service ต้นทางถ้ามี header traceparent)
+// คำสั่ง SQL ใน transaction ที่เริ่มด้วย context ของ request และการอัพโหลดไฟล์จะเป็น span ลูกของ span นี้
+// เก็บระยะเวลาของ request ไว้แสดงใน /admin/diagnostics เสมอ (span เป็น nil เมื่อปิด tracing)
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := spanRoute(r.URL.Path)
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), r.Method+" "+route, tracing.KindServer)
+		defer span.End()
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("client.address", clientIP(r))
+		span.SetAttribute("user_agent.original", r.UserAgent())
+		span.SetAttribute("http.request_id", r.Header.Get(utils.RequestIDHeader))
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttribute("http.response.status_code", status)
+		if status >= 500 {
+			span.SetError(errorStatus(status))
+		}
+		// stream ที่เปิดค้างไว้ (เช่น /events) ไม่นับเป็น endpoint ที่ช้า
+		if !strings.HasPrefix(sw.Header().Get("Content-Type"), "text/event-stream") {
+			tracing.RecordRequest(r.Method+" "+route, time.Since(start), status >= 500)
+		}
+	})
+}
+
+// errorStatus error ของ response ที่ล้มเหลว (ใช้เป็นข้อความของ span)
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return http.StatusText(int(e))
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ประเภทของธุรกรรมในตาราง user_transactions
+// amount ของ deposit, purchase, refund, gift_received เป็นบวกเสมอ (ทิศทางดูจากประเภท)
+// ส่วน adjustment เป็นบวกเมื่อเพิ่มเงินและติดลบเมื่อหักเงิน
+const (
+	TransactionDeposit      = "deposit"       // เติมเงิน (รวมถึงเติมเงินอัตโนมัติ)
+	TransactionPurchase     = "purchase"      // ซื้อเกม
+	TransactionRefund       = "refund"        // คืนเงินค่าเกม
+	TransactionAdjustment   = "adjustment"    // ผู้ดูแลระบบปรับยอดเงิน
+	TransactionGiftReceived = "gift_received" // ได้รับเงินจากการแลกบัตรของขวัญ
+)
+
+// transactionTypes ประเภทธุรกรรมทั้งหมด (ลำดับนี้ใช้สร้าง ENUM ของคอลัมน์ type)
+var transactionTypes = []string{
+	TransactionDeposit,
+	TransactionPurchase,
+	TransactionRefund,
+	TransactionAdjustment,
+	TransactionGiftReceived,
+}
+
+// transactionSign ทิศทางของธุรกรรมต่อยอดเงินในกระเป๋า (+1 เพิ่ม, -1 ลด, 0 ไม่กระทบ)
+// adjustment เก็บ amount แบบมีเครื่องหมายอยู่แล้วจึงคืนค่า +1
+func transactionSign(transactionType string) float64 {
+	switch transactionType {
+	case TransactionDeposit, TransactionRefund, TransactionAdjustment, TransactionGiftReceived:
+		return 1
+	case TransactionPurchase:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// transactionTypeEnum นิยามคอลัมน์ ENUM สำหรับ user_transactions.type
+func transactionTypeEnum() string {
+	return "enum('" + strings.Join(transactionTypes, "','") + "')"
+}
+
+// isTransactionType ตรวจสอบว่าเป็นประเภทธุรกรรมที่รู้จัก
+func isTransactionType(value string) bool {
+	for _, t := range transactionTypes {
+		if t == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTransactionTypes แปลงค่า ?type= (คั่นได้หลายค่าด้วย comma เช่น deposit,refund)
+// คืนค่า nil ถ้าไม่ได้ระบุ และคืน error ถ้ามีประเภทที่ไม่รู้จัก
+func parseTransactionTypes(raw string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !isTransactionType(t) {
+			return nil, fmt.Errorf("invalid transaction type %q (allowed: %s)", t, strings.Join(transactionTypes, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// transactionTypeFilter สร้างเงื่อนไข SQL "column IN (?, ...)" สำหรับประเภทธุรกรรม
+func transactionTypeFilter(column string, types []string) (string, []interface{}) {
+	placeholders := make([]string, len(types))
+	args := make([]interface{}, len(types))
+	for i, t := range types {
+		placeholders[i] = "?"
+		args[i] = t
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), args
+}
+
+// transactionFilters สร้างเงื่อนไข WHERE จาก query parameters ของ endpoint ประวัติธุรกรรม
+// column ของตาราง user_transactions ต้องขึ้นต้นด้วย prefix (เช่น "t." หรือ "")
+//   - type                  ประเภทธุรกรรม คั่นได้หลายค่าด้วย comma
+//   - from, to              ช่วงวันที่ (YYYY-MM-DD, รวมวันที่ to)
+//   - q                     ค้นหาในคำอธิบายธุรกรรม
+//   - min_amount, max_amount ช่วงจำนวนเงิน
+func transactionFilters(query url.Values, prefix string) ([]string, []interface{}, error) {
+	var clauses []string
+	var args []interface{}
+
+	types, err := parseTransactionTypes(query.Get("type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(types) > 0 {
+		clause, typeArgs := transactionTypeFilter(prefix+"type", types)
+		clauses = append(clauses, clause)
+		args = append(args, typeArgs...)
+	}
+
+	var from, to time.Time
+	if raw := query.Get("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid from date format. Use YYYY-MM-DD")
+		}
+		clauses = append(clauses, prefix+"created_at >= ?")
+		args = append(args, from)
+	}
+	if raw := query.Get("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid to date format. Use YYYY-MM-DD")
+		}
+		if !from.IsZero() && to.Before(from) {
+			return nil, nil, fmt.Errorf("to date must not be before from date")
+		}
+		clauses = append(clauses, prefix+"created_at < ?")
+		args = append(args, to.AddDate(0, 0, 1))
+	}
+
+	if search := strings.TrimSpace(query.Get("q")); search != "" {
+		clauses = append(clauses, prefix+"description LIKE ?")
+		args = append(args, "%"+search+"%")
+	}
+
+	var minAmount, maxAmount float64
+	if raw := query.Get("min_amount"); raw != "" {
+		if minAmount, err = strconv.ParseFloat(raw, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid min_amount")
+		}
+		clauses = append(clauses, prefix+"amount >= ?")
+		args = append(args, minAmount)
+	}
+	if raw := query.Get("max_amount"); raw != "" {
+		if maxAmount, err = strconv.ParseFloat(raw, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid max_amount")
+		}
+		if query.Get("min_amount") != "" && maxAmount < minAmount {
+			return nil, nil, fmt.Errorf("max_amount must not be less than min_amount")
+		}
+		clauses = append(clauses, prefix+"amount <= ?")
+		args = append(args, maxAmount)
+	}
+
+	return clauses, args, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// errInvalidUpload ใช้แยกไฟล์ที่ผู้ใช้ส่งมาไม่ถูกต้อง (ตอบ 400) ออกจากข้อผิดพลาดของระบบ (ตอบ 500)
+var errInvalidUpload = errors.New("invalid upload")
+
+// ขนาดไฟล์ภาพสูงสุดเริ่มต้น (ปรับได้ด้วย MAX_UPLOAD_SIZE_MB)
+const defaultMaxUploadSizeMB = 5
+
+// imageExtensions นามสกุลไฟล์ตามชนิดที่ตรวจพบจากเนื้อหาไฟล์
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/avif": ".avif",
+}
+
+// contentHashName ชื่อไฟล์จาก hash ของเนื้อหา เช่น game_3f2a9c0d1e4b5a67.jpg
+// ภาพใหม่ได้ URL ใหม่เสมอ จึง cache ได้ถาวร (immutable) โดย client/CDN ไม่แสดงภาพเก่าหลัง admin เปลี่ยนภาพ
+// ภาพเดียวกันได้ชื่อเดียวกัน จึงอาจใช้ร่วมกันหลายเกมหรือหลายบัญชี (ต้องตรวจก่อนลบไฟล์)
+func contentHashName(prefix string, data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return prefix + hex.EncodeToString(sum[:8]) + ext
+}
+
+// maxUploadSize คืนค่าขนาดไฟล์สูงสุดที่อนุญาต (bytes)
+func maxUploadSize() int64 {
+	if mb, err := strconv.Atoi(os.Getenv("MAX_UPLOAD_SIZE_MB")); err == nil && mb > 0 {
+		return int64(mb) << 20
+	}
+	return defaultMaxUploadSizeMB << 20
+}
+
+// readImageUpload อ่านไฟล์ภาพพร้อมตรวจสอบขนาดและชนิดจาก magic bytes (ไม่เชื่อนามสกุลไฟล์ที่ส่งมา)
+// คืนค่าข้อมูลไฟล์และนามสกุลที่ถูกต้องตามเนื้อหา
+func readImageUpload(file io.Reader) ([]byte, string, error) {
+	limit := maxUploadSize()
+
+	// อ่านเกินขีดจำกัด 1 byte เพื่อตรวจว่าไฟล์ใหญ่เกินหรือไม่
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading file: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, "", fmt.Errorf("%w: file exceeds maximum size of %d MB", errInvalidUpload, limit>>20)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("%w: file is empty", errInvalidUpload)
+	}
+
+	contentType := detectImageType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: file content is not a supported image (jpeg, png, gif, webp, avif)", errInvalidUpload)
+	}
+
+	return data, ext, nil
+}
+
+// detectImageType ตรวจชนิดไฟล์จาก magic bytes
+func detectImageType(data []byte) string {
+	// http.DetectContentType ยังไม่รู้จัก AVIF จึงตรวจ ftyp box เอง
+	if len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) {
+		brand := string(data[8:12])
+		if brand == "avif" || brand == "avis" {
+			return "image/avif"
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+// uploadErrorStatus เลือก HTTP status ตามชนิดของข้อผิดพลาดในการอัพโหลด
+func uploadErrorStatus(err error) int {
+	if errors.Is(err, errInvalidUpload) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
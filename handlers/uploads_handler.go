@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/auth"
+	"go-api-game/storage"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// uploadsDir โฟลเดอร์ที่เก็บไฟล์ที่อัพโหลดไว้ในเครื่อง
+const uploadsDir = "uploads"
+
+// UploadsHandler serves locally stored uploads
+// ฟังก์ชันสำหรับให้บริการไฟล์ใน uploads/ แทน http.FileServer
+// - ป้องกัน path traversal และไม่แสดงรายการไฟล์ในโฟลเดอร์
+// - กำหนด Content-Type จากนามสกุลไฟล์ และห้าม browser เดาชนิดไฟล์เอง
+// - ชื่อไฟล์ตั้งตามเนื้อหา (content hash) เนื้อหาของ URL เดิมจึงไม่เปลี่ยน cache ได้ถาวร (immutable)
+// - รองรับ Range request ผ่าน http.ServeContent
+// - ถ้าตั้ง UPLOADS_PROTECT_AVATARS=true ต้องส่ง token (header หรือ ?token=) เพื่อดู avatar
+func UploadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/uploads/")
+
+	// อนุญาตเฉพาะชื่อไฟล์ในระดับเดียวกับ uploads/ (ไม่มีโฟลเดอร์ย่อย, "..", หรือ backslash)
+	if name == "" || name != path.Base(name) || strings.ContainsAny(name, `\`) || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasPrefix(name, "avatar_") && os.Getenv("UPLOADS_PROTECT_AVATARS") == "true" {
+		if !hasValidUploadToken(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	file, err := os.Open(filepath.Join(uploadsDir, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// ให้บริการเฉพาะไฟล์ภาพที่รู้จัก
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if !strings.HasPrefix(contentType, "image/") {
+		fmt.Printf("⚠️ Refusing to serve non-image upload: %s\n", name)
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	if strings.HasPrefix(name, "avatar_") && os.Getenv("UPLOADS_PROTECT_AVATARS") == "true" {
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", storage.ImageCacheControl)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// hasValidUploadToken ตรวจสอบ JWT จาก Authorization header หรือ query ?token= (สำหรับแท็ก <img>)
+func hasValidUploadToken(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if token == "" {
+		return false
+	}
+	_, err := auth.ValidateToken(token)
+	return err == nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"go-api-game/utils"
+)
+
+// backfillUsernameKeys คำนวณ username_key ให้ผู้ใช้เดิมที่ยังไม่มี (ทำตอนเริ่มเซิร์ฟเวอร์)
+// ผู้ใช้ที่ชื่อซ้ำกับคนอื่นตาม utils.UsernameKey จะคงเป็น NULL และแสดง log ให้ผู้ดูแลเปลี่ยนชื่อก่อน
+func backfillUsernameKeys() {
+	rows, err := db.Query("SELECT id, username FROM users WHERE username_key IS NULL ORDER BY id")
+	if err != nil {
+		fmt.Printf("⚠️ Error loading users without username key: %v\n", err)
+		return
+	}
+	type pendingUser struct {
+		id       int
+		username string
+	}
+	var users []pendingUser
+	for rows.Next() {
+		var u pendingUser
+		if err := rows.Scan(&u.id, &u.username); err != nil {
+			rows.Close()
+			fmt.Printf("⚠️ Error scanning user without username key: %v\n", err)
+			return
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Printf("⚠️ Error loading users without username key: %v\n", err)
+		return
+	}
+
+	assigned := 0
+	for _, u := range users {
+		_, err := db.Exec("UPDATE users SET username_key = ? WHERE id = ?", utils.UsernameKey(u.username), u.id)
+		if _, duplicate := duplicateKeyName(err); duplicate {
+			fmt.Printf("⚠️ Username %q of user %d conflicts with another user, rename it to enforce uniqueness\n", u.username, u.id)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("⚠️ Error assigning username key to user %d: %v\n", u.id, err)
+			continue
+		}
+		assigned++
+	}
+	if assigned > 0 {
+		fmt.Printf("🔑 Assigned username keys to %d users\n", assigned)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"go-api-game/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBackfillUsernameKeysSkipsConflicts(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT id, username FROM users WHERE username_key IS NULL").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "Straße").AddRow(2, "STRASSE").AddRow(3, "Player_One"))
+	mock.ExpectExec("UPDATE users SET username_key").WithArgs("strasse", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET username_key").WithArgs("strasse", 2).WillReturnError(
+		&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'strasse' for key 'users.uniq_users_username_key'"})
+	mock.ExpectExec("UPDATE users SET username_key").WithArgs("player_one", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	backfillUsernameKeys()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDuplicateUsernameKeyIsUsernameTaken(t *testing.T) {
+	key, duplicate := duplicateKeyName(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'strasse' for key 'users.uniq_users_username_key'"})
+	if !duplicate || key != "uniq_users_username_key" {
+		t.Fatalf("duplicateKeyName = %q, %v", key, duplicate)
+	}
+	w := httptest.NewRecorder()
+	writeDuplicateUserError(w, key)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), string(utils.ErrUsernameTaken)) {
+		t.Errorf("response = %d %s", w.Code, w.Body)
+	}
+}
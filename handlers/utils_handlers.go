@@ -16,6 +16,11 @@ var db *sql.DB
 func InitDB(database *sql.DB) {
 	db = database
 	fmt.Println("✅ Database connection initialized in handlers")
+
+	// สร้างตารางเพิ่มเติมที่ยังไม่มี
+	ensureSchema()
+	backfillGameSlugs()
+	backfillUsernameKeys()
 }
 
 // RootHandler handles the root endpoint
@@ -40,11 +45,7 @@ func JSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 }
 
 // JSONError sends a JSON error response
-// ฟังก์ชันสำหรับส่ง error response แบบ JSON
+// ฟังก์ชันสำหรับส่ง error response แบบ JSON (ใช้รูปแบบเดียวกับ utils.JSONError)
 func JSONError(w http.ResponseWriter, message string, statusCode int) {
-	// ส่ง response error ในรูปแบบมาตรฐาน
-	JSONResponse(w, map[string]interface{}{
-		"error":   true,    // บ่งชี้ว่าเป็น error
-		"message": message, // ข้อความ error
-	}, statusCode)
+	utils.JSONError(w, message, statusCode)
 }
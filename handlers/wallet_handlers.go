@@ -7,13 +7,14 @@ import (
 	"go-api-game/utils"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // WalletHandler handles wallet balance retrieval
 // ฟังก์ชันสำหรับดึงยอดเงินในกระเป๋าเงินของผู้ใช้
 func WalletHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userID, _ := requestUserID(r)
 
 	var balance float64
 	// ดึงยอดเงินในกระเป๋าเงินจากฐานข้อมูล
@@ -38,12 +39,12 @@ func DepositHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
+	// ดึง User-ID จาก context
+	userID, _ := requestUserID(r)
 
 	// โครงสร้างสำหรับเก็บข้อมูลจาก request
 	var req struct {
-		Amount float64 `json:"amount"` // จำนวนเงินที่ต้องการฝาก
+		Amount float64 `json:"amount" validate:"gt=0"` // จำนวนเงินที่ต้องการฝาก (ต้องเป็นบวก)
 	}
 
 	// แปลง JSON request body เป็น struct
@@ -52,9 +53,9 @@ func DepositHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ตรวจสอบว่าจำนวนเงินเป็นบวก
-	if req.Amount <= 0 {
-		utils.JSONError(w, "Amount must be positive", http.StatusBadRequest)
+	// ตรวจสอบความถูกต้องของข้อมูลตาม tag validate
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
 		return
 	}
 
@@ -77,8 +78,8 @@ func DepositHandler(w http.ResponseWriter, r *http.Request) {
 	// บันทึกประวัติธุรกรรม
 	_, err = tx.Exec(`
 		INSERT INTO user_transactions (user_id, type, amount, description) 
-		VALUES (?, 'deposit', ?, ?)
-	`, userID, req.Amount, fmt.Sprintf("Deposit: $%.2f", req.Amount))
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionDeposit, req.Amount, fmt.Sprintf("Deposit: $%.2f", req.Amount))
 	if err != nil {
 		tx.Rollback()
 		utils.JSONError(w, "Error recording transaction", http.StatusInternalServerError)
@@ -91,6 +92,11 @@ func DepositHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pushWalletBalance(userID)
+
+	// บันทึกคะแนนความเสี่ยงของการเติมเงิน (ใช้ประกอบการตรวจสอบคำสั่งซื้อที่ถูกพักไว้)
+	recordRiskEvent(r, userID, RiskEventDeposit, assessRisk(r, userID), 0)
+
 	// ส่ง response สำเร็จกลับ
 	utils.JSONResponse(w, map[string]interface{}{
 		"message": "Deposit successful",
@@ -101,32 +107,51 @@ func DepositHandler(w http.ResponseWriter, r *http.Request) {
 // TransactionsHandler handles user transaction history
 // ฟังก์ชันสำหรับดึงประวัติธุรกรรมของผู้ใช้
 func TransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
-
-	fmt.Printf("🔍 Transactions request for user ID: %s\n", userID)
-
-	// ตรวจสอบว่ามี User-ID หรือไม่
-	if userID == "" {
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userIDInt, ok := requestUserID(r)
+	if !ok {
 		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
 		return
 	}
 
-	// แปลง User-ID เป็นตัวเลข
-	userIDInt, err := strconv.Atoi(userID)
+	fmt.Printf("🔍 Transactions request for user ID: %d\n", userIDInt)
+
+	// กรองตามประเภท ช่วงวันที่ คำค้นหา และจำนวนเงิน (ดู transactionFilters)
+	filters, filterArgs, err := transactionFilters(r.URL.Query(), "")
 	if err != nil {
-		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := parsePage(r.URL.Query(), 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// ใช้ DATE_FORMAT เพื่อได้ string โดยตรงจาก MySQL
-	rows, err := db.Query(`
-		SELECT type, amount, description, 
-		       DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') as created_date
+	query := `
+		SELECT type, amount, COALESCE(description, ''), created_at
 		FROM user_transactions 
-		WHERE user_id = ? 
-		ORDER BY created_at DESC
-	`, userIDInt)
+		WHERE user_id = ?`
+	args := []interface{}{userIDInt}
+	if len(filters) > 0 {
+		query += " AND " + strings.Join(filters, " AND ")
+		args = append(args, filterArgs...)
+	}
+
+	// จำนวนทั้งหมดสำหรับการแบ่งหน้า
+	var total int
+	countQuery := "SELECT COUNT(*) FROM user_transactions WHERE user_id = ?"
+	if len(filters) > 0 {
+		countQuery += " AND " + strings.Join(filters, " AND ")
+	}
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting transactions: %v\n", err)
+		utils.JSONError(w, "Error fetching transactions", http.StatusInternalServerError)
+		return
+	}
+
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := db.Query(query, append(args, page.Limit, page.Offset)...)
 
 	if err != nil {
 		fmt.Printf("❌ Error executing transactions query: %v\n", err)
@@ -142,11 +167,12 @@ func TransactionsHandler(w http.ResponseWriter, r *http.Request) {
 		var txType string
 		var amount float64
 		var description string
-		var createdAt string // ใช้ string ธรรมดา
+		var createdAt utils.Timestamp
 
 		if err := rows.Scan(&txType, &amount, &description, &createdAt); err != nil {
 			fmt.Printf("❌ Error scanning transaction row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching transactions", http.StatusInternalServerError)
+			return
 		}
 
 		fmt.Printf("✅ Transaction found: Type=%s, Amount=%.2f\n", txType, amount)
@@ -159,49 +185,56 @@ func TransactionsHandler(w http.ResponseWriter, r *http.Request) {
 			"date":        createdAt,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading transaction rows: %v\n", err)
+		utils.JSONError(w, "Error fetching transactions", http.StatusInternalServerError)
+		return
+	}
 
 	// ตรวจสอบว่า transactions ไม่เป็น nil
 	if transactions == nil {
 		transactions = []map[string]interface{}{}
 	}
 
-	fmt.Printf("✅ Returning %d transactions\n", len(transactions))
-	utils.JSONResponse(w, transactions, http.StatusOK)
+	fmt.Printf("✅ Returning %d of %d transactions\n", len(transactions), total)
+	utils.JSONResponse(w, paginatedResponse(transactions, total, page), http.StatusOK)
 }
 
 // PurchaseHistoryHandler handles user purchase history
 // ฟังก์ชันสำหรับดึงประวัติการซื้อของผู้ใช้
 func PurchaseHistoryHandler(w http.ResponseWriter, r *http.Request) {
-	// ดึง User-ID จาก header
-	userID := r.Header.Get("User-ID")
-
-	fmt.Printf("🔍 Purchase history request for user ID: %s\n", userID)
-
-	// ตรวจสอบว่ามี User-ID หรือไม่
-	if userID == "" {
+	// ดึง User-ID จาก context (ถูกตั้งค่าโดย middleware การยืนยันตัวตน)
+	userIDInt, ok := requestUserID(r)
+	if !ok {
 		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
 		return
 	}
 
-	// แปลง User-ID เป็นตัวเลข
-	userIDInt, err := strconv.Atoi(userID)
+	fmt.Printf("🔍 Purchase history request for user ID: %d\n", userIDInt)
+
+	fmt.Printf("🔍 Querying purchase history for user ID: %d\n", userIDInt)
+
+	page, err := parsePage(r.URL.Query(), 0)
 	if err != nil {
-		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM purchases WHERE user_id = ?", userIDInt).Scan(&total); err != nil {
+		fmt.Printf("❌ Error counting purchases: %v\n", err)
+		utils.JSONError(w, "Error fetching purchase history", http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Printf("🔍 Querying purchase history for user ID: %d\n", userIDInt)
-
-	// ใช้ DATE_FORMAT เพื่อแปลง DATETIME เป็น string โดยตรง
 	rows, err := db.Query(`
-		SELECT p.id, p.total_amount, p.final_amount, 
-		       DATE_FORMAT(p.purchase_date, '%Y-%m-%d %H:%i:%s') as purchase_date,
-		       dc.code as discount_code
+		SELECT p.id, p.total_amount, p.final_amount, p.status, p.purchase_date,
+		       dc.code as discount_code, p.tax_amount, p.tax_rate, p.tax_name
 		FROM purchases p
 		LEFT JOIN discount_codes dc ON p.discount_code_id = dc.id
 		WHERE p.user_id = ?
-		ORDER BY p.purchase_date DESC
-	`, userIDInt)
+		ORDER BY p.purchase_date DESC, p.id DESC
+		LIMIT ? OFFSET ?
+	`, userIDInt, page.Limit, page.Offset)
 
 	if err != nil {
 		fmt.Printf("❌ Error fetching purchase history: %v\n", err)
@@ -216,13 +249,16 @@ func PurchaseHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	// อ่านข้อมูลการซื้อทีละแถว
 	for rows.Next() {
 		var id int
-		var totalAmount, finalAmount float64
-		var purchaseDate string
-		var discountCode sql.NullString
+		var totalAmount, finalAmount, taxAmount, taxRate float64
+		var status string
+		var purchaseDate utils.Timestamp
+		var discountCode, taxName sql.NullString
 
-		if err := rows.Scan(&id, &totalAmount, &finalAmount, &purchaseDate, &discountCode); err != nil {
+		if err := rows.Scan(&id, &totalAmount, &finalAmount, &status, &purchaseDate, &discountCode,
+			&taxAmount, &taxRate, &taxName); err != nil {
 			fmt.Printf("❌ Error scanning purchase history row: %v\n", err)
-			continue
+			utils.JSONError(w, "Error fetching purchase history", http.StatusInternalServerError)
+			return
 		}
 
 		// สร้าง object การซื้อ
@@ -230,8 +266,13 @@ func PurchaseHistoryHandler(w http.ResponseWriter, r *http.Request) {
 			"id":             id,
 			"total_amount":   totalAmount,
 			"final_amount":   finalAmount,
+			"status":         status,
 			"purchase_date":  purchaseDate,
 			"discount_saved": totalAmount - finalAmount, // คำนวณส่วนลดที่ได้รับ
+			"tax_amount":     taxAmount,
+			"tax_rate":       taxRate,
+			"tax_name":       taxName.String,
+			"amount_paid":    finalAmount + taxAmount, // ยอดที่ชำระจริง (รวมภาษี)
 		}
 
 		// จัดการรหัสส่วนลด (อาจเป็น NULL)
@@ -253,14 +294,14 @@ func PurchaseHistoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("✅ Total purchases found: %d\n", count)
+	fmt.Printf("✅ Purchases found: %d (showing %d)\n", total, count)
 
 	// ตรวจสอบว่า purchases ไม่เป็น nil
 	if purchases == nil {
 		purchases = []map[string]interface{}{}
 	}
 
-	utils.JSONResponse(w, purchases, http.StatusOK)
+	utils.JSONResponse(w, paginatedResponse(purchases, total, page), http.StatusOK)
 }
 
 // TransactionStatsHandler handles transaction statistics
@@ -270,79 +311,238 @@ func TransactionStatsHandler(w http.ResponseWriter, r *http.Request) {
 
 	stats := make(map[string]interface{})
 
-	// ยอดรวมทั้งหมด (ฝากและซื้อ)
-	var totalDeposit, totalPurchase float64
-	err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM user_transactions WHERE type = 'deposit'").Scan(&totalDeposit)
-	if err != nil {
-		fmt.Printf("❌ Error getting deposit total: %v\n", err)
+	// ยอดรวมและจำนวนธุรกรรมแยกตามประเภท (ทุกประเภทมีค่าเริ่มต้นเป็น 0)
+	byType := make(map[string]map[string]interface{}, len(transactionTypes))
+	for _, t := range transactionTypes {
+		byType[t] = map[string]interface{}{"count": 0, "total": 0.0}
 	}
-	err = db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM user_transactions WHERE type = 'purchase'").Scan(&totalPurchase)
+	totalTransactions := 0
+	typeRows, err := db.Query("SELECT type, COUNT(*), COALESCE(SUM(amount), 0) FROM user_transactions GROUP BY type")
 	if err != nil {
-		fmt.Printf("❌ Error getting purchase total: %v\n", err)
+		fmt.Printf("❌ Error getting totals by type: %v\n", err)
+		utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+		return
 	}
-
-	// จำนวนธุรกรรมแยกตามประเภท
-	var depositCount, purchaseCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM user_transactions WHERE type = 'deposit'").Scan(&depositCount)
-	if err != nil {
-		fmt.Printf("❌ Error counting deposits: %v\n", err)
+	for typeRows.Next() {
+		var txType string
+		var count int
+		var total float64
+		if err := typeRows.Scan(&txType, &count, &total); err != nil {
+			typeRows.Close()
+			fmt.Printf("❌ Error scanning totals by type: %v\n", err)
+			utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+			return
+		}
+		byType[txType] = map[string]interface{}{"count": count, "total": total}
+		totalTransactions += count
 	}
-	err = db.QueryRow("SELECT COUNT(*) FROM user_transactions WHERE type = 'purchase'").Scan(&purchaseCount)
-	if err != nil {
-		fmt.Printf("❌ Error counting purchases: %v\n", err)
+	typeRows.Close()
+	if err := typeRows.Err(); err != nil {
+		fmt.Printf("❌ Error reading totals by type: %v\n", err)
+		utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+		return
 	}
 
 	// ธุรกรรมล่าสุด
-	var latestTransaction string
-	err = db.QueryRow("SELECT DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s') FROM user_transactions ORDER BY created_at DESC LIMIT 1").Scan(&latestTransaction)
+	var latestTransaction utils.NullTimestamp
+	err = db.QueryRow("SELECT created_at FROM user_transactions ORDER BY created_at DESC LIMIT 1").Scan(&latestTransaction)
 	if err != nil && err != sql.ErrNoRows {
 		fmt.Printf("❌ Error getting latest transaction: %v\n", err)
+		utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+		return
 	}
 
 	// ยอดรวมรายวัน (7 วันที่ผ่านมา)
 	dailyStats := make([]map[string]interface{}, 0)
 	rows, err := db.Query(`
 		SELECT 
-			DATE(created_at) as date,
+			`+dialect.DateFormat("created_at", "%Y-%m-%d")+` as date,
 			COUNT(*) as count,
-			COALESCE(SUM(CASE WHEN type = 'deposit' THEN amount ELSE 0 END), 0) as deposit_total,
-			COALESCE(SUM(CASE WHEN type = 'purchase' THEN amount ELSE 0 END), 0) as purchase_total
+			COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE 0 END), 0) as deposit_total,
+			COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE 0 END), 0) as purchase_total,
+			COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE 0 END), 0) as refund_total
 		FROM user_transactions 
 		WHERE created_at >= DATE_SUB(NOW(), INTERVAL 7 DAY)
-		GROUP BY DATE(created_at)
+		GROUP BY `+dialect.DateFormat("created_at", "%Y-%m-%d")+`
 		ORDER BY date DESC
-	`)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var date string
-			var count int
-			var depositTotal, purchaseTotal float64
-			if err := rows.Scan(&date, &count, &depositTotal, &purchaseTotal); err == nil {
-				dailyStats = append(dailyStats, map[string]interface{}{
-					"date":           date,
-					"count":          count,
-					"deposit_total":  depositTotal,
-					"purchase_total": purchaseTotal,
-				})
-			}
+	`, TransactionDeposit, TransactionPurchase, TransactionRefund)
+	if err != nil {
+		fmt.Printf("❌ Error getting daily transaction stats: %v\n", err)
+		utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date string
+		var count int
+		var depositTotal, purchaseTotal, refundTotal float64
+		if err := rows.Scan(&date, &count, &depositTotal, &purchaseTotal, &refundTotal); err != nil {
+			fmt.Printf("❌ Error scanning daily transaction stats: %v\n", err)
+			utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+			return
 		}
+		dailyStats = append(dailyStats, map[string]interface{}{
+			"date":           date,
+			"count":          count,
+			"deposit_total":  depositTotal,
+			"purchase_total": purchaseTotal,
+			"refund_total":   refundTotal,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading daily transaction stats: %v\n", err)
+		utils.JSONError(w, "Error fetching transaction statistics", http.StatusInternalServerError)
+		return
 	}
 
 	// รวมสถิติทั้งหมด
-	stats["total_deposit"] = totalDeposit
-	stats["total_purchase"] = totalPurchase
-	stats["deposit_count"] = depositCount
-	stats["purchase_count"] = purchaseCount
+	// total_deposit, total_purchase, deposit_count, purchase_count คงไว้สำหรับ frontend เดิม
+	stats["total_deposit"] = byType[TransactionDeposit]["total"]
+	stats["total_purchase"] = byType[TransactionPurchase]["total"]
+	stats["deposit_count"] = byType[TransactionDeposit]["count"]
+	stats["purchase_count"] = byType[TransactionPurchase]["count"]
+	stats["by_type"] = byType
 	stats["latest_transaction"] = latestTransaction
-	stats["total_transactions"] = depositCount + purchaseCount
+	stats["total_transactions"] = totalTransactions
 	stats["daily_stats"] = dailyStats
 
 	fmt.Printf("✅ Transaction statistics loaded\n")
 
+	// ส่งออกเป็น CSV (สถิติรายวัน)
+	if wantsCSV(r) {
+		cw := utils.CSVWriter(w, "transaction_stats.csv")
+		cw.Write([]string{"date", "count", "deposit_total", "purchase_total", "refund_total"})
+		for _, day := range dailyStats {
+			cw.Write([]string{
+				day["date"].(string),
+				strconv.Itoa(day["count"].(int)),
+				formatAmount(day["deposit_total"].(float64)),
+				formatAmount(day["purchase_total"].(float64)),
+				formatAmount(day["refund_total"].(float64)),
+			})
+		}
+		cw.Flush()
+		return
+	}
+
 	// ส่ง response กลับพร้อมสถิติ
 	utils.JSONResponse(w, map[string]interface{}{
 		"stats":   stats,
 		"success": true,
 	}, http.StatusOK)
 }
+
+// AdminWalletAdjustHandler handles manual wallet adjustments by admins
+// ฟังก์ชันสำหรับปรับยอดเงินในกระเป๋าของผู้ใช้โดยผู้ดูแลระบบ (เช่น การฝากเงินที่ล้มเหลว หรือชดเชยให้ลูกค้า)
+// POST /admin/users/{id}/wallet/adjust  body: {"type": "credit"|"debit", "amount": 10, "reason": "..."}
+func AdminWalletAdjustHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/users/123/wallet/adjust → userID = 123
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[3] != "wallet" || pathParts[4] != "adjust" {
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		utils.JSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Type   string  `json:"type" validate:"required,oneof=credit debit"` // credit = เพิ่มเงิน, debit = หักเงิน
+		Amount float64 `json:"amount" validate:"gt=0"`                      // จำนวนเงิน (เป็นบวกเสมอ)
+		Reason string  `json:"reason" validate:"required,max=255"`          // เหตุผล (แสดงในประวัติธุรกรรมของผู้ใช้)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	// ยอดที่บันทึกในธุรกรรม: บวกสำหรับ credit, ลบสำหรับ debit
+	delta := req.Amount
+	if req.Type == "debit" {
+		delta = -req.Amount
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		utils.JSONError(w, "Error starting transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// ล็อกแถวผู้ใช้เพื่อไม่ให้ยอดเงินเปลี่ยนระหว่างการตรวจสอบ
+	var balance float64
+	err = tx.QueryRow("SELECT wallet_balance FROM users WHERE id = ? FOR UPDATE", userID).Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorCode(w, utils.ErrUserNotFound, "User not found", http.StatusNotFound)
+		} else {
+			utils.JSONError(w, "Error fetching wallet balance", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// ไม่อนุญาตให้หักเงินจนยอดติดลบ
+	if balance+delta < 0 {
+		utils.JSONErrorCode(w, utils.ErrInsufficientBalance, fmt.Sprintf("Cannot debit $%.2f from a balance of $%.2f", req.Amount, balance), http.StatusBadRequest)
+		return
+	}
+
+	if _, err = tx.Exec("UPDATE users SET wallet_balance = wallet_balance + ? WHERE id = ?", delta, userID); err != nil {
+		utils.JSONError(w, "Error updating wallet", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO user_transactions (user_id, type, amount, description)
+		VALUES (?, ?, ?, ?)
+	`, userID, TransactionAdjustment, delta, "Adjustment: "+req.Reason)
+	if err != nil {
+		fmt.Printf("❌ Error recording adjustment: %v\n", err)
+		utils.JSONError(w, "Error recording transaction", http.StatusInternalServerError)
+		return
+	}
+	transactionID, _ := result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		utils.JSONError(w, "Error committing transaction", http.StatusInternalServerError)
+		return
+	}
+
+	newBalance := balance + delta
+	recordAudit(r, "wallet_adjust", "user", userID,
+		map[string]interface{}{"wallet_balance": balance},
+		map[string]interface{}{
+			"wallet_balance": newBalance,
+			"type":           req.Type,
+			"amount":         req.Amount,
+			"reason":         req.Reason,
+			"transaction_id": transactionID,
+		})
+
+	notify(userID, NotificationWalletAdjusted, "Wallet adjusted",
+		fmt.Sprintf("Your wallet was %sed $%.2f: %s", req.Type, req.Amount, req.Reason),
+		map[string]interface{}{"transaction_id": transactionID, "amount": delta})
+	pushWalletBalance(userID)
+
+	fmt.Printf("💰 Wallet adjusted: user %d %s $%.2f (balance %.2f → %.2f)\n", userID, req.Type, req.Amount, balance, newBalance)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":        "Wallet adjusted",
+		"transaction_id": transactionID,
+		"type":           req.Type,
+		"amount":         req.Amount,
+		"balance":        newBalance,
+	}, http.StatusOK)
+}
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-api-game/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// เหตุการณ์ที่ส่งออกไปยัง webhook ได้
+const (
+	WebhookGameCreated       = "game.created"
+	WebhookPurchaseCompleted = "purchase.completed"
+	WebhookRefundApproved    = "refund.approved"
+	WebhookUserRegistered    = "user.registered"
+)
+
+// webhookEvents รายชื่อเหตุการณ์ทั้งหมดที่ลงทะเบียนได้
+var webhookEvents = []string{
+	WebhookGameCreated,
+	WebhookPurchaseCompleted,
+	WebhookRefundApproved,
+	WebhookUserRegistered,
+}
+
+// การส่ง webhook ซ้ำเมื่อล้มเหลว
+const (
+	webhookMaxAttempts  = 5
+	webhookRetryBackoff = 10 * time.Second // ระยะรอก่อนส่งซ้ำครั้งแรก (เพิ่มเป็นเท่าตัวทุกครั้ง)
+	webhookTimeout      = 10 * time.Second
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// emitWebhookEvent ส่งเหตุการณ์ไปยังทุก webhook ที่สมัครรับเหตุการณ์นี้ (ทำงานเบื้องหลัง)
+func emitWebhookEvent(event string, data map[string]interface{}) {
+	go func() {
+		rows, err := db.Query("SELECT id, url, secret, events FROM webhooks WHERE active = 1")
+		if err != nil {
+			fmt.Printf("❌ Error loading webhooks: %v\n", err)
+			return
+		}
+		defer rows.Close()
+
+		payload, err := json.Marshal(map[string]interface{}{
+			"event":      event,
+			"data":       data,
+			"created_at": utils.FormatTimestamp(time.Now()),
+		})
+		if err != nil {
+			fmt.Printf("❌ Error encoding webhook payload: %v\n", err)
+			return
+		}
+
+		for rows.Next() {
+			var id int
+			var targetURL, secret, events string
+			if err := rows.Scan(&id, &targetURL, &secret, &events); err != nil {
+				// webhook อื่นยังได้รับเหตุการณ์ตามปกติ
+				fmt.Printf("❌ Error scanning webhook for %s: %v\n", event, err)
+				continue
+			}
+			if !webhookSubscribed(events, event) {
+				continue
+			}
+			go deliverWebhook(id, targetURL, secret, event, payload, 1)
+		}
+		if err := rows.Err(); err != nil {
+			fmt.Printf("❌ Error reading webhooks for %s: %v\n", event, err)
+		}
+	}()
+}
+
+// webhookSubscribed ตรวจสอบว่ารายการเหตุการณ์ (คั่นด้วย comma) มีเหตุการณ์นี้หรือ "*"
+func webhookSubscribed(events, event string) bool {
+	for _, e := range strings.Split(events, ",") {
+		e = strings.TrimSpace(e)
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhookPayload เซ็น payload ด้วย HMAC-SHA256 ของ secret ของ webhook
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook ส่ง payload หนึ่งครั้ง บันทึกผลลงใน webhook_deliveries และตั้งเวลาส่งซ้ำถ้าล้มเหลว
+func deliverWebhook(webhookID int, targetURL, secret, event string, payload []byte, attempt int) {
+	start := time.Now()
+	statusCode := 0
+	var deliveryErr error
+
+	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(payload))
+	if err != nil {
+		deliveryErr = err
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "GameStore-Webhooks/1.0")
+		req.Header.Set("X-Webhook-Event", event)
+		req.Header.Set("X-Webhook-Signature", signWebhookPayload(secret, payload))
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			deliveryErr = err
+		} else {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			statusCode = resp.StatusCode
+			if statusCode < 200 || statusCode >= 300 {
+				deliveryErr = fmt.Errorf("unexpected status %d", statusCode)
+			}
+		}
+	}
+
+	success := deliveryErr == nil
+	var errMsg, status interface{}
+	if deliveryErr != nil {
+		msg := deliveryErr.Error()
+		if len(msg) > 500 {
+			msg = msg[:500]
+		}
+		errMsg = msg
+	}
+	if statusCode > 0 {
+		status = statusCode
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, attempt, status_code, success, error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, webhookID, event, string(payload), attempt, status, success, errMsg, time.Since(start).Milliseconds())
+	if err != nil {
+		fmt.Printf("⚠️ Error recording webhook delivery: %v\n", err)
+	}
+
+	if success {
+		fmt.Printf("✅ Webhook %d delivered: %s\n", webhookID, event)
+		return
+	}
+
+	if attempt >= webhookMaxAttempts {
+		fmt.Printf("❌ Webhook %d gave up after %d attempts: %v\n", webhookID, attempt, deliveryErr)
+		return
+	}
+
+	backoff := webhookRetryBackoff << (attempt - 1)
+	fmt.Printf("⚠️ Webhook %d delivery failed (attempt %d), retrying in %s: %v\n", webhookID, attempt, backoff, deliveryErr)
+	time.AfterFunc(backoff, func() {
+		deliverWebhook(webhookID, targetURL, secret, event, payload, attempt+1)
+	})
+}
+
+// AdminWebhookHandler handles webhook management
+// ฟังก์ชันหลักสำหรับจัดการ webhook โดยผู้ดูแลระบบ
+// GET    /admin/webhooks                 - รายการ webhook
+// POST   /admin/webhooks                 - ลงทะเบียน webhook ใหม่
+// DELETE /admin/webhooks/{id}            - ลบ webhook
+// GET    /admin/webhooks/deliveries      - ประวัติการส่ง (กรองด้วย ?webhook_id=&success=)
+func AdminWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	// ตัวอย่าง URL: /admin/webhooks/123 → ["admin", "webhooks", "123"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(pathParts) >= 3 && pathParts[2] == "deliveries" {
+		if r.Method != "GET" {
+			utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		getWebhookDeliveries(w, r)
+		return
+	}
+
+	var id int
+	if len(pathParts) >= 3 {
+		if parsedID, err := strconv.Atoi(pathParts[2]); err == nil {
+			id = parsedID
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+		getAllWebhooks(w, r)
+	case "POST":
+		createWebhook(w, r)
+	case "DELETE":
+		if id > 0 {
+			deleteWebhook(w, r, id)
+		} else {
+			utils.JSONError(w, "Webhook ID required", http.StatusBadRequest)
+		}
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /admin/webhooks - รายการ webhook ทั้งหมด (ไม่แสดง secret)
+func getAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`
+		SELECT wh.id, wh.url, wh.events, wh.active, wh.created_at,
+		       (SELECT COUNT(*) FROM webhook_deliveries d WHERE d.webhook_id = wh.id AND d.success = 0) as failed_count,
+		       (SELECT MAX(d.created_at) FROM webhook_deliveries d WHERE d.webhook_id = wh.id) as last_delivery
+		FROM webhooks wh
+		ORDER BY wh.created_at DESC
+	`)
+	if err != nil {
+		fmt.Printf("❌ Error fetching webhooks: %v\n", err)
+		utils.JSONError(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []map[string]interface{}{}
+	for rows.Next() {
+		var id, failedCount int
+		var targetURL, events string
+		var active bool
+		var createdAt utils.Timestamp
+		var lastDelivery utils.NullTimestamp
+
+		if err := rows.Scan(&id, &targetURL, &events, &active, &createdAt, &failedCount, &lastDelivery); err != nil {
+			fmt.Printf("❌ Error scanning webhook row: %v\n", err)
+			utils.JSONError(w, "Error fetching webhooks", http.StatusInternalServerError)
+			return
+		}
+
+		webhook := map[string]interface{}{
+			"id":            id,
+			"url":           targetURL,
+			"events":        strings.Split(events, ","),
+			"active":        active,
+			"created_at":    createdAt,
+			"failed_count":  failedCount,
+			"last_delivery": lastDelivery,
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading webhook rows: %v\n", err)
+		utils.JSONError(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"webhooks":         webhooks,
+		"total":            len(webhooks),
+		"available_events": webhookEvents,
+	}, http.StatusOK)
+}
+
+// POST /admin/webhooks - ลงทะเบียน webhook ใหม่ (ถ้าไม่ส่ง secret จะสุ่มให้)
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		utils.JSONError(w, "A valid http(s) URL is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Events) == 0 {
+		utils.JSONError(w, "At least one event is required", http.StatusBadRequest)
+		return
+	}
+	for _, event := range req.Events {
+		valid := event == "*"
+		for _, known := range webhookEvents {
+			if event == known {
+				valid = true
+			}
+		}
+		if !valid {
+			utils.JSONError(w, "Unknown event: "+event, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Secret == "" {
+		secretBytes := make([]byte, 24)
+		if _, err := rand.Read(secretBytes); err != nil {
+			utils.JSONError(w, "Error generating webhook secret", http.StatusInternalServerError)
+			return
+		}
+		req.Secret = hex.EncodeToString(secretBytes)
+	}
+
+	adminID, _ := requestUserID(r)
+
+	result, err := db.Exec(`
+		INSERT INTO webhooks (url, secret, events, created_by)
+		VALUES (?, ?, ?, ?)
+	`, parsed.String(), req.Secret, strings.Join(req.Events, ","), adminID)
+	if err != nil {
+		fmt.Printf("❌ Error creating webhook: %v\n", err)
+		utils.JSONError(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	fmt.Printf("✅ Webhook registered: ID=%d, URL=%s\n", id, parsed.String())
+	recordAudit(r, "create", "webhook", id, nil, snapshotRow("SELECT id, url, events, active FROM webhooks WHERE id = ?", id))
+
+	// secret แสดงครั้งเดียวตอนสร้าง
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Webhook registered successfully",
+		"id":      id,
+		"url":     parsed.String(),
+		"events":  req.Events,
+		"secret":  req.Secret,
+	}, http.StatusCreated)
+}
+
+// DELETE /admin/webhooks/{id} - ลบ webhook (เก็บประวัติการส่งไว้)
+func deleteWebhook(w http.ResponseWriter, r *http.Request, id int) {
+	before := snapshotRow("SELECT id, url, events, active FROM webhooks WHERE id = ?", id)
+
+	result, err := db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		utils.JSONError(w, "Error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		utils.JSONError(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	recordAudit(r, "delete", "webhook", id, before, nil)
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Webhook deleted successfully",
+		"id":      id,
+	}, http.StatusOK)
+}
+
+// GET /admin/webhooks/deliveries - ประวัติการส่ง webhook
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(query.Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	whereClauses := []string{}
+	args := []interface{}{}
+	if webhookID := query.Get("webhook_id"); webhookID != "" {
+		whereClauses = append(whereClauses, "webhook_id = ?")
+		args = append(args, webhookID)
+	}
+	if event := query.Get("event"); event != "" {
+		whereClauses = append(whereClauses, "event = ?")
+		args = append(args, event)
+	}
+	if success := query.Get("success"); success == "true" || success == "false" {
+		whereClauses = append(whereClauses, "success = ?")
+		args = append(args, success == "true")
+	}
+
+	whereSQL := ""
+	if len(whereClauses) > 0 {
+		whereSQL = " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, webhook_id, event, payload, attempt, status_code, success, error, duration_ms, created_at
+		FROM webhook_deliveries`+whereSQL+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		fmt.Printf("❌ Error fetching webhook deliveries: %v\n", err)
+		utils.JSONError(w, "Error fetching webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []map[string]interface{}{}
+	for rows.Next() {
+		var id, webhookID, attempt int
+		var event, payload string
+		var createdAt utils.Timestamp
+		var statusCode, durationMs sql.NullInt64
+		var success bool
+		var errMsg sql.NullString
+
+		if err := rows.Scan(&id, &webhookID, &event, &payload, &attempt, &statusCode, &success, &errMsg, &durationMs, &createdAt); err != nil {
+			fmt.Printf("❌ Error scanning webhook delivery row: %v\n", err)
+			utils.JSONError(w, "Error fetching webhook deliveries", http.StatusInternalServerError)
+			return
+		}
+
+		var parsedPayload interface{}
+		if err := json.Unmarshal([]byte(payload), &parsedPayload); err != nil {
+			parsedPayload = payload
+		}
+
+		delivery := map[string]interface{}{
+			"id":          id,
+			"webhook_id":  webhookID,
+			"event":       event,
+			"payload":     parsedPayload,
+			"attempt":     attempt,
+			"success":     success,
+			"status_code": nil,
+			"error":       nil,
+			"duration_ms": durationMs.Int64,
+			"created_at":  createdAt,
+		}
+		if statusCode.Valid {
+			delivery["status_code"] = statusCode.Int64
+		}
+		if errMsg.Valid {
+			delivery["error"] = errMsg.String
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading webhook delivery rows: %v\n", err)
+		utils.JSONError(w, "Error fetching webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"deliveries": deliveries,
+		"limit":      limit,
+		"offset":     offset,
+		"count":      len(deliveries),
+	}, http.StatusOK)
+}
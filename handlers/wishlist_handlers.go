@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-api-game/jobs"
+	"go-api-game/mailer"
+	"go-api-game/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// โหมดการแจ้งเตือนลดราคาของ wishlist
+const (
+	WishlistAlertInstant = "instant" // แจ้งทันทีที่เกมลดราคา
+	WishlistAlertDigest  = "digest"  // รวมการลดราคาส่งวันละครั้ง
+)
+
+// งานเบื้องหลังของการแจ้งเตือนลดราคา
+const (
+	JobWishlistPriceDrop = "wishlist.price_drop"
+	JobWishlistDigest    = "wishlist.digest"
+)
+
+// wishlistAlertSettings การตั้งค่าแจ้งเตือนลดราคาของผู้ใช้
+type wishlistAlertSettings struct {
+	Enabled            bool   `json:"enabled"`
+	Email              bool   `json:"email"`
+	Mode               string `json:"mode" validate:"oneof=instant digest"`
+	MinDiscountPercent int    `json:"min_discount_percent" validate:"min=0,max=100"`
+}
+
+// priceDropPayload ข้อมูลของงานแจ้งเตือนเมื่อเกมลดราคา
+type priceDropPayload struct {
+	GameID   int64   `json:"game_id"`
+	OldPrice float64 `json:"old_price"`
+	NewPrice float64 `json:"new_price"`
+}
+
+// getWishlistAlertSettings ดึงการตั้งค่าแจ้งเตือนของผู้ใช้ (ค่าเริ่มต้น: แจ้งทันทีพร้อมอีเมล)
+func getWishlistAlertSettings(userID int) (wishlistAlertSettings, error) {
+	settings := wishlistAlertSettings{Enabled: true, Email: true, Mode: WishlistAlertInstant}
+	err := db.QueryRow(`
+		SELECT enabled, email, mode, min_discount_percent FROM wishlist_alert_settings WHERE user_id = ?
+	`, userID).Scan(&settings.Enabled, &settings.Email, &settings.Mode, &settings.MinDiscountPercent)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// enqueuePriceDrop เข้าคิวงานแจ้งผู้ใช้ที่มีเกมนี้ใน wishlist (เรียกหลังจากบันทึกราคาใหม่แล้ว)
+func enqueuePriceDrop(gameID int64, oldPrice, newPrice float64) {
+	if newPrice >= oldPrice {
+		return
+	}
+	payload := priceDropPayload{GameID: gameID, OldPrice: oldPrice, NewPrice: newPrice}
+	if err := jobs.Enqueue(JobWishlistPriceDrop, payload); err != nil {
+		fmt.Printf("⚠️ Error queueing price drop alert for game %d: %v\n", gameID, err)
+	}
+}
+
+// WishlistHandler handles the wishlist of the current user
+// ฟังก์ชันหลักสำหรับ wishlist ของผู้ใช้
+// GET    /wishlist?limit=&offset=   - รายการเกมใน wishlist
+// POST   /wishlist {"game_id": 1}   - เพิ่มเกม
+// DELETE /wishlist/{game_id}        - นำเกมออก
+// GET    /wishlist/alerts           - การตั้งค่าแจ้งเตือนลดราคา (PUT เพื่อแก้ไข)
+func WishlistHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(r)
+	if !ok {
+		utils.JSONError(w, "User ID not found", http.StatusUnauthorized)
+		return
+	}
+
+	// ตัวอย่าง URL: /wishlist/12 → ["wishlist", "12"]
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && pathParts[1] == "alerts":
+		wishlistAlertSettingsHandler(w, r, userID)
+	case len(pathParts) == 1 && r.Method == "GET":
+		getWishlist(w, r, userID)
+	case len(pathParts) == 1 && r.Method == "POST":
+		addToWishlist(w, r, userID)
+	case len(pathParts) == 2 && r.Method == "DELETE":
+		gameID, err := strconv.Atoi(pathParts[1])
+		if err != nil {
+			utils.JSONError(w, "Invalid game ID", http.StatusBadRequest)
+			return
+		}
+		removeFromWishlist(w, userID, gameID)
+	case len(pathParts) <= 2:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		utils.JSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// GET /wishlist - เกมใน wishlist (เพิ่มล่าสุดก่อน) พร้อมป้ายลดราคา
+func getWishlist(w http.ResponseWriter, r *http.Request, userID int) {
+	page, err := parsePage(r.URL.Query(), 0)
+	if err != nil {
+		utils.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM wishlist_items WHERE user_id = ?", userID).Scan(&total); err != nil {
+		utils.JSONError(w, "Error fetching wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.name, g.price, g.status, COALESCE(tv.url, g.image_url) as thumbnail_url,
+		       w.added_at
+		FROM wishlist_items w
+		JOIN games g ON g.id = w.game_id
+		LEFT JOIN image_variants tv ON tv.original_url = g.image_url AND tv.variant = 'thumbnail'
+		WHERE w.user_id = ?
+		ORDER BY w.added_at DESC, g.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, page.Limit, page.Offset)
+	if err != nil {
+		fmt.Printf("❌ Error fetching wishlist: %v\n", err)
+		utils.JSONError(w, "Error fetching wishlist", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	games := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name, status string
+		var addedAt utils.Timestamp
+		var price float64
+		var thumbnailURL sql.NullString
+		if err := rows.Scan(&id, &name, &price, &status, &thumbnailURL, &addedAt); err != nil {
+			fmt.Printf("❌ Error scanning wishlist row: %v\n", err)
+			utils.JSONError(w, "Error fetching wishlist", http.StatusInternalServerError)
+			return
+		}
+		games = append(games, map[string]interface{}{
+			"id":            id,
+			"name":          name,
+			"price":         price,
+			"thumbnail_url": thumbnailURL.String,
+			"available":     status == GameStatusPublished,
+			"added_at":      addedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("❌ Error reading wishlist rows: %v\n", err)
+		utils.JSONError(w, "Error fetching wishlist", http.StatusInternalServerError)
+		return
+	}
+	localizeGames(r, games)
+	addPriceLabels(games)
+
+	utils.JSONResponse(w, paginatedResponse(games, total, page), http.StatusOK)
+}
+
+// POST /wishlist - เพิ่มเกมที่วางขายอยู่และผู้ใช้ยังไม่มีลงใน wishlist
+func addToWishlist(w http.ResponseWriter, r *http.Request, userID int) {
+	var req struct {
+		GameID int `json:"game_id" validate:"gt=0"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errs := utils.Validate(req); errs != nil {
+		utils.JSONValidationError(w, errs)
+		return
+	}
+
+	var status string
+	err := db.QueryRow("SELECT status FROM games WHERE id = ?", req.GameID).Scan(&status)
+	if err == sql.ErrNoRows || (err == nil && status != GameStatusPublished) {
+		utils.JSONErrorCode(w, utils.ErrGameNotFound, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.JSONError(w, "Error checking game", http.StatusInternalServerError)
+		return
+	}
+
+	var owned bool
+	if err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM purchased_games WHERE user_id = ? AND game_id = ?)
+	`, userID, req.GameID).Scan(&owned); err != nil {
+		utils.JSONError(w, "Error checking ownership", http.StatusInternalServerError)
+		return
+	}
+	if owned {
+		utils.JSONErrorCode(w, utils.ErrAlreadyOwned, "You already own this game", http.StatusConflict)
+		return
+	}
+
+	if _, err := db.Exec("INSERT IGNORE INTO wishlist_items (user_id, game_id) VALUES (?, ?)", userID, req.GameID); err != nil {
+		fmt.Printf("❌ Error adding to wishlist: %v\n", err)
+		utils.JSONError(w, "Error updating wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("💝 User %d wishlisted game %d\n", userID, req.GameID)
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Game added to wishlist",
+		"game_id": req.GameID,
+	}, http.StatusCreated)
+}
+
+// DELETE /wishlist/{game_id} - นำเกมออกจาก wishlist
+func removeFromWishlist(w http.ResponseWriter, userID, gameID int) {
+	result, err := db.Exec("DELETE FROM wishlist_items WHERE user_id = ? AND game_id = ?", userID, gameID)
+	if err != nil {
+		utils.JSONError(w, "Error updating wishlist", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		utils.JSONError(w, "Game is not in your wishlist", http.StatusNotFound)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message": "Game removed from wishlist",
+		"game_id": gameID,
+	}, http.StatusOK)
+}
+
+// GET/PUT /wishlist/alerts - ดูและแก้ไขการตั้งค่าแจ้งเตือนลดราคา
+// {"enabled": true, "email": false, "mode": "digest", "min_discount_percent": 20}
+func wishlistAlertSettingsHandler(w http.ResponseWriter, r *http.Request, userID int) {
+	settings, err := getWishlistAlertSettings(userID)
+	if err != nil {
+		fmt.Printf("❌ Error fetching wishlist alert settings: %v\n", err)
+		utils.JSONError(w, "Error fetching alert settings", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		utils.JSONResponse(w, map[string]interface{}{"alerts": settings}, http.StatusOK)
+
+	case "PUT", "PATCH":
+		// ฟิลด์ที่ไม่ได้ส่งมาจะคงค่าเดิมไว้
+		req := settings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if errs := utils.Validate(req); errs != nil {
+			utils.JSONValidationError(w, errs)
+			return
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO wishlist_alert_settings (user_id, enabled, email, mode, min_discount_percent)
+			VALUES (?, ?, ?, ?, ?)
+			`+dialect.OnConflict("user_id")+` `+upsertColumns("enabled", "email", "mode", "min_discount_percent")+`
+		`, userID, req.Enabled, req.Email, req.Mode, req.MinDiscountPercent)
+		if err != nil {
+			fmt.Printf("❌ Error saving wishlist alert settings: %v\n", err)
+			utils.JSONError(w, "Error saving alert settings", http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Printf("🔔 Wishlist alerts for user %d: enabled=%t mode=%s\n", userID, req.Enabled, req.Mode)
+		utils.JSONResponse(w, map[string]interface{}{
+			"message": "Alert settings saved",
+			"alerts":  req,
+		}, http.StatusOK)
+
+	default:
+		utils.JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// discountPercent เปอร์เซ็นต์ที่ลดลงจาก oldPrice (ปัดเป็นจำนวนเต็ม)
+func discountPercent(oldPrice, newPrice float64) int {
+	if oldPrice <= 0 {
+		return 0
+	}
+	return int(math.Round((oldPrice - newPrice) / oldPrice * 100))
+}
+
+// runPriceDropAlert แจ้งผู้ใช้ที่มีเกมใน wishlist เมื่อเกมลดราคา
+// โหมด instant แจ้งทันที (และส่งอีเมลถ้าเปิดไว้) โหมด digest เก็บไว้ส่งในสรุปรายวัน
+func runPriceDropAlert(payload []byte) error {
+	var p priceDropPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid price drop payload: %v", err)
+	}
+
+	// ใช้ราคาปัจจุบัน (ราคาอาจเปลี่ยนอีกครั้งก่อนงานนี้ทำงาน)
+	var name, status string
+	var price float64
+	err := db.QueryRow("SELECT name, status, price FROM games WHERE id = ?", p.GameID).Scan(&name, &status, &price)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading game %d: %v", p.GameID, err)
+	}
+	if status != GameStatusPublished || price >= p.OldPrice {
+		return nil
+	}
+	percent := discountPercent(p.OldPrice, price)
+
+	rows, err := db.Query(`
+		SELECT w.user_id, COALESCE(s.enabled, 1), COALESCE(s.email, 1),
+		       COALESCE(s.mode, 'instant'), COALESCE(s.min_discount_percent, 0)
+		FROM wishlist_items w
+		LEFT JOIN wishlist_alert_settings s ON s.user_id = w.user_id
+		WHERE w.game_id = ?
+		  AND NOT EXISTS (SELECT 1 FROM purchased_games pg WHERE pg.user_id = w.user_id AND pg.game_id = w.game_id)
+	`, p.GameID)
+	if err != nil {
+		return fmt.Errorf("error loading wishlists for game %d: %v", p.GameID, err)
+	}
+	type subscriber struct {
+		userID int
+		wishlistAlertSettings
+	}
+	var subscribers []subscriber
+	for rows.Next() {
+		var s subscriber
+		if err := rows.Scan(&s.userID, &s.Enabled, &s.Email, &s.Mode, &s.MinDiscountPercent); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning wishlist for game %d: %v", p.GameID, err)
+		}
+		subscribers = append(subscribers, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error loading wishlists for game %d: %v", p.GameID, err)
+	}
+
+	instant, queued := 0, 0
+	for _, s := range subscribers {
+		if !s.Enabled || percent < s.MinDiscountPercent {
+			continue
+		}
+		if s.Mode == WishlistAlertDigest {
+			if _, err := db.Exec(`
+				INSERT INTO wishlist_alert_queue (user_id, game_id, old_price, new_price) VALUES (?, ?, ?, ?)
+			`, s.userID, p.GameID, p.OldPrice, price); err != nil {
+				fmt.Printf("⚠️ Error queueing price drop digest for user %d: %v\n", s.userID, err)
+				continue
+			}
+			queued++
+			continue
+		}
+
+		notify(s.userID, NotificationPriceDrop, "Price drop",
+			fmt.Sprintf("%s on your wishlist is now $%.2f (%d%% off)", name, price, percent),
+			map[string]interface{}{"game_id": p.GameID, "old_price": p.OldPrice, "new_price": price, "percent": percent})
+		if s.Email {
+			sendUserMail(s.userID, mailer.TemplatePriceDrop, map[string]interface{}{
+				"GameName": name,
+				"OldPrice": p.OldPrice,
+				"NewPrice": price,
+				"Percent":  percent,
+			})
+		}
+		instant++
+	}
+
+	fmt.Printf("💸 Price drop alert for game %d: %d notified, %d queued for digest\n", p.GameID, instant, queued)
+	return nil
+}
+
+// sendPriceDropDigests ส่งสรุปการลดราคาที่รออยู่ให้ผู้ใช้โหมด digest (คนละหนึ่งรายการต่อรอบ)
+// ข้ามเกมที่กลับมาราคาเดิมแล้ว ถูกนำออกจาก wishlist หรือผู้ใช้ซื้อไปแล้ว
+func sendPriceDropDigests() error {
+	rows, err := db.Query(`
+		SELECT q.id, q.user_id, q.game_id, g.name, q.old_price, g.price, COALESCE(s.email, 1),
+		       (g.status = 'published' AND g.price < q.old_price AND COALESCE(s.enabled, 1) = 1
+		        AND EXISTS (SELECT 1 FROM wishlist_items w WHERE w.user_id = q.user_id AND w.game_id = q.game_id)
+		        AND NOT EXISTS (SELECT 1 FROM purchased_games pg WHERE pg.user_id = q.user_id AND pg.game_id = q.game_id)) AS relevant
+		FROM wishlist_alert_queue q
+		JOIN games g ON g.id = q.game_id
+		LEFT JOIN wishlist_alert_settings s ON s.user_id = q.user_id
+		WHERE q.sent_at IS NULL
+		ORDER BY q.user_id, q.id
+	`)
+	if err != nil {
+		return fmt.Errorf("error loading price drop digests: %v", err)
+	}
+
+	type digestGame struct {
+		GameID   int
+		Name     string
+		OldPrice float64
+		NewPrice float64
+	}
+	type digest struct {
+		email bool
+		games []*digestGame
+		seen  map[int]*digestGame
+	}
+	digests := map[int]*digest{}
+	var order []int
+	var ids []string
+	for rows.Next() {
+		var id, userID int
+		var g digestGame
+		var email, relevant bool
+		if err := rows.Scan(&id, &userID, &g.GameID, &g.Name, &g.OldPrice, &g.NewPrice, &email, &relevant); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning price drop digest: %v", err)
+		}
+		ids = append(ids, strconv.Itoa(id))
+		if !relevant {
+			continue
+		}
+		d, ok := digests[userID]
+		if !ok {
+			d = &digest{email: email, seen: map[int]*digestGame{}}
+			digests[userID] = d
+			order = append(order, userID)
+		}
+		// เกมที่ลดราคาหลายครั้งในรอบเดียวกันแสดงครั้งเดียว เทียบกับราคาก่อนลดครั้งแรก
+		if existing, ok := d.seen[g.GameID]; ok {
+			existing.OldPrice = max(existing.OldPrice, g.OldPrice)
+			continue
+		}
+		game := g
+		d.seen[g.GameID] = &game
+		d.games = append(d.games, &game)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error loading price drop digests: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// ทำเครื่องหมายว่าส่งแล้วก่อนส่งจริง เพื่อไม่ให้ส่งซ้ำเมื่องานถูกลองใหม่
+	if _, err := db.Exec("UPDATE wishlist_alert_queue SET sent_at = NOW() WHERE id IN (" + strings.Join(ids, ",") + ")"); err != nil {
+		return fmt.Errorf("error marking price drop digests sent: %v", err)
+	}
+
+	for _, userID := range order {
+		d := digests[userID]
+		games := make([]map[string]interface{}, 0, len(d.games))
+		for _, g := range d.games {
+			games = append(games, map[string]interface{}{
+				"game_id":   g.GameID,
+				"name":      g.Name,
+				"old_price": g.OldPrice,
+				"new_price": g.NewPrice,
+				"percent":   discountPercent(g.OldPrice, g.NewPrice),
+			})
+		}
+		notify(userID, NotificationPriceDrop, "Wishlist price drops",
+			fmt.Sprintf("%d games on your wishlist are cheaper now", len(d.games)),
+			map[string]interface{}{"games": games})
+		if d.email {
+			sendUserMail(userID, mailer.TemplatePriceDigest, map[string]interface{}{"Games": d.games})
+		}
+	}
+
+	fmt.Printf("📬 Price drop digests sent to %d users\n", len(order))
+	return nil
+}
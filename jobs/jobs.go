@@ -0,0 +1,235 @@
+// jobs/jobs.go
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Handler ทำงานหนึ่งงานจาก payload ที่บันทึกไว้ (คืนค่า error เพื่อให้ลองใหม่ภายหลัง)
+type Handler func(payload []byte) error
+
+// ค่าเริ่มต้นของคิวงาน
+const (
+	defaultMaxAttempts = 5
+	retryBaseBackoff   = 10 * time.Second // ระยะรอก่อนลองใหม่ครั้งแรก (เพิ่มเป็นเท่าตัวทุกครั้ง)
+	pollInterval       = 2 * time.Second
+	batchSize          = 10
+	staleLockTimeout   = 10 * time.Minute // งานที่ค้างสถานะ running นานกว่านี้ถือว่า process ตายระหว่างทำ
+)
+
+var (
+	db *sql.DB
+
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+
+	// wake ปลุก worker ทันทีเมื่อมีงานใหม่ (ไม่ต้องรอรอบ poll)
+	wake    = make(chan struct{}, 1)
+	started bool
+)
+
+// Register ผูกประเภทงานกับฟังก์ชันที่ทำงานนั้น
+func Register(jobType string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[jobType] = h
+}
+
+func handlerFor(jobType string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	h, ok := handlers[jobType]
+	return h, ok
+}
+
+// Enqueue เพิ่มงานเข้าคิวให้ทำทันที
+func Enqueue(jobType string, payload interface{}) error {
+	return EnqueueAt(jobType, payload, time.Now())
+}
+
+// EnqueueAt เพิ่มงานเข้าคิวให้ทำตามเวลาที่กำหนด
+func EnqueueAt(jobType string, payload interface{}, runAt time.Time) error {
+	return insertJob(jobType, payload, runAt, nil)
+}
+
+// EnqueueUnique เพิ่มงานเข้าคิวหลังจาก delay ถ้ายังไม่มีงานประเภทเดียวกันรออยู่
+// ใช้รวมคำขอที่เกิดถี่ๆ ให้เหลือการทำงานครั้งเดียว (เช่น คำนวณอันดับใหม่หลัง checkout)
+func EnqueueUnique(jobType string, delay time.Duration) error {
+	return insertJob(jobType, nil, time.Now().Add(delay), jobType)
+}
+
+func insertJob(jobType string, payload interface{}, runAt time.Time, uniqueKey interface{}) error {
+	if db == nil {
+		return fmt.Errorf("job queue not initialized")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding job payload: %v", err)
+	}
+
+	// unique_key มีค่าเฉพาะตอนงานรออยู่ (UNIQUE index ยอมให้ NULL ซ้ำได้) จึงใช้ INSERT IGNORE กันงานซ้ำ
+	_, err = db.Exec(`
+		INSERT IGNORE INTO jobs (type, payload, run_at, max_attempts, unique_key)
+		VALUES (?, ?, ?, ?, ?)
+	`, jobType, string(data), runAt, defaultMaxAttempts, uniqueKey)
+	if err != nil {
+		return fmt.Errorf("error enqueueing job %s: %v", jobType, err)
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start เริ่ม worker และ scheduler (เรียกหลังจาก Register และ Schedule ทั้งหมดแล้ว)
+// ตาราง jobs และ job_schedules ต้องมีอยู่ก่อน (สร้างโดย handlers.InitDB)
+func Start(database *sql.DB) {
+	if started {
+		return
+	}
+	started = true
+	db = database
+
+	// คืนงานที่ค้างจากการ restart ระหว่างทำงานกลับเข้าคิว
+	result, err := db.Exec(`
+		UPDATE jobs SET status = 'pending', locked_at = NULL
+		WHERE status = 'running' AND locked_at < ?
+	`, time.Now().Add(-staleLockTimeout))
+	if err != nil {
+		log.Printf("❌ Error recovering stale jobs: %v", err)
+	} else if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("♻️ Recovered %d stale jobs", n)
+	}
+
+	// ลบงานที่เสร็จแล้วเก่ากว่า 7 วัน (งานที่ล้มเหลวเก็บไว้ให้ตรวจสอบ)
+	Register("jobs.cleanup", func([]byte) error {
+		_, err := db.Exec("DELETE FROM jobs WHERE status = 'done' AND finished_at < ?", time.Now().AddDate(0, 0, -7))
+		return err
+	})
+	Schedule("jobs-cleanup", 24*time.Hour, "jobs.cleanup")
+
+	go runWorker()
+	go runScheduler()
+	log.Println("✅ Job worker and scheduler started")
+}
+
+// runWorker ดึงงานที่ถึงเวลาทำมาทำทีละชุด
+func runWorker() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for processBatch() == batchSize {
+			// ยังมีงานค้างอยู่ ทำชุดต่อไปเลย
+		}
+		select {
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// processBatch ทำงานที่ถึงเวลาแล้วไม่เกิน batchSize งาน และคืนค่าจำนวนงานที่ดึงมา
+func processBatch() int {
+	rows, err := db.Query(`
+		SELECT id FROM jobs
+		WHERE status = 'pending' AND run_at <= ?
+		ORDER BY run_at, id
+		LIMIT ?
+	`, time.Now(), batchSize)
+	if err != nil {
+		log.Printf("❌ Error polling jobs: %v", err)
+		return 0
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("❌ Error scanning job id: %v", err)
+			return 0
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ Error polling jobs: %v", err)
+		return 0
+	}
+
+	for _, id := range ids {
+		runJob(id)
+	}
+	return len(ids)
+}
+
+// runJob จองงาน (ป้องกันหลาย instance ทำงานเดียวกัน) ทำงาน และบันทึกผล
+func runJob(id int64) {
+	result, err := db.Exec(`
+		UPDATE jobs SET status = 'running', locked_at = ?, attempts = attempts + 1, unique_key = NULL
+		WHERE id = ? AND status = 'pending'
+	`, time.Now(), id)
+	if err != nil {
+		log.Printf("❌ Error locking job %d: %v", id, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return // instance อื่นจองไปแล้ว
+	}
+
+	var jobType, payload string
+	var attempts, maxAttempts int
+	err = db.QueryRow("SELECT type, payload, attempts, max_attempts FROM jobs WHERE id = ?", id).
+		Scan(&jobType, &payload, &attempts, &maxAttempts)
+	if err != nil {
+		log.Printf("❌ Error loading job %d: %v", id, err)
+		return
+	}
+
+	err = execute(jobType, []byte(payload))
+	if err == nil {
+		db.Exec("UPDATE jobs SET status = 'done', finished_at = ?, last_error = NULL WHERE id = ?", time.Now(), id)
+		return
+	}
+
+	errMsg := err.Error()
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+
+	if attempts >= maxAttempts {
+		log.Printf("❌ Job %d (%s) failed permanently after %d attempts: %v", id, jobType, attempts, err)
+		db.Exec("UPDATE jobs SET status = 'failed', finished_at = ?, last_error = ? WHERE id = ?", time.Now(), errMsg, id)
+		return
+	}
+
+	backoff := retryBaseBackoff << (attempts - 1)
+	log.Printf("⚠️ Job %d (%s) failed (attempt %d), retrying in %s: %v", id, jobType, attempts, backoff, err)
+	db.Exec(`
+		UPDATE jobs SET status = 'pending', locked_at = NULL, run_at = ?, last_error = ?
+		WHERE id = ?
+	`, time.Now().Add(backoff), errMsg, id)
+}
+
+// execute เรียก handler ของงาน และแปลง panic เป็น error เพื่อไม่ให้ worker หยุดทำงาน
+func execute(jobType string, payload []byte) (err error) {
+	h, ok := handlerFor(jobType)
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %s", jobType)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return h(payload)
+}
@@ -0,0 +1,76 @@
+// jobs/scheduler.go
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// schedule งานที่ต้องทำซ้ำตามรอบเวลา
+type schedule struct {
+	name     string
+	interval time.Duration
+	jobType  string
+}
+
+const schedulerTick = 30 * time.Second
+
+var (
+	schedulesMu sync.Mutex
+	schedules   []schedule
+)
+
+// Schedule ตั้งให้เพิ่มงาน jobType เข้าคิวทุกๆ interval
+// เวลาที่ทำล่าสุดถูกเก็บในตาราง job_schedules จึงไม่ทำซ้ำทันทีหลัง restart และไม่ซ้ำกันเมื่อมีหลาย instance
+func Schedule(name string, interval time.Duration, jobType string) {
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+	schedules = append(schedules, schedule{name: name, interval: interval, jobType: jobType})
+}
+
+func runScheduler() {
+	schedulesMu.Lock()
+	registered := append([]schedule(nil), schedules...)
+	schedulesMu.Unlock()
+
+	// ลงทะเบียน schedule ใหม่ให้ทำงานครั้งแรกทันที
+	for _, s := range registered {
+		if _, err := db.Exec(`
+			INSERT IGNORE INTO job_schedules (name, job_type, interval_seconds, next_run_at)
+			VALUES (?, ?, ?, ?)
+		`, s.name, s.jobType, int(s.interval.Seconds()), time.Now()); err != nil {
+			log.Printf("❌ Error registering schedule %s: %v", s.name, err)
+		}
+	}
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+	for {
+		for _, s := range registered {
+			fireIfDue(s)
+		}
+		<-ticker.C
+	}
+}
+
+// fireIfDue เพิ่มงานเข้าคิวถ้าถึงเวลา (UPDATE แบบมีเงื่อนไขทำให้มีแค่ instance เดียวที่ได้สิทธิ์)
+func fireIfDue(s schedule) {
+	now := time.Now()
+	result, err := db.Exec(`
+		UPDATE job_schedules
+		SET last_run_at = ?, next_run_at = ?, interval_seconds = ?, job_type = ?
+		WHERE name = ? AND next_run_at <= ?
+	`, now, now.Add(s.interval), int(s.interval.Seconds()), s.jobType, s.name, now)
+	if err != nil {
+		log.Printf("❌ Error checking schedule %s: %v", s.name, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+
+	if err := Enqueue(s.jobType, nil); err != nil {
+		log.Printf("❌ Error enqueueing scheduled job %s: %v", s.name, err)
+	}
+}
@@ -0,0 +1,90 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Message คืออีเมลหนึ่งฉบับที่พร้อมส่ง
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	Headers map[string]string `json:",omitempty"` // header เพิ่มเติม เช่น List-Unsubscribe
+}
+
+// Provider คือผู้ให้บริการส่งอีเมล (SMTP หรือบริการอื่นที่เพิ่มภายหลัง)
+type Provider interface {
+	// Name ชื่อของผู้ให้บริการ (smtp, log)
+	Name() string
+	// Send ส่งอีเมลหนึ่งฉบับ
+	Send(msg Message) error
+}
+
+// Default ผู้ให้บริการส่งอีเมลที่ใช้ทั้งระบบ (ค่าเริ่มต้นแค่แสดง log ไม่ได้ส่งจริง)
+var Default Provider = &LogProvider{}
+
+// providers ผู้ให้บริการที่เลือกได้ผ่าน MAIL_PROVIDER
+var providers = map[string]func() (Provider, error){
+	"smtp": func() (Provider, error) { return NewSMTPProviderFromEnv() },
+	"log":  func() (Provider, error) { return &LogProvider{}, nil },
+}
+
+// Register เพิ่มผู้ให้บริการส่งอีเมลใหม่ (ต้องเรียกก่อน Init)
+func Register(name string, factory func() (Provider, error)) {
+	providers[strings.ToLower(name)] = factory
+}
+
+// Init เลือกผู้ให้บริการตาม MAIL_PROVIDER และลงทะเบียนงานส่งอีเมล (ต้องเรียกก่อน jobs.Start)
+// ถ้าไม่ได้ตั้งค่า จะใช้ smtp เมื่อมี SMTP_HOST มิฉะนั้นแค่แสดง log
+func Init() {
+	name := strings.ToLower(os.Getenv("MAIL_PROVIDER"))
+	if name == "" {
+		name = "log"
+		if os.Getenv("SMTP_HOST") != "" {
+			name = "smtp"
+		}
+	}
+
+	factory, ok := providers[name]
+	if !ok {
+		log.Printf("⚠️  Mail provider %q is not available, emails will only be logged", name)
+	} else if provider, err := factory(); err != nil {
+		log.Printf("❌ Error initializing mail provider %s: %v", name, err)
+	} else {
+		Default = provider
+	}
+
+	registerJobs()
+	log.Printf("✅ Mail provider: %s", Default.Name())
+}
+
+// SendTemplate สร้างอีเมลจาก template และเข้าคิวงานเพื่อส่งแบบ async
+func SendTemplate(to, templateName string, data map[string]interface{}) error {
+	if to == "" {
+		return fmt.Errorf("recipient is required")
+	}
+
+	msg, err := Render(templateName, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+
+	Enqueue(msg)
+	return nil
+}
+
+// LogProvider แสดงอีเมลใน log แทนการส่งจริง (ใช้ระหว่างพัฒนา)
+type LogProvider struct{}
+
+func (p *LogProvider) Name() string { return "log" }
+
+func (p *LogProvider) Send(msg Message) error {
+	fmt.Printf("📧 [mail:log] To=%s Subject=%q\n", msg.To, msg.Subject)
+	return nil
+}
@@ -0,0 +1,35 @@
+// mailer/queue.go
+package mailer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go-api-game/jobs"
+)
+
+// JobSendMail ประเภทงานส่งอีเมลในคิวงานเบื้องหลัง (ลองใหม่อัตโนมัติเมื่อส่งไม่สำเร็จ)
+const JobSendMail = "mail.send"
+
+// Enqueue เพิ่มอีเมลเข้าคิวงานเพื่อส่งเบื้องหลัง (ไม่ block request)
+// ถ้าเข้าคิวไม่ได้จะแสดง log และทิ้งอีเมลนั้น
+func Enqueue(msg Message) {
+	if err := jobs.Enqueue(JobSendMail, msg); err != nil {
+		fmt.Printf("⚠️ Error queueing email to %s (%s): %v\n", msg.To, msg.Subject, err)
+	}
+}
+
+// registerJobs ลงทะเบียนงานส่งอีเมลกับคิวงาน
+func registerJobs() {
+	jobs.Register(JobSendMail, func(payload []byte) error {
+		var msg Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("invalid mail payload: %v", err)
+		}
+		if err := Default.Send(msg); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Email sent to %s: %s\n", msg.To, msg.Subject)
+		return nil
+	})
+}
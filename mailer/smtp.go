@@ -0,0 +1,101 @@
+// mailer/smtp.go
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// SMTPProvider ส่งอีเมลผ่านเซิร์ฟเวอร์ SMTP (รองรับ STARTTLS ผ่าน net/smtp)
+type SMTPProvider struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPProviderFromEnv สร้าง SMTPProvider จาก environment variables
+// SMTP_HOST, SMTP_FROM (จำเป็น)
+// SMTP_PORT (ค่าเริ่มต้น 587), SMTP_USERNAME, SMTP_PASSWORD (ไม่บังคับ)
+func NewSMTPProviderFromEnv() (*SMTPProvider, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, fmt.Errorf("SMTP_HOST not set")
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		return nil, fmt.Errorf("SMTP_FROM not set")
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	return &SMTPProvider{
+		host:     host,
+		port:     port,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     from,
+	}, nil
+}
+
+func (p *SMTPProvider) Name() string { return "smtp" }
+
+func (p *SMTPProvider) Send(msg Message) error {
+	var auth smtp.Auth
+	if p.username != "" {
+		auth = smtp.PlainAuth("", p.username, p.password, p.host)
+	}
+
+	addr := net.JoinHostPort(p.host, p.port)
+	if err := smtp.SendMail(addr, auth, p.from, []string{msg.To}, p.buildMIME(msg)); err != nil {
+		return fmt.Errorf("smtp send error: %v", err)
+	}
+	return nil
+}
+
+// buildMIME สร้างเนื้อหาอีเมลแบบ multipart/alternative (text + HTML)
+func (p *SMTPProvider) buildMIME(msg Message) []byte {
+	boundaryBytes := make([]byte, 12)
+	rand.Read(boundaryBytes)
+	boundary := hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", p.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	for name, value := range msg.Headers {
+		// ไม่รับค่าที่มีการขึ้นบรรทัดใหม่ (ป้องกัน header injection)
+		if strings.ContainsAny(name+value, "\r\n") {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(msg.Text)
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/html; charset=utf-8\r\n\r\n")
+	buf.WriteString(msg.HTML)
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes()
+}
@@ -0,0 +1,178 @@
+// mailer/templates.go
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// ชื่อ template ของอีเมลแต่ละประเภท
+const (
+	TemplateWelcome       = "welcome"
+	TemplateReceipt       = "receipt"
+	TemplatePasswordReset = "password_reset"
+	TemplateRefundStatus  = "refund_status"
+	TemplateGift          = "gift"
+	TemplatePriceDrop     = "price_drop"
+	TemplatePriceDigest   = "price_drop_digest"
+	TemplateNewsletter    = "newsletter"
+)
+
+// mailTemplate เนื้อหาของอีเมลหนึ่งประเภท (subject และ text ใช้ text/template, html ใช้ html/template)
+type mailTemplate struct {
+	subject string
+	html    string
+	text    string
+}
+
+// layout ส่วนหัวและท้ายที่ใช้ร่วมกันของอีเมลแบบ HTML
+const layout = `<!DOCTYPE html>
+<html><body style="font-family: Arial, sans-serif; background: #f4f4f7; padding: 24px;">
+<div style="max-width: 560px; margin: 0 auto; background: #ffffff; border-radius: 8px; padding: 24px;">
+<h2 style="color: #333333;">Game Store</h2>
+{{template "content" .}}
+<p style="color: #999999; font-size: 12px; margin-top: 32px;">This is an automated message, please do not reply.</p>
+</div>
+</body></html>`
+
+var templates = map[string]mailTemplate{
+	TemplateWelcome: {
+		subject: `Welcome to Game Store, {{.Username}}!`,
+		html: `<p>Hi {{.Username}},</p>
+<p>Thanks for joining Game Store. Your account is ready — top up your wallet and start building your library.</p>`,
+		text: `Hi {{.Username}},
+
+Thanks for joining Game Store. Your account is ready - top up your wallet and start building your library.`,
+	},
+	TemplateReceipt: {
+		subject: `Your Game Store receipt #{{.PurchaseID}}`,
+		html: `<p>Hi {{.Username}},</p>
+<p>Thank you for your purchase. Here is your receipt:</p>
+<table style="width: 100%; border-collapse: collapse;">
+{{range .Items}}<tr><td style="padding: 4px 0;">{{.Name}}</td><td style="text-align: right;">${{printf "%.2f" .Price}}</td></tr>
+{{end}}<tr><td style="padding-top: 8px;">Subtotal</td><td style="text-align: right;">${{printf "%.2f" .Total}}</td></tr>
+{{if .Discount}}<tr><td>Discount</td><td style="text-align: right;">-${{printf "%.2f" .Discount}}</td></tr>
+{{end}}{{if .Tax}}<tr><td>{{.TaxName}} ({{printf "%.2f" .TaxRate}}%)</td><td style="text-align: right;">${{printf "%.2f" .Tax}}</td></tr>
+{{end}}<tr><td><strong>Total paid</strong></td><td style="text-align: right;"><strong>${{printf "%.2f" .FinalAmount}}</strong></td></tr>
+</table>`,
+		text: `Hi {{.Username}},
+
+Thank you for your purchase. Receipt #{{.PurchaseID}}:
+{{range .Items}}- {{.Name}}: ${{printf "%.2f" .Price}}
+{{end}}
+Subtotal: ${{printf "%.2f" .Total}}
+{{if .Discount}}Discount: -${{printf "%.2f" .Discount}}
+{{end}}{{if .Tax}}{{.TaxName}} ({{printf "%.2f" .TaxRate}}%): ${{printf "%.2f" .Tax}}
+{{end}}Total paid: ${{printf "%.2f" .FinalAmount}}`,
+	},
+	TemplatePasswordReset: {
+		subject: `Reset your Game Store password`,
+		html: `<p>Hi {{.Username}},</p>
+<p>We received a request to reset your password. Use the link below within {{.ExpiresIn}}:</p>
+<p><a href="{{.ResetURL}}" style="background: #4f46e5; color: #ffffff; padding: 10px 16px; border-radius: 4px; text-decoration: none;">Reset password</a></p>
+<p>If you did not request this, you can ignore this email.</p>`,
+		text: `Hi {{.Username}},
+
+We received a request to reset your password. Use the link below within {{.ExpiresIn}}:
+{{.ResetURL}}
+
+If you did not request this, you can ignore this email.`,
+	},
+	TemplateRefundStatus: {
+		subject: `Your refund request for purchase #{{.PurchaseID}} was {{.Status}}`,
+		html: `<p>Hi {{.Username}},</p>
+<p>Your refund request for purchase #{{.PurchaseID}} was <strong>{{.Status}}</strong>.</p>
+{{if .Amount}}<p>${{printf "%.2f" .Amount}} has been returned to your wallet.</p>{{end}}
+{{if .Reason}}<p>Note: {{.Reason}}</p>{{end}}`,
+		text: `Hi {{.Username}},
+
+Your refund request for purchase #{{.PurchaseID}} was {{.Status}}.
+{{if .Amount}}${{printf "%.2f" .Amount}} has been returned to your wallet.
+{{end}}{{if .Reason}}Note: {{.Reason}}{{end}}`,
+	},
+	TemplateGift: {
+		subject: `You received a gift on Game Store`,
+		html: `<p>Hi {{.Username}},</p>
+<p>{{.Message}}</p>`,
+		text: `Hi {{.Username}},
+
+{{.Message}}`,
+	},
+	TemplatePriceDrop: {
+		subject: `{{.GameName}} on your wishlist is now ${{printf "%.2f" .NewPrice}}`,
+		html: `<p>Hi {{.Username}},</p>
+<p><strong>{{.GameName}}</strong> on your wishlist dropped from <s>${{printf "%.2f" .OldPrice}}</s> to <strong>${{printf "%.2f" .NewPrice}}</strong> ({{.Percent}}% off).</p>`,
+		text: `Hi {{.Username}},
+
+{{.GameName}} on your wishlist dropped from ${{printf "%.2f" .OldPrice}} to ${{printf "%.2f" .NewPrice}} ({{.Percent}}% off).`,
+	},
+	TemplatePriceDigest: {
+		subject: `{{len .Games}} games on your wishlist are cheaper now`,
+		html: `<p>Hi {{.Username}},</p>
+<p>These games on your wishlist dropped in price:</p>
+<table style="width: 100%; border-collapse: collapse;">
+{{range .Games}}<tr><td style="padding: 4px 0;">{{.Name}}</td><td style="text-align: right;"><s>${{printf "%.2f" .OldPrice}}</s> <strong>${{printf "%.2f" .NewPrice}}</strong></td></tr>
+{{end}}</table>`,
+		text: `Hi {{.Username}},
+
+These games on your wishlist dropped in price:
+{{range .Games}}- {{.Name}}: ${{printf "%.2f" .OldPrice}} -> ${{printf "%.2f" .NewPrice}}
+{{end}}`,
+	},
+	// เนื้อหามาจากผู้ดูแลระบบ (Content เป็น html/template.HTML) ต่อท้ายด้วยลิงก์ยกเลิกการรับข่าวสาร
+	TemplateNewsletter: {
+		subject: `{{.Subject}}`,
+		html: `{{.Content}}
+<p style="color: #999999; font-size: 12px; margin-top: 24px;">You are receiving this because you subscribed to Game Store news. <a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>`,
+		text: `{{.Text}}
+
+--
+You are receiving this because you subscribed to Game Store news.
+Unsubscribe: {{.UnsubscribeURL}}`,
+	},
+}
+
+// Render สร้างอีเมลจาก template ตามชื่อ (ยังไม่กำหนดผู้รับ)
+func Render(name string, data map[string]interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template: %s", name)
+	}
+
+	subject, err := renderText(name+".subject", tmpl.subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	text, err := renderText(name+".text", tmpl.text, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	htmlTmpl, err := htmltemplate.New(name).Parse(layout)
+	if err == nil {
+		_, err = htmlTmpl.New("content").Parse(tmpl.html)
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("error parsing mail template %s: %v", name, err)
+	}
+	var html bytes.Buffer
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("error rendering mail template %s: %v", name, err)
+	}
+
+	return Message{Subject: subject, HTML: html.String(), Text: text}, nil
+}
+
+func renderText(name, source string, data map[string]interface{}) (string, error) {
+	tmpl, err := texttemplate.New(name).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("error parsing mail template %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering mail template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
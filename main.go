@@ -3,12 +3,20 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"go-api-game/errreport"
 	"go-api-game/handlers"
+	"go-api-game/tracing"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
+	"go-api-game/cache"
 	"go-api-game/config"
+	"go-api-game/jobs"
+	"go-api-game/mailer"
+	"go-api-game/payments"
+	"go-api-game/storage"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/rs/cors"
@@ -17,26 +25,78 @@ import (
 var db *sql.DB
 
 func main() {
+	// --------------------------
+	// Production Mode
+	// โหมด production (APP_ENV=production) แสดงเฉพาะ log ข้อผิดพลาดและคำเตือน
+	// --------------------------
+	// access log (ACCESS_LOG_FORMAT, ACCESS_LOG_FILE) เปิดก่อนกรอง log เพื่อให้ยังเขียนลง stdout ได้ในโหมด production
+	if err := handlers.InitAccessLog(); err != nil {
+		log.Fatal(err)
+	}
+	// OpenTelemetry tracing (OTEL_EXPORTER_OTLP_ENDPOINT) ต้องเปิดก่อน sql.Open เพื่อใช้ driver ที่สร้าง span ของ SQL
+	tracing.Init()
+	// ส่ง panic ของ handler ไปยังระบบรายงานข้อผิดพลาด (SENTRY_DSN ไม่ตั้ง = แสดงใน log เท่านั้น)
+	errreport.Init()
+	if config.IsProduction() {
+		config.SilenceVerboseLogs()
+		log.Println("🔒 Production mode: verbose logging disabled")
+	}
+
+	// จำกัด IP ที่เรียก /admin/* ได้ (ADMIN_ALLOWED_CIDRS ว่าง = ไม่จำกัด)
+	if err := handlers.SetAdminAllowlist(os.Getenv("ADMIN_ALLOWED_CIDRS")); err != nil {
+		log.Fatal(err)
+	}
+	// TRUST_PROXY=true ใช้ IP จาก X-Forwarded-For ของ reverse proxy กับทุก request (ADMIN_TRUST_PROXY คือชื่อเดิม)
+	if _, ok := os.LookupEnv("TRUST_PROXY"); !ok && os.Getenv("ADMIN_TRUST_PROXY") != "" {
+		log.Println("⚠️ ADMIN_TRUST_PROXY is deprecated and applies to all requests, use TRUST_PROXY instead")
+	}
+
 	// --------------------------
 	// Connect Database
 	// --------------------------
 	var err error
-	// ข้อมูลการเชื่อมต่อฐานข้อมูล MySQL
+	// เลือก SQL dialect (DB_DIALECT ตอนนี้รองรับเฉพาะ mysql ซึ่งเป็นค่าเริ่มต้น)
+	if err = handlers.SetDialect(os.Getenv("DB_DIALECT")); err != nil {
+		log.Fatal(err)
+	}
+	// ข้อมูลการเชื่อมต่อฐานข้อมูล (ตั้ง DB_DSN เพื่อใช้ฐานข้อมูลอื่น)
 	dsn := "65011212151:TxEy2003122@tcp(202.28.34.210:3309)/db65011212151"
-	db, err = sql.Open("mysql", dsn)
+	if envDSN := os.Getenv("DB_DSN"); envDSN != "" {
+		dsn = envDSN
+	}
+	db, err = sql.Open(handlers.DatabaseDriver(), handlers.DatabaseDSN(dsn))
 	if err != nil {
 		log.Fatal("Cannot connect to database:", err)
 	}
 	defer db.Close()
 
+	// จำกัดจำนวน connection เพื่อไม่ให้ใช้ connection ของ MySQL จนหมดเมื่อมี request มาก
+	pool := config.LoadDBPool()
+	pool.Apply(db)
+	fmt.Printf("✅ Database pool: max_open=%d max_idle=%d max_lifetime=%s max_idle_time=%s\n",
+		pool.MaxOpenConns, pool.MaxIdleConns, pool.ConnMaxLifetime, pool.ConnMaxIdleTime)
+
 	// ทดสอบการเชื่อมต่อฐานข้อมูล
 	if err = db.Ping(); err != nil {
 		log.Fatal("Cannot ping database:", err)
 	}
-	fmt.Println("✅ Connected to database successfully")
+	fmt.Printf("✅ Connected to database successfully (%s)\n", handlers.DatabaseDialect())
+
+	// Read replica (ไม่บังคับ) สำหรับ endpoint ที่อ่านข้อมูลจำนวนมาก ถ้าล่มจะใช้ฐานข้อมูลหลักแทน
+	if readDSN := os.Getenv("DB_READ_DSN"); readDSN != "" {
+		replica, err := sql.Open(handlers.DatabaseDriver(), handlers.DatabaseDSN(readDSN))
+		if err != nil {
+			log.Fatal("Invalid DB_READ_DSN:", err)
+		}
+		defer replica.Close()
+		pool.Apply(replica)
+		handlers.InitReadReplica(replica)
+	}
 
 	// Initialize handlers with database
 	handlers.InitDB(db)
+	handlers.StartEventsWorker()
+	handlers.StartGRPCServer() // gRPC สำหรับระบบภายใน (เปิดเมื่อตั้ง GRPC_API_TOKEN)
 
 	// Create uploads folder if not exists
 	// สร้างโฟลเดอร์ uploads หากยังไม่มี (สำหรับเก็บไฟล์ภาพ)
@@ -48,36 +108,96 @@ func main() {
 	// Initialize Cloudinary
 	// --------------------------
 	config.InitCloudinary()
+	storage.Init()
+
+	// --------------------------
+	// Initialize Cache
+	// --------------------------
+	cache.Init()
+
+	// --------------------------
+	// Initialize Mailer
+	// --------------------------
+	mailer.Init()
+
+	// --------------------------
+	// Initialize Payment Gateway
+	// --------------------------
+	payments.Init()
+
+	// --------------------------
+	// Start Background Jobs
+	// งานเบื้องหลัง (ส่งอีเมล, คำนวณอันดับ, archive ส่วนลด, ลบไฟล์ที่ไม่ใช้)
+	// --------------------------
+	handlers.RegisterJobs()
+	jobs.Start(db)
 
 	// --------------------------
 	// Public Routes
 	// เส้นทางที่ไม่ต้องยืนยันตัวตน
 	// --------------------------
-	http.HandleFunc("/", handlers.RootHandler)                 // หน้าแรก
-	http.HandleFunc("/register", handlers.RegisterHandler)     // ลงทะเบียน
-	http.HandleFunc("/login", handlers.LoginHandler)           // เข้าสู่ระบบ
-	http.HandleFunc("/games", handlers.GamesHandler)           // รายการเกมทั้งหมด
-	http.HandleFunc("/games/", handlers.GameByIDHandler)       // ข้อมูลเกมตาม ID
-	http.HandleFunc("/categories", handlers.CategoriesHandler) // รายการหมวดหมู่
-	http.HandleFunc("/search", handlers.SearchHandler)         // ค้นหาเกม
-	http.HandleFunc("/ranking", handlers.RankingHandler)       // อันดับเกม
+	http.HandleFunc("/", handlers.RootHandler)                                                                                                           // หน้าแรก
+	http.HandleFunc("/register", handlers.RegisterHandler)                                                                                               // ลงทะเบียน
+	http.HandleFunc("/login", handlers.LoginHandler)                                                                                                     // เข้าสู่ระบบ
+	http.Handle("/games", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.GamesHandler)))) // รายการเกมทั้งหมด
+	http.Handle("/games/", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.OptionalAuth(http.HandlerFunc(handlers.GameRoutesHandler))))      // ข้อมูลเกม ประวัติราคา และคำถาม
+	http.Handle("/games/new", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.NewGamesHandler))))
+	http.Handle("/games/upcoming", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.UpcomingGamesHandler)))) // ข้อมูลเกมตาม ID
+	http.Handle("/categories", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.CategoriesHandler))))        // รายการหมวดหมู่
+	http.HandleFunc("/sitemap.xml", handlers.SitemapHandler)                                                                                                              // sitemap สำหรับ search engine
+	http.HandleFunc("/feed/games.json", handlers.GamesFeedHandler)                                                                                                        // feed เกมใหม่สำหรับพาร์ทเนอร์
+	http.Handle("/search", handlers.APIKeyOptional(handlers.ScopeReadCatalog, http.HandlerFunc(handlers.SearchHandler)))                                                  // ค้นหาเกม
+	http.Handle("/ranking", handlers.APIKeyOptional(handlers.ScopeReadCatalog, handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.RankingHandler))))              // อันดับเกม
+	http.HandleFunc("/storefront", handlers.ConditionalCatalog(handlers.CacheCatalog(handlers.StorefrontHandler)))
+	http.HandleFunc("/metrics", handlers.MetricsHandler)        // สถิติ connection pool (Prometheus, ต้องตั้ง METRICS_TOKEN)
+	http.HandleFunc("/meta/locale", handlers.MetaLocaleHandler) // สกุลเงิน ภาษา และรูปแบบการแสดงผล
+	http.Handle("/flags", handlers.OptionalAuth(http.HandlerFunc(handlers.FeatureFlagsHandler)))
+	http.Handle("/graphql", handlers.OptionalAuth(http.HandlerFunc(handlers.GraphQLHandler))) // GraphQL (เลือก field และ batching)
+	http.Handle("/lists", handlers.OptionalAuth(http.HandlerFunc(handlers.ListsHandler)))     // รายการเกมของผู้ใช้
+	http.Handle("/lists/", handlers.OptionalAuth(http.HandlerFunc(handlers.ListsHandler)))
+	http.HandleFunc("/avatars/defaults", handlers.DefaultAvatarsHandler) // รูปโปรไฟล์สำเร็จรูป
+	http.HandleFunc("/avatars/defaults/", handlers.DefaultAvatarsHandler)
+	http.HandleFunc("/share/library", handlers.SharedLibraryItemHandler)                           // การ์ดเกมจากลิงก์แชร์ (ลิงก์ที่เซ็นแล้ว)
+	http.Handle("/users/", handlers.OptionalAuth(http.HandlerFunc(handlers.PublicProfileHandler))) // โปรไฟล์สาธารณะ
 
 	// --------------------------
 	// User Routes (Protected)
 	// เส้นทางที่ต้องยืนยันตัวตน (ผู้ใช้ทั่วไป)
 	// --------------------------
+	http.Handle("/logout", handlers.AuthMiddleware(http.HandlerFunc(handlers.LogoutHandler)))
 	http.Handle("/profile", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileHandler)))
 	http.Handle("/wallet", handlers.AuthMiddleware(http.HandlerFunc(handlers.WalletHandler)))
 	http.Handle("/deposit", handlers.AuthMiddleware(http.HandlerFunc(handlers.DepositHandler)))
 	http.Handle("/transactions", handlers.AuthMiddleware(http.HandlerFunc(handlers.TransactionsHandler)))
-	http.Handle("/library", handlers.AuthMiddleware(http.HandlerFunc(handlers.LibraryHandler)))
+	http.Handle("/transactions/statement", handlers.AuthMiddleware(http.HandlerFunc(handlers.StatementHandler)))
+	http.Handle("/library", handlers.APIKeyAuth(handlers.ScopeReadLibrary, http.HandlerFunc(handlers.LibraryHandler))) // รับคีย์ API (read:library) แทน token ได้
+	http.Handle("/library/", handlers.APIKeyAuth(handlers.ScopeReadLibrary, http.HandlerFunc(handlers.LibraryItemHandler)))
 	http.Handle("/cart", handlers.AuthMiddleware(http.HandlerFunc(handlers.CartHandler)))
 	http.Handle("/cart/add", handlers.AuthMiddleware(http.HandlerFunc(handlers.AddToCartHandler)))
 	http.Handle("/cart/remove", handlers.AuthMiddleware(http.HandlerFunc(handlers.RemoveFromCartHandler)))
 	http.Handle("/checkout", handlers.AuthMiddleware(http.HandlerFunc(handlers.CheckoutHandler)))
 	http.Handle("/purchases", handlers.AuthMiddleware(http.HandlerFunc(handlers.PurchaseHistoryHandler)))
 	http.Handle("/profile/update", handlers.AuthMiddleware(http.HandlerFunc(handlers.UpdateProfileHandler)))
+	http.Handle("/profile/settings", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileSettingsHandler)))
+	http.Handle("/profile/preferences", handlers.AuthMiddleware(http.HandlerFunc(handlers.PreferencesHandler)))
+	http.Handle("/profile/activity", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileActivityHandler)))
+	http.Handle("/profile/sessions", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileSessionsHandler)))
+	http.Handle("/profile/sessions/", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileSessionsHandler)))
+	http.Handle("/profile/api-keys", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileAPIKeysHandler))) // คีย์ API ของนักพัฒนา
+	http.Handle("/profile/api-keys/", handlers.AuthMiddleware(http.HandlerFunc(handlers.ProfileAPIKeysHandler)))
 	http.Handle("/discounts/apply", handlers.AuthMiddleware(http.HandlerFunc(handlers.ApplyDiscountHandler)))
+	http.Handle("/wallet/auto-topup", handlers.AuthMiddleware(http.HandlerFunc(handlers.AutoTopUpHandler)))
+	http.Handle("/wallet/redeem", handlers.AuthMiddleware(http.HandlerFunc(handlers.RedeemGiftCardHandler)))
+	http.Handle("/reports", handlers.AuthMiddleware(http.HandlerFunc(handlers.ReportHandler)))
+	http.Handle("/recommendations", handlers.AuthMiddleware(http.HandlerFunc(handlers.RecommendationsHandler)))
+	http.HandleFunc("/events", handlers.EventsHandler) // SSE (ตรวจสอบ token เอง หรือ ?ticket= จาก /events/ticket)
+	http.Handle("/events/ticket", handlers.AuthMiddleware(http.HandlerFunc(handlers.EventsTicketHandler)))
+	http.Handle("/newsletter/subscribe", handlers.OptionalAuth(http.HandlerFunc(handlers.NewsletterSubscribeHandler))) // สมัครรับข่าวสาร
+	http.Handle("/newsletter/unsubscribe", handlers.OptionalAuth(http.HandlerFunc(handlers.NewsletterUnsubscribeHandler)))
+	http.Handle("/wishlist", handlers.AuthMiddleware(http.HandlerFunc(handlers.WishlistHandler))) // wishlist และการแจ้งเตือนลดราคา
+	http.Handle("/wishlist/", handlers.AuthMiddleware(http.HandlerFunc(handlers.WishlistHandler)))
+	http.Handle("/notifications", handlers.AuthMiddleware(http.HandlerFunc(handlers.NotificationsHandler)))
+	http.Handle("/notifications/", handlers.AuthMiddleware(http.HandlerFunc(handlers.NotificationsHandler)))
 
 	// --------------------------
 	// Admin Routes (Protected + Admin only)
@@ -86,11 +206,44 @@ func main() {
 	http.Handle("/admin/games", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAddGameHandler))))
 	http.Handle("/admin/games/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminUpdateGameHandler))))
 	http.Handle("/admin/games/delete/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminDeleteGameHandler))))
+	http.Handle("/admin/games/purge/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminPurgeGameHandler))))
 	http.Handle("/admin/discounts", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminDiscountHandler))))
 	http.Handle("/admin/discounts/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminDiscountHandler))))
+	http.Handle("/admin/gift-cards", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminGiftCardHandler))))
+	http.Handle("/admin/gift-cards/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminGiftCardHandler))))
 	http.Handle("/admin/users", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminUsersHandler))))
+	http.Handle("/admin/users/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminUserActionsHandler))))
+	http.Handle("/admin/dashboard", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminDashboardHandler))))
 	http.Handle("/admin/stats", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminStatsHandler))))
+	http.Handle("/admin/stats/revenue", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminRevenueStatsHandler))))
+	http.Handle("/admin/stats/customers", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminCustomerStatsHandler))))
+	http.Handle("/admin/stats/abandoned-carts", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAbandonedCartsHandler))))
+	http.Handle("/admin/storefront", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminStorefrontHandler))))
+	http.Handle("/admin/storefront/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminStorefrontHandler))))
+	http.Handle("/admin/reports", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminReportHandler))))
+	http.Handle("/admin/reports/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminReportHandler))))
+	http.Handle("/admin/questions/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminQuestionsHandler))))  // ดูแลคำถามและคำตอบของเกม
+	http.Handle("/admin/affiliates", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAffiliatesHandler)))) // พาร์ทเนอร์และค่าคอมมิชชัน
+	http.Handle("/admin/affiliates/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAffiliatesHandler))))
+	http.Handle("/admin/tax-rules", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminTaxRuleHandler))))
+	http.Handle("/admin/tax-rules/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminTaxRuleHandler))))
+	http.Handle("/admin/inventory/low-stock", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminLowStockHandler))))
+	http.Handle("/admin/media", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminMediaHandler))))
+	http.Handle("/admin/media/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminMediaHandler))))
+	http.Handle("/admin/newsletters", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminNewsletterHandler))))
+	http.Handle("/admin/newsletters/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminNewsletterHandler))))
+	http.Handle("/admin/webhooks", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminWebhookHandler))))
+	http.Handle("/admin/webhooks/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminWebhookHandler))))
+	http.Handle("/admin/avatars", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAvatarHandler))))
+	http.Handle("/admin/avatars/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAvatarHandler))))
+	http.Handle("/admin/risk/queue", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminRiskQueueHandler))))
+	http.Handle("/admin/risk/queue/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminRiskQueueHandler))))
+	http.Handle("/admin/flags", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminFeatureFlagHandler))))
+	http.Handle("/admin/flags/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminFeatureFlagHandler))))
+	http.Handle("/admin/audit", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminAuditHandler))))
+	http.Handle("/admin/diagnostics", handlers.AuthMiddleware(handlers.AdminOnly(handlers.RequirePermission(handlers.PermissionDiagnostics, http.HandlerFunc(handlers.AdminDiagnosticsHandler)))))
 	http.Handle("/admin/transactions", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminTransactionsHandler))))
+	http.Handle("/admin/activity/user/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminUserActivityHandler))))
 	http.Handle("/admin/transactions/user/", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.AdminUserTransactionsHandler))))
 	http.Handle("/admin/transactions/stats", handlers.AuthMiddleware(handlers.AdminOnly(http.HandlerFunc(handlers.TransactionStatsHandler))))
 
@@ -98,30 +251,40 @@ func main() {
 	// Serve static files
 	// ให้บริการไฟล์ static (ภาพ)
 	// --------------------------
-	http.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
+	http.HandleFunc("/uploads/", handlers.UploadsHandler)
 
 	// --------------------------
 	// Configure CORS
 	// ตั้งค่า CORS สำหรับการเรียกข้าม domain
 	// --------------------------
+	// อ่าน origin, credentials และ debug จาก environment (ดู config.LoadCORS)
+	corsConfig := config.LoadCORS()
 	c := cors.New(cors.Options{
-		AllowedOrigins: []string{
-			"http://localhost:4200",
-			"https://game-shop-web.onrender.com",
-		},
+		AllowedOrigins: corsConfig.AllowedOrigins,
 		AllowedMethods: []string{
 			"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH",
 		},
 		AllowedHeaders: []string{
 			"Content-Type",
 			"Authorization",
+			"X-CSRF-Token",
+			"X-Request-ID",
+			"X-API-Key",
+		},
+		ExposedHeaders: []string{
+			"X-Request-ID",
+			"X-RateLimit-Limit",
+			"X-RateLimit-Remaining",
+			"X-RateLimit-Reset",
 		},
-		AllowCredentials: true,
-		Debug:            false,
+		AllowCredentials: corsConfig.AllowCredentials,
+		Debug:            corsConfig.Debug,
 	})
 
-	// Wrap the default handler with CORS
-	handler := c.Handler(http.DefaultServeMux)
+	// Wrap the default handler with sparse field selection (?fields=), partner referral tracking (?ref=),
+	// response language selection (Accept-Language / ?lang=), CORS, response compression, removal of client-supplied identity headers (User-ID, Role, ...),
+	// panic recovery, access logging, tracing spans and request IDs (see handlers.Chain)
+	handler := handlers.Chain(http.DefaultServeMux, c.Handler)
 	log.Fatal(http.ListenAndServe(":8080", handler))
 
 	// --------------------------
@@ -132,33 +295,124 @@ func main() {
 	fmt.Printf("🌐 Server IP: %s\n", ip)
 	fmt.Printf("🚀 Server started at http://%s:8080\n", ip)
 	fmt.Printf("🚀 Also available at http://localhost:8080\n")
-	fmt.Println("✅ CORS enabled for: http://localhost:4200")
+	fmt.Printf("✅ CORS enabled for: %s\n", strings.Join(corsConfig.AllowedOrigins, ", "))
 	fmt.Println("📚 Available endpoints:")
 	fmt.Println("   PUBLIC:")
 	fmt.Println("   GET  /                 - Home page")
 	fmt.Println("   POST /register         - Register user")
-	fmt.Println("   POST /login            - Login")
-	fmt.Println("   GET  /games            - List all games")
+	fmt.Println("   POST /login            - Login (\"use_cookie\": true for cookie + CSRF mode)")
+	fmt.Println("   GET  /games            - List all games (?max_age= hides age-rated games)")
 	fmt.Println("   GET  /games/{id}       - Get game details")
+	fmt.Println("   GET  /games/slug/{slug} - Get game details by slug (also /price-history)")
+	fmt.Println("   GET  /games/{id}/price-history - Price chart and lowest price in 30 days (?days=)")
+	fmt.Println("   GET  /games/{id}/questions - Questions and answers (POST to ask, login required)")
+	fmt.Println("   POST /games/{id}/questions/{qid}/answers - Answer a question (notifies the asker)")
+	fmt.Println("   GET  /games/new        - Recently released games")
+	fmt.Println("   GET  /games/upcoming   - Coming soon")
+	fmt.Println("   GET  /sitemap.xml      - Sitemap of store pages, games and public lists")
+	fmt.Println("   GET  /feed/games.json  - JSON Feed of new releases (regenerated hourly)")
 	fmt.Println("   GET  /categories       - List categories")
 	fmt.Println("   GET  /search           - Search games")
 	fmt.Println("   GET  /ranking          - Game rankings")
+	fmt.Println("   GET  /storefront       - Homepage banners and curated collections")
+	fmt.Println("   GET  /meta/locale      - Currency, locales, tax display and date formats")
+	fmt.Println("   GET  /metrics          - Database pool stats, Prometheus format (Bearer METRICS_TOKEN)")
+	fmt.Println("   POST /newsletter/subscribe - Subscribe to newsletter ({email}, or account email when logged in)")
+	fmt.Println("   GET  /newsletter/unsubscribe?email=&token= - Signed unsubscribe link")
 	fmt.Println("   USER:")
+	fmt.Println("   POST /logout           - Log out current session")
 	fmt.Println("   GET  /profile          - User profile")
+	fmt.Println("   GET  /profile/settings - Privacy settings (PUT to update)")
+	fmt.Println("   GET  /profile/preferences - Locale and time zone (PUT to update; CSV/PDF exports use it, or ?tz=)")
+	fmt.Println("   GET  /users/{username} - Public profile")
+	fmt.Println("   GET  /avatars/defaults - Built-in avatars (use id as default_avatar on register/profile)")
+	fmt.Println("   GET  /share/library?u=&g=&exp=&sig= - Shared game card (signed link)")
+	fmt.Println("   GET  /flags            - Feature flags for the current user")
+	fmt.Println("   POST /graphql          - GraphQL queries: games, game, categories, cart, library, purchases (array body = batch)")
+	fmt.Println("   GET  /lists            - My game lists (?following=1 for followed, POST to create)")
+	fmt.Println("   GET  /lists/popular    - Trending public lists")
+	fmt.Println("   GET  /lists/{slug}     - View list (PUT/DELETE by owner)")
+	fmt.Println("   POST /lists/{slug}/follow - Follow list (DELETE to unfollow)")
+	fmt.Println("   GET  /profile/activity - Account security log")
+	fmt.Println("   GET  /profile/sessions - Logged-in devices")
+	fmt.Println("   DELETE /profile/sessions/{id} - Log out a device (no id = all other devices)")
+	fmt.Println("   GET  /profile/api-keys - Developer API keys (POST to create, GET/DELETE /{id}; send as X-API-Key)")
 	fmt.Println("   GET  /wallet           - Wallet balance")
 	fmt.Println("   POST /deposit          - Deposit money")
+	fmt.Println("   POST /wallet/redeem    - Redeem gift card")
+	fmt.Println("   PUT  /wallet/auto-topup - Auto top-up settings")
 	fmt.Println("   GET  /transactions     - Transaction history")
-	fmt.Println("   GET  /library          - User game library")
+	fmt.Println("   GET  /transactions/statement?month=YYYY-MM - Monthly statement (JSON/CSV/PDF)")
+	fmt.Println("   GET  /library          - User game library (?sort=purchased_at|name|playtime|last_played&order=&category=&tag=&installed=&favorite=&hidden=true|all)")
+	fmt.Println("   PATCH /library/{game_id} - Favorite, hide, tag or mark an owned game as installed")
+	fmt.Println("   POST /library/{game_id}/playtime - Record a play session")
+	fmt.Println("   GET  /library/owns/{game_id} - Signed ownership assertion (POST /library/owns {\"game_ids\": [...]} for batch)")
+	fmt.Println("   POST /library/{game_id}/share - Create expiring public link to a game card")
+	fmt.Println("   GET  /library/{game_id}/saves - Download cloud save (PUT ?base_revision= to upload)")
+	fmt.Println("   GET  /library/{game_id}/saves/versions - Cloud save versions")
 	fmt.Println("   GET  /cart             - Get cart")
 	fmt.Println("   POST /cart/add         - Add to cart")
 	fmt.Println("   POST /cart/remove      - Remove from cart")
 	fmt.Println("   POST /checkout         - Checkout cart")
 	fmt.Println("   GET  /purchases        - Purchase history")
+	fmt.Println("   GET  /recommendations  - Recommended games")
+	fmt.Println("   POST /reports          - Report a game, avatar or list")
+	fmt.Println("   GET  /wishlist         - My wishlist (POST {game_id} to add)")
+	fmt.Println("   DELETE /wishlist/{game_id} - Remove from wishlist")
+	fmt.Println("   GET  /wishlist/alerts  - Price drop alert settings: instant or daily digest (PUT to update)")
+	fmt.Println("   GET  /notifications    - Notification center")
+	fmt.Println("   GET  /events           - Real-time updates (SSE; admins also receive admin.metrics and admin.sale)")
+	fmt.Println("   POST /events/ticket    - One-time ticket for EventSource clients (GET /events?ticket=...)")
+	fmt.Println("   PATCH /notifications/{id}/read - Mark notification read")
 	fmt.Println("   ADMIN:")
+	fmt.Println("   GET  /admin/games      - List games (incl. drafts)")
 	fmt.Println("   POST /admin/games      - Add new game")
+	fmt.Println("   GET  /admin/games/{id} - Preview game")
+	fmt.Println("   GET  /admin/games/{id}/stats - Per-game sales stats")
+	fmt.Println("   POST /admin/games/{id}/restock - Restock a limited game")
+	fmt.Println("   POST /admin/games/{id}/duplicate - Clone a game into a new draft")
+	fmt.Println("   PATCH /admin/games/prices - Bulk price update (prices list or percent by category, dry_run)")
+	fmt.Println("   GET  /admin/inventory/low-stock - Games running out of stock")
 	fmt.Println("   POST /admin/discounts  - Add discount code")
+	fmt.Println("   GET  /admin/tax-rules  - List tax rules")
+	fmt.Println("   POST /admin/tax-rules  - Add tax rule (region or \"*\" for default)")
+	fmt.Println("   PUT  /admin/tax-rules/{id} - Update tax rule")
+	fmt.Println("   DELETE /admin/tax-rules/{id} - Delete tax rule")
+	fmt.Println("   GET  /admin/avatars    - Uploaded avatars awaiting review (?status=pending|approved)")
+	fmt.Println("   POST /admin/avatars/{user_id}/approve - Approve avatar (or /reset to a built-in avatar)")
+	fmt.Println("   GET  /admin/risk/queue - Orders held by risk checks")
+	fmt.Println("   POST /admin/risk/queue/{purchase_id}/approve - Deliver held order (or /reject to refund)")
+	fmt.Println("   GET  /admin/reports    - Moderation queue (?status=open|resolved|dismissed)")
+	fmt.Println("   POST /admin/reports/{id}/resolve - Remove reported content (or /dismiss)")
+	fmt.Println("   POST /admin/questions/answers/{id}/highlight - Highlight an answer (DELETE to remove)")
+	fmt.Println("   DELETE /admin/questions/{id} - Delete a question (or /answers/{id} for an answer)")
+	fmt.Println("   GET  /admin/affiliates - Partners with totals (POST to add, PUT/DELETE /{id})")
+	fmt.Println("   GET  /admin/affiliates/report - Commission per partner per period (?from=&to=&group_by=&format=csv)")
+	fmt.Println("   GET  /admin/storefront - Storefront layout (incl. inactive)")
+	fmt.Println("   GET  /admin/games/{id}/translations - Game translations (PUT/DELETE /{locale})")
+	fmt.Println("   POST /admin/storefront/banners - Add banner (PUT/DELETE /{id})")
+	fmt.Println("   POST /admin/storefront/collections - Add curated collection (PUT/DELETE /{id})")
+	fmt.Println("   PUT  /admin/storefront/order - Reorder banners and collections")
+	fmt.Println("   POST /admin/gift-cards - Create gift cards")
+	fmt.Println("   GET  /admin/media      - Image library")
 	fmt.Println("   GET  /admin/users      - List users")
+	fmt.Println("   POST /admin/users/{id}/wallet/adjust - Credit/debit a wallet")
+	fmt.Println("   POST /admin/users/{id}/grant-game - Give a game to a user (zero-value order)")
+	fmt.Println("   GET/PUT /admin/users/{id}/permissions - View/replace an admin's extra permissions (e.g. diagnostics)")
+	fmt.Println("   GET  /admin/activity/user/{id} - User security log")
+	fmt.Println("   GET  /admin/dashboard  - Today's revenue, orders, signups, active discounts, low stock and refunds in one call")
 	fmt.Println("   GET  /admin/stats      - Statistics")
+	fmt.Println("   GET  /admin/stats/revenue - Revenue by day/week/month")
+	fmt.Println("   GET  /admin/stats/customers - Customer analytics")
+	fmt.Println("   GET  /admin/stats/abandoned-carts - Abandoned carts report")
+	fmt.Println("   GET  /admin/audit      - Admin audit log (?action=api_request for raw admin API calls)")
+	fmt.Println("   GET  /admin/diagnostics - Slowest endpoints/SQL, goroutines, DB pool saturation (diagnostics permission)")
+	fmt.Println("   GET  /admin/flags      - Feature flags (POST to create)")
+	fmt.Println("   PUT  /admin/flags/{key} - Update flag: enabled, rollout_percent, user_ids (DELETE to reset to default)")
+	fmt.Println("   POST /admin/newsletters - Send newsletter to a segment (all, category_purchasers; dry_run)")
+	fmt.Println("   GET  /admin/newsletters - Newsletter campaigns")
+	fmt.Println("   POST /admin/webhooks   - Register webhook")
+	fmt.Println("   GET  /admin/webhooks/deliveries - Webhook delivery log")
 
 	// ใช้ handler ที่มี CORS
 	log.Fatal(http.ListenAndServe(":8080", handler))
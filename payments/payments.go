@@ -0,0 +1,87 @@
+// payments/payments.go
+package payments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrNotConfigured ไม่ได้ตั้งค่าช่องทางชำระเงิน (ตัดเงินอัตโนมัติไม่ได้)
+var ErrNotConfigured = errors.New("payment gateway is not configured")
+
+// Gateway คือช่องทางชำระเงินที่ใช้ตัดเงินจากวิธีชำระเงินที่ผู้ใช้บันทึกไว้
+type Gateway interface {
+	// Name ชื่อของช่องทางชำระเงิน (none, simulated)
+	Name() string
+	// Charge ตัดเงินจากผู้ใช้ คืนค่ารหัสอ้างอิงของการชำระเงิน
+	Charge(userID int, amount float64, description string) (string, error)
+}
+
+// Default ช่องทางชำระเงินที่ใช้ทั้งระบบ (ค่าเริ่มต้นคือปิดใช้งาน)
+var Default Gateway = &DisabledGateway{}
+
+// gateways ช่องทางชำระเงินที่เลือกได้ผ่าน PAYMENT_GATEWAY
+var gateways = map[string]func() (Gateway, error){
+	"none":      func() (Gateway, error) { return &DisabledGateway{}, nil },
+	"simulated": func() (Gateway, error) { return &SimulatedGateway{}, nil },
+}
+
+// Register เพิ่มช่องทางชำระเงินใหม่ (ต้องเรียกก่อน Init)
+func Register(name string, factory func() (Gateway, error)) {
+	gateways[strings.ToLower(name)] = factory
+}
+
+// Init เลือกช่องทางชำระเงินตาม PAYMENT_GATEWAY (ค่าเริ่มต้นคือ none)
+func Init() {
+	name := strings.ToLower(os.Getenv("PAYMENT_GATEWAY"))
+	if name == "" {
+		name = "none"
+	}
+
+	factory, ok := gateways[name]
+	if !ok {
+		log.Printf("⚠️  Payment gateway %q is not available, automatic charges are disabled", name)
+	} else if gateway, err := factory(); err != nil {
+		log.Printf("❌ Error initializing payment gateway %s: %v", name, err)
+	} else {
+		Default = gateway
+	}
+
+	log.Printf("✅ Payment gateway: %s", Default.Name())
+}
+
+// Enabled ตรวจสอบว่ามีช่องทางชำระเงินที่ตัดเงินได้จริง
+func Enabled() bool {
+	_, disabled := Default.(*DisabledGateway)
+	return !disabled
+}
+
+// DisabledGateway ไม่ตัดเงิน (ใช้เมื่อไม่ได้ตั้งค่าช่องทางชำระเงิน)
+type DisabledGateway struct{}
+
+func (g *DisabledGateway) Name() string { return "none" }
+
+func (g *DisabledGateway) Charge(userID int, amount float64, description string) (string, error) {
+	return "", ErrNotConfigured
+}
+
+// SimulatedGateway อนุมัติทุกรายการโดยไม่ตัดเงินจริง (ใช้ระหว่างพัฒนาและทดสอบ)
+type SimulatedGateway struct{}
+
+func (g *SimulatedGateway) Name() string { return "simulated" }
+
+func (g *SimulatedGateway) Charge(userID int, amount float64, description string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("charge amount must be positive")
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	reference := "sim_" + hex.EncodeToString(b)
+	fmt.Printf("💳 [payments:simulated] user=%d amount=%.2f ref=%s %q\n", userID, amount, reference, description)
+	return reference, nil
+}
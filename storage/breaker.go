@@ -0,0 +1,65 @@
+// storage/breaker.go
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ค่าของ circuit breaker ของที่เก็บหลัก
+const (
+	breakerFailureThreshold = 3                // อัพโหลดล้มเหลวติดกันกี่ครั้งจึงหยุดใช้ที่เก็บหลัก
+	breakerCooldown         = 60 * time.Second // หยุดใช้นานเท่าไรก่อนลองอัพโหลดใหม่
+)
+
+// breaker circuit breaker ของการอัพโหลดไปยังที่เก็บหลัก (เช่น Cloudinary)
+// เมื่อล้มเหลวติดกันครบกำหนดจะ "เปิด" และส่งไฟล์ไป local ทันทีโดยไม่ต้องรอ timeout ของที่เก็บหลัก
+// เมื่อครบ cooldown จะยอมให้ลองอัพโหลดหนึ่งครั้ง (half-open) ถ้าสำเร็จก็กลับมาใช้ตามปกติ
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// uploadBreaker circuit breaker ของ Default
+var uploadBreaker = &breaker{}
+
+// allow ตรวจสอบว่าควรอัพโหลดไปยังที่เก็บหลักหรือไม่
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < breakerFailureThreshold {
+		return true
+	}
+	// half-open: ให้ลองได้ครั้งละหนึ่ง request หลังครบ cooldown
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success บันทึกว่าอัพโหลดสำเร็จ (ปิด breaker)
+func (b *breaker) success(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= breakerFailureThreshold {
+		fmt.Printf("✅ %s storage recovered, uploads resume\n", name)
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+// failure บันทึกว่าอัพโหลดล้มเหลว (เปิด breaker เมื่อครบกำหนด)
+func (b *breaker) failure(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= breakerFailureThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+		fmt.Printf("⚠️ %s storage failing, using local storage for %s\n", name, breakerCooldown)
+	}
+}
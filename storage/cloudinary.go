@@ -0,0 +1,54 @@
+// storage/cloudinary.go
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go-api-game/config"
+)
+
+// CloudinaryStorage เก็บไฟล์บน Cloudinary (ใช้ client จาก config.InitCloudinary)
+type CloudinaryStorage struct{}
+
+func (s *CloudinaryStorage) Name() string { return "cloudinary" }
+
+// Put คืน SecureURL ที่มี version (เช่น /v1712345678/) ซึ่งเปลี่ยนทุกครั้งที่อัพโหลด CDN จึงไม่ส่งภาพเก่า
+func (s *CloudinaryStorage) Put(key string, data []byte, contentType string) (string, error) {
+	return config.UploadImageFromBytes(data, key)
+}
+
+// Get ดาวน์โหลดไฟล์จาก URL สาธารณะของ Cloudinary
+func (s *CloudinaryStorage) Get(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading Cloudinary file: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloudinary download failed: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *CloudinaryStorage) Delete(url string) error {
+	if err := config.DeleteImage(url); err != nil {
+		return fmt.Errorf("error deleting Cloudinary image: %v", err)
+	}
+	return nil
+}
+
+// URL สร้าง URL แบบไม่มี version (Cloudinary จะส่งเวอร์ชันล่าสุดให้)
+func (s *CloudinaryStorage) URL(key string) string {
+	if config.Cld == nil {
+		return ""
+	}
+	return fmt.Sprintf("https://res.cloudinary.com/%s/image/upload/game-store/%s", config.Cld.Config.Cloud.CloudName, key)
+}
+
+func (s *CloudinaryStorage) Owns(url string) bool {
+	return strings.Contains(url, "cloudinary.com")
+}
@@ -0,0 +1,67 @@
+// storage/local.go
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage เก็บไฟล์ในโฟลเดอร์ของเครื่อง และให้บริการผ่าน /uploads/
+type LocalStorage struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalStorage สร้างที่เก็บไฟล์ในเครื่อง
+func NewLocalStorage(dir, urlPrefix string) *LocalStorage {
+	return &LocalStorage{dir: dir, urlPrefix: urlPrefix}
+}
+
+func (s *LocalStorage) Name() string { return "local" }
+
+func (s *LocalStorage) Put(key string, data []byte, contentType string) (string, error) {
+	// Create uploads directory if not exists
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating upload directory: %v", err)
+	}
+
+	filePath := filepath.Join(s.dir, filepath.Base(key))
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return "", fmt.Errorf("error saving file locally: %v", err)
+	}
+
+	url := s.URL(key)
+	fmt.Printf("✅ File saved locally: %s\n", url)
+	return url, nil
+}
+
+func (s *LocalStorage) Get(url string) ([]byte, error) {
+	filePath := filepath.Join(s.dir, filepath.Base(strings.TrimPrefix(url, s.urlPrefix)))
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading local file: %v", err)
+	}
+	return data, nil
+}
+
+func (s *LocalStorage) Delete(url string) error {
+	filePath := filepath.Join(s.dir, filepath.Base(strings.TrimPrefix(url, s.urlPrefix)))
+	if _, err := os.Stat(filePath); err != nil {
+		return nil
+	}
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("error deleting local file: %v", err)
+	}
+	fmt.Printf("🗑️ Deleted local file: %s\n", filePath)
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.urlPrefix + filepath.Base(key)
+}
+
+func (s *LocalStorage) Owns(url string) bool {
+	return strings.HasPrefix(url, s.urlPrefix)
+}
@@ -0,0 +1,314 @@
+// storage/s3.go
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage เก็บไฟล์บน S3 หรือบริการที่รองรับ S3 API (เช่น MinIO)
+// เซ็น request ด้วย AWS Signature Version 4 โดยไม่ต้องพึ่ง SDK
+type S3Storage struct {
+	endpoint  *url.URL // เช่น https://s3.ap-southeast-1.amazonaws.com หรือ http://localhost:9000
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	prefix    string // โฟลเดอร์ภายใน bucket
+	publicURL string // URL สาธารณะสำหรับเข้าถึงไฟล์ (เช่น CDN) ถ้าไม่กำหนดจะใช้ URL ของ bucket
+	pathStyle bool   // MinIO ใช้ path-style (endpoint/bucket/key)
+	acl       string // เช่น public-read (ถ้า bucket ไม่ได้ตั้ง policy ให้อ่านได้)
+	client    *http.Client
+}
+
+// NewS3StorageFromEnv สร้าง S3Storage จาก environment variables
+// S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY (จำเป็น)
+// S3_ENDPOINT, S3_REGION, S3_PREFIX, S3_PUBLIC_URL, S3_FORCE_PATH_STYLE, S3_ACL (ไม่บังคับ)
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET not set")
+	}
+
+	accessKey := os.Getenv("S3_ACCESS_KEY")
+	secretKey := os.Getenv("S3_SECRET_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("S3_ACCESS_KEY and S3_SECRET_KEY are required")
+	}
+
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// ถ้าตั้ง endpoint เอง (MinIO) จะใช้ path-style เป็นค่าเริ่มต้น
+	rawEndpoint := os.Getenv("S3_ENDPOINT")
+	pathStyle := rawEndpoint != ""
+	if rawEndpoint == "" {
+		rawEndpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	if v := os.Getenv("S3_FORCE_PATH_STYLE"); v != "" {
+		pathStyle = v == "true" || v == "1"
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(rawEndpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %s", rawEndpoint)
+	}
+
+	prefix := os.Getenv("S3_PREFIX")
+	if prefix == "" {
+		prefix = "game-store"
+	}
+
+	s := &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		prefix:    strings.Trim(prefix, "/"),
+		pathStyle: pathStyle,
+		acl:       os.Getenv("S3_ACL"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	s.publicURL = strings.TrimSuffix(os.Getenv("S3_PUBLIC_URL"), "/")
+	if s.publicURL == "" {
+		s.publicURL = s.bucketURL()
+	}
+
+	return s, nil
+}
+
+func (s *S3Storage) Name() string { return "s3" }
+
+func (s *S3Storage) Put(key string, data []byte, contentType string) (string, error) {
+	objectKey := s.objectKey(key)
+
+	headers := map[string]string{"content-type": contentType}
+	// ภาพตั้งชื่อไฟล์ตามเนื้อหา (key ไม่ถูกเขียนทับด้วยเนื้อหาอื่น) ให้ CDN และ browser cache ได้ถาวร
+	if strings.HasPrefix(contentType, "image/") {
+		headers["cache-control"] = ImageCacheControl
+	}
+	if s.acl != "" {
+		headers["x-amz-acl"] = s.acl
+	}
+
+	resp, err := s.do("PUT", objectKey, data, headers)
+	if err != nil {
+		return "", fmt.Errorf("s3 upload error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("s3 upload failed: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	imageURL := s.URL(key)
+	fmt.Printf("✅ File uploaded to S3: %s\n", imageURL)
+	return imageURL, nil
+}
+
+func (s *S3Storage) Get(fileURL string) ([]byte, error) {
+	objectKey := strings.TrimPrefix(fileURL, s.publicURL+"/")
+
+	resp, err := s.do("GET", objectKey, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3 get error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3 get failed: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Storage) Delete(imageURL string) error {
+	objectKey := strings.TrimPrefix(imageURL, s.publicURL+"/")
+
+	resp, err := s.do("DELETE", objectKey, nil, nil)
+	if err != nil {
+		return fmt.Errorf("s3 delete error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// S3 คืนค่า 204 แม้ไม่มีไฟล์อยู่แล้ว
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 delete failed: %s", resp.Status)
+	}
+
+	fmt.Printf("🗑️ Deleted S3 object: %s\n", objectKey)
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + "/" + s.objectKey(key)
+}
+
+func (s *S3Storage) Owns(imageURL string) bool {
+	return strings.HasPrefix(imageURL, s.publicURL+"/")
+}
+
+// objectKey เติมโฟลเดอร์ให้กับ key
+func (s *S3Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// bucketURL คืนค่า URL ของ bucket ตามรูปแบบ path-style หรือ virtual-hosted
+func (s *S3Storage) bucketURL() string {
+	if s.pathStyle {
+		return fmt.Sprintf("%s://%s/%s", s.endpoint.Scheme, s.endpoint.Host, s.bucket)
+	}
+	return fmt.Sprintf("%s://%s.%s", s.endpoint.Scheme, s.bucket, s.endpoint.Host)
+}
+
+// do ส่ง request ไปยัง S3 พร้อมลายเซ็น SigV4
+func (s *S3Storage) do(method, objectKey string, body []byte, headers map[string]string) (*http.Response, error) {
+	host := s.endpoint.Host
+	path := "/" + escapeS3Path(objectKey)
+	if s.pathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		host = s.bucket + "." + host
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", s.endpoint.Scheme, host, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	signV4(req, sha256Hex(body), s.accessKey, s.secretKey, s.region, time.Now())
+
+	return s.client.Do(req)
+}
+
+// signV4 เซ็น request ด้วย AWS Signature Version 4 ของบริการ s3 (ตั้ง x-amz-date, x-amz-content-sha256 และ Authorization)
+// header ทุกตัวที่ตั้งไว้ใน req ก่อนเรียกจะถูกรวมในลายเซ็น
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	signed := map[string]string{"host": host}
+	for k, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		signed[strings.ToLower(k)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + signed[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := dateStamp + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(secretKey, dateStamp, region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// signingKey สร้างกุญแจสำหรับเซ็นจาก secret key ตามวัน, region และบริการ
+func signingKey(secretKey, dateStamp, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), dateStamp)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// canonicalQuery เรียง query string ตามชื่อแล้วเข้ารหัสตาม SigV4 (พารามิเตอร์ที่ไม่มีค่าเป็น "name=")
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapeS3Path เข้ารหัส key ตามที่ SigV4 กำหนด (ทุกส่วนยกเว้น /)
+func escapeS3Path(key string) string {
+	return uriEncode(key, false)
+}
+
+// uriEncode เข้ารหัสทุกไบต์ยกเว้น A-Z a-z 0-9 - _ . ~ ตาม UriEncode ของ SigV4
+// (url.PathEscape ไม่เข้ารหัสอักขระอย่าง $ + = @ : ทำให้ลายเซ็นไม่ตรงกับที่ S3 คำนวณ)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,160 @@
+// storage/storage.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-api-game/config"
+	"go-api-game/tracing"
+)
+
+// Storage คือที่เก็บไฟล์ภาพ (Cloudinary, S3/MinIO หรือเครื่อง local)
+type Storage interface {
+	// Name ชื่อของที่เก็บไฟล์ (cloudinary, s3, local)
+	Name() string
+	// Put อัพโหลดไฟล์ด้วย key ที่กำหนดและคืนค่า URL สาธารณะของไฟล์
+	Put(key string, data []byte, contentType string) (string, error)
+	// Get อ่านข้อมูลของไฟล์จาก URL ที่เคยได้จาก Put
+	Get(url string) ([]byte, error)
+	// Delete ลบไฟล์จาก URL ที่เคยได้จาก Put
+	Delete(url string) error
+	// URL คืนค่า URL สาธารณะของ key
+	URL(key string) string
+	// Owns ตรวจสอบว่า URL นี้เป็นไฟล์ของที่เก็บนี้หรือไม่
+	Owns(url string) bool
+}
+
+// ImageCacheControl Cache-Control ของไฟล์ภาพที่อัพโหลด (ชื่อไฟล์ไม่ซ้ำ จึง cache ได้ 1 ปีโดยไม่ต้องตรวจซ้ำ)
+const ImageCacheControl = "public, max-age=31536000, immutable"
+
+var (
+	// Default ที่เก็บไฟล์หลักที่เลือกจาก STORAGE_BACKEND
+	Default Storage
+	// Local ที่เก็บไฟล์ในเครื่อง ใช้เป็น fallback เมื่อที่เก็บหลักล้มเหลว
+	Local Storage = NewLocalStorage("uploads", "/uploads/")
+
+	// backends ที่เก็บไฟล์ทั้งหมดที่ตั้งค่าไว้ (ใช้หาเจ้าของ URL ตอนลบไฟล์เก่า)
+	backends []Storage
+)
+
+// Init เลือกที่เก็บไฟล์ตาม STORAGE_BACKEND (cloudinary, s3, local)
+// ถ้าไม่ได้ตั้งค่า จะใช้ Cloudinary เมื่อพร้อมใช้งาน มิฉะนั้นใช้ local
+// ต้องเรียกหลังจาก config.InitCloudinary()
+func Init() {
+	backends = []Storage{Local}
+
+	if config.IsCloudinaryAvailable() {
+		backends = append(backends, &CloudinaryStorage{})
+	}
+
+	if os.Getenv("S3_BUCKET") != "" {
+		s3Storage, err := NewS3StorageFromEnv()
+		if err != nil {
+			log.Printf("❌ Error initializing S3 storage: %v", err)
+		} else {
+			backends = append(backends, s3Storage)
+		}
+	}
+
+	backend := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	if backend == "" {
+		backend = "local"
+		if config.IsCloudinaryAvailable() {
+			backend = "cloudinary"
+		}
+	}
+
+	for _, s := range backends {
+		if s.Name() == backend {
+			Default = s
+		}
+	}
+	if Default == nil {
+		log.Printf("⚠️  Storage backend %q is not available, using local storage", backend)
+		Default = Local
+	}
+
+	log.Printf("✅ Storage backend: %s", Default.Name())
+}
+
+// Save อัพโหลดไฟล์ไปยังที่เก็บหลัก และ fallback ไปเก็บในเครื่องถ้าล้มเหลว
+// ถ้าที่เก็บหลักล้มเหลวติดกันหลายครั้ง จะเก็บในเครื่องทันทีโดยไม่ลองที่เก็บหลัก (ดู breaker)
+func Save(key string, data []byte) (string, error) {
+	return SaveContext(context.Background(), key, data)
+}
+
+// SaveContext เหมือน Save และบันทึก span ของการอัพโหลดเป็นลูกของ span ใน ctx (เช่น span ของ request)
+func SaveContext(ctx context.Context, key string, data []byte) (string, error) {
+	_, span := tracing.StartChild(ctx, "storage.upload", tracing.KindClient)
+	defer span.End()
+	span.SetAttribute("storage.key", key)
+	span.SetAttribute("storage.bytes", len(data))
+
+	url, backend, err := save(key, data)
+	span.SetAttribute("storage.backend", backend)
+	span.SetAttribute("storage.fallback", backend != Default.Name())
+	span.SetError(err)
+	return url, err
+}
+
+// save อัพโหลดไฟล์และคืนชื่อที่เก็บที่ใช้จริง
+func save(key string, data []byte) (string, string, error) {
+	if Default == nil {
+		Default = Local
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if Default == Local {
+		url, err := Local.Put(key, data, contentType)
+		return url, Local.Name(), err
+	}
+
+	// ที่เก็บหลักล้มเหลวติดกันหลายครั้ง: เก็บในเครื่องจนกว่าจะครบ cooldown
+	if !uploadBreaker.allow() {
+		url, err := Local.Put(key, data, contentType)
+		return url, Local.Name(), err
+	}
+
+	url, err := Default.Put(key, data, contentType)
+	if err != nil {
+		uploadBreaker.failure(Default.Name())
+		fmt.Printf("❌ %s upload failed, using local storage: %v\n", Default.Name(), err)
+		url, err := Local.Put(key, data, contentType)
+		return url, Local.Name(), err
+	}
+	uploadBreaker.success(Default.Name())
+	return url, Default.Name(), nil
+}
+
+// Remove ลบไฟล์จากที่เก็บที่เป็นเจ้าของ URL (รองรับไฟล์เก่าที่อยู่คนละที่เก็บกับปัจจุบัน)
+func Remove(url string) error {
+	if url == "" {
+		return nil
+	}
+	return ForURL(url).Delete(url)
+}
+
+// Load อ่านไฟล์จากที่เก็บที่เป็นเจ้าของ URL
+func Load(url string) ([]byte, error) {
+	return ForURL(url).Get(url)
+}
+
+// ForURL คืนค่าที่เก็บไฟล์ที่เป็นเจ้าของ URL (ถ้าไม่พบจะถือว่าเป็นไฟล์ local)
+func ForURL(url string) Storage {
+	for _, s := range backends {
+		if s != Local && s.Owns(url) {
+			return s
+		}
+	}
+	return Local
+}
@@ -0,0 +1,181 @@
+// tracing/exporter.go
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ค่าของการส่ง span แบบเป็นชุด
+const (
+	queueSize     = 4096
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// finishedSpan span ที่ปิดแล้วพร้อมเวลาสิ้นสุด
+type finishedSpan struct {
+	span *Span
+	end  time.Time
+}
+
+var (
+	queue   chan finishedSpan
+	dropped atomic.Int64 // span ที่ทิ้งเพราะคิวเต็ม (collector ช้าหรือล่ม)
+)
+
+// export ส่ง span เข้าคิว (ไม่รอ ถ้าคิวเต็มจะทิ้ง span เพื่อไม่ให้ request ช้าลง)
+func export(s *Span, end time.Time) {
+	select {
+	case queue <- finishedSpan{s, end}:
+	default:
+		dropped.Add(1)
+	}
+}
+
+// startExporter เริ่ม goroutine ที่ส่ง span เป็นชุดไปยัง collector ด้วย OTLP/HTTP (JSON)
+func startExporter(endpoint, serviceName string, headers http.Header) {
+	queue = make(chan finishedSpan, queueSize)
+	client := &http.Client{Timeout: exportTimeout}
+	resource := map[string]interface{}{
+		"attributes": encodeAttributes(map[string]interface{}{"service.name": serviceName}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		batch := make([]finishedSpan, 0, batchSize)
+		flush := func() {
+			if n := dropped.Swap(0); n > 0 {
+				log.Printf("⚠️ Tracing: dropped %d spans (export queue full)", n)
+			}
+			if len(batch) == 0 {
+				return
+			}
+			if err := sendBatch(client, endpoint, headers, resource, batch); err != nil {
+				log.Printf("⚠️ Tracing: error exporting %d spans: %v", len(batch), err)
+			}
+			batch = batch[:0]
+		}
+		for {
+			select {
+			case s := <-queue:
+				batch = append(batch, s)
+				if len(batch) >= batchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+}
+
+// sendBatch ส่ง span หนึ่งชุดตามรูปแบบ ExportTraceServiceRequest ของ OTLP (JSON)
+func sendBatch(client *http.Client, endpoint string, headers http.Header, resource map[string]interface{}, batch []finishedSpan) error {
+	spans := make([]map[string]interface{}, 0, len(batch))
+	for _, f := range batch {
+		spans = append(spans, encodeSpan(f.span, f.end))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": resource,
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]string{"name": "go-api-game/tracing"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// encodeSpan แปลง span เป็น JSON ของ OTLP (ID เป็น hex, เวลาเป็น nanosecond ในรูป string)
+func encodeSpan(s *Span, end time.Time) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded := map[string]interface{}{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        encodeAttributes(s.attrs),
+	}
+	if s.parentID != ([8]byte{}) {
+		encoded["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.failed {
+		// STATUS_CODE_ERROR = 2
+		encoded["status"] = map[string]interface{}{"code": 2, "message": s.message}
+	}
+	return encoded
+}
+
+// encodeAttributes แปลง attribute เป็น KeyValue ของ OTLP
+func encodeAttributes(attrs map[string]interface{}) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		var v map[string]interface{}
+		switch value := value.(type) {
+		case string:
+			v = map[string]interface{}{"stringValue": value}
+		case bool:
+			v = map[string]interface{}{"boolValue": value}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": encodeDouble(value)}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": key, "value": v})
+	}
+	return encoded
+}
+
+// encodeDouble ค่า double ตาม proto3 JSON mapping (NaN/Infinity เป็น string เพราะ JSON ไม่มีค่าเหล่านี้ และ json.Marshal จะล้มเหลวทั้งชุด)
+func encodeDouble(v float64) interface{} {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+	return v
+}
@@ -0,0 +1,220 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ตรวจ body ที่ส่งไปยัง collector กับนิยาม protobuf ทางการของ OTLP (go.opentelemetry.io/proto/otlp)
+// OTLP/JSON คือ proto3 JSON mapping ต่างกันเพียง traceId/spanId ที่เป็น hex แทน base64
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+// collect ส่ง batch ไปยัง collector จำลองแล้วคืน request ที่ได้รับ
+func collect(t *testing.T, headers http.Header, batch []finishedSpan) (*http.Request, []byte) {
+	t.Helper()
+	var got *http.Request
+	var body []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	resource := map[string]interface{}{
+		"attributes": encodeAttributes(map[string]interface{}{"service.name": "game-store-test"}),
+	}
+	if err := sendBatch(collector.Client(), collector.URL+"/v1/traces", headers, resource, batch); err != nil {
+		t.Fatalf("sendBatch: %v", err)
+	}
+	return got, body
+}
+
+// parseOTLP แปลง OTLP/JSON เป็น message ทางการ (ไม่ยอมรับ field ที่ไม่มีใน .proto)
+func parseOTLP(t *testing.T, body []byte) *tracepb.TracesData {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	hexIDsToBase64(t, doc)
+	normalized, _ := json.Marshal(doc)
+
+	// TracesData มีรูปแบบเดียวกับ ExportTraceServiceRequest (field resourceSpans = 1)
+	var data tracepb.TracesData
+	if err := protojson.Unmarshal(normalized, &data); err != nil {
+		t.Fatalf("body does not match the OTLP schema: %v\n%s", err, body)
+	}
+	return &data
+}
+
+// hexIDsToBase64 แปลง traceId/spanId/parentSpanId จาก hex เป็น base64 ตามที่ protojson ใช้กับ bytes
+func hexIDsToBase64(t *testing.T, node interface{}) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for key, value := range node {
+			if key == "traceId" || key == "spanId" || key == "parentSpanId" {
+				raw, err := hex.DecodeString(value.(string))
+				if err != nil {
+					t.Fatalf("%s %q is not hex: %v", key, value, err)
+				}
+				node[key] = base64.StdEncoding.EncodeToString(raw)
+				continue
+			}
+			hexIDsToBase64(t, value)
+		}
+	case []interface{}:
+		for _, value := range node {
+			hexIDsToBase64(t, value)
+		}
+	}
+}
+
+func attributeMap(attrs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	m := map[string]*commonpb.AnyValue{}
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestExportMatchesOTLPSchema(t *testing.T) {
+	start := time.Unix(1700000000, 123456789)
+	end := start.Add(1500 * time.Millisecond)
+
+	root := &Span{name: "GET /games", kind: KindServer, start: start}
+	copy(root.traceID[:], bytes.Repeat([]byte{0xab}, 16))
+	copy(root.spanID[:], []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	root.SetAttribute("http.method", "GET")
+	root.SetAttribute("http.status_code", 200)
+	root.SetAttribute("db.rows", int64(math.MaxInt64))
+	root.SetAttribute("cache.hit", true)
+	root.SetAttribute("ratio", 0.25)
+	root.SetAttribute("nan", math.NaN())
+	root.SetAttribute("inf", math.Inf(-1))
+	root.SetAttribute("other", time.Duration(3))
+
+	child := &Span{name: "SELECT games", kind: KindClient, start: start, traceID: root.traceID, parentID: root.spanID}
+	copy(child.spanID[:], []byte{9, 9, 9, 9, 9, 9, 9, 9})
+	child.SetError(errors.New("เชื่อมต่อฐานข้อมูลไม่ได้"))
+
+	headers := http.Header{"Api-Key": {"secret"}}
+	req, body := collect(t, headers, []finishedSpan{{root, end}, {child, end}})
+	if req.Method != "POST" || req.URL.Path != "/v1/traces" {
+		t.Errorf("request = %s %s", req.Method, req.URL.Path)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content-type = %q, want application/json", ct)
+	}
+	if req.Header.Get("Api-Key") != "secret" {
+		t.Error("configured headers were not sent")
+	}
+
+	data := parseOTLP(t, body)
+	if len(data.ResourceSpans) != 1 || len(data.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("resourceSpans = %v", data.ResourceSpans)
+	}
+	rs := data.ResourceSpans[0]
+	if got := attributeMap(rs.Resource.Attributes)["service.name"].GetStringValue(); got != "game-store-test" {
+		t.Errorf("service.name = %q", got)
+	}
+	scope := rs.ScopeSpans[0]
+	if scope.Scope.GetName() != "go-api-game/tracing" {
+		t.Errorf("scope = %q", scope.Scope.GetName())
+	}
+	if len(scope.Spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(scope.Spans))
+	}
+
+	server := scope.Spans[0]
+	if !bytes.Equal(server.TraceId, root.traceID[:]) || !bytes.Equal(server.SpanId, root.spanID[:]) || len(server.ParentSpanId) != 0 {
+		t.Errorf("ids = %x %x %x", server.TraceId, server.SpanId, server.ParentSpanId)
+	}
+	if server.Name != "GET /games" || server.Kind != tracepb.Span_SPAN_KIND_SERVER {
+		t.Errorf("name/kind = %q %v", server.Name, server.Kind)
+	}
+	if server.StartTimeUnixNano != uint64(start.UnixNano()) || server.EndTimeUnixNano != uint64(end.UnixNano()) {
+		t.Errorf("times = %d-%d", server.StartTimeUnixNano, server.EndTimeUnixNano)
+	}
+	if server.Status != nil && server.Status.Code != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("status = %v, want unset", server.Status)
+	}
+
+	attrs := attributeMap(server.Attributes)
+	if len(attrs) != 8 {
+		t.Errorf("got %d attributes, want 8", len(attrs))
+	}
+	if attrs["http.method"].GetStringValue() != "GET" {
+		t.Errorf("http.method = %v", attrs["http.method"])
+	}
+	if v, ok := attrs["http.status_code"].GetValue().(*commonpb.AnyValue_IntValue); !ok || v.IntValue != 200 {
+		t.Errorf("http.status_code = %v", attrs["http.status_code"])
+	}
+	if attrs["db.rows"].GetIntValue() != math.MaxInt64 {
+		t.Errorf("db.rows = %v", attrs["db.rows"])
+	}
+	if v, ok := attrs["cache.hit"].GetValue().(*commonpb.AnyValue_BoolValue); !ok || !v.BoolValue {
+		t.Errorf("cache.hit = %v", attrs["cache.hit"])
+	}
+	if attrs["ratio"].GetDoubleValue() != 0.25 {
+		t.Errorf("ratio = %v", attrs["ratio"])
+	}
+	if !math.IsNaN(attrs["nan"].GetDoubleValue()) {
+		t.Errorf("nan = %v", attrs["nan"])
+	}
+	if !math.IsInf(attrs["inf"].GetDoubleValue(), -1) {
+		t.Errorf("inf = %v", attrs["inf"])
+	}
+	if attrs["other"].GetStringValue() != "3ns" {
+		t.Errorf("other = %v", attrs["other"])
+	}
+
+	client := scope.Spans[1]
+	if !bytes.Equal(client.ParentSpanId, root.spanID[:]) || !bytes.Equal(client.TraceId, root.traceID[:]) {
+		t.Errorf("child ids = %x parent %x", client.TraceId, client.ParentSpanId)
+	}
+	if client.Kind != tracepb.Span_SPAN_KIND_CLIENT {
+		t.Errorf("child kind = %v", client.Kind)
+	}
+	if client.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || client.Status.GetMessage() != "เชื่อมต่อฐานข้อมูลไม่ได้" {
+		t.Errorf("child status = %v", client.Status)
+	}
+}
+
+func TestSpanKindsMatchOTLP(t *testing.T) {
+	kinds := map[int]tracepb.Span_SpanKind{
+		KindInternal: tracepb.Span_SPAN_KIND_INTERNAL,
+		KindServer:   tracepb.Span_SPAN_KIND_SERVER,
+		KindClient:   tracepb.Span_SPAN_KIND_CLIENT,
+	}
+	for kind, want := range kinds {
+		if int32(want) != int32(kind) {
+			t.Errorf("kind %d, OTLP %s = %d", kind, want, want)
+		}
+	}
+}
+
+func TestSendBatchReportsCollectorErrors(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer collector.Close()
+
+	span := &Span{name: "x", kind: KindInternal, start: time.Now()}
+	err := sendBatch(collector.Client(), collector.URL, nil, map[string]interface{}{}, []finishedSpan{{span, time.Now()}})
+	if err == nil {
+		t.Fatal("sendBatch succeeded on a 400 response")
+	}
+}
@@ -0,0 +1,255 @@
+// tracing/sql.go
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStatementLength ความยาวสูงสุดของ SQL ที่เก็บใน attribute db.statement
+const maxStatementLength = 2000
+
+// dbSystems ชื่อ db.system ตามชื่อ driver
+var dbSystems = map[string]string{"mysql": "mysql"}
+
+var registered sync.Map // ชื่อ driver เดิม → ชื่อ driver ที่มี tracing
+
+// Driver คืนชื่อ driver ที่สร้าง span ให้ทุกคำสั่ง SQL (ลงทะเบียนครั้งแรกที่เรียก)
+// ใช้เสมอแม้ปิด tracing เพื่อเก็บระยะเวลาของคำสั่งล่าสุดไว้แสดงใน /admin/diagnostics
+func Driver(name string) string {
+	if traced, ok := registered.Load(name); ok {
+		return traced.(string)
+	}
+
+	// sql.Open ไม่เชื่อมต่อฐานข้อมูล ใช้แค่ดึง driver ที่ลงทะเบียนไว้
+	db, err := sql.Open(name, "")
+	if err != nil {
+		return name
+	}
+	base := db.Driver()
+	db.Close()
+
+	system := dbSystems[name]
+	if system == "" {
+		system = name
+	}
+	traced := "traced-" + name
+	sql.Register(traced, &tracedDriver{base: base, system: system})
+	registered.Store(name, traced)
+	return traced
+}
+
+type tracedDriver struct {
+	base   driver.Driver
+	system string
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{base: conn, system: d.system}, nil
+}
+
+// tracedConn connection ที่สร้าง span ของคำสั่ง SQL
+// คำสั่งที่ไม่ได้ส่ง context มา (db.Query, tx.Exec) จะใช้ span ของ context ที่เริ่ม transaction (db.BeginTx)
+// ถ้าไม่มี span ให้อ้างอิงเลยจะไม่สร้าง span (ไม่ให้เกิด trace ที่ไม่มี request ต้นทาง)
+type tracedConn struct {
+	base   driver.Conn
+	system string
+	txCtx  context.Context // context ของ transaction ที่เปิดอยู่ (connection ถูกใช้โดย transaction เดียวจนกว่าจะ commit/rollback)
+}
+
+// record บันทึกระยะเวลาและ span ของคำสั่งที่ทำเสร็จแล้ว
+func (c *tracedConn) record(ctx context.Context, statement string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		// driver จะลองใหม่ด้วย prepared statement ซึ่งบันทึก span เอง
+		return
+	}
+	failed := err != nil && err != driver.ErrBadConn
+	recordStatement(statement, time.Since(start), failed)
+
+	if FromContext(ctx) == nil && c.txCtx != nil {
+		ctx = c.txCtx
+	}
+	if FromContext(ctx) == nil {
+		return
+	}
+
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(statement), " ", 2)[0])
+	_, span := StartAt(ctx, operation, KindClient, start)
+	span.SetAttribute("db.system", c.system)
+	span.SetAttribute("db.operation", operation)
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+	span.SetAttribute("db.statement", strings.Join(strings.Fields(statement), " "))
+	if failed {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.base.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.base.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *tracedConn) Close() error {
+	return c.base.Close()
+}
+
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if b, ok := c.base.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.base.Begin() // driver ที่ไม่รองรับ BeginTx
+	}
+	c.record(ctx, "BEGIN", start, err)
+	if err != nil {
+		return nil, err
+	}
+	if FromContext(ctx) != nil {
+		c.txCtx = ctx
+	}
+	return &tracedTx{base: tx, conn: c}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.base.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.record(ctx, query, start, err)
+	return result, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.base.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.record(ctx, query, start, err)
+	return rows, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	c.txCtx = nil
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.base.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue ใช้การแปลงค่าของ driver เดิม (เช่น MySQL รองรับ uint64 และ json.RawMessage)
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tracedTx struct {
+	base driver.Tx
+	conn *tracedConn
+}
+
+func (t *tracedTx) Commit() error {
+	start := time.Now()
+	err := t.base.Commit()
+	t.conn.record(context.Background(), "COMMIT", start, err)
+	t.conn.txCtx = nil
+	return err
+}
+
+func (t *tracedTx) Rollback() error {
+	start := time.Now()
+	err := t.base.Rollback()
+	t.conn.record(context.Background(), "ROLLBACK", start, err)
+	t.conn.txCtx = nil
+	return err
+}
+
+// tracedStmt prepared statement ที่สร้าง span ทุกครั้งที่ทำงาน
+type tracedStmt struct {
+	driver.Stmt
+	conn  *tracedConn
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedToValues(args)) // driver ที่ไม่รองรับ context
+	}
+	s.conn.record(ctx, s.query, start, err)
+	return result, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedToValues(args)) // driver ที่ไม่รองรับ context
+	}
+	s.conn.record(ctx, s.query, start, err)
+	return rows, err
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,128 @@
+// tracing/stats.go
+package tracing
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentSampleLimit จำนวนตัวอย่างล่าสุดที่เก็บในหน่วยความจำต่อประเภท (request และคำสั่ง SQL)
+const recentSampleLimit = 2000
+
+// maxSampleNameLength ความยาวสูงสุดของชื่อตัวอย่าง (คำสั่ง SQL ยาวถูกตัด)
+const maxSampleNameLength = 500
+
+// Sample ระยะเวลาของการทำงานหนึ่งครั้ง
+type Sample struct {
+	Name     string
+	Duration time.Duration
+	Failed   bool
+	At       time.Time
+}
+
+// Timing สรุประยะเวลาของตัวอย่างที่มีชื่อเดียวกัน
+type Timing struct {
+	Name       string    `json:"name"`
+	Count      int       `json:"count"`
+	Errors     int       `json:"errors"`
+	AvgMs      float64   `json:"avg_ms"`
+	P95Ms      float64   `json:"p95_ms"`
+	MaxMs      float64   `json:"max_ms"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// sampleRing เก็บตัวอย่างล่าสุดแบบวนทับ (ทำงานเสมอ ไม่ขึ้นกับการเปิด tracing)
+type sampleRing struct {
+	mu      sync.Mutex
+	samples []Sample
+	next    int
+}
+
+var (
+	recentRequests   = &sampleRing{}
+	recentStatements = &sampleRing{}
+)
+
+func (r *sampleRing) add(sample Sample) {
+	if len(sample.Name) > maxSampleNameLength {
+		sample.Name = sample.Name[:maxSampleNameLength]
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.samples) < recentSampleLimit {
+		r.samples = append(r.samples, sample)
+		return
+	}
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % recentSampleLimit
+}
+
+// slowest สรุปตัวอย่างตามชื่อ เรียงตามค่า p95 มากสุดก่อน
+func (r *sampleRing) slowest(limit int) ([]Timing, int) {
+	r.mu.Lock()
+	samples := make([]Sample, len(r.samples))
+	copy(samples, r.samples)
+	r.mu.Unlock()
+
+	groups := map[string][]Sample{}
+	for _, s := range samples {
+		groups[s.Name] = append(groups[s.Name], s)
+	}
+
+	timings := make([]Timing, 0, len(groups))
+	for name, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i].Duration < group[j].Duration })
+		t := Timing{Name: name, Count: len(group)}
+		var total time.Duration
+		for _, s := range group {
+			total += s.Duration
+			if s.Failed {
+				t.Errors++
+			}
+			if s.At.After(t.LastSeenAt) {
+				t.LastSeenAt = s.At
+			}
+		}
+		t.AvgMs = milliseconds(total / time.Duration(len(group)))
+		t.P95Ms = milliseconds(group[(len(group)*95+99)/100-1].Duration)
+		t.MaxMs = milliseconds(group[len(group)-1].Duration)
+		timings = append(timings, t)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].P95Ms != timings[j].P95Ms {
+			return timings[i].P95Ms > timings[j].P95Ms
+		}
+		return timings[i].Name < timings[j].Name
+	})
+	if len(timings) > limit {
+		timings = timings[:limit]
+	}
+	return timings, len(samples)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// RecordRequest เก็บระยะเวลาของ request (name เช่น "GET /games/{id}")
+func RecordRequest(name string, duration time.Duration, failed bool) {
+	recentRequests.add(Sample{Name: name, Duration: duration, Failed: failed, At: time.Now()})
+}
+
+// recordStatement เก็บระยะเวลาของคำสั่ง SQL (รวมช่องว่างให้คำสั่งเดียวกันอยู่กลุ่มเดียวกัน)
+func recordStatement(statement string, duration time.Duration, failed bool) {
+	name := strings.Join(strings.Fields(statement), " ")
+	recentStatements.add(Sample{Name: name, Duration: duration, Failed: failed, At: time.Now()})
+}
+
+// SlowestRequests endpoint ที่ช้าที่สุดจาก request ล่าสุด พร้อมจำนวนตัวอย่างที่ใช้คำนวณ
+func SlowestRequests(limit int) ([]Timing, int) {
+	return recentRequests.slowest(limit)
+}
+
+// SlowestStatements คำสั่ง SQL ที่ช้าที่สุดจากคำสั่งล่าสุด พร้อมจำนวนตัวอย่างที่ใช้คำนวณ
+func SlowestStatements(limit int) ([]Timing, int) {
+	return recentStatements.slowest(limit)
+}
@@ -0,0 +1,231 @@
+// tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ชนิดของ span ตาม OpenTelemetry (SpanKind)
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Span ช่วงเวลาการทำงานหนึ่งช่วงใน trace (ค่า nil ใช้ได้เสมอและไม่ทำอะไร เช่น เมื่อปิด tracing หรือไม่ถูกสุ่มเก็บ)
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+
+	mu      sync.Mutex
+	attrs   map[string]interface{}
+	failed  bool
+	message string
+	ended   bool
+}
+
+// remoteParent span ของ service ต้นทางที่ส่งมาใน header traceparent
+type remoteParent struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+}
+
+type contextKey int
+
+const (
+	spanContextKey contextKey = iota
+	remoteContextKey
+)
+
+var (
+	enabled     bool
+	sampleRatio = 1.0
+)
+
+// Init เปิด tracing เมื่อตั้งค่า endpoint ของ OTLP collector ไว้ (ไม่ตั้ง = ปิด และ span ทั้งหมดเป็น nil)
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT - URL เต็มของ endpoint (เช่น http://localhost:4318/v1/traces)
+// OTEL_EXPORTER_OTLP_ENDPOINT        - URL ของ collector (เติม /v1/traces ให้)
+// OTEL_EXPORTER_OTLP_HEADERS         - header เพิ่มเติม เช่น api-key=xxx,tenant=shop
+// OTEL_SERVICE_NAME                  - ชื่อ service (ค่าเริ่มต้น go-api-game)
+// OTEL_TRACES_SAMPLER_ARG            - สัดส่วนของ trace ใหม่ที่เก็บ 0-1 (ค่าเริ่มต้น 1)
+func Init() {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := strings.TrimSuffix(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/"); base != "" {
+			endpoint = base + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "go-api-game"
+	}
+	if ratio, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64); err == nil {
+		sampleRatio = math.Max(0, math.Min(1, ratio))
+	}
+
+	startExporter(endpoint, serviceName, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	enabled = true
+	log.Printf("✅ Tracing: exporting spans to %s (service %s, sample ratio %.2f)", endpoint, serviceName, sampleRatio)
+}
+
+// Enabled ตรวจสอบว่าเปิด tracing อยู่หรือไม่
+func Enabled() bool {
+	return enabled
+}
+
+// parseHeaders แปลง key=value,key2=value2 เป็น header
+func parseHeaders(raw string) http.Header {
+	header := http.Header{}
+	for _, pair := range strings.Split(raw, ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+			header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+	return header
+}
+
+// FromContext span ปัจจุบันใน context (nil ถ้าไม่มี)
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+	return span
+}
+
+// Start เริ่ม span ลูกของ span ใน context (หรือของ traceparent ที่รับมา หรือเริ่ม trace ใหม่ตามสัดส่วนที่สุ่มเก็บ)
+// ต้องเรียก End เมื่อทำงานเสร็จ
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	return StartAt(ctx, name, kind, time.Now())
+}
+
+// StartChild เหมือน Start แต่ไม่เริ่ม trace ใหม่ (คืน span nil ถ้า ctx ไม่มี span อยู่แล้ว เช่น งานเบื้องหลัง)
+func StartChild(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if FromContext(ctx) == nil {
+		return ctx, nil
+	}
+	return Start(ctx, name, kind)
+}
+
+// StartAt เหมือน Start แต่กำหนดเวลาเริ่มเอง (ใช้บันทึก span หลังจากทำงานเสร็จแล้ว)
+func StartAt(ctx context.Context, name string, kind int, start time.Time) (context.Context, *Span) {
+	if !enabled {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: start}
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else if remote, ok := ctx.Value(remoteContextKey).(remoteParent); ok {
+		if !remote.sampled {
+			return ctx, nil
+		}
+		span.traceID, span.parentID = remote.traceID, remote.spanID
+	} else {
+		if !sampled() {
+			return ctx, nil
+		}
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanContextKey, span), span
+}
+
+// sampled สุ่มว่าจะเก็บ trace ใหม่หรือไม่
+func sampled() bool {
+	if sampleRatio >= 1 {
+		return true
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	n := uint64(0)
+	for _, v := range b {
+		n = n<<8 | uint64(v)
+	}
+	return float64(n)/float64(math.MaxUint64) < sampleRatio
+}
+
+// Extract อ่าน header traceparent (W3C Trace Context) เพื่อให้ span ของ request ต่อจาก trace ของ service ต้นทาง
+func Extract(ctx context.Context, header http.Header) context.Context {
+	// รูปแบบ: 00-{trace id 32 hex}-{span id 16 hex}-{flags 2 hex}
+	parts := strings.Split(strings.TrimSpace(header.Get("traceparent")), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	var remote remoteParent
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ctx
+	}
+	copy(remote.traceID[:], traceID)
+	copy(remote.spanID[:], spanID)
+	if remote.traceID == ([16]byte{}) || remote.spanID == ([8]byte{}) {
+		return ctx
+	}
+	remote.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, remoteContextKey, remote)
+}
+
+// TraceID รหัสของ trace ในรูปแบบ hex (ว่างถ้า span เป็น nil)
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetAttribute เพิ่ม attribute ให้ span (string, bool, int, int64 หรือ float64)
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = map[string]interface{}{}
+	}
+	s.attrs[key] = value
+}
+
+// SetError บันทึกว่า span นี้ล้มเหลว
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed, s.message = true, err.Error()
+}
+
+// End ปิด span และส่งเข้าคิวเพื่อส่งออกไปยัง collector (เรียกซ้ำได้ ครั้งหลังไม่มีผล)
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+	export(s, time.Now())
+}
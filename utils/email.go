@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+)
+
+// emailMXTimeout เวลาสูงสุดในการค้นหา MX record
+const emailMXTimeout = 3 * time.Second
+
+// NormalizeEmail ตัดช่องว่างและแปลงเป็นตัวพิมพ์เล็ก (ใช้ก่อนตรวจสอบและบันทึกอีเมลทุกครั้ง)
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsValidEmail ตรวจสอบรูปแบบอีเมลตาม RFC 5322 ด้วย net/mail
+// ต้องเป็นที่อยู่อีเมลอย่างเดียว (ไม่มีชื่อแสดงผลหรือ <>) และโดเมนต้องมีจุด
+func IsValidEmail(email string) bool {
+	if len(email) < 3 || len(email) > 254 {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return false
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], email[at+1:]
+	if len(local) > 64 {
+		return false
+	}
+
+	// โดเมนต้องมีจุด และแต่ละส่วนต้องไม่ว่าง (ไม่รับ a@localhost หรือ a@b..com)
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// EmailDomainAcceptsMail ตรวจสอบว่าโดเมนของอีเมลรับอีเมลได้ (มี MX หรือ A record)
+// ทำงานเฉพาะเมื่อตั้ง EMAIL_MX_CHECK=true ถ้าค้นหาไม่สำเร็จเพราะ DNS ขัดข้องจะถือว่าผ่าน
+func EmailDomainAcceptsMail(email string) bool {
+	if os.Getenv("EMAIL_MX_CHECK") != "true" {
+		return true
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	ctx, cancel := context.WithTimeout(context.Background(), emailMXTimeout)
+	defer cancel()
+
+	records, err := net.DefaultResolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		return true
+	}
+	if dnsErr, ok := err.(*net.DNSError); ok && !dnsErr.IsNotFound {
+		return true
+	}
+
+	// ไม่มี MX ให้ลองหา A/AAAA record (RFC 5321 implicit MX)
+	hosts, err := net.DefaultResolver.LookupHost(ctx, domain)
+	return err == nil && len(hosts) > 0
+}
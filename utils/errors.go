@@ -0,0 +1,111 @@
+package utils
+
+import "net/http"
+
+// ErrorCode รหัสข้อผิดพลาดที่ frontend ใช้ตรวจสอบแทนการอ่านข้อความ
+type ErrorCode string
+
+// รหัสข้อผิดพลาดทั่วไป (ใช้เมื่อไม่ได้ระบุรหัสเฉพาะ โดยเลือกจาก HTTP status)
+const (
+	ErrBadRequest           ErrorCode = "BAD_REQUEST"
+	ErrValidation           ErrorCode = "VALIDATION_FAILED"
+	ErrUnauthorized         ErrorCode = "UNAUTHORIZED"
+	ErrForbidden            ErrorCode = "FORBIDDEN"
+	ErrNotFound             ErrorCode = "NOT_FOUND"
+	ErrMethodNotAllowed     ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrConflict             ErrorCode = "CONFLICT"
+	ErrPayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrTooManyRequests      ErrorCode = "TOO_MANY_REQUESTS"
+	ErrInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// รหัสข้อผิดพลาดเฉพาะของร้านค้า
+const (
+	ErrInvalidCredentials  ErrorCode = "INVALID_CREDENTIALS"
+	ErrInvalidToken        ErrorCode = "INVALID_TOKEN"
+	ErrCSRFTokenInvalid    ErrorCode = "CSRF_TOKEN_INVALID"
+	ErrUsernameTaken       ErrorCode = "USERNAME_TAKEN"
+	ErrEmailTaken          ErrorCode = "EMAIL_TAKEN"
+	ErrUserNotFound        ErrorCode = "USER_NOT_FOUND"
+	ErrGameNotFound        ErrorCode = "GAME_NOT_FOUND"
+	ErrMediaNotFound       ErrorCode = "MEDIA_NOT_FOUND"
+	ErrGameUnavailable     ErrorCode = "GAME_UNAVAILABLE"
+	ErrOutOfStock          ErrorCode = "OUT_OF_STOCK"
+	ErrAlreadyOwned        ErrorCode = "ALREADY_OWNED"
+	ErrAgeRestricted       ErrorCode = "AGE_RESTRICTED"
+	ErrBirthdateRequired   ErrorCode = "BIRTHDATE_REQUIRED"
+	ErrCartEmpty           ErrorCode = "CART_EMPTY"
+	ErrInsufficientBalance ErrorCode = "INSUFFICIENT_BALANCE"
+	ErrCodeNotFound        ErrorCode = "CODE_NOT_FOUND"
+	ErrCodeNotYetValid     ErrorCode = "CODE_NOT_YET_VALID"
+	ErrCodeExpired         ErrorCode = "CODE_EXPIRED"
+	ErrCodeInactive        ErrorCode = "CODE_INACTIVE"
+	ErrCodeUsageLimit      ErrorCode = "CODE_USAGE_LIMIT_REACHED"
+	ErrCodeAlreadyUsed     ErrorCode = "CODE_ALREADY_USED"
+	ErrCodeAlreadyExists   ErrorCode = "CODE_ALREADY_EXISTS"
+	ErrMinimumNotMet       ErrorCode = "MINIMUM_PURCHASE_NOT_MET"
+	ErrRedeemLimitExceeded ErrorCode = "REDEEM_LIMIT_EXCEEDED"
+	ErrSaveConflict        ErrorCode = "SAVE_CONFLICT"
+)
+
+// RequestIDHeader header ที่เก็บรหัสของ request (ตั้งโดย middleware และแนบไปกับ error response)
+const RequestIDHeader = "X-Request-ID"
+
+// APIError รูปแบบ error response มาตรฐาน
+type APIError struct {
+	Code      ErrorCode   `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Error     string      `json:"error"` // ข้อความเดิม เก็บไว้ให้ client เก่าที่อ่าน field "error" ยังใช้งานได้
+}
+
+// codeForStatus รหัสข้อผิดพลาดทั่วไปตาม HTTP status
+func codeForStatus(statusCode int) ErrorCode {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrMethodNotAllowed
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	}
+	if statusCode >= 500 {
+		return ErrInternal
+	}
+	return ErrBadRequest
+}
+
+// JSONErrorCode sends a JSON error response with a machine-readable code
+// ฟังก์ชันสำหรับส่ง error response พร้อมรหัสข้อผิดพลาด
+func JSONErrorCode(w http.ResponseWriter, code ErrorCode, message string, statusCode int) {
+	JSONErrorDetails(w, code, message, nil, statusCode)
+}
+
+// JSONErrorDetails sends a JSON error response with a code and extra details (e.g. field errors)
+// ฟังก์ชันสำหรับส่ง error response พร้อมรหัสและรายละเอียดเพิ่มเติม
+// message แปลตามภาษาของ client ส่วน field "error" คงเป็นภาษาอังกฤษให้ client เก่า
+func JSONErrorDetails(w http.ResponseWriter, code ErrorCode, message string, details interface{}, statusCode int) {
+	JSONResponse(w, APIError{
+		Code:      code,
+		Message:   Translate(w.Header().Get(ContentLanguageHeader), message),
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+		Error:     message,
+	}, statusCode)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FieldsHeader header ภายในที่ middleware ใช้ส่งรายชื่อ field ที่ client เลือก (?fields=) ให้ JSONResponse
+// ถูกลบออกก่อนส่ง response จึงไม่ถึง client
+const FieldsHeader = "X-Response-Fields"
+
+// MaxSelectedFields จำนวน field สูงสุดที่เลือกได้ใน ?fields=
+const MaxSelectedFields = 50
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseFields แยกและตรวจสอบรายชื่อ field จาก ?fields=id,name,price (ตัดค่าซ้ำและช่องว่าง)
+func ParseFields(raw string) ([]string, error) {
+	var fields []string
+	seen := map[string]bool{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !fieldNamePattern.MatchString(field) {
+			return nil, fmt.Errorf("invalid field name %q in fields", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields must list at least one field name")
+	}
+	if len(fields) > MaxSelectedFields {
+		return nil, fmt.Errorf("fields may list at most %d names", MaxSelectedFields)
+	}
+	return fields, nil
+}
+
+// SelectFields ตัด response ให้เหลือเฉพาะ field ที่เลือก (sparse response)
+//   - array: เลือก field ของแต่ละ object ใน array
+//   - object ที่มี array ของ object หรือ response แบบแบ่งหน้า (เช่น {data: [...], total: 10}):
+//     เลือก field ของ object ใน array
+//     ส่วนข้อมูลอื่นระดับบนสุด (total, limit, next_cursor) คงไว้
+//   - object เดี่ยว (เช่น เกมหนึ่งเกม): เลือก field ของ object นั้น
+//
+// field ที่ไม่มีในข้อมูลจะถูกข้ามไป
+func SelectFields(data interface{}, fields []string) (interface{}, error) {
+	// แปลงเป็นรูปแบบทั่วไปก่อน เพื่อรองรับทั้ง map และ struct ที่มี json tag
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber() // คงรูปแบบตัวเลขเดิม (เช่น ID ขนาดใหญ่)
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	switch v := generic.(type) {
+	case []interface{}:
+		return selectEach(v, fields), nil
+	case map[string]interface{}:
+		// response แบบแบ่งหน้า (มี total) นับเป็นรายการแม้ array จะว่าง
+		_, paginated := v["total"]
+		collection := false
+		for key, value := range v {
+			if items, ok := value.([]interface{}); ok && (isObjectList(items) || paginated) {
+				v[key] = selectEach(items, fields)
+				collection = true
+			}
+		}
+		if collection {
+			return v, nil
+		}
+		return selectObject(v, fields), nil
+	}
+	return generic, nil
+}
+
+func isObjectList(items []interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+	_, ok := items[0].(map[string]interface{})
+	return ok
+}
+
+func selectEach(items []interface{}, fields []string) []interface{} {
+	for i, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			items[i] = selectObject(object, fields)
+		}
+	}
+	return items
+}
+
+func selectObject(object map[string]interface{}, fields []string) map[string]interface{} {
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := object[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
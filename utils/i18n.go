@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentLanguageHeader header ที่เก็บภาษาของ response (ตั้งโดย middleware และใช้แปลข้อความใน JSONResponse)
+const ContentLanguageHeader = "Content-Language"
+
+// ภาษาที่รองรับ (ข้อความต้นฉบับในโค้ดเป็นภาษาอังกฤษ)
+const (
+	LangEnglish = "en"
+	LangThai    = "th"
+)
+
+// DefaultLanguage ภาษาที่ใช้เมื่อ client ไม่ได้ระบุหรือระบุภาษาที่ไม่รองรับ
+const DefaultLanguage = LangEnglish
+
+// SupportedLanguages รายชื่อภาษาที่รองรับ
+var SupportedLanguages = []string{LangEnglish, LangThai}
+
+// LocaleInfo รูปแบบการแสดงผลตัวเลขและวันที่ของแต่ละภาษา (สำหรับ frontend)
+type LocaleInfo struct {
+	Code              string `json:"code"`
+	Name              string `json:"name"`
+	DecimalSeparator  string `json:"decimal_separator"`
+	GroupingSeparator string `json:"grouping_separator"`
+	DateFormat        string `json:"date_format"`     // รูปแบบวันที่สำหรับแสดงผล (token แบบ Unicode CLDR)
+	DateTimeFormat    string `json:"datetime_format"` // รูปแบบวันที่และเวลาสำหรับแสดงผล
+}
+
+// Locales ข้อมูลการแสดงผลของภาษาที่รองรับ
+var Locales = map[string]LocaleInfo{
+	LangEnglish: {Code: LangEnglish, Name: "English", DecimalSeparator: ".", GroupingSeparator: ",",
+		DateFormat: "MMM d, y", DateTimeFormat: "MMM d, y h:mm a"},
+	LangThai: {Code: LangThai, Name: "ไทย", DecimalSeparator: ".", GroupingSeparator: ",",
+		DateFormat: "d MMM y", DateTimeFormat: "d MMM y HH:mm"},
+}
+
+// translations แคตตาล็อกคำแปลของข้อความ error/success (key = ข้อความภาษาอังกฤษในโค้ด)
+// ข้อความที่ไม่มีในแคตตาล็อก (เช่น ข้อความที่สร้างด้วย fmt.Sprintf) จะแสดงเป็นภาษาอังกฤษ
+var translations = map[string]map[string]string{
+	LangThai: {
+		// ทั่วไป
+		"Method not allowed":         "ไม่รองรับ HTTP method นี้",
+		"Invalid request body":       "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+		"Invalid JSON format":        "รูปแบบ JSON ไม่ถูกต้อง",
+		"Not found":                  "ไม่พบข้อมูล",
+		"Invalid ID":                 "ID ไม่ถูกต้อง",
+		"Validation failed":          "ข้อมูลไม่ผ่านการตรวจสอบ",
+		"No fields to update":        "ไม่มีข้อมูลที่ต้องแก้ไข",
+		"Error starting transaction": "เกิดข้อผิดพลาดในการเริ่มทำรายการ",
+		"Error saving data":          "เกิดข้อผิดพลาดในการบันทึกข้อมูล",
+		"Error parsing form data":    "อ่านข้อมูลฟอร์มไม่สำเร็จ",
+		"Internal server error":      "เกิดข้อผิดพลาดภายในระบบ",
+
+		// การยืนยันตัวตน
+		"Authentication required":                                  "กรุณาเข้าสู่ระบบ",
+		"Authorization required":                                   "กรุณาเข้าสู่ระบบ",
+		"Invalid or expired stream ticket":                         "ticket สำหรับรับข้อมูลแบบ real-time ไม่ถูกต้องหรือหมดอายุ",
+		"Authorization header required":                            "ต้องระบุ Authorization header",
+		"Invalid authorization format":                             "รูปแบบ Authorization ไม่ถูกต้อง",
+		"Admin access required":                                    "ต้องเป็นผู้ดูแลระบบ",
+		"Access denied from this network":                          "ไม่อนุญาตให้เข้าถึงจากเครือข่ายนี้",
+		"Invalid or missing CSRF token":                            "CSRF token ไม่ถูกต้องหรือไม่ได้ส่งมา",
+		"Invalid identifier or password":                           "ชื่อผู้ใช้/อีเมลหรือรหัสผ่านไม่ถูกต้อง",
+		"User ID not found":                                        "ไม่พบข้อมูลผู้ใช้ในคำขอ",
+		"User not found":                                           "ไม่พบผู้ใช้",
+		"Username already exists":                                  "ชื่อผู้ใช้นี้ถูกใช้แล้ว",
+		"Email already exists":                                     "อีเมลนี้ถูกใช้แล้ว",
+		"Invalid email format":                                     "รูปแบบอีเมลไม่ถูกต้อง",
+		"Email domain does not accept mail":                        "โดเมนของอีเมลนี้ไม่รับอีเมล",
+		"User registered successfully":                             "ลงทะเบียนสำเร็จ",
+		"Login successful":                                         "เข้าสู่ระบบสำเร็จ",
+		"Logged out":                                               "ออกจากระบบแล้ว",
+		"Logged out from other devices":                            "ออกจากระบบในอุปกรณ์อื่นแล้ว",
+		"Session revoked":                                          "ยกเลิกเซสชันแล้ว",
+		"API key revoked":                                          "เพิกถอนคีย์ API แล้ว",
+		"API key not found":                                        "ไม่พบคีย์ API",
+		"Invalid API key":                                          "คีย์ API ไม่ถูกต้อง",
+		"API keys are read-only":                                   "คีย์ API ใช้อ่านข้อมูลได้อย่างเดียว",
+		"API key daily quota exceeded":                             "ใช้คีย์ API ครบโควตาของวันนี้แล้ว",
+		"API key is missing the required scope":                    "คีย์ API ไม่มีสิทธิ์ (scope) ที่ต้องใช้",
+		"API key created. Copy it now, it will not be shown again": "สร้างคีย์ API แล้ว กรุณาคัดลอกเก็บไว้ คีย์จะไม่แสดงอีก",
+		"Profile updated successfully":                             "แก้ไขโปรไฟล์สำเร็จ",
+		"Birthdate has already been set":                           "ตั้งวันเกิดไปแล้ว ไม่สามารถแก้ไขได้",
+		"Privacy settings saved":                                   "บันทึกการตั้งค่าความเป็นส่วนตัวแล้ว",
+		"Subscribed to newsletter":                                 "สมัครรับข่าวสารแล้ว",
+		"Unsubscribed from newsletter":                             "ยกเลิกการรับข่าวสารแล้ว",
+		"Invalid unsubscribe link":                                 "ลิงก์ยกเลิกการรับข่าวสารไม่ถูกต้อง",
+
+		// เกมและร้านค้า
+		"Game not found":                       "ไม่พบเกม",
+		"Invalid game ID":                      "ID ของเกมไม่ถูกต้อง",
+		"Game ID required":                     "ต้องระบุ ID ของเกม",
+		"Invalid category ID":                  "ID ของหมวดหมู่ไม่ถูกต้อง",
+		"Game is out of stock":                 "เกมนี้สินค้าหมด",
+		"Game is already delisted":             "เกมนี้ถูกถอดออกจากร้านแล้ว",
+		"Game added successfully":              "เพิ่มเกมสำเร็จ",
+		"Game updated successfully":            "แก้ไขเกมสำเร็จ",
+		"Game delisted successfully":           "ถอดเกมออกจากร้านสำเร็จ",
+		"Error fetching games":                 "เกิดข้อผิดพลาดในการดึงข้อมูลเกม",
+		"Error fetching game":                  "เกิดข้อผิดพลาดในการดึงข้อมูลเกม",
+		"Error fetching storefront":            "เกิดข้อผิดพลาดในการดึงข้อมูลหน้าร้าน",
+		"Game duplicated":                      "คัดลอกเกมเป็นร่างใหม่แล้ว",
+		"Prices updated":                       "แก้ราคาเกมแล้ว",
+		"Preview only. No prices were changed": "แสดงตัวอย่างเท่านั้น ยังไม่มีการแก้ราคา",
+		"Game added to wishlist":               "เพิ่มเกมลงใน wishlist แล้ว",
+		"Game removed from wishlist":           "นำเกมออกจาก wishlist แล้ว",
+		"Game is not in your wishlist":         "เกมนี้ไม่อยู่ใน wishlist ของคุณ",
+		"Alert settings saved":                 "บันทึกการตั้งค่าแจ้งเตือนแล้ว",
+		"Slug is already used by another game": "slug นี้ถูกใช้กับเกมอื่นแล้ว",
+
+		// ตะกร้าและการสั่งซื้อ
+		"Cart is empty":                                             "ตะกร้าสินค้าว่างเปล่า",
+		"Game added to cart":                                        "เพิ่มเกมลงตะกร้าแล้ว",
+		"Game removed from cart":                                    "นำเกมออกจากตะกร้าแล้ว",
+		"Insufficient wallet balance":                               "ยอดเงินในกระเป๋าไม่เพียงพอ",
+		"Purchase completed successfully":                           "สั่งซื้อสำเร็จ",
+		"Add your birthdate to your profile to buy age-rated games": "กรุณาเพิ่มวันเกิดในโปรไฟล์ก่อนซื้อเกมที่มีการจำกัดอายุ",
+		"Order held for review":                                     "คำสั่งซื้ออยู่ระหว่างการตรวจสอบ",
+		"Order approved":                                            "อนุมัติคำสั่งซื้อแล้ว",
+		"Order rejected and refunded":                               "ปฏิเสธคำสั่งซื้อและคืนเงินแล้ว",
+		"Order is not on hold":                                      "คำสั่งซื้อนี้ไม่ได้ถูกพักไว้",
+		"Order not found":                                           "ไม่พบคำสั่งซื้อ",
+		"Game granted":                                              "มอบเกมให้ผู้ใช้แล้ว",
+		"User already owns this game":                               "ผู้ใช้มีเกมนี้อยู่แล้ว",
+
+		// ส่วนลดและกระเป๋าเงิน
+		"Discount code not found":             "ไม่พบรหัสส่วนลด",
+		"Discount code not yet valid":         "รหัสส่วนลดยังไม่เริ่มใช้งาน",
+		"Discount code has expired":           "รหัสส่วนลดหมดอายุแล้ว",
+		"Discount code usage limit reached":   "รหัสส่วนลดถูกใช้ครบจำนวนแล้ว",
+		"Discount code already used":          "คุณใช้รหัสส่วนลดนี้ไปแล้ว",
+		"Deposit successful":                  "เติมเงินสำเร็จ",
+		"Gift card redeemed successfully":     "แลกบัตรของขวัญสำเร็จ",
+		"Gift card has expired":               "บัตรของขวัญหมดอายุแล้ว",
+		"Gift card has already been redeemed": "บัตรของขวัญถูกใช้ไปแล้ว",
+		"Auto top-up settings saved":          "บันทึกการตั้งค่าเติมเงินอัตโนมัติแล้ว",
+
+		// รายการเกมและการรายงาน
+		"List not found":                         "ไม่พบรายการเกม",
+		"List created":                           "สร้างรายการเกมแล้ว",
+		"List updated":                           "แก้ไขรายการเกมแล้ว",
+		"List deleted":                           "ลบรายการเกมแล้ว",
+		"List followed":                          "ติดตามรายการเกมแล้ว",
+		"List unfollowed":                        "เลิกติดตามรายการเกมแล้ว",
+		"You cannot follow your own list":        "ไม่สามารถติดตามรายการของตัวเองได้",
+		"Reported content not found":             "ไม่พบเนื้อหาที่รายงาน",
+		"You cannot report your own content":     "ไม่สามารถรายงานเนื้อหาของตัวเองได้",
+		"You have already reported this content": "คุณรายงานเนื้อหานี้ไปแล้ว",
+		"Report submitted. Thank you for helping keep the store safe": "ส่งรายงานแล้ว ขอบคุณที่ช่วยดูแลร้านค้า",
+
+		// คำถามและคำตอบของเกม
+		"Question not found":   "ไม่พบคำถาม",
+		"Answer not found":     "ไม่พบคำตอบ",
+		"Question posted":      "ส่งคำถามแล้ว",
+		"Answer posted":        "ส่งคำตอบแล้ว",
+		"Question deleted":     "ลบคำถามแล้ว",
+		"Answer deleted":       "ลบคำตอบแล้ว",
+		"Answer highlighted":   "ปักหมุดคำตอบแล้ว",
+		"Answer unhighlighted": "ยกเลิกการปักหมุดคำตอบแล้ว",
+
+		// พาร์ทเนอร์ (affiliate)
+		"Affiliate not found":             "ไม่พบพาร์ทเนอร์",
+		"Affiliate created":               "เพิ่มพาร์ทเนอร์แล้ว",
+		"Affiliate updated":               "แก้ไขพาร์ทเนอร์แล้ว",
+		"Affiliate deactivated":           "ปิดใช้งานพาร์ทเนอร์แล้ว",
+		"Affiliate code already exists":   "รหัสพาร์ทเนอร์นี้มีอยู่แล้ว",
+		"Invalid affiliate ID":            "ID พาร์ทเนอร์ไม่ถูกต้อง",
+		"Error fetching affiliate report": "เกิดข้อผิดพลาดในการดึงรายงานพาร์ทเนอร์",
+
+		// สิทธิ์ของ admin
+		"Permission required":                       "ไม่มีสิทธิ์ใช้งานส่วนนี้",
+		"Unknown permission":                        "ไม่รู้จักสิทธิ์นี้",
+		"Permissions can only be granted to admins": "มอบสิทธิ์ได้เฉพาะผู้ดูแลระบบ",
+		"Permissions updated":                       "บันทึกสิทธิ์แล้ว",
+		"Error fetching permissions":                "เกิดข้อผิดพลาดในการดึงสิทธิ์",
+		"Error updating permissions":                "เกิดข้อผิดพลาดในการบันทึกสิทธิ์",
+		"You cannot change your own permissions":    "ไม่สามารถแก้ไขสิทธิ์ของตัวเองได้",
+		"You can only grant permissions you hold":   "มอบหรือถอนได้เฉพาะสิทธิ์ที่คุณมี",
+
+		// คำแปลของเกม
+		"Translation saved":     "บันทึกคำแปลแล้ว",
+		"Translation deleted":   "ลบคำแปลแล้ว",
+		"Translation not found": "ไม่พบคำแปล",
+
+		// ลิงก์แชร์เกมในคลัง
+		"Invalid share link":     "ลิงก์แชร์ไม่ถูกต้อง",
+		"Share link has expired": "ลิงก์แชร์หมดอายุแล้ว",
+
+		// การจัดระเบียบคลังเกม
+		"Library updated":                "บันทึกการจัดระเบียบคลังเกมแล้ว",
+		"Game not found in your library": "ไม่พบเกมนี้ในคลังของคุณ",
+
+		// รูปโปรไฟล์
+		"Avatar not found":        "ไม่พบรูปโปรไฟล์",
+		"Avatar approved":         "อนุมัติรูปโปรไฟล์แล้ว",
+		"Avatar reset to default": "เปลี่ยนเป็นรูปโปรไฟล์เริ่มต้นแล้ว",
+
+		// การตั้งค่าภาษาและ time zone
+		"Preferences saved":   "บันทึกการตั้งค่าแล้ว",
+		"Invalid preferences": "การตั้งค่าไม่ถูกต้อง",
+		"Invalid timezone":    "time zone ไม่ถูกต้อง",
+
+		// การแจ้งเตือน
+		"Notification marked as read": "ทำเครื่องหมายว่าอ่านแล้ว",
+
+		// feature flag
+		"Feature not available":  "ฟีเจอร์นี้ยังไม่เปิดให้ใช้งาน",
+		"Feature flag not found": "ไม่พบ feature flag",
+		"Feature flag deleted":   "ลบ feature flag แล้ว",
+		"Invalid flag key":       "ชื่อ feature flag ไม่ถูกต้อง",
+		"Flag key required":      "กรุณาระบุชื่อ feature flag",
+	},
+}
+
+// Translate แปลข้อความเป็นภาษาที่ระบุ (คืนข้อความเดิมถ้าไม่มีคำแปล)
+func Translate(lang, message string) string {
+	if catalog, ok := translations[lang]; ok {
+		if translated, ok := catalog[message]; ok {
+			return translated
+		}
+	}
+	return message
+}
+
+// NormalizeLanguage แปลงรหัสภาษา (เช่น "th-TH", "EN_us") เป็นภาษาที่รองรับ คืน "" ถ้าไม่รองรับ
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, lang := range SupportedLanguages {
+		if tag == lang {
+			return lang
+		}
+	}
+	return ""
+}
+
+// NegotiateLanguage เลือกภาษาของ response: ค่าที่ระบุตรง (?lang=) ก่อน แล้วจึงดูจาก Accept-Language ตามค่า q
+func NegotiateLanguage(explicit, acceptLanguage string) string {
+	if lang := NormalizeLanguage(explicit); lang != "" {
+		return lang
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := NormalizeLanguage(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+	// เรียงตามค่า q (ค่าเท่ากันคงลำดับเดิมที่ client ส่งมา)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ค่าเริ่มต้นของนโยบายรหัสผ่าน
+// PASSWORD_MIN_LENGTH       - ความยาวขั้นต่ำ (ค่าเริ่มต้น 8)
+// PASSWORD_MIN_CLASSES      - จำนวนประเภทตัวอักษรขั้นต่ำจาก ตัวพิมพ์เล็ก/ตัวพิมพ์ใหญ่/ตัวเลข/สัญลักษณ์ (ค่าเริ่มต้น 2)
+// PASSWORD_BREACH_CHECK     - true เพื่อตรวจสอบกับฐานข้อมูลรหัสผ่านที่รั่วไหล (Have I Been Pwned)
+const (
+	defaultPasswordMinLength  = 8
+	defaultPasswordMinClasses = 2
+	passwordMaxLength         = 72 // bcrypt ใช้แค่ 72 byte แรก
+)
+
+// pwnedPasswordsURL API แบบ k-anonymity (ส่งเฉพาะ 5 ตัวแรกของ SHA-1 ไม่ส่งรหัสผ่านจริง)
+const pwnedPasswordsURL = "https://api.pwnedpasswords.com/range/"
+
+var pwnedClient = &http.Client{Timeout: 3 * time.Second}
+
+// CheckPasswordPolicy ตรวจสอบรหัสผ่านใหม่ตามนโยบาย คืนข้อผิดพลาดแรกที่พบ
+// username และ email ใช้ตรวจสอบว่ารหัสผ่านไม่มีข้อมูลเหล่านี้อยู่ข้างใน (ส่งค่าว่างได้)
+func CheckPasswordPolicy(password, username, email string) error {
+	minLength := envInt("PASSWORD_MIN_LENGTH", defaultPasswordMinLength)
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("password must be at least %d characters", minLength)
+	}
+	if len(password) > passwordMaxLength {
+		return fmt.Errorf("password must be at most %d bytes", passwordMaxLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if minClasses := envInt("PASSWORD_MIN_CLASSES", defaultPasswordMinClasses); classes < minClasses {
+		return fmt.Errorf("password must contain at least %d of: lowercase letters, uppercase letters, numbers, symbols", minClasses)
+	}
+
+	lowered := strings.ToLower(password)
+	if name := strings.ToLower(strings.TrimSpace(username)); len(name) >= 3 && strings.Contains(lowered, name) {
+		return errors.New("password must not contain your username")
+	}
+	if email = NormalizeEmail(email); email != "" {
+		local := email
+		if at := strings.LastIndex(email, "@"); at > 0 {
+			local = email[:at]
+		}
+		if len(local) >= 3 && strings.Contains(lowered, local) {
+			return errors.New("password must not contain your email address")
+		}
+	}
+
+	if os.Getenv("PASSWORD_BREACH_CHECK") == "true" && passwordBreached(password) {
+		return errors.New("this password has appeared in a data breach; please choose a different one")
+	}
+
+	return nil
+}
+
+// passwordBreached ตรวจสอบรหัสผ่านกับ Have I Been Pwned ผ่าน k-anonymity
+// ถ้าเรียก API ไม่สำเร็จจะถือว่าไม่รั่วไหล (ไม่ให้การสมัครสมาชิกล้มเหลวเพราะบริการภายนอก)
+func passwordBreached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest("GET", pwnedPasswordsURL+prefix, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := pwnedClient.Do(req)
+	if err != nil {
+		fmt.Printf("⚠️ Breached password check failed: %v\n", err)
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("⚠️ Breached password check returned status %d\n", resp.StatusCode)
+		return false
+	}
+
+	// แต่ละบรรทัดคือ SUFFIX:COUNT (บรรทัดที่ COUNT เป็น 0 คือ padding)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if found && candidate == suffix && count != "0" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ขนาดหน้ากระดาษ A4 (หน่วย point) และการจัดวางข้อความของ PDF
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfFontSize     = 10
+	pdfLineHeight   = 14
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+// pdfEscape แปลงข้อความให้อยู่ในรูปแบบ string ของ PDF
+// ฟอนต์มาตรฐาน Courier รองรับเฉพาะ ASCII ตัวอักษรอื่น (เช่นภาษาไทย) จะถูกแทนด้วย ?
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// TextPDF สร้างเอกสาร PDF แบบข้อความล้วน (ฟอนต์ monospace) จากรายการบรรทัด แบ่งหน้าอัตโนมัติ
+// ใช้สำหรับเอกสารง่ายๆ เช่นใบแจ้งยอด โดยไม่ต้องพึ่งไลบรารีภายนอก
+func TextPDF(lines []string) []byte {
+	var pages [][]string
+	for start := 0; start < len(lines) || start == 0; start += pdfLinesPerPage {
+		end := min(start+pdfLinesPerPage, len(lines))
+		pages = append(pages, lines[start:end])
+	}
+
+	// object 1 = catalog, 2 = pages, 3 = font, ต่อจากนั้นเป็นคู่ (page, content) ของแต่ละหน้า
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	)
+	for i, pageLines := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range pageLines {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+		}
+		content.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	// เขียนไฟล์พร้อมตาราง xref (ตำแหน่ง byte ของแต่ละ object)
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// PDFResponse ส่งไฟล์ PDF ให้ browser ดาวน์โหลด
+func PDFResponse(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimestampLayout รูปแบบวันเวลาใน JSON ของ API (ISO-8601 / RFC3339 เวลา UTC เช่น 2025-01-31T17:00:00Z)
+const TimestampLayout = "2006-01-02T15:04:05Z07:00"
+
+// FormatTimestamp แปลงวันเวลาเป็น string ตามรูปแบบของ API (UTC ตัดเศษวินาที)
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(TimestampLayout)
+}
+
+// ParseTimestamp แปลง string เป็นวันเวลา รับทั้ง RFC3339 และรูปแบบ DATETIME ของฐานข้อมูล (ถือเป็น UTC)
+func ParseTimestamp(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// scanTime แปลงค่าจาก driver เป็นวันเวลา (time.Time เมื่อ DSN มี parseTime=true หรือ []byte/string ในกรณีอื่น)
+func scanTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case []byte:
+		return ParseTimestamp(string(v))
+	case string:
+		return ParseTimestamp(v)
+	}
+	return time.Time{}, fmt.Errorf("cannot scan %T into timestamp", src)
+}
+
+// Timestamp วันเวลาที่ scan จากฐานข้อมูลได้โดยตรงและแปลงเป็น JSON ตามรูปแบบของ API
+type Timestamp struct {
+	time.Time
+}
+
+// NewTimestamp สร้าง Timestamp จาก time.Time
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{Time: t.UTC()}
+}
+
+// Scan implements sql.Scanner
+func (t *Timestamp) Scan(src interface{}) error {
+	parsed, err := scanTime(src)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.Time.UTC(), nil
+}
+
+// String วันเวลาตามรูปแบบของ API
+func (t Timestamp) String() string {
+	return FormatTimestamp(t.Time)
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseTimestamp(raw)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// NullTimestamp วันเวลาที่อาจเป็น NULL (เช่น redeemed_at, read_at) แปลงเป็น JSON null เมื่อไม่มีค่า
+type NullTimestamp struct {
+	Time  time.Time
+	Valid bool
+}
+
+// NewNullTimestamp สร้าง NullTimestamp จาก pointer (nil = NULL)
+func NewNullTimestamp(t *time.Time) NullTimestamp {
+	if t == nil {
+		return NullTimestamp{}
+	}
+	return NullTimestamp{Time: t.UTC(), Valid: true}
+}
+
+// Scan implements sql.Scanner
+func (t *NullTimestamp) Scan(src interface{}) error {
+	if src == nil {
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	}
+	parsed, err := scanTime(src)
+	if err != nil {
+		return err
+	}
+	t.Time, t.Valid = parsed, true
+	return nil
+}
+
+// Value implements driver.Valuer
+func (t NullTimestamp) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.Time.UTC(), nil
+}
+
+// String วันเวลาตามรูปแบบของ API (ว่างเมื่อเป็น NULL)
+func (t NullTimestamp) String() string {
+	if !t.Valid {
+		return ""
+	}
+	return FormatTimestamp(t.Time)
+}
+
+// Ptr คืน pointer ของ string วันเวลา (nil เมื่อเป็น NULL) สำหรับ struct ที่ใช้ *string
+func (t NullTimestamp) Ptr() *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.String()
+	return &s
+}
+
+// MarshalJSON implements json.Marshaler
+func (t NullTimestamp) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *NullTimestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == "" {
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	}
+	parsed, err := ParseTimestamp(raw)
+	if err != nil {
+		return err
+	}
+	t.Time, t.Valid = parsed, true
+	return nil
+}
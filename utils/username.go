@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ค่าเริ่มต้นของนโยบายชื่อผู้ใช้ (ปรับได้ด้วย USERNAME_MIN_LENGTH และ USERNAME_MAX_LENGTH)
+const (
+	defaultUsernameMinLength = 3
+	defaultUsernameMaxLength = 30
+)
+
+// defaultReservedUsernames ชื่อที่สงวนไว้ (เพิ่มได้ด้วย USERNAME_RESERVED คั่นด้วย comma)
+var defaultReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "support", "moderator",
+	"staff", "api", "null", "undefined", "me", "gameshop",
+}
+
+// usernameDenyList คำที่ห้ามอยู่ในชื่อผู้ใช้ (โหลดครั้งเดียวจาก USERNAME_DENYLIST และ USERNAME_DENYLIST_FILE)
+var (
+	usernameDenyList     []string
+	usernameDenyListOnce sync.Once
+)
+
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+// splitList แยกค่าที่คั่นด้วย comma เป็นรายการตัวพิมพ์เล็ก
+func splitList(raw string) []string {
+	items := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadUsernameDenyList อ่านคำต้องห้ามจาก env และไฟล์ (หนึ่งคำต่อบรรทัด, บรรทัดที่ขึ้นต้นด้วย # เป็น comment)
+func loadUsernameDenyList() []string {
+	usernameDenyListOnce.Do(func() {
+		usernameDenyList = splitList(os.Getenv("USERNAME_DENYLIST"))
+
+		path := os.Getenv("USERNAME_DENYLIST_FILE")
+		if path == "" {
+			return
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("⚠️ Cannot read username deny-list %s: %v\n", path, err)
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			if word != "" && !strings.HasPrefix(word, "#") {
+				usernameDenyList = append(usernameDenyList, word)
+			}
+		}
+	})
+	return usernameDenyList
+}
+
+// NormalizeUsername ตัดช่องว่างหน้า-หลังและแปลงเป็น Unicode NFC
+// ตัวอักษรที่ดูเหมือนกันแต่เขียนต่างกัน (เช่น é แบบตัวเดียว กับ e + U+0301) จะกลายเป็นชื่อเดียวกัน
+// ความซ้ำของชื่อผู้ใช้ตรวจสอบด้วย UsernameKey
+func NormalizeUsername(username string) string {
+	return norm.NFC.String(strings.TrimSpace(username))
+}
+
+// UsernameKey ค่าที่ใช้เปรียบเทียบความซ้ำของชื่อผู้ใช้ (NFC และ case folding ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+// เก็บไว้ในคอลัมน์ users.username_key ที่มี unique index ตอนสมัครและเปลี่ยนชื่อ จึงสมัคร "Straße" กับ "STRASSE" ซ้ำกันไม่ได้
+func UsernameKey(username string) string {
+	return norm.NFC.String(cases.Fold().String(NormalizeUsername(username)))
+}
+
+// isUsernameSeparator ตัวคั่นที่อนุญาตในชื่อผู้ใช้
+func isUsernameSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-'
+}
+
+// ValidateUsername ตรวจสอบชื่อผู้ใช้ตามนโยบาย
+//   - ความยาว (นับเป็นตัวอักษร ไม่ใช่ byte) ระหว่าง USERNAME_MIN_LENGTH ถึง USERNAME_MAX_LENGTH
+//   - ประกอบด้วยตัวอักษรทุกภาษา (รวมสระ/วรรณยุกต์ไทย) ตัวเลข และ _ . - เท่านั้น
+//   - ต้องไม่ขึ้นต้นหรือลงท้ายด้วยตัวคั่น และไม่มีตัวคั่นติดกัน
+//   - ต้องไม่ใช่ชื่อที่สงวนไว้ และไม่มีคำต้องห้าม
+func ValidateUsername(username string) error {
+	minLength := envInt("USERNAME_MIN_LENGTH", defaultUsernameMinLength)
+	maxLength := envInt("USERNAME_MAX_LENGTH", defaultUsernameMaxLength)
+
+	length := utf8.RuneCountInString(username)
+	if length < minLength || length > maxLength {
+		return fmt.Errorf("username must be between %d and %d characters", minLength, maxLength)
+	}
+
+	var previous rune
+	for i, r := range []rune(username) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r):
+			// สระและวรรณยุกต์ (เช่นภาษาไทย) ต้องตามหลังตัวอักษร
+			if i == 0 || isUsernameSeparator(previous) {
+				return errors.New("username cannot start with a combining mark")
+			}
+		case isUsernameSeparator(r):
+			if i == 0 || i == length-1 {
+				return errors.New("username cannot start or end with '_', '.' or '-'")
+			}
+			if isUsernameSeparator(previous) {
+				return errors.New("username cannot contain consecutive '_', '.' or '-'")
+			}
+		default:
+			return errors.New("username may only contain letters, numbers, '_', '.' and '-'")
+		}
+		previous = r
+	}
+
+	key := UsernameKey(username)
+	reserved := append(append([]string{}, defaultReservedUsernames...), splitList(os.Getenv("USERNAME_RESERVED"))...)
+	for _, name := range reserved {
+		if key == name {
+			return errors.New("this username is reserved")
+		}
+	}
+
+	for _, word := range loadUsernameDenyList() {
+		if strings.Contains(key, word) {
+			return errors.New("username contains a disallowed word")
+		}
+	}
+
+	return nil
+}
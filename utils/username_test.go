@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestNormalizeUsernameComposesCombiningMarks(t *testing.T) {
+	precomposed := "caf\u00e9_fan" // é เป็นตัวอักษรเดียว (U+00E9)
+	decomposed := "cafe\u0301_fan" // e ตามด้วย combining acute accent (U+0301)
+
+	if precomposed == decomposed {
+		t.Fatal("test inputs must differ before normalization")
+	}
+	if got, want := NormalizeUsername(decomposed), NormalizeUsername(precomposed); got != want {
+		t.Errorf("NormalizeUsername(%q) = %q, want %q", decomposed, got, want)
+	}
+	if NormalizeUsername(decomposed) != precomposed {
+		t.Errorf("NormalizeUsername(%q) is not NFC", decomposed)
+	}
+	if got, want := UsernameKey("CAFÉ_Fan"), UsernameKey(precomposed); got != want {
+		t.Errorf("UsernameKey = %q, want %q", got, want)
+	}
+	for _, name := range []string{precomposed, decomposed} {
+		if err := ValidateUsername(NormalizeUsername(name)); err != nil {
+			t.Errorf("ValidateUsername(%q) = %v", name, err)
+		}
+	}
+}
+
+func TestUsernameKeyCaseFolds(t *testing.T) {
+	if got, want := UsernameKey("  Straße "), UsernameKey("STRASSE"); got != want {
+		t.Errorf("UsernameKey = %q, want %q", got, want)
+	}
+}
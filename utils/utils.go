@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 )
 
 // JSONResponse sends a JSON response
@@ -11,6 +14,30 @@ func JSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 	// ตั้งค่า Header ให้เป็น application/json
 	w.Header().Set("Content-Type", "application/json")
 
+	// แปลงข้อความ "message" เป็นภาษาของ client (ภาษาตั้งโดย middleware ใน Content-Language)
+	if lang := w.Header().Get(ContentLanguageHeader); lang != "" && lang != DefaultLanguage {
+		switch body := data.(type) {
+		case map[string]interface{}:
+			if message, ok := body["message"].(string); ok {
+				body["message"] = Translate(lang, message)
+			}
+		case map[string]string:
+			if message, ok := body["message"]; ok {
+				body["message"] = Translate(lang, message)
+			}
+		}
+	}
+
+	// เลือกเฉพาะ field ที่ client ขอ (?fields= ตั้งโดย middleware) เฉพาะ response ที่สำเร็จ
+	if fields := w.Header().Get(FieldsHeader); fields != "" {
+		w.Header().Del(FieldsHeader)
+		if statusCode >= 200 && statusCode < 300 {
+			if selected, err := SelectFields(data, strings.Split(fields, ",")); err == nil {
+				data = selected
+			}
+		}
+	}
+
 	// ตั้งค่า HTTP Status Code
 	w.WriteHeader(statusCode)
 
@@ -19,8 +46,22 @@ func JSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 }
 
 // JSONError sends a JSON error response
-// ฟังก์ชันสำหรับส่ง error response แบบ JSON
+// ฟังก์ชันสำหรับส่ง error response แบบ JSON (รหัสข้อผิดพลาดเลือกจาก HTTP status)
 func JSONError(w http.ResponseWriter, message string, statusCode int) {
-	// เรียกใช้ JSONResponse ด้วยรูปแบบ error มาตรฐาน
-	JSONResponse(w, map[string]string{"error": message}, statusCode)
+	// เรียกใช้ JSONErrorCode ด้วยรูปแบบ error มาตรฐาน
+	JSONErrorCode(w, codeForStatus(statusCode), message, statusCode)
+}
+
+// CSVWriter prepares a CSV download response and returns a writer for it
+// ฟังก์ชันสำหรับเตรียม response แบบไฟล์ CSV (ผู้เรียกต้อง Flush เมื่อเขียนเสร็จ)
+func CSVWriter(w http.ResponseWriter, filename string) *csv.Writer {
+	// ตั้งค่า Header ให้ browser ดาวน์โหลดเป็นไฟล์
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	// เขียน UTF-8 BOM เพื่อให้ Excel อ่านภาษาไทยได้ถูกต้อง
+	w.Write([]byte("\xEF\xBB\xBF"))
+
+	return csv.NewWriter(w)
 }
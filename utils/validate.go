@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError ข้อผิดพลาดของ field หนึ่งใน request
+type FieldError struct {
+	Field   string `json:"field"`   // ชื่อ field ตาม json tag
+	Rule    string `json:"rule"`    // กฎที่ไม่ผ่าน เช่น required, min, email
+	Message string `json:"message"` // ข้อความสำหรับแสดงผล
+}
+
+// ValidationErrors รายการข้อผิดพลาดของทุก field ที่ไม่ผ่าน
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate ตรวจสอบ struct ตาม tag `validate:"..."` คืนค่า nil ถ้าผ่านทั้งหมด
+//
+// กฎที่รองรับ (คั่นด้วย comma):
+//
+//	required    ต้องมีค่า (string ที่ไม่ว่าง, pointer ที่ไม่เป็น nil, ตัวเลขที่ไม่เป็น 0)
+//	min=N       string ยาวอย่างน้อย N ตัวอักษร / ตัวเลขมีค่าอย่างน้อย N
+//	max=N       string ยาวไม่เกิน N ตัวอักษร / ตัวเลขมีค่าไม่เกิน N
+//	gt=N        ตัวเลขต้องมากกว่า N
+//	email       รูปแบบอีเมล
+//	oneof=a b   ต้องเป็นหนึ่งในค่าที่กำหนด (คั่นด้วยช่องว่าง)
+//	date        วันที่รูปแบบ YYYY-MM-DD
+//
+// string ที่ว่างและไม่ได้ระบุ required จะข้ามกฎอื่นทั้งหมด
+func Validate(v interface{}) ValidationErrors {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		if fe := validateField(name, value.Field(i), strings.Split(tag, ",")); fe != nil {
+			errs = append(errs, *fe)
+		}
+	}
+	return errs
+}
+
+// validateField ตรวจสอบค่าหนึ่ง field คืนข้อผิดพลาดแรกที่พบ
+func validateField(name string, fieldValue reflect.Value, rules []string) *FieldError {
+	required := false
+	for _, rule := range rules {
+		if rule == "required" {
+			required = true
+		}
+	}
+
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.IsNil() {
+			if required {
+				return &FieldError{name, "required", name + " is required"}
+			}
+			return nil
+		}
+		fieldValue = fieldValue.Elem()
+	}
+
+	isString := fieldValue.Kind() == reflect.String
+	if isString && strings.TrimSpace(fieldValue.String()) == "" {
+		if required {
+			return &FieldError{name, "required", name + " is required"}
+		}
+		return nil
+	}
+	if required && fieldValue.IsZero() {
+		return &FieldError{name, "required", name + " is required"}
+	}
+
+	for _, rule := range rules {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch ruleName {
+		case "min", "max", "gt":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			if isString {
+				length := float64(utf8.RuneCountInString(fieldValue.String()))
+				if ruleName == "min" && length < limit {
+					return &FieldError{name, ruleName, fmt.Sprintf("%s must be at least %s characters", name, param)}
+				}
+				if ruleName == "max" && length > limit {
+					return &FieldError{name, ruleName, fmt.Sprintf("%s must be at most %s characters", name, param)}
+				}
+				continue
+			}
+			number, ok := numericValue(fieldValue)
+			if !ok {
+				continue
+			}
+			switch {
+			case ruleName == "min" && number < limit:
+				return &FieldError{name, ruleName, fmt.Sprintf("%s must be at least %s", name, param)}
+			case ruleName == "max" && number > limit:
+				return &FieldError{name, ruleName, fmt.Sprintf("%s must be at most %s", name, param)}
+			case ruleName == "gt" && number <= limit:
+				return &FieldError{name, ruleName, fmt.Sprintf("%s must be greater than %s", name, param)}
+			}
+		case "email":
+			if isString && !IsValidEmail(fieldValue.String()) {
+				return &FieldError{name, ruleName, name + " must be a valid email address"}
+			}
+		case "oneof":
+			allowed := strings.Fields(param)
+			current := fmt.Sprint(fieldValue.Interface())
+			found := false
+			for _, option := range allowed {
+				if current == option {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return &FieldError{name, ruleName, fmt.Sprintf("%s must be one of: %s", name, strings.Join(allowed, ", "))}
+			}
+		case "date":
+			if _, err := time.Parse("2006-01-02", fieldValue.String()); isString && err != nil {
+				return &FieldError{name, ruleName, name + " must be a date in YYYY-MM-DD format"}
+			}
+		}
+	}
+	return nil
+}
+
+// numericValue แปลงค่าตัวเลขทุกชนิดเป็น float64
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// JSONValidationError sends a validation error response with field-level details
+// ฟังก์ชันสำหรับส่ง error response เมื่อข้อมูลไม่ผ่านการตรวจสอบ (message คือข้อผิดพลาดแรก, details คือทุก field)
+func JSONValidationError(w http.ResponseWriter, errs ValidationErrors) {
+	message := "Validation failed"
+	if len(errs) > 0 {
+		message = errs[0].Message
+	}
+	JSONErrorDetails(w, ErrValidation, message, errs, http.StatusBadRequest)
+}